@@ -31,6 +31,7 @@ func TestCache_CacheInfoAndDirectory(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories: []
 `
 	require.NoError(t, os.WriteFile(cfgPath, []byte(yamlContent), 0o600))
@@ -110,6 +111,7 @@ func TestCache_CacheCleanOperations(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -179,6 +181,7 @@ func TestCache_CacheWithCustomDirectories(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories: []
 `
 	require.NoError(t, os.WriteFile(cfgPath, []byte(yamlContent), 0o600))
@@ -227,6 +230,7 @@ func TestCache_CacheCorruptionRecovery(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories: []
 `
 	require.NoError(t, os.WriteFile(cfgPath, []byte(yamlContent), 0o600))
@@ -43,6 +43,7 @@ func TestCleanup_DryRun(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -126,6 +127,7 @@ func TestCleanup_ActualCleanup(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -198,6 +200,7 @@ func TestCleanup_NoOrphanedArtifacts(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -265,6 +268,7 @@ func TestCleanup_MixedManualAndAutomatic(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -342,6 +346,7 @@ func TestCleanup_ManualDependencyConversion(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -279,7 +279,8 @@ func TestInstall_FailingPreInstallHook(t *testing.T) {
 	// Create artifact source with a failing pre-install hook
 	src := createArtifactSourceWithHook(t, tempDir, "pre-install", `
 // Failing pre-install hook
-ohno
+notCallable := 1
+notCallable()
 `)
 
 	// Build repo with the artifact
@@ -321,6 +322,58 @@ ohno
 	}
 }
 
+func TestInstall_FailingPostInstallHook(t *testing.T) {
+	tempDir := t.TempDir()
+
+	src := createArtifactSourceWithHook(t, tempDir, "post-install", `
+// Failing post-install hook
+notCallable := 1
+notCallable()
+`)
+
+	repoDir := filepath.Join(tempDir, "repo")
+	artifactsDir := filepath.Join(repoDir, "artifacts")
+	require.NoError(t, os.MkdirAll(artifactsDir, 0o755))
+
+	path := createArtifactViaCLI(t, src, "testapp", "1.0.0", artifactsDir, nil, []string{"post-install=post-install.tengo"})
+	require.NotEmpty(t, path)
+
+	outFile := filepath.Join(repoDir, "index.json")
+	generateIndexViaCLI(t, artifactsDir, outFile, "artifacts", true)
+
+	srv, idxURL := startRepoServer(t, repoDir)
+	defer srv.Close()
+
+	cfgPath := filepath.Join(tempDir, "config.yaml")
+	cacheDir := filepath.Join(tempDir, "cache")
+	writeTempConfig(t, cfgPath, "testrepo", idxURL, cacheDir)
+
+	syncCmd := newRootCmd()
+	syncCmd.SetArgs([]string{"--config", cfgPath, "sync"})
+	require.NoError(t, syncCmd.ExecuteContext(context.Background()))
+
+	// By default the failed hook rolls the installation back
+	cmd := newRootCmd()
+	cmd.SetArgs([]string{"--config", cfgPath, "install", "testapp"})
+	require.Error(t, cmd.ExecuteContext(context.Background()), "install should fail when post-install hook fails")
+	for _, a := range getInstalledArtifactsFromDB(t, cfgPath) {
+		assert.NotEqual(t, "testapp", a.Name, "testapp should be rolled back after hook failure")
+	}
+
+	// --keep-on-hook-failure keeps it installed and records the failure
+	cmd = newRootCmd()
+	cmd.SetArgs([]string{"--config", cfgPath, "install", "--keep-on-hook-failure", "testapp"})
+	require.NoError(t, cmd.ExecuteContext(context.Background()))
+	var kept bool
+	for _, a := range getInstalledArtifactsFromDB(t, cfgPath) {
+		if a.Name == "testapp" {
+			kept = true
+			assert.NotEmpty(t, a.PostInstallHookError)
+		}
+	}
+	assert.True(t, kept, "testapp should stay installed with --keep-on-hook-failure")
+}
+
 // createArtifactSourceWithHook creates an artifact source directory with a hook script
 func createArtifactSourceWithHook(t *testing.T, root, hookName, hookScript string) string {
 	t.Helper()
@@ -10,9 +10,11 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
+	"github.com/glorpus-work/gotya/pkg/artifact"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -85,6 +87,7 @@ func TestInstall_InstallWithDependencies(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: dep-repo
     url: ` + depURL + `
@@ -177,6 +180,7 @@ func TestInstall_WithCustomCacheDir(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -232,6 +236,7 @@ func TestInstall_FailureScenarios(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 1s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -283,6 +288,7 @@ repositories:
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -344,6 +350,7 @@ func TestInstall_WithVersionConstraints(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -478,6 +485,7 @@ func TestInstall_VersionUpgradeScenario(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -564,6 +572,7 @@ func TestInstall_DependencyCompatibilityConflict(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -656,6 +665,7 @@ func TestInstall_WithAuthentication(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: authrepo
     url: ` + srv.URL + `/index.json
@@ -729,6 +739,7 @@ repositories:
   state_dir: ` + noAuthTempDir + `/state
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: noauthrepo
     url: ` + srv.URL + `/index.json
@@ -783,6 +794,7 @@ repositories:
   state_dir: ` + wrongAuthTempDir + `/state
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: wrongauthrepo
     url: ` + srv.URL + `/index.json
@@ -807,3 +819,31 @@ repositories:
 		assert.Contains(t, err.Error(), "401")
 	})
 }
+
+func TestInstall_UnmetHostRequirement(t *testing.T) {
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "repo")
+	artifactsDir := filepath.Join(repoDir, "artifacts")
+	require.NoError(t, os.MkdirAll(artifactsDir, 0o755))
+
+	packer := artifact.NewPacker("needs-host", "1.0.0", runtime.GOOS, runtime.GOARCH, "", "requires a missing runtime", nil, nil, createSampleArtifactSource(t, tempDir), artifactsDir)
+	require.NoError(t, packer.SetRequirements(map[string]string{"no-such-runtime": "1.0.0"}))
+	_, err := packer.Pack()
+	require.NoError(t, err)
+	generateIndexViaCLI(t, artifactsDir, filepath.Join(repoDir, "index.json"), "artifacts", true)
+	srv, idxURL := startRepoServer(t, repoDir)
+	defer srv.Close()
+
+	cfgPath := filepath.Join(tempDir, "config.yaml")
+	writeTempConfig(t, cfgPath, "testrepo", idxURL, filepath.Join(tempDir, "cache"))
+	syncCmd := newRootCmd()
+	syncCmd.SetArgs([]string{"--config", cfgPath, "sync"})
+	require.NoError(t, syncCmd.ExecuteContext(context.Background()))
+
+	cmd := newRootCmd()
+	cmd.SetArgs([]string{"--config", cfgPath, "install", "needs-host"})
+	err = cmd.ExecuteContext(context.Background())
+	require.ErrorIs(t, err, artifact.ErrRequirementNotMet)
+	assert.Contains(t, err.Error(), "no-such-runtime")
+	assert.Empty(t, getInstalledArtifactsFromDB(t, cfgPath))
+}
@@ -135,7 +135,8 @@ func writeTempConfig(t *testing.T, path, repoName, indexURL, cacheDir string) {
 		"  meta_dir: " + strings.ReplaceAll(metaDir, "\\", "\\\\") + "\n" +
 		"  state_dir: " + strings.ReplaceAll(stateDir, "\\", "\\\\") + "\n" +
 		"  http_timeout: 5s\n" +
-		"  max_concurrent_syncs: 2\n"
+		"  max_concurrent_syncs: 2\n" +
+		"  allow_insecure: true\n"
 	if indexURL != "" {
 		yamlContent += "repositories:\n" +
 			"  - name: " + repoName + "\n" +
@@ -41,6 +41,7 @@ func TestList_ListAllInstalledPackages(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -146,6 +147,7 @@ func TestList_ListWithNameFiltering(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -236,6 +238,7 @@ func TestList_ListEmptyDatabase(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories: []
 `
 	require.NoError(t, os.WriteFile(cfgPath, []byte(yamlContent), 0o600))
@@ -290,6 +293,7 @@ func TestList_ListWithStatusIndicators(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
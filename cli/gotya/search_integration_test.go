@@ -43,6 +43,7 @@ func TestSearch_BasicFuzzySearch(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -147,6 +148,7 @@ func TestSearch_SearchWithNoResults(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -218,6 +220,7 @@ func TestSearch_SearchAcrossMultipleRepositories(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: lib-repo
     url: ` + libURL + `
@@ -297,6 +300,7 @@ func TestSearch_SearchWithSpecialCharacters(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -35,6 +35,7 @@ func TestUninstall_BasicUninstall(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -107,6 +108,7 @@ func TestUninstall_WithPurgeFlag(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -173,6 +175,7 @@ func TestUninstall_UninstallNonExistentPackage(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories: []
 `
 	require.NoError(t, os.WriteFile(cfgPath, []byte(yamlContent), 0o600))
@@ -221,6 +224,7 @@ func TestUninstall_UninstallWithDependencies(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: dep-repo
     url: ` + depURL + `
@@ -304,6 +308,7 @@ func TestUninstall_UninstallWithMissingFiles(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
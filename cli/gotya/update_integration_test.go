@@ -39,6 +39,7 @@ func TestUpdate_UpdateSpecificPackages(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -117,6 +118,7 @@ func TestUpdate_UpdateAllPackages(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -204,6 +206,7 @@ func TestUpdate_UpdateDryRunMode(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -286,6 +289,7 @@ func TestUpdate_UpdateWithDependencyResolution(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: dep-repo
     url: ` + depURL + `
@@ -359,6 +363,7 @@ func TestUpdate_UpdateFailureScenarios(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -416,6 +421,7 @@ repositories:
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories: []
 `
 		require.NoError(t, os.WriteFile(cfgPath, []byte(yamlContent), 0o600))
@@ -465,6 +471,7 @@ func TestUpdate_CrossRepositoryDependencyResolution(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: lib-repo
     url: ` + libURL + `
@@ -550,6 +557,7 @@ func TestUpdate_UpdateWithVersionConflicts(t *testing.T) {
   state_dir: ` + stateDir + `
   http_timeout: 5s
   max_concurrent_syncs: 2
+  allow_insecure: true
 repositories:
   - name: testrepo
     url: ` + idxURL + `
@@ -18,16 +18,22 @@ import (
 
 // createOptions holds flags for the create command.
 type createOptions struct {
-	sourceDir    string
-	outputDir    string
-	pkgName      string
-	pkgVer       string
-	pkgOS        string
-	pkgArch      string
-	maintainer   string
-	description  string
-	dependencies []string
-	rawHooks     []string
+	sourceDir        string
+	outputDir        string
+	pkgName          string
+	pkgVer           string
+	pkgOS            string
+	pkgArch          string
+	maintainer       string
+	description      string
+	dependencies     []string
+	rawHooks         []string
+	hookLint         string
+	hookModules      []string
+	hookBuiltins     []string
+	tarBlockSize     int
+	compressionLevel int
+	signingKey       string
 }
 
 // NewArtifactCmd creates a new artifact command.
@@ -41,6 +47,7 @@ func NewArtifactCmd() *cobra.Command {
 	// Add subcommands
 	pkgCmd.AddCommand(newArtifactCreateCommand())
 	pkgCmd.AddCommand(newArtifactVerifyCommand())
+	pkgCmd.AddCommand(newArtifactEssentialCommand())
 
 	return pkgCmd
 }
@@ -59,11 +66,45 @@ The source directory should contain a 'meta/artifact.json' file and a 'files/' d
 	return cmd
 }
 
+// newArtifactEssentialCommand creates the 'artifact essential' command.
+func newArtifactEssentialCommand() *cobra.Command {
+	var unset bool
+
+	cmd := &cobra.Command{
+		Use:   "essential ARTIFACT...",
+		Short: "Mark installed artifacts as essential",
+		Long: `Mark installed artifacts as essential to protect them from removal.
+
+Essential artifacts are refused by uninstall and skipped by cleanup
+unless --allow-essential is passed. Use --unset to remove the mark.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			manager := loadArtifactManager(cfg)
+			for _, name := range args {
+				if err := manager.SetArtifactEssential(name, !unset); err != nil {
+					return fmt.Errorf("failed to update %s: %w", name, err)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&unset, "unset", false, "Remove the essential mark instead of setting it")
+
+	return cmd
+}
+
 // newArtifactVerifyCommand creates the 'artifact verify' command.
 func newArtifactVerifyCommand() *cobra.Command {
 	// Command line flags
 	var (
-		filePath string
+		filePath      string
+		publicKeyPath string
 	)
 
 	cmd := &cobra.Command{
@@ -72,7 +113,8 @@ func newArtifactVerifyCommand() *cobra.Command {
 		Long: `Verify the integrity of a gotya artifact file.
 
 This command checks the internal consistency of an artifact file, 
-including file hashes and metadata structure.`,
+including file hashes and metadata structure. With --public-key, the
+artifact must also carry a valid signature of that key.`,
 		RunE: func(_ *cobra.Command, args []string) error {
 			// If file path is provided as an argument, use it (takes precedence over flag)
 			if len(args) > 0 {
@@ -97,7 +139,15 @@ including file hashes and metadata structure.`,
 			log.Printf("Verifying artifact: %s\n", absPath)
 
 			verifier := artifact.NewVerifier()
-			if err := verifier.VerifyArtifact(context.Background(), nil, absPath); err != nil {
+			if publicKeyPath != "" {
+				publicKey, err := artifact.LoadPublicKey(publicKeyPath)
+				if err != nil {
+					return err
+				}
+				if err := verifier.VerifySignature(context.Background(), absPath, publicKey); err != nil {
+					return fmt.Errorf("verification failed: %w", err)
+				}
+			} else if err := verifier.VerifyArtifact(context.Background(), nil, absPath); err != nil {
 				return fmt.Errorf("verification failed: %w", err)
 			}
 
@@ -108,6 +158,7 @@ including file hashes and metadata structure.`,
 
 	// Add flags
 	cmd.Flags().StringVarP(&filePath, "file", "f", "", "Path to the artifact file to verify")
+	cmd.Flags().StringVar(&publicKeyPath, "public-key", "", "Require a valid signature of the ed25519 public key in this PEM file")
 
 	// Mark the file flag as required if not provided as an argument
 	_ = cmd.MarkFlagFilename("file", "gotya")
@@ -128,8 +179,14 @@ func addCreateFlags(cmd *cobra.Command, o *createOptions) {
 	cmd.Flags().StringVar(&o.pkgArch, "arch", runtime.GOARCH, "Target architecture")
 	cmd.Flags().StringVar(&o.maintainer, "maintainer", "", "Artifact maintainer (name <email>)")
 	cmd.Flags().StringVar(&o.description, "description", "", "Artifact description")
-	cmd.Flags().StringSliceVar(&o.dependencies, "depends", nil, "Artifact dependencies (comma-separated)")
-	cmd.Flags().StringSliceVar(&o.rawHooks, "hook", nil, "Artifact hook in format 'name=path' (can be repeated)")
+	cmd.Flags().StringSliceVar(&o.dependencies, "depends", nil, "Artifact dependencies as name, name:constraint or \"name >= version\" (comma-separated)")
+	cmd.Flags().StringSliceVar(&o.rawHooks, "hook", nil, "Artifact hook in format 'name=path' (can be repeated, also for the same name to run several scripts in order)")
+	cmd.Flags().StringVar(&o.hookLint, "hook-lint", string(artifact.HookLintWarn), "How to handle hook scripts referencing paths outside the artifact (warn, error, off)")
+	cmd.Flags().StringSliceVar(&o.hookModules, "hook-allow-modules", nil, "Only allow hook scripts to import these Tengo modules (comma-separated, context and dirs are always allowed)")
+	cmd.Flags().StringSliceVar(&o.hookBuiltins, "hook-allow-builtins", nil, "Only allow hook scripts to use these Tengo builtin functions (comma-separated)")
+	cmd.Flags().IntVar(&o.tarBlockSize, "tar-block-size", 0, "Pad the tar stream to a multiple of this many bytes for strict tar readers (multiple of 512, 0 disables)")
+	cmd.Flags().IntVar(&o.compressionLevel, "compression-level", 0, "Gzip compression level from 1 (fastest) to 9 (smallest), 0 uses the default level")
+	cmd.Flags().StringVar(&o.signingKey, "signing-key", "", "Sign the artifact with the ed25519 private key in this PEM file")
 
 	// Mark required flags
 	must(cmd.MarkFlagRequired("source"))
@@ -149,6 +206,13 @@ func runCreateArtifact(o *createOptions) error {
 		return fmt.Errorf("failed to parse hooks: %w", err)
 	}
 
+	hookLintMode := artifact.HookLintMode(o.hookLint)
+	switch hookLintMode {
+	case artifact.HookLintWarn, artifact.HookLintError, artifact.HookLintOff:
+	default:
+		return errutils.Wrapf(errutils.ErrValidation, "invalid hook lint mode: %s (expected warn, error or off)", o.hookLint)
+	}
+
 	packer := artifact.NewPacker(
 		o.pkgName,
 		o.pkgVer,
@@ -161,6 +225,21 @@ func runCreateArtifact(o *createOptions) error {
 		o.sourceDir,
 		o.outputDir,
 	)
+	packer.SetHookLintMode(hookLintMode)
+	if o.hookModules != nil || o.hookBuiltins != nil {
+		if err := packer.SetHookAllowlist(artifact.HookAllowlist{Modules: o.hookModules, Builtins: o.hookBuiltins}); err != nil {
+			return err
+		}
+	}
+	if err := packer.SetTarBlockSize(o.tarBlockSize); err != nil {
+		return err
+	}
+	if err := packer.SetCompressionLevel(o.compressionLevel); err != nil {
+		return err
+	}
+	if err := packer.SetSigningKeyFile(o.signingKey); err != nil {
+		return err
+	}
 	outputFile, err := packer.Pack()
 	if err != nil {
 		return fmt.Errorf("failed to create artifact: %w", err)
@@ -169,9 +248,10 @@ func runCreateArtifact(o *createOptions) error {
 	return nil
 }
 
-// parseHooks parses raw hook strings in "name=path" format into a map
-func parseHooks(rawHooks []string) (map[string]string, error) {
-	hooks := make(map[string]string)
+// parseHooks parses raw hook strings in "name=path" format into a map.
+// Repeating a hook name adds further scripts that run in the given order.
+func parseHooks(rawHooks []string) (artifact.Hooks, error) {
+	hooks := make(artifact.Hooks)
 	for _, rawHook := range rawHooks {
 		parts := strings.SplitN(rawHook, "=", 2)
 		if len(parts) != 2 {
@@ -185,7 +265,7 @@ func parseHooks(rawHooks []string) (map[string]string, error) {
 		if hookPath == "" {
 			return nil, errutils.Wrapf(errutils.ErrValidation, "hook path cannot be empty in: %s", rawHook)
 		}
-		hooks[hookName] = hookPath
+		hooks[hookName] = append(hooks[hookName], hookPath)
 	}
 	return hooks, nil
 }
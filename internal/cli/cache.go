@@ -1,12 +1,17 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/glorpus-work/gotya/internal/logger"
+	"github.com/glorpus-work/gotya/pkg/artifact"
 	"github.com/glorpus-work/gotya/pkg/cache"
+	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/spf13/cobra"
 )
 
@@ -22,6 +27,7 @@ func NewCacheCmd() *cobra.Command {
 		newCacheCleanCmd(),
 		newCacheInfoCmd(),
 		newCacheDirCmd(),
+		newCacheVerifyCmd(),
 	)
 
 	return cmd
@@ -72,6 +78,23 @@ func newCacheDirCmd() *cobra.Command {
 	return cmd
 }
 
+func newCacheVerifyCmd() *cobra.Command {
+	var concurrency int
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify cached artifacts",
+		Long:  "Verify the integrity of all downloaded artifacts in the cache",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runCacheVerify(concurrency)
+		},
+	}
+
+	cmd.Flags().IntVar(&concurrency, "concurrency", 0, "Number of parallel verifications (0=auto)")
+
+	return cmd
+}
+
 func runCacheClean(all, indexes, packages bool) error {
 	config, err := loadConfig()
 	if err != nil {
@@ -134,6 +157,40 @@ func runCacheDir(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
+func runCacheVerify(concurrency int) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	results, err := artifact.NewVerifier().VerifyCache(context.Background(), config.GetArtifactCacheDir(), artifact.VerifyCacheOptions{
+		Concurrency: concurrency,
+		OnProgress: func(done, total int) {
+			fmt.Printf("\rverified %d/%d", done, total)
+		},
+	})
+	if len(results) > 0 {
+		fmt.Println()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify cache: %w", err)
+	}
+
+	paths := slices.Sorted(maps.Keys(results))
+	failed := 0
+	for _, path := range paths {
+		if results[path] != nil {
+			failed++
+			logger.Errorf("%s: %v", filepath.Base(path), results[path])
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cached artifacts failed verification: %w", failed, len(results), errutils.ErrArtifactInvalid)
+	}
+	logger.Infof("All %d cached artifacts verified", len(results))
+	return nil
+}
+
 // Helper function to get cache directory.
 func getCacheDir(config interface{}) string {
 	// Try to get from config first
@@ -10,7 +10,10 @@ import (
 
 // NewCleanupCmd creates the cleanup command.
 func NewCleanupCmd() *cobra.Command {
-	var dryRun bool
+	var (
+		dryRun         bool
+		allowEssential bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "cleanup",
@@ -20,22 +23,24 @@ func NewCleanupCmd() *cobra.Command {
 This command removes artifacts that were installed as dependencies but are no longer needed.
 Use --dry-run to see what would be cleaned up without actually removing anything.`,
 		RunE: func(_ *cobra.Command, _ []string) error {
-			return runCleanup(dryRun)
+			return runCleanup(dryRun, allowEssential)
 		},
 	}
 
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be cleaned up without actually removing anything")
+	cmd.Flags().BoolVar(&allowEssential, "allow-essential", false, "Also clean up orphaned artifacts marked as essential")
 
 	return cmd
 }
 
-func runCleanup(dryRun bool) error {
+func runCleanup(dryRun, allowEssential bool) error {
 	cfg, err := loadConfig()
 	if err != nil {
 		return err
 	}
 
 	artifactManager := loadArtifactManager(cfg)
+	artifactManager.SetAllowEssentialRemoval(allowEssential)
 
 	ctx := context.Background()
 
@@ -67,6 +72,12 @@ func runCleanup(dryRun bool) error {
 	// Create orchestrator with hooks
 	orch := orchestrator.New(nil, nil, nil, artifactManager, hooks)
 
+	release, err := acquireOperationLock(ctx, artifactManager)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Execute cleanup
 	cleaned, err := orch.Cleanup(ctx)
 	if err != nil {
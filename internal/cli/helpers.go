@@ -1,16 +1,19 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/glorpus-work/gotya/internal/logger"
 	"github.com/glorpus-work/gotya/pkg/artifact"
 	"github.com/glorpus-work/gotya/pkg/config"
 	"github.com/glorpus-work/gotya/pkg/download"
-	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/glorpus-work/gotya/pkg/index"
 	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/spf13/cobra"
 )
 
 // ManagerFactory encapsulates the creation of various managers from configuration.
@@ -37,6 +40,7 @@ func (f *ManagerFactory) CreateIndexManager() index.Manager {
 		repositories = append(repositories, &index.Repository{
 			Name:     repo.Name,
 			URL:      repo.GetURL(),
+			Mirrors:  repo.GetMirrors(),
 			Priority: repo.Priority,
 			Enabled:  repo.Enabled,
 		})
@@ -44,9 +48,28 @@ func (f *ManagerFactory) CreateIndexManager() index.Manager {
 	return index.NewManager(repositories, f.config.GetIndexDir())
 }
 
+// setRepositoryKeys sets the trusted keys configured for repositories, so that their indexes are verified on sync.
+func setRepositoryKeys(cfg *config.Config, repos []*index.Repository) error {
+	for _, repoCfg := range cfg.Repositories {
+		if repoCfg.TrustedKey == "" {
+			continue
+		}
+		key, err := artifact.LoadPublicKey(repoCfg.TrustedKey)
+		if err != nil {
+			return fmt.Errorf("failed to load trusted key of repository %s: %w", repoCfg.Name, err)
+		}
+		for _, repo := range repos {
+			if repo.Name == repoCfg.Name {
+				repo.PublicKey = key
+			}
+		}
+	}
+	return nil
+}
+
 // CreateArtifactManager creates an artifact manager from the configuration.
 func (f *ManagerFactory) CreateArtifactManager() artifact.Manager {
-	return artifact.NewManager(
+	am := artifact.NewManager(
 		f.config.Settings.Platform.OS,
 		f.config.Settings.Platform.Arch,
 		f.config.GetArtifactCacheDir(),
@@ -54,13 +77,41 @@ func (f *ManagerFactory) CreateArtifactManager() artifact.Manager {
 		f.config.GetMetaDir(),
 		f.config.GetDatabasePath(),
 	)
+	am.SetHookOutputLimit(f.config.Settings.HookOutputLimit)
+	// Host requirements are only checked when installing for the running operating system
+	if f.config.Settings.Platform.OS == runtime.GOOS {
+		am.SetHostCapabilities(artifact.SystemHostCapabilities())
+	}
+	return am
+}
+
+// acquireOperationLock takes the lock that keeps mutating commands from running concurrently
+// against the same install tree.
+func acquireOperationLock(ctx context.Context, manager artifact.Manager) (func(), error) {
+	release, err := manager.AcquireOperationLock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire operation lock: %w", err)
+	}
+	return release, nil
 }
 
 // CreateDownloadManager creates a download manager from the configuration.
-func (f *ManagerFactory) CreateDownloadManager() download.Manager {
-	dm := download.NewManager(f.config.Settings.HTTPTimeout, "")
+// It fails if the proxy, client certificate or CA bundle settings cannot be loaded.
+func (f *ManagerFactory) CreateDownloadManager() (download.Manager, error) {
+	settings := f.config.Settings
+	dm := download.NewManager(settings.HTTPTimeout, settings.UserAgent)
 	dm.SetAuthenticators(f.config.ToAuthMap())
-	return dm
+	dm.SetRetryPolicy(download.RetryPolicy{MaxAttempts: settings.DownloadAttempts, BaseDelay: settings.DownloadRetryDelay})
+	if err := dm.SetTransport(download.TransportConfig{
+		Proxy:      settings.Proxy,
+		NoProxy:    settings.NoProxy,
+		ClientCert: settings.ClientCert,
+		ClientKey:  settings.ClientKey,
+		CABundle:   settings.CABundle,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to configure HTTP transport: %w", err)
+	}
+	return dm, nil
 }
 
 // This is a bridge function that the CLI commands can use.
@@ -114,44 +165,53 @@ func loadArtifactManager(cfg *config.Config) artifact.Manager {
 }
 
 // loadDownloadManager creates a download manager from the configuration.
-func loadDownloadManager(cfg *config.Config) download.Manager {
+func loadDownloadManager(cfg *config.Config) (download.Manager, error) {
 	factory := NewManagerFactory(cfg)
 	return factory.CreateDownloadManager()
 }
 
-// ParseDependencies parses a list of dependency strings in the format "package_name[:version_constraint][,package_name[:version_constraint],...]"
+// ParseDependencies parses a list of dependency strings in the format "package_name[:version_constraint]"
+// or "package_name version_constraint", e.g. "libfoo:>= 1.2.0" or "libfoo >= 1.2.0".
 // If no version constraint is provided, it defaults to ">= 0.0.0"
 func ParseDependencies(deps []string) ([]model.Dependency, error) {
 	var dependencies []model.Dependency
 
 	for _, depStr := range deps {
-		depStr = strings.TrimSpace(depStr)
-		if depStr == "" {
+		if strings.TrimSpace(depStr) == "" {
 			continue
 		}
 
-		parts := strings.SplitN(depStr, ":", 2)
-		name := strings.TrimSpace(parts[0])
-		if name == "" {
-			return nil, fmt.Errorf("invalid dependency format: empty package name in '%s': %w", depStr, errutils.ErrValidation)
+		dependency, err := model.ParseDependency(depStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dependency format: %w", err)
 		}
-
-		var versionConstraint string
-		if len(parts) == 2 {
-			versionConstraint = strings.TrimSpace(parts[1])
-			if versionConstraint == "" {
-				return nil, fmt.Errorf("invalid dependency format: empty version constraint for package '%s': %w", name, errutils.ErrValidation)
-			}
-		} else {
+		if dependency.VersionConstraint == "" {
 			// Default version constraint if none provided
-			versionConstraint = ">= 0.0.0"
+			dependency.VersionConstraint = ">= 0.0.0"
 		}
-
-		dependencies = append(dependencies, model.Dependency{
-			Name:              name,
-			VersionConstraint: versionConstraint,
-		})
+		dependencies = append(dependencies, dependency)
 	}
 
 	return dependencies, nil
 }
+
+// completeInstalledArtifacts completes command arguments with the names of installed artifacts,
+// skipping names that were already given.
+func completeInstalledArtifacts(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names, err := loadArtifactManager(cfg).InstalledNames()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	completions := make([]string, 0, len(names))
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) && !slices.Contains(args, name) {
+			completions = append(completions, name)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/glorpus-work/gotya/pkg/artifact"
 	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/glorpus-work/gotya/pkg/model"
 	"github.com/glorpus-work/gotya/pkg/orchestrator"
@@ -13,9 +14,14 @@ import (
 // NewInstallCmd creates the install command.
 func NewInstallCmd() *cobra.Command {
 	var (
-		dryRun      bool
-		concurrency int
-		cacheDir    string
+		dryRun             bool
+		concurrency        int
+		extractConcurrency int
+		cacheDir           string
+		trustCache         bool
+		allowDowngrade     bool
+		keepOnHookFailure  bool
+		maxArtifacts       int
 	)
 
 	cmd := &cobra.Command{
@@ -25,25 +31,37 @@ func NewInstallCmd() *cobra.Command {
 Dependencies will be automatically resolved and installed.`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
-			return runInstall(args, dryRun, concurrency, cacheDir)
+			return runInstall(args, dryRun, concurrency, extractConcurrency, cacheDir, trustCache, allowDowngrade, keepOnHookFailure, maxArtifacts)
 		},
 	}
 
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Resolve and print actions without executing")
 	cmd.Flags().IntVar(&concurrency, "concurrency", 0, "Number of parallel downloads (0=auto)")
+	cmd.Flags().IntVar(&extractConcurrency, "extract-concurrency", 1, "Number of artifacts extracted and installed in parallel")
 	cmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Download cache directory (defaults to config)")
+	cmd.Flags().BoolVar(&trustCache, "trust-cache", false, "Use already cached artifacts without re-downloading or re-verifying them")
+	cmd.Flags().BoolVar(&allowDowngrade, "allow-downgrade", false, "Allow replacing installed artifacts with lower versions")
+	cmd.Flags().BoolVar(&keepOnHookFailure, "keep-on-hook-failure", false, "Keep artifacts installed when their post-install hook fails instead of rolling them back")
+	cmd.Flags().IntVar(&maxArtifacts, "max-artifacts", 0, "Fail if the resolved plan contains more artifacts (0=unlimited)")
 
 	return cmd
 }
 
-func runInstall(packages []string, dryRun bool, concurrency int, cacheDir string) error {
+func runInstall(packages []string, dryRun bool, concurrency, extractConcurrency int, cacheDir string, trustCache, allowDowngrade, keepOnHookFailure bool, maxArtifacts int) error {
 	cfg, err := loadConfig()
 	if err != nil {
 		return err
 	}
 	indexManager := loadIndexManager(cfg)
 	artifactManager := loadArtifactManager(cfg)
-	dlManager := loadDownloadManager(cfg)
+	artifactManager.SetAllowDowngrade(allowDowngrade)
+	if keepOnHookFailure {
+		artifactManager.SetPostInstallHookFailurePolicy(artifact.HookFailurePolicyWarn)
+	}
+	dlManager, err := loadDownloadManager(cfg)
+	if err != nil {
+		return err
+	}
 
 	// default cacheDir from config if not provided
 	if cacheDir == "" {
@@ -75,7 +93,16 @@ func runInstall(packages []string, dryRun bool, concurrency int, cacheDir string
 	// Create orchestrator with hooks
 	orch := orchestrator.New(planner, artifactManager, dlManager, artifactManager, hooks)
 
-	opts := orchestrator.InstallOptions{CacheDir: cacheDir, Concurrency: concurrency, DryRun: dryRun}
+	opts := orchestrator.InstallOptions{
+		CacheDir:           cacheDir,
+		Concurrency:        concurrency,
+		ExtractConcurrency: extractConcurrency,
+		DryRun:             dryRun,
+		TrustCache:         trustCache,
+		Headers:            cfg.GetHTTPHeaders(),
+		AllowInsecure:      cfg.Settings.AllowInsecure,
+		MaxArtifacts:       maxArtifacts,
+	}
 	ctx := context.Background()
 
 	// Build all resolve requests
@@ -89,6 +116,14 @@ func runInstall(packages []string, dryRun bool, concurrency int, cacheDir string
 		})
 	}
 
+	if !dryRun {
+		release, err := acquireOperationLock(ctx, artifactManager)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
 	if err := orch.Install(ctx, requests, opts); err != nil {
 		return fmt.Errorf("failed to install packages: %w", err)
 	}
@@ -3,6 +3,7 @@ package cli
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/glorpus-work/gotya/internal/logger"
 	installer "github.com/glorpus-work/gotya/pkg/orchestrator"
@@ -11,34 +12,65 @@ import (
 
 // NewSyncCmd creates the sync command.
 func NewSyncCmd() *cobra.Command {
+	var (
+		minInterval time.Duration
+		force       bool
+	)
+
 	cmd := &cobra.Command{
 		Use:   "sync",
 		Short: "Synchronize artifact index indexes",
 		Long: `Synchronize artifact index indexes by downloading the latest
 artifact lists from configured repositories.`,
-		RunE: runSync,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runSync(minInterval, force)
+		},
 	}
 
+	cmd.Flags().DurationVar(&minInterval, "min-interval", 0, "Skip repositories synced less than this long ago (e.g. 10m)")
+	cmd.Flags().BoolVar(&force, "force", false, "Sync all repositories regardless of --min-interval")
+
 	return cmd
 }
 
-func runSync(_ *cobra.Command, _ []string) error {
+func runSync(minInterval time.Duration, force bool) error {
 	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
 	// Build components
-	dl := loadDownloadManager(cfg)
+	dl, err := loadDownloadManager(cfg)
+	if err != nil {
+		return err
+	}
 	idx := loadIndexManager(cfg)
-	orch := &installer.Orchestrator{DL: dl}
+	orch := &installer.Orchestrator{DL: dl, Hooks: installer.Hooks{OnEvent: func(e installer.Event) {
+		if e.Phase == "skipped" {
+			logger.Infof("Skipping %s: %s", e.ID, e.Msg)
+		}
+	}}}
 
 	logger.Debug("Synchronizing index indexes...")
 
 	repos := idx.ListRepositories()
-	if err := orch.SyncAll(context.Background(), repos, cfg.GetIndexDir(), installer.Options{Concurrency: cfg.Settings.MaxConcurrent}); err != nil {
+	if err := setRepositoryKeys(cfg, repos); err != nil {
+		return err
+	}
+	if err := orch.SyncAll(context.Background(), repos, cfg.GetIndexDir(), installer.Options{
+		Concurrency:     cfg.Settings.MaxConcurrent,
+		MinSyncInterval: minInterval,
+		Force:           force,
+		Headers:         cfg.GetHTTPHeaders(),
+		AllowInsecure:   cfg.Settings.AllowInsecure,
+	}); err != nil {
 		return fmt.Errorf("failed to sync repositories: %w", err)
 	}
+	for _, repo := range repos {
+		if repo.ServedBy != nil && repo.ServedBy.String() != repo.URL.String() {
+			logger.Infof("Index %s was served by mirror %s", repo.Name, repo.ServedBy)
+		}
+	}
 
 	logger.Success("Repository indexes synchronized successfully")
 	return nil
@@ -10,7 +10,9 @@ import (
 // NewUninstallCmd creates the uninstall command.
 func NewUninstallCmd() *cobra.Command {
 	var (
-		purge bool
+		purge          bool
+		allowEssential bool
+		strict         bool
 	)
 
 	cmd := &cobra.Command{
@@ -18,7 +20,8 @@ func NewUninstallCmd() *cobra.Command {
 		Short: "Uninstall packages",
 		Long: `Uninstall one or more installed packages.
 By default, pre-remove and post-remove hooks will be executed.`,
-		Args: cobra.MinimumNArgs(1),
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeInstalledArtifacts,
 		RunE: func(_ *cobra.Command, args []string) error {
 			cfg, err := loadConfig()
 			if err != nil {
@@ -26,10 +29,19 @@ By default, pre-remove and post-remove hooks will be executed.`,
 			}
 
 			manager := loadArtifactManager(cfg)
+			manager.SetAllowEssentialRemoval(allowEssential)
+			manager.SetStrictUninstall(strict)
+
+			ctx := context.Background()
+			release, err := acquireOperationLock(ctx, manager)
+			if err != nil {
+				return err
+			}
+			defer release()
 
 			// Process each artifact
 			for _, pkgName := range args {
-				if err := manager.UninstallArtifact(context.Background(), pkgName, purge); err != nil {
+				if err := manager.UninstallArtifact(ctx, pkgName, purge); err != nil {
 					return fmt.Errorf("failed to uninstall %s: %w", pkgName, err)
 				}
 			}
@@ -40,6 +52,8 @@ By default, pre-remove and post-remove hooks will be executed.`,
 
 	// Add flags
 	cmd.Flags().BoolVar(&purge, "purge", false, "Remove not only tracked files but all files in the installed directories")
+	cmd.Flags().BoolVar(&allowEssential, "allow-essential", false, "Allow uninstalling artifacts marked as essential")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail if any files remain after uninstalling")
 
 	return cmd
 }
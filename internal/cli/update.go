@@ -12,10 +12,12 @@ import (
 // NewUpdateCmd creates the update command.
 func NewUpdateCmd() *cobra.Command {
 	var (
-		all         bool
-		dryRun      bool
-		concurrency int
-		cacheDir    string
+		all            bool
+		dryRun         bool
+		concurrency    int
+		cacheDir       string
+		allowDowngrade bool
+		force          bool
 	)
 
 	cmd := &cobra.Command{
@@ -26,7 +28,7 @@ func NewUpdateCmd() *cobra.Command {
 Use --all to update all installed packages. If no packages are specified and --all is not used,
 the command will return an error.`,
 		RunE: func(_ *cobra.Command, args []string) error {
-			return runUpdate(args, all, dryRun, concurrency, cacheDir)
+			return runUpdate(args, all, dryRun, concurrency, cacheDir, allowDowngrade, force)
 		},
 	}
 
@@ -34,11 +36,13 @@ the command will return an error.`,
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Resolve and print actions without executing")
 	cmd.Flags().IntVar(&concurrency, "concurrency", 0, "Number of parallel downloads (0=auto)")
 	cmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Download cache directory (defaults to config)")
+	cmd.Flags().BoolVar(&allowDowngrade, "allow-downgrade", false, "Allow replacing installed artifacts with lower versions")
+	cmd.Flags().BoolVar(&force, "force", false, "Reinstall artifacts that are already at the resolved version")
 
 	return cmd
 }
 
-func runUpdate(packages []string, all, dryRun bool, concurrency int, cacheDir string) error {
+func runUpdate(packages []string, all, dryRun bool, concurrency int, cacheDir string, allowDowngrade, force bool) error {
 	cfg, err := loadConfig()
 	if err != nil {
 		return err
@@ -46,7 +50,12 @@ func runUpdate(packages []string, all, dryRun bool, concurrency int, cacheDir st
 
 	indexManager := loadIndexManager(cfg)
 	artifactManager := loadArtifactManager(cfg)
-	dlManager := loadDownloadManager(cfg)
+	artifactManager.SetAllowDowngrade(allowDowngrade)
+	artifactManager.SetForceReinstall(force)
+	dlManager, err := loadDownloadManager(cfg)
+	if err != nil {
+		return err
+	}
 
 	// default cacheDir from config if not provided
 	if cacheDir == "" {
@@ -73,10 +82,12 @@ func runUpdate(packages []string, all, dryRun bool, concurrency int, cacheDir st
 	orch := orchestrator.New(planner, nil, dlManager, artifactManager, hooks)
 
 	opts := orchestrator.UpdateOptions{
-		DryRun:      dryRun,
-		Packages:    packages,
-		Concurrency: concurrency,
-		CacheDir:    cacheDir,
+		DryRun:        dryRun,
+		Packages:      packages,
+		Concurrency:   concurrency,
+		CacheDir:      cacheDir,
+		Headers:       cfg.GetHTTPHeaders(),
+		AllowInsecure: cfg.Settings.AllowInsecure,
 	}
 
 	ctx := context.Background()
@@ -86,6 +97,14 @@ func runUpdate(packages []string, all, dryRun bool, concurrency int, cacheDir st
 		return fmt.Errorf("no packages specified and --all flag not used: %w", errutils.ErrNoArtifactsSpecified)
 	}
 
+	if !dryRun {
+		release, err := acquireOperationLock(ctx, artifactManager)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
 	// Execute update
 	if err := orch.Update(ctx, opts); err != nil {
 		return fmt.Errorf("failed to update packages: %w", err)
@@ -2,29 +2,92 @@
 package archive
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/glorpus-work/gotya/pkg/fsutil"
 	"github.com/mholt/archives"
 )
 
+// preservedModeBits are the mode bits of regular files that are restored on extraction.
+const preservedModeBits = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// DefaultCopyBufferSize is the buffer size used when copying file contents out of an archive.
+// It is larger than io.Copy's 32 KiB so large files need fewer read calls through the
+// decompressor; compare sizes with `go test -bench ExtractAll -benchmem ./pkg/archive`.
+const DefaultCopyBufferSize = 256 << 10
+
+// DefaultExtractConcurrency is the default number of workers writing extracted files.
+const DefaultExtractConcurrency = 4
+
+// pipelinedFileMaxSize is the largest file that is buffered in memory and handed to a write worker.
+// Larger files are streamed to disk by the reading goroutine to keep memory usage bounded.
+const pipelinedFileMaxSize = 1 << 20
+
 // Manager handles archive extraction and creation operations.
-type Manager struct{}
+type Manager struct {
+	copyBufferSize     int
+	extractConcurrency int
+	compression        Compression
+	maxTotalBytes      int64
+	maxFileBytes       int64
+	deterministic      bool
+	symlinkPolicy      SymlinkPolicy
+}
 
-// NewManager creates a new Manager instance.
+// NewManager creates a new Manager instance that creates gzip compressed archives.
 func NewManager() *Manager {
-	return &Manager{}
+	return &Manager{copyBufferSize: DefaultCopyBufferSize, extractConcurrency: DefaultExtractConcurrency}
+}
+
+// SetExtractConcurrency sets how many files ExtractAll writes in parallel.
+// Entries are always read sequentially; values <= 1 also write them sequentially.
+func (am *Manager) SetExtractConcurrency(n int) {
+	am.extractConcurrency = n
+}
+
+// SetCopyBufferSize sets the buffer size used to copy file contents during extraction.
+// Sizes <= 0 use DefaultCopyBufferSize.
+func (am *Manager) SetCopyBufferSize(size int) {
+	am.copyBufferSize = size
+}
+
+// newCopyBuffer allocates a buffer of the configured copy size.
+func (am *Manager) newCopyBuffer() []byte {
+	if am.copyBufferSize <= 0 {
+		return make([]byte, DefaultCopyBufferSize)
+	}
+	return make([]byte, am.copyBufferSize)
 }
 
-// ExtractAll extracts all files from an archive to the specified destination directory
+// copyBuffered copies src to dst through buf. Both sides are wrapped so io.CopyBuffer cannot
+// bypass buf via *os.File's ReaderFrom, which would fall back to a 32 KiB internal buffer.
+func copyBuffered(dst io.Writer, src io.Reader, buf []byte) (int64, error) {
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, buf)
+}
+
+// ExtractAll extracts all files from an archive to the specified destination directory.
+// The compression is detected from the archive's content. Archives with absolute entry names or
+// entries that would land outside destDir are rejected with errutils.ErrInvalidPath before anything is written.
+// Extraction stops with errutils.ErrExtractionTooLarge as soon as a limit set with SetMaxTotalBytes or
+// SetMaxFileBytes is exceeded; files written up to that point are left in destDir.
+// Cancelling ctx stops the extraction between entries and while copying file contents. The context's
+// error is returned wrapped and everything written by the extraction is removed again.
 func (am *Manager) ExtractAll(ctx context.Context, archivePath, destDir string) error {
+	return am.ExtractAllWithOptions(ctx, archivePath, destDir, Options{})
+}
+
+// ExtractAllWithOptions is like ExtractAll and additionally reports progress to opts.OnProgress.
+// The total is the size of all regular files as recorded in the archive headers, or -1 if it is not known.
+func (am *Manager) ExtractAllWithOptions(ctx context.Context, archivePath, destDir string, opts Options) error {
 	// Open the archive file
-	fsys, err := archives.FileSystem(ctx, archivePath, nil)
+	fsys, total, err := openArchiveFS(ctx, archivePath)
 	if err != nil {
 		return fmt.Errorf("failed to open archive file: %w", err)
 	}
@@ -34,25 +97,140 @@ func (am *Manager) ExtractAll(ctx context.Context, archivePath, destDir string)
 	}
 
 	// Ensure the destination directory exists
+	_, statErr := os.Lstat(destDir)
 	if err := os.MkdirAll(destDir, 0755); err != nil {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	// Walk through all files in the archive and extract them via helper
-	walkFn := func(path string, d fs.DirEntry, err error) error {
+	ex := am.newExtraction(ctx)
+	ex.reportProgress(opts.OnProgress, total)
+	if err := am.extractFS(ctx, fsys, destDir, ex); err != nil {
+		if ctx.Err() != nil && os.IsNotExist(statErr) {
+			_ = os.Remove(destDir)
+		}
+		return fmt.Errorf("failed to extract archive %s: %w", archivePath, err)
+	}
+	return nil
+}
+
+// fileWriteJob is a small regular file read from the archive and waiting to be written.
+type fileWriteJob struct {
+	path       string
+	targetPath string
+	info       fs.FileInfo
+	data       []byte
+}
+
+// extractFS walks fsys and extracts every entry to destDir. Entries are read in walk order by the
+// calling goroutine; small regular files are then written by a bounded pool of workers.
+// If ctx is cancelled, everything written so far is removed again. The walking goroutine is the only user of ex.
+func (am *Manager) extractFS(ctx context.Context, fsys fs.FS, destDir string, ex *extraction) error {
+	err := am.walkAndExtract(ctx, fsys, destDir, ex)
+	if err != nil && ctx.Err() != nil {
+		ex.removeCreated()
+	}
+	return err
+}
+
+// walkAndExtract extracts the entries of fsys to destDir, recording the created paths in ex.
+func (am *Manager) walkAndExtract(ctx context.Context, fsys fs.FS, destDir string, ex *extraction) error {
+	workers := am.extractConcurrency
+	if workers <= 1 {
+		return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return am.extractEntry(fsys, path, destDir, d, ex)
+		})
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		writeErr error
+	)
+	failed := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return writeErr
+	}
+
+	jobs := make(chan fileWriteJob, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if err := writeBufferedFile(job); err != nil {
+					mu.Lock()
+					if writeErr == nil {
+						writeErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	walkErr := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		return am.extractEntry(fsys, path, destDir, d)
-	}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := failed(); err != nil {
+			return err
+		}
+		if path == "." || !d.Type().IsRegular() {
+			return am.extractEntry(fsys, path, destDir, d, ex)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("failed to get file info for %s: %w", path, err)
+		}
+		target, err := targetPath(destDir, path)
+		if err != nil {
+			return err
+		}
+		ex.trackDir(filepath.Dir(target))
+		ex.track(target)
+		if info.Size() > pipelinedFileMaxSize {
+			return am.writeRegularFile(fsys, path, target, info, ex)
+		}
+
+		data, err := readEntry(fsys, path, info.Size(), ex)
+		if err != nil {
+			return err
+		}
+		jobs <- fileWriteJob{path: path, targetPath: target, info: info, data: data}
+		return nil
+	})
+
+	close(jobs)
+	wg.Wait()
 
-	return fs.WalkDir(fsys, ".", walkFn)
+	if walkErr != nil {
+		return walkErr
+	}
+	return writeErr
 }
 
-// ExtractFile extracts a specific file from an archive to the specified destination
+// ExtractFile extracts a specific file from an archive to the specified destination.
+// The compression is detected from the archive's content. Unsafe entry names in the archive or in
+// filePath are rejected with errutils.ErrInvalidPath. If ctx is cancelled while the file is copied,
+// the wrapped context error is returned and destPath is removed.
 func (am *Manager) ExtractFile(ctx context.Context, archivePath, filePath, destPath string) error {
+	if _, err := entryPath(filePath); err != nil {
+		return err
+	}
+
 	// Open the archive file
-	fsys, err := archives.FileSystem(ctx, archivePath, nil)
+	fsys, _, err := openArchiveFS(ctx, archivePath)
 	if err != nil {
 		return fmt.Errorf("failed to open archive file: %w", err)
 	}
@@ -81,15 +259,38 @@ func (am *Manager) ExtractFile(ctx context.Context, archivePath, filePath, destP
 	defer func() { _ = dstFile.Close() }()
 
 	// Copy the file content
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
+	if _, err := am.newExtraction(ctx).copy(dstFile, srcFile, filePath); err != nil {
+		if ctx.Err() != nil {
+			_ = dstFile.Close()
+			_ = os.Remove(destPath)
+		}
 		return fmt.Errorf("failed to copy file %s to %s: %w", filePath, destPath, err)
 	}
 
 	return nil
 }
 
-// Create creates an archive from the specified source directory
+// Create creates a tar archive from the specified source directory, compressed with the manager's
+// Compression. The archive is written to archivePath as given; see Compression.Extension for the
+// conventional file name suffix. See SetDeterministic for reproducible archives.
 func (am *Manager) Create(ctx context.Context, sourceDir, archivePath string) error {
+	return am.CreateWithOptions(ctx, sourceDir, archivePath, Options{})
+}
+
+// CreateWithOptions is like Create and additionally leaves out the paths matching opts.Exclude and
+// reports progress to opts.OnProgress. The total is the size of all regular files that are archived.
+func (am *Manager) CreateWithOptions(ctx context.Context, sourceDir, archivePath string, opts Options) error {
+	compressor, err := am.compression.compressor(opts.CompressionLevel)
+	if err != nil {
+		return err
+	}
+	if err := validateExcludePatterns(opts.Exclude); err != nil {
+		return err
+	}
+	if err := validateBlockSize(opts.BlockSize); err != nil {
+		return err
+	}
+
 	// Compute absolute native and forward-slash normalized roots
 	absolutePath, err := filepath.Abs(sourceDir)
 	if err != nil {
@@ -103,6 +304,7 @@ func (am *Manager) Create(ctx context.Context, sourceDir, archivePath string) er
 	if err != nil {
 		return fmt.Errorf("failed to read files from disk: %w", err)
 	}
+	archiveFiles = excludeFiles(archiveFiles, opts.Exclude)
 
 	// Create the output file
 	file, err := os.Create(archivePath)
@@ -115,13 +317,17 @@ func (am *Manager) Create(ctx context.Context, sourceDir, archivePath string) er
 		_ = file.Close()
 	}()
 
-	format := archives.CompressedArchive{
-		Compression: archives.Gz{},
-		Archival:    archives.Tar{},
+	archival := archives.Tar{}
+	if am.deterministic {
+		normalizeFiles(archiveFiles)
+		archival.NumericUIDGID = true
+	}
+	if opts.OnProgress != nil {
+		trackCreateProgress(archiveFiles, opts.OnProgress)
 	}
 
 	// Create the archive
-	err = format.Archive(ctx, file, archiveFiles)
+	err = writeCompressedTar(ctx, file, compressor, archival, archiveFiles, opts.BlockSize)
 	if err != nil {
 		return fmt.Errorf("failed to create archive: %w", err)
 	}
@@ -130,16 +336,20 @@ func (am *Manager) Create(ctx context.Context, sourceDir, archivePath string) er
 }
 
 // extractEntry processes a single archive entry and writes it to destDir.
-func (am *Manager) extractEntry(fsys fs.FS, path, destDir string, d fs.DirEntry) error {
+func (am *Manager) extractEntry(fsys fs.FS, path, destDir string, d fs.DirEntry, ex *extraction) error {
 	// Skip the root directory
 	if path == "." {
 		return nil
 	}
 
-	targetPath := filepath.Join(destDir, path)
+	target, err := targetPath(destDir, path)
+	if err != nil {
+		return err
+	}
 
 	if d.IsDir() {
-		return os.MkdirAll(targetPath, 0755)
+		ex.trackDir(target)
+		return os.MkdirAll(target, 0755)
 	}
 
 	// Handle regular files and symlinks
@@ -148,27 +358,27 @@ func (am *Manager) extractEntry(fsys fs.FS, path, destDir string, d fs.DirEntry)
 		return fmt.Errorf("failed to get file info for %s: %w", path, err)
 	}
 
+	ex.trackDir(filepath.Dir(target))
+	ex.track(target)
+
 	// Handle symlinks
 	if info.Mode()&os.ModeSymlink != 0 {
-		return am.writeSymlink(fsys, path, targetPath)
+		linkTarget, err := readLinkTarget(fsys, path, info)
+		if err != nil {
+			return err
+		}
+		return am.writeSymlink(destDir, path, target, linkTarget, ex)
 	}
 
 	// Handle regular files
-	return am.writeRegularFile(fsys, path, targetPath, info)
+	return am.writeRegularFile(fsys, path, target, info, ex)
 }
 
-// writeSymlink creates a symlink at targetPath with contents from the archive entry at path.
-func (am *Manager) writeSymlink(fsys fs.FS, path, targetPath string) error {
-	linkTarget, err := fsys.Open(path)
-	if err != nil {
-		return fmt.Errorf("failed to read symlink %s: %w", path, err)
-	}
-	defer func() { _ = linkTarget.Close() }()
-
-	// Read the symlink target
-	targetBytes, err := io.ReadAll(linkTarget)
-	if err != nil {
-		return fmt.Errorf("failed to read symlink target %s: %w", path, err)
+// writeSymlink creates a symlink at targetPath pointing to linkTarget as allowed by the symlink policy.
+// path is the name of the entry in the archive.
+func (am *Manager) writeSymlink(destDir, path, targetPath, linkTarget string, ex *extraction) error {
+	if skip, err := am.checkSymlink(destDir, path, targetPath, linkTarget, ex); skip || err != nil {
+		return err
 	}
 
 	// Ensure the target directory exists
@@ -179,11 +389,11 @@ func (am *Manager) writeSymlink(fsys fs.FS, path, targetPath string) error {
 	// Remove existing file/symlink if it exists
 	_ = os.Remove(targetPath)
 
-	return os.Symlink(string(targetBytes), targetPath)
+	return os.Symlink(linkTarget, targetPath)
 }
 
 // writeRegularFile writes a regular file from the archive entry to targetPath and preserves metadata.
-func (am *Manager) writeRegularFile(fsys fs.FS, path, targetPath string, info fs.FileInfo) error {
+func (am *Manager) writeRegularFile(fsys fs.FS, path, targetPath string, info fs.FileInfo, ex *extraction) error {
 	srcFile, err := fsys.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open source file %s: %w", path, err)
@@ -200,11 +410,54 @@ func (am *Manager) writeRegularFile(fsys fs.FS, path, targetPath string, info fs
 	}
 	defer func() { _ = dstFile.Close() }()
 
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
+	if _, err := ex.copy(dstFile, srcFile, path); err != nil {
 		return fmt.Errorf("failed to copy file %s: %w", path, err)
 	}
 
-	if err := os.Chmod(targetPath, info.Mode().Perm()); err != nil {
+	return applyFileMetadata(targetPath, info)
+}
+
+// readEntry reads the contents of the small regular file at path into memory.
+func readEntry(fsys fs.FS, path string, size int64, ex *extraction) ([]byte, error) {
+	srcFile, err := fsys.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source file %s: %w", path, err)
+	}
+	defer func() { _ = srcFile.Close() }()
+
+	var data bytes.Buffer
+	data.Grow(int(size))
+	if _, err := ex.copy(&data, srcFile, path); err != nil {
+		return nil, fmt.Errorf("failed to read source file %s: %w", path, err)
+	}
+	return data.Bytes(), nil
+}
+
+// writeBufferedFile writes a file whose contents were already read from the archive and preserves metadata.
+func writeBufferedFile(job fileWriteJob) error {
+	if err := os.MkdirAll(filepath.Dir(job.targetPath), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory for %s: %w", job.path, err)
+	}
+
+	dstFile, err := fsutil.CreateFilePerm(job.targetPath, job.info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create destination file %s: %w", job.targetPath, err)
+	}
+	_, err = dstFile.Write(job.data)
+	if closeErr := dstFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write file %s: %w", job.path, err)
+	}
+
+	return applyFileMetadata(job.targetPath, job.info)
+}
+
+// applyFileMetadata restores the archived mode bits and modification time of an extracted file.
+func applyFileMetadata(targetPath string, info fs.FileInfo) error {
+	// Keep setuid/setgid/sticky bits so callers can apply their own mode policy
+	if err := os.Chmod(targetPath, info.Mode()&preservedModeBits); err != nil {
 		return fmt.Errorf("failed to set permissions for %s: %w", targetPath, err)
 	}
 	if err := os.Chtimes(targetPath, info.ModTime(), info.ModTime()); err != nil {
@@ -1,14 +1,19 @@
 package archive
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 
+	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -109,10 +114,6 @@ func TestArchiveManager_ExtractAll_WithSymlinks(t *testing.T) {
 		t.Skip("Skipping symlink test on Windows")
 	}
 
-	// Note: This test verifies that symlinks in archives are handled.
-	// The mholt/archives library may handle symlinks differently than expected,
-	// so we're testing that the code path exists rather than exact behavior.
-
 	tempDir := t.TempDir()
 	sourceDir := filepath.Join(tempDir, "source")
 	require.NoError(t, os.MkdirAll(sourceDir, 0755))
@@ -130,22 +131,10 @@ func TestArchiveManager_ExtractAll_WithSymlinks(t *testing.T) {
 	ctx := context.Background()
 	require.NoError(t, am.Create(ctx, sourceDir, archivePath))
 
-	// Extract archive - symlinks may or may not be preserved depending on the library
+	// Symlinks are rejected by default
 	extractDir := filepath.Join(tempDir, "extracted")
 	err := am.ExtractAll(ctx, archivePath, extractDir)
-
-	// The extraction may fail or succeed depending on how the archive library handles symlinks
-	// We're primarily testing that the code path exists and doesn't panic
-	if err != nil {
-		// If extraction fails, that's okay - we've tested the error path
-		t.Logf("Symlink extraction failed (expected): %v", err)
-		return
-	}
-
-	// If extraction succeeded, verify the files exist
-	extractedRegular := filepath.Join(extractDir, "regular.txt")
-	_, err = os.Stat(extractedRegular)
-	require.NoError(t, err)
+	require.ErrorIs(t, err, errutils.ErrSymlinkRejected)
 }
 
 func TestArchiveManager_ExtractAll_InvalidArchive(t *testing.T) {
@@ -315,6 +304,41 @@ func TestArchiveManager_ExtractAll_EmptyArchive(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestArchiveManager_EmptyDirectories(t *testing.T) {
+	for _, deterministic := range []bool{false, true} {
+		t.Run(fmt.Sprintf("deterministic=%t", deterministic), func(t *testing.T) {
+			tempDir := t.TempDir()
+			sourceDir := filepath.Join(tempDir, "source")
+			require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "data", "logs"), 0755))
+			require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "data", "cache", "tmp"), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data", "tool"), []byte("tool"), 0644))
+
+			am := NewManager()
+			am.SetDeterministic(deterministic)
+			archivePath := filepath.Join(tempDir, "dirs.tar.gz")
+			ctx := context.Background()
+			require.NoError(t, am.Create(ctx, sourceDir, archivePath))
+
+			entries, err := am.ListContents(ctx, archivePath)
+			require.NoError(t, err)
+			dirs := make(map[string]bool)
+			for _, entry := range entries {
+				if entry.IsDir {
+					dirs[entry.Path] = true
+				}
+			}
+			assert.True(t, dirs["data/logs"], "empty directory has an archive entry")
+			assert.True(t, dirs["data/cache/tmp"], "nested empty directory has an archive entry")
+
+			extractDir := filepath.Join(tempDir, "extracted")
+			require.NoError(t, am.ExtractAll(ctx, archivePath, extractDir))
+			assert.DirExists(t, filepath.Join(extractDir, "data", "logs"))
+			assert.DirExists(t, filepath.Join(extractDir, "data", "cache", "tmp"))
+			assert.FileExists(t, filepath.Join(extractDir, "data", "tool"))
+		})
+	}
+}
+
 func TestArchiveManager_ExtractAll_NestedDirectories(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -477,6 +501,173 @@ func TestArchiveManager_ExtractFile_LargeFile(t *testing.T) {
 	assert.Len(t, extractedContent, len(largeContent))
 }
 
+// createLargeFileArchive packs a single pseudo-random file of the given size and returns the archive path and content.
+func createLargeFileArchive(tb testing.TB, size int) (string, []byte) {
+	tb.Helper()
+	tempDir := tb.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	require.NoError(tb, os.MkdirAll(sourceDir, 0755))
+
+	// Low-entropy but non-repeating content keeps the archive small while exercising the decompressor
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte((i * 31) ^ (i >> 11))
+	}
+	require.NoError(tb, os.WriteFile(filepath.Join(sourceDir, "large.bin"), content, 0644))
+
+	archivePath := filepath.Join(tempDir, "large.tar.gz")
+	require.NoError(tb, NewManager().Create(context.Background(), sourceDir, archivePath))
+	return archivePath, content
+}
+
+func TestArchiveManager_CopyBufferSize(t *testing.T) {
+	archivePath, content := createLargeFileArchive(t, 4<<20)
+
+	for _, size := range []int{0, 512, DefaultCopyBufferSize, 1 << 20} {
+		t.Run(fmt.Sprintf("buffer_%d", size), func(t *testing.T) {
+			am := NewManager()
+			am.SetCopyBufferSize(size)
+			ctx := context.Background()
+
+			extractDir := filepath.Join(t.TempDir(), "all")
+			require.NoError(t, am.ExtractAll(ctx, archivePath, extractDir))
+			extracted, err := os.ReadFile(filepath.Join(extractDir, "large.bin"))
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(content, extracted), "ExtractAll content mismatch")
+
+			singlePath := filepath.Join(t.TempDir(), "single.bin")
+			require.NoError(t, am.ExtractFile(ctx, archivePath, "large.bin", singlePath))
+			extracted, err = os.ReadFile(singlePath)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(content, extracted), "ExtractFile content mismatch")
+		})
+	}
+}
+
+// BenchmarkArchiveManager_ExtractAll compares extraction throughput of a large file for several copy buffer sizes.
+func BenchmarkArchiveManager_ExtractAll(b *testing.B) {
+	const size = 64 << 20
+	archivePath, _ := createLargeFileArchive(b, size)
+
+	for _, bufSize := range []int{32 << 10, DefaultCopyBufferSize, 1 << 20} {
+		b.Run(fmt.Sprintf("buffer_%dKiB", bufSize>>10), func(b *testing.B) {
+			am := NewManager()
+			am.SetCopyBufferSize(bufSize)
+			b.SetBytes(size)
+			for i := 0; i < b.N; i++ {
+				extractDir := filepath.Join(b.TempDir(), fmt.Sprint(i))
+				if err := am.ExtractAll(context.Background(), archivePath, extractDir); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestArchiveManager_ExtractAll_Concurrent(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+
+	want := map[string]struct {
+		content []byte
+		mode    os.FileMode
+	}{}
+	for i := 0; i < 200; i++ {
+		rel := filepath.Join(fmt.Sprintf("dir%d", i%7), fmt.Sprintf("file%03d.txt", i))
+		mode := os.FileMode(0644)
+		if i%3 == 0 {
+			mode = 0755
+		}
+		want[rel] = struct {
+			content []byte
+			mode    os.FileMode
+		}{[]byte(strings.Repeat(fmt.Sprintf("content %d\n", i), i+1)), mode}
+	}
+	large := make([]byte, pipelinedFileMaxSize+1)
+	for i := range large {
+		large[i] = byte(i % 251)
+	}
+	want["large.bin"] = struct {
+		content []byte
+		mode    os.FileMode
+	}{large, 0644}
+
+	for rel, f := range want {
+		path := filepath.Join(sourceDir, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, f.content, f.mode))
+		require.NoError(t, os.Chmod(path, f.mode))
+	}
+
+	archivePath := filepath.Join(tempDir, "many.tar.gz")
+	require.NoError(t, NewManager().Create(context.Background(), sourceDir, archivePath))
+
+	for _, workers := range []int{1, 8} {
+		t.Run(fmt.Sprintf("workers_%d", workers), func(t *testing.T) {
+			am := NewManager()
+			am.SetExtractConcurrency(workers)
+			extractDir := filepath.Join(t.TempDir(), "out")
+			require.NoError(t, am.ExtractAll(context.Background(), archivePath, extractDir))
+
+			for rel, f := range want {
+				path := filepath.Join(extractDir, rel)
+				content, err := os.ReadFile(path)
+				require.NoError(t, err, rel)
+				assert.True(t, bytes.Equal(f.content, content), "content mismatch for %s", rel)
+				if runtime.GOOS != "windows" {
+					info, err := os.Stat(path)
+					require.NoError(t, err)
+					assert.Equal(t, f.mode, info.Mode().Perm(), "mode mismatch for %s", rel)
+				}
+			}
+		})
+	}
+}
+
+// recordingFS records the order in which regular files are opened.
+type recordingFS struct {
+	fsys   fstest.MapFS
+	mu     sync.Mutex
+	opened []string
+}
+
+func (r *recordingFS) Open(name string) (fs.File, error) {
+	if f, ok := r.fsys[name]; ok && !f.Mode.IsDir() {
+		r.mu.Lock()
+		r.opened = append(r.opened, name)
+		r.mu.Unlock()
+	}
+	return r.fsys.Open(name)
+}
+
+func TestArchiveManager_ExtractFS_ReadsInWalkOrder(t *testing.T) {
+	mapFS := fstest.MapFS{}
+	var walkOrder []string
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("d%d/f%02d", i%5, i)
+		mapFS[name] = &fstest.MapFile{Data: []byte(name), Mode: 0644}
+	}
+	require.NoError(t, fs.WalkDir(mapFS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			walkOrder = append(walkOrder, path)
+		}
+		return err
+	}))
+
+	rec := &recordingFS{fsys: mapFS}
+	am := NewManager()
+	am.SetExtractConcurrency(8)
+	destDir := t.TempDir()
+	require.NoError(t, am.extractFS(context.Background(), rec, destDir, am.newExtraction(context.Background())))
+
+	assert.Equal(t, walkOrder, rec.opened, "entries must be read sequentially in walk order")
+	for _, name := range walkOrder {
+		content, err := os.ReadFile(filepath.Join(destDir, name))
+		require.NoError(t, err)
+		assert.Equal(t, name, string(content))
+	}
+}
+
 func TestNewManager(t *testing.T) {
 	// Test that NewManager creates a valid manager instance
 	am := NewManager()
@@ -516,11 +707,8 @@ func TestArchiveManager_ExtractAll_ContextCancellation(t *testing.T) {
 
 	extractDir := filepath.Join(tempDir, "extracted")
 	err := am.ExtractAll(cancelledCtx, archivePath, extractDir)
-	// The context cancellation may or may not be handled by the underlying library
-	// but we test that the method doesn't panic and returns an error
-	if err != nil {
-		t.Logf("Context cancellation handled: %v", err)
-	}
+	require.ErrorIs(t, err, context.Canceled)
+	assert.NoDirExists(t, extractDir)
 }
 
 func TestArchiveManager_ExtractFile_ContextCancellation(t *testing.T) {
@@ -544,10 +732,8 @@ func TestArchiveManager_ExtractFile_ContextCancellation(t *testing.T) {
 
 	extractPath := filepath.Join(tempDir, "extracted.txt")
 	err := am.ExtractFile(cancelledCtx, archivePath, "test.txt", extractPath)
-	// The context cancellation may or may not be handled by the underlying library
-	if err != nil {
-		t.Logf("Context cancellation handled: %v", err)
-	}
+	require.ErrorIs(t, err, context.Canceled)
+	assert.NoFileExists(t, extractPath)
 }
 
 func TestArchiveManager_Create_ContextCancellation(t *testing.T) {
@@ -934,21 +1120,18 @@ func TestArchiveManager_Create_ArchiveWithSymlinks(t *testing.T) {
 	require.NoError(t, os.Symlink("regular.txt", symlinkPath))
 
 	am := NewManager()
+	am.SetSymlinkPolicy(SymlinkRecreate)
 	archivePath := filepath.Join(tempDir, "test.tar.gz")
 	ctx := context.Background()
 	require.NoError(t, am.Create(ctx, sourceDir, archivePath))
 
-	// Extract and verify symlink handling
+	// Extract and verify the symlink was recreated
 	extractDir := filepath.Join(tempDir, "extracted")
-	err := am.ExtractAll(ctx, archivePath, extractDir)
-	// Symlinks may fail to extract depending on the archive library behavior
-	if err != nil {
-		t.Logf("Symlink extraction handled: %v", err)
-	} else {
-		// If extraction succeeds, verify files exist
-		_, err := os.Stat(filepath.Join(extractDir, "regular.txt"))
-		require.NoError(t, err)
-	}
+	require.NoError(t, am.ExtractAll(ctx, archivePath, extractDir))
+	assert.FileExists(t, filepath.Join(extractDir, "regular.txt"))
+	target, err := os.Readlink(filepath.Join(extractDir, "link.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "regular.txt", target)
 }
 
 func TestArchiveManager_Create_FileWithLongPath(t *testing.T) {
@@ -1197,21 +1380,18 @@ func TestArchiveManager_ExtractAll_ArchiveWithMultipleSymlinks(t *testing.T) {
 	require.NoError(t, os.Symlink("link1.txt", linkToLink))
 
 	am := NewManager()
+	am.SetSymlinkPolicy(SymlinkRecreate)
 	archivePath := filepath.Join(tempDir, "test.tar.gz")
 	ctx := context.Background()
 	require.NoError(t, am.Create(ctx, sourceDir, archivePath))
 
-	// Extract and handle symlink extraction (may fail)
+	// Extract and verify all links resolve
 	extractDir := filepath.Join(tempDir, "extracted")
-	err := am.ExtractAll(ctx, archivePath, extractDir)
-	if err != nil {
-		t.Logf("Multiple symlinks extraction handled: %v", err)
-	} else {
-		// If extraction succeeds, verify some files exist
-		_, err := os.Stat(filepath.Join(extractDir, "file1.txt"))
-		require.NoError(t, err)
-		_, err = os.Stat(filepath.Join(extractDir, "file2.txt"))
+	require.NoError(t, am.ExtractAll(ctx, archivePath, extractDir))
+	for link, want := range map[string]string{"link1.txt": "content 1", "link2.txt": "content 2", "link_to_link.txt": "content 1"} {
+		content, err := os.ReadFile(filepath.Join(extractDir, link))
 		require.NoError(t, err)
+		assert.Equal(t, want, string(content), link)
 	}
 }
 
@@ -1236,20 +1416,17 @@ func TestArchiveManager_Create_ArchiveWithNestedSymlinks(t *testing.T) {
 	require.NoError(t, os.Symlink("subdir/target.txt", link))
 
 	am := NewManager()
+	am.SetSymlinkPolicy(SymlinkRecreate)
 	archivePath := filepath.Join(tempDir, "test.tar.gz")
 	ctx := context.Background()
 	require.NoError(t, am.Create(ctx, sourceDir, archivePath))
 
-	// Extract and handle nested symlink extraction
+	// Extract and verify the link into the subdirectory resolves
 	extractDir := filepath.Join(tempDir, "extracted")
-	err := am.ExtractAll(ctx, archivePath, extractDir)
-	if err != nil {
-		t.Logf("Nested symlinks extraction handled: %v", err)
-	} else {
-		// If extraction succeeds, verify files exist
-		_, err := os.Stat(filepath.Join(extractDir, "subdir", "target.txt"))
-		require.NoError(t, err)
-	}
+	require.NoError(t, am.ExtractAll(ctx, archivePath, extractDir))
+	content, err := os.ReadFile(filepath.Join(extractDir, "link_to_subdir.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "target content", string(content))
 }
 
 func TestArchiveManager_ExtractFile_SymlinkInArchive(t *testing.T) {
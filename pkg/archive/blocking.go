@@ -0,0 +1,56 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/mholt/archives"
+)
+
+// TarBlockSize is the size of a tar block. Record sizes set with Options.BlockSize must be a multiple of it.
+const TarBlockSize = 512
+
+// validateBlockSize rejects record sizes that are not a multiple of TarBlockSize with errutils.ErrValidation.
+func validateBlockSize(size int) error {
+	if size < 0 || size%TarBlockSize != 0 {
+		return fmt.Errorf("block size %d is not a multiple of %d: %w", size, TarBlockSize, errutils.ErrValidation)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// writeCompressedTar writes files as a tar stream compressed with compressor to output. If blockSize is
+// positive, the tar stream is padded with zeros to a multiple of blockSize before it is compressed.
+func writeCompressedTar(ctx context.Context, output io.Writer, compressor archives.Compression, archival archives.Tar, files []archives.FileInfo, blockSize int) error {
+	wc, err := compressor.OpenWriter(output)
+	if err != nil {
+		return err
+	}
+	tarOutput := &countingWriter{w: wc}
+	if err := archival.Archive(ctx, tarOutput, files); err != nil {
+		_ = wc.Close()
+		return err
+	}
+	if blockSize > 0 {
+		if rest := tarOutput.n % int64(blockSize); rest != 0 {
+			if _, err := tarOutput.Write(make([]byte, int64(blockSize)-rest)); err != nil {
+				_ = wc.Close()
+				return fmt.Errorf("failed to pad tar stream: %w", err)
+			}
+		}
+	}
+	return wc.Close()
+}
@@ -0,0 +1,50 @@
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tarStreamSize returns the size of the uncompressed tar stream of a gzip compressed archive.
+func tarStreamSize(t *testing.T, archivePath string) int64 {
+	file, err := os.Open(archivePath)
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+	reader, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	size, err := io.Copy(io.Discard, reader)
+	require.NoError(t, err)
+	return size
+}
+
+func TestArchiveManager_Create_BlockSize(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "data"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data", "app.bin"), []byte("app"), 0o644))
+	am := NewManager()
+
+	for _, blockSize := range []int{TarBlockSize, 10240, 20 * 1024} {
+		archivePath := filepath.Join(t.TempDir(), "test.tar.gz")
+		require.NoError(t, am.CreateWithOptions(context.Background(), sourceDir, archivePath, Options{BlockSize: blockSize}))
+		assert.Zero(t, tarStreamSize(t, archivePath)%int64(blockSize), "block size %d", blockSize)
+
+		destDir := t.TempDir()
+		require.NoError(t, am.ExtractAll(context.Background(), archivePath, destDir))
+		content, err := os.ReadFile(filepath.Join(destDir, "data", "app.bin"))
+		require.NoError(t, err)
+		assert.Equal(t, "app", string(content))
+	}
+
+	for _, blockSize := range []int{-512, 100, 1000} {
+		err := am.CreateWithOptions(context.Background(), sourceDir, filepath.Join(t.TempDir(), "test.tar.gz"), Options{BlockSize: blockSize})
+		assert.ErrorIs(t, err, errutils.ErrValidation, "block size %d", blockSize)
+	}
+}
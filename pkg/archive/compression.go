@@ -0,0 +1,201 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/klauspost/compress/zstd"
+	"github.com/mholt/archives"
+)
+
+// Compression selects the compressor Create applies to the tar stream.
+type Compression int
+
+const (
+	// Gzip compresses archives with gzip (.tar.gz). It is the default.
+	Gzip Compression = iota
+	// Zstd compresses archives with Zstandard (.tar.zst).
+	Zstd
+	// Xz compresses archives with xz (.tar.xz).
+	Xz
+)
+
+// compressionMagic lists the leading bytes identifying each compressed stream.
+var compressionMagic = []struct {
+	compression Compression
+	magic       []byte
+}{
+	{Gzip, []byte{0x1f, 0x8b}},
+	{Zstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{Xz, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+}
+
+// maxMagicLen is the number of bytes read to detect the compression of an archive.
+const maxMagicLen = 6
+
+// String returns the name of the compression.
+func (c Compression) String() string {
+	switch c {
+	case Gzip:
+		return "gzip"
+	case Zstd:
+		return "zstd"
+	case Xz:
+		return "xz"
+	default:
+		return fmt.Sprintf("Compression(%d)", int(c))
+	}
+}
+
+// Extension returns the conventional file extension of archives using this compression, e.g. ".tar.zst".
+func (c Compression) Extension() string {
+	switch c {
+	case Zstd:
+		return ".tar.zst"
+	case Xz:
+		return ".tar.xz"
+	default:
+		return ".tar.gz"
+	}
+}
+
+// compressor returns the archives implementation of the compression. A level of 0 selects the default
+// level of the compressor; other levels must be accepted by ValidateLevel.
+func (c Compression) compressor(level int) (archives.Compression, error) {
+	if err := c.ValidateLevel(level); err != nil {
+		return nil, err
+	}
+	switch c {
+	case Gzip:
+		return archives.Gz{CompressionLevel: level}, nil
+	case Zstd:
+		if level == 0 {
+			return archives.Zstd{}, nil
+		}
+		return archives.Zstd{EncoderOptions: []zstd.EOption{zstd.WithEncoderLevel(zstd.EncoderLevel(level))}}, nil
+	case Xz:
+		return archives.Xz{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", c)
+	}
+}
+
+// ValidateLevel returns an errutils.ErrValidation error if level is not a compression level of c.
+// 0 always selects the default level. Gzip accepts gzip.HuffmanOnly through gzip.BestCompression,
+// zstd accepts zstd.SpeedFastest through zstd.SpeedBestCompression and xz has no levels.
+func (c Compression) ValidateLevel(level int) error {
+	low, high := 0, 0
+	switch c {
+	case Gzip:
+		low, high = gzip.HuffmanOnly, gzip.BestCompression
+	case Zstd:
+		low, high = int(zstd.SpeedFastest), int(zstd.SpeedBestCompression)
+	}
+	if level != 0 && (level < low || level > high) {
+		if low == high {
+			return fmt.Errorf("%s does not support compression levels: %w", c, errutils.ErrValidation)
+		}
+		return fmt.Errorf("compression level %d is out of range for %s, it must be between %d and %d: %w", level, c, low, high, errutils.ErrValidation)
+	}
+	return nil
+}
+
+// NewManagerWithCompression creates a new Manager whose Create uses the given compression.
+func NewManagerWithCompression(compression Compression) *Manager {
+	am := NewManager()
+	am.compression = compression
+	return am
+}
+
+// Compression returns the compression used by Create.
+func (am *Manager) Compression() Compression {
+	return am.compression
+}
+
+// detectCompression identifies the compression of the file at archivePath from its magic bytes.
+// Only the first few bytes are read. It reports false if the file is not compressed in a supported format.
+func detectCompression(archivePath string) (Compression, bool, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = file.Close() }()
+
+	header := make([]byte, maxMagicLen)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return 0, false, err
+	}
+	for _, m := range compressionMagic {
+		if bytes.HasPrefix(header[:n], m.magic) {
+			return m.compression, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// archiveFormat returns the extractor for the archive at archivePath, or nil if it is not an archive.
+// Compressed tar archives are recognized by their content rather than their file name; anything else
+// is left to the archives library to identify.
+func archiveFormat(ctx context.Context, archivePath string) (archives.Extractor, error) {
+	compression, ok, err := detectCompression(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		compressor, err := compression.compressor(0)
+		if err != nil {
+			return nil, err
+		}
+		return archives.CompressedArchive{Extraction: archives.Tar{}, Compression: compressor}, nil
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	format, _, err := archives.Identify(ctx, filepath.Base(archivePath), file)
+	if errors.Is(err, archives.NoMatch) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("identify format: %w", err)
+	}
+	extractor, _ := format.(archives.Extractor)
+	return extractor, nil
+}
+
+// openArchiveFS opens archivePath as a file system after checking that none of its entries would be
+// extracted outside the destination. Directories and plain files are opened as they are.
+// It also returns the total size of the regular files in the archive, or -1 if it is not known.
+func openArchiveFS(ctx context.Context, archivePath string) (fs.FS, int64, error) {
+	info, err := os.Stat(archivePath)
+	if err != nil || !info.Mode().IsRegular() {
+		fsys, err := archives.FileSystem(ctx, archivePath, nil)
+		return fsys, -1, err
+	}
+
+	format, err := archiveFormat(ctx, archivePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	if format == nil {
+		fsys, err := archives.FileSystem(ctx, archivePath, nil)
+		return fsys, -1, err
+	}
+	total, err := checkEntryNames(ctx, archivePath, format)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to check entries of %s: %w", archivePath, err)
+	}
+	return &archives.ArchiveFS{Path: archivePath, Format: format, Context: ctx}, total, nil
+}
@@ -0,0 +1,148 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCompressionSource creates a small source tree with a compressible file.
+func writeCompressionSource(t *testing.T) string {
+	t.Helper()
+	sourceDir := filepath.Join(t.TempDir(), "source")
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "data", "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "meta.json"), []byte(`{"name":"test"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data", "bin", "tool"), []byte(strings.Repeat("binary ", 4096)), 0755))
+	return sourceDir
+}
+
+func TestArchiveManager_Compression_RoundTrip(t *testing.T) {
+	sourceDir := writeCompressionSource(t)
+
+	for _, compression := range []Compression{Gzip, Zstd, Xz} {
+		t.Run(compression.String(), func(t *testing.T) {
+			am := NewManagerWithCompression(compression)
+			assert.Equal(t, compression, am.Compression())
+
+			// The name carries no hint, so extraction must detect the compression from the content
+			archivePath := filepath.Join(t.TempDir(), "artifact.gotya")
+			require.NoError(t, am.Create(context.Background(), sourceDir, archivePath))
+
+			detected, ok, err := detectCompression(archivePath)
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, compression, detected)
+
+			extractDir := filepath.Join(t.TempDir(), "extracted")
+			require.NoError(t, NewManager().ExtractAll(context.Background(), archivePath, extractDir))
+			content, err := os.ReadFile(filepath.Join(extractDir, "data", "bin", "tool"))
+			require.NoError(t, err)
+			assert.Equal(t, strings.Repeat("binary ", 4096), string(content))
+
+			destPath := filepath.Join(t.TempDir(), "meta.json")
+			require.NoError(t, NewManager().ExtractFile(context.Background(), archivePath, "meta.json", destPath))
+			content, err = os.ReadFile(destPath)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"name":"test"}`, string(content))
+		})
+	}
+}
+
+func TestArchiveManager_Compression_Extension(t *testing.T) {
+	assert.Equal(t, ".tar.gz", Gzip.Extension())
+	assert.Equal(t, ".tar.zst", Zstd.Extension())
+	assert.Equal(t, ".tar.xz", Xz.Extension())
+}
+
+func TestArchiveManager_Compression_MismatchedExtension(t *testing.T) {
+	// A zstd archive named like a gzip archive is still extracted by content
+	archivePath := filepath.Join(t.TempDir(), "artifact.tar.gz")
+	require.NoError(t, NewManagerWithCompression(Zstd).Create(context.Background(), writeCompressionSource(t), archivePath))
+
+	extractDir := filepath.Join(t.TempDir(), "extracted")
+	require.NoError(t, NewManager().ExtractAll(context.Background(), archivePath, extractDir))
+	assert.FileExists(t, filepath.Join(extractDir, "meta.json"))
+}
+
+func TestArchiveManager_Compression_TruncatedZstd(t *testing.T) {
+	tempDir := t.TempDir()
+	archivePath := filepath.Join(tempDir, "artifact.tar.zst")
+	require.NoError(t, NewManagerWithCompression(Zstd).Create(context.Background(), writeCompressionSource(t), archivePath))
+
+	data, err := os.ReadFile(archivePath)
+	require.NoError(t, err)
+
+	truncated := filepath.Join(tempDir, "truncated.tar.zst")
+	require.NoError(t, os.WriteFile(truncated, data[:len(data)/2], 0644))
+	corrupt := filepath.Join(tempDir, "corrupt.tar.zst")
+	garbage := append(append([]byte{}, data[:4]...), bytes.Repeat([]byte{0xff}, 512)...)
+	require.NoError(t, os.WriteFile(corrupt, garbage, 0644))
+
+	for _, path := range []string{truncated, corrupt} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			am := NewManager()
+			require.NotPanics(t, func() {
+				err = am.ExtractAll(context.Background(), path, filepath.Join(t.TempDir(), "extracted"))
+			})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), path)
+
+			require.NotPanics(t, func() {
+				err = am.ExtractFile(context.Background(), path, "data/bin/tool", filepath.Join(t.TempDir(), "tool"))
+			})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestArchiveManager_Create_UnsupportedCompression(t *testing.T) {
+	am := NewManagerWithCompression(Compression(42))
+	err := am.Create(context.Background(), writeCompressionSource(t), filepath.Join(t.TempDir(), "artifact.gotya"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Compression(42)")
+}
+
+func TestArchiveManager_Compression_Level(t *testing.T) {
+	// Text from a small vocabulary compresses well, but not so trivially that all levels produce the same size
+	sourceDir := filepath.Join(t.TempDir(), "source")
+	require.NoError(t, os.MkdirAll(sourceDir, 0755))
+	words := strings.Fields("install update remove artifact index checksum version depends hook meta data archive")
+	rng := rand.New(rand.NewPCG(1, 2))
+	var text strings.Builder
+	for text.Len() < 256<<10 {
+		text.WriteString(words[rng.IntN(len(words))])
+		text.WriteByte(" \n"[rng.IntN(2)])
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "words.txt"), []byte(text.String()), 0644))
+
+	createSize := func(t *testing.T, compression Compression, level int) int64 {
+		t.Helper()
+		archivePath := filepath.Join(t.TempDir(), "artifact.gotya")
+		require.NoError(t, NewManagerWithCompression(compression).CreateWithOptions(context.Background(), sourceDir, archivePath, Options{CompressionLevel: level}))
+		require.NoError(t, NewManager().ExtractAll(context.Background(), archivePath, filepath.Join(t.TempDir(), "extracted")))
+		info, err := os.Stat(archivePath)
+		require.NoError(t, err)
+		return info.Size()
+	}
+
+	assert.Less(t, createSize(t, Gzip, gzip.BestCompression), createSize(t, Gzip, gzip.BestSpeed))
+	assert.Less(t, createSize(t, Zstd, int(zstd.SpeedBestCompression)), createSize(t, Zstd, int(zstd.SpeedFastest)))
+
+	for _, tt := range []struct {
+		compression Compression
+		level       int
+	}{{Gzip, 10}, {Gzip, -3}, {Zstd, 5}, {Xz, 6}} {
+		err := NewManagerWithCompression(tt.compression).CreateWithOptions(context.Background(), sourceDir, filepath.Join(t.TempDir(), "artifact.gotya"), Options{CompressionLevel: tt.level})
+		require.ErrorIs(t, err, errutils.ErrValidation, "%s level %d", tt.compression, tt.level)
+	}
+}
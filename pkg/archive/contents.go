@@ -0,0 +1,65 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/mholt/archives"
+)
+
+// Entry describes a file, directory or symlink stored in an archive.
+type Entry struct {
+	// Path is the clean, slash-separated path of the entry relative to the archive root.
+	Path  string
+	Size  int64
+	Mode  fs.FileMode
+	IsDir bool
+}
+
+// ListContents returns the entries of the archive at archivePath in archive order without extracting it.
+// Only the headers are read; file contents are skipped by the decompressor and never held in memory.
+// Archives with unsafe entry names are rejected with errutils.ErrInvalidPath, as they are on extraction.
+func (am *Manager) ListContents(ctx context.Context, archivePath string) ([]Entry, error) {
+	format, err := archiveFormat(ctx, archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive file: %w", err)
+	}
+	if format == nil {
+		return nil, fmt.Errorf("%s is not a supported archive: %w", archivePath, errutils.ErrValidation)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []Entry
+	err = format.Extract(ctx, file, func(ctx context.Context, f archives.FileInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := entryPath(f.NameInArchive)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		entries = append(entries, Entry{
+			Path:  filepath.ToSlash(rel),
+			Size:  f.Size(),
+			Mode:  f.Mode(),
+			IsDir: f.IsDir(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contents of %s: %w", archivePath, err)
+	}
+	return entries, nil
+}
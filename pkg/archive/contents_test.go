@@ -0,0 +1,57 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveManager_ListContents(t *testing.T) {
+	sourceDir := writeCompressionSource(t)
+
+	for _, compression := range []Compression{Gzip, Zstd, Xz} {
+		t.Run(compression.String(), func(t *testing.T) {
+			archivePath := filepath.Join(t.TempDir(), "artifact.gotya")
+			require.NoError(t, NewManagerWithCompression(compression).Create(context.Background(), sourceDir, archivePath))
+
+			entries, err := NewManager().ListContents(context.Background(), archivePath)
+			require.NoError(t, err)
+
+			byPath := make(map[string]Entry, len(entries))
+			for _, e := range entries {
+				byPath[e.Path] = e
+			}
+			require.Contains(t, byPath, "meta.json")
+			require.Contains(t, byPath, "data/bin")
+			require.Contains(t, byPath, "data/bin/tool")
+
+			assert.Equal(t, int64(len(`{"name":"test"}`)), byPath["meta.json"].Size)
+			assert.False(t, byPath["meta.json"].IsDir)
+			assert.True(t, byPath["data/bin"].IsDir)
+			assert.Equal(t, int64(7*4096), byPath["data/bin/tool"].Size)
+			assert.Equal(t, os.FileMode(0755), byPath["data/bin/tool"].Mode.Perm())
+		})
+	}
+}
+
+func TestArchiveManager_ListContents_Invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	evil := filepath.Join(dir, "evil.tar.gz")
+	writeCraftedArchive(t, evil, "data/ok.txt", "../evil.txt")
+	_, err := NewManager().ListContents(context.Background(), evil)
+	require.ErrorIs(t, err, errutils.ErrInvalidPath)
+
+	plain := filepath.Join(dir, "plain.bin")
+	require.NoError(t, os.WriteFile(plain, []byte("not an archive"), 0644))
+	_, err = NewManager().ListContents(context.Background(), plain)
+	require.ErrorIs(t, err, errutils.ErrValidation)
+
+	_, err = NewManager().ListContents(context.Background(), filepath.Join(dir, "missing.tar.gz"))
+	require.Error(t, err)
+}
@@ -0,0 +1,46 @@
+package archive
+
+import (
+	"io/fs"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mholt/archives"
+)
+
+// deterministicModTime is the modification time recorded for every entry of a deterministic archive.
+var deterministicModTime = time.Unix(0, 0).UTC()
+
+// SetDeterministic makes Create produce byte-identical archives for identical source trees.
+// Entries are written sorted by path with a fixed modification time, uid and gid 0, no owner names
+// and only their permission bits, so setuid, setgid and sticky bits are dropped.
+func (am *Manager) SetDeterministic(deterministic bool) {
+	am.deterministic = deterministic
+}
+
+// normalizedFileInfo hides everything of a file's info that differs between otherwise identical trees.
+type normalizedFileInfo struct {
+	fs.FileInfo
+}
+
+func (fi normalizedFileInfo) ModTime() time.Time { return deterministicModTime }
+
+// Mode keeps the file type and the permission bits.
+func (fi normalizedFileInfo) Mode() fs.FileMode {
+	mode := fi.FileInfo.Mode()
+	return mode.Type() | mode.Perm()
+}
+
+// Sys returns nil so no owner, group or access times are taken from the file system.
+func (fi normalizedFileInfo) Sys() any { return nil }
+
+// normalizeFiles sorts files by their name in the archive and normalizes their metadata.
+func normalizeFiles(files []archives.FileInfo) {
+	slices.SortFunc(files, func(a, b archives.FileInfo) int {
+		return strings.Compare(a.NameInArchive, b.NameInArchive)
+	})
+	for i := range files {
+		files[i].FileInfo = normalizedFileInfo{FileInfo: files[i].FileInfo}
+	}
+}
@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fileDigest returns the SHA-256 of the file at path.
+func fileDigest(t *testing.T, path string) [sha256.Size]byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return sha256.Sum256(data)
+}
+
+// touchTree sets the modification time of everything below dir.
+func touchTree(t *testing.T, dir string, mtime time.Time) {
+	t.Helper()
+	require.NoError(t, filepath.Walk(dir, func(path string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Chtimes(path, mtime, mtime)
+	}))
+}
+
+func TestArchiveManager_Create_Deterministic(t *testing.T) {
+	sourceDir := writeCompressionSource(t)
+
+	for _, compression := range []Compression{Gzip, Zstd, Xz} {
+		t.Run(compression.String(), func(t *testing.T) {
+			am := NewManagerWithCompression(compression)
+			am.SetDeterministic(true)
+			outDir := t.TempDir()
+
+			first := filepath.Join(outDir, "first"+compression.Extension())
+			touchTree(t, sourceDir, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			require.NoError(t, am.Create(context.Background(), sourceDir, first))
+
+			second := filepath.Join(outDir, "second"+compression.Extension())
+			touchTree(t, sourceDir, time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC))
+			require.NoError(t, am.Create(context.Background(), sourceDir, second))
+
+			assert.Equal(t, fileDigest(t, first), fileDigest(t, second))
+
+			// Without the option the modification times end up in the archive
+			plain := NewManagerWithCompression(compression)
+			third := filepath.Join(outDir, "third"+compression.Extension())
+			require.NoError(t, plain.Create(context.Background(), sourceDir, third))
+			touchTree(t, sourceDir, time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC))
+			fourth := filepath.Join(outDir, "fourth"+compression.Extension())
+			require.NoError(t, plain.Create(context.Background(), sourceDir, fourth))
+			assert.NotEqual(t, fileDigest(t, third), fileDigest(t, fourth))
+		})
+	}
+}
+
+func TestArchiveManager_Create_DeterministicHeaders(t *testing.T) {
+	sourceDir := writeCompressionSource(t)
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "b.txt"), []byte("b"), 0o640))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "a.txt"), []byte("a"), 0o644))
+	require.NoError(t, os.Chmod(filepath.Join(sourceDir, "data", "bin", "tool"), 0o755|os.ModeSetuid))
+
+	am := NewManager()
+	am.SetDeterministic(true)
+	archivePath := filepath.Join(t.TempDir(), "artifact.tar.gz")
+	require.NoError(t, am.Create(context.Background(), sourceDir, archivePath))
+
+	file, err := os.Open(archivePath)
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+
+		assert.True(t, hdr.ModTime.Equal(deterministicModTime), hdr.Name)
+		assert.Zero(t, hdr.Uid, hdr.Name)
+		assert.Zero(t, hdr.Gid, hdr.Name)
+		assert.Empty(t, hdr.Uname, hdr.Name)
+		assert.Empty(t, hdr.Gname, hdr.Name)
+		assert.Zero(t, hdr.Mode&^0o777, hdr.Name)
+	}
+	assert.IsNonDecreasing(t, names)
+	assert.Contains(t, names, "data/bin/tool")
+}
@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+// SetMaxTotalBytes limits the number of bytes ExtractAll writes for all files of an archive combined.
+// A limit of 0 means unlimited.
+func (am *Manager) SetMaxTotalBytes(n int64) {
+	am.maxTotalBytes = n
+}
+
+// SetMaxFileBytes limits the number of bytes extracted for a single file.
+// A limit of 0 means unlimited.
+func (am *Manager) SetMaxFileBytes(n int64) {
+	am.maxFileBytes = n
+}
+
+// extraction holds the state of a single extraction that belongs to the reading goroutine.
+type extraction struct {
+	ctx           context.Context
+	buf           []byte
+	maxFileBytes  int64
+	maxTotalBytes int64
+	total         int64
+	// created lists the paths written by the extraction in creation order
+	created []string
+	// linkTraversals maps the directories the targets of recreated symlinks pass through to the symlink entry
+	linkTraversals map[string]string
+	// onProgress, if set, is called with the bytes copied so far and progressTotal
+	onProgress    func(done, total int64)
+	progressTotal int64
+	done          int64
+}
+
+// newExtraction starts an extraction with the manager's copy buffer and size limits.
+func (am *Manager) newExtraction(ctx context.Context) *extraction {
+	return &extraction{ctx: ctx, buf: am.newCopyBuffer(), maxFileBytes: am.maxFileBytes, maxTotalBytes: am.maxTotalBytes}
+}
+
+// track records that path is about to be created by the extraction.
+func (ex *extraction) track(path string) {
+	ex.created = append(ex.created, path)
+}
+
+// traverseLink records that the target of the symlink entry name passes through the directory at path.
+func (ex *extraction) traverseLink(path, name string) {
+	if ex.linkTraversals == nil {
+		ex.linkTraversals = make(map[string]string)
+	}
+	ex.linkTraversals[path] = name
+}
+
+// trackDir records the directory at path and its missing parents as about to be created.
+// Directories that already exist are not recorded.
+func (ex *extraction) trackDir(path string) {
+	var missing []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); !os.IsNotExist(err) {
+			break
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	for _, dir := range slices.Backward(missing) {
+		ex.track(dir)
+	}
+}
+
+// removeCreated removes the files and the then empty directories created by the extraction, newest first.
+// Directories that still hold other files are kept.
+func (ex *extraction) removeCreated() {
+	for _, path := range slices.Backward(ex.created) {
+		_ = os.Remove(path)
+	}
+	ex.created = nil
+}
+
+// contextReader fails reads with the context's error once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// copy copies the contents of the archive entry at path from src to dst. The size limits are enforced
+// while copying, so at most the allowed number of bytes is ever written before errutils.ErrExtractionTooLarge is returned.
+// The copy stops with the context's error as soon as the extraction's context is done.
+func (ex *extraction) copy(dst io.Writer, src io.Reader, path string) (int64, error) {
+	if ex.ctx != nil {
+		src = contextReader{ctx: ex.ctx, r: src}
+	}
+	if ex.onProgress != nil {
+		src = &progressReader{r: src, add: ex.addProgress}
+	}
+	if ex.maxFileBytes <= 0 && ex.maxTotalBytes <= 0 {
+		return copyBuffered(dst, src, ex.buf)
+	}
+
+	allowed, limit := int64(-1), ""
+	if ex.maxFileBytes > 0 {
+		allowed, limit = ex.maxFileBytes, fmt.Sprintf("per-file limit of %d bytes", ex.maxFileBytes)
+	}
+	if ex.maxTotalBytes > 0 {
+		if remaining := ex.maxTotalBytes - ex.total; allowed < 0 || remaining < allowed {
+			allowed, limit = remaining, fmt.Sprintf("total limit of %d bytes", ex.maxTotalBytes)
+		}
+	}
+
+	n, err := copyBuffered(dst, &io.LimitedReader{R: src, N: allowed}, ex.buf)
+	ex.total += n
+	if err != nil {
+		return n, err
+	}
+
+	// The limited reader stops at the limit; any further byte means the entry is too large
+	var probe [1]byte
+	if extra, _ := io.ReadFull(src, probe[:]); extra > 0 {
+		return n, fmt.Errorf("%s exceeds the %s: %w", path, limit, errutils.ErrExtractionTooLarge)
+	}
+	return n, nil
+}
@@ -0,0 +1,175 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSizedArchive creates an archive with three 1000 byte files and one 3000 byte file.
+func writeSizedArchive(t *testing.T) string {
+	t.Helper()
+	sourceDir := filepath.Join(t.TempDir(), "source")
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "data"), 0755))
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data", name), bytes.Repeat([]byte(name), 1000), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data", "large"), bytes.Repeat([]byte("L"), 3000), 0644))
+
+	archivePath := filepath.Join(t.TempDir(), "artifact.tar.gz")
+	require.NoError(t, NewManager().Create(context.Background(), sourceDir, archivePath))
+	return archivePath
+}
+
+// extractedBytes returns the total size of the regular files below dir.
+func extractedBytes(t *testing.T, dir string) int64 {
+	t.Helper()
+	var total int64
+	require.NoError(t, filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	}))
+	return total
+}
+
+func TestArchiveManager_ExtractAll_Limits(t *testing.T) {
+	archivePath := writeSizedArchive(t)
+
+	tests := []struct {
+		name     string
+		maxTotal int64
+		maxFile  int64
+		wantErr  string
+	}{
+		{name: "unlimited"},
+		{name: "within limits", maxTotal: 6000, maxFile: 3000},
+		{name: "file limit", maxFile: 2999, wantErr: "per-file limit of 2999 bytes"},
+		{name: "total limit", maxTotal: 5999, wantErr: "total limit of 5999 bytes"},
+	}
+	for _, tt := range tests {
+		for _, concurrency := range []int{1, DefaultExtractConcurrency} {
+			t.Run(fmt.Sprintf("%s/concurrency=%d", tt.name, concurrency), func(t *testing.T) {
+				am := NewManager()
+				am.SetExtractConcurrency(concurrency)
+				am.SetMaxTotalBytes(tt.maxTotal)
+				am.SetMaxFileBytes(tt.maxFile)
+
+				destDir := filepath.Join(t.TempDir(), "extracted")
+				err := am.ExtractAll(context.Background(), archivePath, destDir)
+				if tt.wantErr == "" {
+					require.NoError(t, err)
+					assert.Equal(t, int64(6000), extractedBytes(t, destDir))
+					return
+				}
+				require.ErrorIs(t, err, errutils.ErrExtractionTooLarge)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Contains(t, err.Error(), archivePath)
+
+				// Nothing beyond the limits may reach the disk
+				if tt.maxTotal > 0 {
+					assert.LessOrEqual(t, extractedBytes(t, destDir), tt.maxTotal)
+				}
+				if tt.maxFile > 0 {
+					info, err := os.Stat(filepath.Join(destDir, "data", "large"))
+					if err == nil {
+						assert.LessOrEqual(t, info.Size(), tt.maxFile)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestArchiveManager_ExtractFile_FileLimit(t *testing.T) {
+	archivePath := writeSizedArchive(t)
+	am := NewManager()
+	am.SetMaxFileBytes(1000)
+
+	destPath := filepath.Join(t.TempDir(), "a")
+	require.NoError(t, am.ExtractFile(context.Background(), archivePath, "data/a", destPath))
+
+	err := am.ExtractFile(context.Background(), archivePath, "data/large", filepath.Join(t.TempDir(), "large"))
+	require.ErrorIs(t, err, errutils.ErrExtractionTooLarge)
+}
+
+func TestExtraction_Copy_StopsAtLimit(t *testing.T) {
+	ex := &extraction{buf: make([]byte, 16), maxTotalBytes: 10}
+	var dst bytes.Buffer
+
+	n, err := ex.copy(&dst, strings.NewReader("0123456789"), "first")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), n)
+
+	// The total is exhausted, so the next entry may not write a single byte
+	_, err = ex.copy(&dst, strings.NewReader("x"), "second")
+	require.ErrorIs(t, err, errutils.ErrExtractionTooLarge)
+	assert.Contains(t, err.Error(), "second")
+	assert.Equal(t, "0123456789", dst.String())
+}
+
+// cancelOnOpen cancels a context when the entry at path is opened.
+type cancelOnOpen struct {
+	fs.FS
+	path   string
+	cancel context.CancelFunc
+}
+
+func (c cancelOnOpen) Open(name string) (fs.File, error) {
+	if name == c.path {
+		c.cancel()
+	}
+	return c.FS.Open(name)
+}
+
+func TestArchiveManager_ExtractFS_CancelMidExtraction(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "source")
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "data", "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data", "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data", "nested", "b.txt"), []byte("b"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data", "nested", "large.bin"), bytes.Repeat([]byte("x"), 2*pipelinedFileMaxSize), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data", "z.txt"), []byte("z"), 0644))
+	archivePath := filepath.Join(t.TempDir(), "artifact.tar.gz")
+	require.NoError(t, NewManager().Create(context.Background(), sourceDir, archivePath))
+
+	// Opening an entry cancels the context, so its copy is interrupted after earlier entries were written
+	for _, cancelAt := range []string{"data/nested/b.txt", "data/nested/large.bin"} {
+		for _, concurrency := range []int{1, DefaultExtractConcurrency} {
+			t.Run(fmt.Sprintf("%s/concurrency=%d", cancelAt, concurrency), func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				fsys, _, err := openArchiveFS(ctx, archivePath)
+				require.NoError(t, err)
+
+				// destDir exists beforehand and must survive with its own contents
+				destDir := t.TempDir()
+				require.NoError(t, os.WriteFile(filepath.Join(destDir, "keep.txt"), []byte("keep"), 0644))
+
+				am := NewManager()
+				am.SetExtractConcurrency(concurrency)
+				err = am.extractFS(ctx, cancelOnOpen{FS: fsys, path: cancelAt, cancel: cancel}, destDir, am.newExtraction(ctx))
+				require.ErrorIs(t, err, context.Canceled)
+
+				entries, err := os.ReadDir(destDir)
+				require.NoError(t, err)
+				require.Len(t, entries, 1)
+				assert.Equal(t, "keep.txt", entries[0].Name())
+			})
+		}
+	}
+}
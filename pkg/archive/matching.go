@@ -0,0 +1,128 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/fsutil"
+	"github.com/mholt/archives"
+)
+
+// ExtractMatching extracts the entries of an archive that match any of the patterns to destDir,
+// keeping their paths relative to the archive root. Patterns use path.Match syntax against the
+// slash-separated entry path; an entry also matches if one of its parent directories does, so
+// "meta" extracts the whole meta subtree while "meta/*.json" only extracts the JSON files in it.
+// The archive is read as a single stream and the contents of non-matching entries are skipped.
+// Unsafe entry names are rejected with errutils.ErrInvalidPath, invalid patterns with
+// errutils.ErrValidation, and if nothing matches errutils.ErrFileNotFound is returned.
+// Size limits, symlinks and cancellation are handled as in ExtractAll.
+func (am *Manager) ExtractMatching(ctx context.Context, archivePath, destDir string, patterns []string) error {
+	if len(patterns) == 0 {
+		return fmt.Errorf("no patterns given: %w", errutils.ErrValidation)
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v: %w", pattern, err, errutils.ErrValidation)
+		}
+	}
+
+	format, err := archiveFormat(ctx, archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive file: %w", err)
+	}
+	if format == nil {
+		return fmt.Errorf("%s is not a supported archive: %w", archivePath, errutils.ErrValidation)
+	}
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	ex := am.newExtraction(ctx)
+	matched := 0
+	err = format.Extract(ctx, file, func(ctx context.Context, f archives.FileInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := entryPath(f.NameInArchive)
+		if err != nil {
+			return err
+		}
+		if rel == "." || !matchesAny(filepath.ToSlash(rel), patterns) {
+			return nil
+		}
+		matched++
+		return am.extractStreamedEntry(f, destDir, filepath.Join(destDir, rel), ex)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			ex.removeCreated()
+		}
+		return fmt.Errorf("failed to extract archive %s: %w", archivePath, err)
+	}
+	if matched == 0 {
+		return fmt.Errorf("no entries of %s match %q: %w", archivePath, patterns, errutils.ErrFileNotFound)
+	}
+	return nil
+}
+
+// matchesAny reports whether name or one of its parent directories matches any of the patterns.
+func matchesAny(name string, patterns []string) bool {
+	for candidate := name; candidate != "."; candidate = path.Dir(candidate) {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, candidate); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractStreamedEntry writes an entry read from the archive stream to target.
+func (am *Manager) extractStreamedEntry(f archives.FileInfo, destDir, target string, ex *extraction) error {
+	if f.IsDir() {
+		ex.trackDir(target)
+		return os.MkdirAll(target, 0755)
+	}
+	if f.Mode()&os.ModeSymlink != 0 {
+		if skip, err := am.checkSymlink(destDir, f.NameInArchive, target, f.LinkTarget, ex); skip || err != nil {
+			return err
+		}
+	}
+
+	ex.trackDir(filepath.Dir(target))
+	ex.track(target)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory for %s: %w", f.NameInArchive, err)
+	}
+
+	if f.Mode()&os.ModeSymlink != 0 {
+		_ = os.Remove(target)
+		return os.Symlink(f.LinkTarget, target)
+	}
+	if !f.Mode().IsRegular() {
+		return nil
+	}
+
+	srcFile, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open source file %s: %w", f.NameInArchive, err)
+	}
+	defer func() { _ = srcFile.Close() }()
+
+	dstFile, err := fsutil.CreateFilePerm(target, f.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create destination file %s: %w", target, err)
+	}
+	defer func() { _ = dstFile.Close() }()
+
+	if _, err := ex.copy(dstFile, srcFile, f.NameInArchive); err != nil {
+		return fmt.Errorf("failed to copy file %s: %w", f.NameInArchive, err)
+	}
+	return applyFileMetadata(target, f)
+}
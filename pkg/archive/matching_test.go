@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeArtifactLayout creates an archive with a meta and a data subtree.
+func writeArtifactLayout(t *testing.T) string {
+	t.Helper()
+	sourceDir := filepath.Join(t.TempDir(), "source")
+	files := map[string]string{
+		"meta/artifact.json":         `{"name":"test"}`,
+		"meta/hooks/post-install.tg": "hook",
+		"data/bin/tool":              "tool",
+		"data/share/readme.json":     "{}",
+	}
+	for name, content := range files {
+		path := filepath.Join(sourceDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	archivePath := filepath.Join(t.TempDir(), "artifact.tar.gz")
+	require.NoError(t, NewManager().Create(context.Background(), sourceDir, archivePath))
+	return archivePath
+}
+
+func TestArchiveManager_ExtractMatching(t *testing.T) {
+	archivePath := writeArtifactLayout(t)
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{name: "subtree", patterns: []string{"meta"}, want: []string{"meta/artifact.json", "meta/hooks/post-install.tg"}},
+		{name: "direct children", patterns: []string{"meta/*.json"}, want: []string{"meta/artifact.json"}},
+		{name: "several patterns", patterns: []string{"meta/artifact.json", "data/*/*.json"}, want: []string{"meta/artifact.json", "data/share/readme.json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destDir := t.TempDir()
+			require.NoError(t, NewManager().ExtractMatching(context.Background(), archivePath, destDir, tt.patterns))
+
+			var got []string
+			require.NoError(t, filepath.Walk(destDir, func(path string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(destDir, path)
+				got = append(got, filepath.ToSlash(rel))
+				return err
+			}))
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func TestArchiveManager_ExtractMatching_Errors(t *testing.T) {
+	archivePath := writeArtifactLayout(t)
+	am := NewManager()
+
+	destDir := filepath.Join(t.TempDir(), "extracted")
+	err := am.ExtractMatching(context.Background(), archivePath, destDir, []string{"docs/*"})
+	require.ErrorIs(t, err, errutils.ErrFileNotFound)
+	assert.NoDirExists(t, destDir)
+
+	err = am.ExtractMatching(context.Background(), archivePath, t.TempDir(), []string{"meta/["})
+	require.ErrorIs(t, err, errutils.ErrValidation)
+
+	err = am.ExtractMatching(context.Background(), archivePath, t.TempDir(), nil)
+	require.ErrorIs(t, err, errutils.ErrValidation)
+
+	evil := filepath.Join(t.TempDir(), "evil.tar.gz")
+	writeCraftedArchive(t, evil, "meta/ok.txt", "../evil.txt")
+	err = am.ExtractMatching(context.Background(), evil, t.TempDir(), []string{"meta"})
+	require.ErrorIs(t, err, errutils.ErrInvalidPath)
+}
@@ -0,0 +1,67 @@
+package archive
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/mholt/archives"
+)
+
+// Options holds optional settings for a single extraction or creation.
+type Options struct {
+	// OnProgress, if set, is called with the number of file content bytes processed so far and the
+	// expected total, or -1 if the total is not known. It is called from a single goroutine.
+	OnProgress func(done, total int64)
+	// Exclude lists path.Match globs of paths CreateWithOptions leaves out of the archive, e.g. ".git"
+	// or "*.tmp". Patterns without a slash match the name of a file or directory at any depth; patterns
+	// with a slash match the slash-separated path relative to the source directory. Excluding a
+	// directory excludes everything below it. Extraction ignores it.
+	Exclude []string
+	// BlockSize, if positive, pads the uncompressed tar stream written by CreateWithOptions with zeros to a
+	// multiple of this many bytes, like the record size of tar -b, for readers that require full records.
+	// It must be a multiple of TarBlockSize. Extraction ignores it.
+	BlockSize int
+	// CompressionLevel, if not 0, sets the compression level of archives created by CreateWithOptions,
+	// trading speed for size, e.g. gzip.BestSpeed or gzip.BestCompression. It must be valid for the
+	// compression of the manager, see Compression.ValidateLevel. Extraction ignores it.
+	CompressionLevel int
+}
+
+// validateExcludePatterns rejects malformed exclude patterns with errutils.ErrValidation.
+func validateExcludePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %v: %w", pattern, err, errutils.ErrValidation)
+		}
+	}
+	return nil
+}
+
+// excludeFiles removes the files matching any of the patterns, or lying below a directory that does.
+func excludeFiles(files []archives.FileInfo, patterns []string) []archives.FileInfo {
+	if len(patterns) == 0 {
+		return files
+	}
+	return slices.DeleteFunc(files, func(f archives.FileInfo) bool {
+		return isExcluded(path.Clean(f.NameInArchive), patterns)
+	})
+}
+
+// isExcluded reports whether name or one of its parent directories matches any of the patterns.
+func isExcluded(name string, patterns []string) bool {
+	for candidate := name; candidate != "." && candidate != "/"; candidate = path.Dir(candidate) {
+		for _, pattern := range patterns {
+			subject := candidate
+			if !strings.Contains(pattern, "/") {
+				subject = path.Base(candidate)
+			}
+			if ok, _ := path.Match(pattern, subject); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveManager_Create_Exclude(t *testing.T) {
+	sourceDir := t.TempDir()
+	for name, content := range map[string]string{
+		"data/app.bin":             "app",
+		"data/.git/HEAD":           "ref: refs/heads/main",
+		"data/.git/objects/ab/cd":  "object",
+		"data/cache/build.tmp":     "tmp",
+		"data/.app.bin.swp":        "swap",
+		"meta/artifact.json":       "{}",
+		"meta/hooks/post-install":  "#!/bin/sh",
+		"meta/hooks/notes/keep.md": "keep",
+	} {
+		path := filepath.Join(sourceDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	am := NewManager()
+	archivePath := filepath.Join(t.TempDir(), "test.tar.gz")
+	opts := Options{Exclude: []string{".git", "*.tmp", ".*.swp", "meta/hooks/post-*"}}
+	require.NoError(t, am.CreateWithOptions(context.Background(), sourceDir, archivePath, opts))
+
+	entries, err := am.ListContents(context.Background(), archivePath)
+	require.NoError(t, err)
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir {
+			files = append(files, entry.Path)
+		}
+	}
+	assert.ElementsMatch(t, []string{"data/app.bin", "meta/artifact.json", "meta/hooks/notes/keep.md"}, files)
+
+	destDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, am.ExtractAll(context.Background(), archivePath, destDir))
+	assert.NoDirExists(t, filepath.Join(destDir, "data", ".git"))
+	assert.DirExists(t, filepath.Join(destDir, "data", "cache"))
+	assert.FileExists(t, filepath.Join(destDir, "data", "app.bin"))
+}
+
+func TestArchiveManager_Create_InvalidExclude(t *testing.T) {
+	sourceDir := t.TempDir()
+	archivePath := filepath.Join(t.TempDir(), "test.tar.gz")
+	err := NewManager().CreateWithOptions(context.Background(), sourceDir, archivePath, Options{Exclude: []string{"[a-"}})
+	require.ErrorIs(t, err, errutils.ErrValidation)
+	assert.NoFileExists(t, archivePath)
+}
@@ -0,0 +1,59 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/mholt/archives"
+)
+
+// entryPath validates an archive entry name and returns it as a clean relative path.
+// Absolute names and names that leave the archive root after cleaning are rejected with
+// errutils.ErrInvalidPath. Backslashes count as separators so Windows-style names are caught too.
+func entryPath(name string) (string, error) {
+	slashed := strings.ReplaceAll(name, `\`, "/")
+	if path.IsAbs(slashed) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", errutils.Wrapf(errutils.ErrInvalidPath, "archive entry %q has an absolute path", name)
+	}
+	cleaned := path.Clean(slashed)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", errutils.Wrapf(errutils.ErrInvalidPath, "archive entry %q escapes the destination directory", name)
+	}
+	return filepath.FromSlash(cleaned), nil
+}
+
+// targetPath returns where the entry name is extracted to below destDir.
+func targetPath(destDir, name string) (string, error) {
+	rel, err := entryPath(name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(destDir, rel), nil
+}
+
+// checkEntryNames reads the entry names from the archive headers and rejects the archive if any of them
+// is unsafe. The archive file system view cannot be relied on for this, as it hides or mangles such entries.
+// File contents are not read. It returns the total size of the regular files in the archive.
+func checkEntryNames(ctx context.Context, archivePath string, format archives.Extractor) (int64, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var total int64
+	err = format.Extract(ctx, file, func(_ context.Context, f archives.FileInfo) error {
+		if _, err := entryPath(f.NameInArchive); err != nil {
+			return err
+		}
+		if f.Mode().IsRegular() {
+			total += f.Size()
+		}
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,94 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCraftedArchive writes a gzip compressed tar archive with the given entry names, bypassing any
+// sanitization Create would apply.
+func writeCraftedArchive(t *testing.T, archivePath string, names ...string) {
+	t.Helper()
+	file, err := os.Create(archivePath)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: 4, Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte("evil"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, file.Close())
+}
+
+func TestEntryPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		invalid bool
+	}{
+		{name: "data/file.txt", want: filepath.Join("data", "file.txt")},
+		{name: "./meta/artifact.json", want: filepath.Join("meta", "artifact.json")},
+		{name: "data/../meta/x", want: filepath.Join("meta", "x")},
+		{name: "..", invalid: true},
+		{name: "../evil", invalid: true},
+		{name: "data/../../evil", invalid: true},
+		{name: `..\evil`, invalid: true},
+		{name: "/etc/passwd", invalid: true},
+		{name: `\etc\passwd`, invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := entryPath(tt.name)
+			if tt.invalid {
+				require.ErrorIs(t, err, errutils.ErrInvalidPath)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestArchiveManager_ExtractAll_RejectsPathTraversal(t *testing.T) {
+	for _, evil := range []string{"../evil.txt", "data/../../evil.txt", "/tmp/gotya-zip-slip-evil.txt"} {
+		t.Run(evil, func(t *testing.T) {
+			root := t.TempDir()
+			archivePath := filepath.Join(root, "evil.gotya")
+			writeCraftedArchive(t, archivePath, "data/ok.txt", evil)
+
+			destDir := filepath.Join(root, "dest")
+			err := NewManager().ExtractAll(context.Background(), archivePath, destDir)
+			require.ErrorIs(t, err, errutils.ErrInvalidPath)
+
+			assert.NoFileExists(t, filepath.Join(root, "evil.txt"))
+			assert.NoFileExists(t, "/tmp/gotya-zip-slip-evil.txt")
+			assert.NoFileExists(t, filepath.Join(destDir, "data", "ok.txt"), "nothing is extracted from a rejected archive")
+		})
+	}
+}
+
+func TestArchiveManager_ExtractFile_RejectsPathTraversal(t *testing.T) {
+	root := t.TempDir()
+	archivePath := filepath.Join(root, "evil.gotya")
+	writeCraftedArchive(t, archivePath, "meta/artifact.json", "../evil.txt")
+
+	err := NewManager().ExtractFile(context.Background(), archivePath, "meta/artifact.json", filepath.Join(root, "out", "artifact.json"))
+	require.ErrorIs(t, err, errutils.ErrInvalidPath)
+	assert.NoFileExists(t, filepath.Join(root, "out", "artifact.json"))
+
+	safePath := filepath.Join(root, "safe.gotya")
+	writeCraftedArchive(t, safePath, "meta/artifact.json")
+	err = NewManager().ExtractFile(context.Background(), safePath, "../meta/artifact.json", filepath.Join(root, "out", "artifact.json"))
+	require.ErrorIs(t, err, errutils.ErrInvalidPath)
+}
@@ -0,0 +1,76 @@
+package archive
+
+import (
+	"io"
+	"io/fs"
+
+	"github.com/mholt/archives"
+)
+
+// progressReader reports the bytes read from r to add.
+type progressReader struct {
+	r   io.Reader
+	add func(n int64)
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	if n > 0 {
+		pr.add(int64(n))
+	}
+	return n, err
+}
+
+// reportProgress makes the extraction report its progress towards total to onProgress.
+func (ex *extraction) reportProgress(onProgress func(done, total int64), total int64) {
+	ex.onProgress = onProgress
+	ex.progressTotal = total
+	if onProgress != nil {
+		onProgress(0, total)
+	}
+}
+
+// addProgress records that n more bytes were copied.
+func (ex *extraction) addProgress(n int64) {
+	ex.done += n
+	ex.onProgress(ex.done, ex.progressTotal)
+}
+
+// progressFile counts the bytes read from an archived file.
+type progressFile struct {
+	fs.File
+	reader progressReader
+}
+
+func (pf *progressFile) Read(p []byte) (int, error) {
+	return pf.reader.Read(p)
+}
+
+// trackCreateProgress wraps the files to be archived so reading their contents reports progress to onProgress.
+func trackCreateProgress(files []archives.FileInfo, onProgress func(done, total int64)) {
+	var total, done int64
+	for _, f := range files {
+		if f.Mode().IsRegular() {
+			total += f.Size()
+		}
+	}
+	onProgress(0, total)
+
+	add := func(n int64) {
+		done += n
+		onProgress(done, total)
+	}
+	for i := range files {
+		open := files[i].Open
+		if open == nil {
+			continue
+		}
+		files[i].Open = func() (fs.File, error) {
+			file, err := open()
+			if err != nil {
+				return nil, err
+			}
+			return &progressFile{File: file, reader: progressReader{r: file, add: add}}, nil
+		}
+	}
+}
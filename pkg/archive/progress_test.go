@@ -0,0 +1,86 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// progressRecorder collects the progress reports of an operation.
+type progressRecorder struct {
+	done  []int64
+	total []int64
+}
+
+func (r *progressRecorder) record(done, total int64) {
+	r.done = append(r.done, done)
+	r.total = append(r.total, total)
+}
+
+// assertComplete checks that progress only grows and ends at the expected total.
+func (r *progressRecorder) assertComplete(t *testing.T, total int64) {
+	t.Helper()
+	require.NotEmpty(t, r.done)
+	for i := 1; i < len(r.done); i++ {
+		assert.GreaterOrEqual(t, r.done[i], r.done[i-1])
+	}
+	for _, got := range r.total {
+		assert.Equal(t, total, got)
+	}
+	assert.Equal(t, int64(0), r.done[0])
+	assert.Equal(t, total, r.done[len(r.done)-1])
+}
+
+// writeProgressSource creates a directory with files of known sizes and returns it with their total size.
+func writeProgressSource(t *testing.T) (string, int64) {
+	t.Helper()
+	sourceDir := t.TempDir()
+	files := map[string]string{
+		"small.txt":      "hello",
+		"sub/large.bin":  strings.Repeat("x", 256*1024),
+		"sub/empty.file": "",
+	}
+	var total int64
+	for name, content := range files {
+		path := filepath.Join(sourceDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		total += int64(len(content))
+	}
+	return sourceDir, total
+}
+
+func TestArchiveManager_Progress(t *testing.T) {
+	sourceDir, total := writeProgressSource(t)
+	am := NewManager()
+	archivePath := filepath.Join(t.TempDir(), "test.tar.gz")
+
+	var created progressRecorder
+	require.NoError(t, am.CreateWithOptions(context.Background(), sourceDir, archivePath, Options{OnProgress: created.record}))
+	created.assertComplete(t, total)
+
+	var extracted progressRecorder
+	destDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, am.ExtractAllWithOptions(context.Background(), archivePath, destDir, Options{OnProgress: extracted.record}))
+	extracted.assertComplete(t, total)
+	assert.FileExists(t, filepath.Join(destDir, "sub", "large.bin"))
+}
+
+func TestArchiveManager_Progress_UnknownTotal(t *testing.T) {
+	sourceDir, total := writeProgressSource(t)
+
+	var extracted progressRecorder
+	destDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, NewManager().ExtractAllWithOptions(context.Background(), sourceDir, destDir, Options{OnProgress: extracted.record}))
+
+	require.NotEmpty(t, extracted.done)
+	assert.Equal(t, total, extracted.done[len(extracted.done)-1])
+	for _, got := range extracted.total {
+		assert.Equal(t, int64(-1), got)
+	}
+}
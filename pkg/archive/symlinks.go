@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/mholt/archives"
+)
+
+// SymlinkPolicy decides how symlink entries are handled on extraction.
+type SymlinkPolicy int
+
+const (
+	// SymlinkReject fails the extraction with errutils.ErrSymlinkRejected on the first symlink entry.
+	// It is the default, as a symlink could redirect later entries outside the destination.
+	SymlinkReject SymlinkPolicy = iota
+	// SymlinkSkip leaves symlink entries out and extracts everything else.
+	SymlinkSkip
+	// SymlinkRecreate recreates symlinks whose target resolves to a path within the destination
+	// directory. Absolute targets and targets leaving the destination are rejected with errutils.ErrInvalidPath.
+	SymlinkRecreate
+)
+
+// String returns the name of the policy.
+func (p SymlinkPolicy) String() string {
+	switch p {
+	case SymlinkReject:
+		return "reject"
+	case SymlinkSkip:
+		return "skip"
+	case SymlinkRecreate:
+		return "recreate"
+	default:
+		return fmt.Sprintf("SymlinkPolicy(%d)", int(p))
+	}
+}
+
+// SetSymlinkPolicy sets how ExtractAll and ExtractMatching handle symlink entries. It defaults to SymlinkReject.
+func (am *Manager) SetSymlinkPolicy(policy SymlinkPolicy) {
+	am.symlinkPolicy = policy
+}
+
+// checkSymlink applies the symlink policy to the entry name that would be extracted to targetPath as a
+// symlink to linkTarget. It reports whether the entry is to be skipped.
+func (am *Manager) checkSymlink(destDir, name, targetPath, linkTarget string, ex *extraction) (bool, error) {
+	switch am.symlinkPolicy {
+	case SymlinkSkip:
+		return true, nil
+	case SymlinkRecreate:
+		return false, checkLinkTarget(destDir, name, targetPath, linkTarget, ex)
+	default:
+		return false, errutils.Wrapf(errutils.ErrSymlinkRejected, "archive entry %q is a symlink", name)
+	}
+}
+
+// checkLinkTarget ensures a symlink at targetPath pointing to linkTarget resolves to a path within destDir.
+// The target is resolved component by component against the files on disk: it may not pass through a
+// symlink, as ".." after a symlink leaves the symlink's target rather than its directory. The directories
+// it passes through are recorded in ex, so no symlink can be created there later in the extraction either.
+func checkLinkTarget(destDir, name, targetPath, linkTarget string, ex *extraction) error {
+	if linkTarget == "" || filepath.IsAbs(linkTarget) {
+		return errutils.Wrapf(errutils.ErrInvalidPath, "symlink %q has an absolute or empty target %q", name, linkTarget)
+	}
+	if other, ok := ex.linkTraversals[targetPath]; ok {
+		return errutils.Wrapf(errutils.ErrInvalidPath, "symlink %q would redirect the target of symlink %q", name, other)
+	}
+	dir, err := filepath.Rel(destDir, filepath.Dir(targetPath))
+	if err != nil {
+		return errutils.Wrapf(errutils.ErrInvalidPath, "symlink %q points outside the destination directory", name)
+	}
+
+	// The directory of the symlink is passed through, too
+	components := strings.Split(filepath.ToSlash(dir), "/")
+	components = append(components, strings.Split(filepath.ToSlash(linkTarget), "/")...)
+	var resolved []string
+	for i, component := range components {
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return errutils.Wrapf(errutils.ErrInvalidPath, "symlink %q points to %q outside the destination directory", name, linkTarget)
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+		resolved = append(resolved, component)
+		if i == len(components)-1 {
+			// The final component may be a symlink, it resolves relative to its own directory
+			break
+		}
+		path := filepath.Join(destDir, filepath.Join(resolved...))
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return errutils.Wrapf(errutils.ErrInvalidPath, "symlink %q points to %q through another symlink", name, linkTarget)
+		}
+		ex.traverseLink(path, name)
+	}
+	return nil
+}
+
+// readLinkTarget returns the target of the symlink entry at path. Archive entries carry it in their
+// header; directories opened as a file system are read from disk.
+func readLinkTarget(fsys fs.FS, path string, info fs.FileInfo) (string, error) {
+	if fi, ok := info.(archives.FileInfo); ok {
+		return fi.LinkTarget, nil
+	}
+	if dir, ok := fsys.(archives.DirFS); ok {
+		return os.Readlink(filepath.Join(string(dir), filepath.FromSlash(path)))
+	}
+	return "", fmt.Errorf("failed to read symlink target %s: unsupported file system", path)
+}
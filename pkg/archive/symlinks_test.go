@@ -0,0 +1,192 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSymlinkArchive writes a gzip compressed tar archive with a regular file and symlinks to the given targets,
+// ordered by name.
+func writeSymlinkArchive(t *testing.T, archivePath string, links map[string]string) {
+	t.Helper()
+	file, err := os.Create(archivePath)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "data/file.txt", Mode: 0o644, Size: 4, Typeflag: tar.TypeReg}))
+	_, err = tw.Write([]byte("data"))
+	require.NoError(t, err)
+	for _, name := range slices.Sorted(maps.Keys(links)) {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Linkname: links[name], Mode: 0o777, Typeflag: tar.TypeSymlink}))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, file.Close())
+}
+
+func TestArchiveManager_SymlinkPolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping symlink test on Windows")
+	}
+	archivePath := filepath.Join(t.TempDir(), "links.tar.gz")
+	writeSymlinkArchive(t, archivePath, map[string]string{"data/link.txt": "file.txt", "link-to-data": "data"})
+
+	t.Run("reject", func(t *testing.T) {
+		am := NewManager()
+		require.Equal(t, SymlinkReject, am.symlinkPolicy, "rejecting must be the default")
+		err := am.ExtractAll(context.Background(), archivePath, filepath.Join(t.TempDir(), "out"))
+		require.ErrorIs(t, err, errutils.ErrSymlinkRejected)
+
+		err = am.ExtractMatching(context.Background(), archivePath, t.TempDir(), []string{"data"})
+		require.ErrorIs(t, err, errutils.ErrSymlinkRejected)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		am := NewManager()
+		am.SetSymlinkPolicy(SymlinkSkip)
+		destDir := t.TempDir()
+		require.NoError(t, am.ExtractAll(context.Background(), archivePath, destDir))
+		assert.FileExists(t, filepath.Join(destDir, "data", "file.txt"))
+		assert.NoFileExists(t, filepath.Join(destDir, "data", "link.txt"))
+		_, err := os.Lstat(filepath.Join(destDir, "link-to-data"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("recreate", func(t *testing.T) {
+		am := NewManager()
+		am.SetSymlinkPolicy(SymlinkRecreate)
+		destDir := t.TempDir()
+		require.NoError(t, am.ExtractAll(context.Background(), archivePath, destDir))
+		content, err := os.ReadFile(filepath.Join(destDir, "data", "link.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(content))
+		content, err = os.ReadFile(filepath.Join(destDir, "link-to-data", "file.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(content))
+
+		matchDir := t.TempDir()
+		require.NoError(t, am.ExtractMatching(context.Background(), archivePath, matchDir, []string{"data"}))
+		target, err := os.Readlink(filepath.Join(matchDir, "data", "link.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "file.txt", target)
+	})
+}
+
+func TestArchiveManager_SymlinkRecreate_RejectsEscapingTargets(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping symlink test on Windows")
+	}
+	am := NewManager()
+	am.SetSymlinkPolicy(SymlinkRecreate)
+
+	for name, target := range map[string]string{
+		"relative": "../../outside",
+		"nested":   "../data/../../outside",
+		"absolute": "/etc/passwd",
+		"empty":    "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			archivePath := filepath.Join(tempDir, "links.tar.gz")
+			writeSymlinkArchive(t, archivePath, map[string]string{"data/link": target})
+
+			destDir := filepath.Join(tempDir, "out")
+			err := am.ExtractAll(context.Background(), archivePath, destDir)
+			require.ErrorIs(t, err, errutils.ErrInvalidPath)
+			_, err = os.Lstat(filepath.Join(destDir, "data", "link"))
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestArchiveManager_SymlinkRecreate_RejectsSymlinkChains(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping symlink test on Windows")
+	}
+
+	for name, links := range map[string]map[string]string{
+		// s1 is checked before s2 exists, s2 then redirects it to the parent of the destination
+		"target through later symlink": {"s1": "s2/../outside", "s2": "."},
+		// s2 exists when s1 is checked
+		"target through earlier symlink": {"a0": ".", "a1": "a0/../outside"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			archivePath := filepath.Join(tempDir, "links.tar.gz")
+			writeSymlinkArchive(t, archivePath, links)
+
+			am := NewManager()
+			am.SetSymlinkPolicy(SymlinkRecreate)
+			for _, extract := range []func(string) error{
+				func(destDir string) error { return am.ExtractAll(context.Background(), archivePath, destDir) },
+				func(destDir string) error {
+					return am.ExtractMatching(context.Background(), archivePath, destDir, []string{"data", "s1", "s2", "a0", "a1"})
+				},
+			} {
+				destDir := filepath.Join(t.TempDir(), "out")
+				require.ErrorIs(t, extract(destDir), errutils.ErrInvalidPath)
+				realDest, err := filepath.EvalSymlinks(destDir)
+				if err != nil {
+					continue
+				}
+				for link := range links {
+					if resolved, err := filepath.EvalSymlinks(filepath.Join(destDir, link)); err == nil {
+						assert.True(t, resolved == realDest || strings.HasPrefix(resolved, realDest+string(filepath.Separator)),
+							"symlink %s resolves to %s outside the destination", link, resolved)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestArchiveManager_SymlinkRecreate_RejectsSymlinkBelowSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping symlink test on Windows")
+	}
+	tempDir := t.TempDir()
+	archivePath := filepath.Join(tempDir, "links.tar.gz")
+	writeSymlinkArchive(t, archivePath, map[string]string{"d0": ".", "d0/d1": "../outside"})
+
+	// The archive file system used by ExtractAll does not descend into symlinks, streaming extraction does
+	am := NewManager()
+	am.SetSymlinkPolicy(SymlinkRecreate)
+	err := am.ExtractMatching(context.Background(), archivePath, filepath.Join(tempDir, "out"), []string{"d0"})
+	require.ErrorIs(t, err, errutils.ErrInvalidPath)
+	_, err = os.Lstat(filepath.Join(tempDir, "outside"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestArchiveManager_SymlinkRecreate_Directory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping symlink test on Windows")
+	}
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "file.txt"), []byte("data"), 0644))
+	require.NoError(t, os.Symlink("file.txt", filepath.Join(sourceDir, "inside")))
+	require.NoError(t, os.Symlink("../outside", filepath.Join(sourceDir, "escaping")))
+
+	am := NewManager()
+	am.SetSymlinkPolicy(SymlinkRecreate)
+	err := am.ExtractAll(context.Background(), sourceDir, filepath.Join(t.TempDir(), "out"))
+	require.ErrorIs(t, err, errutils.ErrInvalidPath)
+
+	require.NoError(t, os.Remove(filepath.Join(sourceDir, "escaping")))
+	destDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, am.ExtractAll(context.Background(), sourceDir, destDir))
+	target, err := os.Readlink(filepath.Join(destDir, "inside"))
+	require.NoError(t, err)
+	assert.Equal(t, "file.txt", target)
+}
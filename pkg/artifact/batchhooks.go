@@ -0,0 +1,66 @@
+package artifact
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// PostBatchHook is the hook type of scripts that run once after a whole install plan has completed,
+// e.g. to rebuild a cache several artifacts contribute to.
+const PostBatchHook = "post-batch"
+
+// ExecutePostBatchHooks runs the post-batch hooks declared by the given installed artifacts, in order.
+// Scripts with identical contents run only once, with the context of the first artifact declaring
+// them. Artifacts that are not installed or declare no post-batch hook are ignored.
+func (m *ManagerImpl) ExecutePostBatchHooks(artifactNames []string) error {
+	unlock, err := m.readDB()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	executed := make(map[string]bool)
+	for _, name := range artifactNames {
+		artifact := m.installDB.FindArtifact(name)
+		if artifact == nil || artifact.Status != model.StatusInstalled {
+			continue
+		}
+		metadata, err := m.parseMetadata(filepath.Join(artifact.ArtifactMetaDir, m.metadataFileName()))
+		if err != nil {
+			return err
+		}
+		hookPaths, err := m.verifiedHookPaths(artifact.ArtifactMetaDir, PostBatchHook, metadata, artifact)
+		if err != nil {
+			return err
+		}
+
+		hookContext := &HookContext{
+			ArtifactName:    artifact.Name,
+			ArtifactVersion: artifact.Version,
+			Operation:       "batch",
+			OS:              m.os,
+			Arch:            m.arch,
+			Vars:            m.hookVars,
+			MetaDir:         artifact.ArtifactMetaDir,
+			DataDir:         artifact.ArtifactDataDir,
+		}
+		for _, hookPath := range hookPaths {
+			digest, err := calculateFileHash(hookPath)
+			if err != nil {
+				return errutils.Wrapf(err, "failed to hash post-batch hook of %s", name)
+			}
+			if executed[digest] {
+				continue
+			}
+			executed[digest] = true
+
+			if err := m.hookExecutor.ExecuteHook(hookPath, hookContext); err != nil {
+				return fmt.Errorf("post-batch hook of %s failed: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
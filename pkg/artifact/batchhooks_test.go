@@ -0,0 +1,51 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutePostBatchHooks_Dedup(t *testing.T) {
+	dir := t.TempDir()
+	mgr := NewManager("linux", "amd64", dir, filepath.Join(dir, "install", artifactDataDir), filepath.Join(dir, "install", artifactMetaDir), filepath.Join(dir, "installed.db"))
+
+	// appendScript appends line to the log next to the database every time it runs
+	logPath := filepath.Join(dir, "post-batch.log")
+	appendScript := func(line string) string {
+		return fmt.Sprintf("os := import(\"os\")\nf := os.open_file(%q, os.o_append|os.o_create|os.o_wronly, 420)\nf.write_string(%q)\nf.close()\n", logPath, line+"\n")
+	}
+	install := func(name, script string) {
+		inputDir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0o755))
+		require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, name+".txt"), []byte(name), 0o644))
+		var hooks Hooks
+		if script != "" {
+			require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactMetaDir, "rebuild.tengo"), []byte(script), 0o644))
+			hooks = Hooks{PostBatchHook: {"rebuild.tengo"}}
+		}
+		artifactPath, err := NewPacker(name, "1.0.0", "linux", "amd64", "", "batch", nil, hooks, inputDir, t.TempDir()).Pack()
+		require.NoError(t, err)
+		desc := &model.IndexArtifactDescriptor{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + ".gotya"}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	}
+
+	install("fonts-a", appendScript("rebuild cache"))
+	install("fonts-b", appendScript("rebuild cache"))
+	install("index", appendScript("reindex"))
+	install("plain", "")
+	_, err := os.Stat(logPath)
+	require.True(t, os.IsNotExist(err), "post-batch hooks must not run on install")
+
+	require.NoError(t, mgr.ExecutePostBatchHooks([]string{"fonts-a", "plain", "fonts-b", "index", "unknown"}))
+	log, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, "rebuild cache\nreindex\n", string(log))
+}
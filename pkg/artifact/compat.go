@@ -0,0 +1,68 @@
+package artifact
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/hashicorp/go-version"
+)
+
+// checkDependencyCompatibility verifies that the installed dependencies satisfy the constraints
+// declared in the metadata of an extracted artifact. The installed database must be loaded.
+func (m *ManagerImpl) checkDependencyCompatibility(extractDir string) error {
+	metadata, err := m.parseMetadata(filepath.Join(extractDir, artifactMetaDir, m.metadataFileName()))
+	if err != nil {
+		return errutils.Wrap(err, "failed to parse metadata for dependency check")
+	}
+
+	var problems []string
+	for _, dep := range metadata.Dependencies {
+		installed := m.installDB.FindArtifact(dep.Name)
+		// Missing dependencies are tracked as placeholders on install, only installed versions are checked
+		if installed == nil || installed.Status != model.StatusInstalled || dep.VersionConstraint == "" {
+			continue
+		}
+
+		normalized, err := model.NormalizeConstraint(dep.VersionConstraint)
+		if err != nil {
+			return errutils.Wrapf(err, "invalid version constraint %q for dependency %s", dep.VersionConstraint, dep.Name)
+		}
+		ok, err := m.matchConstraint(installed.Version, normalized)
+		if err != nil {
+			return errutils.Wrapf(err, "invalid version constraint %q for dependency %s", dep.VersionConstraint, dep.Name)
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is required but %s is installed", describeDependency(dep), installed.Version))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errutils.Wrapf(ErrIncompatibleDependency, "%s@%s: %s", metadata.Name, metadata.Version, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// matchConstraint reports whether v satisfies a normalized version constraint under the configured ordering.
+// Versions the ordering cannot parse never match.
+func (m *ManagerImpl) matchConstraint(v, constraint string) (bool, error) {
+	if m.versionComparator != nil {
+		return model.MatchConstraint(v, constraint, m.versionComparator), nil
+	}
+	c, err := version.NewConstraint(constraint)
+	if err != nil {
+		return false, errutils.ErrValidation
+	}
+	parsed, err := version.NewVersion(v)
+	return err == nil && c.Check(parsed), nil
+}
+
+// describeDependency formats a dependency as "name constraint".
+func describeDependency(dep model.Dependency) string {
+	if dep.VersionConstraint == "" {
+		return dep.Name
+	}
+	return dep.Name + " " + dep.VersionConstraint
+}
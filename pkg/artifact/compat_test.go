@@ -0,0 +1,78 @@
+package artifact
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateArtifact_IncompatibleInstalledDependency(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, filepath.Join(tempDir, "install", artifactMetaDir), dbPath)
+	ctx := context.Background()
+
+	build := func(name, ver string, deps ...model.Dependency) (string, *model.IndexArtifactDescriptor) {
+		path := filepath.Join(tempDir, name+"-"+ver+".gotya")
+		setupTestArtifact(t, path, true, &Metadata{
+			Name: name, Version: ver, OS: "linux", Arch: "amd64", Description: "Compatibility test artifact", Dependencies: deps,
+		})
+		return path, &model.IndexArtifactDescriptor{Name: name, Version: ver, OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + "-" + ver + ".gotya"}
+	}
+
+	libV1Path, libV1 := build("lib", "1.0.0")
+	libV2Path, libV2 := build("lib", "2.0.0")
+	appV1Path, appV1 := build("app", "1.0.0", model.Dependency{Name: "lib", VersionConstraint: ">= 1.0"})
+	appV2Path, appV2 := build("app", "2.0.0", model.Dependency{Name: "lib", VersionConstraint: ">= 2.0"})
+
+	require.NoError(t, mgr.InstallArtifact(ctx, libV1, libV1Path, model.InstallationReasonAutomatic))
+	require.NoError(t, mgr.InstallArtifact(ctx, appV1, appV1Path, model.InstallationReasonManual))
+
+	err := mgr.UpdateArtifact(ctx, appV2Path, appV2)
+	require.ErrorIs(t, err, ErrIncompatibleDependency)
+	assert.Contains(t, err.Error(), "lib >= 2.0")
+	assert.Contains(t, err.Error(), "1.0.0 is installed")
+
+	installed := loadInstalledDB(t, dbPath).FindArtifact("app")
+	require.NotNil(t, installed)
+	assert.Equal(t, "1.0.0", installed.Version, "the old version must stay installed")
+	assert.DirExists(t, filepath.Join(dataDir, "app"))
+
+	// Once the dependency is updated the update goes through
+	require.NoError(t, mgr.UpdateArtifact(ctx, libV2Path, libV2))
+	require.NoError(t, mgr.UpdateArtifact(ctx, appV2Path, appV2))
+	assert.Equal(t, "2.0.0", loadInstalledDB(t, dbPath).FindArtifact("app").Version)
+}
+
+func TestUpdateArtifact_IncompatibleDependencyCustomComparator(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), dbPath)
+	mgr.SetVersionComparator(reverseSemverComparator{})
+	ctx := context.Background()
+
+	build := func(name, ver string, deps ...model.Dependency) (string, *model.IndexArtifactDescriptor) {
+		path := filepath.Join(tempDir, name+"-"+ver+".gotya")
+		setupTestArtifact(t, path, true, &Metadata{
+			Name: name, Version: ver, OS: "linux", Arch: "amd64", Description: "Compatibility test artifact", Dependencies: deps,
+		})
+		return path, &model.IndexArtifactDescriptor{Name: name, Version: ver, OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + "-" + ver + ".gotya"}
+	}
+
+	libPath, lib := build("lib", "2.0.0")
+	appV2Path, appV2 := build("app", "2.0.0", model.Dependency{Name: "lib", VersionConstraint: ">= 2.0"})
+	// In reverse order 1.0.0 is newer than 2.0.0, and lib 2.0.0 is older than 1.0
+	appV1Path, appV1 := build("app", "1.0.0", model.Dependency{Name: "lib", VersionConstraint: ">= 1.0"})
+
+	require.NoError(t, mgr.InstallArtifact(ctx, lib, libPath, model.InstallationReasonAutomatic))
+	require.NoError(t, mgr.InstallArtifact(ctx, appV2, appV2Path, model.InstallationReasonManual))
+
+	err := mgr.UpdateArtifact(ctx, appV1Path, appV1)
+	require.ErrorIs(t, err, ErrIncompatibleDependency)
+	assert.Contains(t, err.Error(), "lib >= 1.0")
+}
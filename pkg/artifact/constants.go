@@ -8,5 +8,7 @@ const (
 	artifactSuffix  = "gotya"
 	artifactMetaDir = "meta"
 	artifactDataDir = "data"
-	metadataFile    = "artifact.json"
 )
+
+// DefaultMetadataFile is the default name of the metadata file in an artifact's meta directory.
+const DefaultMetadataFile = "artifact.json"
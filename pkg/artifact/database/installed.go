@@ -3,12 +3,16 @@ package database
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/glorpus-work/gotya/pkg/errutils"
@@ -26,8 +30,10 @@ type InstalledManager interface {
 	AddArtifact(pkg *model.InstalledArtifact)
 	RemoveArtifact(name string) bool
 	GetInstalledArtifacts() []*model.InstalledArtifact
+	InstalledNames() []string
 	FilteredArtifacts(nameFilter string) []*model.InstalledArtifact
 	SetInstallationReason(name string, reason model.InstallationReason) error
+	CheckWritable() error
 }
 
 // InstalledManagerImpl represents the database of installed packages.
@@ -111,6 +117,37 @@ func (installedDB *InstalledManagerImpl) SetInstallationReason(name string, reas
 	return fmt.Errorf("artifact %s not found: %w", name, errutils.ErrArtifactNotFound)
 }
 
+// CheckWritable reports errutils.ErrDatabaseReadOnly if the database could not be saved, either
+// because the database file is marked read-only or because no file can be created next to it.
+// A database file or directory that does not exist yet is not an error.
+func (installedDB *InstalledManagerImpl) CheckWritable() error {
+	if installedDB.databasePath == "" {
+		return errutils.Wrap(errutils.ErrInvalidPath, "database path is not set")
+	}
+	cleanPath := filepath.Clean(installedDB.databasePath)
+
+	info, err := os.Stat(cleanPath)
+	if err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o222 == 0 {
+		return fmt.Errorf("database file %s is not writable (mode %s): %w", cleanPath, info.Mode().Perm(), errutils.ErrDatabaseReadOnly)
+	}
+
+	// Saving replaces the file through a temporary file, so the directory must be writable too
+	dbDir := filepath.Dir(cleanPath)
+	probe, err := os.CreateTemp(dbDir, ".gotya-db-probe-*")
+	switch {
+	case err == nil:
+		_ = probe.Close()
+		_ = os.Remove(probe.Name())
+		return nil
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS):
+		return fmt.Errorf("database directory %s is not writable: %w", dbDir, errutils.ErrDatabaseReadOnly)
+	default:
+		return fmt.Errorf("failed to check database directory %s: %w", dbDir, err)
+	}
+}
+
 // SaveDatabase saves the installed packages database.
 func (installedDB *InstalledManagerImpl) SaveDatabase() error {
 	if installedDB.databasePath == "" {
@@ -247,6 +284,21 @@ func (installedDB *InstalledManagerImpl) GetInstalledArtifacts() []*model.Instal
 	return artifacts
 }
 
+// InstalledNames returns the sorted names of all installed packages, excluding missing placeholders.
+func (installedDB *InstalledManagerImpl) InstalledNames() []string {
+	installedDB.rwMutex.RLock()
+	defer installedDB.rwMutex.RUnlock()
+
+	names := make([]string, 0, len(installedDB.Artifacts))
+	for _, artifact := range installedDB.Artifacts {
+		if artifact.Status == model.StatusInstalled {
+			names = append(names, artifact.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
 // FilteredArtifacts returns installed packages filtered by name (partial match, case-insensitive).
 func (installedDB *InstalledManagerImpl) FilteredArtifacts(nameFilter string) []*model.InstalledArtifact {
 	installedDB.rwMutex.RLock()
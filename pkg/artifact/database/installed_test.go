@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/glorpus-work/gotya/pkg/model"
 )
 
@@ -281,3 +282,43 @@ func TestSetInstallationReason(t *testing.T) {
 		assert.Equal(t, model.InstallationReason(""), found.InstallationReason)
 	})
 }
+
+func TestCheckWritable(t *testing.T) {
+	t.Run("missing database", func(t *testing.T) {
+		db := NewInstalledMangerWithPath(filepath.Join(t.TempDir(), "installed.db"))
+		assert.NoError(t, db.CheckWritable())
+	})
+
+	t.Run("writable database", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "installed.db")
+		db := NewInstalledMangerWithPath(dbPath)
+		require.NoError(t, db.SaveDatabase())
+		assert.NoError(t, db.CheckWritable())
+		entries, err := os.ReadDir(filepath.Dir(dbPath))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "the probe file must be removed")
+	})
+
+	t.Run("read-only file", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "installed.db")
+		db := NewInstalledMangerWithPath(dbPath)
+		require.NoError(t, db.SaveDatabase())
+		require.NoError(t, os.Chmod(dbPath, 0o444))
+
+		err := db.CheckWritable()
+		require.ErrorIs(t, err, errutils.ErrDatabaseReadOnly)
+		assert.Contains(t, err.Error(), dbPath)
+	})
+
+	t.Run("read-only directory", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("directory permissions are not enforced for root")
+		}
+		dir := t.TempDir()
+		db := NewInstalledMangerWithPath(filepath.Join(dir, "installed.db"))
+		require.NoError(t, os.Chmod(dir, 0o555))
+		t.Cleanup(func() { _ = os.Chmod(dir, 0o755) })
+
+		require.ErrorIs(t, db.CheckWritable(), errutils.ErrDatabaseReadOnly)
+	})
+}
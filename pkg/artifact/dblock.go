@@ -0,0 +1,94 @@
+package artifact
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+const (
+	// DefaultDatabaseLockTimeout is how long an operation waits for the installed database lock by default.
+	DefaultDatabaseLockTimeout = 30 * time.Second
+	// databaseLockSuffix is appended to the database path to name its lock file.
+	databaseLockSuffix = ".lock"
+)
+
+// SetDatabaseLockTimeout sets how long operations modifying the installed database wait for other
+// processes to release it. Values <= 0 use DefaultDatabaseLockTimeout.
+func (m *ManagerImpl) SetDatabaseLockTimeout(timeout time.Duration) {
+	m.databaseLockTimeout = timeout
+}
+
+// lockDB serializes a load-modify-save sequence on the installed database. It takes dbMu against other
+// operations of this manager and an advisory lock on a file next to the database against other managers
+// and processes, waiting until the configured timeout elapses or ctx is done. Readers use readDB instead.
+// The returned function releases both locks.
+func (m *ManagerImpl) lockDB(ctx context.Context) (func(), error) {
+	m.dbMu.Lock()
+	if m.installedDBPath == "" {
+		return m.dbMu.Unlock, nil
+	}
+
+	timeout := m.databaseLockTimeout
+	if timeout <= 0 {
+		timeout = DefaultDatabaseLockTimeout
+	}
+	file, err := acquireFileLock(ctx, m.installedDBPath+databaseLockSuffix, timeout)
+	if err != nil {
+		m.dbMu.Unlock()
+		return nil, err
+	}
+	return func() {
+		_ = unlockFile(file)
+		_ = file.Close()
+		m.dbMu.Unlock()
+	}, nil
+}
+
+// readDB loads the installed database for an operation that only reads it. It takes dbMu, so the load
+// does not replace installDB in the middle of another operation's load-modify-save sequence, but no file
+// lock, as the database file is replaced atomically when saved. The returned function releases dbMu and
+// must be called once the operation is done with installDB and the artifacts in it.
+func (m *ManagerImpl) readDB() (func(), error) {
+	m.dbMu.Lock()
+	if err := m.loadInstalledDB(); err != nil {
+		m.dbMu.Unlock()
+		return nil, err
+	}
+	return m.dbMu.Unlock, nil
+}
+
+// acquireFileLock opens or creates the lock file at lockPath and takes an exclusive advisory lock on it,
+// polling until it is free or timeout elapses. The lock is held until the returned file is unlocked or closed.
+func acquireFileLock(ctx context.Context, lockPath string, timeout time.Duration) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return nil, errutils.Wrapf(err, "failed to create directory for lock file %s", lockPath)
+	}
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, errutils.Wrapf(err, "failed to open lock file %s", lockPath)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, errutils.Wrapf(err, "failed to lock %s", lockPath)
+		}
+		if locked {
+			return file, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = file.Close()
+			return nil, errutils.Wrapf(ErrDatabaseLocked, "%s is held by another process", lockPath)
+		case <-time.After(operationLockPollInterval):
+		}
+	}
+}
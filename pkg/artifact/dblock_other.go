@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd) && !windows
+
+package artifact
+
+import "os"
+
+// tryLockFile always succeeds on platforms without supported file locking; only operations of the same
+// manager are serialized there.
+func tryLockFile(*os.File) (bool, error) {
+	return true, nil
+}
+
+// unlockFile implements the unlock counterpart of tryLockFile.
+func unlockFile(*os.File) error {
+	return nil
+}
@@ -0,0 +1,97 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallArtifact_SeparateManagersShareDatabase(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	metaDir := filepath.Join(tempDir, "install", artifactMetaDir)
+
+	// Each manager stands in for a separate gotya process, so only the database file lock serializes them
+	names := []string{"alpha", "beta", "gamma", "delta", "epsilon", "zeta"}
+	var wg sync.WaitGroup
+	errs := make(chan error, len(names))
+	for _, name := range names {
+		path := filepath.Join(tempDir, name+".gotya")
+		setupTestArtifact(t, path, true, &Metadata{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", Description: name})
+		desc := &model.IndexArtifactDescriptor{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + ".gotya"}
+		mgr := NewManager("linux", "amd64", tempDir, dataDir, metaDir, dbPath)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := mgr.InstallArtifact(context.Background(), desc, path, model.InstallationReasonManual); err != nil {
+				errs <- fmt.Errorf("%s: %w", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	db := loadInstalledDB(t, dbPath)
+	require.Len(t, db.GetInstalledArtifacts(), len(names))
+	for _, name := range names {
+		assert.NotNil(t, db.FindArtifact(name), name)
+	}
+	assert.FileExists(t, dbPath+databaseLockSuffix)
+}
+
+func TestLockDB_Timeout(t *testing.T) {
+	mgr := newLockTestManager(t)
+	mgr.SetDatabaseLockTimeout(100 * time.Millisecond)
+
+	// Another process holding the database lock
+	held, err := acquireFileLock(context.Background(), mgr.installedDBPath+databaseLockSuffix, time.Second)
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = mgr.SetArtifactManuallyInstalled("tool")
+	require.ErrorIs(t, err, ErrDatabaseLocked)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond, "locking should wait for the timeout")
+
+	err = mgr.UninstallArtifact(context.Background(), "tool", false)
+	require.ErrorIs(t, err, ErrDatabaseLocked, "a failed lock attempt must not keep the manager locked")
+
+	require.NoError(t, unlockFile(held))
+	require.NoError(t, held.Close())
+	err = mgr.SetArtifactManuallyInstalled("tool")
+	require.ErrorIs(t, err, errutils.ErrArtifactNotFound)
+}
+
+func TestLockDB_ReleasedAfterRollback(t *testing.T) {
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool.txt"), []byte("tool"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactMetaDir, "post-install.tengo"), []byte("notCallable := 1\nnotCallable()\n"), 0o644))
+	packer := NewPacker("failing", "1.0.0", "linux", "amd64", "", "failing hook", nil, Hooks{"post-install": {"post-install.tengo"}}, inputDir, tempDir)
+	artifactPath, err := packer.Pack()
+	require.NoError(t, err)
+
+	mgr := newLockTestManager(t)
+	desc := &model.IndexArtifactDescriptor{Name: "failing", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/failing.gotya"}
+	require.ErrorContains(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual), "post-install hook failed")
+
+	// The rolled back install released the lock for other processes
+	held, err := acquireFileLock(context.Background(), mgr.installedDBPath+databaseLockSuffix, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.NoError(t, held.Close())
+}
@@ -0,0 +1,125 @@
+package artifact
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/glorpus-work/gotya/internal/logger"
+	"github.com/glorpus-work/gotya/pkg/artifact/database"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// SetDeduplicateDataFiles makes installs replace data files with hardlinks to identical files already
+// installed by other artifacts: same hash and same permissions. Linked files are recorded with the file
+// they share their content with in InstalledFile.LinkedTo. Uninstalling an artifact only removes its links,
+// so the other artifacts keep their files. Files that cannot be linked, e.g. because they are on another
+// filesystem, are kept as copies. Disabled by default.
+func (m *ManagerImpl) SetDeduplicateDataFiles(enabled bool) {
+	m.deduplicateDataFiles = enabled
+}
+
+// linkDuplicateDataFiles replaces the data files of the artifact name installed to dataPath with hardlinks
+// to identical files of other installed artifacts and sets LinkedTo of the linked files.
+// The installed database must be loaded.
+func (m *ManagerImpl) linkDuplicateDataFiles(name, dataPath string, files []model.InstalledFile) {
+	// Links always point to the first installed copy, so LinkedTo is never a chain
+	candidates := make(map[string]string)
+	for _, other := range m.installDB.GetInstalledArtifacts() {
+		if other.Name == name || other.Status != model.StatusInstalled {
+			continue
+		}
+		for _, file := range other.DataFiles {
+			target := file.LinkedTo
+			if target == "" {
+				target = filepath.Join(other.ArtifactDataDir, filepath.FromSlash(file.Path))
+			}
+			if _, ok := candidates[file.Hash]; !ok {
+				candidates[file.Hash] = target
+			}
+		}
+	}
+
+	for i := range files {
+		target, ok := candidates[files[i].Hash]
+		if !ok {
+			continue
+		}
+		path := filepath.Join(dataPath, filepath.FromSlash(files[i].Path))
+		if err := linkIdenticalFile(target, path, files[i].Hash); err != nil {
+			logger.Debug("Keeping copy of duplicate data file", logger.Fields{
+				"artifact": name,
+				"file":     path,
+				"error":    err.Error(),
+			})
+			continue
+		}
+		files[i].LinkedTo = target
+	}
+}
+
+// linkIdenticalFile replaces path with a hardlink to target if both are regular files with the same
+// permissions and target still has the given hash. The link is created next to path and renamed over it,
+// so path is never missing.
+func linkIdenticalFile(target, path, hash string) error {
+	targetInfo, err := os.Lstat(target)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !targetInfo.Mode().IsRegular() || !info.Mode().IsRegular() {
+		return errors.New("not a regular file")
+	}
+	if os.SameFile(targetInfo, info) {
+		return nil
+	}
+	if targetInfo.Mode().Perm() != info.Mode().Perm() {
+		return fmt.Errorf("permissions %v and %v differ", targetInfo.Mode().Perm(), info.Mode().Perm())
+	}
+	// The shared file may have been modified since it was installed
+	if targetHash, err := calculateFileHash(target); err != nil || targetHash != hash {
+		return fmt.Errorf("%s no longer matches its recorded hash", target)
+	}
+
+	tempPath := path + ".gotya-link"
+	_ = os.Remove(tempPath)
+	if err := os.Link(target, tempPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// releaseSharedFiles updates the files of other artifacts linked to files of the removed artifact.
+// The first of them takes the place of the removed file and the others are linked to it instead.
+func releaseSharedFiles(db database.InstalledManager, artifact *model.InstalledArtifact) {
+	removed := make(map[string]bool, len(artifact.DataFiles))
+	for _, file := range artifact.DataFiles {
+		removed[filepath.Join(artifact.ArtifactDataDir, filepath.FromSlash(file.Path))] = true
+	}
+
+	successors := make(map[string]string)
+	for _, other := range db.GetInstalledArtifacts() {
+		if other.Name == artifact.Name {
+			continue
+		}
+		for i, file := range other.DataFiles {
+			if file.LinkedTo == "" || !removed[file.LinkedTo] {
+				continue
+			}
+			if successor, ok := successors[file.LinkedTo]; ok {
+				other.DataFiles[i].LinkedTo = successor
+				continue
+			}
+			successors[file.LinkedTo] = filepath.Join(other.ArtifactDataDir, filepath.FromSlash(file.Path))
+			other.DataFiles[i].LinkedTo = ""
+		}
+	}
+}
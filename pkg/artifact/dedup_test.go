@@ -0,0 +1,91 @@
+//go:build unix
+
+package artifact
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallArtifact_DeduplicateDataFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+	mgr.SetDeduplicateDataFiles(true)
+
+	// setupTestArtifact gives every artifact the same data files
+	for _, name := range []string{"first", "second", "third"} {
+		artifactPath := filepath.Join(tempDir, name+".gotya")
+		setupTestArtifact(t, artifactPath, true, &Metadata{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", Description: "shared data"})
+		desc := &model.IndexArtifactDescriptor{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + ".gotya"}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	}
+
+	dataFile := func(name string) string {
+		return filepath.Join(mgr.getArtifactDataInstallPath(&model.IndexArtifactDescriptor{Name: name}), "datafile1.bin")
+	}
+	linkedTo := func(name string) string {
+		t.Helper()
+		require.NoError(t, mgr.loadInstalledDB())
+		artifact := mgr.installDB.FindArtifact(name)
+		require.NotNil(t, artifact)
+		for _, file := range artifact.DataFiles {
+			if file.Path == "datafile1.bin" {
+				return file.LinkedTo
+			}
+		}
+		t.Fatalf("datafile1.bin is not recorded for %s", name)
+		return ""
+	}
+	sameFile := func(a, b string) bool {
+		t.Helper()
+		infoA, err := os.Stat(a)
+		require.NoError(t, err)
+		infoB, err := os.Stat(b)
+		require.NoError(t, err)
+		return os.SameFile(infoA, infoB)
+	}
+
+	assert.True(t, sameFile(dataFile("first"), dataFile("second")))
+	assert.True(t, sameFile(dataFile("first"), dataFile("third")))
+	assert.Empty(t, linkedTo("first"))
+	assert.Equal(t, dataFile("first"), linkedTo("second"))
+	assert.Equal(t, dataFile("first"), linkedTo("third"), "links point to the first copy, not to other links")
+
+	require.NoError(t, mgr.UninstallArtifact(context.Background(), "first", false))
+
+	assert.NoFileExists(t, dataFile("first"))
+	content, err := os.ReadFile(dataFile("second"))
+	require.NoError(t, err)
+	assert.Equal(t, "test data 1", string(content))
+	assert.True(t, sameFile(dataFile("second"), dataFile("third")))
+	assert.Empty(t, linkedTo("second"), "the first remaining link takes the place of the removed file")
+	assert.Equal(t, dataFile("second"), linkedTo("third"))
+
+	results, err := mgr.VerifyInstalled(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestInstallArtifact_DeduplicationDisabledByDefault(t *testing.T) {
+	tempDir := t.TempDir()
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+
+	for _, name := range []string{"first", "second"} {
+		artifactPath := filepath.Join(tempDir, name+".gotya")
+		setupTestArtifact(t, artifactPath, true, &Metadata{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", Description: "shared data"})
+		desc := &model.IndexArtifactDescriptor{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + ".gotya"}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	}
+
+	first, err := os.Stat(filepath.Join(mgr.getArtifactDataInstallPath(&model.IndexArtifactDescriptor{Name: "first"}), "datafile1.bin"))
+	require.NoError(t, err)
+	second, err := os.Stat(filepath.Join(mgr.getArtifactDataInstallPath(&model.IndexArtifactDescriptor{Name: "second"}), "datafile1.bin"))
+	require.NoError(t, err)
+	assert.False(t, os.SameFile(first, second))
+}
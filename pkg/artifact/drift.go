@@ -0,0 +1,58 @@
+package artifact
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// DriftEntry describes an installed artifact whose on-disk metadata disagrees with its database record.
+type DriftEntry struct {
+	Name            string
+	RecordedVersion string // Version in the installed database
+	OnDiskVersion   string // Version in the installed metadata file, empty if it could not be read
+	Err             error  // Set if the installed metadata file could not be read
+}
+
+// DetectVersionDrift compares the version of each installed artifact in the database with the version
+// in its installed metadata file, e.g. to find artifacts whose files were swapped manually.
+// Artifacts whose metadata file is missing or unreadable are reported with Err set.
+// Entries are sorted by name.
+func (m *ManagerImpl) DetectVersionDrift() ([]DriftEntry, error) {
+	unlock, err := m.readDB()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var drift []DriftEntry
+	for _, artifact := range m.installDB.GetInstalledArtifacts() {
+		if artifact.Status != model.StatusInstalled {
+			continue
+		}
+		metadata, err := m.parseMetadata(m.installedMetadataPath(artifact))
+		switch {
+		case err != nil:
+			drift = append(drift, DriftEntry{Name: artifact.Name, RecordedVersion: artifact.Version, Err: err})
+		case metadata.Version != artifact.Version:
+			drift = append(drift, DriftEntry{Name: artifact.Name, RecordedVersion: artifact.Version, OnDiskVersion: metadata.Version})
+		}
+	}
+
+	slices.SortFunc(drift, func(a, b DriftEntry) int { return strings.Compare(a.Name, b.Name) })
+	return drift, nil
+}
+
+// installedMetadataPath returns the path of the metadata file of an installed artifact. Records without
+// a meta directory fall back to the directory the artifact would be installed to now.
+func (m *ManagerImpl) installedMetadataPath(artifact *model.InstalledArtifact) string {
+	metaDir := artifact.ArtifactMetaDir
+	if metaDir == "" {
+		metaDir = m.getArtifactMetaInstallPath(&model.IndexArtifactDescriptor{
+			Name: artifact.Name, Version: artifact.Version, OS: artifact.OS, Arch: artifact.Arch,
+		})
+	}
+	return filepath.Join(metaDir, m.metadataFileName())
+}
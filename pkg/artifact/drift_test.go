@@ -0,0 +1,50 @@
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectVersionDrift(t *testing.T) {
+	tempDir := t.TempDir()
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+
+	for _, name := range []string{"stable", "swapped", "broken"} {
+		artifactPath := filepath.Join(tempDir, name+".gotya")
+		setupTestArtifact(t, artifactPath, true, &Metadata{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64"})
+		desc := &model.IndexArtifactDescriptor{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + ".gotya"}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	}
+
+	drift, err := mgr.DetectVersionDrift()
+	require.NoError(t, err)
+	assert.Empty(t, drift)
+
+	// Simulate a manual file swap by rewriting the installed metadata version
+	swappedPath := filepath.Join(mgr.getArtifactMetaInstallPath(&model.IndexArtifactDescriptor{Name: "swapped"}), DefaultMetadataFile)
+	metadata, err := ParseMetadataFromPath(swappedPath)
+	require.NoError(t, err)
+	metadata.Version = "2.0.0"
+	content, err := json.Marshal(metadata)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(swappedPath, content, 0o644))
+
+	require.NoError(t, os.Remove(filepath.Join(mgr.getArtifactMetaInstallPath(&model.IndexArtifactDescriptor{Name: "broken"}), DefaultMetadataFile)))
+
+	drift, err = mgr.DetectVersionDrift()
+	require.NoError(t, err)
+	require.Len(t, drift, 2)
+
+	assert.Equal(t, "broken", drift[0].Name)
+	assert.Equal(t, "1.0.0", drift[0].RecordedVersion)
+	assert.ErrorIs(t, drift[0].Err, os.ErrNotExist)
+
+	assert.Equal(t, DriftEntry{Name: "swapped", RecordedVersion: "1.0.0", OnDiskVersion: "2.0.0"}, drift[1])
+}
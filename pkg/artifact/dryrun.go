@@ -0,0 +1,97 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// InstallPreview describes what InstallArtifact would do with an artifact.
+type InstallPreview struct {
+	MetaDir   string                // Directory the meta files would be installed to
+	DataDir   string                // Directory the data files would be installed to
+	MetaFiles []model.InstalledFile // Meta files that would be written, relative to MetaDir and sorted by path
+	DataFiles []model.InstalledFile // Data files that would be written, relative to DataDir and sorted by path
+	// AlreadyInstalled is set if the artifact is installed, in which case InstallArtifact only updates
+	// its installation reason and writes no files
+	AlreadyInstalled bool
+	// Overwrites lists the files that would be written but already exist, e.g. left over from an earlier install
+	Overwrites []string
+}
+
+// InstallArtifactDryRun extracts and verifies an artifact like InstallArtifact and reports where its files
+// would be installed, without changing the install directories or the installed database. Hooks are not run.
+// The temporary extract directory is removed afterwards.
+func (m *ManagerImpl) InstallArtifactDryRun(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string) (preview *InstallPreview, err error) {
+	if desc == nil {
+		return nil, errutils.Wrap(errutils.ErrValidation, "artifact descriptor cannot be nil")
+	}
+	if err := desc.VerifyWithLimits(m.nameLimits); err != nil {
+		return nil, errutils.Wrap(err, "invalid artifact descriptor")
+	}
+	if err := m.checkInstallPaths(desc); err != nil {
+		return nil, err
+	}
+	if localPath == "" {
+		return nil, errutils.Wrap(errutils.ErrValidation, "local path cannot be empty")
+	}
+
+	extractDir, err := os.MkdirTemp("", fmt.Sprintf("gotya-extract-%s-%s", desc.Name, desc.Version))
+	if err != nil {
+		return nil, errutils.Wrap(err, "failed to create extract directory")
+	}
+	defer func() { err = m.removeExtractDir(extractDir, err) }()
+
+	if err := m.extractAndVerify(ctx, desc, localPath, extractDir); err != nil {
+		return nil, err
+	}
+	metadataPath := filepath.Join(extractDir, artifactMetaDir, m.metadataFileName())
+	metadata, err := m.parseMetadata(metadataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	metaFiles, dataFiles, err := buildInstalledFileEntries(metadata, metadataPath)
+	if err != nil {
+		return nil, err
+	}
+	byPath := func(a, b model.InstalledFile) int { return strings.Compare(a.Path, b.Path) }
+	slices.SortFunc(metaFiles, byPath)
+	slices.SortFunc(dataFiles, byPath)
+
+	unlock, err := m.readDB()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	existing := m.installDB.FindArtifact(desc.Name)
+
+	preview = &InstallPreview{
+		MetaDir:          m.getArtifactMetaInstallPath(desc),
+		DataDir:          m.getArtifactDataInstallPath(desc),
+		MetaFiles:        metaFiles,
+		DataFiles:        dataFiles,
+		AlreadyInstalled: existing != nil && existing.Status == model.StatusInstalled,
+	}
+	if !preview.AlreadyInstalled {
+		preview.Overwrites = append(existingFiles(preview.MetaDir, metaFiles), existingFiles(preview.DataDir, dataFiles)...)
+	}
+	return preview, nil
+}
+
+// existingFiles returns the paths of the files below dir that already exist.
+func existingFiles(dir string, files []model.InstalledFile) []string {
+	var paths []string
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if _, err := os.Lstat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
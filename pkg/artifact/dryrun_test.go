@@ -0,0 +1,60 @@
+package artifact
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallArtifactDryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	tmpDir := filepath.Join(tempDir, "tmp")
+	require.NoError(t, os.MkdirAll(tmpDir, 0o755))
+	t.Setenv("TMPDIR", tmpDir)
+
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	metaDir := filepath.Join(tempDir, "install", artifactMetaDir)
+	dbPath := filepath.Join(tempDir, "installed.db")
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, metaDir, dbPath)
+	artifactPath := filepath.Join(tempDir, "tool.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64"})
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+
+	preview, err := mgr.InstallArtifactDryRun(context.Background(), desc, artifactPath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(metaDir, "tool"), preview.MetaDir)
+	assert.Equal(t, filepath.Join(dataDir, "tool"), preview.DataDir)
+	require.Len(t, preview.DataFiles, 2)
+	assert.Equal(t, "datafile1.bin", preview.DataFiles[0].Path)
+	assert.Equal(t, "datafile2.bin", preview.DataFiles[1].Path)
+	require.NotEmpty(t, preview.MetaFiles)
+	assert.Equal(t, DefaultMetadataFile, preview.MetaFiles[0].Path)
+	assert.False(t, preview.AlreadyInstalled)
+	assert.Empty(t, preview.Overwrites)
+
+	// Nothing was installed or recorded and the extract directory is gone
+	assert.NoDirExists(t, filepath.Join(tempDir, "install"))
+	assert.NoFileExists(t, dbPath)
+	entries, err := os.ReadDir(tmpDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Files left over in the install directories would be overwritten
+	require.NoError(t, os.MkdirAll(preview.DataDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(preview.DataDir, "datafile2.bin"), []byte("stale"), 0o644))
+	preview, err = mgr.InstallArtifactDryRun(context.Background(), desc, artifactPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dataDir, "tool", "datafile2.bin")}, preview.Overwrites)
+	require.NoError(t, os.RemoveAll(filepath.Join(tempDir, "install")))
+
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	preview, err = mgr.InstallArtifactDryRun(context.Background(), desc, artifactPath)
+	require.NoError(t, err)
+	assert.True(t, preview.AlreadyInstalled)
+	assert.Empty(t, preview.Overwrites)
+}
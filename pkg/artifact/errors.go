@@ -2,6 +2,9 @@ package artifact
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
 )
 
 // Error types for specific error conditions.
@@ -25,12 +28,25 @@ type (
 		ActualVersion   string
 	}
 
+	// MetadataMismatchError is returned when a field of the embedded metadata differs from the descriptor.
+	MetadataMismatchError struct {
+		Field    string
+		Expected string
+		Actual   string
+	}
+
 	// FileOperationError is returned for file operation errors.
 	FileOperationError struct {
 		Path string
 		Op   string
 		Err  error
 	}
+
+	// SharedFileError is returned when several installed artifacts claim the same file.
+	SharedFileError struct {
+		Path   string
+		Owners []FileOwner
+	}
 )
 
 // Common artifact errors.
@@ -51,23 +67,39 @@ var (
 
 	// Artifact installation errors.
 	// ErrArtifactInvalid is imported from artifact/errors/errors.go.
-	ErrSourceDirEmpty       = fmt.Errorf("source directory path cannot be empty")
-	ErrOutputDirEmpty       = fmt.Errorf("output directory path cannot be empty")
-	ErrArtifactNameEmpty    = fmt.Errorf("artifact name cannot be empty")
-	ErrArtifactVersionEmpty = fmt.Errorf("artifact version cannot be empty")
-	ErrNotADirectory        = fmt.Errorf("path is not a directory")
-	ErrNoFilesFound         = fmt.Errorf("no files found to artifact")
-	ErrOutputFileExists     = fmt.Errorf("output file already exists")
-	ErrArtifactTooSmall     = fmt.Errorf("artifact file is too small to be valid")
-	ErrDescriptionRequired  = fmt.Errorf("artifact description is required")
+	ErrSourceDirEmpty         = fmt.Errorf("source directory path cannot be empty")
+	ErrOutputDirEmpty         = fmt.Errorf("output directory path cannot be empty")
+	ErrArtifactNameEmpty      = fmt.Errorf("artifact name cannot be empty")
+	ErrArtifactVersionEmpty   = fmt.Errorf("artifact version cannot be empty")
+	ErrNotADirectory          = fmt.Errorf("path is not a directory")
+	ErrNoFilesFound           = fmt.Errorf("no files found to artifact")
+	ErrOutputFileExists       = fmt.Errorf("output file already exists")
+	ErrArtifactTooSmall       = fmt.Errorf("artifact file is too small to be valid")
+	ErrDescriptionRequired    = fmt.Errorf("artifact description is required")
+	ErrDisallowedFileMode     = fmt.Errorf("artifact contains a file with a disallowed mode")
+	ErrEssentialArtifact      = fmt.Errorf("artifact is essential")
+	ErrUnsafeHookScript       = fmt.Errorf("hook script references a path outside the artifact")
+	ErrInvalidHookScript      = fmt.Errorf("hook script does not compile")
+	ErrDisallowedHookUsage    = fmt.Errorf("hook script uses a module or builtin that is not allowed")
+	ErrSignatureMissing       = fmt.Errorf("artifact is not signed")
+	ErrSignatureInvalid       = fmt.Errorf("artifact signature is invalid")
+	ErrOperationLocked        = fmt.Errorf("another gotya operation is in progress")
+	ErrDatabaseLocked         = fmt.Errorf("installed database is locked by another process")
+	ErrIncompatibleDependency = fmt.Errorf("installed dependencies are incompatible")
+	ErrInsufficientInodes     = fmt.Errorf("not enough free inodes to install artifact")
+	ErrUninstallIncomplete    = fmt.Errorf("files remain after uninstall")
+	ErrHookTampered           = fmt.Errorf("hook script does not match its recorded digest")
+	ErrArtifactPlaceholder    = fmt.Errorf("artifact is a placeholder for a missing dependency")
+	ErrRequirementNotMet      = fmt.Errorf("host does not meet the requirements of the artifact")
 
 	// Metadata related errors.
 	ErrMetadataMissing      = fmt.Errorf("artifact is missing required metadata (artifact.json)")
 	ErrMetadataFileNotFound = fmt.Errorf("artifact metadata file not found")
 	ErrMetadataNotFound     = fmt.Errorf("metadata not found in artifact")
+	ErrMetadataTooLarge     = fmt.Errorf("artifact metadata exceeds the maximum size")
 
 	// Archive and extraction errors.
-	ErrUnsupportedArchiveFormat = fmt.Errorf("unsupported archive format (only .tar.gz and .tgz files are supported)")
+	ErrUnsupportedArchiveFormat = fmt.Errorf("unsupported archive format (only gzip, zstd and xz compressed tar archives are supported)")
 	ErrInvalidFilePath          = fmt.Errorf("invalid file path in archive")
 	ErrInvalidSymlinkTarget     = fmt.Errorf("invalid symlink target: points outside the target directory")
 	ErrInvalidLinkTarget        = fmt.Errorf("invalid link target in archive")
@@ -117,6 +149,17 @@ func (e *VerificationError) Error() string {
 		e.ExpectedName, e.ExpectedVersion, e.ActualName, e.ActualVersion)
 }
 
+// Error implements the error interface for MetadataMismatchError.
+func (e *MetadataMismatchError) Error() string {
+	return fmt.Sprintf("metadata mismatch: %s is %q in the artifact but the descriptor expects %q",
+		e.Field, e.Actual, e.Expected)
+}
+
+// Unwrap returns ErrArtifactInvalid, so mismatches are reported as invalid artifacts.
+func (e *MetadataMismatchError) Unwrap() error {
+	return errutils.ErrArtifactInvalid
+}
+
 // Error implements the error interface for FileOperationError.
 func (e *FileOperationError) Error() string {
 	return fmt.Sprintf("failed to %s %s: %v.", e.Op, e.Path, e.Err)
@@ -127,6 +170,15 @@ func (e *FileOperationError) Unwrap() error {
 	return e.Err
 }
 
+// Error implements the error interface for SharedFileError.
+func (e *SharedFileError) Error() string {
+	names := make([]string, 0, len(e.Owners))
+	for _, owner := range e.Owners {
+		names = append(names, owner.Artifact.Name)
+	}
+	return fmt.Sprintf("%s is claimed by several artifacts: %s", e.Path, strings.Join(names, ", "))
+}
+
 // NewPathTraversalError creates a new PathTraversalError.
 func NewPathTraversalError(path string) error {
 	return &PathTraversalError{Path: path}
@@ -0,0 +1,31 @@
+package artifact
+
+import (
+	"context"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+// SetArtifactEssential marks or unmarks an installed artifact as essential.
+// Essential artifacts are refused by UninstallArtifact and skipped by orphan cleanup.
+func (m *ManagerImpl) SetArtifactEssential(artifactName string, essential bool) error {
+	unlock, err := m.lockDB(context.Background())
+	if err != nil {
+		return errutils.Wrapf(err, "failure to change essential flag for %s", artifactName)
+	}
+	defer unlock()
+	if err := m.loadInstalledDB(); err != nil {
+		return errutils.Wrapf(err, "failure to change essential flag for %s", artifactName)
+	}
+	artifact := m.installDB.FindArtifact(artifactName)
+	if artifact == nil {
+		return errutils.Wrapf(errutils.ErrArtifactNotFound, "failure to change essential flag for %s", artifactName)
+	}
+	artifact.Essential = essential
+	return m.installDB.SaveDatabase()
+}
+
+// SetAllowEssentialRemoval overrides the protection of essential artifacts for uninstall and cleanup.
+func (m *ManagerImpl) SetAllowEssentialRemoval(allow bool) {
+	m.allowEssentialRemoval = allow
+}
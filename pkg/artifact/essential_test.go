@@ -0,0 +1,96 @@
+package artifact
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUninstallArtifact_Essential(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, filepath.Join(tempDir, "install", artifactMetaDir), dbPath)
+
+	artifactPath := filepath.Join(tempDir, "essential.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{
+		Name:        "core",
+		Version:     "1.0.0",
+		OS:          "linux",
+		Arch:        "amd64",
+		Description: "Essential test artifact",
+	})
+	desc := &model.IndexArtifactDescriptor{Name: "core", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/core.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	require.NoError(t, mgr.SetArtifactEssential("core", true))
+
+	err := mgr.UninstallArtifact(context.Background(), "core", false)
+	require.ErrorIs(t, err, ErrEssentialArtifact)
+	assert.True(t, loadInstalledDB(t, dbPath).IsArtifactInstalled("core"))
+	assert.DirExists(t, filepath.Join(dataDir, "core"))
+
+	err = mgr.UninstallArtifact(context.Background(), "core", true)
+	require.ErrorIs(t, err, ErrEssentialArtifact, "purge must not bypass the protection")
+
+	mgr.SetAllowEssentialRemoval(true)
+	require.NoError(t, mgr.UninstallArtifact(context.Background(), "core", false))
+	assert.False(t, loadInstalledDB(t, dbPath).IsArtifactInstalled("core"))
+}
+
+func TestUpdateArtifact_KeepsEssential(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), dbPath)
+
+	for _, v := range []string{"1.0.0", "2.0.0"} {
+		setupTestArtifact(t, filepath.Join(tempDir, "core-"+v+".gotya"), true, &Metadata{
+			Name: "core", Version: v, OS: "linux", Arch: "amd64", Description: "Essential test artifact",
+		})
+	}
+	descV1 := &model.IndexArtifactDescriptor{Name: "core", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/core-1.gotya"}
+	descV2 := &model.IndexArtifactDescriptor{Name: "core", Version: "2.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/core-2.gotya"}
+
+	require.NoError(t, mgr.InstallArtifact(context.Background(), descV1, filepath.Join(tempDir, "core-1.0.0.gotya"), model.InstallationReasonManual))
+	require.NoError(t, mgr.SetArtifactEssential("core", true))
+	require.NoError(t, mgr.UpdateArtifact(context.Background(), filepath.Join(tempDir, "core-2.0.0.gotya"), descV2))
+
+	updated := loadInstalledDB(t, dbPath).FindArtifact("core")
+	require.NotNil(t, updated)
+	assert.Equal(t, "2.0.0", updated.Version)
+	assert.True(t, updated.Essential)
+}
+
+func TestGetOrphanedAutomaticArtifacts_SkipsEssential(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, artifactDataDir), filepath.Join(tempDir, artifactMetaDir), dbPath)
+
+	essential := createTestInstalledArtifact(t, "essential-orphan", "1.0.0", nil)
+	essential.InstallationReason = model.InstallationReasonAutomatic
+	essential.Essential = true
+	regular := createTestInstalledArtifact(t, "regular-orphan", "1.0.0", nil)
+	regular.InstallationReason = model.InstallationReasonAutomatic
+	setupTestDatabaseWithArtifacts(t, dbPath, []*model.InstalledArtifact{essential, regular})
+
+	orphaned, err := mgr.GetOrphanedAutomaticArtifacts()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"regular-orphan"}, orphaned)
+
+	mgr.SetAllowEssentialRemoval(true)
+	orphaned, err = mgr.GetOrphanedAutomaticArtifacts()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"essential-orphan", "regular-orphan"}, orphaned)
+}
+
+func TestSetArtifactEssential_ArtifactNotFound(t *testing.T) {
+	tempDir := t.TempDir()
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, artifactDataDir), filepath.Join(tempDir, artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+
+	err := mgr.SetArtifactEssential("missing", true)
+	require.ErrorIs(t, err, errutils.ErrArtifactNotFound)
+}
@@ -0,0 +1,144 @@
+package artifact
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
+	"github.com/d5/tengo/v2/stdlib"
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+// hookModules are the modules gotya provides to every hook script. They are always allowed.
+var hookModules = []string{"context", "dirs"}
+
+// HookAllowlist restricts the Tengo standard library modules and builtin functions hook scripts may use.
+// A nil list does not restrict that kind, an empty list allows none of them.
+type HookAllowlist struct {
+	Modules  []string
+	Builtins []string
+}
+
+// Validate checks that the allowlist only names existing Tengo modules and builtin functions.
+func (a HookAllowlist) Validate() error {
+	for _, module := range a.Modules {
+		if !slices.Contains(stdlib.AllModuleNames(), module) && !slices.Contains(hookModules, module) {
+			return errutils.Wrapf(errutils.ErrValidation, "unknown hook module %q in allowlist", module)
+		}
+	}
+	for _, builtin := range a.Builtins {
+		if !isTengoBuiltin(builtin) {
+			return errutils.Wrapf(errutils.ErrValidation, "unknown hook builtin %q in allowlist", builtin)
+		}
+	}
+	return nil
+}
+
+// allowsModule reports whether hook scripts may import the module.
+func (a HookAllowlist) allowsModule(module string) bool {
+	return a.Modules == nil || slices.Contains(hookModules, module) || slices.Contains(a.Modules, module)
+}
+
+// allowsBuiltin reports whether hook scripts may use the builtin function.
+func (a HookAllowlist) allowsBuiltin(builtin string) bool {
+	return a.Builtins == nil || slices.Contains(a.Builtins, builtin)
+}
+
+// CheckHookAllowlist statically checks that a hook script only imports allowed modules and only calls allowed
+// builtin functions. Builtins are found in the compiled bytecode, so local variables shadowing a builtin are not
+// reported. It returns the first disallowed usage in the script, wrapped in ErrDisallowedHookUsage.
+func CheckHookAllowlist(name string, content []byte, allowlist HookAllowlist) error {
+	fileSet := parser.NewFileSet()
+	file := fileSet.AddFile(name, -1, len(content))
+	parsed, err := parser.NewParser(file, content, nil).ParseFile()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidHookScript, err)
+	}
+
+	// Allowed modules are stubbed: only the imports of the hook itself matter, not what source modules use.
+	modules := tengo.NewModuleMap()
+	for _, module := range append(stdlib.AllModuleNames(), hookModules...) {
+		if allowlist.allowsModule(module) {
+			modules.AddBuiltinModule(module, map[string]tengo.Object{})
+		}
+	}
+	compiler := tengo.NewCompiler(file, nil, nil, modules, nil)
+	if err := compiler.Compile(parsed); err != nil {
+		var compileErr *tengo.CompilerError
+		if errors.As(err, &compileErr) {
+			if node, ok := compileErr.Node.(*parser.ImportExpr); ok {
+				return fmt.Errorf("%w: %s: module %q", ErrDisallowedHookUsage, fileSet.Position(node.Pos()), node.ModuleName)
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrInvalidHookScript, err)
+	}
+
+	bytecode := compiler.Bytecode()
+	functions := []*tengo.CompiledFunction{bytecode.MainFunction}
+	for _, constant := range bytecode.Constants {
+		if fn, ok := constant.(*tengo.CompiledFunction); ok {
+			functions = append(functions, fn)
+		}
+	}
+	builtins := tengo.GetAllBuiltinFunctions()
+	first, firstPos := "", parser.NoPos
+	for _, fn := range functions {
+		for ip := 0; ip < len(fn.Instructions); {
+			op := fn.Instructions[ip]
+			operands, read := parser.ReadOperands(parser.OpcodeOperands[op], fn.Instructions[ip+1:])
+			if op == parser.OpGetBuiltin {
+				builtin := builtins[operands[0]].Name
+				if pos := fn.SourcePos(ip); !allowlist.allowsBuiltin(builtin) && (first == "" || pos < firstPos) {
+					first, firstPos = builtin, pos
+				}
+			}
+			ip += 1 + read
+		}
+	}
+	if first != "" {
+		return fmt.Errorf("%w: %s: builtin %q", ErrDisallowedHookUsage, fileSet.Position(firstPos), first)
+	}
+	return nil
+}
+
+// isTengoBuiltin reports whether name is a Tengo builtin function.
+func isTengoBuiltin(name string) bool {
+	return slices.ContainsFunc(tengo.GetAllBuiltinFunctions(), func(fn *tengo.BuiltinFunction) bool {
+		return fn.Name == name
+	})
+}
+
+// SetHookAllowlist restricts the modules and builtin functions hook scripts may use. Packing fails with
+// ErrDisallowedHookUsage if a hook script uses anything else. By default hooks are not restricted.
+func (p *Packer) SetHookAllowlist(allowlist HookAllowlist) error {
+	if err := allowlist.Validate(); err != nil {
+		return err
+	}
+	p.hookAllowlist = &allowlist
+	return nil
+}
+
+// checkHookAllowlist checks all referenced hook scripts against the configured allowlist.
+func (p *Packer) checkHookAllowlist() error {
+	if p.hookAllowlist == nil {
+		return nil
+	}
+
+	for _, script := range p.hooks.Scripts() {
+		content, err := os.ReadFile(filepath.Join(p.inputDir, artifactMetaDir, script))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return errutils.Wrapf(err, "failed to read hook script %s", script)
+		}
+		if err := CheckHookAllowlist(script, content, *p.hookAllowlist); err != nil {
+			return err
+		}
+	}
+	return nil
+}
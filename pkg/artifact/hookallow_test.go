@@ -0,0 +1,97 @@
+package artifact
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHookAllowlist(t *testing.T) {
+	allowlist := HookAllowlist{Modules: []string{"fmt", "enum"}, Builtins: []string{"len"}}
+
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{
+			name:   "allowed modules and builtins",
+			script: "fmt := import(\"fmt\")\nenum := import(\"enum\")\nctx := import(\"context\")\nfmt.println(len(ctx.artifact_name))\n",
+		},
+		{
+			name:     "disallowed module",
+			script:   "fmt := import(\"fmt\")\nos := import(\"os\")\nos.remove(\"x\")\n",
+			expected: `post-install.tengo:2:7: module "os"`,
+		},
+		{
+			name:     "disallowed builtin",
+			script:   "x := [1, 2]\nx = append(x, 3)\n",
+			expected: `post-install.tengo:2:5: builtin "append"`,
+		},
+		{
+			name:   "local variable shadowing a builtin",
+			script: "f := func(append) { return append(1) }\nf(func(x) { return x })\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckHookAllowlist("post-install.tengo", []byte(tt.script), allowlist)
+			if tt.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrDisallowedHookUsage)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+
+	t.Run("nil lists do not restrict", func(t *testing.T) {
+		require.NoError(t, CheckHookAllowlist("hook.tengo", []byte("os := import(\"os\")\nappend([], 1)\n"), HookAllowlist{}))
+	})
+}
+
+func TestPacker_Pack_HookAllowlist(t *testing.T) {
+	setup := func(t *testing.T, script string) (string, string) {
+		tempDir := t.TempDir()
+		inputDir := filepath.Join(tempDir, "input")
+		outputDir := filepath.Join(tempDir, "output")
+		require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0755))
+		require.NoError(t, os.MkdirAll(outputDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactMetaDir, "post-install.tengo"), []byte(script), 0644))
+		return inputDir, outputDir
+	}
+	hooks := Hooks{"post-install": {"post-install.tengo"}}
+	allowlist := HookAllowlist{Modules: []string{"fmt"}}
+
+	t.Run("allowed module packs", func(t *testing.T) {
+		inputDir, outputDir := setup(t, "fmt := import(\"fmt\")\nfmt.println(\"installed\")\n")
+		p := NewPacker("allow", "1.0.0", "linux", "amd64", "", "allowlist test", nil, hooks, inputDir, outputDir)
+		require.NoError(t, p.SetHookAllowlist(allowlist))
+
+		outputFile, err := p.Pack()
+		require.NoError(t, err)
+		assert.FileExists(t, outputFile)
+	})
+
+	t.Run("disallowed module rejects", func(t *testing.T) {
+		inputDir, outputDir := setup(t, "exec := import(\"os\").exec\nexec(\"rm\")\n")
+		p := NewPacker("allow", "1.0.0", "linux", "amd64", "", "allowlist test", nil, hooks, inputDir, outputDir)
+		require.NoError(t, p.SetHookAllowlist(allowlist))
+
+		_, err := p.Pack()
+		require.ErrorIs(t, err, ErrDisallowedHookUsage)
+		assert.Contains(t, err.Error(), `module "os"`)
+		assert.NoFileExists(t, filepath.Join(outputDir, "allow_1.0.0_linux_amd64.gotya"))
+	})
+
+	t.Run("unknown names are rejected", func(t *testing.T) {
+		p := NewPacker("allow", "1.0.0", "linux", "amd64", "", "allowlist test", nil, hooks, t.TempDir(), t.TempDir())
+		require.ErrorIs(t, p.SetHookAllowlist(HookAllowlist{Modules: []string{"net"}}), errutils.ErrValidation)
+		require.ErrorIs(t, p.SetHookAllowlist(HookAllowlist{Builtins: []string{"eval"}}), errutils.ErrValidation)
+	})
+}
@@ -0,0 +1,54 @@
+package artifact
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// verifiedHookPaths resolves the hook scripts of hookType below metaDir like resolveHookPaths and checks
+// each against its recorded SHA-256 digest, so a script modified after installation is never run.
+// All scripts are checked before any of them runs. The digests come from installed, the artifact's
+// database record, if given and otherwise from the file hashes in metadata. Scripts without a recorded
+// digest are not checked, and missing scripts are left to the hook executor to report.
+func (m *ManagerImpl) verifiedHookPaths(metaDir, hookType string, metadata *Metadata, installed *model.InstalledArtifact) ([]string, error) {
+	hookPaths := m.resolveHookPaths(metaDir, hookType, metadata)
+	hookFiles := metadata.Hooks.ForPlatform(hookType, m.os, m.arch)
+	for i, hookPath := range hookPaths {
+		want, ok := recordedHookDigest(hookFiles[i], metadata, installed)
+		if !ok {
+			continue
+		}
+
+		got, err := calculateFileHash(hookPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errutils.Wrapf(err, "failed to hash %s hook %s", hookType, hookPath)
+		}
+		if !strings.EqualFold(got, want) {
+			return nil, fmt.Errorf("%s hook %s has digest %s, recorded %s: %w", hookType, hookPath, got, want, ErrHookTampered)
+		}
+	}
+	return hookPaths, nil
+}
+
+// recordedHookDigest returns the recorded digest of the hook script at hookFile, relative to the meta directory.
+func recordedHookDigest(hookFile string, metadata *Metadata, installed *model.InstalledArtifact) (string, bool) {
+	rel := path.Clean(filepath.ToSlash(hookFile))
+	if installed != nil {
+		for _, file := range installed.MetaFiles {
+			if path.Clean(filepath.ToSlash(file.Path)) == rel && file.Hash != "" {
+				return file.Hash, true
+			}
+		}
+	}
+	digest, ok := metadata.Hashes[artifactMetaDir+"/"+rel]
+	return digest, ok && digest != ""
+}
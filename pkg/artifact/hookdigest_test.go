@@ -0,0 +1,81 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks_TamperedScriptIsRefused(t *testing.T) {
+	// install installs an artifact whose hooks of the given types create marker files next to the database
+	install := func(t *testing.T, hookTypes ...string) (*ManagerImpl, string) {
+		t.Helper()
+		dir := t.TempDir()
+		inputDir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0o755))
+		require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool.txt"), []byte("tool"), 0o644))
+
+		hooks := Hooks{}
+		for _, hookType := range hookTypes {
+			script := fmt.Sprintf("os := import(\"os\")\nf := os.create(%q)\nf.close()\n", filepath.Join(dir, hookType+".ran"))
+			require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactMetaDir, hookType+".tengo"), []byte(script), 0o644))
+			hooks[hookType] = []string{hookType + ".tengo"}
+		}
+		artifactPath, err := NewPacker("tool", "1.0.0", "linux", "amd64", "", "hooked", nil, hooks, inputDir, t.TempDir()).Pack()
+		require.NoError(t, err)
+
+		mgr := NewManager("linux", "amd64", dir, filepath.Join(dir, "install", artifactDataDir), filepath.Join(dir, "install", artifactMetaDir), filepath.Join(dir, "installed.db"))
+		desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+		return mgr, dir
+	}
+	tamper := func(t *testing.T, mgr *ManagerImpl, hookType string) {
+		t.Helper()
+		hookPath := filepath.Join(mgr.getArtifactMetaInstallPath(&model.IndexArtifactDescriptor{Name: "tool"}), hookType+".tengo")
+		content, err := os.ReadFile(hookPath)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(hookPath, append(content, []byte("// tampered\n")...), 0o644))
+	}
+
+	t.Run("untouched hooks run", func(t *testing.T) {
+		mgr, dir := install(t, "pre-uninstall", "post-uninstall")
+		require.NoError(t, mgr.UninstallArtifact(context.Background(), "tool", false))
+		assert.FileExists(t, filepath.Join(dir, "pre-uninstall.ran"))
+		assert.FileExists(t, filepath.Join(dir, "post-uninstall.ran"))
+	})
+
+	for _, hookType := range []string{"pre-uninstall", "post-uninstall"} {
+		t.Run(hookType, func(t *testing.T) {
+			mgr, dir := install(t, hookType)
+			tamper(t, mgr, hookType)
+
+			err := mgr.UninstallArtifact(context.Background(), "tool", false)
+			require.ErrorIs(t, err, ErrHookTampered)
+			assert.NoFileExists(t, filepath.Join(dir, hookType+".ran"))
+
+			names, err := mgr.InstalledNames()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"tool"}, names, "the artifact must stay installed")
+		})
+	}
+
+	t.Run("pre-update", func(t *testing.T) {
+		mgr, dir := install(t, "pre-update")
+		tamper(t, mgr, "pre-update")
+
+		newPath := filepath.Join(t.TempDir(), "tool-2.0.0.gotya")
+		setupTestArtifact(t, newPath, true, &Metadata{Name: "tool", Version: "2.0.0", OS: "linux", Arch: "amd64"})
+		desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "2.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool-2.0.0.gotya"}
+
+		err := mgr.UpdateArtifact(context.Background(), newPath, desc)
+		require.ErrorIs(t, err, ErrHookTampered)
+		assert.NoFileExists(t, filepath.Join(dir, "pre-update.ran"))
+	})
+}
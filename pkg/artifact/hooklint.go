@@ -0,0 +1,141 @@
+package artifact
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/glorpus-work/gotya/internal/logger"
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+// HookLintMode controls how the packer reacts to hook scripts that reference paths outside the artifact.
+type HookLintMode string
+
+const (
+	// HookLintWarn logs a warning for every suspicious path and continues packing.
+	HookLintWarn HookLintMode = "warn"
+	// HookLintError aborts packing when a suspicious path is found.
+	HookLintError HookLintMode = "error"
+	// HookLintOff disables hook script linting.
+	HookLintOff HookLintMode = "off"
+)
+
+var (
+	// hookStringLiteral matches double-quoted and raw (backtick) Tengo string literals.
+	hookStringLiteral = regexp.MustCompile("\"(?:[^\"\\\\\\n]|\\\\.)*\"|`[^`]*`")
+	// hookWindowsAbsPath matches literals starting with a drive letter, e.g. C:\ or C:/.
+	hookWindowsAbsPath = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+	// hookParentTraversal matches a ".." path segment.
+	hookParentTraversal = regexp.MustCompile(`(^|[\\/])\.\.([\\/]|$)`)
+)
+
+// HookLintFinding describes a suspicious path literal found in a hook script.
+type HookLintFinding struct {
+	Script  string
+	Line    int
+	Literal string
+	Reason  string
+}
+
+// LintHookScript performs a best-effort static scan of a Tengo hook script and reports string
+// literals that look like absolute paths or parent-directory traversal.
+// Hooks are expected to work relative to the directories provided via the dirs module.
+func LintHookScript(name string, content []byte) []HookLintFinding {
+	var findings []HookLintFinding
+	for i, line := range strings.Split(string(content), "\n") {
+		code := stripLineComment(line)
+		for _, loc := range hookStringLiteral.FindAllStringIndex(code, -1) {
+			value := code[loc[0]+1 : loc[1]-1]
+			// A literal appended to another expression (e.g. dirs.data_dir + "/file") is a path suffix.
+			appended := strings.HasSuffix(strings.TrimRight(code[:loc[0]], " \t"), "+")
+			reason := ""
+			switch {
+			case !appended && (strings.HasPrefix(value, "/") || strings.HasPrefix(value, `\\`) || hookWindowsAbsPath.MatchString(value)):
+				reason = "absolute path"
+			case hookParentTraversal.MatchString(value):
+				reason = "parent directory traversal"
+			default:
+				continue
+			}
+			findings = append(findings, HookLintFinding{Script: name, Line: i + 1, Literal: value, Reason: reason})
+		}
+	}
+	return findings
+}
+
+// stripLineComment removes a trailing // comment that is not part of a string literal.
+func stripLineComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if r == '\\' && quote == '"' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '`':
+			quote = r
+		case r == '/' && strings.HasPrefix(line[i:], "//"):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// SetHookLintMode sets how suspicious paths in hook scripts are handled at pack time.
+func (p *Packer) SetHookLintMode(mode HookLintMode) {
+	p.hookLintMode = mode
+}
+
+// lintHookScripts scans all referenced hook scripts according to the configured lint mode.
+func (p *Packer) lintHookScripts() error {
+	if p.hookLintMode == HookLintOff {
+		return nil
+	}
+
+	for _, script := range p.hooks.Scripts() {
+		content, err := os.ReadFile(filepath.Join(p.inputDir, artifactMetaDir, script))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return errutils.Wrapf(err, "failed to read hook script %s", script)
+		}
+		for _, finding := range LintHookScript(script, content) {
+			if p.hookLintMode == HookLintError {
+				return errutils.Wrapf(ErrUnsafeHookScript, "%s:%d: %s %q", finding.Script, finding.Line, finding.Reason, finding.Literal)
+			}
+			logger.Warn("Hook script references a path outside the artifact", logger.Fields{
+				"script":  finding.Script,
+				"line":    finding.Line,
+				"literal": finding.Literal,
+				"reason":  finding.Reason,
+			})
+		}
+	}
+	return nil
+}
+
+// compileHookScripts compiles all referenced hook scripts with the compiler used to run them at install time.
+func (p *Packer) compileHookScripts() error {
+	executor := NewHookExecutor()
+	for _, script := range p.hooks.Scripts() {
+		content, err := os.ReadFile(filepath.Join(p.inputDir, artifactMetaDir, script))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return errutils.Wrapf(err, "failed to read hook script %s", script)
+		}
+		if err := executor.CompileHook(script, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
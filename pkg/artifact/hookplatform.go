@@ -0,0 +1,85 @@
+package artifact
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/platform"
+)
+
+// hookPlatformSeparator separates a hook type from the platform its scripts are restricted to,
+// e.g. "post-install@windows/amd64" or "post-install@linux".
+const hookPlatformSeparator = "@"
+
+// ForPlatform returns the scripts of hookType for the given OS and architecture. Hook keys may be qualified
+// with a platform as "type@os/arch" or "type@os", where either part can be platform.AnyOS or platform.AnyArch.
+// The most specific matching key is used: a matching OS counts more than a matching architecture, and any
+// qualified key counts more than the unqualified hook type, which is the fallback.
+func (h Hooks) ForPlatform(hookType, os, arch string) []string {
+	bestScore := -1
+	bestKey := ""
+	for key := range h {
+		keyType, keyPlatform, qualified, err := parseHookKey(key)
+		if err != nil || keyType != hookType {
+			continue
+		}
+		score := 0
+		if qualified {
+			if !keyPlatform.Matches(platform.Platform{OS: os, Arch: arch}) {
+				continue
+			}
+			score = 1
+			if keyPlatform.OS != platform.AnyOS {
+				score += 2
+			}
+			if keyPlatform.Arch != platform.AnyArch {
+				score++
+			}
+		}
+		// Equally specific keys, e.g. "type@linux" and "type@linux/any", are decided by name to stay deterministic
+		if score > bestScore || (score == bestScore && key < bestKey) {
+			bestScore, bestKey = score, key
+		}
+	}
+	if bestScore < 0 {
+		return nil
+	}
+	return h[bestKey]
+}
+
+// Validate checks that all hook keys are hook types or platform-qualified hook types and that every
+// hook type has at least one script.
+func (h Hooks) Validate() error {
+	for _, key := range slices.Sorted(maps.Keys(h)) {
+		if _, _, _, err := parseHookKey(key); err != nil {
+			return err
+		}
+		if len(h[key]) == 0 {
+			return errutils.Wrapf(errutils.ErrValidation, "hook %s has no scripts", key)
+		}
+	}
+	return nil
+}
+
+// parseHookKey splits a hook key into its hook type and platform. qualified is false for plain hook types.
+// A platform without architecture matches any architecture.
+func parseHookKey(key string) (hookType string, p platform.Platform, qualified bool, err error) {
+	hookType, qualifier, qualified := strings.Cut(key, hookPlatformSeparator)
+	if hookType == "" {
+		return "", p, false, errutils.Wrapf(errutils.ErrValidation, "hook %q has no hook type", key)
+	}
+	if !qualified {
+		return hookType, p, false, nil
+	}
+
+	osName, arch, hasArch := strings.Cut(qualifier, "/")
+	if !hasArch {
+		arch = platform.AnyArch
+	}
+	if osName == "" || arch == "" || strings.Contains(osName, hookPlatformSeparator) || strings.ContainsAny(arch, "/"+hookPlatformSeparator) {
+		return "", p, false, errutils.Wrapf(errutils.ErrValidation, "hook %q must be qualified as type@os/arch or type@os", key)
+	}
+	return hookType, platform.Platform{OS: osName, Arch: arch}, true, nil
+}
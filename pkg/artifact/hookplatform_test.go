@@ -0,0 +1,91 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks_ForPlatform(t *testing.T) {
+	hooks := Hooks{
+		"post-install":               {"generic.tengo"},
+		"post-install@windows/amd64": {"windows-amd64.tengo"},
+		"post-install@linux":         {"linux.tengo"},
+		"post-install@linux/arm64":   {"linux-arm64.tengo"},
+		"post-install@any/riscv64":   {"riscv64.tengo"},
+		"pre-install@windows":        {"windows-only.tengo"},
+	}
+
+	tests := []struct {
+		hookType, os, arch string
+		expected           []string
+	}{
+		{"post-install", "linux", "arm64", []string{"linux-arm64.tengo"}},
+		{"post-install", "linux", "amd64", []string{"linux.tengo"}},
+		{"post-install", "linux", "riscv64", []string{"linux.tengo"}},
+		{"post-install", "freebsd", "riscv64", []string{"riscv64.tengo"}},
+		{"post-install", "windows", "amd64", []string{"windows-amd64.tengo"}},
+		{"post-install", "windows", "arm64", []string{"generic.tengo"}},
+		{"post-install", "darwin", "arm64", []string{"generic.tengo"}},
+		{"pre-install", "windows", "arm64", []string{"windows-only.tengo"}},
+		{"pre-install", "linux", "amd64", nil},
+		{"post-update", "linux", "amd64", nil},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %s/%s", tt.hookType, tt.os, tt.arch), func(t *testing.T) {
+			assert.Equal(t, tt.expected, hooks.ForPlatform(tt.hookType, tt.os, tt.arch))
+		})
+	}
+}
+
+func TestHooks_Validate(t *testing.T) {
+	require.NoError(t, Hooks{
+		"post-install":               {"a.tengo"},
+		"post-install@windows/amd64": {"b.tengo"},
+		"post-install@linux":         {"c.tengo"},
+	}.Validate())
+
+	for _, key := range []string{"@linux", "post-install@", "post-install@/amd64", "post-install@linux/", "post-install@linux/amd64/v2", "post-install@linux@arm"} {
+		t.Run(key, func(t *testing.T) {
+			require.ErrorIs(t, Hooks{key: {"a.tengo"}}.Validate(), errutils.ErrValidation)
+		})
+	}
+	require.ErrorIs(t, Hooks{"post-install": {}}.Validate(), errutils.ErrValidation)
+}
+
+func TestHooks_PlatformQualifiedHookRuns(t *testing.T) {
+	tempDir := t.TempDir()
+	markerDir := filepath.Join(tempDir, "markers")
+	require.NoError(t, os.MkdirAll(markerDir, 0o755))
+
+	inputDir := filepath.Join(tempDir, "input")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool.txt"), []byte("tool"), 0o644))
+	for _, variant := range []string{"generic", "linux", "windows"} {
+		script := fmt.Sprintf("os := import(\"os\")\nf := os.create(%q)\nf.close()\n", filepath.Join(markerDir, variant))
+		require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactMetaDir, variant+".tengo"), []byte(script), 0o644))
+	}
+	hooks := Hooks{
+		"post-install":               {"generic.tengo"},
+		"post-install@linux/amd64":   {"linux.tengo"},
+		"post-install@windows/amd64": {"windows.tengo"},
+	}
+	artifactPath, err := NewPacker("tool", "1.0.0", "any", "amd64", "", "platform hooks", nil, hooks, inputDir, tempDir).Pack()
+	require.NoError(t, err)
+
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "any", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+
+	assert.FileExists(t, filepath.Join(markerDir, "linux"))
+	assert.NoFileExists(t, filepath.Join(markerDir, "windows"))
+	assert.NoFileExists(t, filepath.Join(markerDir, "generic"), "the unqualified hook is only a fallback")
+}
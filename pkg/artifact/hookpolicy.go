@@ -0,0 +1,77 @@
+package artifact
+
+import (
+	"slices"
+
+	"github.com/glorpus-work/gotya/internal/logger"
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// HookFailurePolicy controls how a failing post-install hook affects the installation.
+type HookFailurePolicy string
+
+const (
+	// HookFailurePolicyRollback fails the installation and removes the installed artifact.
+	HookFailurePolicyRollback HookFailurePolicy = "rollback"
+	// HookFailurePolicyWarn keeps the artifact installed and records the hook failure.
+	HookFailurePolicyWarn HookFailurePolicy = "warn"
+)
+
+// SetPostInstallHookFailurePolicy sets how installations react to a failing post-install hook.
+// The default is HookFailurePolicyRollback; unknown policies are treated as rollback.
+func (m *ManagerImpl) SetPostInstallHookFailurePolicy(policy HookFailurePolicy) {
+	m.postInstallHookFailurePolicy = policy
+}
+
+// handlePostInstallHookFailure applies the post-install hook failure policy. It returns nil if the
+// installation is kept; otherwise it removes the artifact from the database and returns hookErr so
+// the caller rolls back the installed files. previous is the placeholder entry the install replaced, if any.
+func (m *ManagerImpl) handlePostInstallHookFailure(desc *model.IndexArtifactDescriptor, previous *model.InstalledArtifact, hookErr error) error {
+	if m.postInstallHookFailurePolicy == HookFailurePolicyWarn {
+		logger.Warn("Post-install hook failed, keeping artifact installed", logger.Fields{
+			"artifact": desc.Name,
+			"error":    hookErr.Error(),
+		})
+		installed := m.installDB.FindArtifact(desc.Name)
+		if installed == nil {
+			return hookErr
+		}
+		installed.PostInstallHookError = hookErr.Error()
+		if err := m.installDB.SaveDatabase(); err != nil {
+			return errutils.Wrap(err, "failed to record post-install hook failure")
+		}
+		return nil
+	}
+
+	m.removeFailedInstallFromDatabase(desc, previous)
+	if err := m.installDB.SaveDatabase(); err != nil {
+		logger.Warn("Failed to save database after rolling back installation", logger.Fields{
+			"artifact": desc.Name,
+			"error":    err.Error(),
+		})
+	}
+	return hookErr
+}
+
+// removeFailedInstallFromDatabase undoes the database changes of an installation: the artifact's entry,
+// its reverse dependency links and placeholders created only for it. A replaced placeholder is restored.
+func (m *ManagerImpl) removeFailedInstallFromDatabase(desc *model.IndexArtifactDescriptor, previous *model.InstalledArtifact) {
+	m.installDB.RemoveArtifact(desc.Name)
+	if previous != nil && previous.Status == model.StatusMissing {
+		m.installDB.AddArtifact(previous)
+	}
+
+	for _, dep := range desc.Dependencies {
+		artifact := m.installDB.FindArtifact(dep.Name)
+		if artifact == nil {
+			continue
+		}
+		if i := slices.Index(artifact.ReverseDependencies, desc.Name); i >= 0 {
+			artifact.ReverseDependencies = slices.Delete(artifact.ReverseDependencies, i, i+1)
+		}
+		if artifact.Status == model.StatusMissing && len(artifact.ReverseDependencies) == 0 {
+			m.installDB.RemoveArtifact(dep.Name)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package artifact
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallArtifact_PostInstallHookFailurePolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	outputDir := filepath.Join(tempDir, "output")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0o755))
+	require.NoError(t, os.MkdirAll(outputDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool.txt"), []byte("tool"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactMetaDir, "post-install.tengo"), []byte("notCallable := 1\nnotCallable()\n"), 0o644))
+
+	packer := NewPacker("failing", "1.0.0", "linux", "amd64", "", "failing hook", nil, Hooks{"post-install": {"post-install.tengo"}}, inputDir, outputDir)
+	artifactPath, err := packer.Pack()
+	require.NoError(t, err)
+	desc := &model.IndexArtifactDescriptor{
+		Name: "failing", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/failing.gotya",
+		Dependencies: []model.Dependency{{Name: "libdep"}},
+	}
+
+	newManager := func(t *testing.T) *ManagerImpl {
+		dir := t.TempDir()
+		return NewManager("linux", "amd64", dir, filepath.Join(dir, "install", artifactDataDir), filepath.Join(dir, "install", artifactMetaDir), filepath.Join(dir, "installed.db"))
+	}
+
+	t.Run("rollback by default", func(t *testing.T) {
+		mgr := newManager(t)
+		err := mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual)
+		require.ErrorContains(t, err, "post-install hook failed")
+
+		assert.NoDirExists(t, mgr.getArtifactDataInstallPath(&model.IndexArtifactDescriptor{Name: "failing"}))
+		assert.NoDirExists(t, mgr.getArtifactMetaInstallPath(&model.IndexArtifactDescriptor{Name: "failing"}))
+		require.NoError(t, mgr.loadInstalledDB())
+		assert.Nil(t, mgr.installDB.FindArtifact("failing"))
+		assert.Nil(t, mgr.installDB.FindArtifact("libdep"), "placeholder created for the failed install should be removed")
+	})
+
+	t.Run("rollback restores replaced placeholder", func(t *testing.T) {
+		mgr := newManager(t)
+		require.NoError(t, mgr.loadInstalledDB())
+		mgr.installDB.AddArtifact(&model.InstalledArtifact{
+			Name:                "failing",
+			Status:              model.StatusMissing,
+			ReverseDependencies: []string{"app"},
+		})
+		require.NoError(t, mgr.installDB.SaveDatabase())
+
+		err := mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonAutomatic)
+		require.Error(t, err)
+
+		require.NoError(t, mgr.loadInstalledDB())
+		placeholder := mgr.installDB.FindArtifact("failing")
+		require.NotNil(t, placeholder)
+		assert.Equal(t, model.StatusMissing, placeholder.Status)
+		assert.Equal(t, []string{"app"}, placeholder.ReverseDependencies)
+	})
+
+	t.Run("warn keeps the installation", func(t *testing.T) {
+		mgr := newManager(t)
+		mgr.SetPostInstallHookFailurePolicy(HookFailurePolicyWarn)
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+
+		assert.FileExists(t, filepath.Join(mgr.getArtifactDataInstallPath(&model.IndexArtifactDescriptor{Name: "failing"}), "tool.txt"))
+		require.NoError(t, mgr.loadInstalledDB())
+		installed := mgr.installDB.FindArtifact("failing")
+		require.NotNil(t, installed)
+		assert.Equal(t, model.StatusInstalled, installed.Status)
+		assert.Contains(t, installed.PostInstallHookError, "not callable")
+		assert.NotNil(t, mgr.installDB.FindArtifact("libdep"))
+	})
+}
@@ -2,8 +2,12 @@
 package artifact
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/d5/tengo/v2"
 	"github.com/d5/tengo/v2/stdlib"
@@ -20,7 +24,7 @@ type HookExecutor interface {
 type HookContext struct {
 	ArtifactName    string
 	ArtifactVersion string
-	Operation       string // "install", "update", "uninstall"
+	Operation       string // "install", "update", "uninstall", "batch"
 	MetaDir         string
 	DataDir         string
 	TempMetaDir     string // For pre-install hooks (temp extraction dir)
@@ -29,22 +33,76 @@ type HookContext struct {
 	WasMetaDir      string // For post-uninstall hooks (where meta dir was)
 	WasDataDir      string // For post-uninstall hooks (where data dir was)
 	OldVersion      string // For updates (previous version)
+
+	// Set for all hooks
+	OS   string            // Operating system the manager installs for
+	Arch string            // Architecture the manager installs for
+	Vars map[string]string // Additional variables, see ManagerImpl.SetHookVars
 }
 
+// DefaultHookRetryDelay is the delay between hook attempts used when a negative delay is configured.
+const DefaultHookRetryDelay = 500 * time.Millisecond
+
+// DefaultHookOutputLimit is the number of bytes of hook output kept when no limit is configured.
+const DefaultHookOutputLimit = 64 * 1024
+
+// hookOutputTruncatedMarker is appended to hook output cut off at the output limit.
+const hookOutputTruncatedMarker = "\n[hook output truncated after %d bytes]"
+
 // HookExecutorImpl is the default implementation of HookExecutor
-type HookExecutorImpl struct{}
+type HookExecutorImpl struct {
+	retries     int
+	retryDelay  time.Duration
+	outputLimit int
+}
 
 // NewHookExecutor creates a new hook executor instance
 func NewHookExecutor() *HookExecutorImpl {
 	return &HookExecutorImpl{}
 }
 
+// SetRetries sets how many times a failing hook is run again before its last error is returned.
+// Retries are disabled by default. A delay of 0 retries immediately, a negative delay uses DefaultHookRetryDelay.
+func (he *HookExecutorImpl) SetRetries(count int, delay time.Duration) {
+	he.retries = max(count, 0)
+	he.retryDelay = delay
+}
+
+// SetOutputLimit sets how many bytes of the output a hook prints with the fmt module are captured. Output beyond
+// the limit is dropped and marked as truncated, the hook keeps running. A limit <= 0 uses DefaultHookOutputLimit.
+func (he *HookExecutorImpl) SetOutputLimit(limit int) {
+	he.outputLimit = limit
+}
+
 // ExecuteHook executes a Tengo script hook with the provided context
 func (he *HookExecutorImpl) ExecuteHook(hookPath string, context *HookContext) error {
 	if _, err := os.Stat(hookPath); os.IsNotExist(err) {
 		return errutils.Wrapf(errutils.ErrValidation, "hook script %s does not exist", hookPath)
 	}
 
+	delay := he.retryDelay
+	if delay < 0 {
+		delay = DefaultHookRetryDelay
+	}
+	var err error
+	for attempt := 0; attempt <= he.retries; attempt++ {
+		if attempt > 0 {
+			logger.Warn("Hook script failed, retrying", logger.Fields{
+				"hook_path": hookPath,
+				"attempt":   attempt + 1,
+				"error":     err.Error(),
+			})
+			time.Sleep(delay)
+		}
+		if _, err = he.runHook(hookPath, context); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// runHook runs a hook script once and returns its captured output, which is also logged.
+func (he *HookExecutorImpl) runHook(hookPath string, context *HookContext) (string, error) {
 	logger.Debug("Executing hook script", logger.Fields{
 		"hook_path": hookPath,
 		"operation": context.Operation,
@@ -55,20 +113,24 @@ func (he *HookExecutorImpl) ExecuteHook(hookPath string, context *HookContext) e
 	// Read the script file
 	scriptContent, err := os.ReadFile(hookPath)
 	if err != nil {
-		return fmt.Errorf("failed to read hook script %s: %w", hookPath, err)
+		return "", fmt.Errorf("failed to read hook script %s: %w", hookPath, err)
 	}
 
-	// Create Tengo script with module map for variables
-	moduleMap := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
-	he.setupScriptContext(moduleMap, context)
-
-	// Create script with the module map
-	script := tengo.NewScript(scriptContent)
-	script.SetImports(moduleMap)
-
 	// Execute the script
-	if _, err := script.Run(); err != nil {
-		return errutils.Wrapf(err, "hook script execution failed for %s", hookPath)
+	limit := he.outputLimit
+	if limit <= 0 {
+		limit = DefaultHookOutputLimit
+	}
+	output := &hookOutput{limit: limit}
+	_, err = he.newScript(scriptContent, context, output).Run()
+	if output.buf.Len() > 0 {
+		logger.Info("Hook script output", logger.Fields{
+			"hook_path": hookPath,
+			"output":    output.String(),
+		})
+	}
+	if err != nil {
+		return output.String(), errutils.Wrapf(err, "hook script execution failed for %s", hookPath)
 	}
 
 	logger.Debug("Hook script executed successfully", logger.Fields{
@@ -77,16 +139,46 @@ func (he *HookExecutorImpl) ExecuteHook(hookPath string, context *HookContext) e
 		"artifact":  context.ArtifactName,
 	})
 
+	return output.String(), nil
+}
+
+// CompileHook compiles a hook script without running it, so syntax errors and unknown modules are found
+// before the script is shipped. name is used in place of the script path in the error.
+func (he *HookExecutorImpl) CompileHook(name string, content []byte) error {
+	// Hooks always get the dirs module, even though its entries differ per hook type
+	script := he.newScript(content, &HookContext{MetaDir: name}, io.Discard)
+	if _, err := script.Compile(); err != nil {
+		msg := strings.ReplaceAll(strings.ReplaceAll(err.Error(), "\n\t", " "), "(main)", name)
+		return fmt.Errorf("%w: %s", ErrInvalidHookScript, msg)
+	}
 	return nil
 }
 
+// newScript creates the Tengo script for a hook with the standard library and the hook modules as imports.
+// Printing with the fmt module writes to output instead of stdout.
+func (he *HookExecutorImpl) newScript(content []byte, context *HookContext, output io.Writer) *tengo.Script {
+	moduleMap := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
+	moduleMap.AddBuiltinModule("fmt", hookFmtModule(output))
+	he.setupScriptContext(moduleMap, context)
+	script := tengo.NewScript(content)
+	script.SetImports(moduleMap)
+	return script
+}
+
 // setupScriptContext sets up the Tengo script context variables
 func (he *HookExecutorImpl) setupScriptContext(moduleMap *tengo.ModuleMap, context *HookContext) {
 	// Set standard context variables
+	vars := make(map[string]tengo.Object, len(context.Vars))
+	for name, value := range context.Vars {
+		vars[name] = &tengo.String{Value: value}
+	}
 	moduleMap.AddBuiltinModule("context", map[string]tengo.Object{
 		"artifact_name":    &tengo.String{Value: context.ArtifactName},
 		"artifact_version": &tengo.String{Value: context.ArtifactVersion},
 		"operation":        &tengo.String{Value: context.Operation},
+		"os":               &tengo.String{Value: context.OS},
+		"arch":             &tengo.String{Value: context.Arch},
+		"vars":             &tengo.ImmutableMap{Value: vars},
 	})
 
 	// Set directory paths based on what's available
@@ -120,3 +212,85 @@ func (he *HookExecutorImpl) setupScriptContext(moduleMap *tengo.ModuleMap, conte
 		moduleMap.AddBuiltinModule("dirs", dirModule)
 	}
 }
+
+// hookOutput captures the output of a hook script up to limit bytes.
+type hookOutput struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write keeps what fits into the limit and reports everything as written, so printing never fails the hook.
+func (o *hookOutput) Write(p []byte) (int, error) {
+	n := len(p)
+	if remaining := o.limit - o.buf.Len(); n > remaining {
+		p = p[:max(remaining, 0)]
+		o.truncated = true
+	}
+	o.buf.Write(p)
+	return n, nil
+}
+
+// String returns the captured output, followed by a marker if it was truncated.
+func (o *hookOutput) String() string {
+	if !o.truncated {
+		return o.buf.String()
+	}
+	return strings.ToValidUTF8(o.buf.String(), "") + fmt.Sprintf(hookOutputTruncatedMarker, o.limit)
+}
+
+// hookFmtModule returns the Tengo fmt module with its print functions writing to w.
+func hookFmtModule(w io.Writer) map[string]tengo.Object {
+	return map[string]tengo.Object{
+		"print": &tengo.UserFunction{Name: "print", Value: func(args ...tengo.Object) (tengo.Object, error) {
+			printArgs, err := hookPrintArgs(args)
+			if err != nil {
+				return nil, err
+			}
+			_, _ = fmt.Fprint(w, printArgs...)
+			return nil, nil
+		}},
+		"printf": &tengo.UserFunction{Name: "printf", Value: func(args ...tengo.Object) (tengo.Object, error) {
+			if len(args) == 0 {
+				return nil, tengo.ErrWrongNumArguments
+			}
+			format, ok := args[0].(*tengo.String)
+			if !ok {
+				return nil, tengo.ErrInvalidArgumentType{Name: "format", Expected: "string", Found: args[0].TypeName()}
+			}
+			if len(args) == 1 {
+				_, _ = io.WriteString(w, format.Value)
+				return nil, nil
+			}
+			s, err := tengo.Format(format.Value, args[1:]...)
+			if err != nil {
+				return nil, err
+			}
+			_, _ = io.WriteString(w, s)
+			return nil, nil
+		}},
+		"println": &tengo.UserFunction{Name: "println", Value: func(args ...tengo.Object) (tengo.Object, error) {
+			printArgs, err := hookPrintArgs(args)
+			if err != nil {
+				return nil, err
+			}
+			_, _ = fmt.Fprint(w, append(printArgs, "\n")...)
+			return nil, nil
+		}},
+		"sprintf": stdlib.BuiltinModules["fmt"]["sprintf"],
+	}
+}
+
+// hookPrintArgs converts the arguments of fmt.print and fmt.println to strings like the Tengo fmt module does.
+func hookPrintArgs(args []tengo.Object) ([]any, error) {
+	printArgs := make([]any, 0, len(args)+1)
+	length := 0
+	for _, arg := range args {
+		s, _ := tengo.ToString(arg)
+		if length += len(s); length > tengo.MaxStringLen {
+			return nil, tengo.ErrStringLimit
+		}
+		printArgs = append(printArgs, s)
+	}
+	return printArgs, nil
+}
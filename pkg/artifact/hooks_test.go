@@ -1,10 +1,15 @@
 package artifact
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/glorpus-work/gotya/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -74,3 +79,232 @@ invalid tengo syntax !!!
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "hook script execution failed")
 }
+
+// writeFlakyHook writes a hook script that fails on its first run and succeeds afterwards.
+func writeFlakyHook(t *testing.T, hookPath, markerPath string) {
+	t.Helper()
+	script := fmt.Sprintf(`os := import("os")
+if is_error(os.stat(%q)) {
+	f := os.create(%q)
+	f.close()
+	notCallable := 1
+	notCallable()
+}
+`, markerPath, markerPath)
+	require.NoError(t, os.WriteFile(hookPath, []byte(script), 0o644))
+}
+
+func TestHookExecutor_ExecuteHook_Retries(t *testing.T) {
+	context := &HookContext{ArtifactName: "test-artifact", ArtifactVersion: "1.0.0", Operation: "test"}
+
+	t.Run("fails without retries", func(t *testing.T) {
+		tempDir := t.TempDir()
+		hookPath := filepath.Join(tempDir, "flaky.tengo")
+		writeFlakyHook(t, hookPath, filepath.Join(tempDir, "attempted"))
+
+		err := NewHookExecutor().ExecuteHook(hookPath, context)
+		require.Error(t, err)
+	})
+
+	t.Run("succeeds on retry", func(t *testing.T) {
+		tempDir := t.TempDir()
+		hookPath := filepath.Join(tempDir, "flaky.tengo")
+		writeFlakyHook(t, hookPath, filepath.Join(tempDir, "attempted"))
+
+		executor := NewHookExecutor()
+		executor.SetRetries(2, 0)
+		start := time.Now()
+		require.NoError(t, executor.ExecuteHook(hookPath, context))
+		assert.Less(t, time.Since(start), DefaultHookRetryDelay, "a zero delay retries immediately")
+	})
+
+	t.Run("returns last error when retries are exhausted", func(t *testing.T) {
+		hookPath := filepath.Join(t.TempDir(), "broken.tengo")
+		require.NoError(t, os.WriteFile(hookPath, []byte("notCallable := 1\nnotCallable()\n"), 0o644))
+
+		executor := NewHookExecutor()
+		executor.SetRetries(2, 0)
+		err := executor.ExecuteHook(hookPath, context)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "hook script execution failed")
+	})
+}
+
+func TestInstallArtifact_HookRetries(t *testing.T) {
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	outputDir := filepath.Join(tempDir, "output")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0o755))
+	require.NoError(t, os.MkdirAll(outputDir, 0o755))
+	markerPath := filepath.Join(tempDir, "attempted")
+	writeFlakyHook(t, filepath.Join(inputDir, artifactMetaDir, "post-install.tengo"), markerPath)
+
+	packer := NewPacker("flaky", "1.0.0", "linux", "amd64", "", "flaky hook", nil, Hooks{"post-install": {"post-install.tengo"}}, inputDir, outputDir)
+	artifactPath, err := packer.Pack()
+	require.NoError(t, err)
+	desc := &model.IndexArtifactDescriptor{Name: "flaky", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/flaky.gotya"}
+
+	newManager := func(t *testing.T) *ManagerImpl {
+		dir := t.TempDir()
+		return NewManager("linux", "amd64", dir, filepath.Join(dir, "install", artifactDataDir), filepath.Join(dir, "install", artifactMetaDir), filepath.Join(dir, "installed.db"))
+	}
+
+	t.Run("without retries", func(t *testing.T) {
+		_ = os.Remove(markerPath)
+		mgr := newManager(t)
+		err := mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual)
+		require.ErrorContains(t, err, "not callable")
+	})
+
+	t.Run("with retries", func(t *testing.T) {
+		_ = os.Remove(markerPath)
+		mgr := newManager(t)
+		mgr.SetHookRetries(1, 0)
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+		assert.FileExists(t, markerPath)
+	})
+}
+
+func TestHooks_ReceivePlatformAndVars(t *testing.T) {
+	tempDir := t.TempDir()
+	markerDir := filepath.Join(tempDir, "markers")
+	require.NoError(t, os.MkdirAll(markerDir, 0o755))
+
+	inputDir := filepath.Join(tempDir, "input")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool.txt"), []byte("tool"), 0o644))
+	script := `os := import("os")
+ctx := import("context")
+f := os.create(ctx.vars.marker_dir + "/" + ctx.operation)
+f.write_string(ctx.os + "/" + ctx.arch + " " + ctx.vars.channel)
+f.close()
+`
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactMetaDir, "marker.tengo"), []byte(script), 0o644))
+	hooks := Hooks{"post-install": {"marker.tengo"}, "pre-uninstall": {"marker.tengo"}}
+	packer := NewPacker("tool", "1.0.0", "linux", "arm64", "", "marker hooks", nil, hooks, inputDir, tempDir)
+	artifactPath, err := packer.Pack()
+	require.NoError(t, err)
+
+	mgr := NewManager("linux", "arm64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+	vars := map[string]string{"channel": "beta", "marker_dir": markerDir}
+	mgr.SetHookVars(vars)
+	vars["channel"] = "changed" // the manager keeps its own copy
+
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "arm64", URL: "http://example.com/tool.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	require.NoError(t, mgr.UninstallArtifact(context.Background(), "tool", false))
+
+	for _, operation := range []string{"install", "uninstall"} {
+		marker, err := os.ReadFile(filepath.Join(markerDir, operation))
+		require.NoError(t, err, operation)
+		assert.Equal(t, "linux/arm64 beta", string(marker), operation)
+	}
+}
+
+func TestHooks_MultipleScriptsRunInOrder(t *testing.T) {
+	tempDir := t.TempDir()
+	logPath := filepath.Join(tempDir, "hooks.log")
+	appendScript := func(line string) string {
+		return fmt.Sprintf("os := import(\"os\")\nf := os.open_file(%q, os.o_append|os.o_create|os.o_wronly, 420)\nf.write_string(%q)\nf.close()\n", logPath, line+"\n")
+	}
+
+	inputDir := filepath.Join(tempDir, "input")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool.txt"), []byte("tool"), 0o644))
+	scripts := map[string]string{
+		"10-setup.tengo":  appendScript("setup"),
+		"20-perms.tengo":  appendScript("perms"),
+		"30-fail.tengo":   appendScript("fail") + "notCallable := 1\nnotCallable()\n",
+		"40-unused.tengo": appendScript("unused"),
+	}
+	for name, script := range scripts {
+		require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactMetaDir, name), []byte(script), 0o644))
+	}
+	hooks := Hooks{
+		"post-install":  {"10-setup.tengo", "20-perms.tengo"},
+		"pre-uninstall": {"20-perms.tengo", "30-fail.tengo", "40-unused.tengo"},
+	}
+	artifactPath, err := NewPacker("tool", "1.0.0", "linux", "amd64", "", "ordered hooks", nil, hooks, inputDir, tempDir).Pack()
+	require.NoError(t, err)
+
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+
+	log, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, "setup\nperms\n", string(log))
+
+	// The failing script stops the hook before the remaining scripts and the uninstall itself
+	err = mgr.UninstallArtifact(context.Background(), "tool", false)
+	require.ErrorContains(t, err, "pre-uninstall hook failed")
+	log, err = os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, "setup\nperms\nperms\nfail\n", string(log))
+	assert.FileExists(t, filepath.Join(mgr.getArtifactDataInstallPath(desc), "tool.txt"))
+}
+
+// writeChattyHook writes a hook script that prints about 100 KiB and then creates markerPath.
+func writeChattyHook(t *testing.T, hookPath, markerPath string) {
+	t.Helper()
+	script := fmt.Sprintf(`fmt := import("fmt")
+os := import("os")
+for i := 0; i < 1000; i++ {
+	fmt.printf("line %%04d %%s\n", i, "................................................................................................")
+}
+f := os.create(%q)
+f.close()
+`, markerPath)
+	require.NoError(t, os.WriteFile(hookPath, []byte(script), 0o644))
+}
+
+func TestHookExecutor_OutputLimit(t *testing.T) {
+	context := &HookContext{ArtifactName: "test-artifact", ArtifactVersion: "1.0.0", Operation: "test"}
+
+	t.Run("output beyond the limit is truncated", func(t *testing.T) {
+		tempDir := t.TempDir()
+		hookPath := filepath.Join(tempDir, "chatty.tengo")
+		markerPath := filepath.Join(tempDir, "done")
+		writeChattyHook(t, hookPath, markerPath)
+
+		executor := NewHookExecutor()
+		executor.SetOutputLimit(1024)
+		output, err := executor.runHook(hookPath, context)
+		require.NoError(t, err)
+		assert.FileExists(t, markerPath, "the hook runs to completion")
+		assert.True(t, strings.HasPrefix(output, "line 0000 "))
+		assert.True(t, strings.HasSuffix(output, "\n[hook output truncated after 1024 bytes]"))
+		assert.Len(t, strings.TrimSuffix(output, "\n[hook output truncated after 1024 bytes]"), 1024)
+	})
+
+	t.Run("output within the limit is kept", func(t *testing.T) {
+		hookPath := filepath.Join(t.TempDir(), "quiet.tengo")
+		script := "fmt := import(\"fmt\")\nfmt.print(\"a\", 1)\nfmt.printf(\" %d%%\\n\", 50)\nfmt.println(\"done\")\n"
+		require.NoError(t, os.WriteFile(hookPath, []byte(script), 0o644))
+
+		output, err := NewHookExecutor().runHook(hookPath, context)
+		require.NoError(t, err)
+		assert.Equal(t, "a1 50%\ndone\n", output)
+	})
+}
+
+func TestInstallArtifact_HookOutputLimit(t *testing.T) {
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0o755))
+	markerPath := filepath.Join(tempDir, "done")
+	writeChattyHook(t, filepath.Join(inputDir, artifactMetaDir, "post-install.tengo"), markerPath)
+
+	packer := NewPacker("chatty", "1.0.0", "linux", "amd64", "", "chatty hook", nil, Hooks{"post-install": {"post-install.tengo"}}, inputDir, tempDir)
+	artifactPath, err := packer.Pack()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	mgr := NewManager("linux", "amd64", dir, filepath.Join(dir, "install", artifactDataDir), filepath.Join(dir, "install", artifactMetaDir), filepath.Join(dir, "installed.db"))
+	mgr.SetHookOutputLimit(512)
+	desc := &model.IndexArtifactDescriptor{Name: "chatty", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/chatty.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	assert.FileExists(t, markerPath)
+}
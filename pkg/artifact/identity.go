@@ -0,0 +1,45 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// VerifyArtifactIdentity checks that the artifact file at localPath is the artifact desc describes, so a
+// server serving the wrong file at a URL is caught before anything is installed. Only the embedded
+// metadata is read; its name, version, OS and architecture must equal those of desc. A mismatch is
+// returned as a wrapped MetadataMismatchError.
+func (m *ManagerImpl) VerifyArtifactIdentity(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string) error {
+	tempDir, err := os.MkdirTemp("", "gotya-identity-*")
+	if err != nil {
+		return errutils.Wrap(err, "failed to create temp directory")
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	metadataPath := filepath.Join(tempDir, m.metadataFileName())
+	if err := m.archiveExtractor.ExtractFile(ctx, localPath, path.Join(artifactMetaDir, m.metadataFileName()), metadataPath); err != nil {
+		return errutils.Wrapf(err, "failed to extract metadata of %s", localPath)
+	}
+	metadata, err := m.parseMetadata(metadataPath)
+	if err != nil {
+		return errutils.Wrapf(err, "failed to parse metadata of %s", localPath)
+	}
+
+	if err := compareMetadataWithDescriptor(metadata, desc); err != nil {
+		return fmt.Errorf("%s was requested as %s but contains %s: %w", localPath,
+			identityName(desc.Name, desc.Version, desc.GetOS(), desc.GetArch()),
+			identityName(metadata.Name, metadata.Version, metadata.GetOS(), metadata.GetArch()), err)
+	}
+	return nil
+}
+
+// identityName formats the identifying fields of an artifact as name_version_os_arch.
+func identityName(name, version, os, arch string) string {
+	return fmt.Sprintf("%s_%s_%s_%s", name, version, os, arch)
+}
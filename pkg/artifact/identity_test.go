@@ -0,0 +1,33 @@
+package artifact
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyArtifactIdentity(t *testing.T) {
+	tempDir := t.TempDir()
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "data"), filepath.Join(tempDir, "meta"), filepath.Join(tempDir, "installed.db"))
+	artifactPath := filepath.Join(tempDir, "tool_1.0.0_linux_amd64.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: "2.0.0", OS: "linux", Arch: "amd64"})
+
+	err := mgr.VerifyArtifactIdentity(context.Background(), &model.IndexArtifactDescriptor{Name: "tool", Version: "2.0.0", OS: "linux", Arch: "amd64"}, artifactPath)
+	require.NoError(t, err)
+
+	err = mgr.VerifyArtifactIdentity(context.Background(), &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64"}, artifactPath)
+	require.ErrorIs(t, err, errutils.ErrArtifactInvalid)
+	var mismatch *MetadataMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "version", mismatch.Field)
+	assert.Contains(t, err.Error(), "requested as tool_1.0.0_linux_amd64 but contains tool_2.0.0_linux_amd64")
+
+	err = mgr.VerifyArtifactIdentity(context.Background(), &model.IndexArtifactDescriptor{Name: "other", Version: "2.0.0", OS: "linux", Arch: "amd64"}, artifactPath)
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "name", mismatch.Field)
+}
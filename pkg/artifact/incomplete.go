@@ -0,0 +1,123 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// FindIncomplete returns the names of installed artifacts of which at least one recorded meta or data file
+// does not exist, as left behind by interrupted installs or removals. Only the presence of the files is
+// checked, not their content; use VerifyInstalled for that. The names are sorted.
+func (m *ManagerImpl) FindIncomplete() ([]string, error) {
+	unlock, err := m.readDB()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return m.findIncomplete()
+}
+
+// findIncomplete implements FindIncomplete on the loaded database. The caller holds dbMu.
+func (m *ManagerImpl) findIncomplete() ([]string, error) {
+	var names []string
+	for _, artifact := range m.installDB.GetInstalledArtifacts() {
+		incomplete, err := isIncomplete(artifact)
+		if err != nil {
+			return nil, errutils.Wrapf(err, "failed to check files of %s", artifact.Name)
+		}
+		if incomplete {
+			names = append(names, artifact.Name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// CleanupIncomplete completes the rollback of the artifacts reported by FindIncomplete: their remaining
+// recorded files are deleted and their records removed. Artifacts other artifacts still depend on are
+// flagged as missing instead of removed, so they are installed again like any missing dependency.
+// Hooks are not run. The names of the cleaned up artifacts are returned sorted.
+func (m *ManagerImpl) CleanupIncomplete() ([]string, error) {
+	if err := m.installDB.CheckWritable(); err != nil {
+		return nil, err
+	}
+	unlockDB, err := m.lockDB(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer unlockDB()
+
+	if err := m.loadInstalledDB(); err != nil {
+		return nil, err
+	}
+	names, err := m.findIncomplete()
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+	for _, name := range names {
+		artifact := m.installDB.FindArtifact(name)
+		m.cleanupReverseDependencies(m.installDB, artifact)
+		releaseSharedFiles(m.installDB, artifact)
+		m.deleteIncompleteFiles(artifact)
+
+		if len(artifact.ReverseDependencies) == 0 {
+			m.installDB.RemoveArtifact(name)
+			continue
+		}
+		artifact.Status = model.StatusMissing
+		artifact.MetaFiles = make([]model.InstalledFile, 0)
+		artifact.DataFiles = make([]model.InstalledFile, 0)
+	}
+	if err := m.installDB.SaveDatabase(); err != nil {
+		return nil, errutils.Wrap(err, "failed to save database after cleaning up incomplete artifacts")
+	}
+	return names, nil
+}
+
+// isIncomplete reports whether a recorded file of an installed artifact does not exist.
+func isIncomplete(artifact *model.InstalledArtifact) (bool, error) {
+	if artifact.Status != model.StatusInstalled {
+		return false, nil
+	}
+	for _, dir := range []struct {
+		path  string
+		files []model.InstalledFile
+	}{{artifact.ArtifactMetaDir, artifact.MetaFiles}, {artifact.ArtifactDataDir, artifact.DataFiles}} {
+		for _, file := range dir.files {
+			_, err := os.Lstat(filepath.Join(dir.path, filepath.FromSlash(file.Path)))
+			if errors.Is(err, fs.ErrNotExist) {
+				return true, nil
+			}
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// deleteIncompleteFiles deletes the remaining recorded files of an incomplete artifact and the directories
+// emptied by that, including the install directories of the artifact.
+func (m *ManagerImpl) deleteIncompleteFiles(artifact *model.InstalledArtifact) {
+	dirsToCheck := make(map[string]bool)
+	for _, path := range installedFilePaths(artifact) {
+		// Missing files are what makes the artifact incomplete, so they are not reported
+		_ = m.deleteFile(path, dirsToCheck)
+	}
+	m.tryRemoveEmptyDirs(dirsToCheck)
+	for _, dir := range []struct{ path, root string }{
+		{artifact.ArtifactMetaDir, m.artifactMetaInstallDir},
+		{artifact.ArtifactDataDir, m.artifactDataInstallDir},
+	} {
+		if os.Remove(dir.path) == nil {
+			removeEmptyParents(dir.path, dir.root)
+		}
+	}
+}
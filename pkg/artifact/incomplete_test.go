@@ -0,0 +1,70 @@
+package artifact
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindIncomplete_CleanupIncomplete(t *testing.T) {
+	tempDir := t.TempDir()
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+
+	install := func(name string, deps ...model.Dependency) {
+		t.Helper()
+		artifactPath := filepath.Join(tempDir, name+".gotya")
+		setupTestArtifact(t, artifactPath, true, &Metadata{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", Description: "incomplete test"})
+		desc := &model.IndexArtifactDescriptor{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + ".gotya", Dependencies: deps}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	}
+	dataDir := func(name string) string {
+		return mgr.getArtifactDataInstallPath(&model.IndexArtifactDescriptor{Name: name})
+	}
+	metaDir := func(name string) string {
+		return mgr.getArtifactMetaInstallPath(&model.IndexArtifactDescriptor{Name: name})
+	}
+
+	install("lib")
+	install("app", model.Dependency{Name: "lib"})
+	install("broken")
+	install("intact")
+
+	names, err := mgr.FindIncomplete()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	require.NoError(t, os.Remove(filepath.Join(dataDir("broken"), "datafile1.bin")))
+	require.NoError(t, os.Remove(filepath.Join(dataDir("lib"), "datafile2.bin")))
+
+	names, err = mgr.FindIncomplete()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"broken", "lib"}, names)
+
+	names, err = mgr.CleanupIncomplete()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"broken", "lib"}, names)
+
+	assert.NoDirExists(t, dataDir("broken"))
+	assert.NoDirExists(t, metaDir("broken"))
+	assert.NoDirExists(t, dataDir("lib"))
+	assert.NoDirExists(t, metaDir("lib"))
+	assert.FileExists(t, filepath.Join(dataDir("intact"), "datafile1.bin"))
+
+	require.NoError(t, mgr.loadInstalledDB())
+	assert.Nil(t, mgr.installDB.FindArtifact("broken"))
+	lib := mgr.installDB.FindArtifact("lib")
+	require.NotNil(t, lib, "artifacts other artifacts depend on are flagged instead of removed")
+	assert.Equal(t, model.StatusMissing, lib.Status)
+	assert.Equal(t, []string{"app"}, lib.ReverseDependencies)
+	assert.Empty(t, lib.DataFiles)
+	assert.Equal(t, model.StatusInstalled, mgr.installDB.FindArtifact("app").Status)
+
+	names, err = mgr.FindIncomplete()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
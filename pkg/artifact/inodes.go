@@ -0,0 +1,84 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+// FilesystemStats reports filesystem capacity used by pre-install checks.
+type FilesystemStats interface {
+	// FreeInodes returns the number of inodes available on the filesystem containing path.
+	// limited is false if the filesystem does not have a fixed inode count.
+	FreeInodes(path string) (free uint64, limited bool, err error)
+}
+
+// SetFilesystemStats enables a check that the extraction and install filesystems have enough
+// free inodes for an artifact before it is extracted. Passing nil disables the check.
+// Use SystemFilesystemStats for the statistics of the local filesystems.
+func (m *ManagerImpl) SetFilesystemStats(stats FilesystemStats) {
+	m.filesystemStats = stats
+}
+
+// checkFreeInodes estimates the number of inodes the artifact needs from its manifest and fails with
+// ErrInsufficientInodes if a filesystem it is extracted or installed to has fewer available.
+func (m *ManagerImpl) checkFreeInodes(ctx context.Context, localPath, extractDir string) error {
+	tempDir, err := os.MkdirTemp("", "gotya-inodes-*")
+	if err != nil {
+		return errutils.Wrap(err, "failed to create temp directory")
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	metadataPath := filepath.Join(tempDir, m.metadataFileName())
+	if err := m.archiveExtractor.ExtractFile(ctx, localPath, path.Join(artifactMetaDir, m.metadataFileName()), metadataPath); err != nil {
+		return errutils.Wrap(err, "failed to extract metadata for inode check")
+	}
+	metadata, err := m.parseMetadata(metadataPath)
+	if err != nil {
+		return errutils.Wrap(err, "failed to parse metadata for inode check")
+	}
+	required := estimateRequiredInodes(metadata)
+
+	for _, dir := range []string{extractDir, m.artifactMetaInstallDir, m.artifactDataInstallDir} {
+		existing := nearestExistingDir(dir)
+		free, limited, err := m.filesystemStats.FreeInodes(existing)
+		if err != nil {
+			return errutils.Wrapf(err, "failed to determine free inodes for %s", existing)
+		}
+		if limited && free < required {
+			return errutils.Wrapf(ErrInsufficientInodes, "%s needs about %d inodes but only %d are free on the filesystem of %s",
+				metadata.Name, required, free, existing)
+		}
+	}
+	return nil
+}
+
+// estimateRequiredInodes counts the files in the manifest, the metadata file itself and every directory containing them.
+func estimateRequiredInodes(metadata *Metadata) uint64 {
+	dirs := map[string]bool{artifactMetaDir: true}
+	for relPath := range metadata.Hashes {
+		for dir := path.Dir(relPath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	return uint64(len(metadata.Hashes) + 1 + len(dirs))
+}
+
+// nearestExistingDir returns dir or its closest existing ancestor, so filesystems can be queried
+// for directories that are only created during installation.
+func nearestExistingDir(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil || !errors.Is(err, os.ErrNotExist) {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd)
+
+package artifact
+
+// systemFilesystemStats reports no inode limit on platforms whose statfs is not supported.
+type systemFilesystemStats struct{}
+
+// SystemFilesystemStats returns FilesystemStats backed by the operating system.
+func SystemFilesystemStats() FilesystemStats {
+	return systemFilesystemStats{}
+}
+
+// FreeInodes implements FilesystemStats.
+func (systemFilesystemStats) FreeInodes(string) (uint64, bool, error) {
+	return 0, false, nil
+}
@@ -0,0 +1,81 @@
+package artifact
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFilesystemStats reports a fixed number of free inodes for every path.
+type fakeFilesystemStats struct {
+	free    uint64
+	limited bool
+	queried []string
+}
+
+func (f *fakeFilesystemStats) FreeInodes(path string) (uint64, bool, error) {
+	f.queried = append(f.queried, path)
+	return f.free, f.limited, nil
+}
+
+// extractCountingExtractor counts full extractions.
+type extractCountingExtractor struct {
+	ArchiveExtractor
+	extractAllCalls int
+}
+
+func (e *extractCountingExtractor) ExtractAll(ctx context.Context, archivePath, destDir string) error {
+	e.extractAllCalls++
+	return e.ArchiveExtractor.ExtractAll(ctx, archivePath, destDir)
+}
+
+func TestEstimateRequiredInodes(t *testing.T) {
+	metadata := &Metadata{Hashes: map[string]string{
+		"data/bin/tool":           "a",
+		"data/share/doc/README":   "b",
+		"meta/post-install.tengo": "c",
+	}}
+	// 3 files + metadata file + meta, data, data/bin, data/share, data/share/doc
+	assert.Equal(t, uint64(9), estimateRequiredInodes(metadata))
+}
+
+func TestInstallArtifact_InodeCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		stats       *fakeFilesystemStats
+		expectedErr error
+	}{
+		{name: "insufficient inodes", stats: &fakeFilesystemStats{free: 3, limited: true}, expectedErr: ErrInsufficientInodes},
+		{name: "enough inodes", stats: &fakeFilesystemStats{free: 1000, limited: true}},
+		{name: "unlimited inodes", stats: &fakeFilesystemStats{limited: false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+			extractor := &extractCountingExtractor{ArchiveExtractor: mgr.archiveExtractor}
+			mgr.archiveExtractor = extractor
+			mgr.SetFilesystemStats(tt.stats)
+
+			artifactPath := filepath.Join(tempDir, "many-files.gotya")
+			setupTestArtifact(t, artifactPath, true, &Metadata{Name: "many-files", Version: "1.0.0", OS: "linux", Arch: "amd64", Description: "inode check"})
+			desc := &model.IndexArtifactDescriptor{Name: "many-files", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/many-files.gotya"}
+
+			err := mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual)
+			assert.NotEmpty(t, tt.stats.queried)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.Zero(t, extractor.extractAllCalls, "the check must fail before extracting the artifact")
+				assert.NoDirExists(t, filepath.Join(tempDir, "install", artifactDataDir, "many-files"))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, extractor.extractAllCalls)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+//go:build linux || darwin || freebsd
+
+package artifact
+
+import "syscall"
+
+// systemFilesystemStats reads inode counts with statfs.
+type systemFilesystemStats struct{}
+
+// SystemFilesystemStats returns FilesystemStats backed by the operating system.
+func SystemFilesystemStats() FilesystemStats {
+	return systemFilesystemStats{}
+}
+
+// FreeInodes implements FilesystemStats.
+func (systemFilesystemStats) FreeInodes(path string) (uint64, bool, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, false, err
+	}
+	// Filesystems allocating inodes dynamically (e.g. btrfs, zfs) report a total of zero
+	if stat.Files == 0 {
+		return 0, false, nil
+	}
+	return uint64(stat.Ffree), true, nil
+}
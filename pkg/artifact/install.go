@@ -14,7 +14,12 @@ import (
 
 // installArtifactFiles handles the actual file operations for installing an artifact
 // Returns an error if the installation fails
-func (m *ManagerImpl) installArtifactFiles(artifactName, extractDir string) error {
+func (m *ManagerImpl) installArtifactFiles(desc *model.IndexArtifactDescriptor, extractDir string) error {
+	return installArtifactFilesTo(extractDir, m.getArtifactMetaInstallPath(desc), m.getArtifactDataInstallPath(desc))
+}
+
+// installArtifactFilesTo moves the meta and data directories of an extracted artifact to metaPath and dataPath.
+func installArtifactFilesTo(extractDir, metaPath, dataPath string) error {
 	metaSrcDir := filepath.Join(extractDir, artifactMetaDir)
 	dataSrcDir := filepath.Join(extractDir, artifactDataDir)
 
@@ -23,24 +28,21 @@ func (m *ManagerImpl) installArtifactFiles(artifactName, extractDir string) erro
 		return fmt.Errorf("metadata directory not found in artifact: %w", errutils.ErrFileNotFound)
 	}
 
-	err := os.MkdirAll(m.artifactMetaInstallDir, 0o755)
+	// Install the metadata directory
+	err := os.MkdirAll(filepath.Dir(metaPath), 0o755)
 	if err != nil {
 		return err
 	}
-
-	// Install the metadata directory
-	metaPath := m.getArtifactMetaInstallPath(artifactName)
 	if err := fsutil.Move(metaSrcDir, metaPath); err != nil {
 		return fmt.Errorf("failed to install metadata: %w", err)
 	}
 
 	// Only install data directory if it exists
 	if _, err := os.Stat(dataSrcDir); err == nil {
-		err := os.MkdirAll(m.artifactDataInstallDir, 0o755)
+		err := os.MkdirAll(filepath.Dir(dataPath), 0o755)
 		if err != nil {
 			return err
 		}
-		dataPath := m.getArtifactDataInstallPath(artifactName)
 		if err := fsutil.Move(dataSrcDir, dataPath); err != nil {
 			// Clean up the metadata directory if data installation fails
 			_ = os.RemoveAll(metaPath)
@@ -53,12 +55,15 @@ func (m *ManagerImpl) installArtifactFiles(artifactName, extractDir string) erro
 
 // addArtifactToDatabase adds an installed artifact to the database
 // Returns the list of installed files if successful, or an error
-func (m *ManagerImpl) addArtifactToDatabase(desc *model.IndexArtifactDescriptor, existingReverseDeps []string, reason model.InstallationReason) error {
-	metaPath := m.getArtifactMetaInstallPath(desc.Name)
+func (m *ManagerImpl) addArtifactToDatabase(desc *model.IndexArtifactDescriptor, existingReverseDeps []string, reason model.InstallationReason, essential bool, detail string) error {
+	return m.recordInstalledArtifact(desc, m.getArtifactMetaInstallPath(desc), m.getArtifactDataInstallPath(desc), existingReverseDeps, reason, essential, detail)
+}
 
+// recordInstalledArtifact adds an artifact installed to metaPath and dataPath to the database and saves it.
+func (m *ManagerImpl) recordInstalledArtifact(desc *model.IndexArtifactDescriptor, metaPath, dataPath string, existingReverseDeps []string, reason model.InstallationReason, essential bool, detail string) error {
 	// Read and parse the metadata file
-	metadataFilePath := filepath.Join(metaPath, metadataFile)
-	metadata, err := ParseMetadataFromPath(metadataFilePath)
+	metadataFilePath := filepath.Join(metaPath, m.metadataFileName())
+	metadata, err := m.parseMetadata(metadataFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to parse metadata: %w", err)
 	}
@@ -67,6 +72,9 @@ func (m *ManagerImpl) addArtifactToDatabase(desc *model.IndexArtifactDescriptor,
 	if err != nil {
 		return err
 	}
+	if m.deduplicateDataFiles {
+		m.linkDuplicateDataFiles(desc.Name, dataPath, dataFiles)
+	}
 
 	// Create and add the artifact to the database
 	installedArtifact := &model.InstalledArtifact{
@@ -77,14 +85,18 @@ func (m *ManagerImpl) addArtifactToDatabase(desc *model.IndexArtifactDescriptor,
 		Arch:                desc.Arch,
 		InstalledAt:         time.Now(),
 		InstalledFrom:       desc.URL,
+		SourceRepository:    desc.Repository,
 		ArtifactMetaDir:     metaPath,
-		ArtifactDataDir:     m.getArtifactDataInstallPath(desc.Name),
+		ArtifactDataDir:     dataPath,
 		MetaFiles:           metaFiles,
 		DataFiles:           dataFiles,
 		ReverseDependencies: existingReverseDeps,
 		Status:              model.StatusInstalled,
 		Checksum:            desc.Checksum,
+		ManifestDigest:      metadata.ManifestDigest,
 		InstallationReason:  reason,
+		Essential:           essential,
+		InstallationDetail:  detail,
 	}
 
 	m.recordReverseDependencies(desc)
@@ -107,7 +119,16 @@ func buildInstalledFileEntries(metadata *Metadata, metadataFilePath string) ([]m
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to calculate hash: %w", err)
 	}
-	metaFileEntries = append(metaFileEntries, model.InstalledFile{Path: metadataFile, Hash: hash})
+	metaFileEntries = append(metaFileEntries, model.InstalledFile{Path: filepath.Base(metadataFilePath), Hash: hash})
+	// The signature of signed artifacts is installed next to the metadata file
+	signaturePath := filepath.Join(filepath.Dir(metadataFilePath), SignatureFile)
+	if _, err := os.Stat(signaturePath); err == nil {
+		hash, err := calculateFileHash(signaturePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to calculate hash: %w", err)
+		}
+		metaFileEntries = append(metaFileEntries, model.InstalledFile{Path: SignatureFile, Hash: hash})
+	}
 
 	for relPath, h := range metadata.Hashes {
 		if strings.HasPrefix(relPath, artifactDataDir+"/") {
@@ -125,6 +146,10 @@ func buildInstalledFileEntries(metadata *Metadata, metadataFilePath string) ([]m
 func (m *ManagerImpl) recordReverseDependencies(desc *model.IndexArtifactDescriptor) {
 	for _, dep := range desc.Dependencies {
 		artifact := m.installDB.FindArtifact(dep.Name)
+		if artifact == nil && dep.Optional {
+			// Optional dependencies may legitimately be absent
+			continue
+		}
 		if artifact == nil {
 			// Create a dummy entry for missing dependency
 			artifact = &model.InstalledArtifact{
@@ -152,22 +177,24 @@ func (m *ManagerImpl) recordReverseDependencies(desc *model.IndexArtifactDescrip
 }
 
 // installRollback cleans up any partially installed files in case of an error
-func (m *ManagerImpl) installRollback(artifactName string) {
-	metaPath := m.getArtifactMetaInstallPath(artifactName)
+func (m *ManagerImpl) installRollback(desc *model.IndexArtifactDescriptor) {
+	metaPath := m.getArtifactMetaInstallPath(desc)
 	_ = os.RemoveAll(metaPath)
+	removeEmptyParents(metaPath, m.artifactMetaInstallDir)
 
-	dataPath := m.getArtifactDataInstallPath(artifactName)
+	dataPath := m.getArtifactDataInstallPath(desc)
 	_ = os.RemoveAll(dataPath)
+	removeEmptyParents(dataPath, m.artifactDataInstallDir)
 }
 
 // performInstallation contains the core installation logic
-func (m *ManagerImpl) performInstallation(extractDir string, desc *model.IndexArtifactDescriptor, reason model.InstallationReason, existingReverseDeps []string) error {
-	if err := m.installArtifactFiles(desc.Name, extractDir); err != nil {
+func (m *ManagerImpl) performInstallation(extractDir string, desc *model.IndexArtifactDescriptor, reason model.InstallationReason, existingReverseDeps []string, essential bool, detail string) error {
+	if err := m.installArtifactFiles(desc, extractDir); err != nil {
 		return fmt.Errorf("failed to install artifact files: %w", err)
 	}
 
 	// Add the installed artifact to the database
-	err := m.addArtifactToDatabase(desc, existingReverseDeps, reason)
+	err := m.addArtifactToDatabase(desc, existingReverseDeps, reason, essential, detail)
 	if err != nil {
 		return fmt.Errorf("failed to update artifact database: %w", err)
 	}
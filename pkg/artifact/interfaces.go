@@ -3,6 +3,7 @@ package artifact
 
 import (
 	"context"
+	"time"
 
 	"github.com/glorpus-work/gotya/pkg/model"
 )
@@ -12,7 +13,13 @@ type Manager interface {
 	// InstallArtifact installs (verifies/stages) an artifact strictly from a local file.
 	// The descriptor must describe the artifact and localPath must point to the local archive file.
 	InstallArtifact(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string, reason model.InstallationReason) error
+	// InstallArtifactWithDetail is InstallArtifact that also records detail, why the artifact was installed.
+	InstallArtifactWithDetail(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string, reason model.InstallationReason, detail string) error
+	// InstallArtifactDryRun reports where InstallArtifact would install the files of an artifact without installing it.
+	InstallArtifactDryRun(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string) (*InstallPreview, error)
 	UninstallArtifact(ctx context.Context, artifactName string, purge bool) error
+	// UninstallPreview returns the files and directories UninstallArtifact would delete, without deleting them.
+	UninstallPreview(artifactName string, purge bool) ([]string, []string, error)
 	// UpdateArtifact updates an installed artifact by replacing it with a new version.
 	// Uses the simple approach: uninstall the old version, then install the new version.
 	UpdateArtifact(ctx context.Context, newArtifactPath string, newDescriptor *model.IndexArtifactDescriptor) error
@@ -24,7 +31,43 @@ type Manager interface {
 	GetOrphanedAutomaticArtifacts() ([]string, error)
 	// GetInstalledArtifacts returns all installed artifacts
 	GetInstalledArtifacts() ([]*model.InstalledArtifact, error)
+	// InstalledNames returns the sorted names of all installed artifacts
+	InstalledNames() ([]string, error)
+	// InstalledBetween returns the installed artifacts installed in [start, end); zero times leave the range open
+	InstalledBetween(start, end time.Time) ([]*model.InstalledArtifact, error)
+	// WhichArtifactOwns returns the installed artifact that recorded the file at path
+	WhichArtifactOwns(path string) (*model.InstalledArtifact, *model.InstalledFile, error)
+	// ListArtifactFiles returns the recorded files of an installed artifact with absolute paths and hashes
+	ListArtifactFiles(name string) ([]model.InstalledFile, error)
+	// DetectVersionDrift lists installed artifacts whose installed metadata version differs from the database record
+	DetectVersionDrift() ([]DriftEntry, error)
+	// VerifyInstalled reports installed artifacts whose files are modified, missing or not recorded in the database
+	VerifyInstalled(ctx context.Context) ([]VerificationResult, error)
+	// SetVerifyConcurrency sets how many artifacts VerifyInstalled checks in parallel.
+	SetVerifyConcurrency(concurrency int)
+	// RepairArtifact restores missing or modified files of an installed artifact from the artifact file at localPath
+	RepairArtifact(ctx context.Context, name, localPath string) error
+	// FindIncomplete lists installed artifacts with recorded files missing, e.g. left by interrupted installs
+	FindIncomplete() ([]string, error)
+	// CleanupIncomplete removes the remains of the artifacts reported by FindIncomplete
+	CleanupIncomplete() ([]string, error)
 	SetArtifactManuallyInstalled(artifactName string) error
+	// SetArtifactInstallationDetail records why an artifact was installed, e.g. which artifact required it.
+	SetArtifactInstallationDetail(artifactName, detail string) error
+	// SetArtifactEssential marks or unmarks an installed artifact as essential, protecting it from removal.
+	SetArtifactEssential(artifactName string, essential bool) error
+	// SetAllowEssentialRemoval allows uninstalling and cleaning up essential artifacts.
+	SetAllowEssentialRemoval(allow bool)
+	// SetAllowDowngrade allows UpdateArtifact to install a lower version than the installed one.
+	SetAllowDowngrade(allow bool)
+	// SetForceReinstall allows UpdateArtifact to reinstall the version and URL that are already installed.
+	SetForceReinstall(force bool)
+	// SetPostInstallHookFailurePolicy sets whether a failing post-install hook rolls back the installation.
+	SetPostInstallHookFailurePolicy(policy HookFailurePolicy)
+	// SetStrictUninstall makes UninstallArtifact fail if any recorded file remains after removal.
+	SetStrictUninstall(strict bool)
+	// AcquireOperationLock takes the lock preventing concurrent mutating operations on the install tree.
+	AcquireOperationLock(ctx context.Context) (func(), error)
 }
 
 // ArchiveExtractor defines the interface for extracting artifacts from archives.
@@ -0,0 +1,43 @@
+package artifact
+
+import "sync"
+
+// keyedMutex provides one mutex per key, so operations on the same key are serialized
+// while operations on different keys proceed in parallel. The zero value is ready to use.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+// keyedLock is the mutex for a single key together with the number of goroutines holding or waiting for it.
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock blocks until the mutex for key is held and returns the function releasing it.
+func (k *keyedMutex) Lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyedLock)
+	}
+	lock, ok := k.locks[key]
+	if !ok {
+		lock = &keyedLock{}
+		k.locks[key] = lock
+	}
+	lock.refs++
+	k.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		k.mu.Lock()
+		// Drop the entry once nobody uses it so the map does not grow with every name ever seen
+		lock.refs--
+		if lock.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
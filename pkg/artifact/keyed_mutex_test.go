@@ -0,0 +1,154 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrencyTrackingExtractor records how many extractions of the same archive run at once.
+type concurrencyTrackingExtractor struct {
+	ArchiveExtractor
+	mu          sync.Mutex
+	active      map[string]int
+	maxSameName int
+	activeTotal int
+	maxTotal    int
+}
+
+func (e *concurrencyTrackingExtractor) ExtractAll(ctx context.Context, archivePath, destDir string) error {
+	e.mu.Lock()
+	e.active[archivePath]++
+	e.activeTotal++
+	e.maxSameName = max(e.maxSameName, e.active[archivePath])
+	e.maxTotal = max(e.maxTotal, e.activeTotal)
+	e.mu.Unlock()
+
+	// Widen the window in which overlapping installs would interleave
+	time.Sleep(20 * time.Millisecond)
+	err := e.ArchiveExtractor.ExtractAll(ctx, archivePath, destDir)
+
+	e.mu.Lock()
+	e.active[archivePath]--
+	e.activeTotal--
+	e.mu.Unlock()
+	return err
+}
+
+func TestKeyedMutex_RemovesUnusedKeys(t *testing.T) {
+	var k keyedMutex
+	unlockA := k.Lock("a")
+	unlockB := k.Lock("b") // a different key must not block
+
+	done := make(chan struct{})
+	go func() {
+		unlock := k.Lock("a")
+		unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("second lock of the same key acquired while held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlockA()
+	<-done
+	unlockB()
+	assert.Empty(t, k.locks)
+}
+
+func TestInstallArtifact_ConcurrentInstalls(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), dbPath)
+	extractor := &concurrencyTrackingExtractor{ArchiveExtractor: mgr.archiveExtractor, active: make(map[string]int)}
+	mgr.archiveExtractor = extractor
+
+	names := []string{"alpha", "beta", "gamma", "delta"}
+	descs := make(map[string]*model.IndexArtifactDescriptor, len(names))
+	paths := make(map[string]string, len(names))
+	for _, name := range names {
+		paths[name] = filepath.Join(tempDir, name+".gotya")
+		setupTestArtifact(t, paths[name], true, &Metadata{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", Description: name})
+		descs[name] = &model.IndexArtifactDescriptor{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + ".gotya"}
+	}
+
+	const installsPerName = 3
+	const readers = 2
+	var wg sync.WaitGroup
+	errs := make(chan error, len(names)*installsPerName+2*readers)
+	for i := 0; i < installsPerName; i++ {
+		for _, name := range names {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := mgr.InstallArtifact(context.Background(), descs[name], paths[name], model.InstallationReasonManual); err != nil {
+					errs <- fmt.Errorf("%s: %w", name, err)
+				}
+			}()
+		}
+	}
+
+	// Readers load the shared database while the installs modify it
+	done := make(chan struct{})
+	var readersWG sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		readersWG.Add(2)
+		go func() {
+			defer readersWG.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := mgr.GetInstalledArtifacts(); err != nil {
+					errs <- fmt.Errorf("GetInstalledArtifacts: %w", err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer readersWG.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := mgr.InstalledNames(); err != nil {
+					errs <- fmt.Errorf("InstalledNames: %w", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	readersWG.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, extractor.maxSameName, "installs of the same artifact must not overlap")
+	assert.Greater(t, extractor.maxTotal, 1, "installs of different artifacts should run in parallel")
+
+	db := loadInstalledDB(t, dbPath)
+	installed := db.GetInstalledArtifacts()
+	require.Len(t, installed, len(names))
+	for _, name := range names {
+		artifact := db.FindArtifact(name)
+		require.NotNil(t, artifact, name)
+		assert.Equal(t, model.StatusInstalled, artifact.Status)
+		assert.FileExists(t, filepath.Join(tempDir, "install", artifactDataDir, name, "datafile1.bin"))
+	}
+}
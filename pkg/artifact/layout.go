@@ -0,0 +1,123 @@
+package artifact
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// DefaultPathTemplate places the meta and data directories of an artifact directly below the
+// configured install directories, e.g. <dataDir>/<name>.
+const DefaultPathTemplate = "{name}"
+
+// pathPlaceholders are the placeholders a path template may contain.
+var pathPlaceholders = []string{"{name}", "{version}", "{os}", "{arch}"}
+
+var pathPlaceholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// validatePathTemplate ensures a path template only uses known placeholders, includes {name} so
+// different artifacts never share a directory, and stays below the install directory.
+func validatePathTemplate(template string) error {
+	for _, placeholder := range pathPlaceholderPattern.FindAllString(template, -1) {
+		if !slices.Contains(pathPlaceholders, placeholder) {
+			return errutils.Wrapf(errutils.ErrValidation, "unknown placeholder %s in path template %q", placeholder, template)
+		}
+	}
+	if !strings.Contains(template, "{name}") {
+		return errutils.Wrapf(errutils.ErrValidation, "path template %q must contain {name}", template)
+	}
+	if !filepath.IsLocal(filepath.FromSlash(template)) {
+		return errutils.Wrapf(errutils.ErrValidation, "path template %q must be a relative path below the install directory", template)
+	}
+	return nil
+}
+
+// renderPathTemplate substitutes the placeholders of template with the fields of desc.
+func renderPathTemplate(template string, desc *model.IndexArtifactDescriptor) string {
+	return strings.NewReplacer(
+		"{name}", desc.Name,
+		"{version}", desc.Version,
+		"{os}", desc.OS,
+		"{arch}", desc.Arch,
+	).Replace(template)
+}
+
+// SetMetaPathTemplate sets where the meta directory of an artifact is installed, relative to the meta
+// install directory. The template may use the placeholders {name}, {version}, {os} and {arch} and must
+// contain {name}. It defaults to DefaultPathTemplate.
+// The directories are recorded per installed artifact, so changing the template does not affect
+// uninstalling artifacts installed with a previous one.
+func (m *ManagerImpl) SetMetaPathTemplate(template string) error {
+	if err := validatePathTemplate(template); err != nil {
+		return err
+	}
+	m.metaPathTemplate = template
+	return nil
+}
+
+// SetDataPathTemplate sets where the data directory of an artifact is installed, relative to the data
+// install directory. Use e.g. "{name}/{version}" to keep the data of different versions side by side.
+// The same rules as for SetMetaPathTemplate apply.
+func (m *ManagerImpl) SetDataPathTemplate(template string) error {
+	if err := validatePathTemplate(template); err != nil {
+		return err
+	}
+	m.dataPathTemplate = template
+	return nil
+}
+
+// checkInstallPaths rejects descriptors whose fields would place the artifact outside the install directories.
+func (m *ManagerImpl) checkInstallPaths(desc *model.IndexArtifactDescriptor) error {
+	for _, template := range []string{orDefaultPathTemplate(m.metaPathTemplate), orDefaultPathTemplate(m.dataPathTemplate)} {
+		if rel := renderPathTemplate(template, desc); !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return errutils.Wrapf(errutils.ErrInvalidPath, "install path %q of %s is not below the install directory", rel, desc.GetID())
+		}
+	}
+	return nil
+}
+
+// checkInstallDirsWritable verifies that the meta and data directories of the artifact can be created and
+// written, so an install fails with ErrDirectoryNotWritable before anything is extracted. Each directory,
+// or its closest existing ancestor if it does not exist yet, is probed by creating a temporary file in it.
+func (m *ManagerImpl) checkInstallDirsWritable(desc *model.IndexArtifactDescriptor) error {
+	for _, dir := range []string{m.getArtifactMetaInstallPath(desc), m.getArtifactDataInstallPath(desc)} {
+		existing := nearestExistingDir(dir)
+		probe, err := os.CreateTemp(existing, ".gotya-write-probe-*")
+		switch {
+		case err == nil:
+			_ = probe.Close()
+			_ = os.Remove(probe.Name())
+		case errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS):
+			return errutils.Wrapf(ErrDirectoryNotWritable, "cannot create %s: %s is not writable", dir, existing)
+		default:
+			return errutils.Wrapf(err, "failed to check whether %s is writable", existing)
+		}
+	}
+	return nil
+}
+
+// orDefaultPathTemplate returns template, or DefaultPathTemplate if template is empty.
+func orDefaultPathTemplate(template string) string {
+	if template == "" {
+		return DefaultPathTemplate
+	}
+	return template
+}
+
+// removeEmptyParents removes the empty directories between dir and root, starting at the parent of dir.
+// root itself is kept.
+func removeEmptyParents(dir, root string) {
+	for parent := filepath.Dir(dir); parent != root && strings.HasPrefix(parent, root+string(filepath.Separator)); parent = filepath.Dir(parent) {
+		if os.Remove(parent) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package artifact
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePathTemplate(t *testing.T) {
+	for _, template := range []string{"{name}", "{name}/{version}", "{os}-{arch}/{name}", "apps/{name}"} {
+		assert.NoError(t, validatePathTemplate(template), template)
+	}
+	for _, template := range []string{"", "{version}", "{name}/{release}", "../{name}", "/opt/{name}", "{name}/../.."} {
+		assert.ErrorIs(t, validatePathTemplate(template), errutils.ErrValidation, template)
+	}
+}
+
+func TestPathTemplates_SideBySideVersions(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	metaDir := filepath.Join(tempDir, "install", artifactMetaDir)
+
+	// Two environments share the install directories but keep their own database
+	newManager := func(db string) *ManagerImpl {
+		mgr := NewManager("linux", "amd64", tempDir, dataDir, metaDir, filepath.Join(tempDir, db))
+		require.NoError(t, mgr.SetDataPathTemplate("{name}/{version}"))
+		require.NoError(t, mgr.SetMetaPathTemplate("{name}/{version}"))
+		return mgr
+	}
+	install := func(mgr *ManagerImpl, version string) {
+		artifactPath := filepath.Join(tempDir, "tool-"+version+".gotya")
+		setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: version, OS: "linux", Arch: "amd64"})
+		desc := &model.IndexArtifactDescriptor{Name: "tool", Version: version, OS: "linux", Arch: "amd64", URL: "http://example.com/tool-" + version + ".gotya"}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	}
+
+	oldMgr, newMgr := newManager("old.db"), newManager("new.db")
+	install(oldMgr, "1.0.0")
+	install(newMgr, "2.0.0")
+
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		assert.FileExists(t, filepath.Join(dataDir, "tool", version, "datafile1.bin"))
+		assert.FileExists(t, filepath.Join(metaDir, "tool", version, DefaultMetadataFile))
+	}
+
+	installed, err := oldMgr.GetInstalledArtifacts()
+	require.NoError(t, err)
+	require.Len(t, installed, 1)
+	assert.Equal(t, filepath.Join(dataDir, "tool", "1.0.0"), installed[0].ArtifactDataDir)
+	assert.Equal(t, filepath.Join(metaDir, "tool", "1.0.0"), installed[0].ArtifactMetaDir)
+
+	// Uninstalling uses the recorded directories, even after the template changed
+	require.NoError(t, oldMgr.SetDataPathTemplate(DefaultPathTemplate))
+	require.NoError(t, oldMgr.SetMetaPathTemplate(DefaultPathTemplate))
+	require.NoError(t, oldMgr.UninstallArtifact(context.Background(), "tool", true))
+	assert.NoDirExists(t, filepath.Join(dataDir, "tool", "1.0.0"))
+	assert.NoDirExists(t, filepath.Join(metaDir, "tool", "1.0.0"))
+	assert.FileExists(t, filepath.Join(dataDir, "tool", "2.0.0", "datafile1.bin"))
+
+	require.NoError(t, newMgr.UninstallArtifact(context.Background(), "tool", true))
+	assert.NoDirExists(t, filepath.Join(dataDir, "tool"))
+	assert.NoDirExists(t, filepath.Join(metaDir, "tool"))
+	assert.DirExists(t, dataDir)
+}
+
+func TestPathTemplates_Update(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+	require.NoError(t, mgr.SetDataPathTemplate("{name}/{version}"))
+
+	descriptor := func(version string) (*model.IndexArtifactDescriptor, string) {
+		artifactPath := filepath.Join(tempDir, "tool-"+version+".gotya")
+		setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: version, OS: "linux", Arch: "amd64"})
+		return &model.IndexArtifactDescriptor{Name: "tool", Version: version, OS: "linux", Arch: "amd64", URL: "http://example.com/tool-" + version + ".gotya"}, artifactPath
+	}
+
+	desc, artifactPath := descriptor("1.0.0")
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	desc, artifactPath = descriptor("2.0.0")
+	require.NoError(t, mgr.UpdateArtifact(context.Background(), artifactPath, desc))
+
+	assert.NoDirExists(t, filepath.Join(dataDir, "tool", "1.0.0"))
+	assert.FileExists(t, filepath.Join(dataDir, "tool", "2.0.0", "datafile1.bin"))
+}
+
+func TestPathTemplates_RejectsEscapingFields(t *testing.T) {
+	tempDir := t.TempDir()
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "data"), filepath.Join(tempDir, "meta"), filepath.Join(tempDir, "installed.db"))
+	require.NoError(t, mgr.SetDataPathTemplate("{name}/{version}"))
+
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "../../escape", URL: "http://example.com/tool.gotya"}
+	err := mgr.InstallArtifact(context.Background(), desc, filepath.Join(tempDir, "tool.gotya"), model.InstallationReasonManual)
+	require.ErrorIs(t, err, errutils.ErrInvalidPath)
+}
+
+func TestInstallArtifact_ReadOnlyMetaDir(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("skipping read-only directory test on Windows and as root")
+	}
+
+	tempDir := t.TempDir()
+	metaDir := filepath.Join(tempDir, "install", artifactMetaDir)
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	require.NoError(t, os.MkdirAll(metaDir, 0o755))
+	require.NoError(t, os.Chmod(metaDir, 0o555))
+	t.Cleanup(func() { _ = os.Chmod(metaDir, 0o755) })
+
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, metaDir, filepath.Join(tempDir, "installed.db"))
+	extractor := &extractCountingExtractor{ArchiveExtractor: mgr.archiveExtractor}
+	mgr.archiveExtractor = extractor
+
+	artifactPath := filepath.Join(tempDir, "tool.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", Description: "read-only meta dir"})
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+
+	err := mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual)
+	require.ErrorIs(t, err, ErrDirectoryNotWritable)
+	assert.ErrorContains(t, err, metaDir)
+	assert.Zero(t, extractor.extractAllCalls, "the check must fail before extracting the artifact")
+	assert.NoDirExists(t, dataDir)
+	assert.NoFileExists(t, filepath.Join(tempDir, "installed.db"))
+}
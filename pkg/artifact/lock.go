@@ -0,0 +1,187 @@
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+const (
+	// operationLockSuffix is appended to the installed database path to name the operation lock file.
+	operationLockSuffix = ".operation.lock"
+	// operationLockFile is the name of the lock file created in the data install directory
+	// if the manager has no installed database path.
+	operationLockFile = ".gotya.lock"
+	// DefaultOperationLockTimeout is how long AcquireOperationLock waits for another holder by default.
+	DefaultOperationLockTimeout = 30 * time.Second
+	// operationLockPollInterval is how often a held lock is re-checked while waiting.
+	operationLockPollInterval = 100 * time.Millisecond
+	// operationLockWriteGracePeriod is how long a lock file without a readable holder is assumed to be
+	// still being written by the process that created it.
+	operationLockWriteGracePeriod = 5 * time.Second
+)
+
+// OperationLockHolder describes the process holding the operation lock.
+type OperationLockHolder struct {
+	PID        int       `json:"pid"`
+	Hostname   string    `json:"hostname"`
+	Command    string    `json:"command"`
+	AcquiredAt time.Time `json:"acquired_at"`
+}
+
+// String returns a human-readable description of the holder.
+func (h *OperationLockHolder) String() string {
+	return fmt.Sprintf("pid %d on %s (%s) since %s", h.PID, h.Hostname, h.Command, h.AcquiredAt.Format(time.RFC3339))
+}
+
+// SetOperationLockTimeout sets how long AcquireOperationLock waits for the lock.
+// Values <= 0 use DefaultOperationLockTimeout.
+func (m *ManagerImpl) SetOperationLockTimeout(timeout time.Duration) {
+	m.operationLockTimeout = timeout
+}
+
+// AcquireOperationLock takes the process-wide lock guarding the install tree against concurrent
+// mutating operations. It waits until the lock is free, the configured timeout elapses or ctx is done.
+// Locks left behind by a process on this host that is no longer running are taken over, as are lock files
+// without a readable holder that are older than a short grace period, left behind by a crash while writing.
+// The returned release function removes the lock and is safe to call more than once.
+func (m *ManagerImpl) AcquireOperationLock(ctx context.Context) (func(), error) {
+	lockPath := m.operationLockPath()
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return nil, errutils.Wrapf(err, "failed to create lock directory %s", filepath.Dir(lockPath))
+	}
+
+	timeout := m.operationLockTimeout
+	if timeout <= 0 {
+		timeout = DefaultOperationLockTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		acquired, err := tryCreateLockFile(lockPath)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			var once sync.Once
+			return func() { once.Do(func() { _ = os.Remove(lockPath) }) }, nil
+		}
+
+		holder, err := readLockHolder(lockPath)
+		if err != nil && !os.IsNotExist(err) && removeAbandonedLockFile(lockPath) {
+			continue
+		}
+		if holder != nil && holder.isStale() {
+			// The holder crashed without releasing the lock; re-check to avoid removing a lock that was just retaken
+			if current, err := readLockHolder(lockPath); err == nil && current.PID == holder.PID && current.AcquiredAt.Equal(holder.AcquiredAt) {
+				_ = os.Remove(lockPath)
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			desc := "unknown holder"
+			if holder != nil {
+				desc = holder.String()
+			}
+			return nil, errutils.Wrapf(ErrOperationLocked, "%s is held by %s", lockPath, desc)
+		case <-time.After(operationLockPollInterval):
+		}
+	}
+}
+
+// operationLockPath returns the path of the operation lock file. It lies next to the installed database,
+// so it does not end up among the installed data files.
+func (m *ManagerImpl) operationLockPath() string {
+	if m.installedDBPath == "" {
+		return filepath.Join(m.artifactDataInstallDir, operationLockFile)
+	}
+	return m.installedDBPath + operationLockSuffix
+}
+
+// tryCreateLockFile atomically creates the lock file and records the current process as holder.
+// It reports false if the lock file already exists.
+func tryCreateLockFile(lockPath string) (bool, error) {
+	file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errutils.Wrapf(err, "failed to create lock file %s", lockPath)
+	}
+
+	hostname, _ := os.Hostname()
+	holder := OperationLockHolder{
+		PID:        os.Getpid(),
+		Hostname:   hostname,
+		Command:    strings.Join(os.Args, " "),
+		AcquiredAt: time.Now(),
+	}
+	err = json.NewEncoder(file).Encode(holder)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(lockPath)
+		return false, errutils.Wrapf(err, "failed to write lock file %s", lockPath)
+	}
+	return true, nil
+}
+
+// readLockHolder reads the holder recorded in the lock file.
+func readLockHolder(lockPath string) (*OperationLockHolder, error) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	var holder OperationLockHolder
+	if err := json.Unmarshal(data, &holder); err != nil {
+		return nil, err
+	}
+	return &holder, nil
+}
+
+// removeAbandonedLockFile removes the lock file if it was last modified more than
+// operationLockWriteGracePeriod ago; the caller could not read a holder from it. It reports whether the
+// file was removed.
+func removeAbandonedLockFile(lockPath string) bool {
+	st, err := os.Stat(lockPath)
+	if err != nil || time.Since(st.ModTime()) < operationLockWriteGracePeriod {
+		return false
+	}
+	// Re-check to avoid removing a lock that was just retaken
+	if _, err := readLockHolder(lockPath); err == nil {
+		return false
+	}
+	current, err := os.Stat(lockPath)
+	if err != nil || !os.SameFile(st, current) || !current.ModTime().Equal(st.ModTime()) {
+		return false
+	}
+	return os.Remove(lockPath) == nil
+}
+
+// isStale reports whether the holder is a process on this host that no longer exists.
+// Holders on other hosts are never considered stale.
+func (h *OperationLockHolder) isStale() bool {
+	hostname, err := os.Hostname()
+	if err != nil || hostname != h.Hostname || h.PID <= 0 {
+		return false
+	}
+	proc, err := os.FindProcess(h.PID)
+	if err != nil {
+		return true
+	}
+	// Signal 0 only checks for existence; errors other than "done" (e.g. permissions) mean it is alive
+	return errors.Is(proc.Signal(syscall.Signal(0)), os.ErrProcessDone)
+}
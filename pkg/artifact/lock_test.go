@@ -0,0 +1,117 @@
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLockTestManager(t *testing.T) *ManagerImpl {
+	t.Helper()
+	tempDir := t.TempDir()
+	return NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+}
+
+func TestAcquireOperationLock_SecondAcquisitionFails(t *testing.T) {
+	first := newLockTestManager(t)
+	release, err := first.AcquireOperationLock(context.Background())
+	require.NoError(t, err)
+
+	// A second manager on the same install tree stands in for another process
+	second := NewManager("linux", "amd64", t.TempDir(), first.artifactDataInstallDir, first.artifactMetaInstallDir, first.installedDBPath)
+	second.SetOperationLockTimeout(200 * time.Millisecond)
+
+	start := time.Now()
+	_, err = second.AcquireOperationLock(context.Background())
+	require.ErrorIs(t, err, ErrOperationLocked)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond, "acquisition should wait for the timeout")
+	assert.Contains(t, err.Error(), "pid")
+
+	release()
+	release() // releasing twice is harmless
+
+	releaseSecond, err := second.AcquireOperationLock(context.Background())
+	require.NoError(t, err)
+	assert.FileExists(t, first.installedDBPath+operationLockSuffix, "the lock lies next to the installed database")
+	assert.NoDirExists(t, first.artifactDataInstallDir, "the lock must not be created among the installed data files")
+	releaseSecond()
+	assert.NoFileExists(t, first.installedDBPath+operationLockSuffix)
+}
+
+func TestAcquireOperationLock_WaitsForRelease(t *testing.T) {
+	mgr := newLockTestManager(t)
+	release, err := mgr.AcquireOperationLock(context.Background())
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		release()
+	}()
+
+	mgr.SetOperationLockTimeout(5 * time.Second)
+	start := time.Now()
+	releaseSecond, err := mgr.AcquireOperationLock(context.Background())
+	require.NoError(t, err)
+	defer releaseSecond()
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond, "acquisition should block while the lock is held")
+}
+
+func TestAcquireOperationLock_ContextCancelled(t *testing.T) {
+	mgr := newLockTestManager(t)
+	release, err := mgr.AcquireOperationLock(context.Background())
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = mgr.AcquireOperationLock(ctx)
+	require.ErrorIs(t, err, ErrOperationLocked)
+}
+
+func TestAcquireOperationLock_TakesOverStaleLock(t *testing.T) {
+	mgr := newLockTestManager(t)
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	stale, err := json.Marshal(OperationLockHolder{PID: 1 << 30, Hostname: hostname, Command: "gotya install", AcquiredAt: time.Now()})
+	require.NoError(t, err)
+	lockPath := mgr.operationLockPath()
+	require.NoError(t, os.WriteFile(lockPath, stale, 0o644))
+
+	mgr.SetOperationLockTimeout(time.Second)
+	release, err := mgr.AcquireOperationLock(context.Background())
+	require.NoError(t, err)
+	defer release()
+
+	holder, err := readLockHolder(lockPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), holder.PID)
+}
+
+func TestAcquireOperationLock_TakesOverEmptyLockFile(t *testing.T) {
+	mgr := newLockTestManager(t)
+	lockPath := mgr.operationLockPath()
+	require.NoError(t, os.WriteFile(lockPath, nil, 0o644))
+
+	// A lock file that was just created may still be written by its holder
+	mgr.SetOperationLockTimeout(200 * time.Millisecond)
+	_, err := mgr.AcquireOperationLock(context.Background())
+	require.ErrorIs(t, err, ErrOperationLocked)
+
+	// An older one was left behind by a holder that crashed before recording itself
+	old := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(lockPath, old, old))
+	release, err := mgr.AcquireOperationLock(context.Background())
+	require.NoError(t, err)
+	defer release()
+
+	holder, err := readLockHolder(lockPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), holder.PID)
+}
@@ -2,9 +2,14 @@ package artifact
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/glorpus-work/gotya/pkg/archive"
 	"github.com/glorpus-work/gotya/pkg/artifact/database"
@@ -21,30 +26,122 @@ type ManagerImpl struct {
 	artifactCacheDir       string
 	artifactDataInstallDir string
 	artifactMetaInstallDir string
-	verifier               *Verifier
-	archiveExtractor       ArchiveExtractor
-	hookExecutor           HookExecutor
-	installDB              database.InstalledManager
+	// dataPathTemplate and metaPathTemplate derive the directories of an artifact below the install directories
+	dataPathTemplate      string
+	metaPathTemplate      string
+	verifier              *Verifier
+	archiveExtractor      ArchiveExtractor
+	hookExecutor          HookExecutor
+	installDB             database.InstalledManager
+	installedDBPath       string
+	fileModePolicy        FileModePolicy
+	allowEssentialRemoval bool
+	allowDowngrade        bool
+	versionComparator     model.VersionComparator
+	forceReinstall        bool
+	maxMetadataSize       int64
+	operationLockTimeout  time.Duration
+	databaseLockTimeout   time.Duration
+	metadataFile          string
+	filesystemStats       FilesystemStats
+	hostCapabilities      HostCapabilities
+	nameLimits            model.NameLimits
+	// trustedKeys are the keys one of which must have signed installed artifacts; empty disables the check
+	trustedKeys []ed25519.PublicKey
+	// deduplicateDataFiles hardlinks installed data files to identical files of other artifacts
+	deduplicateDataFiles bool
+	// strictUninstall makes UninstallArtifact verify that no recorded files remain
+	strictUninstall bool
+	// postInstallHookFailurePolicy decides whether a failing post-install hook rolls back the install
+	postInstallHookFailurePolicy HookFailurePolicy
+	// unknownStatusPolicy decides whether an installed artifact with an unknown status is reinstalled
+	unknownStatusPolicy UnknownStatusPolicy
+	// hookVars are passed to every hook script
+	hookVars map[string]string
+	// verifyConcurrency is the number of artifacts VerifyInstalled checks in parallel
+	verifyConcurrency int
+	// keepFailedExtract keeps the extract directory of failed operations for inspection
+	keepFailedExtract bool
+	// artifactLocks serializes installs of the same artifact name
+	artifactLocks keyedMutex
+	// dbMu guards installDB across concurrent operations of this manager; see lockDB and readDB
+	dbMu sync.Mutex
 }
 
 // NewManager creates a new artifact manager instance with the specified configuration.
 // It initializes the manager with OS/arch info, cache directories, install directories, and database path.
 func NewManager(operatingSystem, arch, artifactCacheDir, artifactInstallDir, artifactMetaInstallDir, installedDBPath string) *ManagerImpl {
 	return &ManagerImpl{
-		os:                     operatingSystem,
-		arch:                   arch,
-		artifactCacheDir:       artifactCacheDir,
-		artifactDataInstallDir: artifactInstallDir,
-		artifactMetaInstallDir: artifactMetaInstallDir,
-		verifier:               NewVerifier(),
-		archiveExtractor:       archive.NewManager(),
-		hookExecutor:           NewHookExecutor(),
-		installDB:              database.NewInstalledMangerWithPath(installedDBPath),
+		os:                           operatingSystem,
+		arch:                         arch,
+		artifactCacheDir:             artifactCacheDir,
+		artifactDataInstallDir:       artifactInstallDir,
+		artifactMetaInstallDir:       artifactMetaInstallDir,
+		verifier:                     NewVerifier(),
+		archiveExtractor:             archive.NewManager(),
+		hookExecutor:                 NewHookExecutor(),
+		installDB:                    database.NewInstalledMangerWithPath(installedDBPath),
+		installedDBPath:              installedDBPath,
+		fileModePolicy:               FileModePolicyStrict,
+		maxMetadataSize:              DefaultMaxMetadataSize,
+		operationLockTimeout:         DefaultOperationLockTimeout,
+		databaseLockTimeout:          DefaultDatabaseLockTimeout,
+		metadataFile:                 DefaultMetadataFile,
+		nameLimits:                   model.DefaultNameLimits,
+		dataPathTemplate:             DefaultPathTemplate,
+		metaPathTemplate:             DefaultPathTemplate,
+		postInstallHookFailurePolicy: HookFailurePolicyRollback,
+		unknownStatusPolicy:          UnknownStatusPolicyStrict,
 	}
 }
 
+// InstalledNames returns the sorted names of all installed artifacts without copying their records.
+// Placeholder entries for missing dependencies are excluded.
+func (m *ManagerImpl) InstalledNames() ([]string, error) {
+	unlock, err := m.readDB()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return m.installDB.InstalledNames(), nil
+}
+
+// SetHookRetries sets how many times a failing hook is retried, waiting delay between attempts as described
+// for HookExecutorImpl.SetRetries. It only takes effect if the hook executor supports retries, as the default
+// executor does.
+func (m *ManagerImpl) SetHookRetries(count int, delay time.Duration) {
+	if executor, ok := m.hookExecutor.(interface{ SetRetries(int, time.Duration) }); ok {
+		executor.SetRetries(count, delay)
+	}
+}
+
+// SetHookOutputLimit sets how many bytes of hook output are captured and logged per hook run, see
+// HookExecutorImpl.SetOutputLimit. It only takes effect if the hook executor captures output.
+func (m *ManagerImpl) SetHookOutputLimit(limit int) {
+	if executor, ok := m.hookExecutor.(interface{ SetOutputLimit(int) }); ok {
+		executor.SetOutputLimit(limit)
+	}
+}
+
+// SetHookVars sets variables passed to every hook script, which reads them from the vars map of the
+// context module, e.g. vars := import("context").vars.
+func (m *ManagerImpl) SetHookVars(vars map[string]string) {
+	m.hookVars = maps.Clone(vars)
+}
+
+// SetNameLimits sets the maximum lengths of artifact names and versions accepted when installing, updating
+// or staging artifacts. It defaults to model.DefaultNameLimits.
+func (m *ManagerImpl) SetNameLimits(limits model.NameLimits) {
+	m.nameLimits = limits
+}
+
 // SetArtifactManuallyInstalled marks an artifact as manually installed.
 func (m *ManagerImpl) SetArtifactManuallyInstalled(artifactName string) error {
+	unlock, err := m.lockDB(context.Background())
+	if err != nil {
+		return errutils.Wrapf(err, "failure to change artifact install reason for %s", artifactName)
+	}
+	defer unlock()
 	if err := m.loadInstalledDB(); err != nil {
 		return errutils.Wrapf(err, "failure to change artifact install reason for %s", artifactName)
 	}
@@ -56,36 +153,87 @@ func (m *ManagerImpl) SetArtifactManuallyInstalled(artifactName string) error {
 	return m.installDB.SaveDatabase()
 }
 
+// SetArtifactInstallationDetail records a human-readable explanation of why an artifact was installed.
+func (m *ManagerImpl) SetArtifactInstallationDetail(artifactName, detail string) error {
+	unlock, err := m.lockDB(context.Background())
+	if err != nil {
+		return errutils.Wrapf(err, "failure to change artifact installation detail for %s", artifactName)
+	}
+	defer unlock()
+	if err := m.loadInstalledDB(); err != nil {
+		return errutils.Wrapf(err, "failure to change artifact installation detail for %s", artifactName)
+	}
+	artifact := m.installDB.FindArtifact(artifactName)
+	if artifact == nil {
+		return errutils.Wrapf(errutils.ErrArtifactNotFound, "failure to change artifact installation detail for %s", artifactName)
+	}
+	artifact.InstallationDetail = detail
+	return m.installDB.SaveDatabase()
+}
+
 // InstallArtifact installs an artifact from a local file path.
+// Concurrent installs of the same artifact name are serialized; different names are
+// extracted and verified in parallel and only serialize while updating the installed database.
 func (m *ManagerImpl) InstallArtifact(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string, reason model.InstallationReason) error {
+	return m.InstallArtifactWithDetail(ctx, desc, localPath, reason, "")
+}
+
+// InstallArtifactWithDetail installs an artifact like InstallArtifact and records detail, a human-readable
+// explanation of why it was installed, e.g. which artifact required it, in the same database save.
+// The detail of an artifact that is already installed is left as it is.
+func (m *ManagerImpl) InstallArtifactWithDetail(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string, reason model.InstallationReason, detail string) (err error) {
 	// Input validation
 	if desc == nil {
 		return errutils.Wrap(errutils.ErrValidation, "artifact descriptor cannot be nil")
 	}
-	if err := desc.Verify(); err != nil {
+	if err := desc.VerifyWithLimits(m.nameLimits); err != nil {
 		return errutils.Wrap(err, "invalid artifact descriptor")
 	}
+	if err := m.checkInstallPaths(desc); err != nil {
+		return err
+	}
 	if localPath == "" {
 		return errutils.Wrap(errutils.ErrValidation, "local path cannot be empty")
 	}
 
+	unlock := m.artifactLocks.Lock(desc.Name)
+	defer unlock()
+
+	// Fail before extracting anything if the result could not be recorded or installed
+	if err := m.installDB.CheckWritable(); err != nil {
+		return err
+	}
+	if err := m.checkInstallDirsWritable(desc); err != nil {
+		return err
+	}
+
 	var installed bool
-	var err error
-	defer func() {
-		if err != nil && installed {
-			// If we installed files but then failed, clean them up
-			m.installRollback(desc.Name)
-		}
-	}()
 
 	extractDir, err := os.MkdirTemp("", fmt.Sprintf("gotya-extract-%s-%s", desc.Name, desc.Version))
-	defer func() { _ = os.RemoveAll(extractDir) }()
+	if err != nil {
+		return errutils.Wrap(err, "failed to create extract directory")
+	}
+	defer func() { err = m.removeExtractDir(extractDir, err) }()
 
 	err = m.extractAndVerify(ctx, desc, localPath, extractDir)
 	if err != nil {
 		return err
 	}
 
+	// The database is shared by all artifacts, so loading, modifying and saving it must not interleave
+	unlockDB, err := m.lockDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlockDB()
+	// Registered after unlockDB, so the rollback runs while the database is still locked
+	defer func() {
+		if err != nil && installed {
+			// If we installed files but then failed, clean them up
+			m.installRollback(desc)
+		}
+	}()
+
 	// Load or create the installed database
 	err = m.loadInstalledDB()
 	if err != nil {
@@ -100,9 +248,14 @@ func (m *ManagerImpl) InstallArtifact(ctx context.Context, desc *model.IndexArti
 		return nil
 	}
 	var existingReverseDeps []string
+	var essential bool
 	if artifact != nil {
 		existingReverseDeps = artifact.ReverseDependencies
 		reason = artifact.InstallationReason
+		essential = artifact.Essential
+		if detail == "" {
+			detail = artifact.InstallationDetail
+		}
 	}
 
 	err = m.excutePreInstallHook(desc, extractDir)
@@ -111,7 +264,7 @@ func (m *ManagerImpl) InstallArtifact(ctx context.Context, desc *model.IndexArti
 	}
 
 	// Perform the actual installation (includes hook execution)
-	err = m.performInstallation(extractDir, desc, reason, existingReverseDeps)
+	err = m.performInstallation(extractDir, desc, reason, existingReverseDeps, essential, detail)
 	if err != nil {
 		return err
 	}
@@ -119,6 +272,8 @@ func (m *ManagerImpl) InstallArtifact(ctx context.Context, desc *model.IndexArti
 
 	err = m.executePostInstallHook(desc)
 	if err != nil {
+		// A nil result keeps the installation; otherwise the deferred rollback removes the files
+		err = m.handlePostInstallHookFailure(desc, artifact, err)
 		return err
 	}
 
@@ -132,6 +287,12 @@ func (m *ManagerImpl) UninstallArtifact(ctx context.Context, artifactName string
 		return fmt.Errorf("artifact name cannot be empty: %w", errutils.ErrValidation)
 	}
 
+	unlockDB, err := m.lockDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlockDB()
+
 	// Load the installed database
 	if err := m.installDB.LoadDatabase(); err != nil {
 		return fmt.Errorf("failed to load installed database: %w", err)
@@ -146,8 +307,11 @@ func (m *ManagerImpl) UninstallArtifact(ctx context.Context, artifactName string
 	if artifact == nil {
 		return fmt.Errorf("artifact %s not found in database: %w", artifactName, errutils.ErrArtifactNotFound)
 	}
+	if artifact.Essential && !m.allowEssentialRemoval {
+		return fmt.Errorf("refusing to remove %s: %w", artifactName, ErrEssentialArtifact)
+	}
 
-	metadata, err := ParseMetadataFromPath(filepath.Join(artifact.ArtifactMetaDir, metadataFile))
+	metadata, err := m.parseMetadata(filepath.Join(artifact.ArtifactMetaDir, m.metadataFileName()))
 	if err != nil {
 		return err
 	}
@@ -156,10 +320,15 @@ func (m *ManagerImpl) UninstallArtifact(ctx context.Context, artifactName string
 		return err
 	}
 
-	script, err := m.preservePostUninstallHookScript(artifact.ArtifactMetaDir, metadata)
+	scriptDir, scripts, err := m.preservePostUninstallHookScripts(artifact, metadata)
 	if err != nil {
 		return err
 	}
+	if scriptDir != "" {
+		defer func() {
+			_ = os.RemoveAll(scriptDir)
+		}()
+	}
 
 	// Handle purge mode
 	if purge {
@@ -173,46 +342,62 @@ func (m *ManagerImpl) UninstallArtifact(ctx context.Context, artifactName string
 	if err != nil {
 		return err
 	}
-	if script == "" {
-		return nil
+	if len(scripts) > 0 {
+		err = m.executePostUninstallHook(artifact, scripts)
+		if err != nil {
+			return err
+		}
 	}
-	defer func() {
-		_ = os.Remove(script)
-	}()
 
-	err = m.executePostUninstallHook(artifact, script)
-	if err != nil {
-		return err
+	if m.strictUninstall {
+		return m.verifyUninstalled(artifact, purge)
 	}
-
 	return nil
 }
 
 // UpdateArtifact updates an installed artifact by replacing it with a new version.
 // This method uses the simple approach: uninstall the old version, then install the new version.
 // If the installation fails, the old version remains uninstalled.
-func (m *ManagerImpl) UpdateArtifact(ctx context.Context, newArtifactPath string, desc *model.IndexArtifactDescriptor) error {
+// A new version with the same manifest digest keeps the installed data files and only replaces the metadata.
+// Lower versions are rejected with errutils.ErrValidation unless allowed with SetAllowDowngrade.
+func (m *ManagerImpl) UpdateArtifact(ctx context.Context, newArtifactPath string, desc *model.IndexArtifactDescriptor) (err error) {
 	if desc == nil {
 		return errutils.Wrap(errutils.ErrValidation, "new descriptor cannot be nil")
 	}
-	if err := desc.Verify(); err != nil {
+	if err := desc.VerifyWithLimits(m.nameLimits); err != nil {
 		return errutils.Wrap(err, "new descriptor is invalid")
 	}
+	if err := m.checkInstallPaths(desc); err != nil {
+		return err
+	}
 	if newArtifactPath == "" {
 		return errutils.Wrap(errutils.ErrValidation, "new artifact path cannot be empty")
 	}
 
+	unlock := m.artifactLocks.Lock(desc.Name)
+	defer unlock()
+
+	if err := m.installDB.CheckWritable(); err != nil {
+		return err
+	}
+
 	extractDir, err := os.MkdirTemp("", fmt.Sprintf("gotya-extract-%s-%s", desc.Name, desc.Version))
 	if err != nil {
 		return errutils.Wrap(err, "failed to create extract directory")
 	}
-	defer func() { _ = os.RemoveAll(extractDir) }()
+	defer func() { err = m.removeExtractDir(extractDir, err) }()
 
 	err = m.extractAndVerify(ctx, desc, newArtifactPath, extractDir)
 	if err != nil {
 		return err
 	}
 
+	unlockDB, err := m.lockDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlockDB()
+
 	// Load the installed database
 	err = m.loadInstalledDB()
 	if err != nil {
@@ -225,17 +410,34 @@ func (m *ManagerImpl) UpdateArtifact(ctx context.Context, newArtifactPath string
 		return err
 	}
 
+	// The new version may require newer dependencies than the installed ones
+	if err := m.checkDependencyCompatibility(extractDir); err != nil {
+		return err
+	}
+
 	// Execute pre-update hook before uninstalling old version
 	if err := m.executePreUpdateHook(installedArtifact, desc); err != nil {
 		return err
 	}
 
-	tempDataDir, tempMetaDir, err := m.backupInstallationFiles(installedArtifact)
+	// A new version with the same content keeps the installed data files, only the metadata is replaced
+	keepData := m.keepsDataFiles(installedArtifact, desc, extractDir)
+	if keepData {
+		if err := os.RemoveAll(filepath.Join(extractDir, artifactDataDir)); err != nil {
+			return errutils.Wrap(err, "failed to remove unchanged data files from extract directory")
+		}
+	}
+
+	tempDataDir, tempMetaDir, err := m.backupInstallationFiles(installedArtifact, keepData)
 	if err != nil {
 		return err
 	}
 	oldArtifact := m.copyDBArtifact(installedArtifact)
 	m.installDB.RemoveArtifact(installedArtifact.Name)
+	if !keepData {
+		// The old files are removed, so artifacts sharing them now own their copies
+		releaseSharedFiles(m.installDB, installedArtifact)
+	}
 
 	defer func() {
 		if err != nil {
@@ -250,7 +452,7 @@ func (m *ManagerImpl) UpdateArtifact(ctx context.Context, newArtifactPath string
 		}
 	}()
 
-	err = m.performInstallation(extractDir, desc, installedArtifact.InstallationReason, installedArtifact.ReverseDependencies)
+	err = m.performInstallation(extractDir, desc, installedArtifact.InstallationReason, installedArtifact.ReverseDependencies, installedArtifact.Essential, installedArtifact.InstallationDetail)
 	if err != nil {
 		return err
 	}
@@ -273,10 +475,11 @@ func (m *ManagerImpl) VerifyArtifact(ctx context.Context, artifact *model.IndexA
 // ReverseResolve returns the list of artifacts that depend on the given artifact recursively
 func (m *ManagerImpl) ReverseResolve(_ context.Context, req model.ResolveRequest) (model.ResolvedArtifacts, error) {
 	// Load the installed database
-	err := m.loadInstalledDB()
+	unlock, err := m.readDB()
 	if err != nil {
 		return model.ResolvedArtifacts{}, err
 	}
+	defer unlock()
 
 	// Build reverse dependency graph and collect all dependent artifacts
 	dependentArtifacts := m.collectReverseDependencies(req.Name)
@@ -288,9 +491,11 @@ func (m *ManagerImpl) ReverseResolve(_ context.Context, req model.ResolveRequest
 // GetOrphanedAutomaticArtifacts returns all installed artifacts that are automatic and have no reverse dependencies
 func (m *ManagerImpl) GetOrphanedAutomaticArtifacts() ([]string, error) {
 	// Load the installed database
-	if err := m.loadInstalledDB(); err != nil {
-		return nil, fmt.Errorf("failed to load installed database: %w", err)
+	unlock, err := m.readDB()
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 	var orphaned []string
 
 	// Iterate through all installed artifacts
@@ -305,6 +510,11 @@ func (m *ManagerImpl) GetOrphanedAutomaticArtifacts() ([]string, error) {
 			continue
 		}
 
+		// Essential artifacts are never cleaned up unless removal is explicitly allowed
+		if artifact.Essential && !m.allowEssentialRemoval {
+			continue
+		}
+
 		// Check if it has no reverse dependencies
 		if len(artifact.ReverseDependencies) == 0 {
 			orphaned = append(orphaned, artifact.Name)
@@ -317,9 +527,11 @@ func (m *ManagerImpl) GetOrphanedAutomaticArtifacts() ([]string, error) {
 // GetInstalledArtifacts returns all installed artifacts
 func (m *ManagerImpl) GetInstalledArtifacts() ([]*model.InstalledArtifact, error) {
 	// Load the installed database
-	if err := m.loadInstalledDB(); err != nil {
-		return nil, fmt.Errorf("failed to load installed database: %w", err)
+	unlock, err := m.readDB()
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
 	// Get all installed artifacts
 	artifacts := m.installDB.GetInstalledArtifacts()
@@ -335,6 +547,34 @@ func (m *ManagerImpl) GetInstalledArtifacts() ([]*model.InstalledArtifact, error
 	return installed, nil
 }
 
+// InstalledBetween returns the installed artifacts whose InstalledAt lies in [start, end), oldest first.
+// A zero start or end leaves that side of the range open.
+func (m *ManagerImpl) InstalledBetween(start, end time.Time) ([]*model.InstalledArtifact, error) {
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return nil, errutils.Wrapf(errutils.ErrValidation, "end %s is before start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+
+	installed, err := m.GetInstalledArtifacts()
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []*model.InstalledArtifact
+	for _, artifact := range installed {
+		if !start.IsZero() && artifact.InstalledAt.Before(start) {
+			continue
+		}
+		if !end.IsZero() && !artifact.InstalledAt.Before(end) {
+			continue
+		}
+		matching = append(matching, artifact)
+	}
+	slices.SortStableFunc(matching, func(a, b *model.InstalledArtifact) int {
+		return a.InstalledAt.Compare(b.InstalledAt)
+	})
+	return matching, nil
+}
+
 // validateUpdateRequest validates the update request parameters and checks if update is needed
 func (m *ManagerImpl) validateUpdateRequest(newDescriptor *model.IndexArtifactDescriptor) (*model.InstalledArtifact, error) {
 	// Check if the artifact is installed
@@ -353,35 +593,73 @@ func (m *ManagerImpl) validateUpdateRequest(newDescriptor *model.IndexArtifactDe
 	}
 
 	// Check if this is actually an update (different version or URL)
-	if installedArtifact.Version == newDescriptor.Version && installedArtifact.InstalledFrom == newDescriptor.URL {
-		return nil, errutils.Wrapf(errutils.ErrValidation, "artifact %s is already at the latest version", newDescriptor.Name)
+	if !m.forceReinstall && installedArtifact.Version == newDescriptor.Version && installedArtifact.InstalledFrom == newDescriptor.URL {
+		return nil, fmt.Errorf("artifact %s is %w", newDescriptor.Name, errutils.ErrAlreadyUpToDate)
+	}
+
+	// Versions the comparator cannot order are not checked
+	if !m.allowDowngrade {
+		if cmp, err := m.comparator().Compare(newDescriptor.Version, installedArtifact.Version); err == nil && cmp < 0 {
+			return nil, errutils.Wrapf(errutils.ErrValidation, "cannot downgrade %s from %s to %s", newDescriptor.Name, installedArtifact.Version, newDescriptor.Version)
+		}
 	}
 
 	return installedArtifact, nil
 }
 
+// SetAllowDowngrade allows UpdateArtifact to replace an artifact with a lower version. Downgrades are
+// rejected by default.
+func (m *ManagerImpl) SetAllowDowngrade(allow bool) {
+	m.allowDowngrade = allow
+}
+
+// SetVersionComparator sets the ordering used to detect downgrades and to check dependency constraints
+// and host requirements.
+// Passing nil restores the default semantic version ordering.
+func (m *ManagerImpl) SetVersionComparator(compare model.VersionComparator) {
+	m.versionComparator = compare
+}
+
+// comparator returns the configured version comparator or the semantic version default.
+func (m *ManagerImpl) comparator() model.VersionComparator {
+	if m.versionComparator == nil {
+		return model.SemverComparator{}
+	}
+	return m.versionComparator
+}
+
+// SetForceReinstall makes UpdateArtifact reinstall an artifact that is already installed at the same
+// version from the same URL. By default such updates fail with errutils.ErrAlreadyUpToDate.
+func (m *ManagerImpl) SetForceReinstall(force bool) {
+	m.forceReinstall = force
+}
+
 // executePostUpdateHook executes the post-update hook for the artifact
 func (m *ManagerImpl) executePostUpdateHook(newDescriptor *model.IndexArtifactDescriptor, oldVersion string) error {
 	postUpdateContext := &HookContext{
 		ArtifactName:    newDescriptor.Name,
 		ArtifactVersion: newDescriptor.Version,
 		Operation:       "update",
-		MetaDir:         m.getArtifactMetaInstallPath(newDescriptor.Name),
-		DataDir:         m.getArtifactDataInstallPath(newDescriptor.Name),
+		OS:              m.os,
+		Arch:            m.arch,
+		Vars:            m.hookVars,
+		MetaDir:         m.getArtifactMetaInstallPath(newDescriptor),
+		DataDir:         m.getArtifactDataInstallPath(newDescriptor),
 		OldVersion:      oldVersion,
 	}
 
 	// Parse metadata from newly installed artifact's metadata file for hook resolution
-	metadataPath := filepath.Join(m.getArtifactMetaInstallPath(newDescriptor.Name), metadataFile)
-	metadata, err := ParseMetadataFromPath(metadataPath)
+	metadataPath := filepath.Join(m.getArtifactMetaInstallPath(newDescriptor), m.metadataFileName())
+	metadata, err := m.parseMetadata(metadataPath)
 	if err != nil {
 		return err
 	}
-	postUpdateHookPath := m.resolveHookPath(m.getArtifactMetaInstallPath(newDescriptor.Name), "post-update", metadata)
-	if postUpdateHookPath != "" {
-		if err := m.hookExecutor.ExecuteHook(postUpdateHookPath, postUpdateContext); err != nil {
-			return errutils.Wrap(err, "Hook execution failed")
-		}
+	postUpdateHookPaths, err := m.verifiedHookPaths(m.getArtifactMetaInstallPath(newDescriptor), "post-update", metadata, m.installDB.FindArtifact(newDescriptor.Name))
+	if err != nil {
+		return err
+	}
+	if err := m.executeHooks(postUpdateHookPaths, postUpdateContext); err != nil {
+		return errutils.Wrap(err, "Hook execution failed")
 	}
 
 	return nil
@@ -393,23 +671,27 @@ func (m *ManagerImpl) executePreUpdateHook(installedArtifact *model.InstalledArt
 		ArtifactName:    newDescriptor.Name,
 		ArtifactVersion: newDescriptor.Version,
 		Operation:       "update",
+		OS:              m.os,
+		Arch:            m.arch,
+		Vars:            m.hookVars,
 		MetaDir:         installedArtifact.ArtifactMetaDir,
 		DataDir:         installedArtifact.ArtifactDataDir,
 		OldVersion:      installedArtifact.Version,
 	}
 
 	// Parse metadata from installed artifact's metadata file for hook resolution
-	metadataPath := filepath.Join(installedArtifact.ArtifactMetaDir, metadataFile)
-	metadata, err := ParseMetadataFromPath(metadataPath)
+	metadataPath := filepath.Join(installedArtifact.ArtifactMetaDir, m.metadataFileName())
+	metadata, err := m.parseMetadata(metadataPath)
 	if err != nil {
 		return fmt.Errorf("failed to parse metadata for hook resolution: %w", err)
 	}
 
-	preUpdateHookPath := m.resolveHookPath(installedArtifact.ArtifactMetaDir, "pre-update", metadata)
-	if preUpdateHookPath != "" {
-		if err := m.hookExecutor.ExecuteHook(preUpdateHookPath, preUpdateContext); err != nil {
-			return fmt.Errorf("pre-update hook failed: %w", err)
-		}
+	preUpdateHookPaths, err := m.verifiedHookPaths(installedArtifact.ArtifactMetaDir, "pre-update", metadata, installedArtifact)
+	if err != nil {
+		return err
+	}
+	if err := m.executeHooks(preUpdateHookPaths, preUpdateContext); err != nil {
+		return fmt.Errorf("pre-update hook failed: %w", err)
 	}
 
 	return nil
@@ -417,14 +699,49 @@ func (m *ManagerImpl) executePreUpdateHook(installedArtifact *model.InstalledArt
 
 // extractAndVerify extracts and verifies the artifact to a temp directory
 func (m *ManagerImpl) extractAndVerify(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string, extractDir string) error {
+	if m.filesystemStats != nil {
+		if err := m.checkFreeInodes(ctx, localPath, extractDir); err != nil {
+			return err
+		}
+	}
+
 	if err := m.archiveExtractor.ExtractAll(ctx, localPath, extractDir); err != nil {
 		return errutils.Wrap(err, "failed to extract artifact")
 	}
 
-	if err := m.verifier.VerifyArtifactFromPath(ctx, desc, extractDir); err != nil {
+	if len(m.trustedKeys) > 0 {
+		if err := m.verifyTrustedSignature(ctx, desc, extractDir); err != nil {
+			return err
+		}
+	} else if err := m.verifier.VerifyArtifactFromPath(ctx, desc, extractDir); err != nil {
 		return err
 	}
-	return nil
+
+	if m.hostCapabilities != nil {
+		if err := m.checkRequirements(extractDir); err != nil {
+			return err
+		}
+	}
+
+	return m.enforceFileModePolicy(extractDir)
+}
+
+// SetKeepFailedExtract keeps the temporary directory an artifact was extracted to when an install, update,
+// staging or repair fails, so its contents can be inspected; the returned error names the directory.
+// Files already moved into the install directories are not in it anymore. Successful operations always
+// remove it.
+func (m *ManagerImpl) SetKeepFailedExtract(keep bool) {
+	m.keepFailedExtract = keep
+}
+
+// removeExtractDir removes the extract directory of an operation that returned err and returns the error
+// to report. With keepFailedExtract the directory of a failed operation is kept and added to the error.
+func (m *ManagerImpl) removeExtractDir(extractDir string, err error) error {
+	if err != nil && m.keepFailedExtract {
+		return fmt.Errorf("%w (extracted files kept in %s)", err, extractDir)
+	}
+	_ = os.RemoveAll(extractDir)
+	return err
 }
 
 // handleExistingArtifact updates the installation reason for an existing artifact
@@ -453,7 +770,10 @@ func (m *ManagerImpl) handleExistingArtifact(name string, reason model.Installat
 		m.installDB.RemoveArtifact(existingArtifact.Name)
 		return false, existingArtifact, nil
 	default:
-		return false, nil, fmt.Errorf("artifact %s has unknown status: %s: %w", existingArtifact.Name, existingArtifact.Status, errutils.ErrValidation)
+		if err := m.handleUnknownStatus(existingArtifact); err != nil {
+			return false, nil, err
+		}
+		return false, existingArtifact, nil
 	}
 }
 
@@ -465,23 +785,27 @@ func (m *ManagerImpl) excutePreInstallHook(desc *model.IndexArtifactDescriptor,
 		ArtifactName:    desc.Name,
 		ArtifactVersion: desc.Version,
 		Operation:       "install",
+		OS:              m.os,
+		Arch:            m.arch,
+		Vars:            m.hookVars,
 		TempMetaDir:     tempMetaDir,
-		FinalMetaDir:    m.getArtifactMetaInstallPath(desc.Name),
-		FinalDataDir:    m.getArtifactDataInstallPath(desc.Name),
+		FinalMetaDir:    m.getArtifactMetaInstallPath(desc),
+		FinalDataDir:    m.getArtifactDataInstallPath(desc),
 	}
 
 	// Parse metadata from tmpExtractedPath metadata file for hook resolution
-	metadataPath := filepath.Join(tempMetaDir, metadataFile)
-	metadata, err := ParseMetadataFromPath(metadataPath)
+	metadataPath := filepath.Join(tempMetaDir, m.metadataFileName())
+	metadata, err := m.parseMetadata(metadataPath)
 	if err != nil {
 		return fmt.Errorf("failed to parse metadata for hook resolution: %w", err)
 	}
 
-	preInstallHookPath := m.resolveHookPath(tempMetaDir, "pre-install", metadata)
-	if preInstallHookPath != "" {
-		if err := m.hookExecutor.ExecuteHook(preInstallHookPath, hookContext); err != nil {
-			return fmt.Errorf("pre-install hook failed: %w", err)
-		}
+	preInstallHookPaths, err := m.verifiedHookPaths(tempMetaDir, "pre-install", metadata, nil)
+	if err != nil {
+		return err
+	}
+	if err := m.executeHooks(preInstallHookPaths, hookContext); err != nil {
+		return fmt.Errorf("pre-install hook failed: %w", err)
 	}
 	return nil
 }
@@ -489,41 +813,46 @@ func (m *ManagerImpl) excutePreInstallHook(desc *model.IndexArtifactDescriptor,
 // executePostInstallHook runs the post-install hook for the artifact
 func (m *ManagerImpl) executePostInstallHook(desc *model.IndexArtifactDescriptor) error {
 	// Execute post-install hook after successful installation
-	metaPath := m.getArtifactMetaInstallPath(desc.Name)
+	metaPath := m.getArtifactMetaInstallPath(desc)
 	if metaPath != "" {
 		postInstallContext := &HookContext{
 			ArtifactName:    desc.Name,
 			ArtifactVersion: desc.Version,
 			Operation:       "install",
+			OS:              m.os,
+			Arch:            m.arch,
+			Vars:            m.hookVars,
 			MetaDir:         metaPath,
-			DataDir:         m.getArtifactDataInstallPath(desc.Name),
+			DataDir:         m.getArtifactDataInstallPath(desc),
 		}
 
 		// Parse metadata from installed metadata file for hook resolution
-		metadataPath := filepath.Join(metaPath, metadataFile)
-		metadata, err := ParseMetadataFromPath(metadataPath)
+		metadataPath := filepath.Join(metaPath, m.metadataFileName())
+		metadata, err := m.parseMetadata(metadataPath)
 		if err != nil {
 			return fmt.Errorf("failed to parse metadata for hook resolution: %w", err)
 		}
 
-		postInstallHookPath := m.resolveHookPath(metaPath, "post-install", metadata)
-		if postInstallHookPath != "" {
-			if err := m.hookExecutor.ExecuteHook(postInstallHookPath, postInstallContext); err != nil {
-				return fmt.Errorf("post-install hook failed: %w", err)
-			}
+		postInstallHookPaths, err := m.verifiedHookPaths(metaPath, "post-install", metadata, m.installDB.FindArtifact(desc.Name))
+		if err != nil {
+			return err
+		}
+		if err := m.executeHooks(postInstallHookPaths, postInstallContext); err != nil {
+			return fmt.Errorf("post-install hook failed: %w", err)
 		}
 	}
 	return nil
 }
 
-// backupInstallationFiles moves the installation files to a new location
-func (m *ManagerImpl) backupInstallationFiles(installedArtifact *model.InstalledArtifact) (string, string, error) {
+// backupInstallationFiles moves the installation files to a new location.
+// The data files stay in place if keepData is set.
+func (m *ManagerImpl) backupInstallationFiles(installedArtifact *model.InstalledArtifact, keepData bool) (string, string, error) {
 	tempMetaDir, err := os.MkdirTemp(m.artifactMetaInstallDir, fmt.Sprintf(".gotya-update-meta-temp-%s-%s", installedArtifact.Name, installedArtifact.Version))
 	if err != nil {
 		return "", "", errutils.Wrap(err, "failed to create temp meta dir")
 	}
 	var tempDataDir string
-	if len(installedArtifact.DataFiles) > 0 {
+	if len(installedArtifact.DataFiles) > 0 && !keepData {
 		tempDataDir, err := os.MkdirTemp(m.artifactDataInstallDir, fmt.Sprintf(".gotya-update-data-temp-%s-%s", installedArtifact.Name, installedArtifact.Version))
 		if err != nil {
 			return "", tempMetaDir, errutils.Wrap(err, "failed to create temp data dir")
@@ -540,14 +869,24 @@ func (m *ManagerImpl) backupInstallationFiles(installedArtifact *model.Installed
 	return tempDataDir, tempMetaDir, nil
 }
 
+// keepsDataFiles reports whether updating installed to the extracted artifact can leave the installed data
+// files in place, because the new version has the same content and is installed to the same data directory.
+func (m *ManagerImpl) keepsDataFiles(installed *model.InstalledArtifact, desc *model.IndexArtifactDescriptor, extractDir string) bool {
+	metadata, err := m.parseMetadata(filepath.Join(extractDir, artifactMetaDir, m.metadataFileName()))
+	if err != nil {
+		return false
+	}
+	return installed.HasSameContent(metadata.ManifestDigest) && m.getArtifactDataInstallPath(desc) == installed.ArtifactDataDir
+}
+
 func (m *ManagerImpl) restoreInstallationFiles(tempDataDir, tempMetaDir string, installedArtifact *model.InstalledArtifact) error {
 	_ = os.Remove(installedArtifact.ArtifactMetaDir)
-	if err := fsutil.Move(filepath.Join(tempMetaDir, installedArtifact.Name), installedArtifact.ArtifactMetaDir); err != nil {
+	if err := fsutil.Move(filepath.Join(tempMetaDir, filepath.Base(installedArtifact.ArtifactMetaDir)), installedArtifact.ArtifactMetaDir); err != nil {
 		return errutils.Wrapf(err, "failed to move artifact meta from %s to %s", tempMetaDir, m.artifactMetaInstallDir)
 	}
 	if len(tempDataDir) > 0 {
 		_ = os.Remove(installedArtifact.ArtifactDataDir)
-		if err := fsutil.Move(filepath.Join(tempDataDir, installedArtifact.Name), installedArtifact.ArtifactDataDir); err != nil {
+		if err := fsutil.Move(filepath.Join(tempDataDir, filepath.Base(installedArtifact.ArtifactDataDir)), installedArtifact.ArtifactDataDir); err != nil {
 			return errutils.Wrapf(err, "failed to move artifact data from %s to %s", tempDataDir, m.artifactDataInstallDir)
 		}
 	}
@@ -578,22 +917,37 @@ func (m *ManagerImpl) loadInstalledDB() error {
 	return nil
 }
 
-func (m *ManagerImpl) getArtifactDataInstallPath(artifactName string) string {
-	return filepath.Join(m.artifactDataInstallDir, artifactName)
+// getArtifactDataInstallPath returns the data directory of the artifact as derived from the data path template.
+func (m *ManagerImpl) getArtifactDataInstallPath(desc *model.IndexArtifactDescriptor) string {
+	return filepath.Join(m.artifactDataInstallDir, filepath.FromSlash(renderPathTemplate(orDefaultPathTemplate(m.dataPathTemplate), desc)))
+}
+
+// getArtifactMetaInstallPath returns the meta directory of the artifact as derived from the meta path template.
+func (m *ManagerImpl) getArtifactMetaInstallPath(desc *model.IndexArtifactDescriptor) string {
+	return filepath.Join(m.artifactMetaInstallDir, filepath.FromSlash(renderPathTemplate(orDefaultPathTemplate(m.metaPathTemplate), desc)))
 }
 
-func (m *ManagerImpl) getArtifactMetaInstallPath(artifactName string) string {
-	return filepath.Join(m.artifactMetaInstallDir, artifactName)
+// resolveHookPaths resolves a hook type to the paths of its scripts for the manager's platform using metadata,
+// in the order they run
+func (m *ManagerImpl) resolveHookPaths(metaDir string, hookType string, metadata *Metadata) []string {
+	if metadata == nil {
+		return nil
+	}
+	var hookPaths []string
+	for _, hookFile := range metadata.Hooks.ForPlatform(hookType, m.os, m.arch) {
+		hookPaths = append(hookPaths, filepath.Join(metaDir, hookFile))
+	}
+	return hookPaths
 }
 
-// resolveHookPath resolves a hook type to its file path using metadata
-func (m *ManagerImpl) resolveHookPath(metaDir string, hookType string, metadata *Metadata) string {
-	if metadata != nil && metadata.Hooks != nil {
-		if hookFile, exists := metadata.Hooks[hookType]; exists {
-			return filepath.Join(metaDir, hookFile)
+// executeHooks runs the hook scripts in order and stops at the first one that fails.
+func (m *ManagerImpl) executeHooks(hookPaths []string, hookContext *HookContext) error {
+	for _, hookPath := range hookPaths {
+		if err := m.hookExecutor.ExecuteHook(hookPath, hookContext); err != nil {
+			return err
 		}
 	}
-	return ""
+	return nil
 }
 
 func (m *ManagerImpl) findArtifactsDependingOn(targetArtifact string, result map[string]*model.InstalledArtifact) {
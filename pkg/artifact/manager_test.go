@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -33,7 +34,7 @@ var (
 		Maintainer:   "test@example.com",
 		Description:  "Test artifact for unit tests",
 		Dependencies: []model.Dependency{},
-		Hooks:        map[string]string{},
+		Hooks:        Hooks{},
 	}
 	DefaultIndexArtifactDescriptor = &model.IndexArtifactDescriptor{
 		Name:    DefaultMetadata.Name,
@@ -82,11 +83,12 @@ func TestInstallArtifact_RegularPackage(t *testing.T) {
 	setupTestArtifact(t, testArtifact, true, DefaultMetadata)
 
 	desc := &model.IndexArtifactDescriptor{
-		Name:    artifactName,
-		Version: "1.0.0",
-		OS:      "linux",
-		Arch:    "amd64",
-		URL:     "http://example.com/test.gotya",
+		Name:       artifactName,
+		Version:    "1.0.0",
+		OS:         "linux",
+		Arch:       "amd64",
+		URL:        "http://example.com/test.gotya",
+		Repository: "main",
 	}
 
 	// Install the artifact
@@ -112,6 +114,7 @@ func TestInstallArtifact_RegularPackage(t *testing.T) {
 	assert.Equal(t, DefaultArtifactName, installedArtifact.Name, "artifact name in database doesn't match")
 	assert.Equal(t, DefaultArtifactVersion, installedArtifact.Version, "artifact version in database doesn't match")
 	assert.Equal(t, DefaultArtifactURL, installedArtifact.InstalledFrom, "installed from URL doesn't match")
+	assert.Equal(t, "main", installedArtifact.SourceRepository, "source repository doesn't match")
 	assert.NotEmpty(t, installedArtifact.InstalledAt, "installed at timestamp should be set")
 
 	// Verify installed files in database
@@ -311,16 +314,24 @@ func TestInstallArtifact_DatabaseFailure(t *testing.T) {
 
 	// Make database file read-only to cause save failure
 	require.NoError(t, os.WriteFile(dbPath, []byte("test"), 0444)) // Read-only file
+	extractor := &extractCountingExtractor{ArchiveExtractor: mgr.archiveExtractor}
+	mgr.archiveExtractor = extractor
 
-	// Installation should fail
+	// Installation should fail before any work is done
 	err := mgr.InstallArtifact(context.Background(), desc, testArtifact, model.InstallationReasonManual)
-	require.Error(t, err)
+	require.ErrorIs(t, err, errutils.ErrDatabaseReadOnly)
+	assert.Zero(t, extractor.extractAllCalls, "the artifact must not be extracted")
+	assert.NoDirExists(t, filepath.Join(tempDir, artifactDataDir, "test-artifact"))
 
-	// Verify rollback cleaned up installed files
-	assert.NoDirExists(t, filepath.Join(tempDir, "test-artifact"))
+	// Updates are refused the same way
+	err = mgr.UpdateArtifact(context.Background(), testArtifact, desc)
+	require.ErrorIs(t, err, errutils.ErrDatabaseReadOnly)
+	assert.Zero(t, extractor.extractAllCalls, "the artifact must not be extracted")
 
-	// Database should be unchanged (still read-only)
-	// Note: We can't load the database since it's read-only, so we skip this check
+	// Database should be unchanged
+	content, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, "test", string(content))
 }
 
 // TestInstallArtifact_EmptyArtifactName tests installation with empty artifact name
@@ -369,7 +380,7 @@ func TestInstallArtifact_ReverseDependenciesSet(t *testing.T) {
 		Maintainer:   "test@example.com",
 		Description:  "Test dependency for reverse dependencies tests",
 		Dependencies: []model.Dependency{},
-		Hooks:        map[string]string{},
+		Hooks:        Hooks{},
 	}
 
 	desc := &model.IndexArtifactDescriptor{
@@ -427,7 +438,7 @@ func TestUninstallArtifact_UpdatesReverseDependencies(t *testing.T) {
 		Dependencies: []model.Dependency{
 			{Name: depName},
 		},
-		Hooks: make(map[string]string),
+		Hooks: make(Hooks),
 	}
 	setupTestArtifact(t, mainArtifact, true, mainMetadata)
 
@@ -462,7 +473,7 @@ func TestUninstallArtifact_UpdatesReverseDependencies(t *testing.T) {
 		Maintainer:   "test@example.com",
 		Description:  "Test dependency",
 		Dependencies: []model.Dependency{},
-		Hooks:        make(map[string]string),
+		Hooks:        make(Hooks),
 	}
 	setupTestArtifact(t, depArtifact, true, depMetadata)
 
@@ -533,7 +544,7 @@ func TestUninstallArtifact_PurgeMode(t *testing.T) {
 			{Name: "dep1"},
 			{Name: "dep2"},
 		},
-		Hooks: make(map[string]string),
+		Hooks: make(Hooks),
 	}
 	setupTestArtifact(t, testArtifact, true, metadata)
 
@@ -587,7 +598,7 @@ func TestUninstallArtifact_SelectiveMode(t *testing.T) {
 			{Name: "dep1"},
 			{Name: "dep2"},
 		},
-		Hooks: make(map[string]string),
+		Hooks: make(Hooks),
 	}
 	setupTestArtifact(t, testArtifact, true, metadata)
 
@@ -656,7 +667,7 @@ func TestUninstallArtifact_SelectiveMode_MissingFiles(t *testing.T) {
 			{Name: "dep1"},
 			{Name: "dep2"},
 		},
-		Hooks: make(map[string]string),
+		Hooks: make(Hooks),
 	}
 	setupTestArtifact(t, testArtifact, true, metadata)
 
@@ -773,7 +784,7 @@ func TestUpdateArtifact_Successful(t *testing.T) {
 		Dependencies: []model.Dependency{
 			{Name: "dep1"},
 		},
-		Hooks: make(map[string]string),
+		Hooks: make(Hooks),
 	}
 	setupTestArtifact(t, originalArtifact, true, originalMetadata)
 
@@ -809,7 +820,7 @@ func TestUpdateArtifact_Successful(t *testing.T) {
 			{Name: "dep1"},
 			{Name: "dep2"},
 		}, // Added new dependency
-		Hooks: make(map[string]string),
+		Hooks: make(Hooks),
 	}
 	setupTestArtifact(t, updatedArtifact, true, updatedMetadata)
 
@@ -895,8 +906,17 @@ func testUpdateToSameVersion(t *testing.T, testName, description string) {
 	}
 
 	err = mgr.UpdateArtifact(context.Background(), originalArtifact, sameDesc)
-	require.Error(t, err)
+	require.ErrorIs(t, err, errutils.ErrAlreadyUpToDate)
 	assert.Contains(t, err.Error(), "already at the latest version")
+
+	// Forcing reinstalls the same version
+	mgr.SetForceReinstall(true)
+	require.NoError(t, mgr.UpdateArtifact(context.Background(), originalArtifact, sameDesc))
+	require.NoError(t, mgr.loadInstalledDB())
+	reinstalled := mgr.installDB.FindArtifact(artifactName)
+	require.NotNil(t, reinstalled)
+	assert.Equal(t, "1.0.0", reinstalled.Version)
+	assert.Equal(t, model.StatusInstalled, reinstalled.Status)
 }
 
 // TestUpdateArtifact_InvalidNewArtifact tests updating with an invalid new artifact
@@ -1084,18 +1104,57 @@ func TestUpdateArtifact_DowngradeVersion(t *testing.T) {
 		URL:     "http://example.com/v1.0.0.gotya",
 	}
 
-	// Update to lower version should succeed (no version comparison logic)
+	// Updating to a lower version is rejected by default
+	err = mgr.UpdateArtifact(context.Background(), downgradeArtifact, downgradeDesc)
+	require.ErrorIs(t, err, errutils.ErrValidation)
+	assert.Contains(t, err.Error(), "cannot downgrade test-artifact from 2.0.0 to 1.0.0")
+	db := loadInstalledDB(t, dbPath)
+	assert.Equal(t, "2.0.0", db.FindArtifact(artifactName).Version)
+
+	mgr.SetAllowDowngrade(true)
 	err = mgr.UpdateArtifact(context.Background(), downgradeArtifact, downgradeDesc)
 	require.NoError(t, err)
 
 	// Verify the downgrade was successful
-	db := loadInstalledDB(t, dbPath)
+	db = loadInstalledDB(t, dbPath)
 	assert.True(t, db.IsArtifactInstalled(artifactName), "downgraded artifact should be installed")
 	updatedInstalled := db.FindArtifact(artifactName)
 	require.NotNil(t, updatedInstalled)
 	assert.Equal(t, "1.0.0", updatedInstalled.Version)
 }
 
+// reverseSemverComparator orders semantic versions from newest to oldest.
+type reverseSemverComparator struct{}
+
+func (reverseSemverComparator) Compare(a, b string) (int, error) {
+	return model.SemverComparator{}.Compare(b, a)
+}
+
+func TestUpdateArtifact_DowngradeCustomComparator(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), dbPath)
+	mgr.SetVersionComparator(reverseSemverComparator{})
+
+	artifactPath := func(version string) (string, *model.IndexArtifactDescriptor) {
+		path := filepath.Join(tempDir, version+".gotya")
+		setupTestArtifact(t, path, true, &Metadata{Name: "tool", Version: version, OS: "linux", Arch: "amd64"})
+		return path, &model.IndexArtifactDescriptor{Name: "tool", Version: version, OS: "linux", Arch: "amd64", URL: "http://example.com/" + version + ".gotya"}
+	}
+	oldPath, oldDesc := artifactPath("1.0.0")
+	require.NoError(t, mgr.InstallArtifact(context.Background(), oldDesc, oldPath, model.InstallationReasonManual))
+
+	// 2.0.0 sorts before 1.0.0 in the configured ordering
+	newPath, newDesc := artifactPath("2.0.0")
+	err := mgr.UpdateArtifact(context.Background(), newPath, newDesc)
+	require.ErrorIs(t, err, errutils.ErrValidation)
+	assert.Contains(t, err.Error(), "cannot downgrade tool from 1.0.0 to 2.0.0")
+
+	mgr.SetVersionComparator(nil)
+	require.NoError(t, mgr.UpdateArtifact(context.Background(), newPath, newDesc))
+	assert.Equal(t, "2.0.0", loadInstalledDB(t, dbPath).FindArtifact("tool").Version)
+}
+
 // TestUpdateArtifact_RollbackOnExtractionFailure tests that UpdateArtifact rolls back to the old version when extraction fails
 func TestUpdateArtifact_RollbackOnExtractionFailure(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -1320,38 +1379,76 @@ func TestVerifyArtifact_NonExistentFile(t *testing.T) {
 
 // TestVerifyArtifact_InvalidMetadata tests verifying an artifact with mismatched metadata
 func TestVerifyArtifact_InvalidMetadata(t *testing.T) {
-	tempDir := t.TempDir()
-	cacheDir := filepath.Join(tempDir, "cache")
-	require.NoError(t, os.MkdirAll(cacheDir, 0755))
-	mgr := NewManager("linux", "amd64", cacheDir, filepath.Join(tempDir, artifactDataDir), filepath.Join(tempDir, artifactMetaDir), filepath.Join(tempDir, "installed.db"))
-
-	// Create a test artifact with specific metadata
-	testArtifact := filepath.Join(tempDir, "test-artifact.gotya")
-	metadata := &Metadata{
-		Name:        "test-artifact",
-		Version:     "1.0.0",
-		OS:          "linux",
-		Arch:        "amd64",
-		Maintainer:  "test@example.com",
-		Description: "Test artifact for verification tests",
+	tests := []struct {
+		name            string
+		desc            *model.IndexArtifactDescriptor
+		field           string
+		expectedMessage string
+	}{
+		{
+			name:            "name mismatch",
+			desc:            &model.IndexArtifactDescriptor{Name: "different-artifact", Version: "1.0.0", OS: "linux", Arch: "amd64"},
+			field:           "name",
+			expectedMessage: `metadata mismatch: name is "test-artifact" in the artifact but the descriptor expects "different-artifact"`,
+		},
+		{
+			name:            "version mismatch",
+			desc:            &model.IndexArtifactDescriptor{Name: "test-artifact", Version: "2.0.0", OS: "linux", Arch: "amd64"},
+			field:           "version",
+			expectedMessage: `metadata mismatch: version is "1.0.0" in the artifact but the descriptor expects "2.0.0"`,
+		},
+		{
+			name:            "os mismatch",
+			desc:            &model.IndexArtifactDescriptor{Name: "test-artifact", Version: "1.0.0", OS: "windows", Arch: "amd64"},
+			field:           "os",
+			expectedMessage: `metadata mismatch: os is "linux" in the artifact but the descriptor expects "windows"`,
+		},
+		{
+			name:            "arch mismatch",
+			desc:            &model.IndexArtifactDescriptor{Name: "test-artifact", Version: "1.0.0", OS: "linux", Arch: "arm64"},
+			field:           "arch",
+			expectedMessage: `metadata mismatch: arch is "amd64" in the artifact but the descriptor expects "arm64"`,
+		},
 	}
-	setupTestArtifact(t, testArtifact, true, metadata)
 
-	// Copy the artifact to the cache directory with the name that the mismatched descriptor expects
-	cacheArtifact := filepath.Join(cacheDir, "different-artifact_1.0.0_linux_amd64.gotya")
-	require.NoError(t, os.Rename(testArtifact, cacheArtifact))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			cacheDir := filepath.Join(tempDir, "cache")
+			require.NoError(t, os.MkdirAll(cacheDir, 0755))
+			mgr := NewManager("linux", "amd64", cacheDir, filepath.Join(tempDir, artifactDataDir), filepath.Join(tempDir, artifactMetaDir), filepath.Join(tempDir, "installed.db"))
 
-	// Try to verify with mismatched metadata (different name)
-	desc := &model.IndexArtifactDescriptor{
-		Name:    "different-artifact", // Different name - should cause metadata mismatch
-		Version: "1.0.0",
-		OS:      "linux",
-		Arch:    "amd64",
+			// Create a test artifact with specific metadata
+			testArtifact := filepath.Join(tempDir, "test-artifact.gotya")
+			metadata := &Metadata{
+				Name:        "test-artifact",
+				Version:     "1.0.0",
+				OS:          "linux",
+				Arch:        "amd64",
+				Maintainer:  "test@example.com",
+				Description: "Test artifact for verification tests",
+			}
+			setupTestArtifact(t, testArtifact, true, metadata)
+
+			// Copy the artifact to the cache directory with the name that the mismatched descriptor expects
+			cacheArtifact := filepath.Join(cacheDir, fmt.Sprintf("%s_%s_%s_%s.gotya", tt.desc.Name, tt.desc.Version, tt.desc.OS, tt.desc.Arch))
+			require.NoError(t, os.Rename(testArtifact, cacheArtifact))
+
+			err := mgr.VerifyArtifact(context.Background(), tt.desc)
+			require.ErrorIs(t, err, errutils.ErrArtifactInvalid)
+			var mismatch *MetadataMismatchError
+			require.ErrorAs(t, err, &mismatch)
+			assert.Equal(t, tt.field, mismatch.Field)
+			assert.EqualError(t, err, tt.expectedMessage)
+
+			// Installing with the same descriptor fails with the same mismatch
+			installDesc := *tt.desc
+			installDesc.URL = "http://example.com/test.gotya"
+			err = mgr.InstallArtifact(context.Background(), &installDesc, cacheArtifact, model.InstallationReasonManual)
+			require.ErrorAs(t, err, &mismatch)
+			assert.Equal(t, tt.field, mismatch.Field)
+		})
 	}
-
-	err := mgr.VerifyArtifact(context.Background(), desc)
-	require.Error(t, err)
-	assert.ErrorContains(t, err, "metadata mismatch")
 }
 
 // setupTestArtifact creates a test artifact file with the specified structure and metadata
@@ -1400,6 +1497,7 @@ func setupTestArtifact(t *testing.T, artifactPath string, includeDataDir bool, m
 		inputDir,
 		outputDir,
 	)
+	require.NoError(t, packer.SetRequirements(metadata.Requirements))
 
 	// Create the artifact using the packer
 	outputFile, err := packer.Pack()
@@ -1449,6 +1547,80 @@ func createTestInstalledArtifact(t *testing.T, name, version string, reverseDeps
 	}
 }
 
+func TestInstalledNames(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, artifactDataDir), filepath.Join(tempDir, artifactMetaDir), dbPath)
+
+	names, err := mgr.InstalledNames()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	placeholder := createTestInstalledArtifact(t, "libmissing", "", []string{"zeta"})
+	placeholder.Status = model.StatusMissing
+	setupTestDatabaseWithArtifacts(t, dbPath, []*model.InstalledArtifact{
+		createTestInstalledArtifact(t, "zeta", "1.0.0", nil),
+		placeholder,
+		createTestInstalledArtifact(t, "alpha", "2.0.0", nil),
+	})
+
+	names, err = mgr.InstalledNames()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha", "zeta"}, names)
+}
+
+// TestInstalledBetween tests filtering installed artifacts by installation time
+func TestInstalledBetween(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, artifactDataDir), filepath.Join(tempDir, artifactMetaDir), dbPath)
+
+	base := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	var artifacts []*model.InstalledArtifact
+	for i, name := range []string{"day0", "day2", "day5", "day7", "day9"} {
+		a := createTestInstalledArtifact(t, name, "1.0.0", nil)
+		a.InstalledAt = base.AddDate(0, 0, []int{0, 2, 5, 7, 9}[i])
+		artifacts = append(artifacts, a)
+	}
+	missing := createTestInstalledArtifact(t, "missing", "invalid", nil)
+	missing.Status = model.StatusMissing
+	missing.InstalledAt = base.AddDate(0, 0, 3)
+	artifacts = append(artifacts, missing)
+	// Seed in reverse order to check that results are sorted
+	slices.Reverse(artifacts)
+	setupTestDatabaseWithArtifacts(t, dbPath, artifacts)
+
+	names := func(list []*model.InstalledArtifact) []string {
+		out := make([]string, 0, len(list))
+		for _, a := range list {
+			out = append(out, a.Name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       []string
+	}{
+		{name: "week range", start: base.AddDate(0, 0, 2), end: base.AddDate(0, 0, 7), want: []string{"day2", "day5"}},
+		{name: "open start", end: base.AddDate(0, 0, 3), want: []string{"day0", "day2"}},
+		{name: "open end", start: base.AddDate(0, 0, 7), want: []string{"day7", "day9"}},
+		{name: "unbounded", want: []string{"day0", "day2", "day5", "day7", "day9"}},
+		{name: "empty range", start: base.AddDate(0, 0, 10), end: base.AddDate(0, 0, 20), want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mgr.InstalledBetween(tt.start, tt.end)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, names(got))
+		})
+	}
+
+	_, err := mgr.InstalledBetween(base.AddDate(0, 0, 1), base)
+	assert.ErrorIs(t, err, errutils.ErrValidation)
+}
+
 // TestReverseResolve_Basic tests basic reverse dependency resolution
 func TestReverseResolve_Basic(t *testing.T) {
 	tempDir := t.TempDir()
@@ -1945,6 +2117,8 @@ func TestUpdateArtifact_HookBehavior(t *testing.T) {
 		ArtifactName:    DefaultArtifactName,
 		ArtifactVersion: "2.0.0", // New version, not old version
 		Operation:       "update",
+		OS:              "linux",
+		Arch:            "amd64",
 		MetaDir:         filepath.Join(metaDir, "test-artifact"), // Uses installed artifact's MetaDir
 		DataDir:         filepath.Join(dataDir, "test-artifact"), // Uses installed artifact's DataDir (should match getArtifactDataInstallPath)
 		OldVersion:      DefaultArtifactVersion,
@@ -1953,6 +2127,8 @@ func TestUpdateArtifact_HookBehavior(t *testing.T) {
 		ArtifactName:    DefaultArtifactName,
 		ArtifactVersion: "2.0.0", // New version
 		Operation:       "update",
+		OS:              "linux",
+		Arch:            "amd64",
 		MetaDir:         filepath.Join(metaDir, "test-artifact"), // Uses new artifact's MetaDir (same as old in this case)
 		DataDir:         filepath.Join(dataDir, "test-artifact"), // Uses new artifact's DataDir
 		OldVersion:      DefaultArtifactVersion,
@@ -1991,7 +2167,7 @@ func TestUpdateArtifact_HookBehavior(t *testing.T) {
 	}
 	setupTestDatabaseWithArtifacts(t, dbPath, []*model.InstalledArtifact{installedArtifact})
 
-	DefaultMetadata.Hooks["pre-update"] = "pre-update.tengo"
+	DefaultMetadata.Hooks["pre-update"] = []string{"pre-update.tengo"}
 
 	writeMetadata(t, filepath.Join(metaDir, "test-artifact"), DefaultMetadata)
 
@@ -2013,8 +2189,8 @@ func TestUpdateArtifact_HookBehavior(t *testing.T) {
 		Arch:        DefaultArtifactArch,
 		Maintainer:  "test@example.com",
 		Description: "Updated test artifact",
-		Hooks: map[string]string{
-			"post-update": "post-update.tengo",
+		Hooks: Hooks{
+			"post-update": {"post-update.tengo"},
 		},
 	}
 	setupTestArtifact(t, artifactPath, true, metadata)
@@ -2055,6 +2231,8 @@ func TestUninstallArtifact_HookBehavior(t *testing.T) {
 		ArtifactName:    DefaultArtifactName,
 		ArtifactVersion: DefaultArtifactVersion,
 		Operation:       "uninstall",
+		OS:              "linux",
+		Arch:            "amd64",
 		MetaDir:         filepath.Join(metaDir, "test-artifact"),
 		DataDir:         filepath.Join(dataDir, "test-artifact"),
 	}
@@ -2062,12 +2240,14 @@ func TestUninstallArtifact_HookBehavior(t *testing.T) {
 		ArtifactName:    DefaultArtifactName,
 		ArtifactVersion: DefaultArtifactVersion,
 		Operation:       "uninstall",
+		OS:              "linux",
+		Arch:            "amd64",
 		WasMetaDir:      filepath.Join(metaDir, "test-artifact"),
 		WasDataDir:      filepath.Join(dataDir, "test-artifact"),
 	}
 
-	DefaultMetadata.Hooks["pre-uninstall"] = "pre-uninstall.tengo"
-	DefaultMetadata.Hooks["post-uninstall"] = "post-uninstall.tengo"
+	DefaultMetadata.Hooks["pre-uninstall"] = []string{"pre-uninstall.tengo"}
+	DefaultMetadata.Hooks["post-uninstall"] = []string{"post-uninstall.tengo"}
 
 	writeMetadata(t, filepath.Join(metaDir, "test-artifact"), DefaultMetadata)
 
@@ -2137,7 +2317,7 @@ func TestUpdateArtifact_FailingPreUpdateHook(t *testing.T) {
 	err = os.WriteFile(hookPath, []byte(`invalid tengo syntax !!!`), 0o644)
 	require.NoError(t, err)
 
-	DefaultMetadata.Hooks["pre-update"] = "pre-update.tengo"
+	DefaultMetadata.Hooks["pre-update"] = []string{"pre-update.tengo"}
 	writeMetadata(t, filepath.Join(metaDir, "test-artifact"), DefaultMetadata)
 
 	// Create mock hook executor to track calls
@@ -2148,6 +2328,8 @@ func TestUpdateArtifact_FailingPreUpdateHook(t *testing.T) {
 		ArtifactName:    DefaultArtifactName,
 		ArtifactVersion: "2.0.0",
 		Operation:       "update",
+		OS:              "linux",
+		Arch:            "amd64",
 		MetaDir:         filepath.Join(metaDir, "test-artifact"),
 		DataDir:         filepath.Join(dataDir, "test-artifact"),
 		OldVersion:      DefaultArtifactVersion,
@@ -2223,9 +2405,9 @@ func TestInstallArtifact_MetadataDrivenHooks(t *testing.T) {
 	dataDir := filepath.Join(installTempDir, artifactDataDir)
 
 	// Create hook scripts with names that differ from hook types
-	hookScripts := map[string]string{
-		"pre-install":  "before_install.tengo", // Hook type "pre-install" maps to file "before_install.tengo"
-		"post-install": "after_install.tengo",  // Hook type "post-install" maps to file "after_install.tengo"
+	hookScripts := Hooks{
+		"pre-install":  {"before_install.tengo"}, // Hook type "pre-install" maps to file "before_install.tengo"
+		"post-install": {"after_install.tengo"},  // Hook type "post-install" maps to file "after_install.tengo"
 	}
 
 	metadata := &Metadata{
@@ -2242,8 +2424,8 @@ func TestInstallArtifact_MetadataDrivenHooks(t *testing.T) {
 	err := os.MkdirAll(metaDir, 0o755)
 	require.NoError(t, err)
 
-	for hookType, filename := range hookScripts {
-		hookPath := filepath.Join(metaDir, filename)
+	for hookType, filenames := range hookScripts {
+		hookPath := filepath.Join(metaDir, filenames[0])
 		err := os.WriteFile(hookPath, []byte(`// Tengo script for `+hookType), 0o644)
 		require.NoError(t, err)
 	}
@@ -2256,6 +2438,8 @@ func TestInstallArtifact_MetadataDrivenHooks(t *testing.T) {
 		ArtifactName:    "test-artifact",
 		ArtifactVersion: "1.0.0",
 		Operation:       "install",
+		OS:              "linux",
+		Arch:            "amd64",
 		MetaDir:         filepath.Join(metaDir, "test-artifact"),
 		DataDir:         filepath.Join(dataDir, "test-artifact"),
 	}
@@ -2356,6 +2540,36 @@ func TestSetArtifactManuallyInstalled_Success(t *testing.T) {
 	assert.Equal(t, model.StatusInstalled, updatedArtifact.Status)
 }
 
+// TestInstallArtifactWithDetail_PersistsAcrossUpdate tests that the detail is stored and kept on update
+func TestInstallArtifactWithDetail_PersistsAcrossUpdate(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), dbPath)
+
+	for _, v := range []string{"1.0.0", "2.0.0"} {
+		setupTestArtifact(t, filepath.Join(tempDir, "lib-"+v+".gotya"), true, &Metadata{
+			Name: "lib", Version: v, OS: "linux", Arch: "amd64", Description: "Dependency test artifact",
+		})
+	}
+	descV1 := &model.IndexArtifactDescriptor{Name: "lib", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/lib-1.gotya"}
+	descV2 := &model.IndexArtifactDescriptor{Name: "lib", Version: "2.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/lib-2.gotya"}
+
+	require.NoError(t, mgr.InstallArtifactWithDetail(context.Background(), descV1, filepath.Join(tempDir, "lib-1.0.0.gotya"), model.InstallationReasonAutomatic, "required by app >= 1.0"))
+
+	installed := loadInstalledDB(t, dbPath).FindArtifact("lib")
+	require.NotNil(t, installed)
+	assert.Equal(t, "required by app >= 1.0", installed.InstallationDetail)
+
+	require.NoError(t, mgr.UpdateArtifact(context.Background(), filepath.Join(tempDir, "lib-2.0.0.gotya"), descV2))
+	updated := loadInstalledDB(t, dbPath).FindArtifact("lib")
+	require.NotNil(t, updated)
+	assert.Equal(t, "2.0.0", updated.Version)
+	assert.Equal(t, "required by app >= 1.0", updated.InstallationDetail)
+
+	err := mgr.SetArtifactInstallationDetail("missing", "required by app")
+	assert.ErrorIs(t, err, errutils.ErrArtifactNotFound)
+}
+
 // TestSetArtifactManuallyInstalled_ArtifactNotFound tests error when artifact doesn't exist
 func TestSetArtifactManuallyInstalled_ArtifactNotFound(t *testing.T) {
 	tempDir := t.TempDir()
@@ -2387,8 +2601,94 @@ func writeMetadata(t *testing.T, metaDir string, metadata *Metadata) {
 	t.Helper()
 
 	require.NoError(t, os.MkdirAll(metaDir, 0o755))
-	metaFile, err := os.Create(filepath.Join(metaDir, metadataFile))
+	metaFile, err := os.Create(filepath.Join(metaDir, DefaultMetadataFile))
 	require.NoError(t, err)
 	require.NoError(t, json.NewEncoder(metaFile).Encode(metadata))
 	require.NoError(t, metaFile.Close())
 }
+
+func TestUninstallPreview(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), dbPath)
+	artifactName := "test-artifact"
+
+	testArtifact := filepath.Join(tempDir, "test-artifact.gotya")
+	setupTestArtifact(t, testArtifact, true, &Metadata{Name: artifactName, Version: "1.0.0", OS: "linux", Arch: "amd64", Description: "preview"})
+	desc := &model.IndexArtifactDescriptor{Name: artifactName, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/test.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, testArtifact, model.InstallationReasonManual))
+
+	metaDir := filepath.Join(tempDir, "install", artifactMetaDir, artifactName)
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir, artifactName)
+	// A file created after installation is not tracked and keeps the data directory alive in selective mode
+	untracked := filepath.Join(dataDir, "user.conf")
+	require.NoError(t, os.WriteFile(untracked, []byte("user"), 0o644))
+
+	expectedFiles := []string{
+		filepath.Join(dataDir, "datafile1.bin"),
+		filepath.Join(dataDir, "datafile2.bin"),
+		filepath.Join(metaDir, DefaultMetadataFile),
+	}
+
+	purgeFiles, purgeDirs, err := mgr.UninstallPreview(artifactName, true)
+	require.NoError(t, err)
+	assert.Equal(t, expectedFiles, purgeFiles)
+	assert.ElementsMatch(t, []string{metaDir, dataDir}, purgeDirs)
+
+	selectiveFiles, selectiveDirs, err := mgr.UninstallPreview(artifactName, false)
+	require.NoError(t, err)
+	assert.Equal(t, expectedFiles, selectiveFiles)
+	assert.NotContains(t, selectiveFiles, untracked)
+	assert.Contains(t, selectiveDirs, metaDir)
+	assert.NotContains(t, selectiveDirs, dataDir, "data directory still holds an untracked file")
+
+	// The preview must not change anything and must match what the uninstall removes
+	assert.FileExists(t, expectedFiles[0])
+	require.NoError(t, mgr.UninstallArtifact(context.Background(), artifactName, false))
+	for _, dir := range selectiveDirs {
+		assert.NoDirExists(t, dir)
+	}
+	assert.FileExists(t, untracked)
+
+	_, _, err = mgr.UninstallPreview(artifactName, true)
+	require.ErrorIs(t, err, errutils.ErrArtifactNotFound)
+}
+
+func TestInstallArtifact_KeepFailedExtract(t *testing.T) {
+	tempDir := t.TempDir()
+	// Extract directories are created below TMPDIR
+	tmpDir := filepath.Join(tempDir, "tmp")
+	require.NoError(t, os.MkdirAll(tmpDir, 0o755))
+	t.Setenv("TMPDIR", tmpDir)
+	extractDirs := func() []string {
+		matches, err := filepath.Glob(filepath.Join(tmpDir, "gotya-extract-*"))
+		require.NoError(t, err)
+		return matches
+	}
+
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "data"), filepath.Join(tempDir, "meta"), filepath.Join(tempDir, "installed.db"))
+	mgr.SetKeepFailedExtract(true)
+	artifactPath := filepath.Join(tempDir, "tool.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64"})
+
+	// The descriptor does not match the artifact, so the install fails after extracting it
+	wrong := &model.IndexArtifactDescriptor{Name: "tool", Version: "2.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+	err := mgr.InstallArtifact(context.Background(), wrong, artifactPath, model.InstallationReasonManual)
+	require.Error(t, err)
+	kept := extractDirs()
+	require.Len(t, kept, 1)
+	assert.Contains(t, err.Error(), kept[0])
+	assert.FileExists(t, filepath.Join(kept[0], artifactDataDir, "datafile1.bin"))
+	require.NoError(t, os.RemoveAll(kept[0]))
+
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	assert.Empty(t, extractDirs(), "the extract directory of a successful install is removed")
+
+	// Without the option failed installs clean up as well
+	mgr.SetKeepFailedExtract(false)
+	require.NoError(t, mgr.UninstallArtifact(context.Background(), "tool", true))
+	err = mgr.InstallArtifact(context.Background(), wrong, artifactPath, model.InstallationReasonManual)
+	require.Error(t, err)
+	assert.Empty(t, extractDirs())
+}
@@ -0,0 +1,19 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ComputeManifestDigest returns the SHA-256 digest of the canonical manifest built from file hashes:
+// one "path\x00hash\n" line per file, sorted by path. Artifacts with identical content have the same
+// manifest digest regardless of their version or archive layout.
+func ComputeManifestDigest(hashes map[string]string) string {
+	h := sha256.New()
+	for _, path := range slices.Sorted(maps.Keys(hashes)) {
+		_, _ = fmt.Fprintf(h, "%s\x00%s\n", path, hashes[path])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
@@ -4,14 +4,20 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"slices"
+	"strings"
 
+	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/glorpus-work/gotya/pkg/model"
 	"github.com/glorpus-work/gotya/pkg/platform"
 	"github.com/hashicorp/go-version"
 )
 
+// DefaultMaxMetadataSize is the default upper bound for the size of an artifact's metadata file.
+const DefaultMaxMetadataSize int64 = 4 << 20
+
 // Metadata represents the metadata of an artifact, including name, version, OS, architecture,
-// maintainer, description, dependencies, hooks, and file hashes.
+// maintainer, description, dependencies, hooks, host requirements, and file hashes.
 type Metadata struct {
 	Name         string             `json:"name"`
 	Version      string             `json:"version"`
@@ -21,7 +27,67 @@ type Metadata struct {
 	Description  string             `json:"description"`
 	Dependencies []model.Dependency `json:"dependencies,omitempty"`
 	Hashes       map[string]string  `json:"files,omitempty"`
-	Hooks        map[string]string  `json:"hooks,omitempty"`
+	Hooks        Hooks              `json:"hooks,omitempty"`
+	// Requirements maps host capabilities, e.g. "glibc", to the minimum version the artifact needs
+	Requirements map[string]string `json:"requirements,omitempty"`
+	// ManifestDigest is the digest over Hashes as computed by ComputeManifestDigest
+	ManifestDigest string `json:"manifest_digest,omitempty"`
+}
+
+// Hooks maps hook types, e.g. "pre-install", to the scripts run for them in order, relative to the meta directory.
+// In JSON a hook type is either a single script name or an array of script names.
+type Hooks map[string][]string
+
+// UnmarshalJSON accepts a script name or an array of script names for every hook type.
+func (h *Hooks) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*h = nil
+		return nil
+	}
+
+	hooks := make(Hooks, len(raw))
+	for hookType, value := range raw {
+		var script string
+		if err := json.Unmarshal(value, &script); err == nil {
+			hooks[hookType] = []string{script}
+			continue
+		}
+		var scripts []string
+		if err := json.Unmarshal(value, &scripts); err != nil {
+			return errutils.Wrapf(ErrInvalidMetadata, "hook %s must be a script name or an array of script names", hookType)
+		}
+		hooks[hookType] = scripts
+	}
+	*h = hooks
+	return nil
+}
+
+// MarshalJSON writes hook types with a single script as a plain script name, so such artifacts can still
+// be read by versions that only support one script per hook.
+func (h Hooks) MarshalJSON() ([]byte, error) {
+	out := make(map[string]any, len(h))
+	for hookType, scripts := range h {
+		if len(scripts) == 1 {
+			out[hookType] = scripts[0]
+		} else {
+			out[hookType] = scripts
+		}
+	}
+	return json.Marshal(out)
+}
+
+// Scripts returns the scripts referenced by any hook type, sorted and without duplicates.
+func (h Hooks) Scripts() []string {
+	var scripts []string
+	for _, list := range h {
+		scripts = append(scripts, list...)
+	}
+	slices.Sort(scripts)
+	return slices.Compact(scripts)
 }
 
 // GetVersion returns the parsed version of this artifact.
@@ -49,23 +115,131 @@ func (m *Metadata) GetArch() string {
 	return m.Arch
 }
 
-// ParseMetadataFromPath parses metadata from a file path.
+// ParseMetadataFromPath parses metadata from a file path, enforcing DefaultMaxMetadataSize.
 func ParseMetadataFromPath(path string) (*Metadata, error) {
+	return ParseMetadataFromPathWithLimit(path, DefaultMaxMetadataSize)
+}
+
+// ParseMetadataFromPathWithLimit parses metadata from a file path and fails with ErrMetadataTooLarge
+// if the file exceeds maxSize bytes. A maxSize <= 0 uses DefaultMaxMetadataSize.
+func ParseMetadataFromPathWithLimit(path string, maxSize int64) (*Metadata, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = file.Close() }()
 
-	return ParseMetadataFromStream(file)
+	return parseMetadataFile(file, maxSize)
+}
+
+// parseMetadataFile parses metadata from an open file, rejecting oversized files before reading them.
+// The bounded reader still guards against files growing while they are read.
+func parseMetadataFile(file *os.File, maxSize int64) (*Metadata, error) {
+	maxSize = metadataSizeLimit(maxSize)
+	if info, err := file.Stat(); err == nil && info.Mode().IsRegular() && info.Size() > maxSize {
+		return nil, errutils.Wrapf(ErrMetadataTooLarge, "%s is %d bytes, limit is %d", file.Name(), info.Size(), maxSize)
+	}
+	return ParseMetadataFromStreamWithLimit(file, maxSize)
 }
 
-// ParseMetadataFromStream parses metadata from an io.Reader stream.
+// ParseMetadataFromStream parses metadata from an io.Reader stream, enforcing DefaultMaxMetadataSize.
 func ParseMetadataFromStream(stream io.Reader) (*Metadata, error) {
+	return ParseMetadataFromStreamWithLimit(stream, DefaultMaxMetadataSize)
+}
+
+// ParseMetadataFromStreamWithLimit parses metadata from an io.Reader stream and stops reading with
+// ErrMetadataTooLarge once more than maxSize bytes were consumed. A maxSize <= 0 uses DefaultMaxMetadataSize.
+func ParseMetadataFromStreamWithLimit(stream io.Reader, maxSize int64) (*Metadata, error) {
+	maxSize = metadataSizeLimit(maxSize)
+
 	var metadata Metadata
-	decoder := json.NewDecoder(stream)
+	decoder := json.NewDecoder(&boundedReader{r: stream, remaining: maxSize, limit: maxSize})
 	if err := decoder.Decode(&metadata); err != nil {
 		return nil, err
 	}
 	return &metadata, nil
 }
+
+// metadataSizeLimit returns maxSize, or DefaultMaxMetadataSize if maxSize is not positive.
+func metadataSizeLimit(maxSize int64) int64 {
+	if maxSize <= 0 {
+		return DefaultMaxMetadataSize
+	}
+	return maxSize
+}
+
+// boundedReader reads at most remaining bytes from r and fails with ErrMetadataTooLarge
+// if the underlying reader has more data.
+type boundedReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+// Read implements io.Reader.
+func (b *boundedReader) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Probe for a single byte to tell EOF apart from oversized input
+		var probe [1]byte
+		if n, err := b.r.Read(probe[:]); n > 0 {
+			return 0, errutils.Wrapf(ErrMetadataTooLarge, "metadata exceeds %d bytes", b.limit)
+		} else if err != nil {
+			return 0, err
+		}
+		return 0, nil
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// validateMetadataFileName ensures a metadata file name is a plain file name without path components.
+func validateMetadataFileName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return errutils.Wrapf(errutils.ErrValidation, "invalid metadata file name %q", name)
+	}
+	return nil
+}
+
+// orDefaultMetadataFile returns name, or DefaultMetadataFile if name is empty.
+func orDefaultMetadataFile(name string) string {
+	if name == "" {
+		return DefaultMetadataFile
+	}
+	return name
+}
+
+// SetMetadataFileName sets the name of the metadata file in the meta directory of artifacts
+// installed, updated or verified by this manager. It defaults to DefaultMetadataFile.
+func (m *ManagerImpl) SetMetadataFileName(name string) error {
+	if err := validateMetadataFileName(name); err != nil {
+		return err
+	}
+	m.metadataFile = name
+	if m.verifier != nil {
+		return m.verifier.SetMetadataFileName(name)
+	}
+	return nil
+}
+
+// metadataFileName returns the configured metadata file name.
+func (m *ManagerImpl) metadataFileName() string {
+	return orDefaultMetadataFile(m.metadataFile)
+}
+
+// SetMaxMetadataSize sets the maximum size of an artifact's metadata file accepted when installing,
+// updating or verifying artifacts. Sizes <= 0 use DefaultMaxMetadataSize.
+func (m *ManagerImpl) SetMaxMetadataSize(size int64) {
+	m.maxMetadataSize = size
+	if m.verifier != nil {
+		m.verifier.SetMaxMetadataSize(size)
+	}
+}
+
+// parseMetadata parses an installed or extracted metadata file using the configured size limit.
+func (m *ManagerImpl) parseMetadata(path string) (*Metadata, error) {
+	return ParseMetadataFromPathWithLimit(path, m.maxMetadataSize)
+}
@@ -0,0 +1,96 @@
+package artifact
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// DefaultMetadataReadConcurrency is the number of metadata files ReadAllMetadata reads in parallel.
+const DefaultMetadataReadConcurrency = 8
+
+// MetadataReadError is returned by ReadAllMetadata when the metadata of some artifacts could not be read.
+type MetadataReadError struct {
+	// Errors maps the name of each failed artifact to its error
+	Errors map[string]error
+}
+
+// Error lists the failed artifacts sorted by name.
+func (e *MetadataReadError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	msgs := make([]string, 0, len(names))
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", name, e.Errors[name]))
+	}
+	return fmt.Sprintf("failed to read metadata of %d artifacts: %s", len(names), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the per-artifact errors, so errors.Is and errors.As match any of them.
+func (e *MetadataReadError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// ReadAllMetadata reads the installed metadata file of every installed artifact, using up to
+// DefaultMetadataReadConcurrency parallel reads, and returns it by artifact name.
+// Artifacts whose metadata cannot be read are left out of the map and reported together in a
+// *MetadataReadError; the metadata that could be read is returned either way.
+func (m *ManagerImpl) ReadAllMetadata() (map[string]*Metadata, error) {
+	unlock, err := m.readDB()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var artifacts []*model.InstalledArtifact
+	for _, artifact := range m.installDB.GetInstalledArtifacts() {
+		if artifact.Status == model.StatusInstalled {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		metadata = make(map[string]*Metadata, len(artifacts))
+		failed   = make(map[string]error)
+	)
+	jobs := make(chan *model.InstalledArtifact)
+	for i := 0; i < min(DefaultMetadataReadConcurrency, len(artifacts)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for artifact := range jobs {
+				md, err := m.parseMetadata(m.installedMetadataPath(artifact))
+				mu.Lock()
+				if err != nil {
+					failed[artifact.Name] = err
+				} else {
+					metadata[artifact.Name] = md
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, artifact := range artifacts {
+		jobs <- artifact
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return metadata, &MetadataReadError{Errors: failed}
+	}
+	return metadata, nil
+}
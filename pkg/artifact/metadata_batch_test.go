@@ -0,0 +1,53 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAllMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+
+	// More artifacts than workers, so every worker handles several
+	names := make([]string, 0, DefaultMetadataReadConcurrency+4)
+	for i := range DefaultMetadataReadConcurrency + 4 {
+		name := fmt.Sprintf("artifact-%02d", i)
+		names = append(names, name)
+		artifactPath := filepath.Join(tempDir, name+".gotya")
+		setupTestArtifact(t, artifactPath, false, &Metadata{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64"})
+		desc := &model.IndexArtifactDescriptor{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + ".gotya"}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	}
+
+	metadata, err := mgr.ReadAllMetadata()
+	require.NoError(t, err)
+	require.Len(t, metadata, len(names))
+	for _, name := range names {
+		require.Contains(t, metadata, name)
+		assert.Equal(t, name, metadata[name].Name)
+		assert.Equal(t, "1.0.0", metadata[name].Version)
+	}
+
+	broken := names[3]
+	require.NoError(t, os.Remove(filepath.Join(mgr.getArtifactMetaInstallPath(&model.IndexArtifactDescriptor{Name: broken}), DefaultMetadataFile)))
+
+	metadata, err = mgr.ReadAllMetadata()
+	var readErr *MetadataReadError
+	require.ErrorAs(t, err, &readErr)
+	require.Len(t, readErr.Errors, 1)
+	assert.ErrorIs(t, readErr.Errors[broken], os.ErrNotExist)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	assert.Contains(t, err.Error(), broken)
+
+	assert.Len(t, metadata, len(names)-1)
+	assert.NotContains(t, metadata, broken)
+}
@@ -0,0 +1,123 @@
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingReader yields a JSON object with an endless string value and counts the bytes handed out.
+type countingReader struct {
+	prefix io.Reader
+	read   int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.prefix.Read(p)
+	if err == io.EOF {
+		for i := range p {
+			p[i] = 'a'
+		}
+		n, err = len(p), nil
+	}
+	c.read += int64(n)
+	return n, err
+}
+
+func TestParseMetadataFromStreamWithLimit_Oversized(t *testing.T) {
+	const limit = 1024
+	r := &countingReader{prefix: strings.NewReader(`{"name":"`)}
+
+	_, err := ParseMetadataFromStreamWithLimit(r, limit)
+	require.ErrorIs(t, err, ErrMetadataTooLarge)
+	assert.LessOrEqual(t, r.read, int64(limit+1), "reader must stop right after the limit")
+}
+
+func TestParseMetadataFromStreamWithLimit_WithinLimit(t *testing.T) {
+	content := `{"name":"tool","version":"1.0.0","os":"linux","arch":"amd64","description":"x"}`
+
+	metadata, err := ParseMetadataFromStreamWithLimit(strings.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+	assert.Equal(t, "tool", metadata.Name)
+	assert.Equal(t, "1.0.0", metadata.Version)
+}
+
+func TestParseMetadataFromPathWithLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultMetadataFile)
+	content := `{"name":"tool","version":"1.0.0","description":"` + strings.Repeat("a", 4096) + `"}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	_, err := ParseMetadataFromPathWithLimit(path, 1024)
+	require.ErrorIs(t, err, ErrMetadataTooLarge)
+
+	metadata, err := ParseMetadataFromPathWithLimit(path, 0)
+	require.NoError(t, err, "non-positive limit uses the default")
+	assert.Equal(t, "tool", metadata.Name)
+}
+
+func TestVerifier_MaxMetadataSize(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "tool.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{
+		Name:        "tool",
+		Version:     "1.0.0",
+		OS:          "linux",
+		Arch:        "amd64",
+		Description: strings.Repeat("a", 2048),
+	})
+
+	v := NewVerifier()
+	require.NoError(t, v.VerifyArtifact(context.Background(), nil, artifactPath))
+
+	v.SetMaxMetadataSize(1024)
+	err := v.VerifyArtifact(context.Background(), nil, artifactPath)
+	require.ErrorIs(t, err, ErrMetadataTooLarge)
+}
+
+func TestHooks_JSON(t *testing.T) {
+	content := `{"name":"tool","version":"1.0.0","hooks":{"post-install":"setup.tengo","pre-install":["10-setup.tengo","20-perms.tengo"]}}`
+	metadata, err := ParseMetadataFromStream(strings.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, Hooks{
+		"post-install": {"setup.tengo"},
+		"pre-install":  {"10-setup.tengo", "20-perms.tengo"},
+	}, metadata.Hooks)
+	assert.Equal(t, []string{"10-setup.tengo", "20-perms.tengo", "setup.tengo"}, metadata.Hooks.Scripts())
+
+	// Single scripts keep the string form older versions can read
+	data, err := json.Marshal(metadata.Hooks)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"post-install":"setup.tengo","pre-install":["10-setup.tengo","20-perms.tengo"]}`, string(data))
+
+	_, err = ParseMetadataFromStream(strings.NewReader(`{"name":"tool","hooks":{"pre-install":42}}`))
+	require.ErrorIs(t, err, ErrInvalidMetadata)
+}
+
+func TestMetadata_Dependencies(t *testing.T) {
+	// Older packers wrote dependencies as plain names
+	metadata, err := ParseMetadataFromStream(strings.NewReader(`{"name":"tool","version":"1.0.0","dependencies":["libfoo","libbar >= 1.2.0"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []model.Dependency{{Name: "libfoo"}, {Name: "libbar", VersionConstraint: ">= 1.2.0"}}, metadata.Dependencies)
+
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool.txt"), []byte("tool"), 0o644))
+	deps := []model.Dependency{{Name: "libfoo", VersionConstraint: ">= 1.2.0"}}
+	artifactPath, err := NewPacker("tool", "1.0.0", "linux", "amd64", "", "constrained dependency", deps, nil, inputDir, tempDir).Pack()
+	require.NoError(t, err)
+
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	metadata, err = ParseMetadataFromPath(filepath.Join(mgr.getArtifactMetaInstallPath(desc), DefaultMetadataFile))
+	require.NoError(t, err)
+	assert.Equal(t, deps, metadata.Dependencies, "the packed metadata carries the version constraint")
+}
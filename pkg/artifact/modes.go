@@ -0,0 +1,70 @@
+package artifact
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+// FileModePolicy controls how extracted files with unsafe mode bits
+// (setuid, setgid, sticky or world-writable) are treated during installation.
+type FileModePolicy string
+
+const (
+	// FileModePolicyStrict rejects artifacts containing files with unsafe mode bits.
+	FileModePolicyStrict FileModePolicy = "strict"
+	// FileModePolicySanitize strips unsafe mode bits from extracted files and continues.
+	FileModePolicySanitize FileModePolicy = "sanitize"
+	// FileModePolicyPermissive installs files with their archived modes unchanged.
+	FileModePolicyPermissive FileModePolicy = "permissive"
+)
+
+const (
+	specialModeBits   = fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+	worldWritableBits = fs.FileMode(0o002)
+)
+
+// SetFileModePolicy sets the policy applied to file modes of extracted artifacts.
+func (m *ManagerImpl) SetFileModePolicy(policy FileModePolicy) {
+	m.fileModePolicy = policy
+}
+
+// enforceFileModePolicy checks all regular files below extractDir against the configured policy.
+// Unknown policies are treated as strict.
+func (m *ManagerImpl) enforceFileModePolicy(extractDir string) error {
+	if m.fileModePolicy == FileModePolicyPermissive {
+		return nil
+	}
+
+	return filepath.WalkDir(extractDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return errutils.Wrapf(err, "failed to get file info for %s", path)
+		}
+		mode := info.Mode()
+		if mode&specialModeBits == 0 && mode.Perm()&worldWritableBits == 0 {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(extractDir, path)
+		if err != nil {
+			return errutils.Wrapf(err, "failed to get relative path of %s", path)
+		}
+		if m.fileModePolicy == FileModePolicySanitize {
+			if err := os.Chmod(path, mode.Perm()&^worldWritableBits); err != nil {
+				return errutils.Wrapf(err, "failed to sanitize mode of %s", relPath)
+			}
+			return nil
+		}
+		return errutils.Wrapf(ErrDisallowedFileMode, "file %s has mode %s", filepath.ToSlash(relPath), mode)
+	})
+}
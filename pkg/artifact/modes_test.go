@@ -0,0 +1,90 @@
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/archive"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestArtifactWithFileMode creates an artifact containing a single data file with the given mode.
+// The packer always normalizes file modes, so the archive is assembled directly.
+func setupTestArtifactWithFileMode(t *testing.T, artifactPath string, mode os.FileMode) {
+	t.Helper()
+
+	inputDir := t.TempDir()
+	dataDir := filepath.Join(inputDir, artifactDataDir)
+	metaDir := filepath.Join(inputDir, artifactMetaDir)
+	require.NoError(t, os.MkdirAll(dataDir, 0o755))
+	require.NoError(t, os.MkdirAll(metaDir, 0o755))
+
+	content := []byte("#!/bin/sh\necho hello\n")
+	dataFile := filepath.Join(dataDir, "tool")
+	require.NoError(t, os.WriteFile(dataFile, content, 0o755))
+	require.NoError(t, os.Chmod(dataFile, mode))
+
+	metadata := &Metadata{
+		Name:    DefaultArtifactName,
+		Version: DefaultArtifactVersion,
+		OS:      DefaultArtifactOS,
+		Arch:    DefaultArtifactArch,
+		Hashes:  map[string]string{"data/tool": fmt.Sprintf("%x", sha256.Sum256(content))},
+	}
+	metaJSON, err := json.Marshal(metadata)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(metaDir, DefaultMetadataFile), metaJSON, 0o644))
+
+	require.NoError(t, archive.NewManager().Create(context.Background(), inputDir, artifactPath))
+}
+
+func TestInstallArtifact_FileModePolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     FileModePolicy
+		mode       os.FileMode
+		wantErr    bool
+		wantedMode os.FileMode
+	}{
+		{name: "strict rejects setuid", policy: FileModePolicyStrict, mode: 0o755 | os.ModeSetuid, wantErr: true},
+		{name: "strict rejects setgid", policy: FileModePolicyStrict, mode: 0o755 | os.ModeSetgid, wantErr: true},
+		{name: "strict rejects world-writable", policy: FileModePolicyStrict, mode: 0o777, wantErr: true},
+		{name: "strict allows regular executable", policy: FileModePolicyStrict, mode: 0o755, wantedMode: 0o755},
+		{name: "sanitize strips setuid", policy: FileModePolicySanitize, mode: 0o755 | os.ModeSetuid, wantedMode: 0o755},
+		{name: "sanitize strips world-write", policy: FileModePolicySanitize, mode: 0o777, wantedMode: 0o775},
+		{name: "permissive keeps mode", policy: FileModePolicyPermissive, mode: 0o777, wantedMode: 0o777},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			dbPath := filepath.Join(tempDir, "installed.db")
+			dataInstallDir := filepath.Join(tempDir, "install", artifactDataDir)
+			mgr := NewManager("linux", "amd64", tempDir, dataInstallDir, filepath.Join(tempDir, "install", artifactMetaDir), dbPath)
+			mgr.SetFileModePolicy(tt.policy)
+
+			artifactPath := filepath.Join(tempDir, "mode.gotya")
+			setupTestArtifactWithFileMode(t, artifactPath, tt.mode)
+
+			err := mgr.InstallArtifact(context.Background(), DefaultIndexArtifactDescriptor, artifactPath, model.InstallationReasonManual)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrDisallowedFileMode)
+				assert.NoDirExists(t, filepath.Join(dataInstallDir, DefaultArtifactName))
+				assert.False(t, loadInstalledDB(t, dbPath).IsArtifactInstalled(DefaultArtifactName))
+				return
+			}
+			require.NoError(t, err)
+
+			info, err := os.Stat(filepath.Join(dataInstallDir, DefaultArtifactName, "tool"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantedMode, info.Mode()&(os.ModePerm|specialModeBits))
+		})
+	}
+}
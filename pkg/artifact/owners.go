@@ -0,0 +1,98 @@
+package artifact
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// FileOwner is an installed artifact recording a file.
+type FileOwner struct {
+	Artifact *model.InstalledArtifact
+	File     *model.InstalledFile
+}
+
+// WhichArtifactOwns returns the installed artifact that recorded the file at path, together with its
+// record of the file. Relative paths are resolved against the working directory; symlinks are not resolved.
+// If no artifact owns the file, an error wrapping errutils.ErrArtifactNotFound is returned. If several
+// artifacts claim it, a *SharedFileError listing all of them is returned.
+func (m *ManagerImpl) WhichArtifactOwns(path string) (*model.InstalledArtifact, *model.InstalledFile, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, errutils.Wrapf(err, "failed to resolve %s", path)
+	}
+	unlock, err := m.readDB()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+
+	var owners []FileOwner
+	for _, artifact := range m.installDB.GetInstalledArtifacts() {
+		if artifact.Status != model.StatusInstalled {
+			continue
+		}
+		if file := findInstalledFile(artifact.ArtifactMetaDir, artifact.MetaFiles, absPath); file != nil {
+			owners = append(owners, FileOwner{Artifact: artifact, File: file})
+		}
+		if file := findInstalledFile(artifact.ArtifactDataDir, artifact.DataFiles, absPath); file != nil {
+			owners = append(owners, FileOwner{Artifact: artifact, File: file})
+		}
+	}
+
+	switch len(owners) {
+	case 0:
+		return nil, nil, errutils.Wrapf(errutils.ErrArtifactNotFound, "no installed artifact owns %s", absPath)
+	case 1:
+		return owners[0].Artifact, owners[0].File, nil
+	default:
+		return nil, nil, &SharedFileError{Path: absPath, Owners: owners}
+	}
+}
+
+// findInstalledFile returns the file of files whose path below dir is path, or nil.
+func findInstalledFile(dir string, files []model.InstalledFile, path string) *model.InstalledFile {
+	if dir == "" {
+		return nil
+	}
+	for i := range files {
+		if filepath.Join(dir, filepath.FromSlash(files[i].Path)) == path {
+			return &files[i]
+		}
+	}
+	return nil
+}
+
+// ListArtifactFiles returns the recorded meta and data files of an installed artifact with absolute paths
+// and their recorded hashes, sorted by path. If the artifact is not in the database, an error wrapping
+// errutils.ErrArtifactNotFound is returned. Placeholders of missing dependencies have no files; for them
+// an empty list and an error wrapping ErrArtifactPlaceholder are returned.
+func (m *ManagerImpl) ListArtifactFiles(name string) ([]model.InstalledFile, error) {
+	unlock, err := m.readDB()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	artifact := m.installDB.FindArtifact(name)
+	if artifact == nil {
+		return nil, errutils.Wrapf(errutils.ErrArtifactNotFound, "%s is not installed", name)
+	}
+	if artifact.Status == model.StatusMissing {
+		return []model.InstalledFile{}, errutils.Wrapf(ErrArtifactPlaceholder, "%s is required by %s but not installed", name, strings.Join(artifact.ReverseDependencies, ", "))
+	}
+
+	files := make([]model.InstalledFile, 0, len(artifact.MetaFiles)+len(artifact.DataFiles))
+	for _, set := range []struct {
+		dir   string
+		files []model.InstalledFile
+	}{{artifact.ArtifactMetaDir, artifact.MetaFiles}, {artifact.ArtifactDataDir, artifact.DataFiles}} {
+		for _, file := range set.files {
+			files = append(files, model.InstalledFile{Path: filepath.Join(set.dir, filepath.FromSlash(file.Path)), Hash: file.Hash, LinkedTo: file.LinkedTo})
+		}
+	}
+	slices.SortFunc(files, func(a, b model.InstalledFile) int { return strings.Compare(a.Path, b.Path) })
+	return files, nil
+}
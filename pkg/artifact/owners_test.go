@@ -0,0 +1,91 @@
+package artifact
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhichArtifactOwns(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	metaDir := filepath.Join(tempDir, "install", artifactMetaDir)
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, metaDir, filepath.Join(tempDir, "installed.db"))
+	for _, name := range []string{"tool", "other"} {
+		artifactPath := filepath.Join(tempDir, name+".gotya")
+		setupTestArtifact(t, artifactPath, true, &Metadata{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64"})
+		desc := &model.IndexArtifactDescriptor{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + ".gotya"}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	}
+
+	artifact, file, err := mgr.WhichArtifactOwns(filepath.Join(dataDir, "tool", "datafile2.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, "tool", artifact.Name)
+	assert.Equal(t, "datafile2.bin", file.Path)
+
+	artifact, file, err = mgr.WhichArtifactOwns(filepath.Join(metaDir, "other", DefaultMetadataFile))
+	require.NoError(t, err)
+	assert.Equal(t, "other", artifact.Name)
+	assert.Equal(t, DefaultMetadataFile, file.Path)
+
+	_, _, err = mgr.WhichArtifactOwns(filepath.Join(dataDir, "tool", "unknown.bin"))
+	assert.ErrorIs(t, err, errutils.ErrArtifactNotFound)
+
+	// A record claiming the files of tool makes them shared
+	require.NoError(t, mgr.loadInstalledDB())
+	mgr.installDB.AddArtifact(&model.InstalledArtifact{
+		Name:            "claimer",
+		Version:         "1.0.0",
+		Status:          model.StatusInstalled,
+		ArtifactDataDir: filepath.Join(dataDir, "tool"),
+		DataFiles:       []model.InstalledFile{{Path: "datafile2.bin", Hash: "hash"}},
+	})
+	require.NoError(t, mgr.installDB.SaveDatabase())
+
+	_, _, err = mgr.WhichArtifactOwns(filepath.Join(dataDir, "tool", "datafile2.bin"))
+	var shared *SharedFileError
+	require.ErrorAs(t, err, &shared)
+	require.Len(t, shared.Owners, 2)
+	names := []string{shared.Owners[0].Artifact.Name, shared.Owners[1].Artifact.Name}
+	assert.ElementsMatch(t, []string{"tool", "claimer"}, names)
+	assert.Equal(t, "datafile2.bin", shared.Owners[1].File.Path)
+}
+
+func TestListArtifactFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	metaDir := filepath.Join(tempDir, "install", artifactMetaDir)
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, metaDir, filepath.Join(tempDir, "installed.db"))
+	artifactPath := filepath.Join(tempDir, "tool.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64"})
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+
+	files, err := mgr.ListArtifactFiles("tool")
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.Equal(t, filepath.Join(dataDir, "tool", "datafile1.bin"), files[0].Path)
+	assert.Equal(t, filepath.Join(dataDir, "tool", "datafile2.bin"), files[1].Path)
+	assert.Equal(t, filepath.Join(metaDir, "tool", DefaultMetadataFile), files[2].Path)
+	for _, file := range files {
+		hash, err := calculateFileHash(file.Path)
+		require.NoError(t, err)
+		assert.Equal(t, hash, file.Hash, file.Path)
+	}
+
+	_, err = mgr.ListArtifactFiles("unknown")
+	assert.ErrorIs(t, err, errutils.ErrArtifactNotFound)
+
+	require.NoError(t, mgr.loadInstalledDB())
+	mgr.installDB.AddArtifact(&model.InstalledArtifact{Name: "libdep", Status: model.StatusMissing, ReverseDependencies: []string{"tool"}})
+	require.NoError(t, mgr.installDB.SaveDatabase())
+	files, err = mgr.ListArtifactFiles("libdep")
+	require.ErrorIs(t, err, ErrArtifactPlaceholder)
+	assert.NotNil(t, files)
+	assert.Empty(t, files)
+}
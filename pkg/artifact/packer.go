@@ -2,6 +2,7 @@ package artifact
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
 
@@ -17,6 +19,7 @@ import (
 	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/glorpus-work/gotya/pkg/fsutil"
 	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/hashicorp/go-version"
 )
 
 // Packer creates .gotya artifacts from input directories.
@@ -28,7 +31,17 @@ type Packer struct {
 	maintainer   string
 	description  string
 	dependencies []model.Dependency
-	hooks        map[string]string
+	hooks        Hooks
+	requirements map[string]string
+	hookLintMode HookLintMode
+	metadataFile string
+	tarBlockSize int
+	nameLimits   model.NameLimits
+	signingKey   ed25519.PrivateKey
+	// compressionLevel is the gzip level of created artifacts, 0 for the default level
+	compressionLevel int
+	// hookAllowlist restricts what hook scripts may use, nil for no restriction
+	hookAllowlist *HookAllowlist
 
 	inputDir  string
 	outputDir string
@@ -36,13 +49,17 @@ type Packer struct {
 	metadata  *Metadata
 }
 
+// artifactNamePattern is the charset allowed in the names and versions of packed artifacts. They end up in
+// file and directory names, so path separators, whitespace and shell metacharacters are not allowed.
+var artifactNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+~-]*$`)
+
 var allowedTopLevelFiles = []string{
 	artifactMetaDir,
 	artifactDataDir,
 }
 
 // NewPacker creates a new Packer instance with the specified configuration.
-func NewPacker(name, version, operatingSystem, arch, maintainer, description string, dependencies []model.Dependency, hooks map[string]string, inputDir, outputDir string) *Packer {
+func NewPacker(name, version, operatingSystem, arch, maintainer, description string, dependencies []model.Dependency, hooks Hooks, inputDir, outputDir string) *Packer {
 	return &Packer{
 		name:         name,
 		version:      version,
@@ -52,11 +69,71 @@ func NewPacker(name, version, operatingSystem, arch, maintainer, description str
 		description:  description,
 		dependencies: dependencies,
 		hooks:        hooks,
+		hookLintMode: HookLintWarn,
+		metadataFile: DefaultMetadataFile,
+		nameLimits:   model.DefaultNameLimits,
 		inputDir:     inputDir,
 		outputDir:    outputDir,
 	}
 }
 
+// SetMetadataFileName sets the name of the metadata file written to the meta directory.
+// It defaults to DefaultMetadataFile.
+func (p *Packer) SetMetadataFileName(name string) error {
+	if err := validateMetadataFileName(name); err != nil {
+		return err
+	}
+	p.metadataFile = name
+	return nil
+}
+
+// SetTarBlockSize pads the tar stream of created artifacts with zeros to a multiple of size bytes, for
+// tar readers that require complete records. size must be a multiple of archive.TarBlockSize; 0, the
+// default, disables padding.
+func (p *Packer) SetTarBlockSize(size int) error {
+	if size < 0 || size%archive.TarBlockSize != 0 {
+		return errutils.Wrapf(errutils.ErrValidation, "tar block size %d is not a multiple of %d", size, archive.TarBlockSize)
+	}
+	p.tarBlockSize = size
+	return nil
+}
+
+// SetCompressionLevel sets the gzip compression level of created artifacts, from gzip.BestSpeed for fast
+// development builds to gzip.BestCompression for small release artifacts. 0, the default, uses the default
+// level of gzip. Levels outside the range gzip accepts are rejected with errutils.ErrValidation.
+func (p *Packer) SetCompressionLevel(level int) error {
+	if err := archive.Gzip.ValidateLevel(level); err != nil {
+		return err
+	}
+	p.compressionLevel = level
+	return nil
+}
+
+// SetRequirements declares the host capabilities the artifact needs, mapping each capability name,
+// e.g. "glibc", to its minimum version. Versions must be valid semantic versions.
+func (p *Packer) SetRequirements(requirements map[string]string) error {
+	for name, v := range requirements {
+		if name == "" {
+			return errutils.Wrap(errutils.ErrValidation, "requirement name cannot be empty")
+		}
+		if _, err := version.NewVersion(v); err != nil {
+			return errutils.Wrapf(errutils.ErrValidation, "invalid version %q for requirement %s", v, name)
+		}
+	}
+	p.requirements = maps.Clone(requirements)
+	return nil
+}
+
+// SetNameLimits sets the maximum lengths of the artifact name and version. It defaults to model.DefaultNameLimits.
+func (p *Packer) SetNameLimits(limits model.NameLimits) {
+	p.nameLimits = limits
+}
+
+// metadataFileName returns the configured metadata file name.
+func (p *Packer) metadataFileName() string {
+	return orDefaultMetadataFile(p.metadataFile)
+}
+
 // Pack creates a .gotya artifact from the configured input directory and returns the path to the created artifact.
 func (p *Packer) Pack() (string, error) {
 	dir, err := os.MkdirTemp("", "gotya-packer")
@@ -72,6 +149,18 @@ func (p *Packer) Pack() (string, error) {
 		return "", err
 	}
 
+	if err := p.lintHookScripts(); err != nil {
+		return "", err
+	}
+
+	if err := p.compileHookScripts(); err != nil {
+		return "", err
+	}
+
+	if err := p.checkHookAllowlist(); err != nil {
+		return "", err
+	}
+
 	p.metadata = &Metadata{
 		Name:         p.name,
 		Version:      p.version,
@@ -81,19 +170,27 @@ func (p *Packer) Pack() (string, error) {
 		Description:  p.description,
 		Dependencies: p.dependencies,
 		Hooks:        p.hooks,
+		Requirements: p.requirements,
 		Hashes:       make(map[string]string),
 	}
 
 	if err := p.copyInputDir(); err != nil {
 		return "", err
 	}
+	p.metadata.ManifestDigest = ComputeManifestDigest(p.metadata.Hashes)
 
 	if err := p.createMetadataFile(); err != nil {
 		return "", err
 	}
 
+	if p.signingKey != nil {
+		if err := p.signMetadata(); err != nil {
+			return "", err
+		}
+	}
+
 	archiveManager := archive.NewManager()
-	if err := archiveManager.Create(context.Background(), p.tempDir, p.getOutputFile()); err != nil {
+	if err := archiveManager.CreateWithOptions(context.Background(), p.tempDir, p.getOutputFile(), archive.Options{BlockSize: p.tarBlockSize, CompressionLevel: p.compressionLevel}); err != nil {
 		return "", err
 	}
 
@@ -106,6 +203,9 @@ func (p *Packer) Pack() (string, error) {
 
 func (p *Packer) verify() error {
 	verifier := NewVerifier()
+	if err := verifier.SetMetadataFileName(p.metadataFileName()); err != nil {
+		return err
+	}
 	desc := &model.IndexArtifactDescriptor{
 		Name:    p.name,
 		Version: p.version,
@@ -116,23 +216,34 @@ func (p *Packer) verify() error {
 	if err := verifier.VerifyArtifactFromPath(context.Background(), desc, p.tempDir); err != nil {
 		return err
 	}
+	if p.signingKey != nil {
+		if err := verifySignatureFromPath(p.tempDir, p.metadataFileName(), p.signingKey.Public().(ed25519.PublicKey)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // checkInput checks if the input is valid
 // It ensures that:
 // - The input directory exists
-// - No artifact.json exists in the input directory
+// - No metadata file exists in the input directory
 // - No other files than meta and data directories exist in the input directory
 // - Only hook scripts with the .tengo extension exist in the meta directory
-// - All hook scripts in the meta directory are referenced
+// - All hook scripts in the meta directory are referenced, by any position of any hook type
+// - All hook keys are hook types, optionally qualified with a platform as in "post-install@windows/amd64"
+// - Name and version are within the name limits and only use letters, digits and . _ + ~ -
 func (p *Packer) checkInput() error {
 	if _, err := os.Stat(p.inputDir); err != nil {
 		return errutils.Wrapf(errutils.ErrInvalidPath, "input directory %s does not exist", p.inputDir)
 	}
 
-	if _, err := os.Stat(filepath.Join(p.inputDir, artifactMetaDir, metadataFile)); err == nil {
-		return errutils.Wrapf(errutils.ErrInvalidPath, "artifact.json already exists in input directory")
+	if _, err := os.Stat(filepath.Join(p.inputDir, artifactMetaDir, p.metadataFileName())); err == nil {
+		return errutils.Wrapf(errutils.ErrInvalidPath, "%s already exists in input directory", p.metadataFileName())
+	}
+
+	if err := p.hooks.Validate(); err != nil {
+		return err
 	}
 
 	rootDir, err := os.ReadDir(p.inputDir)
@@ -154,12 +265,21 @@ func (p *Packer) checkInput() error {
 			if !strings.HasSuffix(entry.Name(), ".tengo") {
 				return errutils.Wrapf(errutils.ErrInvalidPath, "file %s is not allowed in meta directory", entry.Name())
 			}
-			if !slices.Contains(slices.Collect(maps.Values(p.hooks)), entry.Name()) {
+			if !slices.Contains(p.hooks.Scripts(), entry.Name()) {
 				return errutils.Wrapf(errutils.ErrInvalidPath, "hook %s is not referenced", entry.Name())
 			}
 		}
 	}
 
+	if err := p.nameLimits.Check(p.name, p.version); err != nil {
+		return err
+	}
+	for _, value := range []string{p.name, p.version} {
+		if !artifactNamePattern.MatchString(value) {
+			return errutils.Wrapf(errutils.ErrValidation, "%q may only contain letters, digits and . _ + ~ - and must start with a letter or digit", value)
+		}
+	}
+
 	return nil
 }
 
@@ -282,7 +402,7 @@ func (p *Packer) createMetadataFile() error {
 		return err
 	}
 
-	file, err := fsutil.CreateFilePerm(filepath.Join(p.tempDir, artifactMetaDir, metadataFile), fsutil.FileModeDefault)
+	file, err := fsutil.CreateFilePerm(filepath.Join(p.tempDir, artifactMetaDir, p.metadataFileName()), fsutil.FileModeDefault)
 	if err != nil {
 		return err
 	}
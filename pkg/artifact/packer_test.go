@@ -1,12 +1,18 @@
 package artifact
 
 import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -49,7 +55,7 @@ func TestPacker_Pack(t *testing.T) {
 				arch:        "amd64",
 				maintainer:  "test@example.com",
 				description: "Test package",
-				hooks:       map[string]string{"pre-install": "pre-install.tengo"},
+				hooks:       Hooks{"pre-install": {"pre-install.tengo"}},
 			},
 			expectedErr:  nil,
 			expectOutput: true,
@@ -102,7 +108,7 @@ func TestPacker_Pack(t *testing.T) {
 			},
 			packer: &Packer{
 				name:  "test-package",
-				hooks: map[string]string{},
+				hooks: Hooks{},
 			},
 			expectedErr:  errutils.ErrInvalidPath,
 			expectOutput: false,
@@ -135,7 +141,7 @@ func TestPacker_Pack(t *testing.T) {
 				arch:        "amd64",
 				maintainer:  "test@example.com",
 				description: "Metadata only test package",
-				hooks:       map[string]string{"pre-install": "pre-install.tengo"},
+				hooks:       Hooks{"pre-install": {"pre-install.tengo"}},
 			},
 			expectedErr:  nil,
 			expectOutput: true,
@@ -287,3 +293,319 @@ func TestPackSymlinks(t *testing.T) {
 		})
 	}
 }
+
+func TestLintHookScript(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected []string
+	}{
+		{
+			name:     "absolute path write",
+			script:   "os := import(\"os\")\nos.write_file(\"/etc/passwd\", \"x\")\n",
+			expected: []string{"/etc/passwd"},
+		},
+		{
+			name:     "parent directory traversal",
+			script:   "os := import(\"os\")\ndirs := import(\"dirs\")\nos.remove(dirs.data_dir + \"/../other\")\n",
+			expected: []string{"/../other"},
+		},
+		{
+			name:     "windows drive path in raw string",
+			script:   "os := import(\"os\")\nos.remove(`C:\\Windows\\System32`)\n",
+			expected: []string{`C:\Windows\System32`},
+		},
+		{
+			name:     "relative paths and comments are ignored",
+			script:   "os := import(\"os\")\ndirs := import(\"dirs\")\n// writes to /etc are not allowed\nos.write_file(dirs.data_dir + \"/config.yaml\", \"x\") // not /etc\n",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := LintHookScript("hook.tengo", []byte(tt.script))
+			var literals []string
+			for _, f := range findings {
+				literals = append(literals, f.Literal)
+			}
+			assert.Equal(t, tt.expected, literals)
+		})
+	}
+}
+
+func TestPacker_Pack_HookLint(t *testing.T) {
+	setup := func(t *testing.T) (string, string) {
+		tempDir := t.TempDir()
+		inputDir := filepath.Join(tempDir, "input")
+		outputDir := filepath.Join(tempDir, "output")
+		require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0755))
+		require.NoError(t, os.MkdirAll(outputDir, 0755))
+		script := "os := import(\"os\")\nos.write_file(\"/etc/gotya.conf\", \"x\")\n"
+		require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactMetaDir, "post-install.tengo"), []byte(script), 0644))
+		return inputDir, outputDir
+	}
+	hooks := Hooks{"post-install": {"post-install.tengo"}}
+
+	t.Run("error mode rejects", func(t *testing.T) {
+		inputDir, outputDir := setup(t)
+		p := NewPacker("lint", "1.0.0", "linux", "amd64", "", "lint test", nil, hooks, inputDir, outputDir)
+		p.SetHookLintMode(HookLintError)
+
+		_, err := p.Pack()
+		require.ErrorIs(t, err, ErrUnsafeHookScript)
+		assert.Contains(t, err.Error(), "post-install.tengo:2")
+		assert.Contains(t, err.Error(), "/etc/gotya.conf")
+	})
+
+	t.Run("warn mode packs", func(t *testing.T) {
+		inputDir, outputDir := setup(t)
+		p := NewPacker("lint", "1.0.0", "linux", "amd64", "", "lint test", nil, hooks, inputDir, outputDir)
+
+		outputFile, err := p.Pack()
+		require.NoError(t, err)
+		assert.FileExists(t, outputFile)
+	})
+}
+
+func TestPacker_Pack_HookSyntaxError(t *testing.T) {
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	outputDir := filepath.Join(tempDir, "output")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0755))
+	require.NoError(t, os.MkdirAll(outputDir, 0755))
+	valid := "dirs := import(\"dirs\")\nctx := import(\"context\")\nfmt := import(\"fmt\")\nfmt.println(ctx.artifact_name, dirs.meta_dir)\n"
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactMetaDir, "post-install.tengo"), []byte(valid), 0644))
+	broken := "fmt := import(\"fmt\")\nfmt.println(\"a\" \"b\")\n"
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactMetaDir, "pre-uninstall.tengo"), []byte(broken), 0644))
+
+	hooks := Hooks{"post-install": {"post-install.tengo"}, "pre-uninstall": {"pre-uninstall.tengo"}}
+	_, err := NewPacker("broken", "1.0.0", "linux", "amd64", "", "broken hook", nil, hooks, inputDir, outputDir).Pack()
+	require.ErrorIs(t, err, ErrInvalidHookScript)
+	assert.Contains(t, err.Error(), "pre-uninstall.tengo:2:")
+	assert.NoFileExists(t, filepath.Join(outputDir, "broken_1.0.0_linux_amd64.gotya"))
+
+	delete(hooks, "pre-uninstall")
+	require.NoError(t, os.Remove(filepath.Join(inputDir, artifactMetaDir, "pre-uninstall.tengo")))
+	_, err = NewPacker("broken", "1.0.0", "linux", "amd64", "", "broken hook", nil, hooks, inputDir, outputDir).Pack()
+	require.NoError(t, err, "hooks importing the hook modules compile")
+}
+
+func TestPacker_Pack_NameLimits(t *testing.T) {
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	outputDir := filepath.Join(tempDir, "output")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0755))
+	require.NoError(t, os.MkdirAll(outputDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool.txt"), []byte("tool"), 0644))
+
+	longName := strings.Repeat("a", model.DefaultMaxNameLength+1)
+	for _, tt := range []struct{ name, version string }{
+		{longName, "1.0.0"},
+		{"tool", "1.0.0-" + strings.Repeat("1", model.DefaultMaxVersionLength)},
+		{"my tool", "1.0.0"},
+		{"tool/sub", "1.0.0"},
+		{".tool", "1.0.0"},
+		{"tool", "1.0.0;rm"},
+	} {
+		t.Run(tt.name+"@"+tt.version, func(t *testing.T) {
+			_, err := NewPacker(tt.name, tt.version, "linux", "amd64", "", "name limits", nil, nil, inputDir, outputDir).Pack()
+			require.ErrorIs(t, err, errutils.ErrValidation)
+		})
+	}
+
+	artifactPath, err := NewPacker("my-tool_2.x", "1.0.0+build.1", "linux", "amd64", "", "name limits", nil, nil, inputDir, outputDir).Pack()
+	require.NoError(t, err)
+	assert.FileExists(t, artifactPath)
+
+	packer := NewPacker("tool", "1.0.0", "linux", "amd64", "", "name limits", nil, nil, inputDir, outputDir)
+	packer.SetNameLimits(model.NameLimits{MaxNameLength: 3})
+	_, err = packer.Pack()
+	require.ErrorIs(t, err, errutils.ErrValidation)
+
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+	desc := &model.IndexArtifactDescriptor{Name: longName, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/long.gotya"}
+	err = mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual)
+	require.ErrorIs(t, err, errutils.ErrValidation)
+	assert.Contains(t, err.Error(), "the maximum is 128")
+	assert.NoDirExists(t, filepath.Join(tempDir, "install", artifactDataDir, longName))
+
+	desc = &model.IndexArtifactDescriptor{Name: "my-tool_2.x", Version: "1.0.0+build.1", OS: "linux", Arch: "amd64", URL: "http://example.com/my-tool.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+}
+
+func TestPacker_Pack_CompressionLevel(t *testing.T) {
+	inputDir := filepath.Join(t.TempDir(), "input")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0755))
+	var text strings.Builder
+	for i := 0; text.Len() < 256<<10; i++ {
+		fmt.Fprintf(&text, "line %d of artifact %d\n", i, i*i%977)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "words.txt"), []byte(text.String()), 0644))
+
+	pack := func(t *testing.T, level int) int64 {
+		t.Helper()
+		packer := NewPacker("tool", "1.0.0", "linux", "amd64", "", "compression level", nil, nil, inputDir, t.TempDir())
+		require.NoError(t, packer.SetCompressionLevel(level))
+		artifactPath, err := packer.Pack()
+		require.NoError(t, err)
+		info, err := os.Stat(artifactPath)
+		require.NoError(t, err)
+		return info.Size()
+	}
+	assert.Less(t, pack(t, gzip.BestCompression), pack(t, gzip.BestSpeed))
+
+	packer := NewPacker("tool", "1.0.0", "linux", "amd64", "", "compression level", nil, nil, inputDir, t.TempDir())
+	require.ErrorIs(t, packer.SetCompressionLevel(gzip.BestCompression+1), errutils.ErrValidation)
+}
+
+func TestPacker_CustomMetadataFileName(t *testing.T) {
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	outputDir := filepath.Join(tempDir, "output")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0755))
+	require.NoError(t, os.MkdirAll(outputDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool.bin"), []byte("tool"), 0644))
+
+	p := NewPacker("tool", "1.0.0", "linux", "amd64", "", "custom metadata", nil, nil, inputDir, outputDir)
+	for _, invalid := range []string{"", ".", "..", "meta/package.json", `meta\package.json`} {
+		require.ErrorIs(t, p.SetMetadataFileName(invalid), errutils.ErrValidation, invalid)
+	}
+	require.NoError(t, p.SetMetadataFileName("package.json"))
+
+	artifactPath, err := p.Pack()
+	require.NoError(t, err)
+
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+
+	t.Run("default manager rejects", func(t *testing.T) {
+		dir := t.TempDir()
+		mgr := NewManager("linux", "amd64", dir, filepath.Join(dir, "install", artifactDataDir), filepath.Join(dir, "install", artifactMetaDir), filepath.Join(dir, "installed.db"))
+		err := mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual)
+		require.Error(t, err)
+	})
+
+	t.Run("matching manager installs", func(t *testing.T) {
+		dir := t.TempDir()
+		dbPath := filepath.Join(dir, "installed.db")
+		mgr := NewManager("linux", "amd64", dir, filepath.Join(dir, "install", artifactDataDir), filepath.Join(dir, "install", artifactMetaDir), dbPath)
+		require.NoError(t, mgr.SetMetadataFileName("package.json"))
+
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+		assert.FileExists(t, filepath.Join(dir, "install", artifactMetaDir, "tool", "package.json"))
+		assert.NoFileExists(t, filepath.Join(dir, "install", artifactMetaDir, "tool", DefaultMetadataFile))
+
+		installed := loadInstalledDB(t, dbPath).FindArtifact("tool")
+		require.NotNil(t, installed)
+		require.Len(t, installed.MetaFiles, 1)
+		assert.Equal(t, "package.json", installed.MetaFiles[0].Path)
+	})
+}
+
+func TestPacker_ManifestDigest(t *testing.T) {
+	pack := func(t *testing.T, version, content string) (*Packer, string) {
+		t.Helper()
+		tempDir := t.TempDir()
+		inputDir := filepath.Join(tempDir, "input")
+		outputDir := filepath.Join(tempDir, "output")
+		require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir, "bin"), 0755))
+		require.NoError(t, os.MkdirAll(outputDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "bin", "tool"), []byte(content), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "README"), []byte("readme"), 0644))
+
+		p := NewPacker("tool", version, "linux", "amd64", "", "manifest digest", nil, nil, inputDir, outputDir)
+		artifactPath, err := p.Pack()
+		require.NoError(t, err)
+		return p, artifactPath
+	}
+
+	first, firstPath := pack(t, "1.0.0", "tool v1")
+	same, samePath := pack(t, "1.0.1", "tool v1")
+	changed, changedPath := pack(t, "1.1.0", "tool v2")
+
+	require.NotEmpty(t, first.metadata.ManifestDigest)
+	assert.Equal(t, first.metadata.ManifestDigest, same.metadata.ManifestDigest, "identical content must have the same digest")
+	assert.NotEqual(t, first.metadata.ManifestDigest, changed.metadata.ManifestDigest, "different content must have a different digest")
+
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+
+	t.Run("persisted on install", func(t *testing.T) {
+		dir := t.TempDir()
+		dbPath := filepath.Join(dir, "installed.db")
+		mgr := NewManager("linux", "amd64", dir, filepath.Join(dir, "install", artifactDataDir), filepath.Join(dir, "install", artifactMetaDir), dbPath)
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, firstPath, model.InstallationReasonManual))
+
+		installed := loadInstalledDB(t, dbPath).FindArtifact("tool")
+		require.NotNil(t, installed)
+		assert.Equal(t, first.metadata.ManifestDigest, installed.ManifestDigest)
+		assert.True(t, installed.HasSameContent(same.metadata.ManifestDigest))
+		assert.False(t, installed.HasSameContent(changed.metadata.ManifestDigest))
+	})
+
+	t.Run("update keeps unchanged data files", func(t *testing.T) {
+		dir := t.TempDir()
+		dbPath := filepath.Join(dir, "installed.db")
+		dataDir := filepath.Join(dir, "install", artifactDataDir)
+		mgr := NewManager("linux", "amd64", dir, dataDir, filepath.Join(dir, "install", artifactMetaDir), dbPath)
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, firstPath, model.InstallationReasonManual))
+		toolPath := filepath.Join(dataDir, "tool", "bin", "tool")
+		before, err := os.Stat(toolPath)
+		require.NoError(t, err)
+
+		sameDesc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.1", OS: "linux", Arch: "amd64", URL: "http://example.com/tool-1.0.1.gotya"}
+		require.NoError(t, mgr.UpdateArtifact(context.Background(), samePath, sameDesc))
+		after, err := os.Stat(toolPath)
+		require.NoError(t, err)
+		assert.True(t, os.SameFile(before, after), "unchanged data files must not be replaced")
+		installed := loadInstalledDB(t, dbPath).FindArtifact("tool")
+		require.NotNil(t, installed)
+		assert.Equal(t, "1.0.1", installed.Version)
+		assert.Len(t, installed.DataFiles, 2)
+		metadata, err := mgr.parseMetadata(filepath.Join(installed.ArtifactMetaDir, DefaultMetadataFile))
+		require.NoError(t, err)
+		assert.Equal(t, "1.0.1", metadata.Version)
+
+		changedDesc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.1.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool-1.1.0.gotya"}
+		require.NoError(t, mgr.UpdateArtifact(context.Background(), changedPath, changedDesc))
+		content, err := os.ReadFile(toolPath)
+		require.NoError(t, err)
+		assert.Equal(t, "tool v2", string(content))
+	})
+
+	t.Run("descriptor mismatch", func(t *testing.T) {
+		mismatched := *desc
+		mismatched.ManifestDigest = changed.metadata.ManifestDigest
+		err := NewVerifier().VerifyArtifact(context.Background(), &mismatched, firstPath)
+		var mismatch *MetadataMismatchError
+		require.ErrorAs(t, err, &mismatch)
+		assert.Equal(t, "manifest digest", mismatch.Field)
+	})
+}
+
+func TestPacker_TarBlockSize(t *testing.T) {
+	inputDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool"), []byte("tool"), 0o644))
+
+	packer := NewPacker("tool", "1.0.0", "linux", "amd64", "", "", nil, nil, inputDir, t.TempDir())
+	require.ErrorIs(t, packer.SetTarBlockSize(1000), errutils.ErrValidation)
+	require.NoError(t, packer.SetTarBlockSize(10240))
+	artifactPath, err := packer.Pack()
+	require.NoError(t, err)
+
+	file, err := os.Open(artifactPath)
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+	reader, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	size, err := io.Copy(io.Discard, reader)
+	require.NoError(t, err)
+	assert.Zero(t, size%10240)
+
+	// The padded artifact still installs
+	tempDir := t.TempDir()
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "data"), filepath.Join(tempDir, "meta"), filepath.Join(tempDir, "installed.db"))
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	assert.FileExists(t, filepath.Join(tempDir, "data", "tool", "tool"))
+}
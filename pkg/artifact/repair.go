@@ -0,0 +1,187 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/fsutil"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// repairedFile is a file replaced by RepairArtifact and where its damaged original was moved to.
+type repairedFile struct {
+	target string
+	backup string // Empty if the file was missing
+}
+
+// RepairArtifact restores the damaged files of an installed artifact from the artifact file at localPath,
+// e.g. the cached file it was installed from. The file must contain the installed name, version, OS and
+// architecture. Only files that are missing or whose hash differs are replaced and missing directories,
+// including empty ones, are recreated; files that are not part of the artifact are left alone. The recorded
+// files and checksum are rewritten from the artifact file.
+// If the repair fails, the replaced files and the database record are restored. Hooks are not run.
+func (m *ManagerImpl) RepairArtifact(ctx context.Context, name, localPath string) (err error) {
+	if name == "" {
+		return errutils.Wrap(errutils.ErrValidation, "artifact name cannot be empty")
+	}
+	if localPath == "" {
+		return errutils.Wrap(errutils.ErrValidation, "local path cannot be empty")
+	}
+
+	unlock := m.artifactLocks.Lock(name)
+	defer unlock()
+
+	if err := m.installDB.CheckWritable(); err != nil {
+		return err
+	}
+
+	unlockDB, err := m.lockDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlockDB()
+
+	if err := m.loadInstalledDB(); err != nil {
+		return err
+	}
+	installed := m.installDB.FindArtifact(name)
+	if installed == nil {
+		return errutils.Wrapf(errutils.ErrArtifactNotFound, "%s is not installed", name)
+	}
+	if installed.Status != model.StatusInstalled {
+		return errutils.Wrapf(errutils.ErrValidation, "%s has status %s and cannot be repaired", name, installed.Status)
+	}
+
+	desc := &model.IndexArtifactDescriptor{
+		Name: installed.Name, Version: installed.Version, Description: installed.Description,
+		OS: installed.OS, Arch: installed.Arch, URL: installed.InstalledFrom,
+	}
+	extractDir, err := os.MkdirTemp("", fmt.Sprintf("gotya-repair-%s-%s", desc.Name, desc.Version))
+	if err != nil {
+		return errutils.Wrap(err, "failed to create extract directory")
+	}
+	defer func() { err = m.removeExtractDir(extractDir, err) }()
+
+	if err := m.extractAndVerify(ctx, desc, localPath, extractDir); err != nil {
+		return err
+	}
+	metadataPath := filepath.Join(extractDir, artifactMetaDir, m.metadataFileName())
+	metadata, err := m.parseMetadata(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	metaFiles, dataFiles, err := buildInstalledFileEntries(metadata, metadataPath)
+	if err != nil {
+		return err
+	}
+	checksum, err := calculateFileHash(localPath)
+	if err != nil {
+		return errutils.Wrapf(err, "failed to calculate checksum of %s", localPath)
+	}
+
+	backupDir, err := os.MkdirTemp(m.artifactMetaInstallDir, fmt.Sprintf(".gotya-repair-temp-%s-%s", desc.Name, desc.Version))
+	if err != nil {
+		return errutils.Wrap(err, "failed to create backup directory")
+	}
+	previous := *installed
+	var repaired []repairedFile
+	defer func() {
+		if err != nil {
+			restoreRepairedFiles(repaired)
+			m.restoreDBArtifact(&previous)
+		}
+		_ = os.RemoveAll(backupDir)
+	}()
+
+	for _, dirs := range []struct {
+		source, target, backup string
+		files                  []model.InstalledFile
+	}{
+		{filepath.Join(extractDir, artifactMetaDir), installed.ArtifactMetaDir, filepath.Join(backupDir, artifactMetaDir), metaFiles},
+		{filepath.Join(extractDir, artifactDataDir), installed.ArtifactDataDir, filepath.Join(backupDir, artifactDataDir), dataFiles},
+	} {
+		created, dirErr := recreateMissingDirs(dirs.source, dirs.target)
+		for _, dir := range created {
+			repaired = append(repaired, repairedFile{target: dir})
+		}
+		if err = dirErr; err != nil {
+			return err
+		}
+		for _, file := range dirs.files {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			rel := filepath.FromSlash(file.Path)
+			target := filepath.Join(dirs.target, rel)
+			if hash, hashErr := calculateFileHash(target); hashErr == nil && hash == file.Hash {
+				continue
+			}
+
+			restored := repairedFile{target: target}
+			if _, statErr := os.Lstat(target); statErr == nil {
+				restored.backup = filepath.Join(dirs.backup, rel)
+				if err = fsutil.Move(target, restored.backup); err != nil {
+					return errutils.Wrapf(err, "failed to back up %s", target)
+				}
+			}
+			repaired = append(repaired, restored)
+			if err = fsutil.Move(filepath.Join(dirs.source, rel), target); err != nil {
+				return errutils.Wrapf(err, "failed to restore %s", target)
+			}
+		}
+	}
+
+	installed.MetaFiles = metaFiles
+	installed.DataFiles = dataFiles
+	installed.Checksum = checksum
+	installed.ManifestDigest = metadata.ManifestDigest
+	if err = m.installDB.SaveDatabase(); err != nil {
+		return errutils.Wrap(err, "failed to save installed database")
+	}
+	return nil
+}
+
+// recreateMissingDirs creates the directories of the extracted tree at source that are missing below target,
+// e.g. empty directories shipped by the artifact, and returns the created directories, parents first.
+func recreateMissingDirs(source, target string) ([]string, error) {
+	if _, err := os.Stat(source); os.IsNotExist(err) {
+		return nil, nil
+	}
+	var created []string
+	err := filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Join(target, rel)
+		if _, statErr := os.Lstat(dir); statErr == nil {
+			return nil
+		}
+		if err := os.MkdirAll(dir, fsutil.DirModeDefault); err != nil {
+			return errutils.Wrapf(err, "failed to recreate directory %s", dir)
+		}
+		created = append(created, dir)
+		return nil
+	})
+	return created, err
+}
+
+// restoreRepairedFiles puts the originals of files replaced by a failed repair back, in reverse order.
+func restoreRepairedFiles(repaired []repairedFile) {
+	for i := len(repaired) - 1; i >= 0; i-- {
+		_ = os.RemoveAll(repaired[i].target)
+		if repaired[i].backup != "" {
+			_ = fsutil.Move(repaired[i].backup, repaired[i].target)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package artifact
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairArtifact(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+	artifactPath := filepath.Join(tempDir, "tool.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64"})
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+
+	deleted := filepath.Join(dataDir, "tool", "datafile1.bin")
+	stray := filepath.Join(dataDir, "tool", "stray.txt")
+	require.NoError(t, os.Remove(deleted))
+	require.NoError(t, os.WriteFile(stray, []byte("kept"), 0o644))
+
+	require.NoError(t, mgr.RepairArtifact(context.Background(), "tool", artifactPath))
+
+	content, err := os.ReadFile(deleted)
+	require.NoError(t, err)
+	assert.Equal(t, "test data 1", string(content))
+	assert.FileExists(t, stray, "files not belonging to the artifact are left alone")
+
+	results, err := mgr.VerifyInstalled(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Missing)
+	assert.Empty(t, results[0].Modified)
+
+	expectedChecksum, err := calculateFileHash(artifactPath)
+	require.NoError(t, err)
+	installed, err := mgr.GetInstalledArtifacts()
+	require.NoError(t, err)
+	require.Len(t, installed, 1)
+	assert.Equal(t, expectedChecksum, installed[0].Checksum)
+	assert.Len(t, installed[0].DataFiles, 2)
+}
+
+func TestRepairArtifact_RecreatesEmptyDirectories(t *testing.T) {
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir, "logs"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir, "bin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "bin", "app"), []byte("app"), 0o755))
+	artifactPath, err := NewPacker("app", "1.0.0", "linux", "amd64", "", "empty dirs", nil, nil, inputDir, tempDir).Pack()
+	require.NoError(t, err)
+
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+	desc := &model.IndexArtifactDescriptor{Name: "app", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/app.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+
+	logsDir := filepath.Join(dataDir, "app", "logs")
+	require.DirExists(t, logsDir, "empty directories are installed")
+	require.NoError(t, os.Remove(logsDir))
+
+	require.NoError(t, mgr.RepairArtifact(context.Background(), "app", artifactPath))
+	assert.DirExists(t, logsDir)
+	assert.FileExists(t, filepath.Join(dataDir, "app", "bin", "app"))
+}
+
+func TestRepairArtifact_WrongArtifactLeavesInstallUntouched(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+	artifactPath := filepath.Join(tempDir, "tool.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64"})
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+
+	otherPath := filepath.Join(tempDir, "tool-2.gotya")
+	setupTestArtifact(t, otherPath, true, &Metadata{Name: "tool", Version: "2.0.0", OS: "linux", Arch: "amd64"})
+	require.NoError(t, os.Remove(filepath.Join(dataDir, "tool", "datafile1.bin")))
+
+	err := mgr.RepairArtifact(context.Background(), "tool", otherPath)
+	var mismatch *MetadataMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.NoFileExists(t, filepath.Join(dataDir, "tool", "datafile1.bin"))
+
+	installed, err := mgr.GetInstalledArtifacts()
+	require.NoError(t, err)
+	require.Len(t, installed, 1)
+	assert.Equal(t, "1.0.0", installed[0].Version)
+}
+
+func TestRepairArtifact_RejectsMissingArtifacts(t *testing.T) {
+	tempDir := t.TempDir()
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "data"), filepath.Join(tempDir, "meta"), filepath.Join(tempDir, "installed.db"))
+	require.NoError(t, mgr.loadInstalledDB())
+	mgr.installDB.AddArtifact(&model.InstalledArtifact{Name: "placeholder", Status: model.StatusMissing, ReverseDependencies: []string{"app"}})
+	require.NoError(t, mgr.installDB.SaveDatabase())
+
+	err := mgr.RepairArtifact(context.Background(), "placeholder", filepath.Join(tempDir, "placeholder.gotya"))
+	assert.ErrorIs(t, err, errutils.ErrValidation)
+
+	err = mgr.RepairArtifact(context.Background(), "unknown", filepath.Join(tempDir, "unknown.gotya"))
+	assert.ErrorIs(t, err, errutils.ErrArtifactNotFound)
+}
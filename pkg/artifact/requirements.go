@@ -0,0 +1,73 @@
+package artifact
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+// HostCapabilities reports the runtime capabilities of the host that artifact requirements are checked against,
+// e.g. the glibc version.
+type HostCapabilities interface {
+	// Capability returns the version of the named capability. ok is false if the host does not have it.
+	Capability(name string) (version string, ok bool, err error)
+}
+
+// StaticHostCapabilities is a HostCapabilities with fixed versions, keyed by capability name.
+type StaticHostCapabilities map[string]string
+
+// Capability returns the version recorded for name.
+func (s StaticHostCapabilities) Capability(name string) (string, bool, error) {
+	v, ok := s[name]
+	return v, ok, nil
+}
+
+// SetHostCapabilities enables a check that the host meets the requirements declared in the metadata of an
+// artifact before it is installed. Every requirement needs a capability of at least the required version.
+// Use SystemHostCapabilities for the capabilities of the running host. Passing nil disables the check.
+func (m *ManagerImpl) SetHostCapabilities(capabilities HostCapabilities) {
+	m.hostCapabilities = capabilities
+}
+
+// checkRequirements fails with ErrRequirementNotMet if the host lacks a capability required by the
+// extracted artifact or has an older version of it.
+func (m *ManagerImpl) checkRequirements(extractDir string) error {
+	metadata, err := m.parseMetadata(filepath.Join(extractDir, artifactMetaDir, m.metadataFileName()))
+	if err != nil {
+		return errutils.Wrap(err, "failed to parse metadata for requirement check")
+	}
+
+	names := make([]string, 0, len(metadata.Requirements))
+	for name := range metadata.Requirements {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var problems []string
+	for _, name := range names {
+		required := metadata.Requirements[name]
+		available, ok, err := m.hostCapabilities.Capability(name)
+		if err != nil {
+			return errutils.Wrapf(err, "failed to determine host capability %s", name)
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s >= %s is required but not available", name, required))
+			continue
+		}
+		cmp, err := m.comparator().Compare(available, required)
+		if err != nil {
+			return errutils.Wrapf(err, "failed to compare requirement %s", name)
+		}
+		if cmp < 0 {
+			problems = append(problems, fmt.Sprintf("%s >= %s is required but %s is available", name, required, available))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errutils.Wrapf(ErrRequirementNotMet, "%s@%s: %s", metadata.Name, metadata.Version, strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+//go:build linux
+
+package artifact
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// systemHostCapabilities detects the glibc and kernel versions of a Linux host.
+type systemHostCapabilities struct{}
+
+// SystemHostCapabilities returns HostCapabilities detected from the running host.
+// On Linux it reports the "glibc" and "kernel" versions.
+func SystemHostCapabilities() HostCapabilities {
+	return systemHostCapabilities{}
+}
+
+// Capability implements HostCapabilities.
+func (systemHostCapabilities) Capability(name string) (string, bool, error) {
+	switch name {
+	case "glibc":
+		// getconf only knows GNU_LIBC_VERSION on glibc hosts, so a failure means there is no glibc
+		out, err := exec.Command("getconf", "GNU_LIBC_VERSION").Output()
+		if err != nil {
+			return "", false, nil
+		}
+		version, ok := strings.CutPrefix(strings.TrimSpace(string(out)), "glibc ")
+		return version, ok, nil
+	case "kernel":
+		release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+		if err != nil {
+			return "", false, err
+		}
+		// Distribution suffixes such as "-generic" are not part of the version
+		version, _, _ := strings.Cut(strings.TrimSpace(string(release)), "-")
+		version, _, _ = strings.Cut(version, "+")
+		return version, true, nil
+	}
+	return "", false, nil
+}
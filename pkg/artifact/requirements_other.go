@@ -0,0 +1,17 @@
+//go:build !linux
+
+package artifact
+
+// systemHostCapabilities reports no capabilities on platforms without detection.
+type systemHostCapabilities struct{}
+
+// SystemHostCapabilities returns HostCapabilities detected from the running host.
+// On Linux it reports the "glibc" and "kernel" versions.
+func SystemHostCapabilities() HostCapabilities {
+	return systemHostCapabilities{}
+}
+
+// Capability implements HostCapabilities.
+func (systemHostCapabilities) Capability(string) (string, bool, error) {
+	return "", false, nil
+}
@@ -0,0 +1,97 @@
+package artifact
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallArtifact_RequirementCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		requirements map[string]string
+		expectedErr  error
+	}{
+		{name: "satisfied", requirements: map[string]string{"glibc": "2.31", "python": "3.8"}},
+		{name: "version too old", requirements: map[string]string{"glibc": "2.38"}, expectedErr: ErrRequirementNotMet},
+		{name: "capability missing", requirements: map[string]string{"cuda": "12.0"}, expectedErr: ErrRequirementNotMet},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+			mgr.SetHostCapabilities(StaticHostCapabilities{"glibc": "2.35", "python": "3.12.1"})
+
+			artifactPath := filepath.Join(tempDir, "needs-host.gotya")
+			setupTestArtifact(t, artifactPath, true, &Metadata{
+				Name: "needs-host", Version: "1.0.0", OS: "linux", Arch: "amd64", Description: "requirement check",
+				Requirements: tt.requirements,
+			})
+			desc := &model.IndexArtifactDescriptor{Name: "needs-host", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/needs-host.gotya"}
+
+			err := mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				for name := range tt.requirements {
+					assert.Contains(t, err.Error(), name)
+				}
+				assert.NoDirExists(t, filepath.Join(tempDir, "install", artifactDataDir, "needs-host"))
+				return
+			}
+			require.NoError(t, err)
+			assert.DirExists(t, filepath.Join(tempDir, "install", artifactDataDir, "needs-host"))
+		})
+	}
+}
+
+func TestPacker_SetRequirements(t *testing.T) {
+	packer := NewPacker("tool", "1.0.0", "linux", "amd64", "", "tool", nil, nil, t.TempDir(), t.TempDir())
+	require.NoError(t, packer.SetRequirements(map[string]string{"glibc": "2.31"}))
+	assert.Equal(t, map[string]string{"glibc": "2.31"}, packer.requirements)
+
+	require.ErrorIs(t, packer.SetRequirements(map[string]string{"glibc": "not-a-version"}), errutils.ErrValidation)
+	require.ErrorIs(t, packer.SetRequirements(map[string]string{"": "1.0"}), errutils.ErrValidation)
+}
+
+func TestInstallArtifact_RequirementCheckCustomComparator(t *testing.T) {
+	tempDir := t.TempDir()
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+	mgr.SetHostCapabilities(StaticHostCapabilities{"glibc": "2.35"})
+	mgr.SetVersionComparator(reverseSemverComparator{})
+
+	artifactPath := filepath.Join(tempDir, "needs-host.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{
+		Name: "needs-host", Version: "1.0.0", OS: "linux", Arch: "amd64", Description: "requirement check",
+		Requirements: map[string]string{"glibc": "2.31"},
+	})
+	desc := &model.IndexArtifactDescriptor{Name: "needs-host", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/needs-host.gotya"}
+
+	// In reverse order the available 2.35 is older than the required 2.31
+	err := mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual)
+	require.ErrorIs(t, err, ErrRequirementNotMet)
+	assert.Contains(t, err.Error(), "2.35 is available")
+}
+
+func TestSystemHostCapabilities(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("host capabilities are only detected on Linux")
+	}
+	capabilities := SystemHostCapabilities()
+
+	kernel, ok, err := capabilities.Capability("kernel")
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, err = model.SemverComparator{}.Compare(kernel, kernel)
+	require.NoError(t, err, "the kernel version %q must be comparable", kernel)
+
+	_, ok, err = capabilities.Capability("no-such-runtime")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
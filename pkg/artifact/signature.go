@@ -0,0 +1,196 @@
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/fsutil"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// SignatureFile is the name of the signature embedded next to the metadata file in the meta directory
+// of signed artifacts. It holds the base64 encoded ed25519 signature of the SHA-256 digest of the metadata
+// file. The metadata records the hash of every other file in the data and meta directories, and verification
+// rejects changed, unlisted and missing files, so the signature covers the whole artifact.
+const SignatureFile = "artifact.sig"
+
+// LoadSigningKey reads an ed25519 private key from a PEM encoded PKCS #8 file,
+// as created by "openssl genpkey -algorithm ed25519".
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEMFile(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errutils.Wrapf(errutils.ErrValidation, "failed to parse private key %s: %v", path, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errutils.Wrapf(errutils.ErrValidation, "private key %s is not an ed25519 key", path)
+	}
+	return privateKey, nil
+}
+
+// LoadPublicKey reads an ed25519 public key from a PEM encoded PKIX file,
+// as created by "openssl pkey -pubout".
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEMFile(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errutils.Wrapf(errutils.ErrValidation, "failed to parse public key %s: %v", path, err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errutils.Wrapf(errutils.ErrValidation, "public key %s is not an ed25519 key", path)
+	}
+	return publicKey, nil
+}
+
+// readPEMFile reads the first PEM block of the file at path and checks its type.
+func readPEMFile(path, blockType string) (*pem.Block, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errutils.Wrapf(err, "failed to read key file %s", path)
+	}
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != blockType {
+		return nil, errutils.Wrapf(errutils.ErrValidation, "%s does not contain a PEM encoded %s", path, blockType)
+	}
+	return block, nil
+}
+
+// SetSigningKeyFile makes Pack sign created artifacts with the ed25519 private key in the PEM file at path,
+// see LoadSigningKey. An empty path disables signing, which is the default.
+func (p *Packer) SetSigningKeyFile(path string) error {
+	if path == "" {
+		p.signingKey = nil
+		return nil
+	}
+	key, err := LoadSigningKey(path)
+	if err != nil {
+		return err
+	}
+	p.signingKey = key
+	return nil
+}
+
+// signMetadata writes the signature of the metadata file to the meta directory of the temporary directory.
+func (p *Packer) signMetadata() error {
+	metaDir := filepath.Join(p.tempDir, artifactMetaDir)
+	content, err := os.ReadFile(filepath.Join(metaDir, p.metadataFileName()))
+	if err != nil {
+		return errutils.Wrap(err, "failed to read metadata for signing")
+	}
+	digest := sha256.Sum256(content)
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(p.signingKey, digest[:])) + "\n"
+
+	file, err := fsutil.CreateFilePerm(filepath.Join(metaDir, SignatureFile), fsutil.FileModeDefault)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	_, err = file.WriteString(signature)
+	return err
+}
+
+// VerifySignature checks that the artifact file at archivePath was signed with the private key of pubKey
+// and that its files match the signed metadata. It fails with ErrSignatureMissing for unsigned artifacts
+// and with ErrSignatureInvalid if the signature does not match.
+func VerifySignature(archivePath string, pubKey ed25519.PublicKey) error {
+	return NewVerifier().VerifySignature(context.Background(), archivePath, pubKey)
+}
+
+// VerifySignature checks the signature and the contents of the artifact file at archivePath like the
+// package function VerifySignature, using the metadata file name of the verifier.
+func (v *Verifier) VerifySignature(ctx context.Context, archivePath string, pubKey ed25519.PublicKey) error {
+	if _, err := os.Stat(archivePath); err != nil {
+		return errutils.ErrArtifactNotFound
+	}
+	tempDir, err := os.MkdirTemp("", "gotya-verify-*")
+	if err != nil {
+		return errutils.Wrap(err, "failed to create temp directory")
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	if err := v.extractArchive(ctx, archivePath, tempDir); err != nil {
+		return errutils.Wrap(err, "failed to extract archive")
+	}
+	if err := verifySignatureFromPath(tempDir, orDefaultMetadataFile(v.metadataFile), pubKey); err != nil {
+		return err
+	}
+	return v.VerifyArtifactFromPath(ctx, nil, tempDir)
+}
+
+// verifySignatureFromPath checks the embedded signature of the metadata file of the artifact extracted to dirPath.
+// The signature is valid if it was made with the private key of any of pubKeys.
+func verifySignatureFromPath(dirPath, metadataFile string, pubKeys ...ed25519.PublicKey) error {
+	for _, pubKey := range pubKeys {
+		if len(pubKey) != ed25519.PublicKeySize {
+			return errutils.Wrap(errutils.ErrValidation, "invalid ed25519 public key")
+		}
+	}
+	metaDir := filepath.Join(dirPath, artifactMetaDir)
+	encoded, err := os.ReadFile(filepath.Join(metaDir, SignatureFile))
+	if os.IsNotExist(err) {
+		return ErrSignatureMissing
+	}
+	if err != nil {
+		return errutils.Wrap(err, "failed to read signature")
+	}
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+	if err != nil {
+		return errutils.Wrap(ErrSignatureInvalid, "signature is not base64 encoded")
+	}
+	content, err := os.ReadFile(filepath.Join(metaDir, metadataFile))
+	if err != nil {
+		return errutils.Wrap(err, "failed to read metadata file")
+	}
+	digest := sha256.Sum256(content)
+	for _, pubKey := range pubKeys {
+		if ed25519.Verify(pubKey, digest[:], signature) {
+			return nil
+		}
+	}
+	return ErrSignatureInvalid
+}
+
+// SetTrustedKeys makes the manager require artifacts to be signed with the private key of one of keys before
+// installing, updating, staging or repairing them. Unsigned artifacts and artifacts with an invalid signature
+// are rejected with an errutils.ErrValidation error that also wraps ErrSignatureMissing or ErrSignatureInvalid.
+// Without trusted keys, which is the default, signatures are not checked.
+func (m *ManagerImpl) SetTrustedKeys(keys ...ed25519.PublicKey) error {
+	for _, key := range keys {
+		if len(key) != ed25519.PublicKeySize {
+			return errutils.Wrap(errutils.ErrValidation, "invalid ed25519 public key")
+		}
+	}
+	m.trustedKeys = slices.Clone(keys)
+	return nil
+}
+
+// verifyTrustedSignature checks the signature of the artifact extracted to extractDir against the trusted keys
+// and verifies its contents against desc and the signed metadata. The signature only covers the metadata file,
+// so a signed artifact is only accepted once all its files were checked against the hashes in the metadata.
+func (m *ManagerImpl) verifyTrustedSignature(ctx context.Context, desc *model.IndexArtifactDescriptor, extractDir string) error {
+	if err := verifySignatureFromPath(extractDir, orDefaultMetadataFile(m.metadataFile), m.trustedKeys...); err != nil {
+		return fmt.Errorf("%w: signature of %s: %w", errutils.ErrValidation, desc.GetID(), err)
+	}
+	if err := m.verifier.VerifyArtifactFromPath(ctx, desc, extractDir); err != nil {
+		return fmt.Errorf("%w: contents of signed artifact %s: %w", errutils.ErrValidation, desc.GetID(), err)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package artifact
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/archive"
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestKeys generates an ed25519 key pair and writes it as PEM files to dir.
+func writeTestKeys(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	privatePath := filepath.Join(dir, name+".key")
+	publicPath := filepath.Join(dir, name+".pub")
+	require.NoError(t, os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600))
+	require.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o644))
+	return privatePath, publicPath
+}
+
+func TestPacker_SignedArtifact(t *testing.T) {
+	tempDir := t.TempDir()
+	privatePath, publicPath := writeTestKeys(t, tempDir, "signer")
+	_, otherPublicPath := writeTestKeys(t, tempDir, "other")
+	publicKey, err := LoadPublicKey(publicPath)
+	require.NoError(t, err)
+
+	inputDir := filepath.Join(tempDir, "input")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool.txt"), []byte("tool"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "sub", "nested.txt"), []byte("nested"), 0o644))
+
+	packer := NewPacker("tool", "1.0.0", "linux", "amd64", "", "signed artifact", nil, nil, inputDir, tempDir)
+	require.NoError(t, packer.SetSigningKeyFile(privatePath))
+	artifactPath, err := packer.Pack()
+	require.NoError(t, err)
+
+	require.NoError(t, VerifySignature(artifactPath, publicKey))
+
+	otherKey, err := LoadPublicKey(otherPublicPath)
+	require.NoError(t, err)
+	require.ErrorIs(t, VerifySignature(artifactPath, otherKey), ErrSignatureInvalid)
+
+	// Repacks the signed artifact after modifying its extracted files
+	repack := func(t *testing.T, modify func(dir string)) string {
+		t.Helper()
+		dir := filepath.Join(t.TempDir(), "extracted")
+		require.NoError(t, archive.NewManager().ExtractAll(context.Background(), artifactPath, dir))
+		modify(dir)
+		tampered := filepath.Join(t.TempDir(), "tampered.gotya")
+		require.NoError(t, archive.NewManager().Create(context.Background(), dir, tampered))
+		return tampered
+	}
+	// Repacks the signed artifact after writing content to one of its files
+	tamper := func(t *testing.T, relPath, content string) string {
+		t.Helper()
+		return repack(t, func(dir string) {
+			require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, filepath.FromSlash(relPath))), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, filepath.FromSlash(relPath)), []byte(content), 0o644))
+		})
+	}
+
+	t.Run("tampered data file", func(t *testing.T) {
+		tampered := tamper(t, "data/tool.txt", "evil")
+		require.ErrorContains(t, VerifySignature(tampered, publicKey), "Hashsum mismatch")
+	})
+
+	t.Run("tampered nested data file", func(t *testing.T) {
+		tampered := tamper(t, "data/sub/nested.txt", "evil")
+		err := VerifySignature(tampered, publicKey)
+		require.ErrorIs(t, err, errutils.ErrArtifactInvalid)
+		assert.Contains(t, err.Error(), "Hashsum mismatch for data/sub/nested.txt")
+	})
+
+	t.Run("file without hash", func(t *testing.T) {
+		for _, relPath := range []string{"data/sub/extra.txt", "meta/extra.tengo"} {
+			err := VerifySignature(tamper(t, relPath, "evil"), publicKey)
+			require.ErrorIs(t, err, errutils.ErrArtifactInvalid)
+			assert.Contains(t, err.Error(), "hash for file "+relPath+" not found")
+		}
+	})
+
+	t.Run("missing file with hash", func(t *testing.T) {
+		tampered := repack(t, func(dir string) {
+			require.NoError(t, os.Remove(filepath.Join(dir, artifactDataDir, "sub", "nested.txt")))
+		})
+		err := VerifySignature(tampered, publicKey)
+		require.ErrorIs(t, err, errutils.ErrArtifactInvalid)
+		assert.Contains(t, err.Error(), "missing: data/sub/nested.txt")
+	})
+
+	t.Run("tampered metadata", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "extracted")
+		require.NoError(t, archive.NewManager().ExtractAll(context.Background(), artifactPath, dir))
+		metadata, err := os.ReadFile(filepath.Join(dir, artifactMetaDir, DefaultMetadataFile))
+		require.NoError(t, err)
+		tampered := tamper(t, "meta/"+DefaultMetadataFile, strings.Replace(string(metadata), "signed artifact", "trusted artifact", 1))
+		require.ErrorIs(t, VerifySignature(tampered, publicKey), ErrSignatureInvalid)
+	})
+
+	t.Run("unsigned artifact", func(t *testing.T) {
+		unsigned, err := NewPacker("tool", "1.0.0", "linux", "amd64", "", "signed artifact", nil, nil, inputDir, t.TempDir()).Pack()
+		require.NoError(t, err)
+		require.ErrorIs(t, VerifySignature(unsigned, publicKey), ErrSignatureMissing)
+	})
+
+	t.Run("installed signature is recorded", func(t *testing.T) {
+		dir := t.TempDir()
+		mgr := NewManager("linux", "amd64", dir, filepath.Join(dir, "install", artifactDataDir), filepath.Join(dir, "install", artifactMetaDir), filepath.Join(dir, "installed.db"))
+		desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+		assert.FileExists(t, filepath.Join(mgr.getArtifactMetaInstallPath(desc), SignatureFile))
+
+		results, err := mgr.VerifyInstalled(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	require.Error(t, packer.SetSigningKeyFile(publicPath), "a public key cannot sign")
+}
+
+func TestManager_TrustedKeys(t *testing.T) {
+	tempDir := t.TempDir()
+	privatePath, publicPath := writeTestKeys(t, tempDir, "signer")
+	otherPrivatePath, otherPublicPath := writeTestKeys(t, tempDir, "other")
+	publicKey, err := LoadPublicKey(publicPath)
+	require.NoError(t, err)
+	otherKey, err := LoadPublicKey(otherPublicPath)
+	require.NoError(t, err)
+
+	inputDir := filepath.Join(tempDir, "input")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool.txt"), []byte("tool"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "sub", "nested.txt"), []byte("nested"), 0o644))
+	pack := func(t *testing.T, keyPath string) string {
+		t.Helper()
+		packer := NewPacker("tool", "1.0.0", "linux", "amd64", "", "signed artifact", nil, nil, inputDir, t.TempDir())
+		require.NoError(t, packer.SetSigningKeyFile(keyPath))
+		artifactPath, err := packer.Pack()
+		require.NoError(t, err)
+		return artifactPath
+	}
+	install := func(t *testing.T, artifactPath string, keys ...ed25519.PublicKey) (*ManagerImpl, error) {
+		t.Helper()
+		dir := t.TempDir()
+		mgr := NewManager("linux", "amd64", dir, filepath.Join(dir, "install", artifactDataDir), filepath.Join(dir, "install", artifactMetaDir), filepath.Join(dir, "installed.db"))
+		require.NoError(t, mgr.SetTrustedKeys(keys...))
+		desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+		return mgr, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual)
+	}
+	assertNotInstalled := func(t *testing.T, mgr *ManagerImpl) {
+		t.Helper()
+		names, err := mgr.InstalledNames()
+		require.NoError(t, err)
+		assert.Empty(t, names)
+	}
+
+	signed := pack(t, privatePath)
+	unsigned := pack(t, "")
+
+	// Repacks the signed artifact with a changed nested data file, keeping the valid signature
+	tampered := func(t *testing.T) string {
+		t.Helper()
+		dir := filepath.Join(t.TempDir(), "extracted")
+		require.NoError(t, archive.NewManager().ExtractAll(context.Background(), signed, dir))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, artifactDataDir, "sub", "nested.txt"), []byte("EVIL"), 0o644))
+		artifactPath := filepath.Join(t.TempDir(), "tampered.gotya")
+		require.NoError(t, archive.NewManager().Create(context.Background(), dir, artifactPath))
+		return artifactPath
+	}
+
+	t.Run("valid signature", func(t *testing.T) {
+		mgr, err := install(t, signed, otherKey, publicKey)
+		require.NoError(t, err)
+		names, err := mgr.InstalledNames()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tool"}, names)
+	})
+
+	t.Run("signed by an untrusted key", func(t *testing.T) {
+		mgr, err := install(t, pack(t, otherPrivatePath), publicKey)
+		require.ErrorIs(t, err, errutils.ErrValidation)
+		require.ErrorIs(t, err, ErrSignatureInvalid)
+		assert.Contains(t, err.Error(), "signature of tool@1.0.0")
+		assertNotInstalled(t, mgr)
+	})
+
+	t.Run("valid signature with a tampered nested file", func(t *testing.T) {
+		mgr, err := install(t, tampered(t), publicKey)
+		require.ErrorIs(t, err, errutils.ErrValidation)
+		require.ErrorIs(t, err, errutils.ErrArtifactInvalid)
+		assert.Contains(t, err.Error(), "contents of signed artifact tool@1.0.0")
+		assertNotInstalled(t, mgr)
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		mgr, err := install(t, unsigned, publicKey)
+		require.ErrorIs(t, err, errutils.ErrValidation)
+		require.ErrorIs(t, err, ErrSignatureMissing)
+		assertNotInstalled(t, mgr)
+	})
+
+	t.Run("no trusted keys", func(t *testing.T) {
+		_, err := install(t, unsigned)
+		require.NoError(t, err)
+	})
+
+	mgr := NewManager("linux", "amd64", tempDir, tempDir, tempDir, filepath.Join(tempDir, "installed.db"))
+	require.ErrorIs(t, mgr.SetTrustedKeys(ed25519.PublicKey("short")), errutils.ErrValidation)
+}
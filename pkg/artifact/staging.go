@@ -0,0 +1,241 @@
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+const (
+	// stagingDirName is the directory below the install directories holding the staged versions of artifacts.
+	stagingDirName = ".staged"
+	// activeSlotLink and previousSlotLink point to the active and the previously active version of an artifact.
+	activeSlotLink   = "current"
+	previousSlotLink = "previous"
+	// slotDescriptorSuffix is appended to the version to name the file holding the descriptor of a staged version.
+	slotDescriptorSuffix = ".descriptor.json"
+)
+
+// StageVersion installs an artifact into an inactive staging slot without affecting the installed version.
+// Each version gets its own slot at <install dir>/.staged/<name>/<version> for both meta and data files;
+// staging a version again replaces its slot unless it is the active one. The staged version is verified
+// like an install but only takes effect once it is activated with Activate.
+func (m *ManagerImpl) StageVersion(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string) (err error) {
+	if desc == nil {
+		return errutils.Wrap(errutils.ErrValidation, "artifact descriptor cannot be nil")
+	}
+	if err := desc.VerifyWithLimits(m.nameLimits); err != nil {
+		return errutils.Wrap(err, "invalid artifact descriptor")
+	}
+	if err := checkSlotName(desc.Name, desc.Version); err != nil {
+		return err
+	}
+	if localPath == "" {
+		return errutils.Wrap(errutils.ErrValidation, "local path cannot be empty")
+	}
+
+	unlock := m.artifactLocks.Lock(desc.Name)
+	defer unlock()
+
+	if active, _ := m.slotPointer(desc.Name, activeSlotLink); active == desc.Version {
+		return errutils.Wrapf(errutils.ErrValidation, "%s is the active version of %s and cannot be staged again", desc.Version, desc.Name)
+	}
+
+	extractDir, err := os.MkdirTemp("", fmt.Sprintf("gotya-stage-%s-%s", desc.Name, desc.Version))
+	if err != nil {
+		return errutils.Wrap(err, "failed to create extract directory")
+	}
+	defer func() { err = m.removeExtractDir(extractDir, err) }()
+
+	if err := m.extractAndVerify(ctx, desc, localPath, extractDir); err != nil {
+		return err
+	}
+
+	metaSlot, dataSlot := m.slotDirs(desc.Name, desc.Version)
+	m.removeSlot(desc.Name, desc.Version)
+	if err := installArtifactFilesTo(extractDir, metaSlot, dataSlot); err != nil {
+		m.removeSlot(desc.Name, desc.Version)
+		return errutils.Wrapf(err, "failed to stage %s", desc.GetID())
+	}
+
+	data, err := json.Marshal(desc)
+	if err == nil {
+		err = os.WriteFile(m.slotDescriptorPath(desc.Name, desc.Version), data, 0o644)
+	}
+	if err != nil {
+		m.removeSlot(desc.Name, desc.Version)
+		return errutils.Wrapf(err, "failed to record descriptor of staged %s", desc.GetID())
+	}
+	return nil
+}
+
+// Activate makes a staged version the installed version of an artifact. The installed database is
+// updated to the files of the slot and the active pointer is swapped atomically; the previously active
+// version stays staged and can be restored with ActivatePrevious. Installation reason, reverse dependencies
+// and essential flag carry over from the previously active version. Hooks are not run.
+// An artifact installed outside the staging area must be uninstalled before a staged version can be activated.
+func (m *ManagerImpl) Activate(ctx context.Context, name, version string) error {
+	if err := checkSlotName(name, version); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	unlock := m.artifactLocks.Lock(name)
+	defer unlock()
+	unlockDB, err := m.lockDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlockDB()
+
+	if err := m.loadInstalledDB(); err != nil {
+		return err
+	}
+	desc, err := m.readSlotDescriptor(name, version)
+	if err != nil {
+		return err
+	}
+
+	reason := model.InstallationReasonManual
+	var reverseDeps []string
+	var essential bool
+	var detail string
+	previous := m.installDB.FindArtifact(name)
+	if previous != nil {
+		if previous.Status == model.StatusInstalled {
+			if !m.isSlotDir(name, previous.ArtifactMetaDir) {
+				return errutils.Wrapf(errutils.ErrValidation, "%s is installed outside the staging area", name)
+			}
+			if previous.Version == version {
+				return nil
+			}
+			reason, essential, detail = previous.InstallationReason, previous.Essential, previous.InstallationDetail
+		}
+		reverseDeps = previous.ReverseDependencies
+		m.installDB.RemoveArtifact(name)
+	}
+
+	metaSlot, dataSlot := m.slotDirs(name, version)
+	if err := m.recordInstalledArtifact(desc, metaSlot, dataSlot, reverseDeps, reason, essential, detail); err != nil {
+		m.restoreActivation(name, previous)
+		return errutils.Wrapf(err, "failed to activate %s", desc.GetID())
+	}
+
+	active, _ := m.slotPointer(name, activeSlotLink)
+	if err := m.setSlotPointer(name, activeSlotLink, version); err != nil {
+		m.restoreActivation(name, previous)
+		return errutils.Wrapf(err, "failed to activate %s", desc.GetID())
+	}
+	if active != "" && active != version {
+		if err := m.setSlotPointer(name, previousSlotLink, active); err != nil {
+			return errutils.Wrapf(err, "failed to record previous version of %s", name)
+		}
+	}
+	return nil
+}
+
+// ActivatePrevious activates the version that was active before the current one, e.g. to roll back
+// a rollout. Calling it again switches back.
+func (m *ManagerImpl) ActivatePrevious(ctx context.Context, name string) error {
+	version, err := m.slotPointer(name, previousSlotLink)
+	if os.IsNotExist(err) {
+		return errutils.Wrapf(errutils.ErrArtifactNotFound, "no previously active version of %s", name)
+	}
+	if err != nil {
+		return errutils.Wrapf(err, "failed to read previous version of %s", name)
+	}
+	return m.Activate(ctx, name, version)
+}
+
+// checkSlotName ensures name and version can be used as single path elements of a staging slot.
+func checkSlotName(name, version string) error {
+	for _, element := range []string{name, version} {
+		if element == "" || filepath.Base(element) != element || !filepath.IsLocal(element) {
+			return errutils.Wrapf(errutils.ErrInvalidPath, "%q cannot be used as staging slot of %s", element, name)
+		}
+	}
+	return nil
+}
+
+// slotDirs returns the meta and data directories of the staging slot of a version.
+func (m *ManagerImpl) slotDirs(name, version string) (string, string) {
+	return filepath.Join(m.artifactMetaInstallDir, stagingDirName, name, version),
+		filepath.Join(m.artifactDataInstallDir, stagingDirName, name, version)
+}
+
+// slotDescriptorPath returns the file recording the descriptor of a staged version.
+func (m *ManagerImpl) slotDescriptorPath(name, version string) string {
+	return filepath.Join(m.artifactMetaInstallDir, stagingDirName, name, version+slotDescriptorSuffix)
+}
+
+// isSlotDir reports whether dir is a staging slot of the artifact.
+func (m *ManagerImpl) isSlotDir(name, dir string) bool {
+	return filepath.Dir(dir) == filepath.Join(m.artifactMetaInstallDir, stagingDirName, name)
+}
+
+// removeSlot removes the files of a staged version.
+func (m *ManagerImpl) removeSlot(name, version string) {
+	metaSlot, dataSlot := m.slotDirs(name, version)
+	_ = os.RemoveAll(metaSlot)
+	_ = os.RemoveAll(dataSlot)
+	_ = os.Remove(m.slotDescriptorPath(name, version))
+}
+
+// readSlotDescriptor reads the descriptor recorded when a version was staged.
+func (m *ManagerImpl) readSlotDescriptor(name, version string) (*model.IndexArtifactDescriptor, error) {
+	data, err := os.ReadFile(m.slotDescriptorPath(name, version))
+	if os.IsNotExist(err) {
+		return nil, errutils.Wrapf(errutils.ErrArtifactNotFound, "%s@%s is not staged", name, version)
+	}
+	if err != nil {
+		return nil, errutils.Wrapf(err, "failed to read descriptor of staged %s@%s", name, version)
+	}
+	var desc model.IndexArtifactDescriptor
+	if err := json.Unmarshal(data, &desc); err != nil {
+		return nil, errutils.Wrapf(errutils.ErrValidation, "invalid descriptor of staged %s@%s: %v", name, version, err)
+	}
+	if desc.Name != name || desc.Version != version {
+		return nil, errutils.Wrapf(errutils.ErrValidation, "descriptor of staged %s@%s describes %s", name, version, desc.GetID())
+	}
+	return &desc, nil
+}
+
+// restoreActivation puts the database entry replaced by a failed activation back.
+func (m *ManagerImpl) restoreActivation(name string, previous *model.InstalledArtifact) {
+	m.installDB.RemoveArtifact(name)
+	if previous != nil {
+		m.restoreDBArtifact(previous)
+	}
+	_ = m.installDB.SaveDatabase()
+}
+
+// slotPointer returns the version a pointer of the artifact refers to.
+func (m *ManagerImpl) slotPointer(name, pointer string) (string, error) {
+	return os.Readlink(filepath.Join(m.artifactDataInstallDir, stagingDirName, name, pointer))
+}
+
+// setSlotPointer atomically points a pointer of the artifact to version. The pointers are relative
+// symlinks next to the data slots, so <data dir>/.staged/<name>/current always resolves to the active data.
+func (m *ManagerImpl) setSlotPointer(name, pointer, version string) error {
+	dir := filepath.Join(m.artifactDataInstallDir, stagingDirName, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, pointer+".tmp")
+	_ = os.Remove(tmp)
+	if err := os.Symlink(version, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, pointer)); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
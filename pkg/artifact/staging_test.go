@@ -0,0 +1,93 @@
+package artifact
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaging_ActivateAndRollback(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	metaDir := filepath.Join(tempDir, "install", artifactMetaDir)
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, metaDir, filepath.Join(tempDir, "installed.db"))
+
+	stage := func(version string) {
+		artifactPath := filepath.Join(tempDir, "tool-"+version+".gotya")
+		setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: version, OS: "linux", Arch: "amd64"})
+		desc := &model.IndexArtifactDescriptor{Name: "tool", Version: version, OS: "linux", Arch: "amd64", URL: "http://example.com/tool-" + version + ".gotya"}
+		require.NoError(t, mgr.StageVersion(context.Background(), desc, artifactPath))
+	}
+	assertActive := func(version string) {
+		t.Helper()
+		installed, err := mgr.GetInstalledArtifacts()
+		require.NoError(t, err)
+		require.Len(t, installed, 1)
+		assert.Equal(t, version, installed[0].Version)
+		assert.Equal(t, filepath.Join(dataDir, stagingDirName, "tool", version), installed[0].ArtifactDataDir)
+		assert.FileExists(t, filepath.Join(dataDir, stagingDirName, "tool", activeSlotLink, "datafile1.bin"))
+		target, err := os.Readlink(filepath.Join(dataDir, stagingDirName, "tool", activeSlotLink))
+		require.NoError(t, err)
+		assert.Equal(t, version, target)
+	}
+
+	stage("1.0.0")
+	installed, err := mgr.GetInstalledArtifacts()
+	require.NoError(t, err)
+	assert.Empty(t, installed, "staging must not install")
+
+	require.NoError(t, mgr.Activate(context.Background(), "tool", "1.0.0"))
+	assertActive("1.0.0")
+	require.NoError(t, mgr.SetArtifactInstallationDetail("tool", "rolled out"))
+
+	// Staging the next version leaves the active one untouched
+	stage("2.0.0")
+	assertActive("1.0.0")
+	assert.FileExists(t, filepath.Join(dataDir, stagingDirName, "tool", "2.0.0", "datafile1.bin"))
+
+	require.NoError(t, mgr.Activate(context.Background(), "tool", "2.0.0"))
+	assertActive("2.0.0")
+	installed, err = mgr.GetInstalledArtifacts()
+	require.NoError(t, err)
+	assert.Equal(t, "rolled out", installed[0].InstallationDetail)
+
+	require.NoError(t, mgr.ActivatePrevious(context.Background(), "tool"))
+	assertActive("1.0.0")
+	require.NoError(t, mgr.ActivatePrevious(context.Background(), "tool"))
+	assertActive("2.0.0")
+}
+
+func TestStaging_Errors(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "installed.db"))
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+	artifactPath := filepath.Join(tempDir, "tool.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64"})
+
+	assert.ErrorIs(t, mgr.Activate(context.Background(), "tool", "1.0.0"), errutils.ErrArtifactNotFound)
+	assert.ErrorIs(t, mgr.ActivatePrevious(context.Background(), "tool"), errutils.ErrArtifactNotFound)
+	assert.ErrorIs(t, mgr.Activate(context.Background(), "tool", "../1.0.0"), errutils.ErrInvalidPath)
+
+	require.NoError(t, mgr.StageVersion(context.Background(), desc, artifactPath))
+	require.NoError(t, mgr.Activate(context.Background(), "tool", "1.0.0"))
+	assert.ErrorIs(t, mgr.StageVersion(context.Background(), desc, artifactPath), errutils.ErrValidation)
+
+	// Artifacts installed the regular way are not taken over by staged versions
+	other := NewManager("linux", "amd64", tempDir, dataDir, filepath.Join(tempDir, "install", artifactMetaDir), filepath.Join(tempDir, "other.db"))
+	otherDesc := &model.IndexArtifactDescriptor{Name: "other", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/other.gotya"}
+	otherPath := filepath.Join(tempDir, "other.gotya")
+	setupTestArtifact(t, otherPath, true, &Metadata{Name: "other", Version: "1.0.0", OS: "linux", Arch: "amd64"})
+	require.NoError(t, other.InstallArtifact(context.Background(), otherDesc, otherPath, model.InstallationReasonManual))
+	otherDesc.Version = "2.0.0"
+	otherPath = filepath.Join(tempDir, "other-2.gotya")
+	setupTestArtifact(t, otherPath, true, &Metadata{Name: "other", Version: "2.0.0", OS: "linux", Arch: "amd64"})
+	require.NoError(t, other.StageVersion(context.Background(), otherDesc, otherPath))
+	assert.ErrorIs(t, other.Activate(context.Background(), "other", "2.0.0"), errutils.ErrValidation)
+}
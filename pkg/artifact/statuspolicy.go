@@ -0,0 +1,52 @@
+package artifact
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/glorpus-work/gotya/internal/logger"
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// UnknownStatusPolicy controls how installations treat installed artifacts whose status is not known
+// to this version, e.g. after a newer version introduced a status.
+type UnknownStatusPolicy string
+
+const (
+	// UnknownStatusPolicyStrict fails the installation.
+	UnknownStatusPolicyStrict UnknownStatusPolicy = "strict"
+	// UnknownStatusPolicyReinstall logs a warning and reinstalls the artifact, keeping its installation
+	// reason, essential flag and reverse dependencies.
+	UnknownStatusPolicyReinstall UnknownStatusPolicy = "reinstall"
+)
+
+// SetUnknownStatusPolicy sets how installations react to an installed artifact with an unknown status.
+// The default is UnknownStatusPolicyStrict; unknown policies are treated as strict.
+func (m *ManagerImpl) SetUnknownStatusPolicy(policy UnknownStatusPolicy) {
+	m.unknownStatusPolicy = policy
+}
+
+// handleUnknownStatus applies the unknown status policy to existing. It returns an error unless the
+// policy allows a reinstall, in which case the entry and its recorded directories are removed.
+func (m *ManagerImpl) handleUnknownStatus(existing *model.InstalledArtifact) error {
+	if m.unknownStatusPolicy != UnknownStatusPolicyReinstall {
+		return fmt.Errorf("artifact %s has unknown status: %s: %w", existing.Name, existing.Status, errutils.ErrValidation)
+	}
+
+	logger.Warn("Installed artifact has an unknown status, reinstalling it", logger.Fields{
+		"artifact": existing.Name,
+		"status":   string(existing.Status),
+	})
+	// The files would otherwise be moved into the existing directories instead of replacing them
+	for _, dir := range []string{existing.ArtifactMetaDir, existing.ArtifactDataDir} {
+		if dir == "" {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return errutils.Wrapf(err, "failed to remove %s before reinstalling %s", dir, existing.Name)
+		}
+	}
+	m.installDB.RemoveArtifact(existing.Name)
+	return nil
+}
@@ -0,0 +1,62 @@
+package artifact
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallArtifact_UnknownStatusPolicy(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "tool.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64"})
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+
+	// newManager installs tool and then marks it with a status a newer version might have written
+	newManager := func(t *testing.T) *ManagerImpl {
+		dir := t.TempDir()
+		mgr := NewManager("linux", "amd64", dir, filepath.Join(dir, "install", artifactDataDir), filepath.Join(dir, "install", artifactMetaDir), filepath.Join(dir, "installed.db"))
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+
+		installed := mgr.installDB.FindArtifact("tool")
+		require.NotNil(t, installed)
+		installed.Status = "quarantined"
+		installed.ReverseDependencies = []string{"app"}
+		installed.Essential = true
+		require.NoError(t, mgr.installDB.SaveDatabase())
+		return mgr
+	}
+
+	t.Run("strict by default", func(t *testing.T) {
+		mgr := newManager(t)
+		err := mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonAutomatic)
+		require.ErrorIs(t, err, errutils.ErrValidation)
+		assert.ErrorContains(t, err, "quarantined")
+
+		require.NoError(t, mgr.loadInstalledDB())
+		assert.Equal(t, model.ArtifactStatus("quarantined"), mgr.installDB.FindArtifact("tool").Status)
+	})
+
+	t.Run("reinstall", func(t *testing.T) {
+		mgr := newManager(t)
+		mgr.SetUnknownStatusPolicy(UnknownStatusPolicyReinstall)
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonAutomatic))
+
+		require.NoError(t, mgr.loadInstalledDB())
+		installed := mgr.installDB.FindArtifact("tool")
+		require.NotNil(t, installed)
+		assert.Equal(t, model.StatusInstalled, installed.Status)
+		assert.Equal(t, model.InstallationReasonManual, installed.InstallationReason)
+		assert.Equal(t, []string{"app"}, installed.ReverseDependencies)
+		assert.True(t, installed.Essential)
+
+		dataDir := mgr.getArtifactDataInstallPath(desc)
+		assert.FileExists(t, filepath.Join(dataDir, "datafile1.bin"))
+		assert.NoDirExists(t, filepath.Join(dataDir, filepath.Base(dataDir)), "files must replace the old directory, not nest in it")
+		assert.FileExists(t, filepath.Join(mgr.getArtifactMetaInstallPath(desc), DefaultMetadataFile))
+	})
+}
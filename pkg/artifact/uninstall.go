@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/glorpus-work/gotya/pkg/artifact/database"
 	"github.com/glorpus-work/gotya/pkg/errutils"
@@ -13,18 +16,16 @@ import (
 	"github.com/glorpus-work/gotya/pkg/model"
 )
 
-// cleanupReverseDependencies removes the artifact from all reverse dependency lists of dependent artifacts
+// cleanupReverseDependencies removes the artifact from the reverse dependency lists of all other artifacts.
+// Every record is swept, not only those the artifact depends on, so stale references are scrubbed as well.
 func (m *ManagerImpl) cleanupReverseDependencies(db database.InstalledManager, artifact *model.InstalledArtifact) {
-	for _, dependentName := range artifact.ReverseDependencies {
-		if dependent := db.FindArtifact(dependentName); dependent != nil {
-			// Remove this artifact from the dependent's reverse dependencies
-			for i, revDep := range dependent.ReverseDependencies {
-				if revDep == artifact.Name {
-					dependent.ReverseDependencies = append(dependent.ReverseDependencies[:i], dependent.ReverseDependencies[i+1:]...)
-					break
-				}
-			}
+	for _, other := range db.GetInstalledArtifacts() {
+		if other.Name == artifact.Name || !slices.Contains(other.ReverseDependencies, artifact.Name) {
+			continue
 		}
+		other.ReverseDependencies = slices.DeleteFunc(other.ReverseDependencies, func(name string) bool {
+			return name == artifact.Name
+		})
 	}
 }
 
@@ -43,6 +44,7 @@ func (m *ManagerImpl) uninstallWithPurge(_ context.Context, db database.Installe
 
 	// Clean up reverse dependencies from other artifacts
 	m.cleanupReverseDependencies(db, artifact)
+	releaseSharedFiles(db, artifact)
 
 	// Remove meta directory
 	if err := os.RemoveAll(artifact.ArtifactMetaDir); err != nil {
@@ -53,6 +55,8 @@ func (m *ManagerImpl) uninstallWithPurge(_ context.Context, db database.Installe
 	if err := os.RemoveAll(artifact.ArtifactDataDir); err != nil {
 		return fmt.Errorf("failed to remove data directory %s: %w", artifact.ArtifactDataDir, err)
 	}
+	removeEmptyParents(artifact.ArtifactMetaDir, m.artifactMetaInstallDir)
+	removeEmptyParents(artifact.ArtifactDataDir, m.artifactDataInstallDir)
 	// Remove from database
 	return m.removeArtifactFromDatabase(db, artifact)
 }
@@ -63,36 +67,130 @@ func (m *ManagerImpl) executePreUninstallHook(artifact *model.InstalledArtifact,
 		ArtifactName:    artifact.Name,
 		ArtifactVersion: artifact.Version,
 		Operation:       "uninstall",
+		OS:              m.os,
+		Arch:            m.arch,
+		Vars:            m.hookVars,
 		MetaDir:         artifact.ArtifactMetaDir,
 		DataDir:         artifact.ArtifactDataDir,
 	}
 
-	preUninstallHookPath := m.resolveHookPath(artifact.ArtifactMetaDir, "pre-uninstall", metadata)
-	if preUninstallHookPath != "" {
-		if err := m.hookExecutor.ExecuteHook(preUninstallHookPath, preUninstallContext); err != nil {
-			return fmt.Errorf("pre-uninstall hook failed: %w", err)
-		}
+	preUninstallHookPaths, err := m.verifiedHookPaths(artifact.ArtifactMetaDir, "pre-uninstall", metadata, artifact)
+	if err != nil {
+		return err
+	}
+	if err := m.executeHooks(preUninstallHookPaths, preUninstallContext); err != nil {
+		return fmt.Errorf("pre-uninstall hook failed: %w", err)
 	}
 
 	return nil
 }
 
 // executePostUninstallHook executes the post-uninstall hook for the artifact
-func (m *ManagerImpl) executePostUninstallHook(artifact *model.InstalledArtifact, preservedScriptDir string) error {
+func (m *ManagerImpl) executePostUninstallHook(artifact *model.InstalledArtifact, preservedScripts []string) error {
 	postUninstallContext := &HookContext{
 		ArtifactName:    artifact.Name,
 		ArtifactVersion: artifact.Version,
 		Operation:       "uninstall",
+		OS:              m.os,
+		Arch:            m.arch,
+		Vars:            m.hookVars,
 		WasMetaDir:      artifact.ArtifactMetaDir,
 		WasDataDir:      artifact.ArtifactDataDir,
 	}
 
-	if err := m.hookExecutor.ExecuteHook(preservedScriptDir, postUninstallContext); err != nil {
+	if err := m.executeHooks(preservedScripts, postUninstallContext); err != nil {
 		return errutils.Wrap(err, "failed to execute post-uninstall hook")
 	}
 	return nil
 }
 
+// UninstallPreview returns the files and directories that UninstallArtifact would delete for the
+// given mode, without changing anything. Files are the existing files recorded in the database.
+// In purge mode the directories are the artifact's meta and data directories, which are removed
+// recursively; in selective mode they are the directories left empty after the files are deleted.
+func (m *ManagerImpl) UninstallPreview(artifactName string, purge bool) (files []string, dirs []string, err error) {
+	if artifactName == "" {
+		return nil, nil, fmt.Errorf("artifact name cannot be empty: %w", errutils.ErrValidation)
+	}
+	if err := m.installDB.LoadDatabase(); err != nil {
+		return nil, nil, fmt.Errorf("failed to load installed database: %w", err)
+	}
+	artifact := m.installDB.FindArtifact(artifactName)
+	if artifact == nil || !m.installDB.IsArtifactInstalled(artifactName) {
+		return nil, nil, fmt.Errorf("artifact %s is not installed: %w", artifactName, errutils.ErrArtifactNotFound)
+	}
+
+	for _, path := range installedFilePaths(artifact) {
+		if _, err := os.Lstat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	slices.Sort(files)
+
+	if purge {
+		return files, []string{artifact.ArtifactMetaDir, artifact.ArtifactDataDir}, nil
+	}
+	return files, emptiedDirs(files, m.belowInstallRoot), nil
+}
+
+// installedFilePaths returns the full paths of all files recorded for an installed artifact.
+func installedFilePaths(artifact *model.InstalledArtifact) []string {
+	paths := make([]string, 0, len(artifact.MetaFiles)+len(artifact.DataFiles))
+	for _, file := range artifact.MetaFiles {
+		paths = append(paths, filepath.Join(artifact.ArtifactMetaDir, file.Path))
+	}
+	for _, file := range artifact.DataFiles {
+		paths = append(paths, filepath.Join(artifact.ArtifactDataDir, file.Path))
+	}
+	return paths
+}
+
+// emptiedDirs mirrors tryRemoveEmptyDirs: it returns the directories accepted by removable that would be
+// empty, and therefore removed, once the given files are deleted.
+func emptiedDirs(files []string, removable func(string) bool) []string {
+	removed := make(map[string]bool, len(files))
+	candidates := make(map[string]bool)
+	for _, file := range files {
+		removed[file] = true
+		if parent := filepath.Dir(file); removable(parent) {
+			candidates[parent] = true
+		}
+	}
+
+	var dirs []string
+	for len(candidates) > 0 {
+		// Deepest directories first, so a parent is only checked after all of its children
+		pending := slices.Collect(maps.Keys(candidates))
+		slices.SortFunc(pending, func(a, b string) int {
+			return strings.Count(b, string(filepath.Separator)) - strings.Count(a, string(filepath.Separator))
+		})
+		dir := pending[0]
+		delete(candidates, dir)
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		empty := true
+		for _, entry := range entries {
+			if !removed[filepath.Join(dir, entry.Name())] {
+				empty = false
+				break
+			}
+		}
+		if !empty {
+			continue
+		}
+		removed[dir] = true
+		dirs = append(dirs, dir)
+		if parent := filepath.Dir(dir); removable(parent) {
+			candidates[parent] = true
+		}
+	}
+	slices.Sort(dirs)
+	return dirs
+}
+
 // deleteArtifactFiles deletes all files associated with an artifact
 func (m *ManagerImpl) deleteArtifactFiles(artifact *model.InstalledArtifact) map[string]bool {
 	dirsToCheck := make(map[string]bool)
@@ -124,6 +222,7 @@ func (m *ManagerImpl) uninstallSelectively(_ context.Context, db database.Instal
 
 	// Clean up reverse dependencies from other artifacts
 	m.cleanupReverseDependencies(db, artifact)
+	releaseSharedFiles(db, artifact)
 
 	// Delete artifact files
 	m.deleteArtifactFiles(artifact)
@@ -149,55 +248,112 @@ func (m *ManagerImpl) deleteFile(path string, dirsToCheck map[string]bool) error
 	return nil
 }
 
-// tryRemoveEmptyDirs attempts to remove directories that might be empty after file deletion
+// tryRemoveEmptyDirs attempts to remove directories below the install roots that might be empty after file deletion
 func (m *ManagerImpl) tryRemoveEmptyDirs(dirsToCheck map[string]bool) {
 	// Process directories in a loop since removing a directory can make its parent empty
 	processed := make(map[string]bool)
 
-	for len(dirsToCheck) > 0 && len(processed) < len(dirsToCheck) {
-		for dir := range dirsToCheck {
-			if processed[dir] {
-				continue
+	for {
+		dir, found := "", false
+		for candidate := range dirsToCheck {
+			if !processed[candidate] {
+				dir, found = candidate, true
+				break
 			}
+		}
+		if !found {
+			return
+		}
+		// Mark as processed so a non-empty directory is not retried forever
+		processed[dir] = true
 
-			// Try to remove the directory
-			if err := os.Remove(dir); err == nil {
-				// If successful, check if parent directory should also be removed
-				parent := filepath.Dir(dir)
-				if parent != "." && parent != "/" && parent != dir {
-					dirsToCheck[parent] = true
-				}
-				processed[dir] = true
-				delete(dirsToCheck, dir)
-				log.Printf("Info: removed empty directory %s", dir)
-			} else {
-				// Directory is not empty, mark as processed to avoid infinite loops
-				processed[dir] = true
-			}
+		if !m.belowInstallRoot(dir) {
+			continue
+		}
+		if err := os.Remove(dir); err != nil {
+			continue
+		}
+		delete(dirsToCheck, dir)
+		log.Printf("Info: removed empty directory %s", dir)
+
+		// The parent may have become empty, even if it was tried before
+		parent := filepath.Dir(dir)
+		if m.belowInstallRoot(parent) {
+			dirsToCheck[parent] = true
+			delete(processed, parent)
 		}
 	}
 }
 
-// preservePostUninstallHookScript preserves only hook scripts defined in metadata
-func (m *ManagerImpl) preservePostUninstallHookScript(metaDir string, metadata *Metadata) (string, error) {
-	if metadata == nil || metadata.Hooks == nil {
-		return "", nil // No hooks to preserve
+// belowInstallRoot reports whether dir lies inside the meta or data install directory. Empty directories
+// are only removed below these roots, so the roots themselves and their parents are kept.
+func (m *ManagerImpl) belowInstallRoot(dir string) bool {
+	dir = filepath.Clean(dir)
+	for _, root := range []string{m.artifactMetaInstallDir, m.artifactDataInstallDir} {
+		if strings.HasPrefix(dir, filepath.Clean(root)+string(filepath.Separator)) {
+			return true
+		}
 	}
+	return false
+}
 
-	val, exists := metadata.Hooks["post-uninstall"]
-	if !exists {
-		return "", nil // No hooks to preserve
+// preservePostUninstallHookScripts copies the post-uninstall hook scripts, if defined in metadata, into a
+// temporary directory and returns the temporary directory and the paths of the copies in the order they run.
+// The caller removes the directory. The scripts are checked against their recorded digests before they are copied.
+func (m *ManagerImpl) preservePostUninstallHookScripts(artifact *model.InstalledArtifact, metadata *Metadata) (string, []string, error) {
+	metaDir := artifact.ArtifactMetaDir
+	hookPaths, err := m.verifiedHookPaths(metaDir, "post-uninstall", metadata, artifact)
+	if err != nil || len(hookPaths) == 0 {
+		return "", nil, err // No hooks to preserve
 	}
 
-	preservedScriptDir, err := os.MkdirTemp("", fmt.Sprintf("gotya-hooks-%s", "artifact.Name"))
+	preservedScriptDir, err := os.MkdirTemp("", fmt.Sprintf("gotya-hooks-%s", filepath.Base(metaDir)))
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp directory for hook scripts: %w", err)
+		return "", nil, fmt.Errorf("failed to create temp directory for hook scripts: %w", err)
 	}
 
-	err = fsutil.Copy(filepath.Join(metaDir, val), filepath.Join(preservedScriptDir, val))
-	if err != nil {
-		return "", err
+	// Copies keep their path relative to the meta directory, so scripts with the same name stay apart
+	hookFiles := metadata.Hooks.ForPlatform("post-uninstall", m.os, m.arch)
+	preservedScripts := make([]string, 0, len(hookPaths))
+	for i, hookPath := range hookPaths {
+		preservedScript := filepath.Join(preservedScriptDir, filepath.FromSlash(hookFiles[i]))
+		if err := os.MkdirAll(filepath.Dir(preservedScript), 0o755); err != nil {
+			_ = os.RemoveAll(preservedScriptDir)
+			return "", nil, err
+		}
+		if err := fsutil.Copy(hookPath, preservedScript); err != nil {
+			_ = os.RemoveAll(preservedScriptDir)
+			return "", nil, err
+		}
+		preservedScripts = append(preservedScripts, preservedScript)
+	}
+
+	return preservedScriptDir, preservedScripts, nil
+}
+
+// SetStrictUninstall enables a verification pass after UninstallArtifact that fails with
+// ErrUninstallIncomplete if any recorded file, or in purge mode the artifact directories, still exist.
+func (m *ManagerImpl) SetStrictUninstall(strict bool) {
+	m.strictUninstall = strict
+}
+
+// verifyUninstalled checks that the recorded files of an uninstalled artifact are gone, and in purge
+// mode also its meta and data directories. The returned error lists all remaining paths.
+func (m *ManagerImpl) verifyUninstalled(artifact *model.InstalledArtifact, purge bool) error {
+	paths := installedFilePaths(artifact)
+	if purge {
+		paths = append(paths, artifact.ArtifactMetaDir, artifact.ArtifactDataDir)
 	}
 
-	return preservedScriptDir, nil
+	var remaining []string
+	for _, path := range paths {
+		if _, err := os.Lstat(path); err == nil {
+			remaining = append(remaining, path)
+		}
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+	slices.Sort(remaining)
+	return fmt.Errorf("%s: %w: %s", artifact.Name, ErrUninstallIncomplete, strings.Join(remaining, ", "))
 }
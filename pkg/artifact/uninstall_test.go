@@ -0,0 +1,132 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUninstallArtifact_StrictVerification(t *testing.T) {
+	// installLeftover installs an artifact whose post-uninstall hook re-creates the given path,
+	// simulating a file that survives the uninstall, e.g. because another process holds it
+	installLeftover := func(t *testing.T, mgr *ManagerImpl, leftover string) {
+		t.Helper()
+		inputDir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactMetaDir), 0o755))
+		require.NoError(t, os.MkdirAll(filepath.Join(inputDir, artifactDataDir), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactDataDir, "tool.txt"), []byte("tool"), 0o644))
+		hooks := Hooks{}
+		if leftover != "" {
+			script := fmt.Sprintf("os := import(\"os\")\nos.mkdir_all(%q, 0755)\nf := os.create(%q)\nf.close()\n", filepath.Dir(leftover), leftover)
+			require.NoError(t, os.WriteFile(filepath.Join(inputDir, artifactMetaDir, "post-uninstall.tengo"), []byte(script), 0o644))
+			hooks["post-uninstall"] = []string{"post-uninstall.tengo"}
+		}
+
+		packer := NewPacker("tool", "1.0.0", "linux", "amd64", "", "leftover", nil, hooks, inputDir, t.TempDir())
+		artifactPath, err := packer.Pack()
+		require.NoError(t, err)
+		desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	}
+	newManager := func(t *testing.T) *ManagerImpl {
+		dir := t.TempDir()
+		return NewManager("linux", "amd64", dir, filepath.Join(dir, "install", artifactDataDir), filepath.Join(dir, "install", artifactMetaDir), filepath.Join(dir, "installed.db"))
+	}
+
+	t.Run("reports re-created file", func(t *testing.T) {
+		mgr := newManager(t)
+		mgr.SetStrictUninstall(true)
+		leftover := filepath.Join(mgr.getArtifactDataInstallPath(&model.IndexArtifactDescriptor{Name: "tool"}), "tool.txt")
+		installLeftover(t, mgr, leftover)
+
+		err := mgr.UninstallArtifact(context.Background(), "tool", false)
+		require.ErrorIs(t, err, ErrUninstallIncomplete)
+		assert.Contains(t, err.Error(), leftover)
+	})
+
+	t.Run("reports leftover directory in purge mode", func(t *testing.T) {
+		mgr := newManager(t)
+		mgr.SetStrictUninstall(true)
+		dataDir := mgr.getArtifactDataInstallPath(&model.IndexArtifactDescriptor{Name: "tool"})
+		installLeftover(t, mgr, filepath.Join(dataDir, "cache", "state"))
+
+		err := mgr.UninstallArtifact(context.Background(), "tool", true)
+		require.ErrorIs(t, err, ErrUninstallIncomplete)
+		assert.Contains(t, err.Error(), dataDir)
+		assert.NotContains(t, err.Error(), "tool.txt")
+	})
+
+	t.Run("not checked unless strict", func(t *testing.T) {
+		mgr := newManager(t)
+		leftover := filepath.Join(mgr.getArtifactDataInstallPath(&model.IndexArtifactDescriptor{Name: "tool"}), "tool.txt")
+		installLeftover(t, mgr, leftover)
+
+		require.NoError(t, mgr.UninstallArtifact(context.Background(), "tool", false))
+		assert.FileExists(t, leftover)
+	})
+
+	t.Run("passes without leftovers", func(t *testing.T) {
+		mgr := newManager(t)
+		mgr.SetStrictUninstall(true)
+		installLeftover(t, mgr, "")
+
+		require.NoError(t, mgr.UninstallArtifact(context.Background(), "tool", true))
+	})
+}
+
+func TestUninstallArtifact_ScrubsStaleReverseDependencies(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "install", artifactDataDir), filepath.Join(tempDir, "install", artifactMetaDir), dbPath)
+	for _, name := range []string{"tool", "lib", "other"} {
+		path := filepath.Join(tempDir, name+".gotya")
+		setupTestArtifact(t, path, true, &Metadata{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", Description: name})
+		desc := &model.IndexArtifactDescriptor{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + ".gotya"}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, path, model.InstallationReasonManual))
+	}
+
+	// Neither lib nor other is a dependency of tool, but their records mistakenly reference it
+	db := loadInstalledDB(t, dbPath)
+	db.FindArtifact("lib").ReverseDependencies = []string{"tool", "other", "tool"}
+	db.FindArtifact("other").ReverseDependencies = []string{"tool"}
+	require.NoError(t, db.SaveDatabaseTo(dbPath))
+
+	require.NoError(t, mgr.UninstallArtifact(context.Background(), "tool", false))
+
+	db = loadInstalledDB(t, dbPath)
+	assert.Nil(t, db.FindArtifact("tool"))
+	assert.Equal(t, []string{"other"}, db.FindArtifact("lib").ReverseDependencies)
+	assert.Empty(t, db.FindArtifact("other").ReverseDependencies)
+	for _, artifact := range db.GetInstalledArtifacts() {
+		assert.NotContains(t, artifact.ReverseDependencies, "tool", artifact.Name)
+	}
+}
+
+func TestUninstallArtifact_KeepsInstallRoots(t *testing.T) {
+	tempDir := t.TempDir()
+	installDir := filepath.Join(tempDir, "install")
+	dataRoot, metaRoot := filepath.Join(installDir, artifactDataDir), filepath.Join(installDir, artifactMetaDir)
+	mgr := NewManager("linux", "amd64", tempDir, dataRoot, metaRoot, filepath.Join(tempDir, "installed.db"))
+
+	artifactPath := filepath.Join(tempDir, "last.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{Name: "last", Version: "1.0.0", OS: "linux", Arch: "amd64", Description: "last artifact"})
+	desc := &model.IndexArtifactDescriptor{Name: "last", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/last.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+
+	_, dirs, err := mgr.UninstallPreview("last", false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{filepath.Join(metaRoot, "last"), filepath.Join(dataRoot, "last")}, dirs)
+
+	require.NoError(t, mgr.UninstallArtifact(context.Background(), "last", false))
+	assert.NoDirExists(t, filepath.Join(dataRoot, "last"))
+	assert.NoDirExists(t, filepath.Join(metaRoot, "last"))
+	assert.DirExists(t, dataRoot)
+	assert.DirExists(t, metaRoot)
+	assert.DirExists(t, installDir)
+}
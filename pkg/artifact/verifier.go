@@ -3,12 +3,15 @@ package artifact
 import (
 	"context"
 	"crypto/sha256"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/fs"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/glorpus-work/gotya/pkg/model"
@@ -16,11 +19,28 @@ import (
 )
 
 // Verifier handles artifact verification operations
-type Verifier struct{}
+type Verifier struct {
+	maxMetadataSize int64
+	metadataFile    string
+}
 
 // NewVerifier creates a new Verifier instance
 func NewVerifier() *Verifier {
-	return &Verifier{}
+	return &Verifier{maxMetadataSize: DefaultMaxMetadataSize, metadataFile: DefaultMetadataFile}
+}
+
+// SetMetadataFileName sets the name of the metadata file expected in the meta directory.
+func (v *Verifier) SetMetadataFileName(name string) error {
+	if err := validateMetadataFileName(name); err != nil {
+		return err
+	}
+	v.metadataFile = name
+	return nil
+}
+
+// SetMaxMetadataSize sets the maximum size of an artifact's metadata file accepted during verification.
+func (v *Verifier) SetMaxMetadataSize(size int64) {
+	v.maxMetadataSize = size
 }
 
 // VerifyArtifact verifies an artifact from a local file path against the provided descriptor.
@@ -57,32 +77,53 @@ func (v *Verifier) VerifyArtifactFromPath(_ context.Context, artifact *model.Ind
 	}
 
 	// Open the metadata file from the extracted directory
-	metadataPath := filepath.Join(dirPath, artifactMetaDir, metadataFile)
+	metadataPath := filepath.Join(dirPath, artifactMetaDir, orDefaultMetadataFile(v.metadataFile))
 	metadataFile, err := os.Open(metadataPath)
 	if err != nil {
 		return errutils.Wrap(err, "failed to open metadata file")
 	}
 	defer func() { _ = metadataFile.Close() }()
 
-	metadata := &Metadata{}
-	if err := json.NewDecoder(metadataFile).Decode(metadata); err != nil {
+	metadata, err := parseMetadataFile(metadataFile, v.maxMetadataSize)
+	if err != nil {
 		return errutils.Wrap(err, "failed to decode metadata")
 	}
 
 	// Only verify against descriptor if provided
 	if artifact != nil {
-		if metadata.Name != artifact.Name || metadata.Version != artifact.Version ||
-			metadata.GetOS() != artifact.GetOS() || metadata.GetArch() != artifact.GetArch() {
-			return errutils.Wrapf(errutils.ErrArtifactInvalid,
-				"metadata mismatch - expected Name: %s, Version: %s, OS: %s, Arch: %s but got Name: %s, Version: %s, OS: %s, Arch: %s",
-				artifact.Name, artifact.Version, artifact.GetOS(), artifact.GetArch(),
-				metadata.Name, metadata.Version, metadata.GetOS(), metadata.GetArch())
+		if err := compareMetadataWithDescriptor(metadata, artifact); err != nil {
+			return err
 		}
 	}
 
 	return v.verifyArtifactContentsFromPath(dirPath, metadata)
 }
 
+// compareMetadataWithDescriptor checks that the identifying fields of the embedded metadata equal the
+// descriptor and returns a MetadataMismatchError for the first field that differs.
+// Metadata written by older packers may lack the platform; OS and Arch are only compared when present.
+func compareMetadataWithDescriptor(metadata *Metadata, artifact *model.IndexArtifactDescriptor) error {
+	fields := []struct {
+		name, expected, actual string
+		recorded               bool
+	}{
+		{"name", artifact.Name, metadata.Name, true},
+		{"version", artifact.Version, metadata.Version, true},
+		{"os", artifact.GetOS(), metadata.GetOS(), metadata.OS != ""},
+		{"arch", artifact.GetArch(), metadata.GetArch(), metadata.Arch != ""},
+	}
+	for _, f := range fields {
+		if f.recorded && f.expected != f.actual {
+			return &MetadataMismatchError{Field: f.name, Expected: f.expected, Actual: f.actual}
+		}
+	}
+	// Older descriptors and artifacts carry no manifest digest, so only compare when both have one
+	if artifact.ManifestDigest != "" && metadata.ManifestDigest != "" && artifact.ManifestDigest != metadata.ManifestDigest {
+		return &MetadataMismatchError{Field: "manifest digest", Expected: artifact.ManifestDigest, Actual: metadata.ManifestDigest}
+	}
+	return nil
+}
+
 // extractArchive extracts an archive file to the specified destination directory
 func (v *Verifier) extractArchive(ctx context.Context, archivePath, destDir string) error {
 	// Open the archive file
@@ -179,49 +220,72 @@ func (v *Verifier) extractEntryFromArchiveFS(fsys fs.FS, path, destDir string, d
 }
 
 // verifyArtifactContentsFromPath verifies the internal consistency of an artifact's contents from a local directory path.
+// Every regular file in the data and meta directories, except the metadata file and the signature, must match its
+// hash in the metadata, and every file with a hash must exist.
 func (v *Verifier) verifyArtifactContentsFromPath(dirPath string, metadata *Metadata) error {
-	dataDirPath := filepath.Join(dirPath, artifactDataDir)
-
-	// Check if data directory exists
-	if _, err := os.Stat(dataDirPath); os.IsNotExist(err) {
-		return nil
+	if metadata.ManifestDigest != "" && metadata.ManifestDigest != ComputeManifestDigest(metadata.Hashes) {
+		return errutils.Wrap(errutils.ErrArtifactInvalid, "manifest digest does not match the file hashes")
 	}
 
-	entries, err := os.ReadDir(dataDirPath)
-	if err != nil {
-		return errutils.Wrap(err, "failed to read data directory")
+	unhashed := []string{path.Join(artifactMetaDir, orDefaultMetadataFile(v.metadataFile)), path.Join(artifactMetaDir, SignatureFile)}
+	missing := make(map[string]struct{}, len(metadata.Hashes))
+	for artifactFile := range metadata.Hashes {
+		missing[artifactFile] = struct{}{}
 	}
-
-	for _, entry := range entries {
-		if !entry.Type().IsRegular() {
+	for _, dir := range []string{artifactDataDir, artifactMetaDir} {
+		root := filepath.Join(dirPath, dir)
+		// The data directory is optional and the meta directory may have been left out on extraction
+		if _, err := os.Stat(root); os.IsNotExist(err) {
 			continue
 		}
-		artifactFile := filepath.ToSlash(filepath.Join(artifactDataDir, entry.Name()))
-		val, ok := metadata.Hashes[artifactFile]
-		if !ok {
-			return errutils.Wrapf(errutils.ErrArtifactInvalid, "hash for file %s not found", artifactFile)
-		}
-
-		h := sha256.New()
-
-		filePath := filepath.Join(dataDirPath, entry.Name())
-		file, err := os.Open(filePath)
+		err := filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(dirPath, filePath)
+			if err != nil {
+				return err
+			}
+			artifactFile := filepath.ToSlash(rel)
+			if slices.Contains(unhashed, artifactFile) {
+				return nil
+			}
+			val, ok := metadata.Hashes[artifactFile]
+			if !ok {
+				return errutils.Wrapf(errutils.ErrArtifactInvalid, "hash for file %s not found", artifactFile)
+			}
+			delete(missing, artifactFile)
+			return verifyFileHash(filePath, artifactFile, val)
+		})
 		if err != nil {
-			return errutils.Wrap(err, "failed to open file")
-		}
-
-		if _, err := io.Copy(h, file); err != nil {
-			return errutils.Wrap(err, "failed to copy file")
+			return errutils.Wrapf(err, "failed to verify %s directory", dir)
 		}
+	}
 
-		if err := file.Close(); err != nil {
-			return errutils.Wrap(err, "failed to close file")
-		}
+	if len(missing) > 0 {
+		return errutils.Wrapf(errutils.ErrArtifactInvalid, "files listed in the metadata are missing: %s",
+			strings.Join(slices.Sorted(maps.Keys(missing)), ", "))
+	}
+	return nil
+}
 
-		if fmt.Sprintf("%x", h.Sum(nil)) != val {
-			return errutils.Wrapf(errutils.ErrArtifactInvalid, "Hashsum mismatch %x, %s", h.Sum(nil), val)
-		}
+// verifyFileHash checks that the SHA-256 hash of the artifact file at filePath is the hex encoded expected hash.
+func verifyFileHash(filePath, artifactFile, expected string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return errutils.Wrap(err, "failed to open file")
 	}
+	defer func() { _ = file.Close() }()
 
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return errutils.Wrap(err, "failed to copy file")
+	}
+	if fmt.Sprintf("%x", h.Sum(nil)) != expected {
+		return errutils.Wrapf(errutils.ErrArtifactInvalid, "Hashsum mismatch for %s: %x, %s", artifactFile, h.Sum(nil), expected)
+	}
 	return nil
 }
@@ -41,7 +41,7 @@ func TestVerifier_extractArtifact_Success(t *testing.T) {
 	assert.DirExists(t, filepath.Join(destDir, artifactDataDir))
 
 	// Verify metadata file exists
-	metadataFile := filepath.Join(destDir, artifactMetaDir, metadataFile)
+	metadataFile := filepath.Join(destDir, artifactMetaDir, DefaultMetadataFile)
 	assert.FileExists(t, metadataFile)
 
 	// Verify data files exist
@@ -97,7 +97,7 @@ func TestVerifier_extractArtifact_MetaPackage(t *testing.T) {
 	assert.NoDirExists(t, filepath.Join(destDir, artifactDataDir))
 
 	// Verify metadata file exists
-	metadataFile := filepath.Join(destDir, artifactMetaDir, metadataFile)
+	metadataFile := filepath.Join(destDir, artifactMetaDir, DefaultMetadataFile)
 	assert.FileExists(t, metadataFile)
 }
 
@@ -126,7 +126,7 @@ func TestVerifier_extractArtifact_WithSymlinks(t *testing.T) {
 	assert.DirExists(t, destDir)
 	assert.DirExists(t, filepath.Join(destDir, artifactMetaDir))
 	assert.DirExists(t, filepath.Join(destDir, artifactDataDir))
-	assert.FileExists(t, filepath.Join(destDir, artifactMetaDir, metadataFile))
+	assert.FileExists(t, filepath.Join(destDir, artifactMetaDir, DefaultMetadataFile))
 	assert.FileExists(t, filepath.Join(destDir, artifactDataDir, "datafile1.bin"))
 	assert.FileExists(t, filepath.Join(destDir, artifactDataDir, "datafile2.bin"))
 }
@@ -211,6 +211,36 @@ func TestVerifier_Verify(t *testing.T) {
 			expectError: true,
 			errorMsg:    "metadata mismatch",
 		},
+		{
+			name: "VerifyArtifact with mismatched os",
+			doc:  "Should fail when descriptor os doesn't match the os recorded in the metadata",
+			descriptor: &model.IndexArtifactDescriptor{
+				Name:    "test-artifact",
+				Version: "1.0.0",
+				OS:      "darwin",
+				Arch:    "amd64",
+			},
+			setup:       validArtifactSetup,
+			verifyFn:    (*Verifier).VerifyArtifact,
+			expectError: true,
+			errorMsg:    "metadata mismatch",
+		},
+		{
+			name: "VerifyArtifact with metadata lacking os and arch",
+			doc:  "Should successfully verify artifacts of older packers that did not record the platform",
+			descriptor: &model.IndexArtifactDescriptor{
+				Name:    "test-artifact",
+				Version: "1.0.0",
+				OS:      "linux",
+				Arch:    "amd64",
+			},
+			setup: func(t *testing.T, tempDir string) string {
+				testArtifact := filepath.Join(tempDir, "legacy.gotya")
+				setupTestArtifact(t, testArtifact, true, &Metadata{Name: "test-artifact", Version: "1.0.0"})
+				return testArtifact
+			},
+			verifyFn: (*Verifier).VerifyArtifact,
+		},
 		{
 			name:       "VerifyArtifact with nil descriptor",
 			doc:        "Should successfully verify when no descriptor is provided",
@@ -312,6 +342,6 @@ func TestVerifier_extractArtifact_OverwriteProtection(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify files still exist
-	assert.FileExists(t, filepath.Join(destDir, artifactMetaDir, metadataFile))
+	assert.FileExists(t, filepath.Join(destDir, artifactMetaDir, DefaultMetadataFile))
 	assert.FileExists(t, filepath.Join(destDir, artifactDataDir, "datafile1.bin"))
 }
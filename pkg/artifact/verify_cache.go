@@ -0,0 +1,110 @@
+package artifact
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+// VerifyCacheOptions controls how VerifyCache checks the artifact cache.
+type VerifyCacheOptions struct {
+	// Concurrency is the number of entries verified in parallel; values <= 0 use the number of CPUs.
+	Concurrency int
+	// OnProgress, if set, is called after each entry with the number of verified entries and the total.
+	// Calls are serialized, so the callback does not need to be safe for concurrent use.
+	OnProgress func(done, total int)
+}
+
+// VerifyCache verifies every artifact in cacheDir and returns the result per file path.
+// A nil error in the result means the entry is valid. Entries named after a SHA-256 checksum,
+// as stored by the download manager, must also match that checksum.
+// The returned error is only set if the cache could not be read or ctx was cancelled.
+func (v *Verifier) VerifyCache(ctx context.Context, cacheDir string, opts VerifyCacheOptions) (map[string]error, error) {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]error{}, nil
+		}
+		return nil, errutils.Wrapf(err, "failed to read cache directory %s", cacheDir)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		// Skip directories and partial downloads
+		if !entry.Type().IsRegular() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		paths = append(paths, filepath.Join(cacheDir, entry.Name()))
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	results := make(map[string]error, len(paths))
+	var mu sync.Mutex
+
+	tasks := make(chan string)
+	var wg sync.WaitGroup
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range tasks {
+				verifyErr := v.verifyCacheEntry(ctx, path)
+				mu.Lock()
+				results[path] = verifyErr
+				if opts.OnProgress != nil {
+					opts.OnProgress(len(results), len(paths))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		tasks <- path
+	}
+	close(tasks)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// verifyCacheEntry checks the checksum encoded in the file name, if any, and the artifact's internal consistency.
+func (v *Verifier) verifyCacheEntry(ctx context.Context, path string) error {
+	name := filepath.Base(path)
+	if isSHA256Hex(name) {
+		hash, err := calculateFileHash(path)
+		if err != nil {
+			return errutils.Wrapf(err, "failed to hash %s", name)
+		}
+		if !strings.EqualFold(hash, name) {
+			return errutils.Wrapf(errutils.ErrFileHashMismatch, "%s has checksum %s", name, hash)
+		}
+	}
+	return v.VerifyArtifact(ctx, nil, path)
+}
+
+// isSHA256Hex reports whether s is a hex-encoded SHA-256 digest.
+func isSHA256Hex(s string) bool {
+	if len(s) != hex.EncodedLen(32) {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,89 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_VerifyCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	const validCount = 20
+
+	var valid []string
+	for i := 0; i < validCount; i++ {
+		path := filepath.Join(cacheDir, fmt.Sprintf("artifact-%02d.gotya", i))
+		setupTestArtifact(t, path, true, &Metadata{
+			Name: fmt.Sprintf("artifact-%02d", i), Version: "1.0.0", OS: "linux", Arch: "amd64", Description: "cached artifact",
+		})
+		valid = append(valid, path)
+	}
+
+	// A checksum-named entry whose content matches its name
+	checksumPath := filepath.Join(cacheDir, "checksum.gotya")
+	setupTestArtifact(t, checksumPath, true, &Metadata{Name: "named", Version: "1.0.0", OS: "linux", Arch: "amd64", Description: "checksum named"})
+	hash, err := calculateFileHash(checksumPath)
+	require.NoError(t, err)
+	namedPath := filepath.Join(cacheDir, hash)
+	require.NoError(t, os.Rename(checksumPath, namedPath))
+	valid = append(valid, namedPath)
+
+	// A checksum-named entry with mismatching content and a corrupt archive
+	mismatchPath := filepath.Join(cacheDir, fmt.Sprintf("%064x", 1))
+	require.NoError(t, os.WriteFile(mismatchPath, []byte("not what the name promises"), 0o644))
+	corruptPath := filepath.Join(cacheDir, "corrupt.gotya")
+	require.NoError(t, os.WriteFile(corruptPath, []byte("not an archive"), 0o644))
+
+	// Partial downloads and directories are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "dl-123.tmp"), []byte("partial"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(cacheDir, "subdir"), 0o755))
+
+	total := len(valid) + 2
+	var mu sync.Mutex
+	var progress []int
+	results, err := NewVerifier().VerifyCache(context.Background(), cacheDir, VerifyCacheOptions{
+		Concurrency: 4,
+		OnProgress: func(done, gotTotal int) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, total, gotTotal)
+			progress = append(progress, done)
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, results, total)
+	for _, path := range valid {
+		assert.NoError(t, results[path], path)
+	}
+	assert.ErrorIs(t, results[mismatchPath], errutils.ErrFileHashMismatch)
+	assert.Error(t, results[corruptPath])
+
+	require.Len(t, progress, total)
+	for i, done := range progress {
+		assert.Equal(t, i+1, done)
+	}
+}
+
+func TestVerifier_VerifyCache_MissingDir(t *testing.T) {
+	results, err := NewVerifier().VerifyCache(context.Background(), filepath.Join(t.TempDir(), "missing"), VerifyCacheOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestVerifier_VerifyCache_Cancelled(t *testing.T) {
+	cacheDir := t.TempDir()
+	setupTestArtifact(t, filepath.Join(cacheDir, "a.gotya"), true, &Metadata{Name: "a", Version: "1.0.0", OS: "linux", Arch: "amd64", Description: "a"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewVerifier().VerifyCache(ctx, cacheDir, VerifyCacheOptions{})
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,154 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// VerificationResult describes an installed artifact whose files differ from its database record.
+// Paths are absolute and sorted.
+type VerificationResult struct {
+	Name     string
+	Version  string
+	Modified []string // Recorded files whose content no longer matches the recorded hash
+	Missing  []string // Recorded files that do not exist
+	Extra    []string // Files in the install directories of the artifact that are not recorded
+	Err      error    // Set if files or directories of the artifact could not be read
+}
+
+// SetVerifyConcurrency sets how many artifacts VerifyInstalled checks in parallel.
+// Values <= 0 use the number of CPUs.
+func (m *ManagerImpl) SetVerifyConcurrency(concurrency int) {
+	m.verifyConcurrency = concurrency
+}
+
+// VerifyInstalled checks the files of all installed artifacts against the installed database: every
+// recorded meta and data file is hashed and compared with its recorded hash, and the install directories
+// are searched for regular files that are not recorded. Symlinks are not recorded and thus not reported.
+// Only artifacts with problems are returned, sorted by name. Nothing is modified.
+// Artifacts are checked in parallel as set with SetVerifyConcurrency. If ctx is done, the workers stop
+// between files and the results of the artifacts checked completely so far are returned with ctx.Err().
+func (m *ManagerImpl) VerifyInstalled(ctx context.Context) ([]VerificationResult, error) {
+	unlock, err := m.readDB()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var artifacts []*model.InstalledArtifact
+	for _, artifact := range m.installDB.GetInstalledArtifacts() {
+		if artifact.Status == model.StatusInstalled {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+
+	concurrency := m.verifyConcurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results []VerificationResult
+	)
+	jobs := make(chan *model.InstalledArtifact)
+	for i := 0; i < min(concurrency, len(artifacts)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for artifact := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				result, ok := verifyInstalledArtifact(ctx, artifact)
+				// An interrupted check is incomplete, so it is dropped
+				if !ok || ctx.Err() != nil {
+					continue
+				}
+				mu.Lock()
+				results = append(results, result)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, artifact := range artifacts {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- artifact
+	}
+	close(jobs)
+	wg.Wait()
+
+	slices.SortFunc(results, func(a, b VerificationResult) int { return strings.Compare(a.Name, b.Name) })
+	return results, ctx.Err()
+}
+
+// verifyInstalledArtifact checks the files of an installed artifact. It reports false if the artifact has no problems.
+func verifyInstalledArtifact(ctx context.Context, artifact *model.InstalledArtifact) (VerificationResult, bool) {
+	result := VerificationResult{Name: artifact.Name, Version: artifact.Version}
+	var errs []error
+	errs = append(errs, verifyInstalledFiles(ctx, &result, artifact.ArtifactMetaDir, artifact.MetaFiles)...)
+	errs = append(errs, verifyInstalledFiles(ctx, &result, artifact.ArtifactDataDir, artifact.DataFiles)...)
+	result.Err = errors.Join(errs...)
+	if len(result.Modified) == 0 && len(result.Missing) == 0 && len(result.Extra) == 0 && result.Err == nil {
+		return result, false
+	}
+	slices.Sort(result.Modified)
+	slices.Sort(result.Missing)
+	slices.Sort(result.Extra)
+	return result, true
+}
+
+// verifyInstalledFiles compares the files recorded below dir with the files on disk and adds the
+// differences to result. It returns the errors of files that could not be read, or ctx.Err() if ctx is done.
+func verifyInstalledFiles(ctx context.Context, result *VerificationResult, dir string, files []model.InstalledFile) []error {
+	if dir == "" {
+		return nil
+	}
+
+	var errs []error
+	recorded := make(map[string]bool, len(files))
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return []error{err}
+		}
+		path := filepath.Join(dir, filepath.FromSlash(file.Path))
+		recorded[path] = true
+		hash, err := calculateFileHash(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			result.Missing = append(result.Missing, path)
+		case err != nil:
+			errs = append(errs, err)
+		case hash != file.Hash:
+			result.Modified = append(result.Modified, path)
+		}
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && !recorded[path] {
+			result.Extra = append(result.Extra, path)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,130 @@
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyInstalled(t *testing.T) {
+	tempDir := t.TempDir()
+	dataDir := filepath.Join(tempDir, "install", artifactDataDir)
+	metaDir := filepath.Join(tempDir, "install", artifactMetaDir)
+	mgr := NewManager("linux", "amd64", tempDir, dataDir, metaDir, filepath.Join(tempDir, "installed.db"))
+
+	for _, name := range []string{"intact", "broken"} {
+		artifactPath := filepath.Join(tempDir, name+".gotya")
+		setupTestArtifact(t, artifactPath, true, &Metadata{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64"})
+		desc := &model.IndexArtifactDescriptor{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/" + name + ".gotya"}
+		require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+	}
+
+	results, err := mgr.VerifyInstalled(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	brokenData := filepath.Join(dataDir, "broken")
+	brokenMeta := filepath.Join(metaDir, "broken")
+	require.NoError(t, os.WriteFile(filepath.Join(brokenData, "datafile1.bin"), []byte("tampered"), 0o644))
+	require.NoError(t, os.Remove(filepath.Join(brokenData, "datafile2.bin")))
+	require.NoError(t, os.MkdirAll(filepath.Join(brokenData, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(brokenData, "sub", "stray.txt"), []byte("stray"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(brokenMeta, "notes.txt"), []byte("stray"), 0o644))
+	require.NoError(t, os.Symlink("datafile1.bin", filepath.Join(brokenData, "link")))
+
+	results, err = mgr.VerifyInstalled(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, VerificationResult{
+		Name:     "broken",
+		Version:  "1.0.0",
+		Modified: []string{filepath.Join(brokenData, "datafile1.bin")},
+		Missing:  []string{filepath.Join(brokenData, "datafile2.bin")},
+		Extra:    []string{filepath.Join(brokenData, "sub", "stray.txt"), filepath.Join(brokenMeta, "notes.txt")},
+	}, results[0])
+
+	// Verifying only reports, it leaves the files and the database untouched
+	assert.NoFileExists(t, filepath.Join(brokenData, "datafile2.bin"))
+	assert.FileExists(t, filepath.Join(brokenMeta, "notes.txt"))
+	installed, err := mgr.GetInstalledArtifacts()
+	require.NoError(t, err)
+	assert.Len(t, installed, 2)
+}
+
+func TestVerifyInstalled_Canceled(t *testing.T) {
+	tempDir := t.TempDir()
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "data"), filepath.Join(tempDir, "meta"), filepath.Join(tempDir, "installed.db"))
+	artifactPath := filepath.Join(tempDir, "tool.gotya")
+	setupTestArtifact(t, artifactPath, true, &Metadata{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64"})
+	desc := &model.IndexArtifactDescriptor{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64", URL: "http://example.com/tool.gotya"}
+	require.NoError(t, mgr.InstallArtifact(context.Background(), desc, artifactPath, model.InstallationReasonManual))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := mgr.VerifyInstalled(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// cancelAfterContext is cancelled by the call to Err after the given number of calls, so cancellation
+// happens at a deterministic point in the middle of an operation.
+type cancelAfterContext struct {
+	context.Context
+	cancel    context.CancelFunc
+	mu        sync.Mutex
+	remaining int
+}
+
+func newCancelAfterContext(calls int) *cancelAfterContext {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cancelAfterContext{Context: ctx, cancel: cancel, remaining: calls}
+}
+
+func (c *cancelAfterContext) Err() error {
+	c.mu.Lock()
+	if c.remaining == 0 {
+		c.cancel()
+	} else {
+		c.remaining--
+	}
+	c.mu.Unlock()
+	return c.Context.Err()
+}
+
+func TestVerifyInstalled_CanceledMidway(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "installed.db")
+	mgr := NewManager("linux", "amd64", tempDir, filepath.Join(tempDir, "data"), filepath.Join(tempDir, "meta"), dbPath)
+	mgr.SetVerifyConcurrency(4)
+
+	// Every artifact is missing all of its files, so each one that is checked completely is reported
+	const total = 100
+	var artifacts []*model.InstalledArtifact
+	for i := range total {
+		name := fmt.Sprintf("artifact-%03d", i)
+		artifact := createTestInstalledArtifact(t, name, "1.0.0", nil)
+		artifact.ArtifactMetaDir = filepath.Join(tempDir, "meta", name)
+		artifact.ArtifactDataDir = filepath.Join(tempDir, "data", name)
+		artifact.DataFiles = []model.InstalledFile{{Path: "a.bin"}, {Path: "b.bin"}, {Path: "c.bin"}}
+		artifacts = append(artifacts, artifact)
+	}
+	setupTestDatabaseWithArtifacts(t, dbPath, artifacts)
+
+	results, err := mgr.VerifyInstalled(newCancelAfterContext(120))
+	require.ErrorIs(t, err, context.Canceled)
+	assert.NotEmpty(t, results, "artifacts checked before the cancellation are returned")
+	assert.Less(t, len(results), total/2, "verification should stop promptly")
+	for _, result := range results {
+		assert.Len(t, result.Missing, 4, "only completely checked artifacts are returned: %s", result.Name)
+		assert.NoError(t, result.Err, result.Name)
+	}
+	assert.True(t, slices.IsSortedFunc(results, func(a, b VerificationResult) int { return strings.Compare(a.Name, b.Name) }))
+}
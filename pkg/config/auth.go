@@ -66,14 +66,14 @@ func (c *Config) ToAuthMap() map[string]auth.Authenticator {
 			continue
 		}
 
-		// Use the repository URL as the key for authentication matching
-		if repo.URL != "" {
+		// Use the repository URL and its mirrors as the keys for authentication matching
+		for _, repoURL := range append([]string{repo.URL}, repo.Mirrors...) {
+			if repoURL == "" {
+				continue
+			}
 			// For URLs that end with /index.json, use the base URL as the prefix
 			// This allows authentication to work for both index.json and artifact downloads
-			urlPrefix := repo.URL
-			if strings.HasSuffix(repo.URL, "/index.json") {
-				urlPrefix = strings.TrimSuffix(repo.URL, "/index.json")
-			}
+			urlPrefix := strings.TrimSuffix(repoURL, "/index.json")
 
 			switch {
 			case repo.Auth.BasicAuth != nil:
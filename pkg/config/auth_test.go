@@ -62,6 +62,22 @@ func TestToAuthMap(t *testing.T) {
 			},
 			expected: nil,
 		},
+		{
+			name: "repository with mirrors",
+			repos: []*RepositoryConfig{
+				{
+					URL:     "https://example.com/repo/index.json",
+					Mirrors: []string{"https://mirror.example.org/repo/index.json"},
+					Auth: &AuthConfig{
+						BearerAuth: &BearerAuth{Token: "token"},
+					},
+				},
+			},
+			expected: map[string]auth.Authenticator{
+				"https://example.com/repo":        &auth.BearerAuth{Token: "token"},
+				"https://mirror.example.org/repo": &auth.BearerAuth{Token: "token"},
+			},
+		},
 		{
 			name: "repository with basic auth",
 			repos: []*RepositoryConfig{
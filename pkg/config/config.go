@@ -8,6 +8,7 @@ package config
 import (
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -35,6 +36,12 @@ type RepositoryConfig struct {
 	Enabled  bool        `yaml:"enabled"`
 	Priority uint        `yaml:"priority"`
 	Auth     *AuthConfig `yaml:"auth,omitempty"`
+	// Mirrors are alternative URLs of the index, tried in order when URL is unavailable. Artifacts are
+	// downloaded from the same relative location below a mirror. Auth applies to the mirrors as well.
+	Mirrors []string `yaml:"mirrors,omitempty"`
+	// TrustedKey is the path of a PEM encoded ed25519 public key. If set, the index must have a detached
+	// signature made with its private key at its URL with ".sig" appended.
+	TrustedKey string `yaml:"trusted_key,omitempty"`
 }
 
 // PlatformConfig represents platform-specific configuration.
@@ -62,8 +69,25 @@ type Settings struct {
 	MetaDir    string `yaml:"meta_dir,omitempty"`
 
 	// Network settings
-	HTTPTimeout   time.Duration `yaml:"http_timeout"`
-	MaxConcurrent int           `yaml:"max_concurrent_syncs"`
+	HTTPTimeout   time.Duration     `yaml:"http_timeout"`
+	MaxConcurrent int               `yaml:"max_concurrent_syncs"`
+	UserAgent     string            `yaml:"user_agent,omitempty"`     // Defaults to the download manager's user agent
+	HTTPHeaders   map[string]string `yaml:"http_headers,omitempty"`   // Static headers sent with every download
+	AllowInsecure bool              `yaml:"allow_insecure,omitempty"` // Permit plain-HTTP repository and artifact URLs
+
+	// Proxy and TLS settings for downloads and index syncs
+	Proxy      string `yaml:"proxy,omitempty"`       // Proxy URL, defaults to the HTTP_PROXY/HTTPS_PROXY environment
+	NoProxy    string `yaml:"no_proxy,omitempty"`    // Comma-separated hosts, domains and CIDRs not to proxy, like NO_PROXY
+	ClientCert string `yaml:"client_cert,omitempty"` // PEM client certificate file for mutual TLS
+	ClientKey  string `yaml:"client_key,omitempty"`  // PEM private key file of the client certificate
+	CABundle   string `yaml:"ca_bundle,omitempty"`   // PEM file of CA certificates trusted in addition to the system roots
+
+	// Retry settings for downloads failing with a connection error or a 5xx/429 response
+	DownloadAttempts   int           `yaml:"download_attempts"`    // Attempts per download, 1 disables retries
+	DownloadRetryDelay time.Duration `yaml:"download_retry_delay"` // Delay before the first retry, doubled for every further retry
+
+	// Hook settings
+	HookOutputLimit int `yaml:"hook_output_limit"` // Bytes of output captured per hook run, further output is truncated
 
 	// Platform settings
 	Platform PlatformConfig `yaml:"platform,omitempty"`
@@ -84,6 +108,15 @@ const (
 	// DefaultMaxConcurrent is the default maximum number of concurrent operations.
 	DefaultMaxConcurrent = 5
 
+	// DefaultDownloadAttempts is the default number of attempts per download.
+	DefaultDownloadAttempts = 3
+
+	// DefaultDownloadRetryDelay is the default delay before the first retry of a download.
+	DefaultDownloadRetryDelay = 500 * time.Millisecond
+
+	// DefaultHookOutputLimit is the default number of bytes of output captured per hook run.
+	DefaultHookOutputLimit = 64 * 1024
+
 	// YAMLIndent is the number of spaces to use for YAML indentation.
 	YAMLIndent = 2
 )
@@ -114,6 +147,10 @@ func DefaultConfig() *Config {
 			InstallDir:    filepath.Join(userConfigDir, "bin"),
 			MetaDir:       filepath.Join(userConfigDir, "meta"),
 			StateDir:      defaultStateDir,
+
+			DownloadAttempts:   DefaultDownloadAttempts,
+			DownloadRetryDelay: DefaultDownloadRetryDelay,
+			HookOutputLimit:    DefaultHookOutputLimit,
 			Platform: PlatformConfig{
 				OS:   runtime.GOOS,
 				Arch: runtime.GOARCH,
@@ -275,6 +312,18 @@ func validateSettings(s Settings) error {
 	if s.MaxConcurrent < 1 {
 		return errutils.ErrMaxConcurrentInvalid
 	}
+	if s.DownloadAttempts < 0 {
+		return errutils.ErrDownloadAttemptsNegative
+	}
+	if s.DownloadRetryDelay < 0 {
+		return errutils.ErrDownloadRetryDelayNegative
+	}
+	if s.HookOutputLimit < 0 {
+		return errutils.ErrHookOutputLimitNegative
+	}
+	if (s.ClientCert == "") != (s.ClientKey == "") {
+		return errutils.ErrClientCertIncomplete
+	}
 	validFormats := map[string]bool{"text": true, "json": true}
 	if !validFormats[s.OutputFormat] {
 		return errutils.ErrInvalidOutputFormatWithDetails(s.OutputFormat)
@@ -299,8 +348,13 @@ func GetDefaultConfigPath() (string, error) {
 }
 
 // AddRepository adds a index to the configuration.
-// Returns an error if a index with the same name already exists.
+// Returns an error if a index with the same name already exists, or if the URL uses plain HTTP
+// and Settings.AllowInsecure is not set.
 func (c *Config) AddRepository(name, url string, enabled bool) error {
+	if !c.Settings.AllowInsecure && strings.HasPrefix(strings.ToLower(url), "http://") {
+		return fmt.Errorf("repository %s uses plain HTTP, set allow_insecure to permit it: %w", name, errutils.ErrInsecureURL)
+	}
+
 	// Check if index already exists
 	for _, repo := range c.Repositories {
 		if repo.Name == name {
@@ -386,6 +440,18 @@ func (c *Config) GetStateDir() string {
 	return c.Settings.StateDir
 }
 
+// GetHTTPHeaders returns the configured static download headers.
+func (c *Config) GetHTTPHeaders() http.Header {
+	if len(c.Settings.HTTPHeaders) == 0 {
+		return nil
+	}
+	headers := make(http.Header, len(c.Settings.HTTPHeaders))
+	for key, value := range c.Settings.HTTPHeaders {
+		headers.Set(key, value)
+	}
+	return headers
+}
+
 // GetMetaDir returns the path to the meta directory.
 func (c *Config) GetMetaDir() string {
 	return c.Settings.MetaDir
@@ -405,6 +471,15 @@ func (c *Config) applyDefaults() {
 	if c.Settings.MaxConcurrent == 0 {
 		c.Settings.MaxConcurrent = defaults.Settings.MaxConcurrent
 	}
+	if c.Settings.DownloadAttempts == 0 {
+		c.Settings.DownloadAttempts = defaults.Settings.DownloadAttempts
+	}
+	if c.Settings.DownloadRetryDelay == 0 {
+		c.Settings.DownloadRetryDelay = defaults.Settings.DownloadRetryDelay
+	}
+	if c.Settings.HookOutputLimit == 0 {
+		c.Settings.HookOutputLimit = defaults.Settings.HookOutputLimit
+	}
 	if c.Settings.OutputFormat == "" {
 		c.Settings.OutputFormat = defaults.Settings.OutputFormat
 	}
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/glorpus-work/gotya/pkg/fsutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -106,6 +107,16 @@ func TestValidateConfig(t *testing.T) {
 			wantErr: true,
 			errMsg:  "invalid log level",
 		},
+		{
+			name: "client certificate without key",
+			config: func() *Config {
+				cfg := DefaultConfig()
+				cfg.Settings.ClientCert = "/etc/gotya/client.pem"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "client_cert and client_key must be set together",
+		},
 	}
 
 	for _, tt := range tests {
@@ -223,6 +234,20 @@ func TestGetDatabasePath(t *testing.T) {
 	})
 }
 
+func TestAddRepository_InsecureURL(t *testing.T) {
+	cfg := DefaultConfig()
+
+	err := cfg.AddRepository("plain", "http://example.com/index.json", true)
+	require.ErrorIs(t, err, errutils.ErrInsecureURL)
+	assert.Empty(t, cfg.Repositories)
+
+	require.NoError(t, cfg.AddRepository("secure", "https://example.com/index.json", true))
+
+	cfg.Settings.AllowInsecure = true
+	require.NoError(t, cfg.AddRepository("plain", "http://example.com/index.json", true))
+	assert.Len(t, cfg.Repositories, 2)
+}
+
 func TestRepositoryManagement(t *testing.T) {
 	cfg := DefaultConfig()
 
@@ -485,6 +510,15 @@ func TestConfig_GetMetaDir(t *testing.T) {
 	assert.Equal(t, "/custom/meta", metaDir)
 }
 
+func TestConfig_GetHTTPHeaders(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Nil(t, cfg.GetHTTPHeaders())
+
+	cfg.Settings.HTTPHeaders = map[string]string{"x-client-id": "build-42"}
+	headers := cfg.GetHTTPHeaders()
+	assert.Equal(t, "build-42", headers.Get("X-Client-Id"))
+}
+
 func TestRepositoryConfig_GetURL(t *testing.T) {
 	tests := []struct {
 		name     string
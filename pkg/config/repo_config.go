@@ -5,11 +5,27 @@ import "net/url"
 // GetURL parses and returns the repository URL.
 // Returns nil if the URL is invalid or empty.
 func (rc *RepositoryConfig) GetURL() *url.URL {
-	if rc.URL == "" {
+	return parseRepositoryURL(rc.URL)
+}
+
+// GetMirrors parses and returns the mirror URLs of the repository in order. Invalid or empty URLs are skipped.
+func (rc *RepositoryConfig) GetMirrors() []*url.URL {
+	var mirrors []*url.URL
+	for _, mirror := range rc.Mirrors {
+		if u := parseRepositoryURL(mirror); u != nil {
+			mirrors = append(mirrors, u)
+		}
+	}
+	return mirrors
+}
+
+// parseRepositoryURL parses an absolute repository URL, returning nil if it is invalid or empty.
+func parseRepositoryURL(rawURL string) *url.URL {
+	if rawURL == "" {
 		return nil
 	}
 
-	parse, err := url.Parse(rc.URL)
+	parse, err := url.Parse(rawURL)
 	if err != nil {
 		return nil
 	}
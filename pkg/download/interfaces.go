@@ -6,6 +6,7 @@ package download
 
 import (
 	"context"
+	"net/http"
 	"net/url"
 
 	"github.com/glorpus-work/gotya/pkg/auth"
@@ -33,10 +34,33 @@ type Item struct {
 	URL      *url.URL // source URL to download
 	Checksum string   // optional hex-encoded SHA-256 checksum; if provided, will be verified
 	Filename string   // optional preferred filename; if empty, a name will be derived
+	// Validators, if set, makes the download revalidate a cached file instead of reusing it. The request
+	// carries If-None-Match/If-Modified-Since and a 304 response keeps the cached file as is. After a
+	// full download the validators of the response are stored back into it.
+	Validators *Validators
+	// Mirrors are alternative URLs of the same file. They are tried in order when the download from URL
+	// fails; the error of the last one lists the failure of every URL.
+	Mirrors []*url.URL
+	// ServedBy, if set, receives the URL the file was downloaded from. It is left alone when a cached
+	// file is reused without a request.
+	ServedBy *url.URL
+}
+
+// Validators are the HTTP cache validators of a downloaded file.
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// NotModified is set by the download when a 304 response confirmed the cached file.
+	NotModified bool `json:"-"`
 }
 
 // Options control the behavior of the download manager.
 type Options struct {
 	Dir         string // destination directory (cache). Must be absolute.
 	Concurrency int    // number of parallel downloads; if <=0, a sane default is used
+	// Headers are static headers sent with every request. A User-Agent set here overrides
+	// the manager's user agent; authentication headers take precedence over these.
+	Headers http.Header
+	// AllowInsecure permits plain-HTTP URLs, including redirects to them. By default they are refused.
+	AllowInsecure bool
 }
@@ -4,9 +4,12 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -19,24 +22,113 @@ import (
 	"github.com/glorpus-work/gotya/pkg/fsutil"
 )
 
-// ManagerImpl is a simple HTTP-based download manager with optional checksum verification
-// and basic de-duplication. It is intentionally minimal and can be extended later
-// with retries, backoff, mirror selection, and content-addressed storage.
+// DefaultUserAgent is the User-Agent sent when no other user agent is configured.
+const DefaultUserAgent = "gotya/1.0"
+
+// ManagerImpl is a simple HTTP-based download manager with optional checksum verification,
+// basic de-duplication, retries with backoff and failover to mirrors. It is intentionally minimal
+// and can be extended later with content-addressed storage.
 type ManagerImpl struct {
 	client         *http.Client
 	userAgent      string
 	authenticators map[string]auth.Authenticator
+	retry          RetryPolicy
+}
+
+// RetryPolicy controls how requests failing with a connection error, a 5xx or a 429 response are retried.
+// Other responses fail immediately. The delay before a retry doubles with every attempt and is jittered.
+type RetryPolicy struct {
+	MaxAttempts int           // total number of attempts per request; values below 1 mean a single attempt
+	BaseDelay   time.Duration // delay before the first retry
+}
+
+// delay returns the jittered backoff before retrying after the given failed attempt, counted from 1.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	backoff := p.BaseDelay << (attempt - 1)
+	if backoff <= 0 {
+		return 0
+	}
+	// Equal jitter: keep half of the backoff and randomize the other half
+	half := backoff / 2
+	return half + rand.N(backoff-half+1)
 }
 
 // NewManager creates a new download manager with the given timeout and user agent.
 func NewManager(timeout time.Duration, userAgent string) *ManagerImpl {
 	if userAgent == "" {
-		userAgent = "gotya/1.0"
+		userAgent = DefaultUserAgent
 	}
-	return &ManagerImpl{
+	m := &ManagerImpl{
 		client:    &http.Client{Timeout: timeout},
 		userAgent: userAgent,
 	}
+	m.client.CheckRedirect = m.checkRedirect
+	return m
+}
+
+// SetRetryPolicy sets how failed requests are retried. By default every request is attempted once.
+func (m *ManagerImpl) SetRetryPolicy(policy RetryPolicy) {
+	m.retry = policy
+}
+
+// allowInsecureKey marks request contexts of downloads that may use plain HTTP.
+type allowInsecureKey struct{}
+
+// maxRedirects matches the net/http default redirect limit.
+const maxRedirects = 10
+
+// checkURLScheme refuses plain-HTTP URLs unless allowInsecure is set.
+func checkURLScheme(u *url.URL, allowInsecure bool) error {
+	if !allowInsecure && strings.EqualFold(u.Scheme, "http") {
+		return fmt.Errorf("refusing to download %s over plain HTTP, allow insecure downloads to permit it: %w", u.Redacted(), pkgerrors.ErrInsecureURL)
+	}
+	return nil
+}
+
+// checkRedirect applies the plain-HTTP policy of the original request to redirects and re-evaluates
+// authentication for the redirect target, see redirectAuth.
+func (m *ManagerImpl) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects: %w", maxRedirects, pkgerrors.ErrDownloadFailed)
+	}
+	allowInsecure, _ := req.Context().Value(allowInsecureKey{}).(bool)
+	if err := checkURLScheme(req.URL, allowInsecure); err != nil {
+		return err
+	}
+	return m.redirectAuth(req, via[0])
+}
+
+// redirectAuth replaces the credentials the original request carried with those configured for the
+// redirect target. net/http only drops a few well-known headers on redirects to other hosts, so
+// credentials sent in custom headers would otherwise reach hosts they were not configured for.
+// Redirects to the same host without credentials of their own keep those of the original request.
+func (m *ManagerImpl) redirectAuth(req, original *http.Request) error {
+	target := m.authenticatorFor(req.URL.String())
+	if target == nil && req.URL.Host == original.URL.Host {
+		return nil
+	}
+	if source := m.authenticatorFor(original.URL.String()); source != nil {
+		for _, key := range authHeaderKeys(source) {
+			req.Header.Del(key)
+		}
+	}
+	if target == nil {
+		return nil
+	}
+	return target.Apply(req)
+}
+
+// authHeaderKeys returns the names of the headers authenticator sets.
+func authHeaderKeys(authenticator auth.Authenticator) []string {
+	probe := &http.Request{Header: http.Header{}}
+	if err := authenticator.Apply(probe); err != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(probe.Header))
+	for key := range probe.Header {
+		keys = append(keys, key)
+	}
+	return keys
 }
 
 // SetAuthenticators sets the authenticators for the manager. The mapping is url prefix to authenticator.
@@ -56,7 +148,7 @@ func (m *ManagerImpl) FetchAll(ctx context.Context, items []Item, opts Options)
 		return nil, pkgerrors.Wrap(err, "could not create download dir")
 	}
 
-	byURL, err := buildURLIndex(items)
+	byURL, err := buildURLIndex(items, opts.AllowInsecure)
 	if err != nil {
 		return nil, err
 	}
@@ -67,12 +159,15 @@ func (m *ManagerImpl) FetchAll(ctx context.Context, items []Item, opts Options)
 	return mapResultsByID(items, results), nil
 }
 
-func buildURLIndex(items []Item) (map[string][]int, error) {
+func buildURLIndex(items []Item, allowInsecure bool) (map[string][]int, error) {
 	byURL := make(map[string][]int)
 	for i, it := range items {
 		if it.URL == nil {
 			return nil, fmt.Errorf("item %d has nil URL: %w", i, pkgerrors.ErrDownloadFailed)
 		}
+		if err := checkItemURLs(it, allowInsecure); err != nil {
+			return nil, err
+		}
 		key := it.URL.String()
 		byURL[key] = append(byURL[key], i)
 	}
@@ -147,35 +242,114 @@ func (m *ManagerImpl) fetchOne(ctx context.Context, item Item, opts Options) (st
 	if item.URL == nil {
 		return "", fmt.Errorf("nil URL: %w", pkgerrors.ErrDownloadFailed)
 	}
+	if err := checkItemURLs(item, opts.AllowInsecure); err != nil {
+		return "", err
+	}
 	filename := selectFilename(item)
 	absPath := filepath.Join(opts.Dir, filename)
-	if reuse, ok := tryReuseExisting(absPath, item.Checksum); ok {
-		return reuse, nil
+	if item.Validators == nil {
+		if reuse, ok := tryReuseExisting(absPath, item.Checksum); ok {
+			return reuse, nil
+		}
 	}
-	resp, err := m.doRequest(ctx, item)
-	if err != nil {
-		return "", err
+	headers := revalidationHeaders(opts.Headers, absPath, item.Validators)
+	ctx = context.WithValue(ctx, allowInsecureKey{}, opts.AllowInsecure)
+	if len(item.Mirrors) == 0 {
+		return m.fetchFrom(ctx, item, item.URL, absPath, headers)
 	}
-	defer func() { _ = resp.Body.Close() }()
-	tmpPath, err := writeBodyToTemp(resp, absPath)
+
+	var errs []error
+	for _, source := range append([]*url.URL{item.URL}, item.Mirrors...) {
+		path, err := m.fetchFrom(ctx, item, source, absPath, headers)
+		if err == nil {
+			return path, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return "", fmt.Errorf("download failed from all %d URLs: %w", len(errs), errors.Join(errs...))
+}
+
+// fetchFrom downloads item from source, one of its URL and mirrors, to absPath.
+func (m *ManagerImpl) fetchFrom(ctx context.Context, item Item, source *url.URL, absPath string, headers http.Header) (string, error) {
+	resp, err := m.doRequest(ctx, source, headers)
 	if err != nil {
 		return "", err
 	}
-	if item.Checksum != "" {
-		ok, err := verifySHA256(tmpPath, item.Checksum)
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusNotModified {
+		tmpPath, err := writeBodyToTemp(resp, absPath)
 		if err != nil {
 			return "", err
 		}
-		if !ok {
-			return "", fmt.Errorf("checksum mismatch for %s: %w", item.URL, pkgerrors.ErrFileHashMismatch)
+		if item.Checksum != "" {
+			ok, err := verifySHA256(tmpPath, item.Checksum)
+			if err != nil {
+				_ = os.Remove(tmpPath)
+				return "", err
+			}
+			if !ok {
+				_ = os.Remove(tmpPath)
+				return "", fmt.Errorf("checksum mismatch for %s: %w", source, pkgerrors.ErrFileHashMismatch)
+			}
+		}
+		if err := finalizeFile(tmpPath, absPath); err != nil {
+			return "", err
+		}
+		if item.Validators != nil {
+			*item.Validators = Validators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
 		}
+	} else {
+		item.Validators.NotModified = true
 	}
-	if err := finalizeFile(tmpPath, absPath); err != nil {
-		return "", err
+	if item.ServedBy != nil {
+		*item.ServedBy = *source
 	}
 	return absPath, nil
 }
 
+// checkItemURLs checks the scheme of the URL and the mirrors of item.
+func checkItemURLs(item Item, allowInsecure bool) error {
+	for _, u := range append([]*url.URL{item.URL}, item.Mirrors...) {
+		if u == nil {
+			return fmt.Errorf("item %s has nil URL: %w", item.ID, pkgerrors.ErrDownloadFailed)
+		}
+		if err := checkURLScheme(u, allowInsecure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revalidationHeaders returns headers extended with the conditional request headers for validators.
+// Without a cached file at absPath there is nothing to revalidate and headers are returned unchanged.
+func revalidationHeaders(headers http.Header, absPath string, validators *Validators) http.Header {
+	if validators == nil || (validators.ETag == "" && validators.LastModified == "") {
+		return headers
+	}
+	if st, err := os.Stat(absPath); err != nil || st.Size() == 0 {
+		return headers
+	}
+	conditional := headers.Clone()
+	if conditional == nil {
+		conditional = http.Header{}
+	}
+	if validators.ETag != "" {
+		conditional.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		conditional.Set("If-Modified-Since", validators.LastModified)
+	}
+	return conditional
+}
+
+// CacheFilename returns the name under which the item is stored in the download directory.
+func CacheFilename(item Item) string {
+	return selectFilename(item)
+}
+
 func selectFilename(item Item) string {
 	if item.Filename != "" {
 		return item.Filename
@@ -200,33 +374,90 @@ func tryReuseExisting(absPath, checksum string) (string, bool) {
 	return "", false
 }
 
-func (m *ManagerImpl) doRequest(ctx context.Context, item Item) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, item.URL.String(), http.NoBody)
+// doRequest performs the request for source according to the retry policy. It gives up early when the
+// context would expire before the next attempt.
+func (m *ManagerImpl) doRequest(ctx context.Context, source *url.URL, headers http.Header) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, retryable, err := m.attemptRequest(ctx, source, headers)
+		if err == nil || !retryable || attempt >= m.retry.MaxAttempts {
+			return resp, err
+		}
+		delay := m.retry.delay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// attemptRequest performs a single request for source and reports whether a failure may be retried.
+func (m *ManagerImpl) attemptRequest(ctx context.Context, source *url.URL, headers http.Header) (*http.Response, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.String(), http.NoBody)
 	if err != nil {
-		return nil, pkgerrors.Wrap(err, "failed to create request")
+		return nil, false, pkgerrors.Wrap(err, "failed to create request")
+	}
+	for key, values := range headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
 	}
-	if err := m.applyAuthenticators(req, item.URL.String()); err != nil {
-		return nil, pkgerrors.Wrap(err, "failed to apply authenticators")
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", m.userAgent)
+	}
+	if err := m.applyAuthenticators(req, source.String()); err != nil {
+		return nil, false, pkgerrors.Wrap(err, "failed to apply authenticators")
 	}
-	req.Header.Set("User-Agent", m.userAgent)
 	resp, err := m.client.Do(req)
 	if err != nil {
-		return nil, pkgerrors.Wrap(err, "download failed")
+		// Connection errors are transient, a canceled context or a refused redirect are not
+		retryable := ctx.Err() == nil && !errors.Is(err, pkgerrors.ErrInsecureURL) && !errors.Is(err, pkgerrors.ErrDownloadFailed)
+		return nil, retryable, pkgerrors.Wrap(err, "download failed")
 	}
-	if resp.StatusCode != http.StatusOK {
+	notModified := resp.StatusCode == http.StatusNotModified && (req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "")
+	if resp.StatusCode != http.StatusOK && !notModified {
 		_ = resp.Body.Close()
-		return nil, fmt.Errorf("unexpected status code: %d: %w", resp.StatusCode, pkgerrors.ErrDownloadFailed)
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return nil, retryable, fmt.Errorf("unexpected status code: %d: %w", resp.StatusCode, pkgerrors.ErrDownloadFailed)
 	}
-	return resp, nil
+	return resp, false, nil
 }
 
 func (m *ManagerImpl) applyAuthenticators(req *http.Request, url string) error {
+	if authenticator := m.authenticatorFor(url); authenticator != nil {
+		return authenticator.Apply(req)
+	}
+	return nil
+}
+
+// authenticatorFor returns the authenticator of the longest URL prefix that url lies below, or nil.
+// Prefixes only match at a path boundary, so credentials for https://host/repo are not sent to
+// https://host/repository.
+func (m *ManagerImpl) authenticatorFor(url string) auth.Authenticator {
+	var best auth.Authenticator
+	bestLen := -1
 	for prefix, authenticator := range m.authenticators {
-		if strings.HasPrefix(url, prefix) {
-			return authenticator.Apply(req)
+		if len(prefix) > bestLen && matchesURLPrefix(url, prefix) {
+			best, bestLen = authenticator, len(prefix)
 		}
 	}
-	return nil
+	return best
+}
+
+// matchesURLPrefix reports whether url equals prefix or continues it with a new path segment, query or fragment.
+func matchesURLPrefix(url, prefix string) bool {
+	if !strings.HasPrefix(url, prefix) {
+		return false
+	}
+	if len(url) == len(prefix) || strings.HasSuffix(prefix, "/") {
+		return true
+	}
+	return strings.ContainsRune("/?#", rune(url[len(prefix)]))
 }
 
 func writeBodyToTemp(resp *http.Response, absPath string) (string, error) {
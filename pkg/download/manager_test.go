@@ -9,10 +9,13 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/glorpus-work/gotya/pkg/auth"
+	pkgerrors "github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -102,7 +105,7 @@ func TestFetch_SingleFile(t *testing.T) {
 			tempDir := t.TempDir()
 			m := NewManager(time.Second, "test")
 
-			path, err := m.Fetch(context.Background(), tt.item, Options{Dir: tempDir})
+			path, err := m.Fetch(context.Background(), tt.item, Options{Dir: tempDir, AllowInsecure: true})
 			if tt.expectError {
 				require.Error(t, err)
 				assert.Contains(t, err.Error(), tt.expectErrorMsg)
@@ -164,7 +167,7 @@ func TestFetch_WithChecksum(t *testing.T) {
 			tempDir := t.TempDir()
 			m := NewManager(time.Second, "test")
 
-			_, err = m.Fetch(context.Background(), item, Options{Dir: tempDir})
+			_, err = m.Fetch(context.Background(), item, Options{Dir: tempDir, AllowInsecure: true})
 
 			if tt.expectError {
 				require.Error(t, err)
@@ -229,7 +232,8 @@ func TestFetchAll_Concurrent(t *testing.T) {
 			m := NewManager(5*time.Second, "test")
 
 			opts := Options{
-				Dir: tempDir,
+				Dir:           tempDir,
+				AllowInsecure: true,
 			}
 			if tt.concurrent {
 				opts.Concurrency = 3 // Test with 3 concurrent workers
@@ -373,7 +377,7 @@ func TestFetch_WithAuthentication(t *testing.T) {
 			authenticators := tt.setupAuth(authURL)
 			m.SetAuthenticators(authenticators)
 
-			_, err = m.Fetch(context.Background(), item, Options{Dir: tempDir})
+			_, err = m.Fetch(context.Background(), item, Options{Dir: tempDir, AllowInsecure: true})
 
 			if tt.expectError {
 				require.Error(t, err)
@@ -442,9 +446,362 @@ func TestFetch_ErrorHandling(t *testing.T) {
 			tempDir := t.TempDir()
 			m := NewManager(time.Second, "test")
 
-			_, err := m.Fetch(context.Background(), tt.item, Options{Dir: tempDir})
+			_, err := m.Fetch(context.Background(), tt.item, Options{Dir: tempDir, AllowInsecure: true})
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), tt.expectError)
 		})
 	}
 }
+
+func TestFetchAll_Headers(t *testing.T) {
+	var mu sync.Mutex
+	var observed []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		observed = append(observed, r.Header.Clone())
+		mu.Unlock()
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	items := func() []Item {
+		u1, _ := url.Parse(server.URL + "/a")
+		u2, _ := url.Parse(server.URL + "/b")
+		return []Item{{ID: "a", URL: u1}, {ID: "b", URL: u2}}
+	}
+
+	t.Run("default user agent", func(t *testing.T) {
+		observed = nil
+		m := NewManager(time.Second, "")
+		_, err := m.FetchAll(context.Background(), items(), Options{Dir: t.TempDir(), AllowInsecure: true})
+		require.NoError(t, err)
+		require.Len(t, observed, 2)
+		for _, h := range observed {
+			assert.Equal(t, DefaultUserAgent, h.Get("User-Agent"))
+		}
+	})
+
+	t.Run("configured headers", func(t *testing.T) {
+		observed = nil
+		m := NewManager(time.Second, "")
+		m.SetAuthenticators(map[string]auth.Authenticator{server.URL: &auth.BearerAuth{Token: "secret"}})
+		headers := http.Header{}
+		headers.Set("User-Agent", "acme-ci/2.1")
+		headers.Set("X-Client-Id", "build-42")
+		headers.Set("Authorization", "overridden")
+
+		_, err := m.FetchAll(context.Background(), items(), Options{Dir: t.TempDir(), Headers: headers, AllowInsecure: true})
+		require.NoError(t, err)
+		require.Len(t, observed, 2)
+		for _, h := range observed {
+			assert.Equal(t, "acme-ci/2.1", h.Get("User-Agent"))
+			assert.Equal(t, "build-42", h.Get("X-Client-Id"))
+			assert.Equal(t, "Bearer secret", h.Get("Authorization"), "authentication takes precedence over static headers")
+		}
+	})
+}
+
+func TestFetch_InsecureURLPolicy(t *testing.T) {
+	var hits int
+	var mu sync.Mutex
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		_, _ = w.Write([]byte("content"))
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+
+	plainURL, _ := url.Parse(plain.URL + "/index.json")
+	secureURL, _ := url.Parse(secure.URL + "/index.json")
+
+	newManager := func() *ManagerImpl {
+		m := NewManager(time.Second, "")
+		// Trust the test server's certificate while keeping the manager's redirect policy
+		m.client.Transport = secure.Client().Transport
+		return m
+	}
+
+	t.Run("http refused by default", func(t *testing.T) {
+		hits = 0
+		_, err := newManager().FetchAll(context.Background(), []Item{{ID: "main", URL: plainURL}}, Options{Dir: t.TempDir()})
+		require.ErrorIs(t, err, pkgerrors.ErrInsecureURL)
+		_, err = newManager().Fetch(context.Background(), Item{ID: "main", URL: plainURL}, Options{Dir: t.TempDir()})
+		require.ErrorIs(t, err, pkgerrors.ErrInsecureURL)
+		assert.Zero(t, hits, "no request may be sent")
+	})
+
+	t.Run("http allowed when insecure", func(t *testing.T) {
+		_, err := newManager().FetchAll(context.Background(), []Item{{ID: "main", URL: plainURL}}, Options{Dir: t.TempDir(), AllowInsecure: true})
+		require.NoError(t, err)
+	})
+
+	t.Run("https always allowed", func(t *testing.T) {
+		for _, allowInsecure := range []bool{false, true} {
+			_, err := newManager().FetchAll(context.Background(), []Item{{ID: "main", URL: secureURL}}, Options{Dir: t.TempDir(), AllowInsecure: allowInsecure})
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("redirect to http refused", func(t *testing.T) {
+		redirecting := httptest.NewTLSServer(http.RedirectHandler(plainURL.String(), http.StatusFound))
+		defer redirecting.Close()
+		u, _ := url.Parse(redirecting.URL + "/index.json")
+
+		m := NewManager(time.Second, "")
+		m.client.Transport = redirecting.Client().Transport
+		_, err := m.Fetch(context.Background(), Item{ID: "main", URL: u}, Options{Dir: t.TempDir()})
+		require.ErrorIs(t, err, pkgerrors.ErrInsecureURL)
+	})
+}
+
+func TestFetch_AuthenticatorScope(t *testing.T) {
+	var mu sync.Mutex
+	observed := make(map[string]http.Header)
+	record := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		observed[r.Host+r.URL.Path] = r.Header.Clone()
+		mu.Unlock()
+		_, _ = w.Write([]byte("content"))
+	}
+	cdn := httptest.NewServer(http.HandlerFunc(record))
+	defer cdn.Close()
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repo/cdn.gotya":
+			http.Redirect(w, r, cdn.URL+"/blob", http.StatusFound)
+		case "/repo/moved.gotya":
+			http.Redirect(w, r, "/repo/current.gotya", http.StatusFound)
+		default:
+			record(w, r)
+		}
+	}))
+	defer repo.Close()
+	repoHost := repo.Listener.Addr().String()
+	cdnHost := cdn.Listener.Addr().String()
+
+	m := NewManager(time.Second, "")
+	m.SetAuthenticators(map[string]auth.Authenticator{
+		repo.URL:           &auth.BearerAuth{Token: "server-token"},
+		repo.URL + "/repo": &auth.HeaderAuth{Headers: map[string]string{"X-Api-Key": "repo-key"}},
+	})
+	fetch := func(t *testing.T, path string) {
+		t.Helper()
+		u, err := url.Parse(repo.URL + path)
+		require.NoError(t, err)
+		_, err = m.Fetch(context.Background(), Item{ID: path, URL: u}, Options{Dir: t.TempDir(), AllowInsecure: true})
+		require.NoError(t, err)
+	}
+
+	t.Run("longest prefix wins", func(t *testing.T) {
+		fetch(t, "/repo/app.gotya")
+		assert.Equal(t, "repo-key", observed[repoHost+"/repo/app.gotya"].Get("X-Api-Key"))
+		assert.Empty(t, observed[repoHost+"/repo/app.gotya"].Get("Authorization"))
+	})
+
+	t.Run("prefixes match whole path segments", func(t *testing.T) {
+		fetch(t, "/repository/app.gotya")
+		assert.Empty(t, observed[repoHost+"/repository/app.gotya"].Get("X-Api-Key"))
+		assert.Equal(t, "Bearer server-token", observed[repoHost+"/repository/app.gotya"].Get("Authorization"))
+	})
+
+	t.Run("redirect to another host drops credentials", func(t *testing.T) {
+		fetch(t, "/repo/cdn.gotya")
+		require.Contains(t, observed, cdnHost+"/blob")
+		assert.Empty(t, observed[cdnHost+"/blob"].Get("X-Api-Key"))
+		assert.Empty(t, observed[cdnHost+"/blob"].Get("Authorization"))
+	})
+
+	t.Run("redirect on the same host keeps credentials", func(t *testing.T) {
+		fetch(t, "/repo/moved.gotya")
+		assert.Equal(t, "repo-key", observed[repoHost+"/repo/current.gotya"].Get("X-Api-Key"))
+	})
+}
+
+func TestFetch_Validators(t *testing.T) {
+	var conditional []string
+	body := "v1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditional = append(conditional, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"`+body+`"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"`+body+`"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/index.json")
+	require.NoError(t, err)
+	m := NewManager(time.Second, "")
+	opts := Options{Dir: t.TempDir(), AllowInsecure: true}
+	validators := &Validators{}
+	item := Item{ID: "main", URL: u, Filename: "main.json", Validators: validators}
+
+	path, err := m.Fetch(context.Background(), item, opts)
+	require.NoError(t, err)
+	assert.Equal(t, Validators{ETag: `"v1"`, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"}, *validators)
+
+	// Not modified: the cached file is kept
+	_, err = m.Fetch(context.Background(), item, opts)
+	require.NoError(t, err)
+	assert.True(t, validators.NotModified)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+
+	// Modified: the cached file is replaced instead of being reused
+	body = "v2"
+	_, err = m.Fetch(context.Background(), item, opts)
+	require.NoError(t, err)
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(content))
+	assert.Equal(t, `"v2"`, validators.ETag)
+	assert.False(t, validators.NotModified)
+
+	assert.Equal(t, []string{"", `"v1"`, `"v1"`}, conditional)
+}
+
+func TestFetch_Retry(t *testing.T) {
+	newServer := func(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			n := int(requests.Add(1))
+			if n <= len(statuses) {
+				w.WriteHeader(statuses[n-1])
+				return
+			}
+			_, _ = w.Write([]byte("content"))
+		}))
+		t.Cleanup(server.Close)
+		return server, &requests
+	}
+	fetch := func(ctx context.Context, t *testing.T, m *ManagerImpl, server *httptest.Server) error {
+		u, err := url.Parse(server.URL + "/file")
+		require.NoError(t, err)
+		_, err = m.Fetch(ctx, Item{ID: "file", URL: u}, Options{Dir: t.TempDir(), AllowInsecure: true})
+		return err
+	}
+
+	t.Run("flaky server succeeds on the third attempt", func(t *testing.T) {
+		server, requests := newServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+		m := NewManager(time.Second, "")
+		m.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+		require.NoError(t, fetch(context.Background(), t, m, server))
+		assert.Equal(t, int32(3), requests.Load())
+	})
+
+	t.Run("attempts are limited", func(t *testing.T) {
+		server, requests := newServer(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+		m := NewManager(time.Second, "")
+		m.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+
+		require.ErrorIs(t, fetch(context.Background(), t, m, server), pkgerrors.ErrDownloadFailed)
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("client errors fail fast", func(t *testing.T) {
+		server, requests := newServer(t, http.StatusNotFound)
+		m := NewManager(time.Second, "")
+		m.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+		require.ErrorIs(t, fetch(context.Background(), t, m, server), pkgerrors.ErrDownloadFailed)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("connection errors are retried", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if requests.Add(1) == 1 {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				_ = conn.Close()
+				return
+			}
+			_, _ = w.Write([]byte("content"))
+		}))
+		defer server.Close()
+		m := NewManager(time.Second, "")
+		m.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+
+		require.NoError(t, fetch(context.Background(), t, m, server))
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("retries stop at the context deadline", func(t *testing.T) {
+		server, requests := newServer(t, http.StatusServiceUnavailable)
+		m := NewManager(time.Second, "")
+		m.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour})
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		start := time.Now()
+		require.ErrorIs(t, fetch(ctx, t, m, server), pkgerrors.ErrDownloadFailed)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+}
+
+func TestFetch_Mirrors(t *testing.T) {
+	newServer := func(t *testing.T, status int, body string) (*httptest.Server, *url.URL) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}))
+		t.Cleanup(server.Close)
+		u, err := url.Parse(server.URL + "/file")
+		require.NoError(t, err)
+		return server, u
+	}
+
+	t.Run("falls over to the next mirror", func(t *testing.T) {
+		_, broken := newServer(t, http.StatusInternalServerError, "")
+		_, healthy := newServer(t, http.StatusOK, "content")
+		served := &url.URL{}
+
+		path, err := NewManager(time.Second, "").Fetch(context.Background(), Item{ID: "file", URL: broken, Mirrors: []*url.URL{healthy}, ServedBy: served}, Options{Dir: t.TempDir(), AllowInsecure: true})
+		require.NoError(t, err)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(content))
+		assert.Equal(t, healthy.String(), served.String())
+	})
+
+	t.Run("checksum mismatch falls over to the next mirror", func(t *testing.T) {
+		_, corrupt := newServer(t, http.StatusOK, "corrupt")
+		_, healthy := newServer(t, http.StatusOK, "content")
+		sum := sha256.Sum256([]byte("content"))
+
+		path, err := NewManager(time.Second, "").Fetch(context.Background(), Item{ID: "file", URL: corrupt, Mirrors: []*url.URL{healthy}, Checksum: hex.EncodeToString(sum[:])}, Options{Dir: t.TempDir(), AllowInsecure: true})
+		require.NoError(t, err)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(content))
+	})
+
+	t.Run("lists the failure of every mirror", func(t *testing.T) {
+		_, first := newServer(t, http.StatusInternalServerError, "")
+		_, second := newServer(t, http.StatusNotFound, "")
+
+		_, err := NewManager(time.Second, "").Fetch(context.Background(), Item{ID: "file", URL: first, Mirrors: []*url.URL{second}}, Options{Dir: t.TempDir(), AllowInsecure: true})
+		require.ErrorIs(t, err, pkgerrors.ErrDownloadFailed)
+		assert.Contains(t, err.Error(), first.String()+": unexpected status code: 500")
+		assert.Contains(t, err.Error(), second.String()+": unexpected status code: 404")
+	})
+
+	t.Run("insecure mirrors are refused", func(t *testing.T) {
+		primary, err := url.Parse("https://example.com/file")
+		require.NoError(t, err)
+		_, plain := newServer(t, http.StatusOK, "content")
+
+		_, err = NewManager(time.Second, "").Fetch(context.Background(), Item{ID: "file", URL: primary, Mirrors: []*url.URL{plain}}, Options{Dir: t.TempDir()})
+		require.ErrorIs(t, err, pkgerrors.ErrInsecureURL)
+	})
+}
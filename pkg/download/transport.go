@@ -0,0 +1,163 @@
+package download
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	pkgerrors "github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+// TransportConfig configures the proxy and TLS settings of the connections made by the download manager.
+type TransportConfig struct {
+	// Proxy is the URL of the proxy used for all requests. If empty, the proxy is taken from the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy string
+	// NoProxy lists the hosts connected to directly although Proxy is set, separated by commas or spaces,
+	// with the semantics of NO_PROXY: a host name matches itself and its subdomains, a name with a leading
+	// dot only its subdomains, IP addresses and CIDR ranges match IP hosts, an optional port restricts an
+	// entry to that port and "*" matches all hosts. Loopback hosts are never proxied.
+	NoProxy string
+	// ClientCert and ClientKey are the PEM files of the certificate and private key presented for mutual TLS.
+	ClientCert string
+	ClientKey  string
+	// CABundle is a PEM file of CA certificates trusted in addition to the system roots.
+	CABundle string
+}
+
+// NewTransport returns an HTTP transport for cfg, based on http.DefaultTransport.
+func NewTransport(cfg TransportConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, pkgerrors.Wrapf(pkgerrors.ErrValidation, "invalid proxy URL %q", cfg.Proxy)
+		}
+		noProxy := parseNoProxy(cfg.NoProxy)
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			if noProxy.matches(req.URL) {
+				return nil, nil
+			}
+			return proxyURL, nil
+		}
+	}
+
+	if cfg.ClientCert == "" && cfg.ClientKey == "" && cfg.CABundle == "" {
+		return transport, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		if cfg.ClientCert == "" || cfg.ClientKey == "" {
+			return nil, pkgerrors.Wrap(pkgerrors.ErrValidation, "client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, pkgerrors.Wrapf(pkgerrors.ErrValidation, "failed to load client certificate %s: %v", cfg.ClientCert, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.CABundle != "" {
+		bundle, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, pkgerrors.Wrapf(err, "failed to read CA bundle %s", cfg.CABundle)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, pkgerrors.Wrapf(pkgerrors.ErrValidation, "CA bundle %s contains no PEM certificates", cfg.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// SetTransport makes the manager connect through a transport built from cfg, see NewTransport.
+func (m *ManagerImpl) SetTransport(cfg TransportConfig) error {
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return err
+	}
+	m.client.Transport = transport
+	return nil
+}
+
+// noProxyRule is an entry of a NO_PROXY list.
+type noProxyRule struct {
+	network *net.IPNet
+	ip      net.IP
+	domain  string // Lower case with a leading dot
+	exact   bool   // Whether domain also matches the host without the leading dot
+	port    string // Empty for all ports
+}
+
+// noProxyList is a parsed NO_PROXY list. An empty list matches nothing but loopback hosts.
+type noProxyList struct {
+	all   bool
+	rules []noProxyRule
+}
+
+// parseNoProxy parses a NO_PROXY list, see TransportConfig.NoProxy. Invalid entries are ignored.
+func parseNoProxy(list string) noProxyList {
+	var parsed noProxyList
+	for _, entry := range strings.FieldsFunc(strings.ToLower(list), func(r rune) bool { return r == ',' || r == ' ' }) {
+		if entry == "*" {
+			parsed.all = true
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			parsed.rules = append(parsed.rules, noProxyRule{network: network})
+			continue
+		}
+		var rule noProxyRule
+		if host, port, err := net.SplitHostPort(entry); err == nil {
+			entry, rule.port = host, port
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			rule.ip = ip
+		} else {
+			entry = strings.TrimPrefix(entry, "*")
+			rule.exact = !strings.HasPrefix(entry, ".")
+			rule.domain = "." + strings.TrimPrefix(entry, ".")
+		}
+		parsed.rules = append(parsed.rules, rule)
+	}
+	return parsed
+}
+
+// matches reports whether requests to u bypass the proxy.
+func (l noProxyList) matches(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	ip := net.ParseIP(host)
+	if host == "localhost" || (ip != nil && ip.IsLoopback()) || l.all {
+		return true
+	}
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	for _, rule := range l.rules {
+		if rule.port != "" && rule.port != port {
+			continue
+		}
+		switch {
+		case rule.network != nil:
+			if ip != nil && rule.network.Contains(ip) {
+				return true
+			}
+		case rule.ip != nil:
+			if ip != nil && rule.ip.Equal(ip) {
+				return true
+			}
+		case strings.HasSuffix(host, rule.domain) || (rule.exact && host == rule.domain[1:]):
+			return true
+		}
+	}
+	return false
+}
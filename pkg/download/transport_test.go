@@ -0,0 +1,166 @@
+package download
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransport_Proxy(t *testing.T) {
+	transport, err := NewTransport(TransportConfig{
+		Proxy:   "http://proxy.corp.example:3128",
+		NoProxy: "internal.example.com, .svc.local,10.0.0.0/8 192.168.1.5,registry.example.com:8443",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, transport.Proxy)
+
+	tests := []struct {
+		url     string
+		proxied bool
+	}{
+		{url: "https://repo.example.com/index.json", proxied: true},
+		{url: "https://internal.example.com/index.json"},
+		{url: "https://mirror.internal.example.com/index.json"},
+		{url: "https://INTERNAL.example.com/index.json"},
+		{url: "https://notinternal.example.com/index.json", proxied: true},
+		{url: "https://svc.local/index.json", proxied: true},
+		{url: "https://repo.svc.local/index.json"},
+		{url: "http://10.1.2.3/index.json"},
+		{url: "http://11.1.2.3/index.json", proxied: true},
+		{url: "http://192.168.1.5:8080/index.json"},
+		{url: "https://registry.example.com:8443/index.json"},
+		{url: "https://registry.example.com/index.json", proxied: true},
+		{url: "http://localhost:8080/index.json"},
+		{url: "http://127.0.0.1:8080/index.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, http.NoBody)
+			require.NoError(t, err)
+			proxyURL, err := transport.Proxy(req)
+			require.NoError(t, err)
+			if tt.proxied {
+				require.NotNil(t, proxyURL)
+				assert.Equal(t, "http://proxy.corp.example:3128", proxyURL.String())
+			} else {
+				assert.Nil(t, proxyURL)
+			}
+		})
+	}
+
+	t.Run("wildcard bypasses the proxy for all hosts", func(t *testing.T) {
+		transport, err := NewTransport(TransportConfig{Proxy: "http://proxy.corp.example:3128", NoProxy: "*"})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodGet, "https://repo.example.com/index.json", http.NoBody)
+		require.NoError(t, err)
+		proxyURL, err := transport.Proxy(req)
+		require.NoError(t, err)
+		assert.Nil(t, proxyURL)
+	})
+
+	t.Run("invalid proxy URL", func(t *testing.T) {
+		_, err := NewTransport(TransportConfig{Proxy: "proxy.corp.example"})
+		require.ErrorIs(t, err, pkgerrors.ErrValidation)
+	})
+}
+
+// writeCertificate creates a certificate for commonName signed by parent, or self-signed without a parent,
+// and writes it and its key as PEM files to dir.
+func writeCertificate(t *testing.T, dir, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPath, keyPath := filepath.Join(dir, commonName+".pem"), filepath.Join(dir, commonName+".key")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return cert, key, certPath, keyPath
+}
+
+func TestNewTransport_TLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCA, clientCAKey, _, _ := writeCertificate(t, dir, "client-ca", nil, nil)
+	_, _, clientCert, clientKey := writeCertificate(t, dir, "client", clientCA, clientCAKey)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+	caBundle := filepath.Join(dir, "server-ca.pem")
+	require.NoError(t, os.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+	u, err := url.Parse(server.URL + "/index.json")
+	require.NoError(t, err)
+
+	t.Run("client certificate and CA bundle are wired", func(t *testing.T) {
+		transport, err := NewTransport(TransportConfig{ClientCert: clientCert, ClientKey: clientKey, CABundle: caBundle})
+		require.NoError(t, err)
+		require.NotNil(t, transport.TLSClientConfig)
+		assert.Len(t, transport.TLSClientConfig.Certificates, 1)
+		assert.NotNil(t, transport.TLSClientConfig.RootCAs)
+	})
+
+	t.Run("mutual TLS download", func(t *testing.T) {
+		m := NewManager(time.Second, "")
+		require.NoError(t, m.SetTransport(TransportConfig{ClientCert: clientCert, ClientKey: clientKey, CABundle: caBundle}))
+		path, err := m.Fetch(context.Background(), Item{ID: "index", URL: u}, Options{Dir: t.TempDir()})
+		require.NoError(t, err)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "client", string(content))
+	})
+
+	t.Run("server rejects downloads without client certificate", func(t *testing.T) {
+		m := NewManager(time.Second, "")
+		require.NoError(t, m.SetTransport(TransportConfig{CABundle: caBundle}))
+		_, err := m.Fetch(context.Background(), Item{ID: "index", URL: u}, Options{Dir: t.TempDir()})
+		require.Error(t, err)
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		_, err := NewTransport(TransportConfig{ClientCert: clientCert})
+		require.ErrorIs(t, err, pkgerrors.ErrValidation)
+		_, err = NewTransport(TransportConfig{ClientCert: clientKey, ClientKey: clientKey})
+		require.ErrorIs(t, err, pkgerrors.ErrValidation)
+		_, err = NewTransport(TransportConfig{CABundle: clientKey})
+		require.ErrorIs(t, err, pkgerrors.ErrValidation)
+	})
+}
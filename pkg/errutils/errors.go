@@ -119,6 +119,18 @@ var (
 	// ErrMaxConcurrentInvalid is returned when max_concurrent_syncs is less than 1.
 	ErrMaxConcurrentInvalid = fmt.Errorf("max_concurrent_syncs must be at least 1")
 
+	// ErrDownloadAttemptsNegative is returned when download_attempts is set to a negative value.
+	ErrDownloadAttemptsNegative = fmt.Errorf("download_attempts cannot be negative")
+
+	// ErrDownloadRetryDelayNegative is returned when download_retry_delay is set to a negative value.
+	ErrDownloadRetryDelayNegative = fmt.Errorf("download_retry_delay cannot be negative")
+
+	// ErrHookOutputLimitNegative is returned when hook_output_limit is set to a negative value.
+	ErrHookOutputLimitNegative = fmt.Errorf("hook_output_limit cannot be negative")
+
+	// ErrClientCertIncomplete is returned when only one of client_cert and client_key is set.
+	ErrClientCertIncomplete = fmt.Errorf("client_cert and client_key must be set together")
+
 	// ErrInvalidOutputFormat is returned when an invalid output format is specified.
 	ErrInvalidOutputFormat = fmt.Errorf("invalid output format")
 
@@ -141,6 +153,21 @@ var (
 
 	// ErrDownloadFailed is returned when a download operation fails.
 	ErrDownloadFailed = fmt.Errorf("download failed")
+
+	// ErrInsecureURL is returned when a plain-HTTP URL is used without allowing insecure downloads.
+	ErrInsecureURL = fmt.Errorf("insecure URL")
+
+	// ErrDatabaseReadOnly is returned when the installed database cannot be written.
+	ErrDatabaseReadOnly = fmt.Errorf("installed database is read-only")
+
+	// ErrExtractionTooLarge is returned when an archive exceeds the configured extraction size limits.
+	ErrExtractionTooLarge = fmt.Errorf("extraction size limit exceeded")
+
+	// ErrSymlinkRejected is returned when an archive contains a symlink and the symlink policy rejects them.
+	ErrSymlinkRejected = fmt.Errorf("archive contains a symlink")
+
+	// ErrAlreadyUpToDate is returned when an update would reinstall the version and URL already installed.
+	ErrAlreadyUpToDate = fmt.Errorf("already at the latest version")
 )
 
 // Wrap wraps an error with additional context.
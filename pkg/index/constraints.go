@@ -0,0 +1,134 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// versionBound is one end of a version range together with the constraint that introduced it.
+type versionBound struct {
+	version   string
+	inclusive bool
+	source    string
+}
+
+// versionRange is the intersection of all constraints seen so far.
+// A nil bound means the range is unbounded on that side.
+type versionRange struct {
+	compare  model.VersionComparator
+	lower    *versionBound
+	upper    *versionBound
+	excluded []versionBound
+}
+
+// intersectConstraints computes the intersection of all constraints for an artifact and
+// reports an error naming two conflicting constraints when the intersection is empty.
+// Each entry may itself be a comma separated list of constraints. Versions are ordered by compare.
+func intersectConstraints(name string, constraints []string, compare model.VersionComparator) error {
+	r := versionRange{compare: compare}
+	for _, c := range constraints {
+		for _, part := range strings.Split(c, ",") {
+			// The default constraint matches everything, whatever the version scheme
+			if p := strings.TrimSpace(part); p == "" || p == defaultConstraint {
+				continue
+			}
+			if err := r.add(part, c); err != nil {
+				return fmt.Errorf("invalid version constraint %q for %s: %w", c, name, err)
+			}
+			if a, b, empty := r.conflict(); empty {
+				return fmt.Errorf("no version of %s satisfies both %q and %q (combined constraint: %s): %w",
+					name, a, b, strings.Join(constraints, ", "), ErrUnsatisfiableConstraints)
+			}
+		}
+	}
+	return nil
+}
+
+// add narrows the range by a single constraint part originating from source.
+func (r *versionRange) add(part, source string) error {
+	op, v, ok := model.SplitConstraintPart(part)
+	if !ok {
+		return errutils.Wrapf(errutils.ErrValidation, "malformed constraint %q", part)
+	}
+	// Comparing v with itself rejects versions the comparator cannot order
+	if _, err := r.compare.Compare(v, v); err != nil {
+		return errutils.Wrap(errutils.ErrValidation, err.Error())
+	}
+
+	switch op {
+	case "", "=":
+		r.tightenLower(versionBound{version: v, inclusive: true, source: source})
+		r.tightenUpper(versionBound{version: v, inclusive: true, source: source})
+	case "!=":
+		r.excluded = append(r.excluded, versionBound{version: v, source: source})
+	case ">":
+		r.tightenLower(versionBound{version: v, source: source})
+	case ">=":
+		r.tightenLower(versionBound{version: v, inclusive: true, source: source})
+	case "<":
+		r.tightenUpper(versionBound{version: v, source: source})
+	case "<=":
+		r.tightenUpper(versionBound{version: v, inclusive: true, source: source})
+	case "~>":
+		upper, err := model.PessimisticUpperBound(v)
+		if err != nil {
+			return err
+		}
+		r.tightenLower(versionBound{version: v, inclusive: true, source: source})
+		r.tightenUpper(versionBound{version: upper, source: source})
+	}
+	return nil
+}
+
+// cmp compares two versions that were already accepted by the comparator in add.
+func (r *versionRange) cmp(a, b string) int {
+	c, _ := r.compare.Compare(a, b)
+	return c
+}
+
+// tightenLower replaces the lower bound if b is more restrictive.
+func (r *versionRange) tightenLower(b versionBound) {
+	if r.lower == nil {
+		r.lower = &b
+		return
+	}
+	cmp := r.cmp(b.version, r.lower.version)
+	if cmp > 0 || (cmp == 0 && !b.inclusive && r.lower.inclusive) {
+		r.lower = &b
+	}
+}
+
+// tightenUpper replaces the upper bound if b is more restrictive.
+func (r *versionRange) tightenUpper(b versionBound) {
+	if r.upper == nil {
+		r.upper = &b
+		return
+	}
+	cmp := r.cmp(b.version, r.upper.version)
+	if cmp < 0 || (cmp == 0 && !b.inclusive && r.upper.inclusive) {
+		r.upper = &b
+	}
+}
+
+// conflict reports whether the range is empty and, if so, which two constraints caused it.
+func (r *versionRange) conflict() (string, string, bool) {
+	if r.lower == nil || r.upper == nil {
+		return "", "", false
+	}
+	cmp := r.cmp(r.lower.version, r.upper.version)
+	if cmp > 0 || (cmp == 0 && (!r.lower.inclusive || !r.upper.inclusive)) {
+		return r.lower.source, r.upper.source, true
+	}
+	if cmp == 0 {
+		// The range is a single version; it is empty if that version is excluded.
+		for _, ex := range r.excluded {
+			if r.cmp(ex.version, r.lower.version) == 0 {
+				return r.lower.source, ex.source, true
+			}
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,51 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntersectConstraints(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints []string
+		conflict    []string
+	}{
+		{name: "no constraints", constraints: nil},
+		{name: "overlapping range", constraints: []string{">= 1.2", "< 2.0"}},
+		{name: "touching inclusive bounds", constraints: []string{">= 1.5.0", "<= 1.5.0"}},
+		{name: "exact version inside range", constraints: []string{">= 1.0.0", "= 1.4.0", "< 2.0.0"}},
+		{name: "pessimistic minor", constraints: []string{"~> 1.2", ">= 1.9"}},
+		{name: "disjoint range", constraints: []string{">= 2.0", "< 1.5"}, conflict: []string{">= 2.0", "< 1.5"}},
+		{name: "touching exclusive bound", constraints: []string{"> 1.5.0", "<= 1.5.0"}, conflict: []string{"> 1.5.0", "<= 1.5.0"}},
+		{name: "two exact versions", constraints: []string{"= 1.0.0", "= 2.0.0"}, conflict: []string{"= 2.0.0", "= 1.0.0"}},
+		{name: "pessimistic patch excludes next minor", constraints: []string{"~> 1.2.3", ">= 1.3.0"}, conflict: []string{">= 1.3.0", "~> 1.2.3"}},
+		{name: "pessimistic minor excludes next major", constraints: []string{"~> 1.2", ">= 2.0.0"}, conflict: []string{">= 2.0.0", "~> 1.2"}},
+		{name: "excluded single version", constraints: []string{"= 1.0.0", "!= 1.0.0"}, conflict: []string{"= 1.0.0", "!= 1.0.0"}},
+		{name: "conflict within one constraint", constraints: []string{">= 3.0, < 2.0"}, conflict: []string{">= 3.0, < 2.0", ">= 3.0, < 2.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := intersectConstraints("lib", tt.constraints, model.SemverComparator{})
+			if tt.conflict == nil {
+				assert.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrUnsatisfiableConstraints)
+			for _, c := range tt.conflict {
+				assert.Contains(t, err.Error(), `"`+c+`"`)
+			}
+		})
+	}
+}
+
+func TestIntersectConstraints_Invalid(t *testing.T) {
+	err := intersectConstraints("lib", []string{">= not-a-version"}, model.SemverComparator{})
+	require.ErrorIs(t, err, errutils.ErrValidation)
+	assert.Contains(t, err.Error(), "lib")
+}
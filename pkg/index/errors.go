@@ -8,4 +8,13 @@ import (
 var (
 	// ErrArtifactNotFound is returned when a artifact is not found in any index.
 	ErrArtifactNotFound = fmt.Errorf("artifact not found")
+
+	// ErrUnsatisfiableConstraints is returned when the version constraints for an artifact have no common versions.
+	ErrUnsatisfiableConstraints = fmt.Errorf("unsatisfiable version constraints")
+
+	// ErrMissingChecksum is returned when checksums are required but a resolved descriptor has none.
+	ErrMissingChecksum = fmt.Errorf("artifact descriptor has no checksum")
+
+	// ErrIndexSignatureInvalid is returned when the detached signature of an index does not match it.
+	ErrIndexSignatureInvalid = fmt.Errorf("index signature is invalid")
 )
@@ -35,6 +35,9 @@ type Generator struct {
 	// BaselineIndexPath is the path to an existing index file to use as a baseline.
 	// If provided, only new/changed artifacts will be included in the output.
 	BaselineIndexPath string
+	// MetadataFile is the name of the metadata file in each artifact's meta directory.
+	// It defaults to artifact.DefaultMetadataFile if empty.
+	MetadataFile string
 }
 
 // Generator builds an index.json from a directory of .gotya artifact files.
@@ -151,6 +154,11 @@ func artifactsEqual(a, b *model.IndexArtifactDescriptor) bool {
 		return false
 	}
 
+	// Baseline indexes written before installed sizes were recorded have none
+	if a.InstalledSize != 0 && b.InstalledSize != 0 && a.InstalledSize != b.InstalledSize {
+		return false
+	}
+
 	// Compare dependencies
 	for i := range a.Dependencies {
 		if a.Dependencies[i] != b.Dependencies[i] {
@@ -289,9 +297,13 @@ func (g *Generator) describeArtifact(ctx context.Context, filePath string) (*mod
 	defer func() { _ = os.RemoveAll(tempDir) }()
 
 	// Extract the metadata file from the artifact
+	metadataFile := g.MetadataFile
+	if metadataFile == "" {
+		metadataFile = artifact.DefaultMetadataFile
+	}
 	archiveManager := archive.NewManager()
-	metaFilePath := filepath.Join(tempDir, "artifact.json")
-	err = archiveManager.ExtractFile(ctx, filePath, path.Join("meta", "artifact.json"), metaFilePath)
+	metaFilePath := filepath.Join(tempDir, filepath.Base(metadataFile))
+	err = archiveManager.ExtractFile(ctx, filePath, path.Join("meta", metadataFile), metaFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract metadata: %w", err)
 	}
@@ -313,6 +325,10 @@ func (g *Generator) describeArtifact(ctx context.Context, filePath string) (*mod
 	if err != nil {
 		return nil, err
 	}
+	installedSize, err := installedSize(ctx, archiveManager, filePath)
+	if err != nil {
+		return nil, err
+	}
 	checksum, err := sha256File(filePath)
 	if err != nil {
 		return nil, err
@@ -324,19 +340,38 @@ func (g *Generator) describeArtifact(ctx context.Context, filePath string) (*mod
 	}
 
 	desc := &model.IndexArtifactDescriptor{
-		Name:         md.Name,
-		Version:      md.Version,
-		Description:  md.Description,
-		URL:          urlStr,
-		Checksum:     checksum,
-		Size:         stat.Size(),
-		OS:           md.GetOS(),
-		Arch:         md.GetArch(),
-		Dependencies: md.Dependencies,
+		Name:          md.Name,
+		Version:       md.Version,
+		Description:   md.Description,
+		URL:           urlStr,
+		Checksum:      checksum,
+		Size:          stat.Size(),
+		InstalledSize: installedSize,
+		OS:            md.GetOS(),
+		Arch:          md.GetArch(),
+		Dependencies:  md.Dependencies,
+		// Artifacts packed before manifest digests were introduced have none
+		ManifestDigest: md.ManifestDigest,
 	}
 	return desc, nil
 }
 
+// installedSize returns the total size of the regular files in the artifact file, i.e. the disk space its
+// meta and data files take once installed. Only the archive headers are read.
+func installedSize(ctx context.Context, archiveManager *archive.Manager, filePath string) (int64, error) {
+	entries, err := archiveManager.ListContents(ctx, filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list artifact contents: %w", err)
+	}
+	var size int64
+	for _, entry := range entries {
+		if entry.Mode.IsRegular() {
+			size += entry.Size
+		}
+	}
+	return size, nil
+}
+
 // writeIndex writes the index to the output file.
 func (g *Generator) writeIndex(index *Index) error {
 	// Ensure the output directory exists
@@ -1,13 +1,16 @@
 package index
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/glorpus-work/gotya/pkg/archive"
 	"github.com/glorpus-work/gotya/pkg/artifact"
 	"github.com/glorpus-work/gotya/pkg/model"
 	"github.com/stretchr/testify/assert"
@@ -109,6 +112,7 @@ func TestGenerator_Generate(t *testing.T) {
 				assert.NotEmpty(t, art.URL)
 				assert.NotZero(t, art.Size)
 				assert.NotEmpty(t, art.Checksum)
+				assert.NotEmpty(t, art.ManifestDigest)
 			},
 		},
 		{
@@ -218,6 +222,60 @@ func TestGenerator_describeArtifact(t *testing.T) {
 	}
 }
 
+func TestGenerator_describeArtifact_CustomMetadataFile(t *testing.T) {
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, "meta"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, "data"), 0o755))
+	packer := artifact.NewPacker("custom", "1.0.0", "linux", "amd64", "", "Custom metadata file", nil, nil, inputDir, outputDir)
+	require.NoError(t, packer.SetMetadataFileName("package.json"))
+	path, err := packer.Pack()
+	require.NoError(t, err)
+
+	desc, err := (&Generator{MetadataFile: "package.json"}).describeArtifact(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, "custom@1.0.0", desc.GetID())
+
+	_, err = (&Generator{}).describeArtifact(context.Background(), path)
+	require.Error(t, err, "the default name does not exist in the artifact")
+}
+
+func TestGenerator_ArtifactSizes(t *testing.T) {
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	artifactsDir := filepath.Join(tempDir, "artifacts")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, "data", "sub"), 0o755))
+	require.NoError(t, os.MkdirAll(artifactsDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, "data", "a.bin"), bytes.Repeat([]byte{1}, 1000), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, "data", "sub", "b.bin"), bytes.Repeat([]byte{2}, 234), 0o644))
+	artifactPath, err := artifact.NewPacker("sized", "1.0.0", "linux", "amd64", "", "Sized artifact", nil, nil, inputDir, artifactsDir).Pack()
+	require.NoError(t, err)
+
+	outputPath := filepath.Join(tempDir, "index.json")
+	require.NoError(t, NewGenerator(artifactsDir, outputPath).Generate(context.Background()))
+	index, err := LoadIndex(outputPath)
+	require.NoError(t, err)
+	require.Len(t, index.Artifacts, 1)
+
+	stat, err := os.Stat(artifactPath)
+	require.NoError(t, err)
+	assert.Equal(t, stat.Size(), index.Artifacts[0].Size)
+
+	// The installed size is what the extracted files take, including the metadata file
+	extractDir := filepath.Join(tempDir, "extracted")
+	require.NoError(t, archive.NewManager().ExtractAll(context.Background(), artifactPath, extractDir))
+	var extractedSize int64
+	require.NoError(t, filepath.WalkDir(extractDir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		extractedSize += info.Size()
+		return err
+	}))
+	assert.Equal(t, extractedSize, index.Artifacts[0].InstalledSize)
+	assert.Greater(t, index.Artifacts[0].InstalledSize, int64(1234))
+}
+
 func TestGenerator_WithBaseline(t *testing.T) {
 	// Create a temporary directory for the test
 	tempDir := t.TempDir()
@@ -40,8 +40,14 @@ func (idx *Index) GetArtifacts() []*model.IndexArtifactDescriptor {
 	return idx.Artifacts
 }
 
-// ParseIndex parses an index from JSON data.
+// ParseIndex parses an index from JSON data. Artifact versions must be semantic versions.
 func ParseIndex(data []byte) (*Index, error) {
+	return ParseIndexWithComparator(data, model.SemverComparator{})
+}
+
+// ParseIndexWithComparator parses an index from JSON data and rejects artifact versions compare
+// cannot order. A nil comparator accepts any non-empty version.
+func ParseIndexWithComparator(data []byte, compare model.VersionComparator) (*Index, error) {
 	var index Index
 	if err := json.Unmarshal(data, &index); err != nil {
 		return nil, errutils.Wrap(err, "failed to parse index")
@@ -52,9 +58,59 @@ func ParseIndex(data []byte) (*Index, error) {
 		return nil, fmt.Errorf("missing format version in index: %w", errutils.ErrValidation)
 	}
 
+	if err := index.validateArtifacts(compare); err != nil {
+		return nil, err
+	}
+
 	return &index, nil
 }
 
+// validateArtifacts rejects entries that are missing required descriptor fields.
+// OS and architecture are optional; an empty value means the artifact matches any platform.
+// Versions are checked against compare unless it is nil.
+func (idx *Index) validateArtifacts(compare model.VersionComparator) error {
+	for i, artifact := range idx.Artifacts {
+		if artifact == nil {
+			return fmt.Errorf("invalid index entry %d: entry is null: %w", i, errutils.ErrValidation)
+		}
+		var missing []string
+		if strings.TrimSpace(artifact.Name) == "" {
+			missing = append(missing, "name")
+		}
+		if strings.TrimSpace(artifact.Version) == "" {
+			missing = append(missing, "version")
+		}
+		if strings.TrimSpace(artifact.URL) == "" {
+			missing = append(missing, "url")
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("invalid index entry %d (%s): missing required field(s) %s: %w",
+				i, describeEntry(artifact), strings.Join(missing, ", "), errutils.ErrValidation)
+		}
+		if compare == nil {
+			continue
+		}
+		// Comparing the version with itself rejects versions the comparator cannot order
+		if _, err := compare.Compare(artifact.Version, artifact.Version); err != nil {
+			return fmt.Errorf("invalid index entry %d (%s): malformed version %q: %w",
+				i, describeEntry(artifact), artifact.Version, errutils.ErrValidation)
+		}
+	}
+	return nil
+}
+
+// describeEntry identifies an index entry in error messages as far as its fields allow.
+func describeEntry(artifact *model.IndexArtifactDescriptor) string {
+	switch {
+	case artifact.Name == "":
+		return "unnamed"
+	case artifact.Version == "":
+		return artifact.Name
+	default:
+		return artifact.GetID()
+	}
+}
+
 // ParseIndexFromReader parses an index from an io.Reader.
 func ParseIndexFromReader(reader io.Reader) (*Index, error) {
 	data, err := io.ReadAll(reader)
@@ -67,12 +123,17 @@ func ParseIndexFromReader(reader io.Reader) (*Index, error) {
 
 // ParseIndexFromFile reads and parses an index from the specified file path.
 func ParseIndexFromFile(filePath string) (*Index, error) {
-	file, err := os.Open(filePath)
+	return ParseIndexFromFileWithComparator(filePath, model.SemverComparator{})
+}
+
+// ParseIndexFromFileWithComparator reads and parses an index from the specified file path,
+// validating artifact versions with compare as ParseIndexWithComparator does.
+func ParseIndexFromFileWithComparator(filePath string, compare model.VersionComparator) (*Index, error) {
+	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, errutils.Wrapf(err, "Cannot open index file %s for parsing", filePath)
 	}
-	defer func() { _ = file.Close() }()
-	return ParseIndexFromReader(file)
+	return ParseIndexWithComparator(data, compare)
 }
 
 // ToJSON converts the index to JSON bytes.
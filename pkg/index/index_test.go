@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/glorpus-work/gotya/pkg/model"
 )
 
@@ -191,3 +192,69 @@ func TestWriteIndexToFile(t *testing.T) {
 		assert.NotContains(t, string(data), "existing content")
 	})
 }
+
+func TestParseIndex_Validation(t *testing.T) {
+	tests := []struct {
+		name      string
+		artifacts string
+		wantErr   []string
+	}{
+		{
+			name:      "valid entries",
+			artifacts: `[{"name":"app","version":"1.0.0","os":"linux","arch":"amd64","url":"https://ex/app"},{"name":"any","version":"2.0.0","url":"https://ex/any"}]`,
+		},
+		{
+			name:      "missing url",
+			artifacts: `[{"name":"app","version":"1.0.0","url":"https://ex/app"},{"name":"lib","version":"1.2.0","os":"linux","arch":"amd64"}]`,
+			wantErr:   []string{"entry 1", "lib@1.2.0", "url"},
+		},
+		{
+			name:      "missing name and version",
+			artifacts: `[{"url":"https://ex/x"}]`,
+			wantErr:   []string{"entry 0", "unnamed", "name, version"},
+		},
+		{
+			name:      "malformed version",
+			artifacts: `[{"name":"app","version":"latest","url":"https://ex/app"}]`,
+			wantErr:   []string{"entry 0", "app@latest", "malformed version"},
+		},
+		{
+			name:      "null entry",
+			artifacts: `[null]`,
+			wantErr:   []string{"entry 0", "null"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx, err := ParseIndex([]byte(`{"format_version":"1","packages":` + tt.artifacts + `}`))
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				assert.NotEmpty(t, idx.Artifacts)
+				return
+			}
+			require.ErrorIs(t, err, errutils.ErrValidation)
+			for _, want := range tt.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestParseIndexWithComparator(t *testing.T) {
+	data := []byte(`{"format_version":"1","packages":[{"name":"tool","version":"2024.01","url":"https://ex/tool"},{"name":"tool","version":"nightly","url":"https://ex/nightly"}]}`)
+
+	_, err := ParseIndexWithComparator(data, dateComparator{})
+	require.ErrorIs(t, err, errutils.ErrValidation)
+	assert.Contains(t, err.Error(), "tool@nightly")
+
+	idx, err := ParseIndexWithComparator(data, nil)
+	require.NoError(t, err)
+	assert.Len(t, idx.Artifacts, 2)
+}
+
+func TestParseIndex_WrongFieldType(t *testing.T) {
+	_, err := ParseIndex([]byte(`{"format_version":"1","packages":[{"name":"app","version":1,"url":"https://ex/app"}]}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse index")
+}
@@ -16,9 +16,12 @@ type UintSlice []uint
 
 // ManagerImpl provides artifact management functionality for repositories and indexes.
 type ManagerImpl struct {
-	repositories []*Repository
-	indexPath    string
-	indexes      map[string]*Index
+	repositories   []*Repository
+	indexPath      string
+	indexes        map[string]*Index
+	resolveOptions ResolveOptions
+	// versionComparator orders versions; nil means semantic versioning
+	versionComparator model.VersionComparator
 }
 
 func (x UintSlice) Len() int           { return len(x) }
@@ -83,12 +86,24 @@ func (rm *ManagerImpl) FindArtifacts(name string) (map[string][]*model.IndexArti
 
 // ResolveArtifact finds the best matching artifact for the given name, version, OS, and architecture constraints.
 func (rm *ManagerImpl) ResolveArtifact(name, version, os, arch string) (*model.IndexArtifactDescriptor, error) {
+	return rm.resolveArtifact(name, version, os, arch, false)
+}
+
+// resolveArtifact implements ResolveArtifact. With allowPrerelease, semantic prerelease versions satisfy
+// constraints that do not mention a prerelease, too.
+func (rm *ManagerImpl) resolveArtifact(name, version, os, arch string, allowPrerelease bool) (*model.IndexArtifactDescriptor, error) {
+	normalized, err := model.NormalizeConstraint(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %q for %s: %w", version, name, err)
+	}
+	version = normalized
+
 	repoArtifacts, err := rm.FindArtifacts(name)
 	if err != nil {
 		return nil, err
 	}
 
-	repoPrioArtifacts, err := rm.filterAndGroupByPriority(repoArtifacts, version, os, arch)
+	repoPrioArtifacts, err := rm.filterAndGroupByPriority(repoArtifacts, version, os, arch, allowPrerelease)
 	if err != nil {
 		return nil, err
 	}
@@ -101,21 +116,25 @@ func (rm *ManagerImpl) ResolveArtifact(name, version, os, arch string) (*model.I
 		return nil, fmt.Errorf("artifact %s not found with version constraint %s (available versions: %v, os: %s, arch: %s): %w", name, version, availableVersions, os, arch, ErrArtifactNotFound)
 	}
 
-	finalArtifact := selectBestByPriorityAndVersion(repoPrioArtifacts)
+	finalArtifact := selectBestByPriorityAndVersion(repoPrioArtifacts, rm.comparator())
 	if finalArtifact == nil {
 		return nil, ErrArtifactNotFound
 	}
 
 	desc := &model.IndexArtifactDescriptor{
-		Name:         finalArtifact.Name,
-		Version:      finalArtifact.Version,
-		Description:  finalArtifact.Description,
-		URL:          finalArtifact.URL,
-		Checksum:     finalArtifact.Checksum,
-		Size:         finalArtifact.Size,
-		OS:           finalArtifact.GetOS(),
-		Arch:         finalArtifact.GetArch(),
-		Dependencies: finalArtifact.Dependencies,
+		Name:           finalArtifact.Name,
+		Version:        finalArtifact.Version,
+		Description:    finalArtifact.Description,
+		URL:            finalArtifact.URL,
+		Checksum:       finalArtifact.Checksum,
+		Size:           finalArtifact.Size,
+		OS:             finalArtifact.GetOS(),
+		Arch:           finalArtifact.GetArch(),
+		Dependencies:   finalArtifact.Dependencies,
+		ManifestDigest: finalArtifact.ManifestDigest,
+		InstalledSize:  finalArtifact.InstalledSize,
+		Repository:     finalArtifact.Repository,
+		Mirrors:        finalArtifact.Mirrors,
 	}
 	return desc, nil
 }
@@ -134,13 +153,18 @@ func availableVersionsForPlatform(repoArtifacts map[string][]*model.IndexArtifac
 }
 
 // selectBestByPriorityAndVersion selects the highest-priority, highest-version artifact.
-func selectBestByPriorityAndVersion(repoPrioArtifacts map[uint][]*model.IndexArtifactDescriptor) *model.IndexArtifactDescriptor {
+// Versions the comparator cannot order are never preferred over an already selected artifact.
+func selectBestByPriorityAndVersion(repoPrioArtifacts map[uint][]*model.IndexArtifactDescriptor, compare model.VersionComparator) *model.IndexArtifactDescriptor {
 	prios := slices.Collect(maps.Keys(repoPrioArtifacts))
 	sort.Sort(sort.Reverse(UintSlice(prios)))
 	var finalArtifact *model.IndexArtifactDescriptor
 	for _, prio := range prios {
 		for _, pkg := range repoPrioArtifacts[prio] {
-			if finalArtifact == nil || pkg.GetVersion().GreaterThanOrEqual(finalArtifact.GetVersion()) {
+			if finalArtifact == nil {
+				finalArtifact = pkg
+				continue
+			}
+			if cmp, err := compare.Compare(pkg.Version, finalArtifact.Version); err == nil && cmp >= 0 {
 				finalArtifact = pkg
 			}
 		}
@@ -148,9 +172,45 @@ func selectBestByPriorityAndVersion(repoPrioArtifacts map[uint][]*model.IndexArt
 	return finalArtifact
 }
 
+// SetVersionComparator sets the ordering used to pick the latest version and to evaluate version constraints.
+// Passing nil restores the default semantic version ordering.
+func (rm *ManagerImpl) SetVersionComparator(compare model.VersionComparator) {
+	rm.versionComparator = compare
+}
+
+// comparator returns the configured version comparator or the semantic version default.
+func (rm *ManagerImpl) comparator() model.VersionComparator {
+	if rm.versionComparator == nil {
+		return model.SemverComparator{}
+	}
+	return rm.versionComparator
+}
+
+// matchVersion reports whether pkg satisfies the version constraint under the configured ordering.
+// The default semantic versioning only matches prereleases if the constraint mentions one, unless
+// allowPrerelease is set.
+func (rm *ManagerImpl) matchVersion(pkg *model.IndexArtifactDescriptor, constraint string, allowPrerelease bool) bool {
+	if rm.versionComparator == nil {
+		if allowPrerelease {
+			return model.MatchConstraint(pkg.Version, constraint, model.SemverComparator{})
+		}
+		return pkg.MatchVersion(constraint)
+	}
+	return model.MatchConstraint(pkg.Version, constraint, rm.versionComparator)
+}
+
+// parseIndexFile parses an index file. Versions are only validated for the default semantic versioning;
+// entries a custom ordering cannot compare are skipped during resolution instead.
+func (rm *ManagerImpl) parseIndexFile(path string) (*Index, error) {
+	if rm.versionComparator != nil {
+		return ParseIndexFromFileWithComparator(path, nil)
+	}
+	return ParseIndexFromFile(path)
+}
+
 // GetIndex retrieves the index for a specific repository by name.
 func (rm *ManagerImpl) GetIndex(name string) (*Index, error) {
-	index, err := ParseIndexFromFile(rm.getIndexPath(name))
+	index, err := rm.parseIndexFile(rm.getIndexPath(name))
 	if err != nil {
 		return nil, err
 	}
@@ -169,11 +229,11 @@ func (rm *ManagerImpl) ListRepositories() []*Repository {
 }
 
 // filterAndGroupByPriority filters artifacts by constraints and groups them by repository priority.
-func (rm *ManagerImpl) filterAndGroupByPriority(repoArtifacts map[string][]*model.IndexArtifactDescriptor, version, os, arch string) (map[uint][]*model.IndexArtifactDescriptor, error) {
+func (rm *ManagerImpl) filterAndGroupByPriority(repoArtifacts map[string][]*model.IndexArtifactDescriptor, version, os, arch string, allowPrerelease bool) (map[uint][]*model.IndexArtifactDescriptor, error) {
 	repoPrioArtifacts := make(map[uint][]*model.IndexArtifactDescriptor)
 	for idxName, pkgs := range repoArtifacts {
 		for _, pkg := range pkgs {
-			if !pkg.MatchVersion(version) || !pkg.MatchOs(os) || !pkg.MatchArch(arch) {
+			if !rm.matchVersion(pkg, version, allowPrerelease) || !pkg.MatchOs(os) || !pkg.MatchArch(arch) {
 				continue
 			}
 			repo, err := rm.getRepository(idxName)
@@ -200,10 +260,14 @@ func (rm *ManagerImpl) getIndexes() (map[string]*Index, error) {
 
 func (rm *ManagerImpl) loadIndexes() error {
 	for _, repo := range rm.repositories {
-		index, err := ParseIndexFromFile(rm.getIndexPath(repo.Name))
+		index, err := rm.parseIndexFile(rm.getIndexPath(repo.Name))
 		if err != nil {
 			return err
 		}
+		for _, artifact := range index.Artifacts {
+			artifact.Repository = repo.Name
+			artifact.Mirrors = repo.ArtifactMirrors(artifact.URL)
+		}
 		rm.indexes[repo.Name] = index
 	}
 	return nil
@@ -74,6 +74,7 @@ func TestManager_ResolveArtifact_Basic(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "test-artifact", pkg.Name)
 	assert.Equal(t, "1.0.0", pkg.Version)
+	assert.Equal(t, "test-repo", pkg.Repository)
 }
 
 func TestManager_ResolveArtifact_VersionAcrossPriorities(t *testing.T) {
@@ -92,6 +93,7 @@ func TestManager_ResolveArtifact_VersionAcrossPriorities(t *testing.T) {
 	require.NoError(t, err)
 	// Given current implementation, lower-priority repo can win if version is newer
 	assert.Equal(t, "2.0.0", pkg.Version)
+	assert.Equal(t, "lo", pkg.Repository)
 }
 
 func TestManager_ResolveArtifact_OSArchFilter(t *testing.T) {
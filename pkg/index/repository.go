@@ -1,6 +1,10 @@
 package index
 
-import "net/url"
+import (
+	"crypto/ed25519"
+	"net/url"
+	"strings"
+)
 
 // Repository represents a package repository with a name, URL, priority, and enabled status.
 type Repository struct {
@@ -8,4 +12,45 @@ type Repository struct {
 	URL      *url.URL
 	Priority uint
 	Enabled  bool
+	// ETag and LastModified are the HTTP cache validators of the last synced index. They make
+	// the next sync a conditional request.
+	ETag         string
+	LastModified string
+	// Mirrors are alternative URLs of the index, tried in order when URL fails. Artifacts below the
+	// directory of URL are mirrored below the directory of each mirror.
+	Mirrors []*url.URL
+	// ServedBy is the URL the index was downloaded from by the last sync, nil if it was not downloaded.
+	ServedBy *url.URL
+	// PublicKey, if set, is the trusted key the index is signed with. Sync then requires a detached
+	// signature next to the index, see SignatureURL and VerifyIndexSignature.
+	PublicKey ed25519.PublicKey
+}
+
+// ArtifactMirrors returns the URLs of the artifact at artifactURL on the mirrors of the repository.
+// Artifacts outside the directory of the repository URL have no mirrors.
+func (r *Repository) ArtifactMirrors(artifactURL string) []*url.URL {
+	if r.URL == nil || len(r.Mirrors) == 0 {
+		return nil
+	}
+	rel, ok := strings.CutPrefix(artifactURL, BaseURL(r.URL))
+	if !ok {
+		return nil
+	}
+	mirrors := make([]*url.URL, 0, len(r.Mirrors))
+	for _, mirror := range r.Mirrors {
+		if u, err := url.Parse(BaseURL(mirror) + rel); err == nil {
+			mirrors = append(mirrors, u)
+		}
+	}
+	return mirrors
+}
+
+// BaseURL returns the directory of a repository index URL, with a trailing slash. Relative artifact URLs
+// in the index are relative to it.
+func BaseURL(indexURL *url.URL) string {
+	base := strings.TrimSuffix(indexURL.String(), "/index.json")
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	return base
 }
@@ -3,6 +3,7 @@ package index
 import (
 	"context"
 	"fmt"
+	"maps"
 	slices2 "slices"
 	"strings"
 
@@ -19,6 +20,21 @@ type multiResolver struct {
 	deps        map[string][]string                       // name -> dep names
 	visiting    map[string]struct{}                       // for cycle detection
 	preferences map[string]versionPreference              // name -> version preferences
+	requiredBy  map[string]map[string]string              // name -> dependent name -> constraint
+	skipped     []model.SkippedDependency                 // optional dependencies left out of the plan
+	relax       relaxation                                // constraints relaxed in a fallback pass
+}
+
+// relaxation selects the constraints a fallback resolution pass may relax.
+type relaxation struct {
+	allowPrerelease bool // Select a prerelease if no release satisfies the constraints of an artifact
+	ignoreOptional  bool // Leave all optional dependencies out of the plan
+}
+
+// fallbackRelaxations are tried in order by Resolve with RelaxOnFailure, each relaxing more than the one before.
+var fallbackRelaxations = []relaxation{
+	{allowPrerelease: true},
+	{allowPrerelease: true, ignoreOptional: true},
 }
 
 // versionPreference represents version preference settings for an artifact.
@@ -27,15 +43,32 @@ type versionPreference struct {
 	keepVersion bool
 }
 
-const defaultConstraint = ">= 0.0.0"
+const defaultConstraint = model.AnyVersionConstraint
+
+// ResolveOptions controls additional checks performed by Resolve.
+type ResolveOptions struct {
+	// RequireChecksums makes resolution fail if an artifact in the plan has no checksum in the index.
+	RequireChecksums bool
+	// RelaxOnFailure retries a failed resolution with relaxed constraints: prereleases are selected for
+	// artifacts without a matching release and, if that is not enough, optional dependencies are ignored.
+	// The relaxations are reported in ResolvedArtifacts.Relaxations.
+	RelaxOnFailure bool
+}
+
+// SetResolveOptions sets the options applied to subsequent Resolve calls.
+func (rm *ManagerImpl) SetResolveOptions(opts ResolveOptions) {
+	rm.resolveOptions = opts
+}
 
 // Resolve computes resolved artifacts with dependency resolution for multiple requests.
 // Rules:
 // - Resolve transitive dependencies for all requests.
 // - For each artifact name, select a single version that satisfies all accumulated constraints.
-// - Pick the latest version (by semver) that satisfies constraints and platform filters across all indexes.
+// - Pick the latest version (by the configured VersionComparator, semver by default) that satisfies constraints and platform filters across all indexes.
+// - Accept semver ranges such as "^1.2", "~1.2.3", "1.x" and ">=1.2 <2.0" in constraints, see model.NormalizeConstraint.
 // - Honor KeepVersion preferences where possible, but hard constraints take precedence.
 // - Error if a dependency cannot be found in any index, or if no version satisfies combined constraints.
+// - With ResolveOptions.RelaxOnFailure, retry a failed resolution with relaxed constraints before giving up.
 func (rm *ManagerImpl) Resolve(ctx context.Context, requests []*model.ResolveRequest) (model.ResolvedArtifacts, error) { //nolint:revive // ctx reserved for future
 	_ = ctx // reserved for future use
 
@@ -43,22 +76,63 @@ func (rm *ManagerImpl) Resolve(ctx context.Context, requests []*model.ResolveReq
 		return model.ResolvedArtifacts{}, fmt.Errorf("no resolve requests provided: %w", errutils.ErrValidation)
 	}
 
-	// Normalize version constraints
+	// Normalize version constraints, rewriting semver ranges into plain comparisons
 	for i := range requests {
-		if requests[i].VersionConstraint == "" {
-			requests[i].VersionConstraint = defaultConstraint
+		constraint, err := model.NormalizeConstraint(requests[i].VersionConstraint)
+		if err != nil {
+			return model.ResolvedArtifacts{}, fmt.Errorf("invalid version constraint %q for %s: %w", requests[i].VersionConstraint, requests[i].Name, err)
 		}
+		if constraint == "" {
+			constraint = defaultConstraint
+		}
+		requests[i].VersionConstraint = constraint
 	}
 
+	plan, err := rm.resolvePass(requests, relaxation{})
+	if err == nil || !rm.resolveOptions.RelaxOnFailure {
+		return plan, err
+	}
+	for _, relax := range fallbackRelaxations {
+		if relaxed, relaxedErr := rm.resolvePass(requests, relax); relaxedErr == nil {
+			return relaxed, nil
+		}
+	}
+	// The strict error names the actual conflict
+	return model.ResolvedArtifacts{}, err
+}
+
+// resolvePass resolves requests once with the given relaxation.
+func (rm *ManagerImpl) resolvePass(requests []*model.ResolveRequest, relax relaxation) (model.ResolvedArtifacts, error) {
 	// Delegate to a small resolver helper for clarity.
 	res := newMultiResolver(rm, requests)
+	res.relax = relax
 	if err := res.resolveAll(); err != nil {
 		return model.ResolvedArtifacts{}, err
 	}
 
 	order := res.topoOrder()
 	artifacts := res.resolveArtifacts(order)
-	return model.ResolvedArtifacts{Artifacts: artifacts}, nil
+	if rm.resolveOptions.RequireChecksums {
+		if err := requireChecksums(artifacts); err != nil {
+			return model.ResolvedArtifacts{}, err
+		}
+	}
+	relaxations := res.reportRelaxations(order)
+	return model.ResolvedArtifacts{Artifacts: artifacts, SkippedOptional: res.skipped, Relaxations: relaxations}, nil
+}
+
+// requireChecksums reports all planned artifacts whose index descriptor lacks a checksum.
+func requireChecksums(artifacts []model.ResolvedArtifact) error {
+	var missing []string
+	for _, a := range artifacts {
+		if a.Checksum == "" {
+			missing = append(missing, fmt.Sprintf("%s (%s/%s)", a.GetID(), a.OS, a.Arch))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("checksums are required but missing for %s: %w", strings.Join(missing, ", "), ErrMissingChecksum)
+	}
+	return nil
 }
 
 // --- Internal planning helpers ---
@@ -81,6 +155,7 @@ func newMultiResolver(mgr *ManagerImpl, requests []*model.ResolveRequest) *multi
 		deps:        make(map[string][]string),
 		visiting:    make(map[string]struct{}),
 		preferences: preferences,
+		requiredBy:  make(map[string]map[string]string),
 	}
 }
 
@@ -91,6 +166,39 @@ func (r *multiResolver) addConstraint(name, c string) {
 	r.constraints[name] = append(r.constraints[name], c)
 }
 
+// addRequiredBy records that dependent requires name with the given constraint.
+func (r *multiResolver) addRequiredBy(name, dependent, c string) {
+	if r.requiredBy[name] == nil {
+		r.requiredBy[name] = make(map[string]string)
+	}
+	r.requiredBy[name][dependent] = c
+}
+
+// dependencyReason describes which selected artifacts pulled name in, e.g. "required by app >= 1.2".
+// It returns an empty string if name is not a dependency of any selected artifact.
+func (r *multiResolver) dependencyReason(name string) string {
+	dependents := make([]string, 0, len(r.requiredBy[name]))
+	for dependent := range r.requiredBy[name] {
+		if _, ok := r.selected[dependent]; ok {
+			dependents = append(dependents, dependent)
+		}
+	}
+	if len(dependents) == 0 {
+		return ""
+	}
+	slices2.Sort(dependents)
+
+	parts := make([]string, 0, len(dependents))
+	for _, dependent := range dependents {
+		if c := r.requiredBy[name][dependent]; c != "" {
+			parts = append(parts, dependent+" "+c)
+		} else {
+			parts = append(parts, dependent)
+		}
+	}
+	return "required by " + strings.Join(parts, ", ")
+}
+
 func (r *multiResolver) combineConstraints(list []string) string {
 	// deduplicate while preserving order
 	out := make([]string, 0, len(list))
@@ -135,6 +243,9 @@ func (r *multiResolver) resolveNode(name string) error {
 	r.visiting[name] = struct{}{}
 	defer delete(r.visiting, name)
 
+	if err := intersectConstraints(name, r.constraints[name], r.manager.comparator()); err != nil {
+		return err
+	}
 	constraint := r.combineConstraints(r.constraints[name])
 
 	// Try to honor keep preference by pinning to OldVersion if possible.
@@ -143,18 +254,18 @@ func (r *multiResolver) resolveNode(name string) error {
 	var err error
 	if pref, hasPref := r.preferences[name]; hasPref && pref.keepVersion && pref.oldVersion != "" {
 		pinned := constraint + ", = " + pref.oldVersion
-		if d, e := r.manager.ResolveArtifact(name, pinned, r.getCommonOS(), r.getCommonArch()); e == nil {
+		if d, e := r.resolveArtifact(name, pinned); e == nil {
 			desc = d
 		} else {
 			// fall back to non-pinned constraint
-			desc, err = r.manager.ResolveArtifact(name, constraint, r.getCommonOS(), r.getCommonArch())
+			desc, err = r.resolveArtifact(name, constraint)
 			if err != nil {
 				return err
 			}
 		}
 	} else {
 		// No keep preference, resolve with hard constraint
-		desc, err = r.manager.ResolveArtifact(name, constraint, r.getCommonOS(), r.getCommonArch())
+		desc, err = r.resolveArtifact(name, constraint)
 		if err != nil {
 			return err
 		}
@@ -175,11 +286,20 @@ func (r *multiResolver) resolveNode(name string) error {
 		if desc == nil {
 			return errutils.Wrapf(errutils.ErrArtifactNotFound, "failed to resolve artifact %s: no descriptor returned", name)
 		}
+		// The previously selected version may have had different dependencies
+		for _, dep := range r.deps[name] {
+			delete(r.requiredBy[dep], name)
+		}
 		r.deps[name] = nil
 		for _, d := range desc.Dependencies {
-			r.deps[name] = append(r.deps[name], d.Name)
-			r.addConstraint(d.Name, d.VersionConstraint)
-			if err := r.resolveNode(d.Name); err != nil {
+			if d.Optional && r.relax.ignoreOptional {
+				continue
+			}
+			if d.Optional {
+				r.resolveOptional(name, d)
+				continue
+			}
+			if err := r.resolveDependency(name, d); err != nil {
 				return err
 			}
 		}
@@ -187,6 +307,104 @@ func (r *multiResolver) resolveNode(name string) error {
 	return nil
 }
 
+// resolveArtifact selects the best artifact satisfying constraint. Prereleases are only considered
+// if the pass allows them and no release matches.
+func (r *multiResolver) resolveArtifact(name, constraint string) (*model.IndexArtifactDescriptor, error) {
+	desc, err := r.manager.ResolveArtifact(name, constraint, r.getCommonOS(), r.getCommonArch())
+	if err == nil || !r.relax.allowPrerelease {
+		return desc, err
+	}
+	if relaxed, relaxedErr := r.manager.resolveArtifact(name, constraint, r.getCommonOS(), r.getCommonArch(), true); relaxedErr == nil {
+		return relaxed, nil
+	}
+	return nil, err
+}
+
+// reportRelaxations describes how the selected artifacts, in order, depend on the relaxed constraints of
+// the pass. Ignored optional dependencies are also recorded as skipped.
+func (r *multiResolver) reportRelaxations(order []string) []string {
+	var out []string
+	for _, name := range order {
+		d := r.selected[name]
+		if d == nil {
+			continue
+		}
+		constraint := r.combineConstraints(r.constraints[name])
+		if r.relax.allowPrerelease && !r.manager.matchVersion(d, constraint, false) {
+			out = append(out, fmt.Sprintf("selected prerelease %s for %s %s", d.GetID(), name, constraint))
+		}
+		if !r.relax.ignoreOptional {
+			continue
+		}
+		for _, dep := range d.Dependencies {
+			if dep.Optional && !slices2.Contains(r.deps[name], dep.Name) {
+				out = append(out, fmt.Sprintf("ignored optional dependency %s of %s", dep.Name, name))
+				r.skipped = append(r.skipped, model.SkippedDependency{Name: dep.Name, RequiredBy: name, Reason: "ignored by relaxed resolution"})
+			}
+		}
+	}
+	return out
+}
+
+// resolveDependency records d as a dependency of name and resolves it.
+func (r *multiResolver) resolveDependency(name string, d model.Dependency) error {
+	constraint, err := model.NormalizeConstraint(d.VersionConstraint)
+	if err != nil {
+		return fmt.Errorf("invalid version constraint %q for dependency %s of %s: %w", d.VersionConstraint, d.Name, name, err)
+	}
+	r.deps[name] = append(r.deps[name], d.Name)
+	r.addConstraint(d.Name, constraint)
+	r.addRequiredBy(d.Name, name, d.VersionConstraint)
+	return r.resolveNode(d.Name)
+}
+
+// resolveOptional tries to resolve an optional dependency of name. If it cannot be resolved,
+// all state changes made by the attempt are rolled back and the dependency is recorded as skipped.
+func (r *multiResolver) resolveOptional(name string, d model.Dependency) {
+	saved := r.snapshot()
+	if err := r.resolveDependency(name, d); err != nil {
+		r.restore(saved)
+		r.skipped = append(r.skipped, model.SkippedDependency{Name: d.Name, RequiredBy: name, Reason: err.Error()})
+	}
+}
+
+// resolverState is a copy of the mutable resolver state used to roll back failed optional resolutions.
+type resolverState struct {
+	constraints map[string][]string
+	selected    map[string]*model.IndexArtifactDescriptor
+	deps        map[string][]string
+	requiredBy  map[string]map[string]string
+	skipped     []model.SkippedDependency
+}
+
+func (r *multiResolver) snapshot() resolverState {
+	st := resolverState{
+		constraints: make(map[string][]string, len(r.constraints)),
+		selected:    maps.Clone(r.selected),
+		deps:        make(map[string][]string, len(r.deps)),
+		requiredBy:  make(map[string]map[string]string, len(r.requiredBy)),
+		skipped:     slices2.Clone(r.skipped),
+	}
+	for k, v := range r.constraints {
+		st.constraints[k] = slices2.Clone(v)
+	}
+	for k, v := range r.deps {
+		st.deps[k] = slices2.Clone(v)
+	}
+	for k, v := range r.requiredBy {
+		st.requiredBy[k] = maps.Clone(v)
+	}
+	return st
+}
+
+func (r *multiResolver) restore(st resolverState) {
+	r.constraints = st.constraints
+	r.selected = st.selected
+	r.deps = st.deps
+	r.requiredBy = st.requiredBy
+	r.skipped = st.skipped
+}
+
 func (r *multiResolver) getCommonOS() string {
 	osSet := make(map[string]bool)
 	for _, req := range r.requests {
@@ -248,6 +466,13 @@ func (r *multiResolver) topoOrder() []string {
 	return order
 }
 
+// isRequested reports whether name was explicitly requested.
+func (r *multiResolver) isRequested(name string) bool {
+	return slices2.ContainsFunc(r.requests, func(req *model.ResolveRequest) bool {
+		return req.Name == name
+	})
+}
+
 func (r *multiResolver) resolveArtifacts(order []string) []model.ResolvedArtifact {
 	steps := make([]model.ResolvedArtifact, 0, len(order))
 	for _, name := range order {
@@ -267,22 +492,39 @@ func (r *multiResolver) resolveArtifacts(order []string) []model.ResolvedArtifac
 		// Determine the action to take for artifacts that need changes
 		action := model.ResolvedActionInstall
 		reason := "new artifact installation"
+		if !r.isRequested(name) {
+			if depReason := r.dependencyReason(name); depReason != "" {
+				reason = depReason
+			}
+		}
 
 		// Check if this artifact has a preference (indicating it was already installed)
 		if pref, hasPref := r.preferences[name]; hasPref && pref.oldVersion != "" {
 			action = model.ResolvedActionUpdate
 			reason = fmt.Sprintf("updating from %s to %s", pref.oldVersion, d.Version)
+			if cmp, err := r.manager.comparator().Compare(d.Version, pref.oldVersion); err == nil && cmp < 0 {
+				reason = fmt.Sprintf("downgrading from %s to %s", pref.oldVersion, d.Version)
+			}
+		}
+
+		var deps []string
+		for _, dep := range d.Dependencies {
+			deps = append(deps, dep.Name)
 		}
 
 		steps = append(steps, model.ResolvedArtifact{
-			Name:      d.Name,
-			Version:   d.Version,
-			OS:        d.GetOS(),
-			Arch:      d.GetArch(),
-			SourceURL: d.GetURL(),
-			Checksum:  d.Checksum,
-			Action:    action,
-			Reason:    reason,
+			Name:           d.Name,
+			Version:        d.Version,
+			OS:             d.GetOS(),
+			Arch:           d.GetArch(),
+			SourceURL:      d.GetURL(),
+			Checksum:       d.Checksum,
+			ManifestDigest: d.ManifestDigest,
+			Action:         action,
+			Reason:         reason,
+			Dependencies:   deps,
+			Repository:     d.Repository,
+			Mirrors:        d.Mirrors,
 		})
 	}
 	return steps
@@ -2,8 +2,11 @@ package index
 
 import (
 	"context"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/glorpus-work/gotya/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -81,6 +84,9 @@ func TestResolve_SimpleDependencyChain(t *testing.T) {
 	assert.Equal(t, "c@1.0.0", plan.Artifacts[0].GetID())
 	assert.Equal(t, "b@1.0.0", plan.Artifacts[1].GetID())
 	assert.Equal(t, "a@1.0.0", plan.Artifacts[2].GetID())
+	assert.Empty(t, plan.Artifacts[0].Dependencies)
+	assert.Equal(t, []string{"c"}, plan.Artifacts[1].Dependencies)
+	assert.Equal(t, []string{"b"}, plan.Artifacts[2].Dependencies)
 }
 
 func TestResolve_VersionConflictResolution(t *testing.T) {
@@ -254,6 +260,7 @@ func TestResolve_NoDependencies(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, plan.Artifacts, 1)
 	assert.Equal(t, "standalone@1.0.0", plan.Artifacts[0].GetID())
+	assert.Equal(t, "test-repo", plan.Artifacts[0].Repository)
 }
 
 func TestResolve_NonExistentPackage(t *testing.T) {
@@ -608,3 +615,405 @@ func TestResolve_EmptyRequestsList(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "no resolve requests provided")
 }
+
+func TestResolve_SharedDependencyConstraintIntersection(t *testing.T) {
+	artifacts := func(libAConstraint, libBConstraint string) string {
+		return `[
+		{"name":"tool-a","version":"1.0.0","dependencies":[{"name":"shared","version_constraint":"` + libAConstraint + `"}],"url":"https://ex/tool-a","checksum":"ta1"},
+		{"name":"tool-b","version":"1.0.0","dependencies":[{"name":"shared","version_constraint":"` + libBConstraint + `"}],"url":"https://ex/tool-b","checksum":"tb1"},
+		{"name":"shared","version":"1.0.0","url":"https://ex/shared-1.0","checksum":"s10"},
+		{"name":"shared","version":"1.2.0","url":"https://ex/shared-1.2","checksum":"s12"},
+		{"name":"shared","version":"1.9.0","url":"https://ex/shared-1.9","checksum":"s19"},
+		{"name":"shared","version":"2.0.0","url":"https://ex/shared-2.0","checksum":"s20"}
+	]`
+	}
+	requests := func() []*model.ResolveRequest {
+		return []*model.ResolveRequest{
+			{Name: "tool-a", OS: "linux", Arch: "amd64"},
+			{Name: "tool-b", OS: "linux", Arch: "amd64"},
+		}
+	}
+
+	t.Run("compatible constraints pick highest version in intersection", func(t *testing.T) {
+		mgr := setupTestManager(t, artifacts(">= 1.2", "< 2.0"))
+
+		plan, err := mgr.Resolve(context.Background(), requests())
+		require.NoError(t, err)
+
+		var shared *model.ResolvedArtifact
+		for i := range plan.Artifacts {
+			if plan.Artifacts[i].Name == "shared" {
+				shared = &plan.Artifacts[i]
+			}
+		}
+		require.NotNil(t, shared)
+		assert.Equal(t, "1.9.0", shared.Version)
+	})
+
+	t.Run("pessimistic constraint is intersected", func(t *testing.T) {
+		mgr := setupTestManager(t, artifacts("~> 1.0", "> 1.0.0"))
+
+		plan, err := mgr.Resolve(context.Background(), requests())
+		require.NoError(t, err)
+		assert.Contains(t, idsOf(plan), "shared@1.9.0")
+	})
+
+	t.Run("incompatible constraints name both constraints", func(t *testing.T) {
+		mgr := setupTestManager(t, artifacts(">= 2.0", "< 1.5"))
+
+		_, err := mgr.Resolve(context.Background(), requests())
+		require.ErrorIs(t, err, ErrUnsatisfiableConstraints)
+		assert.Contains(t, err.Error(), "shared")
+		assert.Contains(t, err.Error(), `">= 2.0"`)
+		assert.Contains(t, err.Error(), `"< 1.5"`)
+	})
+}
+
+func TestResolve_DependencyReason(t *testing.T) {
+	mgr := setupTestManager(t, `[
+		{"name":"app","version":"1.0.0","dependencies":[{"name":"lib","version_constraint":">= 1.2"},{"name":"util"}],"url":"https://ex/app","checksum":"a1"},
+		{"name":"tool","version":"1.0.0","dependencies":[{"name":"lib","version_constraint":"< 2.0"}],"url":"https://ex/tool","checksum":"t1"},
+		{"name":"lib","version":"1.5.0","url":"https://ex/lib","checksum":"l15"},
+		{"name":"util","version":"1.0.0","url":"https://ex/util","checksum":"u1"}
+	]`)
+
+	plan, err := mgr.Resolve(context.Background(), []*model.ResolveRequest{
+		{Name: "app", OS: "linux", Arch: "amd64"},
+		{Name: "tool", OS: "linux", Arch: "amd64"},
+	})
+	require.NoError(t, err)
+
+	reasons := make(map[string]string, len(plan.Artifacts))
+	for _, a := range plan.Artifacts {
+		reasons[a.Name] = a.Reason
+	}
+	assert.Equal(t, "new artifact installation", reasons["app"])
+	assert.Equal(t, "new artifact installation", reasons["tool"])
+	assert.Equal(t, "required by app >= 1.2, tool < 2.0", reasons["lib"])
+	assert.Equal(t, "required by app", reasons["util"])
+}
+
+func TestResolve_OptionalDependencies(t *testing.T) {
+	mgr := setupTestManager(t, `[
+		{"name":"app","version":"1.0.0","dependencies":[
+			{"name":"core"},
+			{"name":"plugin","version_constraint":">= 1.0","optional":true},
+			{"name":"extras","version_constraint":">= 3.0","optional":true},
+			{"name":"missing","optional":true}
+		],"url":"https://ex/app","checksum":"a1"},
+		{"name":"core","version":"1.0.0","url":"https://ex/core","checksum":"c1"},
+		{"name":"plugin","version":"1.1.0","dependencies":[{"name":"plugin-lib"}],"url":"https://ex/plugin","checksum":"p1"},
+		{"name":"plugin-lib","version":"1.0.0","url":"https://ex/plugin-lib","checksum":"pl1"},
+		{"name":"extras","version":"2.0.0","dependencies":[{"name":"extras-lib"}],"url":"https://ex/extras","checksum":"e2"},
+		{"name":"extras-lib","version":"1.0.0","url":"https://ex/extras-lib","checksum":"el1"}
+	]`)
+
+	plan, err := mgr.Resolve(context.Background(), []*model.ResolveRequest{{Name: "app", OS: "linux", Arch: "amd64"}})
+	require.NoError(t, err)
+
+	ids := idsOf(plan)
+	assert.ElementsMatch(t, []string{"core@1.0.0", "plugin-lib@1.0.0", "plugin@1.1.0", "app@1.0.0"}, ids,
+		"satisfiable optional dependency is installed, unsatisfiable ones are left out")
+	assert.Less(t, slices.Index(ids, "plugin@1.1.0"), slices.Index(ids, "app@1.0.0"))
+
+	require.Len(t, plan.SkippedOptional, 2)
+	skipped := map[string]model.SkippedDependency{}
+	for _, s := range plan.SkippedOptional {
+		skipped[s.Name] = s
+	}
+	assert.Equal(t, "app", skipped["extras"].RequiredBy)
+	assert.NotEmpty(t, skipped["extras"].Reason)
+	assert.Equal(t, "app", skipped["missing"].RequiredBy)
+}
+
+func TestResolve_OptionalDependencyRequiredElsewhere(t *testing.T) {
+	mgr := setupTestManager(t, `[
+		{"name":"app","version":"1.0.0","dependencies":[{"name":"lib","version_constraint":">= 5.0","optional":true}],"url":"https://ex/app","checksum":"a1"},
+		{"name":"tool","version":"1.0.0","dependencies":[{"name":"lib"}],"url":"https://ex/tool","checksum":"t1"},
+		{"name":"lib","version":"1.0.0","url":"https://ex/lib","checksum":"l1"}
+	]`)
+
+	plan, err := mgr.Resolve(context.Background(), []*model.ResolveRequest{
+		{Name: "app", OS: "linux", Arch: "amd64"},
+		{Name: "tool", OS: "linux", Arch: "amd64"},
+	})
+	require.NoError(t, err, "a failed optional constraint must not leak into hard requirements")
+	assert.Contains(t, idsOf(plan), "lib@1.0.0")
+	require.Len(t, plan.SkippedOptional, 1)
+	assert.Equal(t, "lib", plan.SkippedOptional[0].Name)
+}
+
+func TestResolve_UpdateRequiresNewerInstalledDependency(t *testing.T) {
+	mgr := setupTestManager(t, `[
+		{"name":"app","version":"1.0.0","dependencies":[{"name":"lib","version_constraint":">= 1.0"}],"url":"https://ex/app-1","checksum":"a1"},
+		{"name":"app","version":"2.0.0","dependencies":[{"name":"lib","version_constraint":">= 2.0"}],"url":"https://ex/app-2","checksum":"a2"},
+		{"name":"lib","version":"1.0.0","url":"https://ex/lib-1","checksum":"l1"},
+		{"name":"lib","version":"2.0.0","url":"https://ex/lib-2","checksum":"l2"}
+	]`)
+
+	plan, err := mgr.Resolve(context.Background(), []*model.ResolveRequest{
+		{Name: "app", OldVersion: "1.0.0", OS: "linux", Arch: "amd64"},
+		{Name: "lib", OldVersion: "1.0.0", KeepVersion: true, OS: "linux", Arch: "amd64"},
+	})
+	require.NoError(t, err)
+
+	ids := idsOf(plan)
+	assert.Equal(t, []string{"lib@2.0.0", "app@2.0.0"}, ids, "the dependency must be updated before the dependent")
+	for _, step := range plan.Artifacts {
+		assert.Equal(t, model.ResolvedActionUpdate, step.Action)
+	}
+}
+
+func TestResolve_RequireChecksums(t *testing.T) {
+	artifacts := `[
+		{"name":"app","version":"1.0.0","dependencies":[{"name":"lib"}],"url":"https://ex/app","checksum":"a1"},
+		{"name":"lib","version":"1.0.0","url":"https://ex/lib"},
+		{"name":"other","version":"1.0.0","url":"https://ex/other"}
+	]`
+	requests := func() []*model.ResolveRequest {
+		return []*model.ResolveRequest{{Name: "app", OS: "linux", Arch: "amd64"}}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		mgr := setupTestManager(t, artifacts)
+		plan, err := mgr.Resolve(context.Background(), requests())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"lib@1.0.0", "app@1.0.0"}, idsOf(plan))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		mgr := setupTestManager(t, artifacts)
+		mgr.SetResolveOptions(ResolveOptions{RequireChecksums: true})
+		_, err := mgr.Resolve(context.Background(), requests())
+		require.ErrorIs(t, err, ErrMissingChecksum)
+		assert.Contains(t, err.Error(), "lib@1.0.0")
+		assert.NotContains(t, err.Error(), "app@1.0.0")
+		assert.NotContains(t, err.Error(), "other", "only descriptors in the plan are checked")
+	})
+}
+
+func TestResolve_RelaxOnFailure(t *testing.T) {
+	t.Run("prerelease", func(t *testing.T) {
+		artifacts := `[
+			{"name":"app","version":"1.0.0","dependencies":[{"name":"lib","version_constraint":">= 1.0"},{"name":"util"}],"url":"https://ex/app","checksum":"a1"},
+			{"name":"lib","version":"0.9.0","url":"https://ex/lib-0.9","checksum":"l0"},
+			{"name":"lib","version":"2.0.0-rc1","url":"https://ex/lib-2-rc1","checksum":"l2"},
+			{"name":"util","version":"1.0.0","url":"https://ex/util-1","checksum":"u1"},
+			{"name":"util","version":"1.1.0-beta","url":"https://ex/util-1.1-beta","checksum":"u2"}
+		]`
+		requests := []*model.ResolveRequest{{Name: "app", OS: "linux", Arch: "amd64"}}
+
+		mgr := setupTestManager(t, artifacts)
+		_, err := mgr.Resolve(context.Background(), requests)
+		require.ErrorIs(t, err, ErrArtifactNotFound, "prereleases are not selected by strict resolution")
+
+		mgr.SetResolveOptions(ResolveOptions{RelaxOnFailure: true})
+		plan, err := mgr.Resolve(context.Background(), requests)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"lib@2.0.0-rc1", "util@1.0.0", "app@1.0.0"}, idsOf(plan),
+			"releases are still preferred where one matches")
+		assert.Equal(t, []string{"selected prerelease lib@2.0.0-rc1 for lib >= 1.0"}, plan.Relaxations)
+		assert.Empty(t, plan.SkippedOptional)
+	})
+
+	t.Run("optional dependencies", func(t *testing.T) {
+		mgr := setupTestManager(t, `[
+			{"name":"app","version":"1.0.0","dependencies":[
+				{"name":"plugin","optional":true},
+				{"name":"core"}
+			],"url":"https://ex/app","checksum":"a1"},
+			{"name":"plugin","version":"1.0.0","dependencies":[{"name":"shared","version_constraint":">= 2.0"}],"url":"https://ex/plugin","checksum":"p1"},
+			{"name":"core","version":"1.0.0","dependencies":[{"name":"shared","version_constraint":"< 2.0"}],"url":"https://ex/core","checksum":"c1"},
+			{"name":"shared","version":"1.0.0","url":"https://ex/shared-1","checksum":"s1"},
+			{"name":"shared","version":"2.0.0","url":"https://ex/shared-2","checksum":"s2"}
+		]`)
+		requests := []*model.ResolveRequest{{Name: "app", OS: "linux", Arch: "amd64"}}
+
+		_, err := mgr.Resolve(context.Background(), requests)
+		require.ErrorIs(t, err, ErrUnsatisfiableConstraints, "the resolved optional dependency constrains shared")
+
+		mgr.SetResolveOptions(ResolveOptions{RelaxOnFailure: true})
+		plan, err := mgr.Resolve(context.Background(), requests)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"shared@1.0.0", "core@1.0.0", "app@1.0.0"}, idsOf(plan))
+		assert.Equal(t, []string{"ignored optional dependency plugin of app"}, plan.Relaxations)
+		require.Len(t, plan.SkippedOptional, 1)
+		assert.Equal(t, "plugin", plan.SkippedOptional[0].Name)
+		assert.Equal(t, "app", plan.SkippedOptional[0].RequiredBy)
+	})
+
+	t.Run("unsatisfiable", func(t *testing.T) {
+		mgr := setupTestManager(t, `[
+			{"name":"app","version":"1.0.0","dependencies":[{"name":"lib","version_constraint":">= 3.0"}],"url":"https://ex/app","checksum":"a1"},
+			{"name":"lib","version":"1.0.0","url":"https://ex/lib","checksum":"l1"}
+		]`)
+		mgr.SetResolveOptions(ResolveOptions{RelaxOnFailure: true})
+		_, err := mgr.Resolve(context.Background(), []*model.ResolveRequest{{Name: "app", OS: "linux", Arch: "amd64"}})
+		require.ErrorIs(t, err, ErrArtifactNotFound)
+		assert.Contains(t, err.Error(), ">= 3.0", "the error of the strict pass is reported")
+	})
+}
+
+// dateComparator orders calendar versions of the form YYYY.MM.
+type dateComparator struct{}
+
+func (dateComparator) Compare(a, b string) (int, error) {
+	ta, err := time.Parse("2006.01", a)
+	if err != nil {
+		return 0, err
+	}
+	tb, err := time.Parse("2006.01", b)
+	if err != nil {
+		return 0, err
+	}
+	return ta.Compare(tb), nil
+}
+
+func TestResolve_CustomVersionComparator(t *testing.T) {
+	// 2024.4 is a valid semantic version newer than 2024.03, but not a date version
+	artifacts := `[
+		{"name":"tool","version":"2023.12","url":"https://ex/tool-2023.12","checksum":"t1"},
+		{"name":"tool","version":"2024.01","url":"https://ex/tool-2024.01","checksum":"t2"},
+		{"name":"tool","version":"2024.03","url":"https://ex/tool-2024.03","checksum":"t3"},
+		{"name":"tool","version":"2024.4","url":"https://ex/tool-2024.4","checksum":"t4"}
+	]`
+	resolve := func(mgr *ManagerImpl, req *model.ResolveRequest) (model.ResolvedArtifacts, error) {
+		req.Name, req.OS, req.Arch = "tool", "linux", "amd64"
+		return mgr.Resolve(context.Background(), []*model.ResolveRequest{req})
+	}
+
+	t.Run("default semver", func(t *testing.T) {
+		plan, err := resolve(setupTestManager(t, artifacts), &model.ResolveRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tool@2024.4"}, idsOf(plan))
+	})
+
+	t.Run("latest date", func(t *testing.T) {
+		mgr := setupTestManager(t, artifacts)
+		mgr.SetVersionComparator(dateComparator{})
+		plan, err := resolve(mgr, &model.ResolveRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tool@2024.03"}, idsOf(plan))
+	})
+
+	t.Run("versions the comparator cannot order are skipped", func(t *testing.T) {
+		mgr := setupTestManager(t, `[
+			{"name":"tool","version":"2024.01","url":"https://ex/tool-2024.01","checksum":"t1"},
+			{"name":"tool","version":"nightly","url":"https://ex/tool-nightly","checksum":"t2"}
+		]`)
+		mgr.SetVersionComparator(dateComparator{})
+		plan, err := resolve(mgr, &model.ResolveRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tool@2024.01"}, idsOf(plan))
+	})
+
+	t.Run("constraint", func(t *testing.T) {
+		mgr := setupTestManager(t, artifacts)
+		mgr.SetVersionComparator(dateComparator{})
+		plan, err := resolve(mgr, &model.ResolveRequest{VersionConstraint: ">= 2023.12, < 2024.03"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tool@2024.01"}, idsOf(plan))
+
+		_, err = resolve(mgr, &model.ResolveRequest{VersionConstraint: ">= 2024.03, < 2024.01"})
+		require.ErrorIs(t, err, ErrUnsatisfiableConstraints)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		mgr := setupTestManager(t, artifacts)
+		mgr.SetVersionComparator(dateComparator{})
+		plan, err := resolve(mgr, &model.ResolveRequest{VersionConstraint: ">= 2024.01", OldVersion: "2024.01"})
+		require.NoError(t, err)
+		require.Len(t, plan.Artifacts, 1)
+		assert.Equal(t, "updating from 2024.01 to 2024.03", plan.Artifacts[0].Reason)
+
+		// Updates require a version at least as new as the installed one
+		_, err = resolve(mgr, &model.ResolveRequest{VersionConstraint: ">= 2024.05", OldVersion: "2024.05"})
+		require.ErrorIs(t, err, ErrArtifactNotFound)
+	})
+
+	t.Run("downgrade", func(t *testing.T) {
+		mgr := setupTestManager(t, artifacts)
+		mgr.SetVersionComparator(dateComparator{})
+		plan, err := resolve(mgr, &model.ResolveRequest{OldVersion: "2024.05"})
+		require.NoError(t, err)
+		require.Len(t, plan.Artifacts, 1)
+		assert.Equal(t, model.ResolvedActionUpdate, plan.Artifacts[0].Action)
+		assert.Equal(t, "downgrading from 2024.05 to 2024.03", plan.Artifacts[0].Reason)
+	})
+}
+
+func TestResolve_SemverRanges(t *testing.T) {
+	dir := t.TempDir()
+	writeIndexFile(t, dir, "stable", `[
+		{"name":"tool","version":"0.2.1","url":"https://ex/tool-0.2.1","checksum":"t021"},
+		{"name":"tool","version":"0.3.0","url":"https://ex/tool-0.3.0","checksum":"t030"},
+		{"name":"tool","version":"1.0.0","url":"https://ex/tool-1.0.0","checksum":"t100"},
+		{"name":"tool","version":"1.2.0","url":"https://ex/tool-1.2.0","checksum":"t120"},
+		{"name":"tool","version":"1.2.5","url":"https://ex/tool-1.2.5","checksum":"t125"},
+		{"name":"app","version":"1.0.0","dependencies":[{"name":"tool","version_constraint":"^1.0"}],"url":"https://ex/app","checksum":"a1"}
+	]`)
+	writeIndexFile(t, dir, "testing", `[
+		{"name":"tool","version":"1.3.0","url":"https://ex/tool-1.3.0","checksum":"t130"},
+		{"name":"tool","version":"1.9.9","url":"https://ex/tool-1.9.9","checksum":"t199"},
+		{"name":"tool","version":"2.0.0-rc.1","url":"https://ex/tool-2.0.0-rc.1","checksum":"t200rc1"},
+		{"name":"tool","version":"2.1.0","url":"https://ex/tool-2.1.0","checksum":"t210"}
+	]`)
+	mgr := NewManager([]*Repository{{Name: "stable", Priority: 10}, {Name: "testing", Priority: 1}}, dir)
+
+	tests := []struct {
+		constraint string
+		expected   string
+	}{
+		{constraint: "^1.2", expected: "tool@1.9.9"},
+		{constraint: "^1.2.5", expected: "tool@1.9.9"},
+		{constraint: "^0.2", expected: "tool@0.2.1"},
+		{constraint: "~1.2.3", expected: "tool@1.2.5"},
+		{constraint: "~1.2", expected: "tool@1.2.5"},
+		{constraint: "~1", expected: "tool@1.9.9"},
+		{constraint: "1.x", expected: "tool@1.9.9"},
+		{constraint: "1.2.*", expected: "tool@1.2.5"},
+		{constraint: "*", expected: "tool@2.1.0"},
+		{constraint: ">=1.2 <2.0", expected: "tool@1.9.9"},
+		{constraint: ">= 1.0.0, < 1.3", expected: "tool@1.2.5"},
+		{constraint: "^1.0 <1.2.5", expected: "tool@1.2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			plan, err := mgr.Resolve(context.Background(), []*model.ResolveRequest{
+				{Name: "tool", VersionConstraint: tt.constraint, OS: "linux", Arch: "amd64"},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []string{tt.expected}, idsOf(plan))
+		})
+	}
+
+	t.Run("ranges of dependencies are intersected", func(t *testing.T) {
+		plan, err := mgr.Resolve(context.Background(), []*model.ResolveRequest{
+			{Name: "app", OS: "linux", Arch: "amd64"},
+			{Name: "tool", VersionConstraint: "~1.2", OS: "linux", Arch: "amd64"},
+		})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"app@1.0.0", "tool@1.2.5"}, idsOf(plan))
+		for _, a := range plan.Artifacts {
+			if a.Name == "tool" {
+				assert.Equal(t, "stable", a.Repository)
+			}
+		}
+	})
+
+	t.Run("invalid range names the request", func(t *testing.T) {
+		_, err := mgr.Resolve(context.Background(), []*model.ResolveRequest{
+			{Name: "tool", VersionConstraint: "^1.x.3", OS: "linux", Arch: "amd64"},
+		})
+		require.ErrorIs(t, err, errutils.ErrValidation)
+		assert.Contains(t, err.Error(), `invalid version constraint "^1.x.3" for tool`)
+	})
+}
+
+func idsOf(plan model.ResolvedArtifacts) []string {
+	ids := make([]string, 0, len(plan.Artifacts))
+	for _, a := range plan.Artifacts {
+		ids = append(ids, a.GetID())
+	}
+	return ids
+}
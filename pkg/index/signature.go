@@ -0,0 +1,48 @@
+package index
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+// SignatureURL returns the URL of the detached signature of the index at indexURL, which is indexURL with
+// ".sig" appended to its path.
+func SignatureURL(indexURL *url.URL) *url.URL {
+	sigURL := *indexURL
+	sigURL.Path += ".sig"
+	if sigURL.RawPath != "" {
+		sigURL.RawPath += ".sig"
+	}
+	return &sigURL
+}
+
+// SignIndex returns the detached signature of the index bytes data: the base64 encoded ed25519 signature of
+// their SHA-256 digest, like the signature of artifacts.
+func SignIndex(data []byte, key ed25519.PrivateKey) []byte {
+	digest := sha256.Sum256(data)
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, digest[:])) + "\n")
+}
+
+// VerifyIndexSignature checks that signature is a detached signature of the index bytes data made with the
+// private key of key, see SignIndex. It fails with an errutils.ErrValidation error wrapping
+// ErrIndexSignatureInvalid otherwise.
+func VerifyIndexSignature(data, signature []byte, key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return errutils.Wrap(errutils.ErrValidation, "invalid ed25519 public key")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		return fmt.Errorf("%w: %w: signature is not base64 encoded", errutils.ErrValidation, ErrIndexSignatureInvalid)
+	}
+	digest := sha256.Sum256(data)
+	if !ed25519.Verify(key, digest[:], decoded) {
+		return fmt.Errorf("%w: %w", errutils.ErrValidation, ErrIndexSignatureInvalid)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package index
+
+import (
+	"crypto/ed25519"
+	"net/url"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyIndexSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	data := []byte(`{"format_version":"1","packages":[]}`)
+	signature := SignIndex(data, priv)
+
+	require.NoError(t, VerifyIndexSignature(data, signature, pub))
+
+	tests := []struct {
+		name      string
+		data      []byte
+		signature []byte
+		key       ed25519.PublicKey
+	}{
+		{name: "tampered index", data: []byte(`{"format_version":"1","packages":[{}]}`), signature: signature, key: pub},
+		{name: "other key", data: data, signature: signature, key: otherPub},
+		{name: "not base64", data: data, signature: []byte("not a signature!"), key: pub},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyIndexSignature(tt.data, tt.signature, tt.key)
+			require.ErrorIs(t, err, errutils.ErrValidation)
+			require.ErrorIs(t, err, ErrIndexSignatureInvalid)
+		})
+	}
+
+	require.ErrorIs(t, VerifyIndexSignature(data, signature, ed25519.PublicKey{1, 2, 3}), errutils.ErrValidation)
+}
+
+func TestSignatureURL(t *testing.T) {
+	u, err := url.Parse("https://example.com/repo/index.json?token=abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/repo/index.json.sig?token=abc", SignatureURL(u).String())
+	assert.Equal(t, "https://example.com/repo/index.json?token=abc", u.String(), "the index URL is not modified")
+}
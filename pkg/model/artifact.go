@@ -17,6 +17,8 @@ import (
 type Dependency struct {
 	Name              string `json:"name"`
 	VersionConstraint string `json:"version_constraint,omitempty"`
+	// Optional dependencies are installed when they can be resolved, but do not fail resolution otherwise.
+	Optional bool `json:"optional,omitempty"`
 }
 
 // IndexArtifactDescriptor represents the metadata and properties of an indexed artifact in a repository or package.
@@ -26,10 +28,44 @@ type IndexArtifactDescriptor struct {
 	Description  string       `json:"description"`
 	URL          string       `json:"url"`
 	Checksum     string       `json:"checksum"`
-	Size         int64        `json:"size"`
+	Size         int64        `json:"size"` // Size of the artifact file in bytes
 	OS           string       `json:"os,omitempty"`
 	Arch         string       `json:"arch,omitempty"`
 	Dependencies []Dependency `json:"dependencies,omitempty"`
+	// ManifestDigest is a digest over the artifact's sorted file list and file hashes; equal digests mean equal content
+	ManifestDigest string `json:"manifest_digest,omitempty"`
+	// InstalledSize is the total size of the files of the artifact once extracted, 0 if unknown
+	InstalledSize int64 `json:"installed_size,omitempty"`
+	// Repository is the name of the repository whose index lists the artifact. It is set when indexes are
+	// loaded and is not part of the index format.
+	Repository string `json:"-"`
+	// Mirrors are the URLs of the artifact on the mirrors of its repository, see Repository. They are set
+	// like Repository.
+	Mirrors []*url.URL `json:"-"`
+}
+
+// ArtifactKey identifies an artifact independently of its version.
+// Different versions of the same artifact for the same platform share a key.
+type ArtifactKey struct {
+	Name string
+	OS   string
+	Arch string
+}
+
+// String returns the key in the form name/os/arch.
+func (k ArtifactKey) String() string {
+	return k.Name + "/" + k.OS + "/" + k.Arch
+}
+
+// newArtifactKey builds a key, normalizing empty platform fields to their "any" values.
+func newArtifactKey(name, os, arch string) ArtifactKey {
+	if os == "" {
+		os = platform.AnyOS
+	}
+	if arch == "" {
+		arch = platform.AnyArch
+	}
+	return ArtifactKey{Name: name, OS: os, Arch: arch}
 }
 
 // InstallationReason tracks why an artifact was installed
@@ -53,8 +89,13 @@ func (a *IndexArtifactDescriptor) MatchArch(arch string) bool {
 }
 
 // MatchVersion checks if this artifact's version satisfies the given version constraint.
+// Semver ranges are supported, see NormalizeConstraint.
 func (a *IndexArtifactDescriptor) MatchVersion(versionConstraint string) bool {
-	constraint, err := version.NewConstraint(versionConstraint)
+	normalized, err := NormalizeConstraint(versionConstraint)
+	if err != nil {
+		return false
+	}
+	constraint, err := version.NewConstraint(normalized)
 	if err != nil {
 		return false
 	}
@@ -90,6 +131,16 @@ func (a *IndexArtifactDescriptor) GetArch() string {
 	return a.Arch
 }
 
+// ArtifactKey returns the version-independent identity of this artifact.
+func (a *IndexArtifactDescriptor) ArtifactKey() ArtifactKey {
+	return newArtifactKey(a.Name, a.OS, a.Arch)
+}
+
+// GetID returns the unique identifier for this artifact version (name@version).
+func (a *IndexArtifactDescriptor) GetID() string {
+	return a.Name + "@" + a.Version
+}
+
 // GetURL returns the parsed URL of this artifact.
 func (a *IndexArtifactDescriptor) GetURL() *url.URL {
 	parse, err := url.Parse(a.URL)
@@ -99,11 +150,16 @@ func (a *IndexArtifactDescriptor) GetURL() *url.URL {
 	return parse
 }
 
-// Verify checks if this artifact descriptor is valid.
+// Verify checks if this artifact descriptor is valid, with name and version limited by DefaultNameLimits.
 func (a *IndexArtifactDescriptor) Verify() error {
+	return a.VerifyWithLimits(DefaultNameLimits)
+}
+
+// VerifyWithLimits checks if this artifact descriptor is valid, with name and version limited by limits.
+func (a *IndexArtifactDescriptor) VerifyWithLimits(limits NameLimits) error {
 	if a.Name == "" || a.Version == "" || a.URL == "" {
 		return errutils.ErrValidation
 
 	}
-	return nil
+	return limits.Check(a.Name, a.Version)
 }
@@ -1,8 +1,10 @@
 package model
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/glorpus-work/gotya/pkg/platform"
 	"github.com/hashicorp/go-version"
 	"github.com/stretchr/testify/assert"
@@ -243,3 +245,32 @@ func TestArtifact_GetArch(t *testing.T) {
 		})
 	}
 }
+
+func TestArtifact_ArtifactKey(t *testing.T) {
+	v1 := &IndexArtifactDescriptor{Name: "tool", Version: "1.0.0"}
+	v2 := &IndexArtifactDescriptor{Name: "tool", Version: "2.0.0", OS: platform.AnyOS, Arch: platform.AnyArch}
+
+	assert.Equal(t, v1.ArtifactKey(), v2.ArtifactKey(), "empty platform fields should normalize to any")
+	assert.NotEqual(t, v1.GetID(), v2.GetID())
+	assert.Equal(t, ArtifactKey{Name: "tool", OS: platform.AnyOS, Arch: platform.AnyArch}, v1.ArtifactKey())
+}
+
+func TestIndexArtifactDescriptor_VerifyNameLimits(t *testing.T) {
+	desc := func(name, version string) *IndexArtifactDescriptor {
+		return &IndexArtifactDescriptor{Name: name, Version: version, URL: "http://example.com/a.gotya"}
+	}
+
+	require.NoError(t, desc("tool", "1.0.0").Verify())
+	require.NoError(t, desc(strings.Repeat("a", DefaultMaxNameLength), "1.0.0").Verify())
+
+	err := desc(strings.Repeat("a", DefaultMaxNameLength+1), "1.0.0").Verify()
+	require.ErrorIs(t, err, errutils.ErrValidation)
+	assert.Contains(t, err.Error(), "the maximum is 128")
+	require.ErrorIs(t, desc("tool", "1.0.0-"+strings.Repeat("1", DefaultMaxVersionLength)).Verify(), errutils.ErrValidation)
+
+	limits := NameLimits{MaxNameLength: 4, MaxVersionLength: 5}
+	require.NoError(t, desc("tool", "1.0.0").VerifyWithLimits(limits))
+	require.ErrorIs(t, desc("tools", "1.0.0").VerifyWithLimits(limits), errutils.ErrValidation)
+	require.ErrorIs(t, desc("tool", "1.0.10").VerifyWithLimits(limits), errutils.ErrValidation)
+	require.NoError(t, desc(strings.Repeat("a", 1000), "1.0.0").VerifyWithLimits(NameLimits{}), "zero limits disable the bounds")
+}
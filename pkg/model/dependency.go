@@ -0,0 +1,59 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+// constraintOperatorChars are the characters a version constraint following a dependency name can start with.
+const constraintOperatorChars = " \t<>=!"
+
+// ParseDependency parses a dependency declared as "name", "name:constraint" or "name constraint",
+// e.g. "libfoo:>= 1.2.0" or "libfoo >= 1.2.0". Without a constraint, VersionConstraint is empty.
+func ParseDependency(s string) (Dependency, error) {
+	s = strings.TrimSpace(s)
+	name, constraint, hasConstraint := strings.Cut(s, ":")
+	if !hasConstraint {
+		i := strings.IndexAny(s, constraintOperatorChars)
+		if j := strings.Index(s, "~>"); j >= 0 && (i < 0 || j < i) {
+			i = j
+		}
+		if i >= 0 {
+			name, constraint, hasConstraint = s[:i], s[i:], true
+		}
+	}
+
+	name = strings.TrimSpace(name)
+	constraint = strings.TrimSpace(constraint)
+	if name == "" {
+		return Dependency{}, errutils.Wrapf(errutils.ErrValidation, "invalid dependency %q: empty name", s)
+	}
+	if hasConstraint && constraint == "" {
+		return Dependency{}, errutils.Wrapf(errutils.ErrValidation, "invalid dependency %q: empty version constraint for %s", s, name)
+	}
+	return Dependency{Name: name, VersionConstraint: constraint}, nil
+}
+
+// UnmarshalJSON decodes a dependency object or, as written by older packers, a string parsed with ParseDependency.
+func (d *Dependency) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		dep, err := ParseDependency(s)
+		if err != nil {
+			return err
+		}
+		*d = dep
+		return nil
+	}
+
+	// The alias type has no methods, so decoding it does not recurse into UnmarshalJSON
+	type dependency Dependency
+	var dep dependency
+	if err := json.Unmarshal(data, &dep); err != nil {
+		return err
+	}
+	*d = Dependency(dep)
+	return nil
+}
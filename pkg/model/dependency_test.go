@@ -0,0 +1,59 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDependency(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Dependency
+	}{
+		{"libfoo", Dependency{Name: "libfoo"}},
+		{" libfoo ", Dependency{Name: "libfoo"}},
+		{"libfoo:>= 1.2.0", Dependency{Name: "libfoo", VersionConstraint: ">= 1.2.0"}},
+		{"libfoo >= 1.2.0", Dependency{Name: "libfoo", VersionConstraint: ">= 1.2.0"}},
+		{"libfoo>=1.2.0, <2.0.0", Dependency{Name: "libfoo", VersionConstraint: ">=1.2.0, <2.0.0"}},
+		{"libfoo ~> 1.2", Dependency{Name: "libfoo", VersionConstraint: "~> 1.2"}},
+		{"lib~foo~>1.2", Dependency{Name: "lib~foo", VersionConstraint: "~>1.2"}},
+		{"libfoo != 1.3.0", Dependency{Name: "libfoo", VersionConstraint: "!= 1.3.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			dep, err := ParseDependency(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, dep)
+		})
+	}
+
+	for _, input := range []string{"", ">= 1.0.0", ":>= 1.0.0", "libfoo:", "libfoo: "} {
+		_, err := ParseDependency(input)
+		require.ErrorIs(t, err, errutils.ErrValidation, input)
+	}
+}
+
+func TestDependency_UnmarshalJSON(t *testing.T) {
+	var deps []Dependency
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"name":"libfoo","version_constraint":">= 1.2.0","optional":true},
+		"libbar",
+		"libbaz >= 2.0.0"
+	]`), &deps))
+	assert.Equal(t, []Dependency{
+		{Name: "libfoo", VersionConstraint: ">= 1.2.0", Optional: true},
+		{Name: "libbar"},
+		{Name: "libbaz", VersionConstraint: ">= 2.0.0"},
+	}, deps)
+
+	data, err := json.Marshal(deps[2])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"libbaz","version_constraint":">= 2.0.0"}`, string(data), "dependencies are always written as objects")
+
+	require.Error(t, json.Unmarshal([]byte(`[42]`), &deps))
+	require.ErrorIs(t, json.Unmarshal([]byte(`[""]`), &deps), errutils.ErrValidation)
+}
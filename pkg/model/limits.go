@@ -0,0 +1,39 @@
+package model
+
+import (
+	"github.com/glorpus-work/gotya/pkg/errutils"
+)
+
+const (
+	// DefaultMaxNameLength is the default maximum length of artifact names in bytes.
+	DefaultMaxNameLength = 128
+	// DefaultMaxVersionLength is the default maximum length of artifact versions in bytes.
+	DefaultMaxVersionLength = 64
+)
+
+// DefaultNameLimits are the limits used by IndexArtifactDescriptor.Verify. With them, artifact file
+// names and install directories stay well below the 255 bytes most filesystems allow per path element.
+var DefaultNameLimits = NameLimits{MaxNameLength: DefaultMaxNameLength, MaxVersionLength: DefaultMaxVersionLength}
+
+// NameLimits bounds the length of artifact names and versions. Values <= 0 disable the respective bound.
+type NameLimits struct {
+	MaxNameLength    int
+	MaxVersionLength int
+}
+
+// Check returns an errutils.ErrValidation error if name or version is empty or exceeds its maximum length.
+func (l NameLimits) Check(name, version string) error {
+	for _, field := range []struct {
+		kind, value string
+		max         int
+	}{{"name", name, l.MaxNameLength}, {"version", version, l.MaxVersionLength}} {
+		if field.value == "" {
+			return errutils.Wrapf(errutils.ErrValidation, "artifact %s cannot be empty", field.kind)
+		}
+		if field.max > 0 && len(field.value) > field.max {
+			return errutils.Wrapf(errutils.ErrValidation, "artifact %s %.20q... is %d bytes long, the maximum is %d",
+				field.kind, field.value, len(field.value), field.max)
+		}
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/url"
 	"time"
 )
@@ -17,14 +19,57 @@ type ResolveRequest struct {
 
 // ResolvedArtifact represents a concrete installation action.
 type ResolvedArtifact struct {
-	Name      string
-	Version   string
-	OS        string
-	Arch      string
-	SourceURL *url.URL
-	Checksum  string
-	Action    ResolvedAction
-	Reason    string
+	Name      string   `json:"name"`
+	Version   string   `json:"version"`
+	OS        string   `json:"os"`
+	Arch      string   `json:"arch"`
+	SourceURL *url.URL `json:"-"` // encoded as "url", see MarshalJSON
+	Checksum  string   `json:"checksum,omitempty"`
+	// ManifestDigest is the content digest from the index descriptor, if known
+	ManifestDigest string         `json:"manifest_digest,omitempty"`
+	Action         ResolvedAction `json:"action"`
+	Reason         string         `json:"reason,omitempty"`
+	// Dependencies lists the names of the artifact's dependencies; steps for them come earlier in a plan
+	Dependencies []string `json:"dependencies,omitempty"`
+	// Repository is the name of the repository the artifact was resolved from
+	Repository string `json:"repository,omitempty"`
+	// Mirrors are alternative source URLs on the mirrors of the repository, tried in order when SourceURL fails
+	Mirrors []*url.URL `json:"-"`
+}
+
+// resolvedArtifactJSON is the JSON form of ResolvedArtifact with the source URL as a string.
+type resolvedArtifactJSON struct {
+	resolvedArtifactFields
+	URL string `json:"url,omitempty"`
+}
+
+// resolvedArtifactFields has the fields of ResolvedArtifact but not its JSON methods.
+type resolvedArtifactFields ResolvedArtifact
+
+// MarshalJSON encodes the artifact with its source URL as a string.
+func (ra ResolvedArtifact) MarshalJSON() ([]byte, error) {
+	out := resolvedArtifactJSON{resolvedArtifactFields: resolvedArtifactFields(ra)}
+	if ra.SourceURL != nil {
+		out.URL = ra.SourceURL.String()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes an artifact encoded by MarshalJSON.
+func (ra *ResolvedArtifact) UnmarshalJSON(data []byte) error {
+	var in resolvedArtifactJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*ra = ResolvedArtifact(in.resolvedArtifactFields)
+	if in.URL != "" {
+		u, err := url.Parse(in.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url for %s: %w", ra.GetID(), err)
+		}
+		ra.SourceURL = u
+	}
+	return nil
 }
 
 // ResolvedAction represents the type of action to take for an artifact.
@@ -32,13 +77,27 @@ type ResolvedAction string
 
 // ResolvedArtifacts is an ordered list of steps, topologically sorted if dependencies are present.
 type ResolvedArtifacts struct {
-	Artifacts []ResolvedArtifact
+	Artifacts []ResolvedArtifact `json:"artifacts"`
+	// SkippedOptional lists optional dependencies that could not be resolved and were left out of the plan.
+	SkippedOptional []SkippedDependency `json:"skipped_optional,omitempty"`
+	// Relaxations describes the constraints relaxed to find the plan after strict resolution failed.
+	Relaxations []string `json:"relaxations,omitempty"`
+}
+
+// SkippedDependency describes an optional dependency that was not included in a plan.
+type SkippedDependency struct {
+	Name       string `json:"name"`        // Name of the optional dependency
+	RequiredBy string `json:"required_by"` // Name of the artifact declaring the dependency
+	Reason     string `json:"reason"`      // Why the dependency could not be resolved
 }
 
 // InstalledFile represents a file installed by an artifact with its hash.
 type InstalledFile struct {
 	Path string // Relative path from its base directory
 	Hash string // SHA256 hash of the file contents
+	// LinkedTo is the absolute path of the identical file of another artifact this file is hardlinked to,
+	// empty if the file is not shared
+	LinkedTo string `json:",omitempty"`
 }
 
 // ArtifactStatus represents the status of an installed artifact.
@@ -53,6 +112,7 @@ type InstalledArtifact struct {
 	Arch                string // target architecture
 	InstalledAt         time.Time
 	InstalledFrom       string // URL or index where it was installed from
+	SourceRepository    string // Name of the repository the artifact was resolved from, empty for local installs
 	ArtifactMetaDir     string // Base directory for meta files
 	ArtifactDataDir     string // Base directory for data files
 	MetaFiles           []InstalledFile
@@ -60,7 +120,12 @@ type InstalledArtifact struct {
 	ReverseDependencies []string       // List of artifact names that depend on this artifact
 	Status              ArtifactStatus // Status of the artifact
 	Checksum            string
+	ManifestDigest      string             // Digest over the installed file list and hashes, empty for older installs
 	InstallationReason  InstallationReason // Why this artifact was installed
+	Essential           bool               // Essential artifacts are protected from removal
+	InstallationDetail  string             // Human-readable explanation of the reason, e.g. "required by app >= 1.2"
+	// PostInstallHookError is the error of a post-install hook that failed while the artifact was kept installed
+	PostInstallHookError string
 }
 
 const (
@@ -79,3 +144,19 @@ const (
 func (ra *ResolvedArtifact) GetID() string {
 	return ra.Name + "@" + ra.Version
 }
+
+// ArtifactKey returns the version-independent identity of this artifact.
+func (ra *ResolvedArtifact) ArtifactKey() ArtifactKey {
+	return newArtifactKey(ra.Name, ra.OS, ra.Arch)
+}
+
+// ArtifactKey returns the version-independent identity of this installed artifact.
+func (ia *InstalledArtifact) ArtifactKey() ArtifactKey {
+	return newArtifactKey(ia.Name, ia.OS, ia.Arch)
+}
+
+// HasSameContent reports whether the installed artifact has the given manifest digest.
+// It is false if either digest is unknown, so artifacts without digests are never considered equal.
+func (ia *InstalledArtifact) HasSameContent(manifestDigest string) bool {
+	return ia.ManifestDigest != "" && ia.ManifestDigest == manifestDigest
+}
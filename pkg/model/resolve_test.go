@@ -45,3 +45,32 @@ func TestResolvedArtifact_GetID(t *testing.T) {
 		})
 	}
 }
+
+func TestResolvedArtifact_ArtifactKey(t *testing.T) {
+	v1 := ResolvedArtifact{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64"}
+	v2 := ResolvedArtifact{Name: "tool", Version: "2.0.0", OS: "linux", Arch: "amd64"}
+
+	if v1.ArtifactKey() != v2.ArtifactKey() {
+		t.Errorf("ArtifactKey() = %v and %v, want equal keys across versions", v1.ArtifactKey(), v2.ArtifactKey())
+	}
+	if v1.GetID() == v2.GetID() {
+		t.Errorf("GetID() = %v for both versions, want distinct IDs", v1.GetID())
+	}
+
+	other := ResolvedArtifact{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "arm64"}
+	if v1.ArtifactKey() == other.ArtifactKey() {
+		t.Errorf("ArtifactKey() = %v for different architectures, want distinct keys", v1.ArtifactKey())
+	}
+}
+
+func TestInstalledArtifact_ArtifactKey(t *testing.T) {
+	installed := InstalledArtifact{Name: "tool", Version: "1.0.0", OS: "linux", Arch: "amd64"}
+	resolved := ResolvedArtifact{Name: "tool", Version: "1.1.0", OS: "linux", Arch: "amd64"}
+
+	if installed.ArtifactKey() != resolved.ArtifactKey() {
+		t.Errorf("ArtifactKey() = %v, want %v", installed.ArtifactKey(), resolved.ArtifactKey())
+	}
+	if got := installed.ArtifactKey().String(); got != "tool/linux/amd64" {
+		t.Errorf("ArtifactKey().String() = %v, want tool/linux/amd64", got)
+	}
+}
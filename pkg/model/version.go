@@ -0,0 +1,265 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/hashicorp/go-version"
+)
+
+// VersionComparator defines the ordering of artifact versions.
+// Compare returns a negative number if a sorts before b, zero if both are equal and a positive number otherwise.
+// It returns an error if either version is not valid for this ordering.
+type VersionComparator interface {
+	Compare(a, b string) (int, error)
+}
+
+// SemverComparator orders versions as semantic versions. It is the default VersionComparator.
+type SemverComparator struct{}
+
+// Compare compares a and b as semantic versions.
+func (SemverComparator) Compare(a, b string) (int, error) {
+	va, err := version.NewVersion(a)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q: %w", a, errutils.ErrValidation)
+	}
+	vb, err := version.NewVersion(b)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q: %w", b, errutils.ErrValidation)
+	}
+	return va.Compare(vb), nil
+}
+
+// AnyVersionConstraint is the constraint that matches every version, whatever the version scheme.
+const AnyVersionConstraint = ">= 0.0.0"
+
+// constraintPartRegexp splits a single constraint such as ">= 1.2.0" into operator and version.
+var constraintPartRegexp = regexp.MustCompile(`^\s*(=|!=|>=|<=|>|<|~>)?\s*(\S+)\s*$`)
+
+// SplitConstraintPart splits a single constraint such as ">= 1.2.0" into its operator and version.
+// The operator is empty for a bare version. ok is false if the constraint is malformed.
+func SplitConstraintPart(part string) (op, v string, ok bool) {
+	m := constraintPartRegexp.FindStringSubmatch(part)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// MatchConstraint reports whether v satisfies every part of a comma separated constraint under compare.
+// Malformed constraints and versions the comparator cannot order never match.
+func MatchConstraint(v, constraint string, compare VersionComparator) bool {
+	for _, part := range strings.Split(constraint, ",") {
+		if p := strings.TrimSpace(part); p == "" || p == AnyVersionConstraint {
+			continue
+		}
+		op, bound, ok := SplitConstraintPart(part)
+		if !ok {
+			return false
+		}
+		cmp, err := compare.Compare(v, bound)
+		if err != nil {
+			return false
+		}
+		switch op {
+		case "", "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "~>":
+			upper, err := PessimisticUpperBound(bound)
+			if err != nil {
+				return false
+			}
+			below, err := compare.Compare(v, upper)
+			ok = err == nil && cmp >= 0 && below < 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// PessimisticUpperBound returns the exclusive upper bound of a "~>" constraint,
+// e.g. 1.2 -> 2.0.0 and 1.2.3 -> 1.3.0. The operator is only defined for semantic versions.
+func PessimisticUpperBound(raw string) (string, error) {
+	v, err := version.NewVersion(raw)
+	if err != nil {
+		return "", errutils.Wrapf(errutils.ErrValidation, "~> requires a semantic version, got %q", raw)
+	}
+	// Only the segments written in the constraint count, so "~> 1.2" differs from "~> 1.2.0".
+	core := strings.TrimPrefix(v.Original(), "v")
+	core, _, _ = strings.Cut(core, "-")
+	core, _, _ = strings.Cut(core, "+")
+	keep := max(strings.Count(core, "."), 1)
+
+	segments := v.Segments()
+	upper := make([]string, 3)
+	for i := range upper {
+		switch {
+		case i < keep-1:
+			upper[i] = fmt.Sprint(segments[i])
+		case i == keep-1:
+			upper[i] = fmt.Sprint(segments[i] + 1)
+		default:
+			upper[i] = "0"
+		}
+	}
+	return strings.Join(upper, "."), nil
+}
+
+// rangeComparatorRegexp matches the first comparator of a version range, e.g. "^1.2", ">=1.2" or "~> 1.2.0".
+var rangeComparatorRegexp = regexp.MustCompile(`^\s*(~>|>=|<=|!=|=|>|<|\^|~)?\s*([^\s,<>=!^~]+)`)
+
+// NormalizeConstraint rewrites the semver ranges in a version constraint into the comma separated
+// comparisons understood by the resolver, e.g. "^1.2" becomes ">= 1.2.0, < 2.0.0". Supported are caret
+// ("^1.2.3"), tilde ("~1.2.3"), wildcard ("1.x", "1.2.*") and compound ranges separated by spaces or
+// commas (">=1.2 <2.0"). Other comparisons are kept as written, so constraints for custom version
+// orderings pass through unchanged. An empty constraint stays empty.
+func NormalizeConstraint(constraint string) (string, error) {
+	var out []string
+	for _, part := range strings.Split(constraint, ",") {
+		for rest := strings.TrimSpace(part); rest != ""; {
+			m := rangeComparatorRegexp.FindStringSubmatch(rest)
+			if m == nil {
+				return "", errutils.Wrapf(errutils.ErrValidation, "malformed range %q", rest)
+			}
+			rest = strings.TrimSpace(rest[len(m[0]):])
+			comparisons, err := expandComparator(m[1], m[2])
+			if err != nil {
+				return "", err
+			}
+			out = append(out, comparisons...)
+		}
+	}
+	return strings.Join(out, ", "), nil
+}
+
+// expandComparator rewrites a single range comparator into plain comparisons.
+func expandComparator(op, raw string) ([]string, error) {
+	if op != "^" && op != "~" && !hasWildcard(raw) {
+		if !unicode.IsLetter(rune(raw[0])) && !unicode.IsDigit(rune(raw[0])) {
+			return nil, errutils.Wrapf(errutils.ErrValidation, "malformed version %q", raw)
+		}
+		if op == "" {
+			return []string{raw}, nil
+		}
+		return []string{op + " " + raw}, nil
+	}
+
+	v, err := parsePartialVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+	if v.specified == 0 {
+		// "*", "^*" and friends match every release
+		if op != "" && op != "=" && op != ">=" && op != "<=" && op != "^" && op != "~" {
+			return nil, errutils.Wrapf(errutils.ErrValidation, "%s%s matches no version", op, raw)
+		}
+		return []string{">= 0.0.0"}, nil
+	}
+
+	switch op {
+	case "^":
+		// The first non-zero segment may not change, e.g. ^1.2.3 < 2.0.0 and ^0.2.3 < 0.3.0
+		bump := v.specified - 1
+		for i := 0; i < v.specified; i++ {
+			if v.segments[i] != 0 {
+				bump = i
+				break
+			}
+		}
+		return []string{">= " + v.lower(), "< " + v.upper(bump)}, nil
+	case "~":
+		// Patch updates if a minor version is given, minor updates otherwise
+		return []string{">= " + v.lower(), "< " + v.upper(min(v.specified-1, 1))}, nil
+	case "", "=":
+		return []string{">= " + v.lower(), "< " + v.upper(v.specified-1)}, nil
+	case ">=":
+		return []string{">= " + v.lower()}, nil
+	case "<":
+		return []string{"< " + v.lower()}, nil
+	case ">":
+		return []string{">= " + v.upper(v.specified-1)}, nil
+	case "<=":
+		return []string{"< " + v.upper(v.specified-1)}, nil
+	default:
+		return nil, errutils.Wrapf(errutils.ErrValidation, "%s does not support wildcard version %q", op, raw)
+	}
+}
+
+// partialVersion is a semantic version whose trailing segments may be omitted or wildcards.
+type partialVersion struct {
+	segments   [3]int
+	specified  int    // Number of leading segments given as numbers
+	prerelease string // Only allowed if all segments are specified
+}
+
+// parsePartialVersion parses versions such as "1", "1.2", "1.x", "1.2.*" or "v1.2.3-rc.1".
+func parsePartialVersion(raw string) (partialVersion, error) {
+	var v partialVersion
+	core, _, _ := strings.Cut(strings.TrimPrefix(raw, "v"), "+")
+	core, v.prerelease, _ = strings.Cut(core, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) > len(v.segments) {
+		return v, errutils.Wrapf(errutils.ErrValidation, "invalid version %q", raw)
+	}
+	wildcard := false
+	for i, part := range parts {
+		if isWildcard(part) {
+			wildcard = true
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || wildcard {
+			return v, errutils.Wrapf(errutils.ErrValidation, "invalid version %q", raw)
+		}
+		v.segments[i] = n
+		v.specified++
+	}
+	if v.prerelease != "" && v.specified != len(v.segments) {
+		return v, errutils.Wrapf(errutils.ErrValidation, "invalid version %q: prereleases require a full version", raw)
+	}
+	return v, nil
+}
+
+// lower returns the lowest version matched by v.
+func (v partialVersion) lower() string {
+	s := fmt.Sprintf("%d.%d.%d", v.segments[0], v.segments[1], v.segments[2])
+	if v.prerelease != "" {
+		s += "-" + v.prerelease
+	}
+	return s
+}
+
+// upper returns the exclusive upper bound reached by incrementing segment i.
+func (v partialVersion) upper(i int) string {
+	segments := [3]int{}
+	copy(segments[:i], v.segments[:i])
+	segments[i] = v.segments[i] + 1
+	return fmt.Sprintf("%d.%d.%d", segments[0], segments[1], segments[2])
+}
+
+// hasWildcard reports whether a version contains a wildcard segment.
+func hasWildcard(raw string) bool {
+	core, _, _ := strings.Cut(raw, "-")
+	return slices.ContainsFunc(strings.Split(core, "."), isWildcard)
+}
+
+func isWildcard(segment string) bool {
+	return segment == "x" || segment == "X" || segment == "*"
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemverComparator_Compare(t *testing.T) {
+	var c VersionComparator = SemverComparator{}
+
+	cmp, err := c.Compare("1.2.0", "1.10.0")
+	require.NoError(t, err)
+	assert.Negative(t, cmp)
+
+	cmp, err = c.Compare("v2.0", "2.0.0")
+	require.NoError(t, err)
+	assert.Zero(t, cmp)
+
+	_, err = c.Compare("1.0.0", "not-a-version")
+	require.ErrorIs(t, err, errutils.ErrValidation)
+}
+
+func TestNormalizeConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		expected   string
+	}{
+		{constraint: "", expected: ""},
+		{constraint: ">= 1.0.0", expected: ">= 1.0.0"},
+		{constraint: ">=1.2 <2.0", expected: ">= 1.2, < 2.0"},
+		{constraint: "~> 1.2, != 1.2.3", expected: "~> 1.2, != 1.2.3"},
+		{constraint: "^1.2", expected: ">= 1.2.0, < 2.0.0"},
+		{constraint: "^0.2.3", expected: ">= 0.2.3, < 0.3.0"},
+		{constraint: "^0.0.3", expected: ">= 0.0.3, < 0.0.4"},
+		{constraint: "^1.2.3-beta.1", expected: ">= 1.2.3-beta.1, < 2.0.0"},
+		{constraint: "~1.2.3", expected: ">= 1.2.3, < 1.3.0"},
+		{constraint: "~1", expected: ">= 1.0.0, < 2.0.0"},
+		{constraint: "1.x", expected: ">= 1.0.0, < 2.0.0"},
+		{constraint: "=1.2.*", expected: ">= 1.2.0, < 1.3.0"},
+		{constraint: ">1.x", expected: ">= 2.0.0"},
+		{constraint: "<=1.2.x", expected: "< 1.3.0"},
+		{constraint: "*", expected: ">= 0.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			normalized, err := NormalizeConstraint(tt.constraint)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+
+	for _, invalid := range []string{"^1.x.3", "~banana", "1.2.x-rc.1", "!=1.x", "<*", "1.2 - 2.0"} {
+		t.Run(invalid, func(t *testing.T) {
+			_, err := NormalizeConstraint(invalid)
+			require.ErrorIs(t, err, errutils.ErrValidation)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// Ensure makes sure the requested artifacts are installed: requests already satisfied by an installed
+// artifact of a matching version and platform are left alone, the others are resolved and installed like
+// Install does. Running it again with the same requests changes nothing, which suits declarative provisioning.
+// The returned summary lists the installed artifacts, including dependencies pulled in, in Installed and
+// the requested artifacts that were already present in Skipped. Present artifacts that were installed as
+// a dependency are marked as manually installed, as Install does.
+func (o *Orchestrator) Ensure(ctx context.Context, requests []*model.ResolveRequest, opts InstallOptions) (*Summary, error) {
+	if o.ArtifactManager == nil {
+		return nil, fmt.Errorf("artifact installer is not configured: %w", errutils.ErrValidation)
+	}
+	installed, err := o.ArtifactManager.GetInstalledArtifacts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load installed artifacts: %w", err)
+	}
+	byName := make(map[string]*model.InstalledArtifact, len(installed))
+	for _, artifact := range installed {
+		byName[artifact.Name] = artifact
+	}
+
+	summary := &Summary{}
+	var missing []*model.ResolveRequest
+	for _, req := range requests {
+		artifact := byName[req.Name]
+		if artifact == nil || !satisfiesRequest(artifact, req) {
+			missing = append(missing, req)
+			continue
+		}
+		emit(o.Hooks, Event{Phase: "skipped", ID: artifact.Name + "@" + artifact.Version, Msg: req.Name + " is already installed"})
+		if !opts.DryRun && artifact.InstallationReason != model.InstallationReasonManual {
+			if err := o.ArtifactManager.SetArtifactManuallyInstalled(req.Name); err != nil {
+				return summary, err
+			}
+		}
+		summary.Skipped = append(summary.Skipped, req.Name)
+	}
+
+	if len(missing) == 0 {
+		emit(o.Hooks, Event{Phase: "done", Msg: summary.String(), Summary: summary})
+		return summary, nil
+	}
+	return summary, o.install(ctx, missing, opts, summary)
+}
+
+// satisfiesRequest reports whether the installed artifact fulfills req: its version matches the version
+// constraint, if any, and it targets the requested platform.
+func satisfiesRequest(artifact *model.InstalledArtifact, req *model.ResolveRequest) bool {
+	desc := &model.IndexArtifactDescriptor{Name: artifact.Name, Version: artifact.Version, OS: artifact.OS, Arch: artifact.Arch}
+	if req.VersionConstraint != "" && !desc.MatchVersion(req.VersionConstraint) {
+		return false
+	}
+	return (req.OS == "" || desc.MatchOs(req.OS)) && (req.Arch == "" || desc.MatchArch(req.Arch))
+}
@@ -0,0 +1,87 @@
+package orchestrator
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/artifact"
+	"github.com/glorpus-work/gotya/pkg/model"
+	mocks "github.com/glorpus-work/gotya/pkg/orchestrator/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestEnsure_Idempotent(t *testing.T) {
+	served := make(map[string][]byte)
+	var steps []model.ResolvedArtifact
+	for _, name := range []string{"lib", "app"} {
+		inputDir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(inputDir, "data"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(inputDir, "data", name+".txt"), []byte(name), 0o644))
+		path, err := artifact.NewPacker(name, "1.0.0", "linux", "amd64", "", name, nil, nil, inputDir, t.TempDir()).Pack()
+		require.NoError(t, err)
+		served[name], err = os.ReadFile(path)
+		require.NoError(t, err)
+		u, _ := url.Parse("https://example.com/" + name + "-1.0.0.gotya")
+		steps = append(steps, model.ResolvedArtifact{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", SourceURL: u, Checksum: sha256Hex(served[name]), Action: model.ResolvedActionInstall})
+	}
+
+	ctrl := gomock.NewController(t)
+	dir := t.TempDir()
+	// The second run must not resolve or download anything
+	idx := mocks.NewMockArtifactResolver(ctrl)
+	idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(model.ResolvedArtifacts{Artifacts: steps}, nil).Times(1)
+	am := artifact.NewManager("linux", "amd64", dir, filepath.Join(dir, "data"), filepath.Join(dir, "meta"), filepath.Join(dir, "installed.db"))
+	var events []Event
+	orch := New(idx, nil, servingDownloader(t, ctrl, t.TempDir(), served), am, Hooks{OnEvent: func(e Event) { events = append(events, e) }})
+
+	requests := func() []*model.ResolveRequest {
+		return []*model.ResolveRequest{
+			{Name: "app", VersionConstraint: ">= 1.0.0", OS: "linux", Arch: "amd64"},
+			{Name: "lib", OS: "linux", Arch: "amd64"},
+		}
+	}
+
+	summary, err := orch.Ensure(context.Background(), requests(), InstallOptions{CacheDir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lib", "app"}, summary.Installed)
+	assert.Empty(t, summary.Skipped)
+	assert.Same(t, summary, lastSummary(t, events))
+
+	events = nil
+	summary, err = orch.Ensure(context.Background(), requests(), InstallOptions{CacheDir: dir})
+	require.NoError(t, err)
+	assert.Empty(t, summary.Installed)
+	assert.Empty(t, summary.Updated)
+	assert.Equal(t, []string{"app", "lib"}, summary.Skipped)
+	assert.Equal(t, "installed 0, updated 0, removed 0, skipped 2, failed 0", summary.String())
+	assert.Same(t, summary, lastSummary(t, events))
+
+	names, err := am.InstalledNames()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app", "lib"}, names)
+}
+
+func TestSatisfiesRequest(t *testing.T) {
+	installed := &model.InstalledArtifact{Name: "app", Version: "1.2.0", OS: "linux", Arch: "amd64"}
+	tests := []struct {
+		name string
+		req  model.ResolveRequest
+		want bool
+	}{
+		{"any version", model.ResolveRequest{Name: "app"}, true},
+		{"matching constraint", model.ResolveRequest{Name: "app", VersionConstraint: ">= 1.0.0, < 2.0.0", OS: "linux", Arch: "amd64"}, true},
+		{"newer version required", model.ResolveRequest{Name: "app", VersionConstraint: ">= 2.0.0"}, false},
+		{"other os", model.ResolveRequest{Name: "app", OS: "darwin"}, false},
+		{"other arch", model.ResolveRequest{Name: "app", Arch: "arm64"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, satisfiesRequest(installed, &tt.req))
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// LockfileFormatVersion is the lockfile format written by WriteLock and understood by ReadLock.
+const LockfileFormatVersion = "1"
+
+// Lockfile pins a resolved plan so the same artifacts can be installed again without resolving.
+type Lockfile struct {
+	FormatVersion string    `json:"format_version"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	// Requested lists the names of the explicitly requested artifacts; they are installed as manual.
+	Requested []string                `json:"requested"`
+	Plan      model.ResolvedArtifacts `json:"plan"`
+}
+
+// WriteLock resolves requests and writes the plan to path as a lockfile. The resolution ignores
+// installed artifacts, so the lockfile describes a complete environment. Every artifact in the plan
+// must have a URL and a checksum.
+func (o *Orchestrator) WriteLock(ctx context.Context, requests []*model.ResolveRequest, path string) error {
+	if o.Index == nil {
+		return fmt.Errorf("index planner is not configured: %w", errutils.ErrValidation)
+	}
+
+	emit(o.Hooks, Event{Phase: "planning", Msg: fmt.Sprintf("locking %d packages", len(requests))})
+	plan, err := o.Index.Resolve(ctx, requests)
+	if err != nil {
+		return err
+	}
+
+	lock := Lockfile{
+		FormatVersion: LockfileFormatVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Plan:          plan,
+	}
+	for _, req := range requests {
+		lock.Requested = append(lock.Requested, req.Name)
+	}
+	for i := range lock.Plan.Artifacts {
+		step := &lock.Plan.Artifacts[i]
+		if step.SourceURL == nil || step.Checksum == "" {
+			return fmt.Errorf("cannot lock %s: url and checksum are required: %w", step.GetID(), errutils.ErrValidation)
+		}
+		// Actions and reasons are recomputed against the installed artifacts when the lock is installed
+		step.Action = model.ResolvedActionInstall
+	}
+
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return errutils.Wrap(err, "failed to encode lockfile")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errutils.Wrapf(err, "failed to create directory for lockfile %s", path)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return errutils.Wrapf(err, "failed to write lockfile %s", path)
+	}
+	emit(o.Hooks, Event{Phase: "done", Msg: fmt.Sprintf("locked %d artifacts in %s", len(plan.Artifacts), path)})
+	return nil
+}
+
+// ReadLock reads and validates a lockfile written by WriteLock.
+func ReadLock(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errutils.Wrapf(err, "failed to read lockfile %s", path)
+	}
+	var lock Lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %v: %w", path, err, errutils.ErrValidation)
+	}
+	if lock.FormatVersion != LockfileFormatVersion {
+		return nil, fmt.Errorf("unsupported lockfile format version %q in %s: %w", lock.FormatVersion, path, errutils.ErrValidation)
+	}
+	for _, step := range lock.Plan.Artifacts {
+		if step.Name == "" || step.Version == "" || step.SourceURL == nil || step.Checksum == "" {
+			return nil, fmt.Errorf("invalid lockfile entry %s in %s: name, version, url and checksum are required: %w", step.GetID(), path, errutils.ErrValidation)
+		}
+	}
+	return &lock, nil
+}
+
+// InstallFromLock installs exactly the artifacts pinned in the lockfile at path without resolving.
+// Artifacts already installed at the locked version are left alone and others are installed or
+// updated to it. Installation fails before anything is changed if a downloaded artifact does not
+// match its locked checksum. The cache is never trusted, so opts.TrustCache is ignored.
+func (o *Orchestrator) InstallFromLock(ctx context.Context, path string, opts InstallOptions) error {
+	lock, err := ReadLock(path)
+	if err != nil {
+		return err
+	}
+	emit(o.Hooks, Event{Phase: "planning", Msg: fmt.Sprintf("installing %d locked artifacts from %s", len(lock.Plan.Artifacts), path)})
+
+	var installed []*model.InstalledArtifact
+	if o.ArtifactManager != nil {
+		installed, err = o.ArtifactManager.GetInstalledArtifacts()
+		if err != nil {
+			return fmt.Errorf("failed to load installed artifacts: %w", err)
+		}
+	}
+
+	plan := lockedPlan(lock.Plan, installed)
+	requests := make([]*model.ResolveRequest, 0, len(lock.Requested))
+	for _, name := range lock.Requested {
+		requests = append(requests, &model.ResolveRequest{Name: name})
+	}
+
+	opts.TrustCache = false
+	return o.installPlan(ctx, plan, requests, opts, &Summary{})
+}
+
+// lockedPlan returns the locked steps that still need work, with actions matching the installed artifacts.
+func lockedPlan(locked model.ResolvedArtifacts, installed []*model.InstalledArtifact) model.ResolvedArtifacts {
+	versions := make(map[string]string, len(installed))
+	for _, artifact := range installed {
+		if artifact.Status != model.StatusMissing {
+			versions[artifact.Name] = artifact.Version
+		}
+	}
+
+	plan := model.ResolvedArtifacts{SkippedOptional: locked.SkippedOptional}
+	for _, step := range locked.Artifacts {
+		current, ok := versions[step.Name]
+		switch {
+		case !ok:
+			step.Action = model.ResolvedActionInstall
+		case current == step.Version:
+			continue
+		default:
+			step.Action = model.ResolvedActionUpdate
+			step.Reason = fmt.Sprintf("locked version %s replaces %s", step.Version, current)
+		}
+		plan.Artifacts = append(plan.Artifacts, step)
+	}
+	return plan
+}
@@ -0,0 +1,184 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/download"
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+	mocks "github.com/glorpus-work/gotya/pkg/orchestrator/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeTestLock writes a lockfile for app@1.0.0 depending on lib@1.0.0 and returns its path.
+func writeTestLock(t *testing.T, contents map[string][]byte) string {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+
+	newStep := func(name string, deps ...string) model.ResolvedArtifact {
+		u, _ := url.Parse("https://example.com/" + name + "-1.0.0.gotya")
+		return model.ResolvedArtifact{
+			Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", SourceURL: u,
+			Checksum: sha256Hex(contents[name]), Action: model.ResolvedActionInstall, Dependencies: deps,
+		}
+	}
+	lib := newStep("lib")
+	lib.Reason = "required by app"
+	plan := model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{lib, newStep("app", "lib")}}
+
+	idx := mocks.NewMockArtifactResolver(ctrl)
+	idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(plan, nil)
+
+	path := filepath.Join(t.TempDir(), "gotya.lock")
+	orch := New(idx, nil, nil, nil, Hooks{})
+	require.NoError(t, orch.WriteLock(context.Background(), []*model.ResolveRequest{{Name: "app", OS: "linux", Arch: "amd64"}}, path))
+	return path
+}
+
+// servingDownloader returns a downloader that writes the given contents per artifact name into dir.
+func servingDownloader(t *testing.T, ctrl *gomock.Controller, dir string, served map[string][]byte) *mocks.MockDownloader {
+	dl := mocks.NewMockDownloader(ctrl)
+	dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, items []download.Item, _ download.Options) (map[string]string, error) {
+			fetched := make(map[string]string, len(items))
+			for _, item := range items {
+				name, _, _ := strings.Cut(item.ID, "@")
+				path := filepath.Join(dir, item.ID)
+				require.NoError(t, os.WriteFile(path, served[name], 0o644))
+				fetched[item.ID] = path
+			}
+			return fetched, nil
+		})
+	return dl
+}
+
+func TestWriteLock_RoundTrip(t *testing.T) {
+	contents := map[string][]byte{"app": []byte("app artifact"), "lib": []byte("lib artifact")}
+	path := writeTestLock(t, contents)
+
+	lock, err := ReadLock(path)
+	require.NoError(t, err)
+	assert.Equal(t, LockfileFormatVersion, lock.FormatVersion)
+	assert.Equal(t, []string{"app"}, lock.Requested)
+	require.Len(t, lock.Plan.Artifacts, 2)
+
+	app := lock.Plan.Artifacts[1]
+	assert.Equal(t, "app@1.0.0", app.GetID())
+	assert.Equal(t, "https://example.com/app-1.0.0.gotya", app.SourceURL.String())
+	assert.Equal(t, sha256Hex(contents["app"]), app.Checksum)
+	assert.Equal(t, []string{"lib"}, app.Dependencies)
+}
+
+func TestWriteLock_RequiresChecksums(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	u, _ := url.Parse("https://example.com/app.gotya")
+	idx := mocks.NewMockArtifactResolver(ctrl)
+	idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{
+		{Name: "app", Version: "1.0.0", SourceURL: u},
+	}}, nil)
+
+	path := filepath.Join(t.TempDir(), "gotya.lock")
+	err := New(idx, nil, nil, nil, Hooks{}).WriteLock(context.Background(), []*model.ResolveRequest{{Name: "app"}}, path)
+	require.ErrorIs(t, err, errutils.ErrValidation)
+	assert.NoFileExists(t, path)
+}
+
+func TestInstallFromLock(t *testing.T) {
+	contents := map[string][]byte{"app": []byte("app artifact"), "lib": []byte("lib artifact")}
+	lockPath := writeTestLock(t, contents)
+
+	t.Run("matching checksums", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		cacheDir := t.TempDir()
+		dl := servingDownloader(t, ctrl, cacheDir, contents)
+
+		am := mocks.NewMockArtifactManager(ctrl)
+		am.EXPECT().GetInstalledArtifacts().Return(nil, nil)
+		reasons := make(map[string]model.InstallationReason)
+		am.EXPECT().InstallArtifactWithDetail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, desc *model.IndexArtifactDescriptor, _ string, reason model.InstallationReason, _ string) error {
+				assert.Equal(t, sha256Hex(contents[desc.Name]), desc.Checksum)
+				reasons[desc.Name] = reason
+				return nil
+			}).Times(2)
+
+		// The index must not be consulted
+		idx := mocks.NewMockArtifactResolver(ctrl)
+		var events []Event
+		orch := New(idx, nil, dl, am, Hooks{OnEvent: func(e Event) { events = append(events, e) }})
+		require.NoError(t, orch.InstallFromLock(context.Background(), lockPath, InstallOptions{CacheDir: cacheDir}))
+
+		assert.Equal(t, map[string]model.InstallationReason{"app": model.InstallationReasonManual, "lib": model.InstallationReasonAutomatic}, reasons)
+		assert.Equal(t, []string{"lib", "app"}, lastSummary(t, events).Installed)
+	})
+
+	t.Run("mismatching checksum", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		cacheDir := t.TempDir()
+		dl := servingDownloader(t, ctrl, cacheDir, map[string][]byte{"app": contents["app"], "lib": []byte("tampered")})
+
+		am := mocks.NewMockArtifactManager(ctrl)
+		am.EXPECT().GetInstalledArtifacts().Return(nil, nil)
+		am.EXPECT().InstallArtifactWithDetail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		orch := New(nil, nil, dl, am, Hooks{})
+		err := orch.InstallFromLock(context.Background(), lockPath, InstallOptions{CacheDir: cacheDir})
+		require.ErrorIs(t, err, errutils.ErrFileHashMismatch)
+		assert.Contains(t, err.Error(), "lib@1.0.0")
+	})
+
+	t.Run("installed artifacts", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		cacheDir := t.TempDir()
+		dl := servingDownloader(t, ctrl, cacheDir, contents)
+
+		am := mocks.NewMockArtifactManager(ctrl)
+		am.EXPECT().GetInstalledArtifacts().Return([]*model.InstalledArtifact{
+			{Name: "lib", Version: "1.0.0", Status: model.StatusInstalled},
+			{Name: "app", Version: "0.9.0", Status: model.StatusInstalled},
+		}, nil)
+		am.EXPECT().UpdateArtifact(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, desc *model.IndexArtifactDescriptor) error {
+				assert.Equal(t, "app@1.0.0", desc.GetID())
+				return nil
+			})
+
+		var events []Event
+		orch := New(nil, nil, dl, am, Hooks{OnEvent: func(e Event) { events = append(events, e) }})
+		require.NoError(t, orch.InstallFromLock(context.Background(), lockPath, InstallOptions{CacheDir: cacheDir}))
+		assert.Equal(t, []string{"app"}, lastSummary(t, events).Updated)
+	})
+}
+
+func TestReadLock_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"garbage":         "not json",
+		"format":          `{"format_version":"99","plan":{"artifacts":[]}}`,
+		"missing-sha":     `{"format_version":"1","plan":{"artifacts":[{"name":"app","version":"1.0.0","url":"https://example.com/app"}]}}`,
+		"missing-url":     `{"format_version":"1","plan":{"artifacts":[{"name":"app","version":"1.0.0","checksum":"abc"}]}}`,
+		"malformed-url":   `{"format_version":"1","plan":{"artifacts":[{"name":"app","version":"1.0.0","url":"://bad","checksum":"abc"}]}}`,
+		"missing-version": `{"format_version":"1","plan":{"artifacts":[{"name":"app","url":"https://example.com/app","checksum":"abc"}]}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name+".lock")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			_, err := ReadLock(path)
+			require.ErrorIs(t, err, errutils.ErrValidation)
+		})
+	}
+}
@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/glorpus-work/gotya/pkg/orchestrator (interfaces: ArtifactResolver,ArtifactReverseResolver,ArtifactManager,Downloader)
+// Source: github.com/glorpus-work/gotya/pkg/orchestrator (interfaces: ArtifactResolver,ArtifactReverseResolver,ArtifactManager,PostBatchHookRunner,ArtifactIdentityVerifier,Downloader)
 //
 // Generated by this command:
 //
-//	mockgen -destination=./mocks/orchestrator.go . ArtifactResolver,ArtifactReverseResolver,ArtifactManager,Downloader
+//	mockgen -destination=./mocks/orchestrator.go . ArtifactResolver,ArtifactReverseResolver,ArtifactManager,PostBatchHookRunner,ArtifactIdentityVerifier,Downloader
 //
 
 // Package mock_orchestrator is a generated GoMock package.
@@ -42,6 +42,21 @@ func (m *MockArtifactResolver) EXPECT() *MockArtifactResolverMockRecorder {
 	return m.recorder
 }
 
+// FindArtifacts mocks base method.
+func (m *MockArtifactResolver) FindArtifacts(name string) (map[string][]*model.IndexArtifactDescriptor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindArtifacts", name)
+	ret0, _ := ret[0].(map[string][]*model.IndexArtifactDescriptor)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindArtifacts indicates an expected call of FindArtifacts.
+func (mr *MockArtifactResolverMockRecorder) FindArtifacts(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindArtifacts", reflect.TypeOf((*MockArtifactResolver)(nil).FindArtifacts), name)
+}
+
 // Resolve mocks base method.
 func (m *MockArtifactResolver) Resolve(ctx context.Context, requests []*model.ResolveRequest) (model.ResolvedArtifacts, error) {
 	m.ctrl.T.Helper()
@@ -150,18 +165,18 @@ func (mr *MockArtifactManagerMockRecorder) GetOrphanedAutomaticArtifacts() *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrphanedAutomaticArtifacts", reflect.TypeOf((*MockArtifactManager)(nil).GetOrphanedAutomaticArtifacts))
 }
 
-// InstallArtifact mocks base method.
-func (m *MockArtifactManager) InstallArtifact(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string, reason model.InstallationReason) error {
+// InstallArtifactWithDetail mocks base method.
+func (m *MockArtifactManager) InstallArtifactWithDetail(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string, reason model.InstallationReason, detail string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InstallArtifact", ctx, desc, localPath, reason)
+	ret := m.ctrl.Call(m, "InstallArtifactWithDetail", ctx, desc, localPath, reason, detail)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// InstallArtifact indicates an expected call of InstallArtifact.
-func (mr *MockArtifactManagerMockRecorder) InstallArtifact(ctx, desc, localPath, reason any) *gomock.Call {
+// InstallArtifactWithDetail indicates an expected call of InstallArtifactWithDetail.
+func (mr *MockArtifactManagerMockRecorder) InstallArtifactWithDetail(ctx, desc, localPath, reason, detail any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallArtifact", reflect.TypeOf((*MockArtifactManager)(nil).InstallArtifact), ctx, desc, localPath, reason)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallArtifactWithDetail", reflect.TypeOf((*MockArtifactManager)(nil).InstallArtifactWithDetail), ctx, desc, localPath, reason, detail)
 }
 
 // SetArtifactManuallyInstalled mocks base method.
@@ -206,6 +221,82 @@ func (mr *MockArtifactManagerMockRecorder) UpdateArtifact(ctx, newArtifactPath,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateArtifact", reflect.TypeOf((*MockArtifactManager)(nil).UpdateArtifact), ctx, newArtifactPath, newDescriptor)
 }
 
+// MockPostBatchHookRunner is a mock of PostBatchHookRunner interface.
+type MockPostBatchHookRunner struct {
+	ctrl     *gomock.Controller
+	recorder *MockPostBatchHookRunnerMockRecorder
+	isgomock struct{}
+}
+
+// MockPostBatchHookRunnerMockRecorder is the mock recorder for MockPostBatchHookRunner.
+type MockPostBatchHookRunnerMockRecorder struct {
+	mock *MockPostBatchHookRunner
+}
+
+// NewMockPostBatchHookRunner creates a new mock instance.
+func NewMockPostBatchHookRunner(ctrl *gomock.Controller) *MockPostBatchHookRunner {
+	mock := &MockPostBatchHookRunner{ctrl: ctrl}
+	mock.recorder = &MockPostBatchHookRunnerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPostBatchHookRunner) EXPECT() *MockPostBatchHookRunnerMockRecorder {
+	return m.recorder
+}
+
+// ExecutePostBatchHooks mocks base method.
+func (m *MockPostBatchHookRunner) ExecutePostBatchHooks(artifactNames []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExecutePostBatchHooks", artifactNames)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExecutePostBatchHooks indicates an expected call of ExecutePostBatchHooks.
+func (mr *MockPostBatchHookRunnerMockRecorder) ExecutePostBatchHooks(artifactNames any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecutePostBatchHooks", reflect.TypeOf((*MockPostBatchHookRunner)(nil).ExecutePostBatchHooks), artifactNames)
+}
+
+// MockArtifactIdentityVerifier is a mock of ArtifactIdentityVerifier interface.
+type MockArtifactIdentityVerifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockArtifactIdentityVerifierMockRecorder
+	isgomock struct{}
+}
+
+// MockArtifactIdentityVerifierMockRecorder is the mock recorder for MockArtifactIdentityVerifier.
+type MockArtifactIdentityVerifierMockRecorder struct {
+	mock *MockArtifactIdentityVerifier
+}
+
+// NewMockArtifactIdentityVerifier creates a new mock instance.
+func NewMockArtifactIdentityVerifier(ctrl *gomock.Controller) *MockArtifactIdentityVerifier {
+	mock := &MockArtifactIdentityVerifier{ctrl: ctrl}
+	mock.recorder = &MockArtifactIdentityVerifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockArtifactIdentityVerifier) EXPECT() *MockArtifactIdentityVerifierMockRecorder {
+	return m.recorder
+}
+
+// VerifyArtifactIdentity mocks base method.
+func (m *MockArtifactIdentityVerifier) VerifyArtifactIdentity(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyArtifactIdentity", ctx, desc, localPath)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyArtifactIdentity indicates an expected call of VerifyArtifactIdentity.
+func (mr *MockArtifactIdentityVerifierMockRecorder) VerifyArtifactIdentity(ctx, desc, localPath any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyArtifactIdentity", reflect.TypeOf((*MockArtifactIdentityVerifier)(nil).VerifyArtifactIdentity), ctx, desc, localPath)
+}
+
 // MockDownloader is a mock of Downloader interface.
 type MockDownloader struct {
 	ctrl     *gomock.Controller
@@ -5,14 +5,23 @@ package orchestrator
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/glorpus-work/gotya/pkg/download"
 	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/fsutil"
 	"github.com/glorpus-work/gotya/pkg/index"
 	"github.com/glorpus-work/gotya/pkg/model"
 )
@@ -20,21 +29,59 @@ import (
 const phaseUpdating = "updating"
 
 // SyncAll downloads index files for the provided repositories into indexDir.
-// The caller decides which repositories to pass (e.g., enabled-only). No TTL/autosync logic here.
+// The caller decides which repositories to pass (e.g., enabled-only).
+// Indexes synced within opts.MinSyncInterval are skipped unless opts.Force is set.
+// Cached indexes are revalidated with the ETag and Last-Modified validators of their last sync,
+// which are stored next to the index. A 304 response keeps the content of the cached index and only
+// renews its modification time, so the index counts as synced for opts.MinSyncInterval.
+// An index is downloaded from the repository URL or else from the first of its mirrors that succeeds;
+// the URL that served it is recorded in the repository's ServedBy.
+// Indexes of repositories with a public key are downloaded to a staging directory and only replace the
+// cached index once their detached signature is verified; otherwise the download is discarded and an
+// errutils.ErrValidation error is returned. A cached index not yet verified with the repository's current
+// key is downloaded again instead of being revalidated.
 func (o *Orchestrator) SyncAll(ctx context.Context, repos []*index.Repository, indexDir string, opts Options) error {
 	if o.DL == nil {
 		return fmt.Errorf("download manager is not configured: %w", errutils.ErrValidation)
 	}
+	defer func() { _ = os.RemoveAll(filepath.Join(indexDir, unverifiedIndexDir)) }()
 
 	items := make([]download.Item, 0, len(repos))
+	toSync := make([]*index.Repository, 0, len(repos))
 	for _, r := range repos {
 		if r == nil || r.URL == nil {
 			continue
 		}
+		if !opts.Force {
+			if age, fresh := indexFreshness(indexDir, r.Name, opts.MinSyncInterval); fresh {
+				emit(o.Hooks, Event{Phase: "skipped", ID: r.Name, Msg: fmt.Sprintf("index is fresh (synced %s ago)", age.Round(time.Second))})
+				continue
+			}
+		}
+		if r.ETag == "" && r.LastModified == "" {
+			loadIndexValidators(indexDir, r)
+		}
+		filename := r.Name + ".json"
+		if r.PublicKey != nil {
+			staged, err := stageSignedIndex(indexDir, r.Name)
+			if err != nil {
+				return fmt.Errorf("failed to stage index %s: %w", r.Name, err)
+			}
+			filename = staged
+		}
+		validators := &download.Validators{ETag: r.ETag, LastModified: r.LastModified}
+		if r.PublicKey != nil && !indexVerifiedWith(indexDir, r) {
+			// A 304 response would keep a cached index that was never checked against the key
+			validators = &download.Validators{}
+		}
+		toSync = append(toSync, r)
 		items = append(items, download.Item{
-			ID:       r.Name,
-			URL:      r.URL,
-			Filename: r.Name + ".json",
+			ID:         r.Name,
+			URL:        r.URL,
+			Filename:   filename,
+			Validators: validators,
+			Mirrors:    r.Mirrors,
+			ServedBy:   &url.URL{},
 		})
 	}
 	if len(items) == 0 {
@@ -42,16 +89,40 @@ func (o *Orchestrator) SyncAll(ctx context.Context, repos []*index.Repository, i
 	}
 
 	// Download all indexes
-	_, err := o.DL.FetchAll(ctx, items, download.Options{Dir: indexDir, Concurrency: opts.Concurrency})
-	if err != nil {
+	dlOpts := download.Options{Dir: indexDir, Concurrency: opts.Concurrency, Headers: opts.Headers, AllowInsecure: opts.AllowInsecure}
+	if _, err := o.DL.FetchAll(ctx, items, dlOpts); err != nil {
+		return err
+	}
+	if err := o.verifySignedIndexes(ctx, toSync, items, indexDir, dlOpts); err != nil {
 		return err
 	}
 
-	// Transform relative URLs in downloaded indexes to absolute URLs
-	for _, repo := range repos {
-		if repo == nil || repo.URL == nil {
+	for i, repo := range toSync {
+		if served := items[i].ServedBy; served.String() != "" {
+			repo.ServedBy = served
+		}
+		if items[i].Validators.NotModified {
+			if err := touchIndex(indexDir, repo.Name); err != nil {
+				return fmt.Errorf("failed to record sync of index %s: %w", repo.Name, err)
+			}
+		}
+		if repo.PublicKey == nil && !items[i].Validators.NotModified {
+			if err := os.Remove(indexVerifiedKeyFile(indexDir, repo.Name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to reset verification of index %s: %w", repo.Name, err)
+			}
+		}
+		validators := items[i].Validators
+		if validators.ETag == repo.ETag && validators.LastModified == repo.LastModified {
 			continue
 		}
+		repo.ETag, repo.LastModified = validators.ETag, validators.LastModified
+		if err := saveIndexValidators(indexDir, repo); err != nil {
+			return fmt.Errorf("failed to save cache validators of index %s: %w", repo.Name, err)
+		}
+	}
+
+	// Transform relative URLs in downloaded indexes to absolute URLs
+	for _, repo := range toSync {
 		// If the index file doesn't exist (e.g., mocked downloader didn't actually create it), skip transformation
 		indexPath := filepath.Join(indexDir, repo.Name+".json")
 		if _, statErr := os.Stat(indexPath); statErr != nil {
@@ -68,6 +139,151 @@ func (o *Orchestrator) SyncAll(ctx context.Context, repos []*index.Repository, i
 	return nil
 }
 
+// unverifiedIndexDir is the directory below the index directory that signed indexes are downloaded to
+// until their signature is verified.
+const unverifiedIndexDir = ".unverified"
+
+// stageSignedIndex prepares the download of the index of a signed repository to the staging directory and
+// returns its filename relative to indexDir. The cached index is copied there, so it can be revalidated.
+func stageSignedIndex(indexDir, repoName string) (string, error) {
+	filename := filepath.Join(unverifiedIndexDir, repoName+".json")
+	staged := filepath.Join(indexDir, filename)
+	if err := os.MkdirAll(filepath.Dir(staged), fsutil.DirModeSecure); err != nil {
+		return "", err
+	}
+	for _, path := range []string{staged, staged + ".sig"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	cached := filepath.Join(indexDir, repoName+".json")
+	if _, err := os.Stat(cached); err == nil {
+		if err := fsutil.Copy(cached, staged); err != nil {
+			return "", err
+		}
+	}
+	return filename, nil
+}
+
+// verifySignedIndexes checks the staged indexes of repositories with a public key against their detached
+// signature, downloaded from the URL that served the index, and moves them in place of the cached index.
+// An index confirmed by a 304 response stays as it is: validators are only sent for cached indexes that
+// were verified with the repository's key, see indexVerifiedWith.
+func (o *Orchestrator) verifySignedIndexes(ctx context.Context, repos []*index.Repository, items []download.Item, indexDir string, opts download.Options) error {
+	var signed []int
+	var sigItems []download.Item
+	for i, repo := range repos {
+		if repo.PublicKey == nil || items[i].Validators.NotModified {
+			continue
+		}
+		source := repo.URL
+		if items[i].ServedBy.String() != "" {
+			source = items[i].ServedBy
+		}
+		signed = append(signed, i)
+		sigItems = append(sigItems, download.Item{ID: repo.Name + ".sig", URL: index.SignatureURL(source), Filename: items[i].Filename + ".sig"})
+	}
+	if len(sigItems) == 0 {
+		return nil
+	}
+	if _, err := o.DL.FetchAll(ctx, sigItems, opts); err != nil {
+		return fmt.Errorf("%w: failed to download index signature: %w", errutils.ErrValidation, err)
+	}
+
+	for _, i := range signed {
+		repo, staged := repos[i], filepath.Join(indexDir, items[i].Filename)
+		data, err := os.ReadFile(staged)
+		if err != nil {
+			return fmt.Errorf("failed to read index %s: %w", repo.Name, err)
+		}
+		signature, err := os.ReadFile(staged + ".sig")
+		if err != nil {
+			return fmt.Errorf("failed to read signature of index %s: %w", repo.Name, err)
+		}
+		if err := index.VerifyIndexSignature(data, signature, repo.PublicKey); err != nil {
+			return fmt.Errorf("index %s: %w", repo.Name, err)
+		}
+		if err := fsutil.Move(staged, filepath.Join(indexDir, repo.Name+".json")); err != nil {
+			return fmt.Errorf("failed to store index %s: %w", repo.Name, err)
+		}
+		if err := os.WriteFile(indexVerifiedKeyFile(indexDir, repo.Name), []byte(hex.EncodeToString(repo.PublicKey)), fsutil.FileModeSecure); err != nil {
+			return fmt.Errorf("failed to record verification of index %s: %w", repo.Name, err)
+		}
+	}
+	return nil
+}
+
+// indexVerifiedKeyFile returns the path of the file recording the public key the cached index of a
+// repository was last verified with.
+func indexVerifiedKeyFile(indexDir, repoName string) string {
+	return filepath.Join(indexDir, repoName+".json.verified")
+}
+
+// indexVerifiedWith reports whether the cached index of the repository was verified with its public key.
+func indexVerifiedWith(indexDir string, repo *index.Repository) bool {
+	data, err := os.ReadFile(indexVerifiedKeyFile(indexDir, repo.Name))
+	return err == nil && string(data) == hex.EncodeToString(repo.PublicKey)
+}
+
+// indexValidatorsFile returns the path of the file storing the cache validators of a repository's index.
+func indexValidatorsFile(indexDir, repoName string) string {
+	return filepath.Join(indexDir, repoName+".json.validators")
+}
+
+// loadIndexValidators sets the cache validators of the repository from the ones stored with its cached index.
+// Missing or unreadable validators are ignored, the index is then downloaded unconditionally.
+func loadIndexValidators(indexDir string, repo *index.Repository) {
+	data, err := os.ReadFile(indexValidatorsFile(indexDir, repo.Name))
+	if err != nil {
+		return
+	}
+	var validators download.Validators
+	if err := json.Unmarshal(data, &validators); err != nil {
+		return
+	}
+	repo.ETag, repo.LastModified = validators.ETag, validators.LastModified
+}
+
+// saveIndexValidators stores the cache validators of the repository next to its cached index.
+func saveIndexValidators(indexDir string, repo *index.Repository) error {
+	path := indexValidatorsFile(indexDir, repo.Name)
+	if repo.ETag == "" && repo.LastModified == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(download.Validators{ETag: repo.ETag, LastModified: repo.LastModified})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, fsutil.FileModeSecure)
+}
+
+// touchIndex sets the modification time of the cached index of a repository to now, which indexFreshness
+// takes as the time of its last sync.
+func touchIndex(indexDir, repoName string) error {
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(indexDir, repoName+".json"), now, now); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// indexFreshness reports how long ago the index of a repository was synced and
+// whether that is within minInterval. A missing index is never fresh.
+func indexFreshness(indexDir, repoName string, minInterval time.Duration) (time.Duration, bool) {
+	if minInterval <= 0 {
+		return 0, false
+	}
+	st, err := os.Stat(filepath.Join(indexDir, repoName+".json"))
+	if err != nil {
+		return 0, false
+	}
+	age := time.Since(st.ModTime())
+	return age, age < minInterval
+}
+
 // Cleanup removes orphaned automatic artifacts that have no reverse dependencies.
 // Returns the list of artifacts that were successfully cleaned up.
 func (o *Orchestrator) Cleanup(ctx context.Context) ([]string, error) {
@@ -101,6 +317,48 @@ func (o *Orchestrator) Cleanup(ctx context.Context) ([]string, error) {
 	return cleaned, nil
 }
 
+// Untracked returns the sorted names of installed artifacts whose installed version is not listed in
+// any cached index, e.g. because their repository was removed or they were installed from a local file.
+// Such artifacts can no longer be updated.
+func (o *Orchestrator) Untracked() ([]string, error) {
+	if o.ArtifactManager == nil || o.Index == nil {
+		return nil, fmt.Errorf("artifact manager and index are required: %w", errutils.ErrValidation)
+	}
+
+	installed, err := o.ArtifactManager.GetInstalledArtifacts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get installed artifacts: %w", err)
+	}
+
+	var untracked []string
+	for _, artifact := range installed {
+		if artifact.Status != model.StatusInstalled {
+			continue
+		}
+		found, err := o.Index.FindArtifacts(artifact.Name)
+		if err != nil && !errors.Is(err, errutils.ErrArtifactNotFound) {
+			return nil, fmt.Errorf("failed to look up %s: %w", artifact.Name, err)
+		}
+		if !indexesContainVersion(found, artifact.Version) {
+			untracked = append(untracked, artifact.Name)
+		}
+	}
+	slices.Sort(untracked)
+	return untracked, nil
+}
+
+// indexesContainVersion reports whether any of the per-repository descriptors has the given version.
+func indexesContainVersion(found map[string][]*model.IndexArtifactDescriptor, version string) bool {
+	for _, descriptors := range found {
+		for _, desc := range descriptors {
+			if desc.Version == version {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Update resolves and updates packages to their latest compatible versions.
 func (o *Orchestrator) Update(ctx context.Context, opts UpdateOptions) error {
 	if o.ArtifactManager == nil {
@@ -115,7 +373,7 @@ func (o *Orchestrator) Update(ctx context.Context, opts UpdateOptions) error {
 		return fmt.Errorf("failed to get installed artifacts: %w", err)
 	}
 	if len(installed) == 0 {
-		emit(o.Hooks, Event{Phase: "done", Msg: "no packages installed to update"})
+		emit(o.Hooks, Event{Phase: "done", Msg: "no packages installed to update", Summary: &Summary{}})
 		return nil
 	}
 
@@ -143,14 +401,28 @@ func (o *Orchestrator) Update(ctx context.Context, opts UpdateOptions) error {
 		return nil
 	}
 
+	// Packages requested for update that the plan leaves untouched are already up to date
+	summary := &Summary{Skipped: skippedPackages(packagesToUpdate, plan)}
+
 	// Check if updates are needed
 	if !checkForUpdates(plan) {
-		emit(o.Hooks, Event{Phase: "done", Msg: "all packages are already at the latest compatible versions"})
+		emit(o.Hooks, Event{Phase: "done", Msg: "all packages are already at the latest compatible versions", Summary: summary})
 		return nil
 	}
 
 	// Execute updates and report results
-	return o.executeUpdateWithResults(ctx, plan, opts)
+	return o.executeUpdateWithResults(ctx, plan, opts, summary)
+}
+
+// skippedPackages returns the names of packages that do not appear in the plan.
+func skippedPackages(packages []*model.InstalledArtifact, plan model.ResolvedArtifacts) []string {
+	var skipped []string
+	for _, pkg := range packages {
+		if !slices.ContainsFunc(plan.Artifacts, func(step model.ResolvedArtifact) bool { return step.Name == pkg.Name }) {
+			skipped = append(skipped, pkg.Name)
+		}
+	}
+	return skipped
 }
 
 // filterPackagesForUpdate filters installed artifacts to determine which packages should be updated.
@@ -173,7 +445,7 @@ func (o *Orchestrator) filterPackagesForUpdate(installed []*model.InstalledArtif
 		packagesToUpdate = installed
 	}
 	if len(packagesToUpdate) == 0 {
-		emit(o.Hooks, Event{Phase: "done", Msg: "no packages to update"})
+		emit(o.Hooks, Event{Phase: "done", Msg: "no packages to update", Summary: &Summary{}})
 		return nil, nil
 	}
 	return packagesToUpdate, nil
@@ -200,25 +472,32 @@ func checkForUpdates(plan model.ResolvedArtifacts) bool {
 }
 
 // executeUpdateWithResults handles the update execution and result reporting.
-func (o *Orchestrator) executeUpdateWithResults(ctx context.Context, plan model.ResolvedArtifacts, opts UpdateOptions) error {
+func (o *Orchestrator) executeUpdateWithResults(ctx context.Context, plan model.ResolvedArtifacts, opts UpdateOptions, summary *Summary) error {
 	// Prefetch and execute
-	fetched, err := o.prefetchPlanArtifacts(ctx, plan, download.Options{Dir: opts.CacheDir, Concurrency: opts.Concurrency})
+	fetched, _, servedBy, err := o.prefetchPlanArtifacts(ctx, plan, download.Options{Dir: opts.CacheDir, Concurrency: opts.Concurrency, Headers: opts.Headers, AllowInsecure: opts.AllowInsecure}, false)
 	if err != nil {
 		return fmt.Errorf("failed to prefetch updates: %w", err)
 	}
-	updatedCount, newlyInstalledCount, err := o.executeUpdatePlan(ctx, plan, fetched)
-	if err != nil {
+	if err := o.verifyPlanChecksums(plan, fetched, nil); err != nil {
+		return err
+	}
+	if err := o.verifyFetchedIdentities(ctx, plan, fetched); err != nil {
+		return err
+	}
+	if err := o.executeUpdatePlan(ctx, plan, fetched, servedBy, summary); err != nil {
+		emit(o.Hooks, Event{Phase: "error", Msg: err.Error(), Summary: summary})
 		return err
 	}
 
+	updatedCount, newlyInstalledCount := len(summary.Updated), len(summary.Installed)
 	if updatedCount > 0 || newlyInstalledCount > 0 {
 		msg := fmt.Sprintf("successfully updated %d packages", updatedCount)
 		if newlyInstalledCount > 0 {
 			msg += fmt.Sprintf(" and installed %d new dependencies", newlyInstalledCount)
 		}
-		emit(o.Hooks, Event{Phase: "done", Msg: msg})
+		emit(o.Hooks, Event{Phase: "done", Msg: msg, Summary: summary})
 	} else {
-		emit(o.Hooks, Event{Phase: "done", Msg: "no updates were performed"})
+		emit(o.Hooks, Event{Phase: "done", Msg: "no updates were performed", Summary: summary})
 	}
 	return nil
 }
@@ -228,8 +507,8 @@ func (o *Orchestrator) executeUpdateWithResults(ctx context.Context, plan model.
 func (o *Orchestrator) transformIndexURLs(repo *index.Repository, indexDir string) error {
 	indexPath := filepath.Join(indexDir, repo.Name+".json")
 
-	// Parse the index
-	idx, err := index.ParseIndexFromFile(indexPath)
+	// Parse the index; versions are validated with the configured ordering when the index is loaded
+	idx, err := index.ParseIndexFromFileWithComparator(indexPath, nil)
 	if err != nil {
 		return fmt.Errorf("failed to parse index: %w", err)
 	}
@@ -239,13 +518,7 @@ func (o *Orchestrator) transformIndexURLs(repo *index.Repository, indexDir strin
 	for _, artifact := range idx.Artifacts {
 		if artifact.URL != "" && !strings.HasPrefix(artifact.URL, "http") {
 			// This is a relative URL, convert it to absolute
-			repoURL := repo.URL.String()
-			// Remove the index.json part from the repository URL
-			baseURL := strings.TrimSuffix(repoURL, "/index.json")
-			if !strings.HasSuffix(baseURL, "/") {
-				baseURL += "/"
-			}
-			artifact.URL = baseURL + artifact.URL
+			artifact.URL = index.BaseURL(repo.URL) + artifact.URL
 			modified = true
 		}
 	}
@@ -266,8 +539,13 @@ func emit(h Hooks, e Event) {
 	}
 }
 
-// Install resolves and installs according to the plan (sequentially for now).
+// Install resolves and installs according to the plan, extracting up to opts.ExtractConcurrency artifacts at a time.
 func (o *Orchestrator) Install(ctx context.Context, requests []*model.ResolveRequest, opts InstallOptions) error {
+	return o.install(ctx, requests, opts, &Summary{})
+}
+
+// install resolves the requests and installs the plan, recording the outcome in summary.
+func (o *Orchestrator) install(ctx context.Context, requests []*model.ResolveRequest, opts InstallOptions, summary *Summary) error {
 	if o.Index == nil {
 		return fmt.Errorf("index planner is not configured: %w", errutils.ErrValidation)
 	}
@@ -282,6 +560,22 @@ func (o *Orchestrator) Install(ctx context.Context, requests []*model.ResolveReq
 	if err != nil {
 		return err
 	}
+	for _, skipped := range plan.SkippedOptional {
+		emit(o.Hooks, Event{Phase: "planning", Msg: fmt.Sprintf("skipping optional dependency %s of %s: %s", skipped.Name, skipped.RequiredBy, skipped.Reason)})
+	}
+	for _, relaxed := range plan.Relaxations {
+		emit(o.Hooks, Event{Phase: "planning", Msg: "relaxed resolution: " + relaxed})
+	}
+
+	return o.installPlan(ctx, plan, requests, opts, summary)
+}
+
+// installPlan downloads and installs the steps of a resolved plan and records the outcome in summary.
+// Every downloaded file is checked against the checksum of its step before anything is installed.
+func (o *Orchestrator) installPlan(ctx context.Context, plan model.ResolvedArtifacts, requests []*model.ResolveRequest, opts InstallOptions, summary *Summary) error {
+	if opts.MaxArtifacts > 0 && len(plan.Artifacts) > opts.MaxArtifacts {
+		return fmt.Errorf("plan contains %d artifacts, more than the limit of %d: %w", len(plan.Artifacts), opts.MaxArtifacts, errutils.ErrValidation)
+	}
 
 	// Dry run: just emit steps and return
 	if opts.DryRun {
@@ -293,19 +587,53 @@ func (o *Orchestrator) Install(ctx context.Context, requests []*model.ResolveReq
 	}
 
 	// Prefetch via Download Manager and capture paths (required for local-only installs)
-	fetched, err := o.prefetchPlanArtifacts(ctx, plan, download.Options{Dir: opts.CacheDir, Concurrency: opts.Concurrency})
+	fetched, trusted, servedBy, err := o.prefetchPlanArtifacts(ctx, plan, download.Options{Dir: opts.CacheDir, Concurrency: opts.Concurrency, Headers: opts.Headers, AllowInsecure: opts.AllowInsecure}, opts.TrustCache)
 	if err != nil {
 		return err
 	}
+	if err := o.verifyPlanChecksums(plan, fetched, trusted); err != nil {
+		return err
+	}
 
 	if o.ArtifactManager == nil {
 		return fmt.Errorf("artifact installer is not configured: %w", errutils.ErrValidation)
 	}
+	if err := o.verifyFetchedIdentities(ctx, plan, fetched); err != nil {
+		return err
+	}
 
-	if err := o.executeInstallPlan(ctx, plan, requests, fetched); err != nil {
+	if err := o.executeInstallPlan(ctx, plan, requests, fetched, servedBy, summary, opts.ExtractConcurrency); err != nil {
+		emit(o.Hooks, Event{Phase: "error", Msg: err.Error(), Summary: summary})
+		return err
+	}
+	if err := o.runPostBatchHooks(plan, summary); err != nil {
+		emit(o.Hooks, Event{Phase: "error", Msg: err.Error(), Summary: summary})
 		return err
 	}
-	emit(o.Hooks, Event{Phase: "done"})
+	emit(o.Hooks, Event{Phase: "done", Msg: summary.String(), Summary: summary})
+	return nil
+}
+
+// runPostBatchHooks runs the post-batch hooks of the artifacts installed or updated by the plan once,
+// in plan order, if the artifact manager supports them.
+func (o *Orchestrator) runPostBatchHooks(plan model.ResolvedArtifacts, summary *Summary) error {
+	runner, ok := o.ArtifactManager.(PostBatchHookRunner)
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, step := range plan.Artifacts {
+		if slices.Contains(summary.Installed, step.Name) || slices.Contains(summary.Updated, step.Name) {
+			names = append(names, step.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	emit(o.Hooks, Event{Phase: "post-batch", Msg: fmt.Sprintf("running post-batch hooks of %d artifacts", len(names))})
+	if err := runner.ExecutePostBatchHooks(names); err != nil {
+		return fmt.Errorf("post-batch hooks failed: %w", err)
+	}
 	return nil
 }
 
@@ -347,42 +675,144 @@ func (o *Orchestrator) buildInstallRequests(requests []*model.ResolveRequest) ([
 	return allRequests, nil
 }
 
+// verifyFetchedIdentities checks that every fetched file of the plan contains the artifact its step
+// requested, if the artifact manager supports it.
+func (o *Orchestrator) verifyFetchedIdentities(ctx context.Context, plan model.ResolvedArtifacts, fetched map[string]string) error {
+	verifier, ok := o.ArtifactManager.(ArtifactIdentityVerifier)
+	if !ok {
+		return nil
+	}
+	for _, step := range plan.Artifacts {
+		path := fetched[step.GetID()]
+		if path == "" {
+			continue
+		}
+		desc := &model.IndexArtifactDescriptor{Name: step.Name, Version: step.Version, OS: step.OS, Arch: step.Arch, ManifestDigest: step.ManifestDigest}
+		if err := verifier.VerifyArtifactIdentity(ctx, desc, path); err != nil {
+			return fmt.Errorf("downloaded file for %s does not match: %w", step.GetID(), err)
+		}
+	}
+	return nil
+}
+
+// verifyPlanChecksums checks every fetched file of the plan against the checksum of its step before anything
+// is installed, so a corrupt artifact late in the plan aborts it before earlier steps change the system.
+// All mismatches are reported together. Files taken from the cache as trusted are not hashed again.
+func (o *Orchestrator) verifyPlanChecksums(plan model.ResolvedArtifacts, fetched map[string]string, trusted map[string]bool) error {
+	var errs []error
+	emitted := false
+	for _, step := range plan.Artifacts {
+		id := step.GetID()
+		path := fetched[id]
+		if path == "" || step.Checksum == "" || trusted[id] {
+			continue
+		}
+		if !emitted {
+			emit(o.Hooks, Event{Phase: "verifying", Msg: "verifying checksums of downloaded artifacts"})
+			emitted = true
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			errs = append(errs, errutils.Wrapf(err, "failed to hash %s", path))
+			continue
+		}
+		if !strings.EqualFold(sum, step.Checksum) {
+			errs = append(errs, fmt.Errorf("checksum of %s is %s, expected %s: %w", id, sum, step.Checksum, errutils.ErrFileHashMismatch))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("aborting before installing any artifact: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 of the file at path.
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // prefetchPlanArtifacts downloads artifacts for a plan when a downloader is configured.
-func (o *Orchestrator) prefetchPlanArtifacts(ctx context.Context, plan model.ResolvedArtifacts, dlOpts download.Options) (map[string]string, error) {
+// With trustCache, items whose cache file already exists are used as-is without downloading or verifying them;
+// their IDs are returned in trusted. The URL each downloaded item was served by, its source URL or a mirror,
+// is returned in servedBy.
+func (o *Orchestrator) prefetchPlanArtifacts(ctx context.Context, plan model.ResolvedArtifacts, dlOpts download.Options, trustCache bool) (fetched map[string]string, trusted map[string]bool, servedBy map[string]*url.URL, err error) {
 	if o.DL == nil || !filepath.IsAbs(dlOpts.Dir) {
-		return map[string]string{}, nil
+		return map[string]string{}, nil, nil, nil
 	}
+	cached := make(map[string]string)
+	trusted = make(map[string]bool)
 	items := make([]download.Item, 0, len(plan.Artifacts))
 	for _, s := range plan.Artifacts {
 		if s.SourceURL == nil {
 			continue
 		}
-		items = append(items, download.Item{ID: s.GetID(), URL: s.SourceURL, Checksum: s.Checksum})
+		item := download.Item{ID: s.GetID(), URL: s.SourceURL, Checksum: s.Checksum, Mirrors: s.Mirrors, ServedBy: &url.URL{}}
+		if trustCache {
+			if path, ok := trustedCacheFile(dlOpts.Dir, item); ok {
+				emit(o.Hooks, Event{Phase: "downloading", ID: item.ID, Msg: "using cached artifact " + path})
+				cached[item.ID] = path
+				trusted[item.ID] = true
+				continue
+			}
+		}
+		items = append(items, item)
 	}
 	if len(items) == 0 {
-		return map[string]string{}, nil
+		return cached, trusted, nil, nil
 	}
 	emit(o.Hooks, Event{Phase: "downloading", Msg: "prefetching artifacts"})
-	fetched, err := o.DL.FetchAll(ctx, items, dlOpts)
+	fetched, err = o.DL.FetchAll(ctx, items, dlOpts)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
+	}
+	for id, path := range cached {
+		fetched[id] = path
+	}
+	servedBy = make(map[string]*url.URL, len(items))
+	for _, item := range items {
+		if item.ServedBy.String() != "" {
+			servedBy[item.ID] = item.ServedBy
+		}
 	}
-	return fetched, nil
+	return fetched, trusted, servedBy, nil
 }
 
-// executeInstallPlan installs/updates artifacts as instructed by the plan.
-func (o *Orchestrator) executeInstallPlan(ctx context.Context, plan model.ResolvedArtifacts, requests []*model.ResolveRequest, fetched map[string]string) error {
-	onlyUpdateReasonRequest := make([]*model.ResolveRequest, 0, len(requests))
-	onlyUpdateReasonRequest = append(onlyUpdateReasonRequest, requests...)
+// trustedCacheFile returns the cache path for item if a non-empty regular file exists there.
+func trustedCacheFile(cacheDir string, item download.Item) (string, bool) {
+	path := filepath.Join(cacheDir, download.CacheFilename(item))
+	st, err := os.Stat(path)
+	if err != nil || !st.Mode().IsRegular() || st.Size() == 0 {
+		return "", false
+	}
+	return path, true
+}
 
-	for _, step := range plan.Artifacts {
-		reqIdx := slices.IndexFunc(onlyUpdateReasonRequest, func(req *model.ResolveRequest) bool {
-			return req.Name == step.Name
-		})
-		if reqIdx != -1 {
-			onlyUpdateReasonRequest = append(onlyUpdateReasonRequest[:reqIdx], onlyUpdateReasonRequest[reqIdx+1:]...)
-		}
+// executeInstallPlan installs/updates artifacts as instructed by the plan and records the outcome in summary.
+// At most concurrency steps are extracted and installed at the same time.
+func (o *Orchestrator) executeInstallPlan(ctx context.Context, plan model.ResolvedArtifacts, requests []*model.ResolveRequest, fetched map[string]string, servedBy map[string]*url.URL, summary *Summary, concurrency int) error {
+	var mu sync.Mutex
+	record := func(list *[]string, name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		*list = append(*list, name)
+	}
+	recordSource := func(step model.ResolvedArtifact) {
+		mu.Lock()
+		defer mu.Unlock()
+		summary.Sources = append(summary.Sources, artifactSource(step, servedBy[step.GetID()]))
+	}
 
+	err := runPlanSteps(plan.Artifacts, concurrency, func(step model.ResolvedArtifact) error {
 		var actionMsg string
 		switch step.Action {
 		case model.ResolvedActionInstall:
@@ -397,15 +827,18 @@ func (o *Orchestrator) executeInstallPlan(ctx context.Context, plan model.Resolv
 			path = fetched[step.GetID()]
 		}
 		if path == "" {
+			record(&summary.Failed, step.Name)
 			return fmt.Errorf("no local file available for step %s; downloads are required for install: %w", step.GetID(), errutils.ErrDownloadFailed)
 		}
 		desc := &model.IndexArtifactDescriptor{
-			Name:     step.Name,
-			Version:  step.Version,
-			OS:       step.OS,
-			Arch:     step.Arch,
-			Checksum: step.Checksum,
-			URL:      "",
+			Name:           step.Name,
+			Version:        step.Version,
+			OS:             step.OS,
+			Arch:           step.Arch,
+			Checksum:       step.Checksum,
+			ManifestDigest: step.ManifestDigest,
+			URL:            "",
+			Repository:     step.Repository,
 		}
 		if step.SourceURL != nil {
 			desc.URL = step.SourceURL.String()
@@ -420,25 +853,50 @@ func (o *Orchestrator) executeInstallPlan(ctx context.Context, plan model.Resolv
 		}
 		switch step.Action {
 		case model.ResolvedActionInstall:
-			if err := o.ArtifactManager.InstallArtifact(ctx, desc, path, reason); err != nil {
+			if err := o.ArtifactManager.InstallArtifactWithDetail(ctx, desc, path, reason, installationDetail(step, reason)); err != nil {
+				record(&summary.Failed, step.Name)
 				return err
 			}
+			record(&summary.Installed, step.Name)
+			recordSource(step)
 		case model.ResolvedActionUpdate:
 			if err := o.ArtifactManager.UpdateArtifact(ctx, path, desc); err != nil {
+				record(&summary.Failed, step.Name)
 				return err
 			}
+			record(&summary.Updated, step.Name)
+			recordSource(step)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	for _, req := range onlyUpdateReasonRequest {
+	// Requested artifacts missing from the plan are already installed
+	for _, req := range requests {
+		if slices.ContainsFunc(plan.Artifacts, func(step model.ResolvedArtifact) bool { return step.Name == req.Name }) {
+			continue
+		}
 		if err := o.ArtifactManager.SetArtifactManuallyInstalled(req.Name); err != nil {
+			summary.Failed = append(summary.Failed, req.Name)
 			return err
 		}
+		summary.Skipped = append(summary.Skipped, req.Name)
 	}
 
 	return nil
 }
 
+// installationDetail returns the detail recorded for an installed step: why an automatically installed
+// artifact was pulled in. Manually installed artifacts need no explanation and get none.
+func installationDetail(step model.ResolvedArtifact, reason model.InstallationReason) string {
+	if reason != model.InstallationReasonAutomatic {
+		return ""
+	}
+	return step.Reason
+}
+
 // Uninstall resolves and uninstalls according to the reverse dependency plan (reverse order for dependencies).
 func (o *Orchestrator) Uninstall(ctx context.Context, req model.ResolveRequest, opts UninstallOptions) error {
 	emit(o.Hooks, Event{Phase: "planning", Msg: req.Name})
@@ -485,13 +943,17 @@ func (o *Orchestrator) Uninstall(ctx context.Context, req model.ResolveRequest,
 	}
 
 	// Process artifacts in reverse order to handle dependencies properly
+	summary := &Summary{}
 	for _, artifact := range slices.Backward(artifacts.Artifacts) {
 		emit(o.Hooks, Event{Phase: "uninstalling", ID: artifact.GetID(), Msg: artifact.Name + "@" + artifact.Version})
 		if err := o.ArtifactManager.UninstallArtifact(ctx, artifact.Name, false); err != nil {
+			summary.Failed = append(summary.Failed, artifact.Name)
+			emit(o.Hooks, Event{Phase: "error", Msg: err.Error(), Summary: summary})
 			return err
 		}
+		summary.Removed = append(summary.Removed, artifact.Name)
 	}
-	emit(o.Hooks, Event{Phase: "done"})
+	emit(o.Hooks, Event{Phase: "done", Msg: summary.String(), Summary: summary})
 	return nil
 }
 
@@ -538,23 +1000,26 @@ func buildUpdateRequests(installed, packagesToUpdate []*model.InstalledArtifact)
 	return reqs
 }
 
-// executeUpdatePlan runs the resolved update and install steps during update flow.
-func (o *Orchestrator) executeUpdatePlan(ctx context.Context, plan model.ResolvedArtifacts, fetched map[string]string) (updatedCount, newlyInstalledCount int, err error) {
+// executeUpdatePlan runs the resolved update and install steps during update flow and records the outcome in summary.
+func (o *Orchestrator) executeUpdatePlan(ctx context.Context, plan model.ResolvedArtifacts, fetched map[string]string, servedBy map[string]*url.URL, summary *Summary) error {
 	for _, step := range plan.Artifacts {
 		path := ""
 		if fetched != nil {
 			path = fetched[step.GetID()]
 		}
 		if path == "" {
-			return 0, 0, fmt.Errorf("no local file available for update step %s: %w", step.GetID(), errutils.ErrDownloadFailed)
+			summary.Failed = append(summary.Failed, step.Name)
+			return fmt.Errorf("no local file available for update step %s: %w", step.GetID(), errutils.ErrDownloadFailed)
 		}
 		desc := &model.IndexArtifactDescriptor{
-			Name:     step.Name,
-			Version:  step.Version,
-			OS:       step.OS,
-			Arch:     step.Arch,
-			Checksum: step.Checksum,
-			URL:      "",
+			Name:           step.Name,
+			Version:        step.Version,
+			OS:             step.OS,
+			Arch:           step.Arch,
+			Checksum:       step.Checksum,
+			ManifestDigest: step.ManifestDigest,
+			URL:            "",
+			Repository:     step.Repository,
 		}
 		if step.SourceURL != nil {
 			desc.URL = step.SourceURL.String()
@@ -562,17 +1027,41 @@ func (o *Orchestrator) executeUpdatePlan(ctx context.Context, plan model.Resolve
 		switch step.Action {
 		case model.ResolvedActionUpdate:
 			emit(o.Hooks, Event{Phase: "updating", ID: step.GetID(), Msg: step.Name + "@" + step.Version})
-			if err := o.ArtifactManager.UpdateArtifact(ctx, path, desc); err != nil {
-				return 0, 0, fmt.Errorf("failed to update %s: %w", step.Name, err)
+			err := o.ArtifactManager.UpdateArtifact(ctx, path, desc)
+			if errors.Is(err, errutils.ErrAlreadyUpToDate) {
+				// One up-to-date artifact does not fail the batch
+				emit(o.Hooks, Event{Phase: "skipped", ID: step.GetID(), Msg: step.Name + " is already at the latest version"})
+				summary.Skipped = append(summary.Skipped, step.Name)
+				continue
 			}
-			updatedCount++
+			if err != nil {
+				summary.Failed = append(summary.Failed, step.Name)
+				return fmt.Errorf("failed to update %s: %w", step.Name, err)
+			}
+			summary.Updated = append(summary.Updated, step.Name)
+			summary.Sources = append(summary.Sources, artifactSource(step, servedBy[step.GetID()]))
 		case model.ResolvedActionInstall:
 			emit(o.Hooks, Event{Phase: "installing", ID: step.GetID(), Msg: step.Name + "@" + step.Version})
-			if err := o.ArtifactManager.InstallArtifact(ctx, desc, path, model.InstallationReasonAutomatic); err != nil {
-				return 0, 0, fmt.Errorf("failed to install dependency %s: %w", step.Name, err)
+			if err := o.ArtifactManager.InstallArtifactWithDetail(ctx, desc, path, model.InstallationReasonAutomatic, installationDetail(step, model.InstallationReasonAutomatic)); err != nil {
+				summary.Failed = append(summary.Failed, step.Name)
+				return fmt.Errorf("failed to install dependency %s: %w", step.Name, err)
 			}
-			newlyInstalledCount++
+			summary.Installed = append(summary.Installed, step.Name)
+			summary.Sources = append(summary.Sources, artifactSource(step, servedBy[step.GetID()]))
 		}
 	}
-	return updatedCount, newlyInstalledCount, nil
+	return nil
+}
+
+// artifactSource returns the provenance of a plan step for the summary. servedBy is the URL its file was
+// downloaded from; if it is nil, e.g. for a file reused from the cache, the step's source URL is reported.
+func artifactSource(step model.ResolvedArtifact, servedBy *url.URL) ArtifactSource {
+	source := ArtifactSource{Name: step.Name, Version: step.Version, Checksum: step.Checksum}
+	switch {
+	case servedBy != nil:
+		source.URL = servedBy.String()
+	case step.SourceURL != nil:
+		source.URL = step.SourceURL.String()
+	}
+	return source
 }
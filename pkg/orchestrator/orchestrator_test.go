@@ -2,12 +2,21 @@ package orchestrator
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/glorpus-work/gotya/pkg/artifact"
+	"github.com/glorpus-work/gotya/pkg/auth"
 	"github.com/glorpus-work/gotya/pkg/download"
 	"github.com/glorpus-work/gotya/pkg/errutils"
 	"github.com/glorpus-work/gotya/pkg/index"
@@ -18,6 +27,13 @@ import (
 	"go.uber.org/mock/gomock"
 )
 
+// writeFetchedFile writes content to path like a download would and returns its checksum.
+func writeFetchedFile(t *testing.T, path, content string) string {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return sha256Hex([]byte(content))
+}
+
 func TestSyncAll_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -216,6 +232,7 @@ func TestInstall_PrefetchAndInstall_Success(t *testing.T) {
 
 	// Setup test data
 	tmp := t.TempDir()
+	checksum := writeFetchedFile(t, filepath.Join(tmp, "pkgA-1.0.0.tgz"), "pkgA")
 	sURL, _ := url.Parse("https://example.com/pkgA-1.0.0.tgz")
 	requests := []*model.ResolveRequest{
 		{
@@ -232,7 +249,7 @@ func TestInstall_PrefetchAndInstall_Success(t *testing.T) {
 		OS:        "linux",
 		Arch:      "amd64",
 		SourceURL: sURL,
-		Checksum:  "deadbeef",
+		Checksum:  checksum,
 		Action:    model.ResolvedActionInstall,
 	}
 	plan := model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{step}}
@@ -270,8 +287,8 @@ func TestInstall_PrefetchAndInstall_Success(t *testing.T) {
 		Times(1)
 	expectedArtifactPath := filepath.Join(tmp, "pkgA-1.0.0.tgz")
 	art.EXPECT().
-		InstallArtifact(gomock.Any(), gomock.Any(), expectedArtifactPath, gomock.Any()).
-		DoAndReturn(func(_ context.Context, desc *model.IndexArtifactDescriptor, _ string, _ model.InstallationReason) error {
+		InstallArtifactWithDetail(gomock.Any(), gomock.Any(), expectedArtifactPath, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, desc *model.IndexArtifactDescriptor, _ string, _ model.InstallationReason, _ string) error {
 			assert.Equal(t, step.Name, desc.Name, "artifact name should match")
 			assert.Equal(t, step.Version, desc.Version, "artifact version should match")
 			assert.Equal(t, step.OS, desc.OS, "artifact OS should match")
@@ -380,7 +397,7 @@ func TestInstall_ArtifactInstallError(t *testing.T) {
 	// Setup test data
 	tmpDir := t.TempDir()
 	tmpFile := filepath.Join(tmpDir, "pkgA-1.0.0.tgz")
-	require.NoError(t, os.WriteFile(tmpFile, []byte("test"), 0644), "failed to create temp file")
+	checksum := writeFetchedFile(t, tmpFile, "test")
 
 	sURL, _ := url.Parse("https://example.com/pkgA-1.0.0.tgz")
 
@@ -390,7 +407,7 @@ func TestInstall_ArtifactInstallError(t *testing.T) {
 		OS:        "linux",
 		Arch:      "amd64",
 		SourceURL: sURL,
-		Checksum:  "abc123",
+		Checksum:  checksum,
 		Action:    model.ResolvedActionInstall,
 	}
 
@@ -420,8 +437,8 @@ func TestInstall_ArtifactInstallError(t *testing.T) {
 		Return([]*model.InstalledArtifact{}, nil).
 		Times(1)
 	art.EXPECT().
-		InstallArtifact(gomock.Any(), gomock.Any(), tmpFile, gomock.Any()).
-		DoAndReturn(func(_ context.Context, desc *model.IndexArtifactDescriptor, _ string, _ model.InstallationReason) error {
+		InstallArtifactWithDetail(gomock.Any(), gomock.Any(), tmpFile, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, desc *model.IndexArtifactDescriptor, _ string, _ model.InstallationReason, _ string) error {
 			assert.Equal(t, step.Name, desc.Name, "artifact name should match")
 			assert.Equal(t, step.Version, desc.Version, "artifact version should match")
 			assert.Equal(t, step.OS, desc.OS, "artifact OS should match")
@@ -771,6 +788,8 @@ func TestInstall_InstallationReason_FirstArtifactManual(t *testing.T) {
 	defer ctrl.Finish()
 
 	// Setup test data - single artifact that should be manual
+	fetchedPath := filepath.Join(t.TempDir(), "pkgA-1.0.0.tgz")
+	checksum := writeFetchedFile(t, fetchedPath, "pkgA")
 	sURL, _ := url.Parse("https://example.com/pkgA-1.0.0.tgz")
 	requests := []*model.ResolveRequest{
 		{
@@ -787,7 +806,7 @@ func TestInstall_InstallationReason_FirstArtifactManual(t *testing.T) {
 		OS:        "linux",
 		Arch:      "amd64",
 		SourceURL: sURL,
-		Checksum:  "abc123",
+		Checksum:  checksum,
 		Action:    model.ResolvedActionInstall,
 	}
 
@@ -810,7 +829,7 @@ func TestInstall_InstallationReason_FirstArtifactManual(t *testing.T) {
 
 	dl.EXPECT().
 		FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).
-		Return(map[string]string{step.GetID(): "/tmp/pkgA-1.0.0.tgz"}, nil).
+		Return(map[string]string{step.GetID(): fetchedPath}, nil).
 		Times(1)
 
 	am.EXPECT().
@@ -818,10 +837,10 @@ func TestInstall_InstallationReason_FirstArtifactManual(t *testing.T) {
 		Return([]*model.InstalledArtifact{}, nil).
 		Times(1)
 
-	// Expect InstallArtifact call with InstallationReasonManual for the first (and only) artifact
+	// Expect InstallArtifactWithDetail call with InstallationReasonManual for the first (and only) artifact
 	am.EXPECT().
-		InstallArtifact(gomock.Any(), gomock.Any(), "/tmp/pkgA-1.0.0.tgz", model.InstallationReasonManual).
-		DoAndReturn(func(_ context.Context, desc *model.IndexArtifactDescriptor, _ string, reason model.InstallationReason) error {
+		InstallArtifactWithDetail(gomock.Any(), gomock.Any(), fetchedPath, model.InstallationReasonManual, gomock.Any()).
+		DoAndReturn(func(_ context.Context, desc *model.IndexArtifactDescriptor, _ string, reason model.InstallationReason, _ string) error {
 			// Verify that the reason is Manual for the primary artifact
 			assert.Equal(t, model.InstallationReasonManual, reason, "first artifact should have InstallationReasonManual")
 			assert.Equal(t, step.Name, desc.Name, "artifact name should match")
@@ -850,6 +869,46 @@ func TestInstall_InstallationReason_FirstArtifactManual(t *testing.T) {
 	require.NoError(t, err, "install should succeed")
 }
 
+func TestInstall_RecordsDependencyReason(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	libURL, _ := url.Parse("https://example.com/lib-1.5.0.tgz")
+	appURL, _ := url.Parse("https://example.com/app-1.0.0.tgz")
+	lib := model.ResolvedArtifact{Name: "lib", Version: "1.5.0", OS: "linux", Arch: "amd64", SourceURL: libURL, Action: model.ResolvedActionInstall, Reason: "required by app >= 1.2"}
+	app := model.ResolvedArtifact{Name: "app", Version: "1.0.0", OS: "linux", Arch: "amd64", SourceURL: appURL, Action: model.ResolvedActionInstall, Reason: "new artifact installation"}
+	plan := model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{lib, app}}
+
+	idx := mocks.NewMockArtifactResolver(ctrl)
+	dl := mocks.NewMockDownloader(ctrl)
+	am := mocks.NewMockArtifactManager(ctrl)
+
+	idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(plan, nil)
+	dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(map[string]string{lib.GetID(): "/tmp/lib.tgz", app.GetID(): "/tmp/app.tgz"}, nil)
+	am.EXPECT().GetInstalledArtifacts().Return([]*model.InstalledArtifact{}, nil)
+	gomock.InOrder(
+		am.EXPECT().InstallArtifactWithDetail(gomock.Any(), gomock.Any(), "/tmp/lib.tgz", model.InstallationReasonAutomatic, "required by app >= 1.2").Return(nil),
+		am.EXPECT().InstallArtifactWithDetail(gomock.Any(), gomock.Any(), "/tmp/app.tgz", model.InstallationReasonManual, "").Return(nil),
+	)
+
+	var messages []string
+	orch := &Orchestrator{
+		Index:           idx,
+		DL:              dl,
+		ArtifactManager: am,
+		Hooks: Hooks{OnEvent: func(e Event) {
+			if e.Phase == "installing" {
+				messages = append(messages, e.Msg)
+			}
+		}},
+	}
+
+	err := orch.Install(context.Background(), []*model.ResolveRequest{{Name: "app", OS: "linux", Arch: "amd64"}}, InstallOptions{CacheDir: t.TempDir()})
+	require.NoError(t, err)
+	assert.Contains(t, messages, "lib@1.5.0 (required by app >= 1.2)")
+}
+
 // Test functions that tested the old InstalledArtifacts approach have been removed
 // as the new resolver interface uses a different approach with multiple ResolveRequests.
 // The core resolver functionality is tested in pkg/index/resolve_test.go
@@ -968,6 +1027,43 @@ func TestCleanup_GetOrphanedError(t *testing.T) {
 	require.Nil(t, cleaned)
 }
 
+func TestUntracked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	am := mocks.NewMockArtifactManager(ctrl)
+	am.EXPECT().GetInstalledArtifacts().Return([]*model.InstalledArtifact{
+		{Name: "tracked", Version: "1.0.0", Status: model.StatusInstalled},
+		{Name: "local", Version: "1.0.0", Status: model.StatusInstalled},
+		{Name: "outdated", Version: "0.9.0", Status: model.StatusInstalled},
+		{Name: "placeholder", Version: "invalid", Status: model.StatusMissing},
+	}, nil)
+
+	idx := mocks.NewMockArtifactResolver(ctrl)
+	idx.EXPECT().FindArtifacts("tracked").Return(map[string][]*model.IndexArtifactDescriptor{
+		"main":  {{Name: "tracked", Version: "2.0.0"}},
+		"extra": {{Name: "tracked", Version: "1.0.0"}},
+	}, nil)
+	idx.EXPECT().FindArtifacts("local").Return(nil, errutils.ErrArtifactNotFound)
+	idx.EXPECT().FindArtifacts("outdated").Return(map[string][]*model.IndexArtifactDescriptor{
+		"main": {{Name: "outdated", Version: "1.0.0"}},
+	}, nil)
+
+	untracked, err := New(idx, nil, nil, am, Hooks{}).Untracked()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"local", "outdated"}, untracked)
+}
+
+func TestUntracked_IndexError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	am := mocks.NewMockArtifactManager(ctrl)
+	am.EXPECT().GetInstalledArtifacts().Return([]*model.InstalledArtifact{{Name: "app", Version: "1.0.0", Status: model.StatusInstalled}}, nil)
+	idx := mocks.NewMockArtifactResolver(ctrl)
+	idx.EXPECT().FindArtifacts("app").Return(nil, fmt.Errorf("corrupt index"))
+
+	_, err := New(idx, nil, nil, am, Hooks{}).Untracked()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "corrupt index")
+}
+
 // TestUpdate_NoInstalledPackages tests update when no packages are installed
 func TestUpdate_NoInstalledPackages(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -1150,6 +1246,8 @@ func TestUpdate_SuccessfulUpdate(t *testing.T) {
 
 	// Setup test data
 	tmpDir := t.TempDir()
+	fetchedPath := filepath.Join(tmpDir, "pkgA-2.0.0.tgz")
+	checksum := writeFetchedFile(t, fetchedPath, "pkgA")
 	sURL, _ := url.Parse("https://example.com/pkgA-2.0.0.tgz")
 	plan := model.ResolvedArtifacts{
 		Artifacts: []model.ResolvedArtifact{
@@ -1159,7 +1257,7 @@ func TestUpdate_SuccessfulUpdate(t *testing.T) {
 				OS:        "linux",
 				Arch:      "amd64",
 				SourceURL: sURL,
-				Checksum:  "abc123",
+				Checksum:  checksum,
 				Action:    model.ResolvedActionUpdate,
 				Reason:    "updating from 1.0.0 to 2.0.0",
 			},
@@ -1183,7 +1281,7 @@ func TestUpdate_SuccessfulUpdate(t *testing.T) {
 	dl := mocks.NewMockDownloader(ctrl)
 	dl.EXPECT().
 		FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).
-		Return(map[string]string{plan.Artifacts[0].GetID(): "/tmp/pkgA-2.0.0.tgz"}, nil).
+		Return(map[string]string{plan.Artifacts[0].GetID(): fetchedPath}, nil).
 		Times(1)
 
 	am := mocks.NewMockArtifactManager(ctrl)
@@ -1195,7 +1293,7 @@ func TestUpdate_SuccessfulUpdate(t *testing.T) {
 		Times(1)
 
 	am.EXPECT().
-		UpdateArtifact(gomock.Any(), "/tmp/pkgA-2.0.0.tgz", gomock.Any()).
+		UpdateArtifact(gomock.Any(), fetchedPath, gomock.Any()).
 		Return(nil).
 		Times(1)
 
@@ -1218,15 +1316,16 @@ func TestUpdate_SuccessfulUpdate(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify events
-	require.Len(t, events, 5) // planning (analyzing), planning (resolving), downloading, updating, done
+	require.Len(t, events, 6) // planning (analyzing), planning (resolving), downloading, verifying, updating, done
 	assert.Equal(t, "planning", events[0].Phase)
 	assert.Equal(t, "analyzing installed packages", events[0].Msg)
 	assert.Equal(t, "planning", events[1].Phase)
 	assert.Equal(t, "resolving updates for 1 packages", events[1].Msg)
 	assert.Equal(t, "downloading", events[2].Phase)
-	assert.Equal(t, "updating", events[3].Phase)
-	assert.Equal(t, "done", events[4].Phase)
-	assert.Contains(t, events[4].Msg, "successfully updated 1 packages")
+	assert.Equal(t, "verifying", events[3].Phase)
+	assert.Equal(t, "updating", events[4].Phase)
+	assert.Equal(t, "done", events[5].Phase)
+	assert.Contains(t, events[5].Msg, "successfully updated 1 packages")
 }
 
 // TestUpdate_NoUpdatesAvailable tests update when all packages are already at latest versions
@@ -1289,6 +1388,8 @@ func TestUpdate_SpecificPackageUpdate(t *testing.T) {
 	defer ctrl.Finish()
 
 	// Setup test data
+	fetchedPath := filepath.Join(t.TempDir(), "pkgB-3.0.0.tgz")
+	checksum := writeFetchedFile(t, fetchedPath, "pkgB")
 	sURL, _ := url.Parse("https://example.com/pkgB-3.0.0.tgz")
 	plan := model.ResolvedArtifacts{
 		Artifacts: []model.ResolvedArtifact{
@@ -1298,7 +1399,7 @@ func TestUpdate_SpecificPackageUpdate(t *testing.T) {
 				OS:        "linux",
 				Arch:      "amd64",
 				SourceURL: sURL,
-				Checksum:  "def456",
+				Checksum:  checksum,
 				Action:    model.ResolvedActionUpdate,
 				Reason:    "updating from 2.0.0 to 3.0.0",
 			},
@@ -1320,7 +1421,7 @@ func TestUpdate_SpecificPackageUpdate(t *testing.T) {
 	dl := mocks.NewMockDownloader(ctrl)
 	dl.EXPECT().
 		FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).
-		Return(map[string]string{plan.Artifacts[0].GetID(): "/tmp/pkgB-3.0.0.tgz"}, nil).
+		Return(map[string]string{plan.Artifacts[0].GetID(): fetchedPath}, nil).
 		Times(1)
 
 	am := mocks.NewMockArtifactManager(ctrl)
@@ -1333,7 +1434,7 @@ func TestUpdate_SpecificPackageUpdate(t *testing.T) {
 		Times(1)
 
 	am.EXPECT().
-		UpdateArtifact(gomock.Any(), "/tmp/pkgB-3.0.0.tgz", gomock.Any()).
+		UpdateArtifact(gomock.Any(), fetchedPath, gomock.Any()).
 		Return(nil).
 		Times(1)
 
@@ -1357,15 +1458,16 @@ func TestUpdate_SpecificPackageUpdate(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify events
-	require.Len(t, events, 5) // planning (analyzing), planning (resolving), downloading, updating, done
+	require.Len(t, events, 6) // planning (analyzing), planning (resolving), downloading, verifying, updating, done
 	assert.Equal(t, "planning", events[0].Phase)
 	assert.Equal(t, "analyzing installed packages", events[0].Msg)
 	assert.Equal(t, "planning", events[1].Phase)
 	assert.Equal(t, "resolving updates for 2 packages", events[1].Msg)
 	assert.Equal(t, "downloading", events[2].Phase)
-	assert.Equal(t, "updating", events[3].Phase)
-	assert.Equal(t, "done", events[4].Phase)
-	assert.Contains(t, events[4].Msg, "successfully updated 1 packages")
+	assert.Equal(t, "verifying", events[3].Phase)
+	assert.Equal(t, "updating", events[4].Phase)
+	assert.Equal(t, "done", events[5].Phase)
+	assert.Contains(t, events[5].Msg, "successfully updated 1 packages")
 }
 func TestCleanup_UninstallError(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -1424,3 +1526,930 @@ func TestCleanup_UninstallError(t *testing.T) {
 // Test functions that tested the old InstalledArtifacts approach have been removed
 // as the new resolver interface uses a different approach with multiple ResolveRequests.
 // The core resolver functionality is tested in pkg/index/resolve_test.go
+
+func TestInstall_TrustCache(t *testing.T) {
+	newStep := func(name, checksum string) model.ResolvedArtifact {
+		u, _ := url.Parse("https://example.com/" + name + "-1.0.0.tgz")
+		return model.ResolvedArtifact{
+			Name:      name,
+			Version:   "1.0.0",
+			OS:        "linux",
+			Arch:      "amd64",
+			SourceURL: u,
+			Checksum:  checksum,
+			Action:    model.ResolvedActionInstall,
+		}
+	}
+	cachedStep := newStep("pkgA", sha256Hex([]byte("pkgA")))
+	missingStep := newStep("pkgB", sha256Hex([]byte("pkgB")))
+	requests := []*model.ResolveRequest{{Name: "pkgA", OS: "linux", Arch: "amd64"}, {Name: "pkgB", OS: "linux", Arch: "amd64"}}
+
+	tests := []struct {
+		name       string
+		trustCache bool
+		plan       []model.ResolvedArtifact
+		fetchedIDs []string
+	}{
+		{name: "all cached skips download", trustCache: true, plan: []model.ResolvedArtifact{cachedStep}},
+		{name: "only missing items are downloaded", trustCache: true, plan: []model.ResolvedArtifact{cachedStep, missingStep}, fetchedIDs: []string{missingStep.GetID()}},
+		{name: "cache is ignored without trust", trustCache: false, plan: []model.ResolvedArtifact{cachedStep}, fetchedIDs: []string{cachedStep.GetID()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			tmp := t.TempDir()
+			// Seed the cache using the file name the download manager would use (the checksum).
+			cachedPath := filepath.Join(tmp, cachedStep.Checksum)
+			writeFetchedFile(t, cachedPath, "pkgA")
+
+			idx := mocks.NewMockArtifactResolver(ctrl)
+			idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(model.ResolvedArtifacts{Artifacts: tt.plan}, nil)
+
+			dl := mocks.NewMockDownloader(ctrl)
+			if len(tt.fetchedIDs) == 0 {
+				dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			} else {
+				dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, items []download.Item, _ download.Options) (map[string]string, error) {
+						result := make(map[string]string, len(items))
+						var ids []string
+						for _, item := range items {
+							ids = append(ids, item.ID)
+							result[item.ID] = filepath.Join(tmp, "downloaded-"+item.Checksum)
+							for _, step := range tt.plan {
+								if step.GetID() == item.ID {
+									writeFetchedFile(t, result[item.ID], step.Name)
+								}
+							}
+						}
+						assert.Equal(t, tt.fetchedIDs, ids)
+						return result, nil
+					})
+			}
+
+			art := mocks.NewMockArtifactManager(ctrl)
+			art.EXPECT().GetInstalledArtifacts().Return(nil, nil)
+			installedFrom := make(map[string]string)
+			art.EXPECT().InstallArtifactWithDetail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, desc *model.IndexArtifactDescriptor, path string, _ model.InstallationReason, _ string) error {
+					installedFrom[desc.Name] = path
+					return nil
+				}).Times(len(tt.plan))
+			art.EXPECT().SetArtifactManuallyInstalled(gomock.Any()).Return(nil).AnyTimes()
+
+			orch := &Orchestrator{Index: idx, DL: dl, ArtifactManager: art}
+			err := orch.Install(context.Background(), requests, InstallOptions{CacheDir: tmp, TrustCache: tt.trustCache})
+			require.NoError(t, err)
+
+			if tt.trustCache {
+				assert.Equal(t, cachedPath, installedFrom[cachedStep.Name])
+			} else {
+				assert.Equal(t, filepath.Join(tmp, "downloaded-"+cachedStep.Checksum), installedFrom[cachedStep.Name])
+			}
+		})
+	}
+}
+
+// lastSummary returns the summary attached to the final event.
+func lastSummary(t *testing.T, events []Event) *Summary {
+	t.Helper()
+	require.NotEmpty(t, events)
+	summary := events[len(events)-1].Summary
+	require.NotNil(t, summary, "final event should carry a summary")
+	return summary
+}
+
+func TestInstall_Summary_MixedPlan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tmp := t.TempDir()
+
+	newStep := func(name, version string, action model.ResolvedAction) model.ResolvedArtifact {
+		u, _ := url.Parse("https://example.com/" + name + ".tgz")
+		return model.ResolvedArtifact{Name: name, Version: version, OS: "linux", Arch: "amd64", SourceURL: u, Checksum: sha256Hex([]byte(name)), Action: action}
+	}
+	plan := model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{
+		newStep("dep", "1.0.0", model.ResolvedActionInstall),
+		newStep("app", "1.0.0", model.ResolvedActionInstall),
+		newStep("lib", "2.0.0", model.ResolvedActionUpdate),
+	}}
+	requests := []*model.ResolveRequest{
+		{Name: "app", OS: "linux", Arch: "amd64"},
+		{Name: "tool", OS: "linux", Arch: "amd64"}, // already installed, not part of the plan
+	}
+
+	idx := mocks.NewMockArtifactResolver(ctrl)
+	idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(plan, nil)
+	dl := mocks.NewMockDownloader(ctrl)
+	dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, items []download.Item, _ download.Options) (map[string]string, error) {
+			fetched := make(map[string]string, len(items))
+			for _, item := range items {
+				name, _, _ := strings.Cut(item.ID, "@")
+				fetched[item.ID] = filepath.Join(tmp, name)
+				writeFetchedFile(t, fetched[item.ID], name)
+			}
+			return fetched, nil
+		})
+	am := mocks.NewMockArtifactManager(ctrl)
+	am.EXPECT().GetInstalledArtifacts().Return([]*model.InstalledArtifact{
+		{Name: "tool", Version: "1.0.0"},
+		{Name: "lib", Version: "1.0.0"},
+	}, nil)
+	am.EXPECT().InstallArtifactWithDetail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	am.EXPECT().UpdateArtifact(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	am.EXPECT().SetArtifactManuallyInstalled("tool").Return(nil)
+
+	var events []Event
+	orch := New(idx, nil, dl, am, Hooks{OnEvent: func(e Event) { events = append(events, e) }})
+	require.NoError(t, orch.Install(context.Background(), requests, InstallOptions{CacheDir: tmp}))
+
+	summary := lastSummary(t, events)
+	assert.Equal(t, "done", events[len(events)-1].Phase)
+	assert.Equal(t, []string{"dep", "app"}, summary.Installed)
+	assert.Equal(t, []string{"lib"}, summary.Updated)
+	assert.Equal(t, []string{"tool"}, summary.Skipped)
+	assert.Empty(t, summary.Removed)
+	assert.Empty(t, summary.Failed)
+	assert.Equal(t, "installed 2, updated 1, removed 0, skipped 1, failed 0", summary.String())
+	assert.Equal(t, []ArtifactSource{
+		{Name: "dep", Version: "1.0.0", Checksum: sha256Hex([]byte("dep")), URL: "https://example.com/dep.tgz"},
+		{Name: "app", Version: "1.0.0", Checksum: sha256Hex([]byte("app")), URL: "https://example.com/app.tgz"},
+		{Name: "lib", Version: "2.0.0", Checksum: sha256Hex([]byte("lib")), URL: "https://example.com/lib.tgz"},
+	}, summary.Sources)
+}
+
+func TestInstall_Summary_Failure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tmp := t.TempDir()
+
+	u1, _ := url.Parse("https://example.com/dep.tgz")
+	u2, _ := url.Parse("https://example.com/app.tgz")
+	plan := model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{
+		{Name: "dep", Version: "1.0.0", SourceURL: u1, Action: model.ResolvedActionInstall},
+		{Name: "app", Version: "1.0.0", SourceURL: u2, Action: model.ResolvedActionInstall},
+	}}
+
+	idx := mocks.NewMockArtifactResolver(ctrl)
+	idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(plan, nil)
+	dl := mocks.NewMockDownloader(ctrl)
+	dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).Return(map[string]string{
+		plan.Artifacts[0].GetID(): filepath.Join(tmp, "dep"),
+		plan.Artifacts[1].GetID(): filepath.Join(tmp, "app"),
+	}, nil)
+	am := mocks.NewMockArtifactManager(ctrl)
+	am.EXPECT().GetInstalledArtifacts().Return(nil, nil)
+	gomock.InOrder(
+		am.EXPECT().InstallArtifactWithDetail(gomock.Any(), gomock.Any(), filepath.Join(tmp, "dep"), gomock.Any(), gomock.Any()).Return(nil),
+		am.EXPECT().InstallArtifactWithDetail(gomock.Any(), gomock.Any(), filepath.Join(tmp, "app"), gomock.Any(), gomock.Any()).Return(fmt.Errorf("boom")),
+	)
+
+	var events []Event
+	orch := New(idx, nil, dl, am, Hooks{OnEvent: func(e Event) { events = append(events, e) }})
+	err := orch.Install(context.Background(), []*model.ResolveRequest{{Name: "app"}}, InstallOptions{CacheDir: tmp})
+	require.Error(t, err)
+
+	summary := lastSummary(t, events)
+	assert.Equal(t, "error", events[len(events)-1].Phase)
+	assert.Equal(t, []string{"dep"}, summary.Installed)
+	assert.Equal(t, []string{"app"}, summary.Failed)
+	assert.Equal(t, []ArtifactSource{{Name: "dep", Version: "1.0.0", URL: "https://example.com/dep.tgz"}}, summary.Sources,
+		"failed artifacts have no source")
+}
+
+func TestUpdate_Summary(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tmp := t.TempDir()
+
+	writeFetchedFile(t, filepath.Join(tmp, "pkgA"), "pkgA")
+	sURL, _ := url.Parse("https://example.com/pkgA-2.0.0.tgz")
+	plan := model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{
+		{Name: "pkgA", Version: "2.0.0", SourceURL: sURL, Checksum: sha256Hex([]byte("pkgA")), Action: model.ResolvedActionUpdate},
+	}}
+
+	idx := mocks.NewMockArtifactResolver(ctrl)
+	idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(plan, nil)
+	dl := mocks.NewMockDownloader(ctrl)
+	dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).Return(map[string]string{plan.Artifacts[0].GetID(): filepath.Join(tmp, "pkgA")}, nil)
+	am := mocks.NewMockArtifactManager(ctrl)
+	am.EXPECT().GetInstalledArtifacts().Return([]*model.InstalledArtifact{
+		{Name: "pkgA", Version: "1.0.0"},
+		{Name: "pkgB", Version: "3.0.0"}, // already at the latest version
+	}, nil)
+	am.EXPECT().UpdateArtifact(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	var events []Event
+	orch := New(idx, nil, dl, am, Hooks{OnEvent: func(e Event) { events = append(events, e) }})
+	require.NoError(t, orch.Update(context.Background(), UpdateOptions{CacheDir: tmp}))
+
+	summary := lastSummary(t, events)
+	assert.Equal(t, []string{"pkgA"}, summary.Updated)
+	assert.Equal(t, []string{"pkgB"}, summary.Skipped)
+	assert.Empty(t, summary.Installed)
+	assert.Empty(t, summary.Failed)
+	assert.Equal(t, []ArtifactSource{{Name: "pkgA", Version: "2.0.0", Checksum: sha256Hex([]byte("pkgA")), URL: "https://example.com/pkgA-2.0.0.tgz"}}, summary.Sources)
+}
+
+func TestUninstall_Summary(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	req := model.ResolveRequest{Name: "lib"}
+
+	reverseIdx := mocks.NewMockArtifactReverseResolver(ctrl)
+	reverseIdx.EXPECT().ReverseResolve(gomock.Any(), req).Return(model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{
+		{Name: "lib", Version: "1.0.0"},
+		{Name: "app", Version: "1.0.0"},
+	}}, nil)
+	am := mocks.NewMockArtifactManager(ctrl)
+	am.EXPECT().UninstallArtifact(gomock.Any(), "app", false).Return(nil)
+	am.EXPECT().UninstallArtifact(gomock.Any(), "lib", false).Return(nil)
+
+	var events []Event
+	orch := &Orchestrator{ReverseIndex: reverseIdx, ArtifactManager: am, Hooks: Hooks{OnEvent: func(e Event) { events = append(events, e) }}}
+	require.NoError(t, orch.Uninstall(context.Background(), req, UninstallOptions{}))
+
+	summary := lastSummary(t, events)
+	assert.Equal(t, []string{"app", "lib"}, summary.Removed)
+	assert.Empty(t, summary.Failed)
+}
+
+func TestSyncAll_MinSyncInterval(t *testing.T) {
+	u1, _ := url.Parse("https://example.com/fresh/index.json")
+	u2, _ := url.Parse("https://example.com/stale/index.json")
+	repos := []*index.Repository{{Name: "fresh", URL: u1}, {Name: "stale", URL: u2}}
+
+	seed := func(t *testing.T) string {
+		dir := t.TempDir()
+		stalePath := filepath.Join(dir, "stale.json")
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "fresh.json"), []byte(`{"format_version":"1","packages":[]}`), 0o644))
+		require.NoError(t, os.WriteFile(stalePath, []byte(`{"format_version":"1","packages":[]}`), 0o644))
+		old := time.Now().Add(-2 * time.Hour)
+		require.NoError(t, os.Chtimes(stalePath, old, old))
+		return dir
+	}
+	fetchedIDs := func(t *testing.T, ctrl *gomock.Controller, ids *[]string) *mocks.MockDownloader {
+		dl := mocks.NewMockDownloader(ctrl)
+		dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, items []download.Item, _ download.Options) (map[string]string, error) {
+				for _, item := range items {
+					*ids = append(*ids, item.ID)
+				}
+				return map[string]string{}, nil
+			})
+		return dl
+	}
+
+	t.Run("sync within cooldown is skipped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		dir := seed(t)
+		var ids []string
+		var skipped []string
+		orch := &Orchestrator{DL: fetchedIDs(t, ctrl, &ids), Hooks: Hooks{OnEvent: func(e Event) {
+			if e.Phase == "skipped" {
+				skipped = append(skipped, e.ID)
+			}
+		}}}
+
+		require.NoError(t, orch.SyncAll(context.Background(), repos, dir, Options{MinSyncInterval: time.Hour}))
+		assert.Equal(t, []string{"stale"}, ids)
+		assert.Equal(t, []string{"fresh"}, skipped)
+	})
+
+	t.Run("all fresh performs no download", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		dir := seed(t)
+		dl := mocks.NewMockDownloader(ctrl)
+		dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+		orch := &Orchestrator{DL: dl}
+
+		require.NoError(t, orch.SyncAll(context.Background(), repos, dir, Options{MinSyncInterval: 24 * time.Hour}))
+	})
+
+	t.Run("force overrides cooldown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		dir := seed(t)
+		var ids []string
+		orch := &Orchestrator{DL: fetchedIDs(t, ctrl, &ids)}
+
+		require.NoError(t, orch.SyncAll(context.Background(), repos, dir, Options{MinSyncInterval: time.Hour, Force: true}))
+		assert.Equal(t, []string{"fresh", "stale"}, ids)
+	})
+
+	t.Run("not modified response starts cooldown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		dir := seed(t)
+		dl := mocks.NewMockDownloader(ctrl)
+		dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, items []download.Item, _ download.Options) (map[string]string, error) {
+				require.Len(t, items, 1)
+				items[0].Validators.NotModified = true
+				return map[string]string{}, nil
+			}).Times(1)
+		orch := &Orchestrator{DL: dl}
+
+		require.NoError(t, orch.SyncAll(context.Background(), repos, dir, Options{MinSyncInterval: time.Hour}))
+		require.NoError(t, orch.SyncAll(context.Background(), repos, dir, Options{MinSyncInterval: time.Hour}))
+	})
+}
+
+func TestSyncAll_SendsConfiguredHeaders(t *testing.T) {
+	var observed []http.Header
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		observed = append(observed, r.Header.Clone())
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"format_version":"1","packages":[]}`))
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/index.json")
+	headers := http.Header{}
+	headers.Set("User-Agent", "acme-mirror/1.0")
+	headers.Set("X-Analytics", "off")
+
+	orch := &Orchestrator{DL: download.NewManager(time.Second, "")}
+	err := orch.SyncAll(context.Background(), []*index.Repository{{Name: "main", URL: u}}, t.TempDir(), Options{Headers: headers, AllowInsecure: true})
+	require.NoError(t, err)
+
+	require.Len(t, observed, 1)
+	assert.Equal(t, "acme-mirror/1.0", observed[0].Get("User-Agent"))
+	assert.Equal(t, "off", observed[0].Get("X-Analytics"))
+}
+
+func TestSyncAll_BearerAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"format_version":"1","packages":[]}`))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL + "/index.json")
+	repos := []*index.Repository{{Name: "private", URL: u}}
+
+	orch := &Orchestrator{DL: download.NewManager(time.Second, "")}
+	require.Error(t, orch.SyncAll(context.Background(), repos, t.TempDir(), Options{AllowInsecure: true}))
+
+	dl := download.NewManager(time.Second, "")
+	dl.SetAuthenticators(map[string]auth.Authenticator{server.URL: &auth.BearerAuth{Token: "secret"}})
+	orch = &Orchestrator{DL: dl}
+	dir := t.TempDir()
+	require.NoError(t, orch.SyncAll(context.Background(), repos, dir, Options{AllowInsecure: true}))
+
+	cached, err := os.ReadFile(filepath.Join(dir, "private.json"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(cached), "secret")
+}
+
+func TestSyncAll_ConditionalSync(t *testing.T) {
+	const etag = `"rev-1"`
+	var requests []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Clone())
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		_, _ = w.Write([]byte(`{"format_version":"1","packages":[]}`))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL + "/index.json")
+	orch := &Orchestrator{DL: download.NewManager(time.Second, "")}
+	dir := t.TempDir()
+	indexPath := filepath.Join(dir, "main.json")
+
+	require.NoError(t, orch.SyncAll(context.Background(), []*index.Repository{{Name: "main", URL: u}}, dir, Options{AllowInsecure: true}))
+	cached, err := os.ReadFile(indexPath)
+	require.NoError(t, err)
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(indexPath, old, old))
+
+	// A new run knows nothing but the repository configuration, the validators come from the cache
+	repo := &index.Repository{Name: "main", URL: u}
+	require.NoError(t, orch.SyncAll(context.Background(), []*index.Repository{repo}, dir, Options{AllowInsecure: true}))
+
+	require.Len(t, requests, 2)
+	assert.Empty(t, requests[0].Get("If-None-Match"))
+	assert.Equal(t, etag, requests[1].Get("If-None-Match"))
+	assert.Equal(t, "Mon, 02 Jan 2006 15:04:05 GMT", requests[1].Get("If-Modified-Since"))
+	assert.Equal(t, etag, repo.ETag)
+	assert.Equal(t, "Mon, 02 Jan 2006 15:04:05 GMT", repo.LastModified)
+
+	retained, err := os.ReadFile(indexPath)
+	require.NoError(t, err)
+	assert.Equal(t, cached, retained)
+	st, err := os.Stat(indexPath)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), st.ModTime(), time.Minute, "a 304 counts as a sync for the cooldown")
+}
+
+func TestSyncAll_Mirrors(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer primary.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repo/pkgs/tool.gotya" {
+			_, _ = w.Write([]byte("tool"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"format_version":"1","packages":[{"name":"tool","version":"1.0.0","url":"pkgs/tool.gotya"}]}`))
+	}))
+	defer mirror.Close()
+	primaryURL, _ := url.Parse(primary.URL + "/repo/index.json")
+	mirrorURL, _ := url.Parse(mirror.URL + "/repo/index.json")
+	repo := &index.Repository{Name: "main", URL: primaryURL, Mirrors: []*url.URL{mirrorURL}}
+	orch := &Orchestrator{DL: download.NewManager(time.Second, "")}
+	dir := t.TempDir()
+
+	require.NoError(t, orch.SyncAll(context.Background(), []*index.Repository{repo}, dir, Options{AllowInsecure: true}))
+	require.NotNil(t, repo.ServedBy)
+	assert.Equal(t, mirrorURL.String(), repo.ServedBy.String())
+
+	// Artifacts are downloaded from the repository first and from the same location on its mirrors after
+	desc, err := index.NewManager([]*index.Repository{repo}, dir).ResolveArtifact("tool", ">= 0.0.0", "linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, primary.URL+"/repo/pkgs/tool.gotya", desc.URL)
+	require.Len(t, desc.Mirrors, 1)
+	assert.Equal(t, mirror.URL+"/repo/pkgs/tool.gotya", desc.Mirrors[0].String())
+
+	// The summary reports the mirror the artifact was downloaded from
+	ctrl := gomock.NewController(t)
+	sourceURL, _ := url.Parse(desc.URL)
+	plan := model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{
+		{Name: "tool", Version: "1.0.0", SourceURL: sourceURL, Mirrors: desc.Mirrors, Action: model.ResolvedActionInstall},
+	}}
+	idx := mocks.NewMockArtifactResolver(ctrl)
+	idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(plan, nil)
+	am := mocks.NewMockArtifactManager(ctrl)
+	am.EXPECT().GetInstalledArtifacts().Return(nil, nil)
+	am.EXPECT().InstallArtifactWithDetail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	var events []Event
+	orch = New(idx, nil, orch.DL, am, Hooks{OnEvent: func(e Event) { events = append(events, e) }})
+	require.NoError(t, orch.Install(context.Background(), []*model.ResolveRequest{{Name: "tool"}}, InstallOptions{CacheDir: t.TempDir(), AllowInsecure: true}))
+	assert.Equal(t, []ArtifactSource{{Name: "tool", Version: "1.0.0", URL: mirror.URL + "/repo/pkgs/tool.gotya"}}, lastSummary(t, events).Sources)
+}
+
+func TestSyncAll_SignedIndex(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	const original = `{"format_version":"1","packages":[{"name":"tool","version":"1.0.0","url":"https://example.com/tool.gotya"}]}`
+	const tampered = `{"format_version":"1","packages":[{"name":"tool","version":"1.0.0","url":"https://evil.example.com/tool.gotya"}]}`
+	body, signature := original, index.SignIndex([]byte(original), priv)
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/index.json":
+			if r.Header.Get("If-None-Match") == `"`+body+`"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"`+body+`"`)
+			_, _ = w.Write([]byte(body))
+		case "/index.json.sig":
+			if signature == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(signature)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL + "/index.json")
+	orch := &Orchestrator{DL: download.NewManager(time.Second, "")}
+	dir := t.TempDir()
+	indexPath := filepath.Join(dir, "main.json")
+	syncIndex := func() error {
+		repo := &index.Repository{Name: "main", URL: u, PublicKey: pub}
+		return orch.SyncAll(context.Background(), []*index.Repository{repo}, dir, Options{AllowInsecure: true})
+	}
+
+	require.NoError(t, syncIndex())
+	cached, err := os.ReadFile(indexPath)
+	require.NoError(t, err)
+	assert.JSONEq(t, original, string(cached))
+
+	// Unchanged indexes are revalidated without fetching the signature again
+	requested = nil
+	require.NoError(t, syncIndex())
+	assert.Equal(t, []string{"/index.json"}, requested)
+
+	t.Run("tampered index is discarded", func(t *testing.T) {
+		body = tampered
+		err := syncIndex()
+		require.ErrorIs(t, err, errutils.ErrValidation)
+		require.ErrorIs(t, err, index.ErrIndexSignatureInvalid)
+		retained, err := os.ReadFile(indexPath)
+		require.NoError(t, err)
+		assert.Equal(t, cached, retained)
+		assert.NoDirExists(t, filepath.Join(dir, unverifiedIndexDir))
+	})
+
+	t.Run("missing signature is rejected", func(t *testing.T) {
+		body, signature = tampered, nil
+		require.ErrorIs(t, syncIndex(), errutils.ErrValidation)
+		retained, err := os.ReadFile(indexPath)
+		require.NoError(t, err)
+		assert.Equal(t, cached, retained)
+	})
+
+	t.Run("without a key the index is not verified", func(t *testing.T) {
+		body, signature = tampered, nil
+		repo := &index.Repository{Name: "main", URL: u}
+		require.NoError(t, orch.SyncAll(context.Background(), []*index.Repository{repo}, dir, Options{AllowInsecure: true}))
+		synced, err := os.ReadFile(indexPath)
+		require.NoError(t, err)
+		assert.JSONEq(t, tampered, string(synced))
+	})
+
+	t.Run("adding a key verifies the cached index", func(t *testing.T) {
+		// The unverified cache from the previous sync would be confirmed by a 304 if its ETag was sent
+		body, signature = tampered, index.SignIndex([]byte(original), priv)
+		requested = nil
+		err := syncIndex()
+		require.ErrorIs(t, err, index.ErrIndexSignatureInvalid)
+		assert.Equal(t, []string{"/index.json", "/index.json.sig"}, requested)
+
+		body = original
+		require.NoError(t, syncIndex())
+		synced, err := os.ReadFile(indexPath)
+		require.NoError(t, err)
+		assert.JSONEq(t, original, string(synced))
+
+		requested = nil
+		require.NoError(t, syncIndex())
+		assert.Equal(t, []string{"/index.json"}, requested, "a verified cache is revalidated")
+	})
+}
+
+func TestSyncAll_ManyRepositoriesConcurrently(t *testing.T) {
+	const repoCount, concurrency = 24, 4
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			current := maxInFlight.Load()
+			if n <= current || maxInFlight.CompareAndSwap(current, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"format_version":"1","packages":[]}`))
+	}))
+	defer server.Close()
+
+	repos := make([]*index.Repository, 0, repoCount)
+	for i := range repoCount {
+		u, _ := url.Parse(fmt.Sprintf("%s/repo-%d/index.json", server.URL, i))
+		repos = append(repos, &index.Repository{Name: fmt.Sprintf("repo-%d", i), URL: u})
+	}
+	orch := &Orchestrator{DL: download.NewManager(time.Second, "")}
+	dir := t.TempDir()
+
+	require.NoError(t, orch.SyncAll(context.Background(), repos, dir, Options{Concurrency: concurrency, AllowInsecure: true}))
+	for _, repo := range repos {
+		assert.FileExists(t, filepath.Join(dir, repo.Name+".json"))
+		assert.Equal(t, repo.URL.String(), repo.ServedBy.String())
+	}
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(concurrency))
+}
+
+func TestSyncAll_InsecureRepositoryURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"format_version":"1","packages":[]}`))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL + "/index.json")
+	repos := []*index.Repository{{Name: "plain", URL: u}}
+	orch := &Orchestrator{DL: download.NewManager(time.Second, "")}
+
+	dir := t.TempDir()
+	err := orch.SyncAll(context.Background(), repos, dir, Options{})
+	require.ErrorIs(t, err, errutils.ErrInsecureURL)
+	assert.NoFileExists(t, filepath.Join(dir, "plain.json"))
+
+	require.NoError(t, orch.SyncAll(context.Background(), repos, dir, Options{AllowInsecure: true}))
+	assert.FileExists(t, filepath.Join(dir, "plain.json"))
+}
+
+func TestInstall_ExtractConcurrency(t *testing.T) {
+	newPlan := func() model.ResolvedArtifacts {
+		var steps []model.ResolvedArtifact
+		for _, name := range []string{"lib1", "lib2", "lib3", "lib4", "lib5"} {
+			u, _ := url.Parse("https://example.com/" + name + ".tgz")
+			steps = append(steps, model.ResolvedArtifact{Name: name, Version: "1.0.0", SourceURL: u, Action: model.ResolvedActionInstall})
+		}
+		u, _ := url.Parse("https://example.com/app.tgz")
+		steps = append(steps, model.ResolvedArtifact{Name: "app", Version: "1.0.0", SourceURL: u, Action: model.ResolvedActionInstall, Dependencies: []string{"lib1", "lib5"}})
+		return model.ResolvedArtifacts{Artifacts: steps}
+	}
+
+	run := func(t *testing.T, extractConcurrency int) (maxInFlight int, finished []string, appStarted []string) {
+		ctrl := gomock.NewController(t)
+		tmp := t.TempDir()
+		plan := newPlan()
+
+		idx := mocks.NewMockArtifactResolver(ctrl)
+		idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(plan, nil)
+		dl := mocks.NewMockDownloader(ctrl)
+		dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, items []download.Item, _ download.Options) (map[string]string, error) {
+				fetched := make(map[string]string, len(items))
+				for _, item := range items {
+					fetched[item.ID] = filepath.Join(tmp, item.ID)
+				}
+				return fetched, nil
+			})
+
+		// The instrumented install stands in for extraction and records how many run at once
+		var mu sync.Mutex
+		inFlight := 0
+		am := mocks.NewMockArtifactManager(ctrl)
+		am.EXPECT().GetInstalledArtifacts().Return(nil, nil)
+		am.EXPECT().InstallArtifactWithDetail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, desc *model.IndexArtifactDescriptor, _ string, _ model.InstallationReason, _ string) error {
+				mu.Lock()
+				inFlight++
+				maxInFlight = max(maxInFlight, inFlight)
+				if desc.Name == "app" {
+					appStarted = append([]string(nil), finished...)
+				}
+				mu.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				mu.Lock()
+				inFlight--
+				finished = append(finished, desc.Name)
+				mu.Unlock()
+				return nil
+			}).Times(len(plan.Artifacts))
+
+		orch := New(idx, nil, dl, am, Hooks{})
+		err := orch.Install(context.Background(), []*model.ResolveRequest{{Name: "app"}}, InstallOptions{CacheDir: tmp, ExtractConcurrency: extractConcurrency})
+		require.NoError(t, err)
+		return maxInFlight, finished, appStarted
+	}
+
+	t.Run("sequential by default", func(t *testing.T) {
+		maxInFlight, finished, _ := run(t, 0)
+		assert.Equal(t, 1, maxInFlight)
+		assert.Equal(t, []string{"lib1", "lib2", "lib3", "lib4", "lib5", "app"}, finished)
+	})
+
+	t.Run("bounded parallel extraction", func(t *testing.T) {
+		maxInFlight, finished, appStarted := run(t, 2)
+		assert.Equal(t, 2, maxInFlight, "no more than 2 extractions may run at once")
+		assert.Len(t, finished, 6)
+		assert.Contains(t, appStarted, "lib1", "app must wait for its dependencies")
+		assert.Contains(t, appStarted, "lib5", "app must wait for its dependencies")
+	})
+}
+
+func TestRunPlanSteps_StopsAfterFailure(t *testing.T) {
+	steps := []model.ResolvedArtifact{{Name: "a"}, {Name: "b", Dependencies: []string{"a"}}, {Name: "c"}, {Name: "d"}}
+
+	var mu sync.Mutex
+	var ran []string
+	err := runPlanSteps(steps, 1, func(step model.ResolvedArtifact) error {
+		mu.Lock()
+		ran = append(ran, step.Name)
+		mu.Unlock()
+		if step.Name == "a" {
+			return fmt.Errorf("boom")
+		}
+		return nil
+	})
+	require.EqualError(t, err, "boom")
+	assert.Equal(t, []string{"a"}, ran)
+}
+
+// batchArtifactManager is an artifact manager that supports post-batch hooks.
+type batchArtifactManager struct {
+	*mocks.MockArtifactManager
+	*mocks.MockPostBatchHookRunner
+}
+
+func TestInstall_PostBatchHooks(t *testing.T) {
+	contents := map[string][]byte{"app": []byte("app artifact"), "lib": []byte("lib artifact")}
+	lockPath := writeTestLock(t, contents)
+
+	setup := func(t *testing.T, hookErr error) (*Orchestrator, *[]Event, string) {
+		ctrl := gomock.NewController(t)
+		cacheDir := t.TempDir()
+		am := mocks.NewMockArtifactManager(ctrl)
+		am.EXPECT().GetInstalledArtifacts().Return(nil, nil)
+		am.EXPECT().InstallArtifactWithDetail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+		// Both artifacts are handed over together, once, after the whole plan is installed
+		runner := mocks.NewMockPostBatchHookRunner(ctrl)
+		runner.EXPECT().ExecutePostBatchHooks([]string{"lib", "app"}).Return(hookErr).Times(1)
+
+		var events []Event
+		orch := New(nil, nil, servingDownloader(t, ctrl, cacheDir, contents), batchArtifactManager{am, runner}, Hooks{OnEvent: func(e Event) { events = append(events, e) }})
+		return orch, &events, cacheDir
+	}
+
+	t.Run("run once", func(t *testing.T) {
+		orch, events, cacheDir := setup(t, nil)
+		require.NoError(t, orch.InstallFromLock(context.Background(), lockPath, InstallOptions{CacheDir: cacheDir}))
+		assert.Equal(t, []string{"lib", "app"}, lastSummary(t, *events).Installed)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		orch, events, cacheDir := setup(t, fmt.Errorf("cache rebuild failed"))
+		err := orch.InstallFromLock(context.Background(), lockPath, InstallOptions{CacheDir: cacheDir})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cache rebuild failed")
+		assert.Equal(t, "error", (*events)[len(*events)-1].Phase)
+	})
+}
+
+func TestInstall_MislabeledDownload(t *testing.T) {
+	// packArtifact packs a real artifact with the given identity
+	packArtifact := func(t *testing.T, name, version string) []byte {
+		inputDir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(inputDir, "meta"), 0o755))
+		require.NoError(t, os.MkdirAll(filepath.Join(inputDir, "data"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(inputDir, "data", name+".txt"), []byte(name), 0o644))
+		path, err := artifact.NewPacker(name, version, "linux", "amd64", "", name, nil, nil, inputDir, t.TempDir()).Pack()
+		require.NoError(t, err)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return content
+	}
+	install := func(t *testing.T, served map[string][]byte) (*artifact.ManagerImpl, error) {
+		ctrl := gomock.NewController(t)
+		dir := t.TempDir()
+		u, _ := url.Parse("https://example.com/app_1.0.0_linux_amd64.gotya")
+		idx := mocks.NewMockArtifactResolver(ctrl)
+		idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{
+			{Name: "app", Version: "1.0.0", OS: "linux", Arch: "amd64", SourceURL: u, Action: model.ResolvedActionInstall},
+		}}, nil)
+		am := artifact.NewManager("linux", "amd64", dir, filepath.Join(dir, "data"), filepath.Join(dir, "meta"), filepath.Join(dir, "installed.db"))
+
+		orch := New(idx, nil, servingDownloader(t, ctrl, t.TempDir(), served), am, Hooks{})
+		return am, orch.Install(context.Background(), []*model.ResolveRequest{{Name: "app", OS: "linux", Arch: "amd64"}}, InstallOptions{CacheDir: dir})
+	}
+
+	t.Run("matching file", func(t *testing.T) {
+		am, err := install(t, map[string][]byte{"app": packArtifact(t, "app", "1.0.0")})
+		require.NoError(t, err)
+		names, err := am.InstalledNames()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app"}, names)
+	})
+
+	t.Run("mislabeled file", func(t *testing.T) {
+		am, err := install(t, map[string][]byte{"app": packArtifact(t, "other", "2.0.0")})
+		require.ErrorIs(t, err, errutils.ErrArtifactInvalid)
+		assert.Contains(t, err.Error(), "downloaded file for app@1.0.0 does not match")
+		assert.Contains(t, err.Error(), "requested as app_1.0.0_linux_amd64 but contains other_2.0.0_linux_amd64")
+		names, err := am.InstalledNames()
+		require.NoError(t, err)
+		assert.Empty(t, names)
+	})
+}
+
+func TestInstall_RecordsSourceRepository(t *testing.T) {
+	inputDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDir, "data"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(inputDir, "data", "app.txt"), []byte("app"), 0o644))
+	path, err := artifact.NewPacker("app", "1.0.0", "linux", "amd64", "", "app", nil, nil, inputDir, t.TempDir()).Pack()
+	require.NoError(t, err)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	indexDir := t.TempDir()
+	indexJSON := fmt.Sprintf(`{"format_version":"1","packages":[{"name":"app","version":"1.0.0","url":"https://acme.example.com/app.gotya","checksum":%q,"os":"linux","arch":"amd64"}]}`, sha256Hex(content))
+	require.NoError(t, os.WriteFile(filepath.Join(indexDir, "acme.json"), []byte(indexJSON), 0o644))
+	idx := index.NewManager([]*index.Repository{{Name: "acme"}}, indexDir)
+
+	ctrl := gomock.NewController(t)
+	dir := t.TempDir()
+	am := artifact.NewManager("linux", "amd64", dir, filepath.Join(dir, "data"), filepath.Join(dir, "meta"), filepath.Join(dir, "installed.db"))
+	orch := New(idx, nil, servingDownloader(t, ctrl, t.TempDir(), map[string][]byte{"app": content}), am, Hooks{})
+
+	require.NoError(t, orch.Install(context.Background(), []*model.ResolveRequest{{Name: "app", OS: "linux", Arch: "amd64"}}, InstallOptions{CacheDir: dir}))
+	installed, err := am.GetInstalledArtifacts()
+	require.NoError(t, err)
+	require.Len(t, installed, 1)
+	assert.Equal(t, "acme", installed[0].SourceRepository)
+	assert.Equal(t, "https://acme.example.com/app.gotya", installed[0].InstalledFrom)
+}
+
+func TestInstall_MaxArtifacts(t *testing.T) {
+	plan := model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{
+		{Name: "app", Version: "1.0.0", OS: "linux", Arch: "amd64", Action: model.ResolvedActionInstall},
+		{Name: "lib", Version: "1.0.0", OS: "linux", Arch: "amd64", Action: model.ResolvedActionInstall},
+		{Name: "base", Version: "1.0.0", OS: "linux", Arch: "amd64", Action: model.ResolvedActionInstall},
+	}}
+	install := func(t *testing.T, limit int) error {
+		ctrl := gomock.NewController(t)
+		idx := mocks.NewMockArtifactResolver(ctrl)
+		idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(plan, nil)
+		orch := New(idx, nil, nil, nil, Hooks{})
+		return orch.Install(context.Background(), []*model.ResolveRequest{{Name: "app", OS: "linux", Arch: "amd64"}}, InstallOptions{DryRun: true, MaxArtifacts: limit})
+	}
+
+	require.NoError(t, install(t, 3))
+	require.NoError(t, install(t, 0), "0 disables the limit")
+
+	err := install(t, 2)
+	require.ErrorIs(t, err, errutils.ErrValidation)
+	assert.Contains(t, err.Error(), "plan contains 3 artifacts, more than the limit of 2")
+}
+
+func TestInstall_VerifiesPlanChecksumsBeforeInstalling(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tmp := t.TempDir()
+
+	newStep := func(name, checksum string) model.ResolvedArtifact {
+		u, _ := url.Parse("https://example.com/" + name + ".gotya")
+		return model.ResolvedArtifact{Name: name, Version: "1.0.0", OS: "linux", Arch: "amd64", SourceURL: u, Checksum: checksum, Action: model.ResolvedActionInstall}
+	}
+	// The index checksum of the last artifact does not match the downloaded file
+	plan := model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{
+		newStep("base", sha256Hex([]byte("base"))),
+		newStep("lib", sha256Hex([]byte("lib"))),
+		newStep("app", sha256Hex([]byte("app from the index"))),
+	}}
+
+	idx := mocks.NewMockArtifactResolver(ctrl)
+	idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(plan, nil)
+	dl := mocks.NewMockDownloader(ctrl)
+	dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, items []download.Item, _ download.Options) (map[string]string, error) {
+			fetched := make(map[string]string, len(items))
+			for _, item := range items {
+				name, _, _ := strings.Cut(item.ID, "@")
+				fetched[item.ID] = filepath.Join(tmp, name)
+				writeFetchedFile(t, fetched[item.ID], name)
+			}
+			return fetched, nil
+		})
+	am := mocks.NewMockArtifactManager(ctrl)
+	am.EXPECT().GetInstalledArtifacts().Return(nil, nil).AnyTimes()
+	am.EXPECT().InstallArtifactWithDetail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	var phases []string
+	orch := New(idx, nil, dl, am, Hooks{OnEvent: func(e Event) { phases = append(phases, e.Phase) }})
+	err := orch.Install(context.Background(), []*model.ResolveRequest{{Name: "app", OS: "linux", Arch: "amd64"}}, InstallOptions{CacheDir: tmp})
+	require.ErrorIs(t, err, errutils.ErrFileHashMismatch)
+	assert.Contains(t, err.Error(), "checksum of app@1.0.0 is "+sha256Hex([]byte("app")))
+	assert.NotContains(t, err.Error(), "lib@1.0.0")
+	assert.Contains(t, phases, "verifying")
+	assert.NotContains(t, phases, "installing")
+}
+
+func TestUpdate_SkipsUpToDateArtifact(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tmp := t.TempDir()
+
+	newStep := func(name string) model.ResolvedArtifact {
+		u, _ := url.Parse("https://example.com/" + name + "-2.0.0.tgz")
+		return model.ResolvedArtifact{Name: name, Version: "2.0.0", OS: "linux", Arch: "amd64", SourceURL: u, Checksum: sha256Hex([]byte(name)), Action: model.ResolvedActionUpdate}
+	}
+	plan := model.ResolvedArtifacts{Artifacts: []model.ResolvedArtifact{newStep("pkgA"), newStep("pkgB"), newStep("pkgC")}}
+
+	idx := mocks.NewMockArtifactResolver(ctrl)
+	idx.EXPECT().Resolve(gomock.Any(), gomock.Any()).Return(plan, nil)
+	dl := mocks.NewMockDownloader(ctrl)
+	dl.EXPECT().FetchAll(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, items []download.Item, _ download.Options) (map[string]string, error) {
+			fetched := make(map[string]string, len(items))
+			for _, item := range items {
+				name, _, _ := strings.Cut(item.ID, "@")
+				fetched[item.ID] = filepath.Join(tmp, name)
+				writeFetchedFile(t, fetched[item.ID], name)
+			}
+			return fetched, nil
+		})
+	am := mocks.NewMockArtifactManager(ctrl)
+	am.EXPECT().GetInstalledArtifacts().Return([]*model.InstalledArtifact{
+		{Name: "pkgA", Version: "1.0.0"},
+		{Name: "pkgB", Version: "2.0.0"},
+		{Name: "pkgC", Version: "1.0.0"},
+	}, nil)
+	gomock.InOrder(
+		am.EXPECT().UpdateArtifact(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+		am.EXPECT().UpdateArtifact(gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("artifact pkgB is %w", errutils.ErrAlreadyUpToDate)),
+		am.EXPECT().UpdateArtifact(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+	)
+
+	var events []Event
+	orch := New(idx, nil, dl, am, Hooks{OnEvent: func(e Event) { events = append(events, e) }})
+	require.NoError(t, orch.Update(context.Background(), UpdateOptions{CacheDir: tmp}))
+
+	summary := lastSummary(t, events)
+	assert.Equal(t, "done", events[len(events)-1].Phase)
+	assert.Equal(t, []string{"pkgA", "pkgC"}, summary.Updated)
+	assert.Equal(t, []string{"pkgB"}, summary.Skipped)
+	assert.Empty(t, summary.Failed)
+	assert.Contains(t, events, Event{Phase: "skipped", ID: "pkgB@2.0.0", Msg: "pkgB is already at the latest version"})
+}
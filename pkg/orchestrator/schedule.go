@@ -0,0 +1,72 @@
+package orchestrator
+
+import (
+	"sync"
+
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// runPlanSteps calls run for each step of a topologically sorted plan with at most concurrency steps in
+// flight. Steps start in plan order and wait for their dependencies in the plan to finish first, so a
+// concurrency of 1 runs the plan sequentially. After the first failure no further steps are started,
+// and the first error is returned once all started steps have finished.
+func runPlanSteps(steps []model.ResolvedArtifact, concurrency int, run func(model.ResolvedArtifact) error) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	position := make(map[string]int, len(steps))
+	for i, step := range steps {
+		position[step.Name] = i
+	}
+	done := make([]chan struct{}, len(steps))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	slots := make(chan struct{}, concurrency)
+	for i, step := range steps {
+		// Taking the slot before starting keeps plan order; dependencies come earlier and already hold
+		// or released a slot, so waiting for them while holding one cannot deadlock
+		slots <- struct{}{}
+		if failed() {
+			<-slots
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer close(done[i])
+
+			for _, dep := range step.Dependencies {
+				if j, ok := position[dep]; ok && j < i {
+					<-done[j]
+				}
+			}
+			if failed() {
+				return
+			}
+			if err := run(step); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,60 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/model"
+)
+
+// TreeNode is an artifact in a dependency tree with the version chosen by the resolver.
+type TreeNode struct {
+	Name         string      `json:"name"`
+	Version      string      `json:"version"`
+	Dependencies []*TreeNode `json:"dependencies,omitempty"`
+}
+
+// DependencyTree resolves req against the indexes and returns the artifact with its dependencies as a tree,
+// e.g. to preview an install. Installed artifacts are ignored, so the tree is complete even for dependencies
+// that are already installed. An artifact required by several others is the same node in each of them.
+// Optional dependencies that could not be resolved are left out.
+func (o *Orchestrator) DependencyTree(ctx context.Context, req *model.ResolveRequest) (*TreeNode, error) {
+	if o.Index == nil {
+		return nil, fmt.Errorf("index planner is not configured: %w", errutils.ErrValidation)
+	}
+	if req == nil {
+		return nil, fmt.Errorf("resolve request cannot be nil: %w", errutils.ErrValidation)
+	}
+
+	plan, err := o.Index.Resolve(ctx, []*model.ResolveRequest{req})
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make(map[string]model.ResolvedArtifact, len(plan.Artifacts))
+	for _, step := range plan.Artifacts {
+		steps[step.Name] = step
+	}
+	if _, ok := steps[req.Name]; !ok {
+		return nil, fmt.Errorf("%s is not part of its resolved plan: %w", req.Name, errutils.ErrArtifactNotFound)
+	}
+
+	nodes := make(map[string]*TreeNode, len(steps))
+	var build func(name string) *TreeNode
+	build = func(name string) *TreeNode {
+		if node, ok := nodes[name]; ok {
+			return node
+		}
+		step := steps[name]
+		node := &TreeNode{Name: step.Name, Version: step.Version}
+		nodes[name] = node
+		for _, dep := range step.Dependencies {
+			if _, ok := steps[dep]; ok {
+				node.Dependencies = append(node.Dependencies, build(dep))
+			}
+		}
+		return node
+	}
+	return build(req.Name), nil
+}
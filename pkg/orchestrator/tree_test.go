@@ -0,0 +1,47 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glorpus-work/gotya/pkg/errutils"
+	"github.com/glorpus-work/gotya/pkg/index"
+	"github.com/glorpus-work/gotya/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependencyTree(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "repo.json"), []byte(`{
+  "format_version": "1.0",
+  "last_update": "2024-08-16T10:00:00Z",
+  "packages": [
+    {"name":"a","version":"1.0.0","dependencies":[{"name":"b","version_constraint":"< 2.0.0"}],"url":"https://ex/a","checksum":"a1"},
+    {"name":"b","version":"1.5.0","dependencies":[{"name":"c","version_constraint":">= 1.0.0"}],"url":"https://ex/b-1.5","checksum":"b1"},
+    {"name":"b","version":"2.0.0","url":"https://ex/b-2.0","checksum":"b2"},
+    {"name":"c","version":"1.0.0","url":"https://ex/c-1.0","checksum":"c1"},
+    {"name":"c","version":"1.2.0","url":"https://ex/c-1.2","checksum":"c2"}
+  ]
+}`), 0o644))
+	orch := New(index.NewManager([]*index.Repository{{Name: "repo"}}, dir), nil, nil, nil, Hooks{})
+
+	tree, err := orch.DependencyTree(context.Background(), &model.ResolveRequest{Name: "a", OS: "linux", Arch: "amd64"})
+	require.NoError(t, err)
+	assert.Equal(t, &TreeNode{Name: "a", Version: "1.0.0", Dependencies: []*TreeNode{
+		{Name: "b", Version: "1.5.0", Dependencies: []*TreeNode{
+			{Name: "c", Version: "1.2.0"},
+		}},
+	}}, tree)
+
+	_, err = orch.DependencyTree(context.Background(), &model.ResolveRequest{Name: "unknown", OS: "linux", Arch: "amd64"})
+	require.Error(t, err)
+}
+
+func TestDependencyTree_NoIndex(t *testing.T) {
+	orch := New(nil, nil, nil, nil, Hooks{})
+	_, err := orch.DependencyTree(context.Background(), &model.ResolveRequest{Name: "a"})
+	assert.ErrorIs(t, err, errutils.ErrValidation)
+}
@@ -1,9 +1,12 @@
-//go:generate mockgen -destination=./mocks/orchestrator.go . ArtifactResolver,ArtifactReverseResolver,ArtifactManager,Downloader
+//go:generate mockgen -destination=./mocks/orchestrator.go . ArtifactResolver,ArtifactReverseResolver,ArtifactManager,PostBatchHookRunner,ArtifactIdentityVerifier,Downloader
 
 package orchestrator
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/glorpus-work/gotya/pkg/download"
 	"github.com/glorpus-work/gotya/pkg/model"
@@ -12,6 +15,7 @@ import (
 // ArtifactResolver is the subset of the index manager used by the orchestrator.
 type ArtifactResolver interface {
 	Resolve(ctx context.Context, requests []*model.ResolveRequest) (model.ResolvedArtifacts, error)
+	FindArtifacts(name string) (map[string][]*model.IndexArtifactDescriptor, error)
 }
 
 // ArtifactReverseResolver provides reverse dependency resolution.
@@ -21,7 +25,7 @@ type ArtifactReverseResolver interface {
 
 // ArtifactManager is the subset of the artifact manager used by the orchestrator.
 type ArtifactManager interface {
-	InstallArtifact(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string, reason model.InstallationReason) error
+	InstallArtifactWithDetail(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string, reason model.InstallationReason, detail string) error
 	UninstallArtifact(ctx context.Context, artifactName string, purge bool) error
 	UpdateArtifact(ctx context.Context, newArtifactPath string, newDescriptor *model.IndexArtifactDescriptor) error
 	GetOrphanedAutomaticArtifacts() ([]string, error)
@@ -29,6 +33,20 @@ type ArtifactManager interface {
 	SetArtifactManuallyInstalled(artifactName string) error
 }
 
+// PostBatchHookRunner is implemented by artifact managers that support post-batch hooks.
+// If the ArtifactManager implements it, the post-batch hooks of the installed and updated
+// artifacts run once after an install plan has completed.
+type PostBatchHookRunner interface {
+	ExecutePostBatchHooks(artifactNames []string) error
+}
+
+// ArtifactIdentityVerifier is implemented by artifact managers that can check a downloaded file is the
+// artifact it was requested as. If the ArtifactManager implements it, every fetched file of a plan is
+// checked before the first step is installed.
+type ArtifactIdentityVerifier interface {
+	VerifyArtifactIdentity(ctx context.Context, desc *model.IndexArtifactDescriptor, localPath string) error
+}
+
 // Downloader handles artifact downloading.
 type Downloader interface {
 	FetchAll(ctx context.Context, items []download.Item, opts download.Options) (map[string]string, error)
@@ -45,9 +63,35 @@ type Orchestrator struct {
 
 // Event represents a simple progress notification.
 type Event struct {
-	Phase string // resolving|planning|downloading|installing|done|error
-	ID    string // step ID
-	Msg   string
+	Phase   string // resolving|planning|downloading|verifying|installing|updating|skipped|post-batch|done|error
+	ID      string // step ID
+	Msg     string
+	Summary *Summary // set on the final event of a non dry-run Install, Update or Uninstall
+}
+
+// Summary reports the outcome of an Install, Update or Uninstall by artifact name.
+type Summary struct {
+	Installed []string
+	Updated   []string
+	Removed   []string
+	Skipped   []string
+	Failed    []string
+	// Sources records where each installed or updated artifact came from, in the order they were installed
+	Sources []ArtifactSource
+}
+
+// ArtifactSource is the provenance of an artifact installed or updated by the orchestrator.
+type ArtifactSource struct {
+	Name     string
+	Version  string
+	Checksum string // Checksum of the artifact file as listed in the index
+	URL      string // URL the artifact file was downloaded from
+}
+
+// String returns a short human-readable form of the summary counts.
+func (s *Summary) String() string {
+	return fmt.Sprintf("installed %d, updated %d, removed %d, skipped %d, failed %d",
+		len(s.Installed), len(s.Updated), len(s.Removed), len(s.Skipped), len(s.Failed))
 }
 
 // Hooks carries callbacks for progress events.
@@ -57,9 +101,19 @@ type Hooks struct {
 
 // InstallOptions control orchestrator install execution.
 type InstallOptions struct {
-	CacheDir    string
-	Concurrency int
-	DryRun      bool
+	CacheDir      string
+	Concurrency   int
+	DryRun        bool
+	TrustCache    bool        // Use existing cache files without re-downloading or re-verifying them
+	Headers       http.Header // Static headers sent with every artifact download
+	AllowInsecure bool        // Permit plain-HTTP artifact URLs
+	// ExtractConcurrency bounds how many artifacts are extracted and installed at the same time, independently
+	// of the download Concurrency. Values <= 1 install one artifact at a time in plan order. With higher values
+	// an artifact still waits for its dependencies, and Hooks.OnEvent may be called from several goroutines.
+	ExtractConcurrency int
+	// MaxArtifacts fails the install before anything is downloaded if the resolved plan has more steps,
+	// as a guard against runaway dependency expansion. 0 disables the limit.
+	MaxArtifacts int
 }
 
 // UninstallOptions control orchestrator uninstall execution.
@@ -71,15 +125,21 @@ type UninstallOptions struct {
 
 // UpdateOptions control orchestrator update execution.
 type UpdateOptions struct {
-	DryRun      bool
-	Packages    []string // Specific packages to update, empty means update all
-	Concurrency int
-	CacheDir    string
+	DryRun        bool
+	Packages      []string // Specific packages to update, empty means update all
+	Concurrency   int
+	CacheDir      string
+	Headers       http.Header // Static headers sent with every artifact download
+	AllowInsecure bool        // Permit plain-HTTP artifact URLs
 }
 
 // Options control orchestrator execution.
 type Options struct {
-	CacheDir    string
-	Concurrency int
-	DryRun      bool
+	CacheDir        string
+	Concurrency     int
+	DryRun          bool
+	MinSyncInterval time.Duration // Skip syncing indexes downloaded less than this long ago; 0 disables the cooldown
+	Force           bool          // Sync regardless of MinSyncInterval
+	Headers         http.Header   // Static headers sent with every index download
+	AllowInsecure   bool          // Permit plain-HTTP repository URLs
 }